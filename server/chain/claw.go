@@ -1,6 +1,7 @@
 package chain
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/ecdsa"
@@ -9,10 +10,16 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
 
 	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/util"
 )
 
 // ClawWallet holds the generated wallet data for a Claw agent.
@@ -48,6 +55,50 @@ func GenerateClawWallet() (*ClawWallet, error) {
 	}, nil
 }
 
+// RegisterClawAgent registers a Claw as an ERC-8004 agent in the Identity Registry.
+// The Claw's own wallet sends the tx, so it owns the resulting agent identity.
+// Returns the agentId and the transaction hash.
+func RegisterClawAgent(ctx context.Context, clawKey *ecdsa.PrivateKey, agentURI string) (*big.Int, string, error) {
+	if C() == nil {
+		return nil, "", fmt.Errorf("chain client not initialized")
+	}
+
+	opts, err := C().TransactOptsFromKey(ctx, clawKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tx, err := C().identityRegistry.Register(opts, agentURI)
+	if err != nil {
+		return nil, "", fmt.Errorf("register() call failed: %w", err)
+	}
+
+	receipt, err := bind.WaitMined(ctx, C().ethClient, tx)
+	if err != nil {
+		return nil, tx.Hash().Hex(), fmt.Errorf("waiting for tx receipt: %w", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, tx.Hash().Hex(), fmt.Errorf("register() tx reverted (status=%d)", receipt.Status)
+	}
+
+	agentId, err := extractAgentIdFromReceipt(receipt)
+	if err != nil {
+		return nil, tx.Hash().Hex(), fmt.Errorf("failed to extract agentId from receipt: %w", err)
+	}
+
+	util.Log.Info("[chain] Claw registered on-chain: agentId=%s, tx=%s", agentId.String(), tx.Hash().Hex())
+	return agentId, tx.Hash().Hex(), nil
+}
+
+// AgentRegistryID returns the CAIP-10 style identifier of the Identity Registry
+// ("eip155:<chainId>:<address>"), as used in registration files.
+func AgentRegistryID() string {
+	if C() == nil {
+		return ""
+	}
+	return fmt.Sprintf("eip155:%s:%s", C().ChainID().String(), C().identityRegistry.Address().Hex())
+}
+
 // DecryptClawPrivateKey decrypts a Claw's encrypted private key to use for signing transactions.
 func DecryptClawPrivateKey(encryptedPK string) (*ecdsa.PrivateKey, error) {
 	pkBytes, err := decryptPrivateKey(encryptedPK)
@@ -72,6 +123,22 @@ func GetClawAddress(encryptedPK string) (string, error) {
 	return crypto.PubkeyToAddress(key.PublicKey).Hex(), nil
 }
 
+// ExportClawKeystore re-encrypts a Claw's private key as a standard Web3
+// Secret Storage (keystore v3) JSON file protected by passphrase, which
+// wallets such as MetaMask or geth can import.
+func ExportClawKeystore(encryptedPK, passphrase string) ([]byte, error) {
+	privateKey, err := DecryptClawPrivateKey(encryptedPK)
+	if err != nil {
+		return nil, err
+	}
+	key := &keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}
+	return keystore.EncryptKey(key, passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+}
+
 // encryptPrivateKey encrypts raw private key bytes using AES-256-GCM.
 // Returns base64-encoded ciphertext (nonce prepended).
 func encryptPrivateKey(plaintext []byte) (string, error) {
@@ -5,6 +5,7 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
@@ -21,33 +22,54 @@ type Client struct {
 	ethClient          *ethclient.Client
 	identityRegistry   *contracts.IdentityRegistry
 	reputationRegistry *contracts.ReputationRegistry
+	multicall          *contracts.Multicall3 // nil = batch reads as JSON-RPC batches
 	platformKey        *ecdsa.PrivateKey
 	platformAddr       common.Address
 	chainID            *big.Int
 }
 
-// Global chain client instance
-var C *Client
+// current is the global chain client. Set once, by the first Init that
+// succeeds; an unreachable RPC at startup leaves it nil until the
+// chain-health job's retry connects from its own goroutine.
+var current atomic.Pointer[Client]
+
+// C returns the global chain client, or nil while it isn't connected. Once
+// set it never changes back, so a non-nil result stays valid.
+func C() *Client {
+	return current.Load()
+}
 
 // Init initializes the blockchain client and contract bindings.
-// It connects to the BSC RPC, parses the platform private key, and binds to
+// It connects to the BSC RPC (BSC_RPC_URL, failing over to
+// BSC_RPC_FALLBACK_URLS), parses the platform private key, and binds to
 // the pre-deployed ERC-8004 IdentityRegistry and ReputationRegistry contracts.
+// It returns an error wrapping ErrRPCUnavailable when no endpoint answers.
 func Init() error {
 	cfg := config.Cfg
 
-	// Connect to BSC RPC
-	client, err := ethclient.Dial(cfg.BSCRPCURL)
+	// Connect to BSC RPC through the failover pool
+	if pool == nil {
+		p, err := newRPCPool(rpcEndpointURLs())
+		if err != nil {
+			return err
+		}
+		pool = p
+	}
+	client, err := pool.dial()
 	if err != nil {
-		return fmt.Errorf("failed to connect to BSC RPC (%s): %w", cfg.BSCRPCURL, err)
+		return fmt.Errorf("failed to connect to BSC RPC: %w", err)
 	}
 
 	// Get chain ID for transaction signing
-	chainID, err := client.ChainID(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), rpcResponseTimeout)
+	defer cancel()
+	chainID, err := client.ChainID(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get chain ID: %w", err)
+		return fmt.Errorf("%w: failed to get chain ID: %v", ErrRPCUnavailable, err)
 	}
 	log := util.Log.WithPrefix("[chain]")
-	log.Info("Connected to chain ID: %s (RPC: %s)", chainID.String(), cfg.BSCRPCURL)
+	log.Info("Connected to chain ID: %s (RPC: %s, %d endpoints)", chainID.String(),
+		pool.endpoints[pool.active.Load()].label, len(pool.endpoints))
 
 	// Parse the platform private key (used for minting souls)
 	var platformKey *ecdsa.PrivateKey
@@ -84,6 +106,17 @@ func Init() error {
 	}
 	log.Debug("Reputation Registry bound: %s", reputationAddr.Hex())
 
+	// Bind to Multicall3 for batched reads
+	var multicall *contracts.Multicall3
+	if cfg.MulticallAddr != "" {
+		multicallAddr := common.HexToAddress(cfg.MulticallAddr)
+		multicall, err = contracts.NewMulticall3(multicallAddr, client)
+		if err != nil {
+			return fmt.Errorf("failed to bind Multicall3 at %s: %w", multicallAddr.Hex(), err)
+		}
+		log.Debug("Multicall3 bound: %s", multicallAddr.Hex())
+	}
+
 	// Verify contracts are accessible by reading version
 	version, err := identityRegistry.GetVersion(&bind.CallOpts{})
 	if err != nil {
@@ -99,14 +132,15 @@ func Init() error {
 		log.Debug("Reputation Registry version: %s", repVersion)
 	}
 
-	C = &Client{
+	current.Store(&Client{
 		ethClient:          client,
 		identityRegistry:   identityRegistry,
 		reputationRegistry: reputationRegistry,
+		multicall:          multicall,
 		platformKey:        platformKey,
 		platformAddr:       platformAddr,
 		chainID:            chainID,
-	}
+	})
 
 	return nil
 }
@@ -0,0 +1,33 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// dnaMetadataKey is the soul metadata key anchoring a DNA version's hash.
+func dnaMetadataKey(version int) string {
+	return fmt.Sprintf("ensoul:dna:v%d", version)
+}
+
+// SetDNAHash records the hash of a soul's DNA version in its
+// "ensoul:dna:v<N>" metadata. Returns an empty tx hash when the chain client
+// is not configured.
+func SetDNAHash(ctx context.Context, agentId *big.Int, version int, dnaHash string) (string, error) {
+	return setSoulMetadata(ctx, agentId, dnaMetadataKey(version), dnaHash)
+}
+
+// ReadDNAHash reads the anchored hash of a DNA version, "" if none was written.
+func ReadDNAHash(ctx context.Context, agentId *big.Int, version int) (string, error) {
+	if C() == nil {
+		return "", fmt.Errorf("chain client not initialized")
+	}
+	value, err := C().identityRegistry.GetMetadata(&bind.CallOpts{Context: ctx}, agentId, dnaMetadataKey(version))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
@@ -25,12 +25,12 @@ var (
 
 // NeedsGasDrip checks if a Claw wallet's BNB balance is below the minimum threshold.
 func NeedsGasDrip(ctx context.Context, clawAddr string) (bool, error) {
-	if C == nil {
+	if C() == nil {
 		return false, fmt.Errorf("chain client not initialized")
 	}
 
 	addr := common.HexToAddress(clawAddr)
-	balance, err := C.ethClient.BalanceAt(ctx, addr, nil)
+	balance, err := C().ethClient.BalanceAt(ctx, addr, nil)
 	if err != nil {
 		return false, fmt.Errorf("failed to check balance for %s: %w", clawAddr, err)
 	}
@@ -47,23 +47,23 @@ func NeedsGasDrip(ctx context.Context, clawAddr string) (bool, error) {
 // DripGas sends a small amount of BNB from the platform wallet to a Claw wallet for gas fees.
 // Returns the tx hash on success.
 func DripGas(ctx context.Context, clawAddr string) (string, error) {
-	if C == nil {
+	if C() == nil {
 		return "", fmt.Errorf("chain client not initialized")
 	}
-	if C.platformKey == nil {
+	if C().platformKey == nil {
 		return "", fmt.Errorf("platform private key not configured, cannot drip gas")
 	}
 
 	toAddr := common.HexToAddress(clawAddr)
 
 	// Get the platform wallet nonce
-	nonce, err := C.ethClient.PendingNonceAt(ctx, C.platformAddr)
+	nonce, err := C().ethClient.PendingNonceAt(ctx, C().platformAddr)
 	if err != nil {
 		return "", fmt.Errorf("failed to get nonce: %w", err)
 	}
 
 	// Get suggested gas price
-	gasPrice, err := C.ethClient.SuggestGasPrice(ctx)
+	gasPrice, err := C().ethClient.SuggestGasPrice(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get gas price: %w", err)
 	}
@@ -75,13 +75,13 @@ func DripGas(ctx context.Context, clawAddr string) (string, error) {
 	tx := types.NewTransaction(nonce, toAddr, DripAmount, gasLimit, gasPrice, nil)
 
 	// Sign with platform key
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(C.chainID), C.platformKey)
+	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(C().chainID), C().platformKey)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign drip tx: %w", err)
 	}
 
 	// Send
-	if err := C.ethClient.SendTransaction(ctx, signedTx); err != nil {
+	if err := C().ethClient.SendTransaction(ctx, signedTx); err != nil {
 		return "", fmt.Errorf("failed to send drip tx: %w", err)
 	}
 
@@ -113,8 +113,17 @@ func EnsureGasAndDrip(ctx context.Context, clawAddr string) error {
 
 	util.Log.Debug("[chain] Gas drip successful for %s, waiting for confirmation... tx=%s", clawAddr, txHash)
 
-	// Wait for the drip tx to be mined before proceeding
-	// (the Claw needs the BNB in its account before it can send a tx)
+	if err := WaitForDrip(ctx, txHash); err != nil {
+		return err
+	}
+
+	util.Log.Info("[chain] Gas drip confirmed for %s: tx=%s", clawAddr, txHash)
+	return nil
+}
+
+// WaitForDrip waits for a drip tx to be mined successfully.
+// The Claw needs the BNB in its account before it can send a tx.
+func WaitForDrip(ctx context.Context, txHash string) error {
 	receipt, err := waitForTx(ctx, txHash)
 	if err != nil {
 		return fmt.Errorf("drip tx not confirmed: %w", err)
@@ -123,8 +132,6 @@ func EnsureGasAndDrip(ctx context.Context, clawAddr string) error {
 	if receipt.Status != types.ReceiptStatusSuccessful {
 		return fmt.Errorf("drip tx reverted: %s", txHash)
 	}
-
-	util.Log.Info("[chain] Gas drip confirmed for %s: tx=%s", clawAddr, txHash)
 	return nil
 }
 
@@ -140,7 +147,7 @@ func waitForTx(ctx context.Context, txHashHex string) (*types.Receipt, error) {
 	defer ticker.Stop()
 
 	for {
-		receipt, err := C.ethClient.TransactionReceipt(ctx, txHash)
+		receipt, err := C().ethClient.TransactionReceipt(ctx, txHash)
 		if err == nil {
 			return receipt, nil
 		}
@@ -156,8 +163,8 @@ func waitForTx(ctx context.Context, txHashHex string) (*types.Receipt, error) {
 
 // GetPlatformBalance returns the platform wallet's BNB balance for monitoring.
 func GetPlatformBalance(ctx context.Context) (*big.Int, error) {
-	if C == nil {
+	if C() == nil {
 		return nil, fmt.Errorf("chain client not initialized")
 	}
-	return C.ethClient.BalanceAt(ctx, C.platformAddr, nil)
+	return C().ethClient.BalanceAt(ctx, C().platformAddr, nil)
 }
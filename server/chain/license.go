@@ -0,0 +1,71 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// VerifyPayment checks a native BNB transfer: it must have succeeded, been sent
+// from `from` to `to`, and carried at least minWei. Returns the amount paid.
+// Waits briefly for the receipt if the tx is not mined yet.
+func VerifyPayment(ctx context.Context, txHashHex string, from, to common.Address, minWei *big.Int) (*big.Int, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+
+	tx, _, err := C().ethClient.TransactionByHash(ctx, common.HexToHash(txHashHex))
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s not found: %w", txHashHex, err)
+	}
+	if tx.To() == nil || *tx.To() != to {
+		return nil, fmt.Errorf("transaction %s is not a payment to %s", txHashHex, to.Hex())
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(C().chainID), tx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot recover sender of %s: %w", txHashHex, err)
+	}
+	if sender != from {
+		return nil, fmt.Errorf("transaction %s was sent by %s, not %s", txHashHex, sender.Hex(), from.Hex())
+	}
+	if tx.Value().Cmp(minWei) < 0 {
+		return nil, fmt.Errorf("transaction %s paid %s wei, %s required", txHashHex, tx.Value(), minWei)
+	}
+
+	receipt, err := waitForTx(ctx, txHashHex)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("transaction %s reverted", txHashHex)
+	}
+	return tx.Value(), nil
+}
+
+// SignPlatformMessage signs data with the platform key as an EIP-191 personal
+// message, so anyone can check it against PlatformAddress with ecrecover.
+// Returns an empty signature when no platform key is configured.
+func SignPlatformMessage(data []byte) (string, error) {
+	if C() == nil || !C().HasPlatformKey() {
+		return "", nil
+	}
+	sig, err := crypto.Sign(accounts.TextHash(data), C().platformKey)
+	if err != nil {
+		return "", err
+	}
+	sig[64] += 27 // wallets expect V as 27/28
+	return "0x" + common.Bytes2Hex(sig), nil
+}
+
+// SetLicenseReceipt records the latest access receipt hash of a prompt license
+// in the soul's "ensoul:license:<id>" metadata. Receipts are hash-chained, so
+// the on-chain value commits to every earlier access as well.
+func SetLicenseReceipt(ctx context.Context, agentId *big.Int, licenseID, receiptHash string) (string, error) {
+	return setSoulMetadata(ctx, agentId, "ensoul:license:"+strings.ToLower(licenseID), receiptHash)
+}
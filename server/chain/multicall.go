@@ -0,0 +1,259 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/contracts"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// viewCall is one contract view call of a batch.
+type viewCall struct {
+	target common.Address
+	data   []byte
+}
+
+// viewResult is the outcome of a viewCall. ok is false if the call reverted.
+type viewResult struct {
+	ok   bool
+	data []byte
+}
+
+// multicallMissing is set once Multicall3 turns out not to be deployed at
+// MULTICALL_ADDR, so later batches go straight to the JSON-RPC fallback.
+var multicallMissing atomic.Bool
+
+// batchView runs view calls in as few RPC requests as possible: through
+// Multicall3 in chunks of MULTICALL_BATCH_SIZE calls, or as one JSON-RPC
+// batch of eth_calls per chunk when Multicall3 is not available. A reverted
+// call is reported in its result; only transport failures return an error.
+func batchView(ctx context.Context, calls []viewCall) ([]viewResult, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+	size := config.Cfg.MulticallBatchSize
+	if size < 1 {
+		size = 1
+	}
+
+	results := make([]viewResult, 0, len(calls))
+	for start := 0; start < len(calls); start += size {
+		chunk := calls[start:min(start+size, len(calls))]
+		var out []viewResult
+		var err error
+		if C().multicall != nil && !multicallMissing.Load() {
+			out, err = aggregateViews(ctx, chunk)
+			if err != nil && missingView(err) {
+				util.Log.Warn("[chain] Multicall3 not available at %s, batching eth_calls instead: %v", C().multicall.Address().Hex(), err)
+				multicallMissing.Store(true)
+				out, err = rpcBatchViews(ctx, chunk)
+			}
+		} else {
+			out, err = rpcBatchViews(ctx, chunk)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, out...)
+	}
+	return results, nil
+}
+
+// aggregateViews runs calls in a single Multicall3 aggregate3 eth_call.
+func aggregateViews(ctx context.Context, calls []viewCall) ([]viewResult, error) {
+	batch := make([]contracts.Multicall3Call, len(calls))
+	for i, call := range calls {
+		batch[i] = contracts.Multicall3Call{Target: call.target, AllowFailure: true, CallData: call.data}
+	}
+	out, err := C().multicall.Aggregate3(&bind.CallOpts{Context: ctx}, batch)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate3() call failed: %w", err)
+	}
+	if len(out) != len(calls) {
+		return nil, fmt.Errorf("aggregate3() returned %d results for %d calls", len(out), len(calls))
+	}
+	results := make([]viewResult, len(out))
+	for i, r := range out {
+		results[i] = viewResult{ok: r.Success, data: r.ReturnData}
+	}
+	return results, nil
+}
+
+// rpcBatchViews sends calls as one JSON-RPC batch of eth_calls.
+func rpcBatchViews(ctx context.Context, calls []viewCall) ([]viewResult, error) {
+	data := make([]hexutil.Bytes, len(calls))
+	batch := make([]rpc.BatchElem, len(calls))
+	for i, call := range calls {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args: []interface{}{map[string]interface{}{
+				"to":   call.target,
+				"data": hexutil.Bytes(call.data),
+			}, "latest"},
+			Result: &data[i],
+		}
+	}
+	if err := C().ethClient.Client().BatchCallContext(ctx, batch); err != nil {
+		return nil, fmt.Errorf("eth_call batch failed: %w", err)
+	}
+	results := make([]viewResult, len(calls))
+	for i := range batch {
+		if batch[i].Error != nil {
+			if !isRevert(batch[i].Error) {
+				return nil, fmt.Errorf("eth_call failed: %w", batch[i].Error)
+			}
+			continue
+		}
+		results[i] = viewResult{ok: true, data: data[i]}
+	}
+	return results, nil
+}
+
+// SoulState is a soul NFT as read from the Identity Registry.
+type SoulState struct {
+	Exists bool           // false once burned (ownerOf reverts)
+	Owner  common.Address // zero if !Exists
+	URI    string         // agentURI
+	ReadAt time.Time
+}
+
+// soulCache keeps SoulStates for CHAIN_READ_CACHE_SECONDS.
+var soulCache = struct {
+	sync.Mutex
+	entries map[uint64]SoulState
+}{entries: make(map[uint64]SoulState)}
+
+// ReadSouls returns the owner and agentURI of many soul NFTs. States read
+// within CHAIN_READ_CACHE_SECONDS are served from memory; the rest are read
+// together, two calls per soul, in as few RPC requests as batchView allows.
+// Use ReadSoulOwner where a stale owner would be wrong (e.g. access checks).
+func ReadSouls(ctx context.Context, agentIDs []uint64) (map[uint64]SoulState, error) {
+	states := make(map[uint64]SoulState, len(agentIDs))
+	ttl := time.Duration(config.Cfg.ChainReadCacheSeconds) * time.Second
+
+	var missing []uint64
+	soulCache.Lock()
+	for _, id := range agentIDs {
+		if s, ok := soulCache.entries[id]; ok && time.Since(s.ReadAt) < ttl {
+			states[id] = s
+		} else if _, dup := states[id]; !dup {
+			missing = append(missing, id)
+		}
+	}
+	soulCache.Unlock()
+	if len(missing) == 0 {
+		return states, nil
+	}
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+
+	registry := C().identityRegistry
+	calls := make([]viewCall, 0, 2*len(missing))
+	for _, id := range missing {
+		tokenID := new(big.Int).SetUint64(id)
+		ownerData, err := registry.ABI.Pack("ownerOf", tokenID)
+		if err != nil {
+			return nil, err
+		}
+		uriData, err := registry.ABI.Pack("tokenURI", tokenID)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls,
+			viewCall{target: registry.Address(), data: ownerData},
+			viewCall{target: registry.Address(), data: uriData})
+	}
+	results, err := batchView(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	soulCache.Lock()
+	defer soulCache.Unlock()
+	for i, id := range missing {
+		state := SoulState{ReadAt: now}
+		if r := results[2*i]; r.ok {
+			if out, err := registry.ABI.Unpack("ownerOf", r.data); err == nil && len(out) == 1 {
+				state.Owner, _ = out[0].(common.Address)
+			}
+		}
+		state.Exists = state.Owner != (common.Address{})
+		if r := results[2*i+1]; r.ok && state.Exists {
+			if out, err := registry.ABI.Unpack("tokenURI", r.data); err == nil && len(out) == 1 {
+				state.URI, _ = out[0].(string)
+			}
+		}
+		states[id] = state
+		if ttl > 0 {
+			soulCache.entries[id] = state
+		}
+	}
+	return states, nil
+}
+
+// forgetSoul drops a soul's cached state after the server changed it on-chain.
+func forgetSoul(agentId *big.Int) {
+	soulCache.Lock()
+	delete(soulCache.entries, agentId.Uint64())
+	soulCache.Unlock()
+}
+
+// SummaryQuery is one getSummary read of the Reputation Registry.
+type SummaryQuery struct {
+	AgentID *big.Int
+	Clients []common.Address
+	Tag1    string
+	Tag2    string
+}
+
+// ReadReputationSummaries runs many getSummary reads in as few RPC requests
+// as batchView allows. Results are in query order; any failed read fails
+// the whole batch.
+func ReadReputationSummaries(ctx context.Context, queries []SummaryQuery) ([]contracts.SummaryResult, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+	registry := C().reputationRegistry
+	calls := make([]viewCall, len(queries))
+	for i, q := range queries {
+		data, err := registry.ABI.Pack("getSummary", q.AgentID, q.Clients, q.Tag1, q.Tag2)
+		if err != nil {
+			return nil, err
+		}
+		calls[i] = viewCall{target: registry.Address(), data: data}
+	}
+	results, err := batchView(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]contracts.SummaryResult, len(queries))
+	for i, r := range results {
+		if !r.ok {
+			return nil, fmt.Errorf("getSummary() call failed: reverted for agent %s", queries[i].AgentID)
+		}
+		out, err := registry.ABI.Unpack("getSummary", r.data)
+		if err != nil || len(out) != 3 {
+			return nil, fmt.Errorf("getSummary() returned malformed data for agent %s: %v", queries[i].AgentID, err)
+		}
+		summaries[i] = contracts.SummaryResult{
+			Count:                out[0].(uint64),
+			SummaryValue:         out[1].(*big.Int),
+			SummaryValueDecimals: out[2].(uint8),
+		}
+	}
+	return summaries, nil
+}
@@ -0,0 +1,55 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// FetchReceipts looks up many transaction receipts in a single batched RPC
+// request. Transactions that are not mined yet are absent from the result;
+// per-transaction lookup errors are treated the same way so one bad hash
+// doesn't hold up the rest.
+func FetchReceipts(ctx context.Context, txHashes []string) (map[string]*types.Receipt, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+
+	receipts := make([]*types.Receipt, len(txHashes))
+	batch := make([]rpc.BatchElem, len(txHashes))
+	for i, h := range txHashes {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{common.HexToHash(h)},
+			Result: &receipts[i],
+		}
+	}
+	if err := C().ethClient.Client().BatchCallContext(ctx, batch); err != nil {
+		return nil, fmt.Errorf("receipt batch failed: %w", err)
+	}
+
+	mined := make(map[string]*types.Receipt, len(txHashes))
+	for i, h := range txHashes {
+		if batch[i].Error == nil && receipts[i] != nil {
+			mined[h] = receipts[i]
+		}
+	}
+	return mined, nil
+}
+
+// TxReceipt returns a transaction's receipt, or nil if it is not mined yet.
+func TxReceipt(ctx context.Context, txHashHex string) (*types.Receipt, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+	receipt, err := C().ethClient.TransactionReceipt(ctx, common.HexToHash(txHashHex))
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
+	return receipt, err
+}
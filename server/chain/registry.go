@@ -0,0 +1,92 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// transferEventSig is the topic of the ERC-721
+// Transfer(address indexed from, address indexed to, uint256 indexed tokenId).
+var transferEventSig = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+// LatestBlock returns the current block number.
+func LatestBlock(ctx context.Context) (uint64, error) {
+	if C() == nil {
+		return 0, fmt.Errorf("chain client not initialized")
+	}
+	return C().ethClient.BlockNumber(ctx)
+}
+
+// FindBurns returns the agent IDs the Identity Registry burned (transferred
+// to the zero address) in blocks [fromBlock, toBlock].
+func FindBurns(ctx context.Context, fromBlock, toBlock uint64) ([]uint64, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+	logs, err := C().ethClient.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: []common.Address{C().identityRegistry.Address()},
+		Topics:    [][]common.Hash{{transferEventSig}, nil, {common.Hash{}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter Transfer logs %d-%d: %w", fromBlock, toBlock, err)
+	}
+
+	ids := make([]uint64, 0, len(logs))
+	for _, l := range logs {
+		if len(l.Topics) == 4 {
+			ids = append(ids, new(big.Int).SetBytes(l.Topics[3].Bytes()).Uint64())
+		}
+	}
+	return ids, nil
+}
+
+// RegistryPaused reports whether the Identity Registry is paused. A registry
+// without a paused() view cannot be paused.
+func RegistryPaused(ctx context.Context) (bool, error) {
+	if C() == nil {
+		return false, fmt.Errorf("chain client not initialized")
+	}
+	paused, err := C().identityRegistry.Paused(&bind.CallOpts{Context: ctx})
+	if err != nil && missingView(err) {
+		return false, nil
+	}
+	return paused, err
+}
+
+// SoulExists reports whether a soul NFT still exists. ownerOf reverts for
+// burned (or never minted) tokens.
+func SoulExists(ctx context.Context, agentId *big.Int) (bool, error) {
+	if C() == nil {
+		return false, fmt.Errorf("chain client not initialized")
+	}
+	owner, err := C().identityRegistry.OwnerOf(&bind.CallOpts{Context: ctx}, agentId)
+	if err != nil {
+		if isRevert(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return owner != (common.Address{}), nil
+}
+
+// isRevert reports whether a call failed because the contract reverted,
+// as opposed to an RPC or network failure.
+func isRevert(err error) bool {
+	return strings.Contains(err.Error(), "execution reverted")
+}
+
+// missingView reports whether a view call failed because the contract does
+// not implement it: a revert, or empty return data.
+func missingView(err error) bool {
+	return isRevert(err) || errors.Is(err, bind.ErrNoCode) ||
+		strings.Contains(err.Error(), "attempting to unmarshal an empty string")
+}
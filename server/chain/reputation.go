@@ -1,6 +1,7 @@
 package chain
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"fmt"
@@ -10,17 +11,51 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 
+	"github.com/ensoul-labs/ensoul-server/contracts"
 	"github.com/ensoul-labs/ensoul-server/util"
 )
 
-// SubmitFeedback sends reputation feedback from a Claw's wallet to the Reputation Registry.
+// SubmitFeedback sends reputation feedback from a Claw's wallet to the Reputation Registry
+// and waits for it to be mined. See SendFeedback for the parameters.
+func SubmitFeedback(
+	ctx context.Context,
+	clawKey *ecdsa.PrivateKey,
+	agentId *big.Int,
+	value int64,
+	tag1, tag2 string,
+	endpoint, feedbackURI string,
+	feedbackHash [32]byte,
+) (string, error) {
+	tx, err := SendFeedback(ctx, clawKey, agentId, value, tag1, tag2, endpoint, feedbackURI, feedbackHash)
+	if err != nil {
+		return "", err
+	}
+
+	// Wait for confirmation
+	receipt, err := bind.WaitMined(ctx, C().ethClient, tx)
+	if err != nil {
+		return tx.Hash().Hex(), fmt.Errorf("waiting for feedback receipt: %w", err)
+	}
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return tx.Hash().Hex(), fmt.Errorf("giveFeedback() tx reverted")
+	}
+
+	util.Log.Info("[chain] Reputation feedback confirmed: agentId=%s, value=%d, tx=%s",
+		agentId.String(), value, tx.Hash().Hex())
+
+	return tx.Hash().Hex(), nil
+}
+
+// SendFeedback sends reputation feedback from a Claw's wallet to the Reputation Registry
+// without waiting for it to be mined.
 // The Claw's own wallet address is the msg.sender, making each feedback independently verifiable.
 // value: the feedback score (e.g., 85 for 85% quality). valueDecimals: typically 0.
 // tag1/tag2: optional categorization tags (e.g., "personality", "knowledge").
 // endpoint: the agent's service endpoint URL.
 // feedbackURI: link to the detailed feedback content.
 // feedbackHash: keccak256 hash of the feedback content for integrity verification.
-func SubmitFeedback(
+func SendFeedback(
 	ctx context.Context,
 	clawKey *ecdsa.PrivateKey,
 	agentId *big.Int,
@@ -28,21 +63,52 @@ func SubmitFeedback(
 	tag1, tag2 string,
 	endpoint, feedbackURI string,
 	feedbackHash [32]byte,
-) (string, error) {
-	if C == nil {
-		return "", fmt.Errorf("chain client not initialized")
+) (*types.Transaction, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
 	}
 
 	// Create transaction opts from the Claw's key
-	opts, err := C.TransactOptsFromKey(ctx, clawKey)
+	opts, err := C().TransactOptsFromKey(ctx, clawKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+	return sendFeedback(opts, agentId, value, tag1, tag2, endpoint, feedbackURI, feedbackHash)
+}
+
+// RelayFeedback sends the same feedback as SendFeedback from the platform
+// wallet, for a self-custody Claw that authorized it off-chain. The platform
+// pays the gas, so the Claw's own address never needs BNB.
+func RelayFeedback(
+	ctx context.Context,
+	agentId *big.Int,
+	value int64,
+	tag1, tag2 string,
+	endpoint, feedbackURI string,
+	feedbackHash [32]byte,
+) (*types.Transaction, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+	opts, err := C().PlatformTransactOpts(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to create transactor: %w", err)
+		return nil, err
 	}
+	return sendFeedback(opts, agentId, value, tag1, tag2, endpoint, feedbackURI, feedbackHash)
+}
 
+func sendFeedback(
+	opts *bind.TransactOpts,
+	agentId *big.Int,
+	value int64,
+	tag1, tag2 string,
+	endpoint, feedbackURI string,
+	feedbackHash [32]byte,
+) (*types.Transaction, error) {
 	// Prepare feedback parameters
 	feedbackValue := big.NewInt(value)
 
-	tx, err := C.reputationRegistry.GiveFeedback(
+	tx, err := C().reputationRegistry.GiveFeedback(
 		opts,
 		agentId,
 		feedbackValue,
@@ -54,26 +120,13 @@ func SubmitFeedback(
 		feedbackHash,
 	)
 	if err != nil {
-		return "", fmt.Errorf("giveFeedback() call failed: %w", err)
+		return nil, fmt.Errorf("giveFeedback() call failed: %w", err)
 	}
 
 	util.Log.Debug("[chain] Reputation feedback tx sent: %s (agentId=%s, value=%d, tag1=%s)",
 		tx.Hash().Hex(), agentId.String(), value, tag1)
 
-	// Wait for confirmation
-	receipt, err := bind.WaitMined(ctx, C.ethClient, tx)
-	if err != nil {
-		return tx.Hash().Hex(), fmt.Errorf("waiting for feedback receipt: %w", err)
-	}
-
-	if receipt.Status != types.ReceiptStatusSuccessful {
-		return tx.Hash().Hex(), fmt.Errorf("giveFeedback() tx reverted")
-	}
-
-	util.Log.Info("[chain] Reputation feedback confirmed: agentId=%s, value=%d, tx=%s",
-		agentId.String(), value, tx.Hash().Hex())
-
-	return tx.Hash().Hex(), nil
+	return tx, nil
 }
 
 // ReadReputationSummary reads the aggregated reputation for a soul from the chain.
@@ -83,15 +136,26 @@ func ReadReputationSummary(
 	agentId *big.Int,
 	clientAddresses []common.Address,
 ) (uint64, *big.Int, uint8, error) {
-	if C == nil {
+	return ReadTaggedReputationSummary(ctx, agentId, clientAddresses, "", "") // No tag filtering
+}
+
+// ReadTaggedReputationSummary is ReadReputationSummary restricted to feedback
+// with the given tags; an empty tag matches any value.
+func ReadTaggedReputationSummary(
+	ctx context.Context,
+	agentId *big.Int,
+	clientAddresses []common.Address,
+	tag1, tag2 string,
+) (uint64, *big.Int, uint8, error) {
+	if C() == nil {
 		return 0, nil, 0, fmt.Errorf("chain client not initialized")
 	}
 
-	summary, err := C.reputationRegistry.GetSummary(
+	summary, err := C().reputationRegistry.GetSummary(
 		&bind.CallOpts{Context: ctx},
 		agentId,
 		clientAddresses,
-		"", "", // No tag filtering
+		tag1, tag2,
 	)
 	if err != nil {
 		return 0, nil, 0, fmt.Errorf("getSummary() call failed: %w", err)
@@ -106,12 +170,12 @@ func ReadFeedbackForClaw(
 	agentId *big.Int,
 	clawAddr common.Address,
 ) (*big.Int, string, string, error) {
-	if C == nil {
+	if C() == nil {
 		return nil, "", "", fmt.Errorf("chain client not initialized")
 	}
 
 	// Get the last feedback index
-	lastIndex, err := C.reputationRegistry.GetLastIndex(
+	lastIndex, err := C().reputationRegistry.GetLastIndex(
 		&bind.CallOpts{Context: ctx},
 		agentId,
 		clawAddr,
@@ -125,7 +189,7 @@ func ReadFeedbackForClaw(
 	}
 
 	// Read the latest feedback
-	feedback, err := C.reputationRegistry.ReadFeedback(
+	feedback, err := C().reputationRegistry.ReadFeedback(
 		&bind.CallOpts{Context: ctx},
 		agentId,
 		clawAddr,
@@ -140,12 +204,83 @@ func ReadFeedbackForClaw(
 
 // GetReputationClients returns all addresses that have given feedback to an agent.
 func GetReputationClients(ctx context.Context, agentId *big.Int) ([]common.Address, error) {
-	if C == nil {
+	if C() == nil {
 		return nil, fmt.Errorf("chain client not initialized")
 	}
 
-	return C.reputationRegistry.GetClients(
+	return C().reputationRegistry.GetClients(
 		&bind.CallOpts{Context: ctx},
 		agentId,
 	)
 }
+
+// ReadFeedbackFromTx returns the NewFeedback event emitted by a giveFeedback transaction.
+func ReadFeedbackFromTx(ctx context.Context, txHash string) (*contracts.NewFeedbackEvent, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+
+	receipt, err := C().ethClient.TransactionReceipt(ctx, common.HexToHash(txHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch receipt: %w", err)
+	}
+
+	for _, vLog := range receipt.Logs {
+		ev, err := C().reputationRegistry.ParseNewFeedbackEvent(*vLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode NewFeedback event: %w", err)
+		}
+		if ev != nil {
+			return ev, nil
+		}
+	}
+	return nil, fmt.Errorf("NewFeedback event not found in tx %s", txHash)
+}
+
+// FeedbackCall returns the Reputation Registry address and the giveFeedback
+// calldata for the same feedback SendFeedback would send, so a Claw holding
+// its own key can sign and broadcast it.
+func FeedbackCall(
+	agentId *big.Int,
+	value int64,
+	tag1, tag2 string,
+	endpoint, feedbackURI string,
+	feedbackHash [32]byte,
+) (common.Address, []byte, error) {
+	if C() == nil {
+		return common.Address{}, nil, fmt.Errorf("chain client not initialized")
+	}
+	data, err := C().reputationRegistry.ABI.Pack("giveFeedback",
+		agentId, big.NewInt(value), uint8(0), tag1, tag2, endpoint, feedbackURI, feedbackHash)
+	if err != nil {
+		return common.Address{}, nil, fmt.Errorf("failed to pack giveFeedback(): %w", err)
+	}
+	return C().reputationRegistry.Address(), data, nil
+}
+
+// VerifyFeedbackTx checks that txHash was sent by from to the Reputation
+// Registry with exactly the given calldata. It does not wait for the tx to
+// be mined.
+func VerifyFeedbackTx(ctx context.Context, txHashHex string, from common.Address, data []byte) error {
+	if C() == nil {
+		return fmt.Errorf("chain client not initialized")
+	}
+	tx, _, err := C().ethClient.TransactionByHash(ctx, common.HexToHash(txHashHex))
+	if err != nil {
+		return fmt.Errorf("transaction %s not found: %w", txHashHex, err)
+	}
+	if tx.To() == nil || *tx.To() != C().reputationRegistry.Address() {
+		return fmt.Errorf("transaction %s is not a call to the Reputation Registry", txHashHex)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(C().chainID), tx)
+	if err != nil {
+		return fmt.Errorf("cannot recover sender of %s: %w", txHashHex, err)
+	}
+	if sender != from {
+		return fmt.Errorf("transaction %s was sent by %s, not %s", txHashHex, sender.Hex(), from.Hex())
+	}
+	if !bytes.Equal(tx.Data(), data) {
+		return fmt.Errorf("transaction %s does not carry the expected feedback", txHashHex)
+	}
+	return nil
+}
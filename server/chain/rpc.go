@@ -0,0 +1,423 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// RPC failover settings.
+const (
+	rpcProbeTimeout     = 10 * time.Second
+	rpcResponseTimeout  = 30 * time.Second // a node that sends no response headers in this time is treated as down
+	rpcBackoffBase      = 5 * time.Second  // first retry of a failed endpoint; doubles per failure
+	rpcBackoffMax       = 5 * time.Minute
+	rpcMaxErrorBodySize = 4 << 10
+)
+
+// ErrRPCUnavailable is returned by Init when no RPC endpoint answers; the
+// chain-health job keeps retrying.
+var ErrRPCUnavailable = errors.New("no RPC endpoint reachable")
+
+var (
+	rpcHealthy = util.NewGaugeVec("ensoul_chain_rpc_healthy",
+		"Whether an RPC endpoint passed its last health check (1) or not (0).", "rpc")
+	rpcHeadBlock = util.NewGaugeVec("ensoul_chain_rpc_head_block",
+		"Latest block number reported by an RPC endpoint.", "rpc")
+	rpcLagBlocks = util.NewGaugeVec("ensoul_chain_rpc_lag_blocks",
+		"Blocks an RPC endpoint is behind the best one.", "rpc")
+	rpcHeadAge = util.NewGaugeVec("ensoul_chain_head_age_seconds",
+		"Age of the latest block on the RPC endpoint in use.")
+	rpcFailovers = util.NewCounterVec("ensoul_chain_rpc_failovers_total",
+		"Switches of the RPC endpoint in use, by the endpoint switched to.", "rpc")
+)
+
+// rpcEndpoint is one configured RPC URL and what is known of its health.
+type rpcEndpoint struct {
+	url   *url.URL
+	label string            // host only: RPC URLs often carry an API key
+	probe *ethclient.Client // direct client for health checks, bypassing failover
+
+	mu        sync.Mutex
+	healthy   bool
+	head      uint64
+	headTime  time.Time
+	lag       uint64
+	checkedAt time.Time
+	lastErr   string
+	failures  int
+	retryAt   time.Time // requests and probes skip the endpoint until then
+}
+
+// rpcPool routes JSON-RPC requests to the preferred healthy endpoint and, on
+// a network error or a 5xx / 429 answer, retries them on the next one.
+// Resending is safe: reads are idempotent, and a signed transaction sent
+// twice is the same transaction.
+type rpcPool struct {
+	endpoints []*rpcEndpoint
+	active    atomic.Int32
+	transport http.RoundTripper
+}
+
+// pool is the RPC pool behind C, kept across Init retries.
+var pool *rpcPool
+
+// rpcEndpointURLs returns BSC_RPC_URL followed by BSC_RPC_FALLBACK_URLS,
+// without duplicates.
+func rpcEndpointURLs() []string {
+	cfg := config.Cfg
+	seen := map[string]bool{}
+	var urls []string
+	for _, u := range append([]string{cfg.BSCRPCURL}, cfg.BSCRPCFallbackURLs...) {
+		if u = strings.TrimSpace(u); u != "" && !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// newRPCPool builds the pool from the configured endpoints. Only http(s)
+// endpoints can fail over; others are skipped with a warning.
+func newRPCPool(urls []string) (*rpcPool, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: rpcProbeTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.ResponseHeaderTimeout = rpcResponseTimeout
+
+	p := &rpcPool{transport: transport}
+	probeHTTP := &http.Client{Transport: transport, Timeout: rpcProbeTimeout}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			util.Log.Warn("[chain] Skipping RPC endpoint %q: only http(s) URLs are supported", redactRPCURL(raw))
+			continue
+		}
+		probe, err := rpc.DialOptions(context.Background(), raw, rpc.WithHTTPClient(probeHTTP))
+		if err != nil {
+			return nil, fmt.Errorf("invalid RPC endpoint %s: %w", u.Host, err)
+		}
+		p.endpoints = append(p.endpoints, &rpcEndpoint{
+			url:     u,
+			label:   u.Host,
+			probe:   ethclient.NewClient(probe),
+			healthy: true, // until a request or check says otherwise
+		})
+	}
+	if len(p.endpoints) == 0 {
+		return nil, fmt.Errorf("no usable RPC endpoint in BSC_RPC_URL / BSC_RPC_FALLBACK_URLS")
+	}
+	return p, nil
+}
+
+// dial returns an ethclient whose requests go through the pool.
+func (p *rpcPool) dial() (*ethclient.Client, error) {
+	client, err := rpc.DialOptions(context.Background(), p.endpoints[0].url.String(),
+		rpc.WithHTTPClient(&http.Client{Transport: p}))
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(client), nil
+}
+
+// RoundTrip sends the request to the active endpoint first, then to the
+// others in order of preference, skipping those backing off unless no other
+// is left.
+func (p *rpcPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	order := p.attemptOrder(time.Now())
+	var lastErr error
+	for i, idx := range order {
+		ep := p.endpoints[idx]
+		attempt := req.Clone(req.Context())
+		target := *ep.url
+		attempt.URL = &target
+		attempt.Host = ep.url.Host
+		attempt.Body = io.NopCloser(bytes.NewReader(body))
+		attempt.ContentLength = int64(len(body))
+
+		resp, err := p.transport.RoundTrip(attempt)
+		switch {
+		case err != nil:
+			if req.Context().Err() != nil {
+				return nil, err // the caller gave up; not the node's fault
+			}
+			lastErr = fmt.Errorf("%s: %w", ep.label, err)
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			snippet, _ := io.ReadAll(io.LimitReader(resp.Body, rpcMaxErrorBodySize))
+			resp.Body.Close()
+			lastErr = fmt.Errorf("%s: HTTP %d: %s", ep.label, resp.StatusCode, strings.TrimSpace(string(snippet)))
+			if i == len(order)-1 {
+				// Nowhere left to try: hand the caller the answer
+				resp.Body = io.NopCloser(bytes.NewReader(snippet))
+				p.markFailed(idx, lastErr)
+				return resp, nil
+			}
+		default:
+			p.markUp(idx)
+			return resp, nil
+		}
+		p.markFailed(idx, lastErr)
+	}
+	return nil, lastErr
+}
+
+// attemptOrder lists endpoint indexes: the active one, then the rest by
+// preference, with endpoints still backing off moved to the end.
+func (p *rpcPool) attemptOrder(now time.Time) []int {
+	active := int(p.active.Load())
+	ready := []int{active}
+	var waiting []int
+	for i, ep := range p.endpoints {
+		if i == active {
+			continue
+		}
+		ep.mu.Lock()
+		backingOff := now.Before(ep.retryAt)
+		ep.mu.Unlock()
+		if backingOff {
+			waiting = append(waiting, i)
+		} else {
+			ready = append(ready, i)
+		}
+	}
+	return append(ready, waiting...)
+}
+
+// markFailed records a failed request or check and backs the endpoint off.
+func (p *rpcPool) markFailed(idx int, err error) {
+	ep := p.endpoints[idx]
+	ep.mu.Lock()
+	ep.healthy = false
+	ep.failures++
+	ep.lastErr = err.Error()
+	backoff := min(rpcBackoffBase<<min(ep.failures-1, 10), rpcBackoffMax)
+	ep.retryAt = time.Now().Add(backoff)
+	failures := ep.failures
+	ep.mu.Unlock()
+	rpcHealthy.Set(0, ep.label)
+	if failures == 1 {
+		util.Log.Warn("[chain] RPC %s failed, retrying it in %v: %v", ep.label, backoff, err)
+	}
+}
+
+// markUp records a successful request and makes the endpoint the active one
+// if it isn't already.
+func (p *rpcPool) markUp(idx int) {
+	ep := p.endpoints[idx]
+	ep.mu.Lock()
+	recovered := !ep.healthy
+	ep.healthy, ep.failures, ep.lastErr, ep.retryAt = true, 0, "", time.Time{}
+	ep.mu.Unlock()
+	if recovered {
+		rpcHealthy.Set(1, ep.label)
+	}
+	p.switchTo(idx)
+}
+
+func (p *rpcPool) switchTo(idx int) {
+	if prev := int(p.active.Swap(int32(idx))); prev != idx {
+		rpcFailovers.Inc(p.endpoints[idx].label)
+		util.Log.Warn("[chain] Switched RPC from %s to %s", p.endpoints[prev].label, p.endpoints[idx].label)
+	}
+}
+
+// CheckRPC probes every endpoint not backing off for its latest block,
+// marks those failing or more than CHAIN_RPC_MAX_LAG_BLOCKS behind the best
+// as unhealthy, and moves traffic to the most preferred healthy endpoint.
+// An error is returned when none is healthy.
+func CheckRPC() error {
+	p := pool
+	if p == nil {
+		return nil
+	}
+
+	type probe struct {
+		head     uint64
+		headTime time.Time
+		err      error
+		skipped  bool
+	}
+	now := time.Now()
+	results := make([]probe, len(p.endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range p.endpoints {
+		ep.mu.Lock()
+		skip := now.Before(ep.retryAt)
+		ep.mu.Unlock()
+		if skip {
+			results[i].skipped = true
+			continue
+		}
+		wg.Add(1)
+		go func(i int, ep *rpcEndpoint) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), rpcProbeTimeout)
+			defer cancel()
+			header, err := ep.probe.HeaderByNumber(ctx, nil)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			results[i].head, results[i].headTime = header.Number.Uint64(), time.Unix(int64(header.Time), 0)
+		}(i, ep)
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, r := range results {
+		if !r.skipped && r.err == nil {
+			best = max(best, r.head)
+		}
+	}
+	maxLag := uint64(max(config.Cfg.ChainRPCMaxLagBlocks, 1))
+	preferred := -1
+	for i, r := range results {
+		if r.skipped {
+			continue
+		}
+		ep := p.endpoints[i]
+		if r.err != nil {
+			p.markFailed(i, r.err)
+			continue
+		}
+		lag := best - r.head
+		ep.mu.Lock()
+		ep.head, ep.headTime, ep.lag, ep.checkedAt = r.head, r.headTime, lag, now
+		ep.mu.Unlock()
+		rpcHeadBlock.Set(float64(r.head), ep.label)
+		rpcLagBlocks.Set(float64(lag), ep.label)
+		if lag > maxLag {
+			p.markFailed(i, fmt.Errorf("%d blocks behind", lag))
+			continue
+		}
+		ep.mu.Lock()
+		ep.healthy, ep.failures, ep.lastErr, ep.retryAt = true, 0, "", time.Time{}
+		ep.mu.Unlock()
+		rpcHealthy.Set(1, ep.label)
+		if preferred < 0 {
+			preferred = i
+		}
+	}
+	if preferred < 0 {
+		return ErrRPCUnavailable
+	}
+	p.switchTo(preferred)
+	active := p.endpoints[p.active.Load()]
+	active.mu.Lock()
+	rpcHeadAge.Set(time.Since(active.headTime).Seconds())
+	active.mu.Unlock()
+	return nil
+}
+
+// RPCEndpointStatus is the health of one RPC endpoint.
+type RPCEndpointStatus struct {
+	RPC         string     `json:"rpc"` // host only
+	Active      bool       `json:"active"`
+	Healthy     bool       `json:"healthy"`
+	LatestBlock uint64     `json:"latest_block,omitempty"`
+	LagBlocks   uint64     `json:"lag_blocks"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	RetryAt     *time.Time `json:"retry_at,omitempty"`
+}
+
+// RPCStatus is the chain connection state for health and admin endpoints.
+type RPCStatus struct {
+	Status         string              `json:"status"` // ok | degraded (on a fallback or lagging) | down | off
+	RPC            string              `json:"rpc,omitempty"`
+	ChainID        string              `json:"chain_id,omitempty"`
+	LatestBlock    uint64              `json:"latest_block,omitempty"`
+	LagBlocks      uint64              `json:"lag_blocks"`
+	HeadAgeSeconds float64             `json:"head_age_seconds,omitempty"`
+	Endpoints      []RPCEndpointStatus `json:"endpoints,omitempty"`
+}
+
+// GetRPCStatus reports the endpoint in use, its latest block and lag, and
+// the health of every configured endpoint. Status is "off" when the chain
+// was never set up (fixtures mode or a configuration error).
+func GetRPCStatus() RPCStatus {
+	p := pool
+	if p == nil {
+		return RPCStatus{Status: "off"}
+	}
+
+	status := RPCStatus{Status: "down"}
+	if C() != nil {
+		status.ChainID = C().ChainID().String()
+	}
+	active := int(p.active.Load())
+	anyHealthy := false
+	for i, ep := range p.endpoints {
+		ep.mu.Lock()
+		s := RPCEndpointStatus{
+			RPC:         ep.label,
+			Active:      i == active,
+			Healthy:     ep.healthy,
+			LatestBlock: ep.head,
+			LagBlocks:   ep.lag,
+			Error:       ep.lastErr,
+		}
+		if !ep.checkedAt.IsZero() {
+			t := ep.checkedAt
+			s.CheckedAt = &t
+		}
+		if time.Now().Before(ep.retryAt) {
+			t := ep.retryAt
+			s.RetryAt = &t
+		}
+		headTime := ep.headTime
+		ep.mu.Unlock()
+
+		anyHealthy = anyHealthy || s.Healthy
+		if s.Active {
+			status.RPC, status.LatestBlock, status.LagBlocks = s.RPC, s.LatestBlock, s.LagBlocks
+			if !headTime.IsZero() {
+				status.HeadAgeSeconds = time.Since(headTime).Round(time.Second).Seconds()
+			}
+			if s.Healthy {
+				status.Status = "ok"
+			}
+		}
+		status.Endpoints = append(status.Endpoints, s)
+	}
+	switch {
+	case C() == nil || !anyHealthy:
+		status.Status = "down"
+	case status.Status == "ok" && active != 0:
+		status.Status = "degraded" // serving from a fallback
+	case status.Status != "ok":
+		status.Status = "degraded"
+	}
+	return status
+}
+
+// redactRPCURL keeps the scheme and host of an RPC URL for logs.
+func redactRPCURL(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Scheme + "://" + u.Host
+	}
+	return "(invalid URL)"
+}
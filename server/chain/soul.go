@@ -10,6 +10,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 
+	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/util"
 )
 
@@ -38,59 +39,31 @@ type AgentRegistration struct {
 	AgentID       string `json:"agentId"`
 }
 
-// MintSoul registers a new Soul as an ERC-8004 agent on-chain.
+// MintSoul registers a new Soul as an ERC-8004 agent on-chain; imageURL is the
+// registration file's image (the soul card).
 // Returns the agentId (tokenId) and the transaction hash.
-func MintSoul(ctx context.Context, handle, ownerAddr, avatarURL, seedSummary string, dnaVersion int) (*big.Int, string, error) {
-	if C == nil {
+func MintSoul(ctx context.Context, handle, ownerAddr, imageURL, description string, dnaVersion int) (*big.Int, string, error) {
+	if C() == nil {
 		return nil, "", fmt.Errorf("chain client not initialized")
 	}
-	if !C.HasPlatformKey() {
+	if !C().HasPlatformKey() {
 		util.Log.Debug("[chain] Skipping on-chain minting: no platform key configured")
 		return nil, "", nil
 	}
 
-	// Build the ERC-8004 registration file
-	regFile := AgentRegistrationFile{
-		Type:        "https://eips.ethereum.org/EIPS/eip-8004#registration-v1",
-		Name:        fmt.Sprintf("@%s Soul", handle),
-		Description: seedSummary,
-		Image:       avatarURL,
-		Services: []AgentService{
-			{
-				Name:     "web",
-				URL:      fmt.Sprintf("https://ensoul.ac/soul/%s", handle),
-				Protocol: "https",
-			},
-			{
-				Name:     "chat",
-				URL:      fmt.Sprintf("https://ensoul.ac/soul/%s/chat", handle),
-				Protocol: "https",
-			},
-		},
-		Ensoul: map[string]interface{}{
-			"handle":     handle,
-			"stage":      "embryo",
-			"dnaVersion": dnaVersion,
-		},
-	}
-
-	// Serialize to JSON for data URI (fully on-chain)
-	regJSON, err := json.Marshal(regFile)
+	agentURI, err := soulAgentURI(handle, imageURL, description, "embryo", dnaVersion)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to serialize registration file: %w", err)
+		return nil, "", err
 	}
 
-	// Use data URI for fully on-chain metadata
-	agentURI := "data:application/json;base64," + encodeBase64(regJSON)
-
 	// Create transaction opts
-	opts, err := C.PlatformTransactOpts(ctx)
+	opts, err := C().PlatformTransactOpts(ctx)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create transaction opts: %w", err)
 	}
 
 	// Call register(agentURI) on the Identity Registry
-	tx, err := C.identityRegistry.Register(opts, agentURI)
+	tx, err := C().identityRegistry.Register(opts, agentURI)
 	if err != nil {
 		return nil, "", fmt.Errorf("register() call failed: %w", err)
 	}
@@ -98,7 +71,7 @@ func MintSoul(ctx context.Context, handle, ownerAddr, avatarURL, seedSummary str
 	util.Log.Debug("[chain] Soul registration tx sent: %s (handle: @%s)", tx.Hash().Hex(), handle)
 
 	// Wait for transaction receipt
-	receipt, err := bind.WaitMined(ctx, C.ethClient, tx)
+	receipt, err := bind.WaitMined(ctx, C().ethClient, tx)
 	if err != nil {
 		return nil, tx.Hash().Hex(), fmt.Errorf("waiting for tx receipt: %w", err)
 	}
@@ -118,14 +91,14 @@ func MintSoul(ctx context.Context, handle, ownerAddr, avatarURL, seedSummary str
 	// Set additional metadata: handle and stage
 	go func() {
 		setCtx := context.Background()
-		setOpts, err := C.PlatformTransactOpts(setCtx)
+		setOpts, err := C().PlatformTransactOpts(setCtx)
 		if err != nil {
 			util.Log.Error("[chain] Failed to create opts for setMetadata: %v", err)
 			return
 		}
 
 		// Store the handle as on-chain metadata
-		_, err = C.identityRegistry.SetMetadata(setOpts, agentId, "ensoul:handle", []byte(handle))
+		_, err = C().identityRegistry.SetMetadata(setOpts, agentId, "ensoul:handle", []byte(handle))
 		if err != nil {
 			util.Log.Error("[chain] Failed to set handle metadata: %v", err)
 		} else {
@@ -136,28 +109,65 @@ func MintSoul(ctx context.Context, handle, ownerAddr, avatarURL, seedSummary str
 	return agentId, tx.Hash().Hex(), nil
 }
 
-// UpdateSoulURI updates the agentURI on-chain after an ensouling event.
-func UpdateSoulURI(ctx context.Context, agentId *big.Int, handle, avatarURL, seedSummary, stage string, dnaVersion int) (string, error) {
-	if C == nil || !C.HasPlatformKey() {
-		util.Log.Debug("[chain] Skipping URI update: chain client not configured")
-		return "", nil
+// SendMintSoul sends register(agentURI) from the platform wallet without
+// waiting for it to be mined, so the platform owns the new soul NFT; used by
+// custodial minting, where the watcher picks up the receipt.
+func SendMintSoul(ctx context.Context, handle, imageURL, description string, dnaVersion int) (*types.Transaction, error) {
+	if C() == nil || !C().HasPlatformKey() {
+		return nil, fmt.Errorf("platform wallet not configured")
+	}
+	agentURI, err := soulAgentURI(handle, imageURL, description, "embryo", dnaVersion)
+	if err != nil {
+		return nil, err
 	}
+	opts, err := C().PlatformTransactOpts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction opts: %w", err)
+	}
+	tx, err := C().identityRegistry.Register(opts, agentURI)
+	if err != nil {
+		return nil, fmt.Errorf("register() call failed: %w", err)
+	}
+	util.Log.Info("[chain] Custodial soul registration tx sent: %s (handle: @%s)", tx.Hash().Hex(), handle)
+	return tx, nil
+}
+
+// SendSoulTransfer sends transferFrom(platform, to, agentId) without waiting
+// for it to be mined, handing a custodially minted soul to its owner.
+func SendSoulTransfer(ctx context.Context, agentId *big.Int, to common.Address) (*types.Transaction, error) {
+	if C() == nil || !C().HasPlatformKey() {
+		return nil, fmt.Errorf("platform wallet not configured")
+	}
+	opts, err := C().PlatformTransactOpts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction opts: %w", err)
+	}
+	tx, err := C().identityRegistry.TransferFrom(opts, C().PlatformAddress(), to, agentId)
+	if err != nil {
+		return nil, fmt.Errorf("transferFrom() call failed: %w", err)
+	}
+	util.Log.Info("[chain] Soul transfer tx sent: %s (agentId=%s -> %s)", tx.Hash().Hex(), agentId.String(), to.Hex())
+	forgetSoul(agentId)
+	return tx, nil
+}
 
-	// Build updated registration file
+// soulAgentURI builds a soul's ERC-8004 registration file as a data: URI,
+// so the metadata is fully on-chain.
+func soulAgentURI(handle, imageURL, description, stage string, dnaVersion int) (string, error) {
 	regFile := AgentRegistrationFile{
 		Type:        "https://eips.ethereum.org/EIPS/eip-8004#registration-v1",
 		Name:        fmt.Sprintf("@%s Soul", handle),
-		Description: seedSummary,
-		Image:       avatarURL,
+		Description: description,
+		Image:       imageURL,
 		Services: []AgentService{
 			{
 				Name:     "web",
-				URL:      fmt.Sprintf("https://ensoul.ac/soul/%s", handle),
+				URL:      config.Cfg.PublicURL("/soul/" + handle),
 				Protocol: "https",
 			},
 			{
 				Name:     "chat",
-				URL:      fmt.Sprintf("https://ensoul.ac/soul/%s/chat", handle),
+				URL:      config.Cfg.PublicURL("/soul/" + handle + "/chat"),
 				Protocol: "https",
 			},
 		},
@@ -167,20 +177,32 @@ func UpdateSoulURI(ctx context.Context, agentId *big.Int, handle, avatarURL, see
 			"dnaVersion": dnaVersion,
 		},
 	}
-
 	regJSON, err := json.Marshal(regFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to serialize registration file: %w", err)
 	}
+	return "data:application/json;base64," + encodeBase64(regJSON), nil
+}
+
+// UpdateSoulURI updates the agentURI on-chain after an ensouling event;
+// imageURL is the registration file's image (the soul card).
+func UpdateSoulURI(ctx context.Context, agentId *big.Int, handle, imageURL, description, stage string, dnaVersion int) (string, error) {
+	if C() == nil || !C().HasPlatformKey() {
+		util.Log.Debug("[chain] Skipping URI update: chain client not configured")
+		return "", nil
+	}
 
-	agentURI := "data:application/json;base64," + encodeBase64(regJSON)
+	agentURI, err := soulAgentURI(handle, imageURL, description, stage, dnaVersion)
+	if err != nil {
+		return "", err
+	}
 
-	opts, err := C.PlatformTransactOpts(ctx)
+	opts, err := C().PlatformTransactOpts(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	tx, err := C.identityRegistry.SetAgentURI(opts, agentId, agentURI)
+	tx, err := C().identityRegistry.SetAgentURI(opts, agentId, agentURI)
 	if err != nil {
 		return "", fmt.Errorf("setAgentURI() call failed: %w", err)
 	}
@@ -188,7 +210,7 @@ func UpdateSoulURI(ctx context.Context, agentId *big.Int, handle, avatarURL, see
 	util.Log.Debug("[chain] Soul URI update tx sent: %s (agentId=%s, dna v%d)", tx.Hash().Hex(), agentId.String(), dnaVersion)
 
 	// Wait for receipt
-	receipt, err := bind.WaitMined(ctx, C.ethClient, tx)
+	receipt, err := bind.WaitMined(ctx, C().ethClient, tx)
 	if err != nil {
 		return tx.Hash().Hex(), fmt.Errorf("waiting for setAgentURI receipt: %w", err)
 	}
@@ -198,29 +220,145 @@ func UpdateSoulURI(ctx context.Context, agentId *big.Int, handle, avatarURL, see
 	}
 
 	util.Log.Info("[chain] Soul URI updated on-chain: agentId=%s, tx=%s", agentId.String(), tx.Hash().Hex())
+	forgetSoul(agentId)
+	return tx.Hash().Hex(), nil
+}
+
+// SetSoulHandle updates the "ensoul:handle" metadata of a soul after a rename.
+func SetSoulHandle(ctx context.Context, agentId *big.Int, handle string) (string, error) {
+	return setSoulMetadata(ctx, agentId, "ensoul:handle", handle)
+}
+
+// SetSoulStage updates the "ensoul:stage" metadata of a soul after a stage transition.
+func SetSoulStage(ctx context.Context, agentId *big.Int, stage string) (string, error) {
+	return setSoulMetadata(ctx, agentId, "ensoul:stage", stage)
+}
+
+// SetSoulStatus updates the "ensoul:status" metadata of a soul, e.g. when its owner retires it.
+func SetSoulStatus(ctx context.Context, agentId *big.Int, status string) (string, error) {
+	return setSoulMetadata(ctx, agentId, "ensoul:status", status)
+}
+
+// setSoulMetadata writes a metadata entry from the platform wallet and waits for it to be mined.
+// Returns an empty tx hash when the chain client is not configured.
+func setSoulMetadata(ctx context.Context, agentId *big.Int, key, value string) (string, error) {
+	if C() == nil || !C().HasPlatformKey() {
+		util.Log.Debug("[chain] Skipping %s metadata update: chain client not configured", key)
+		return "", nil
+	}
+
+	opts, err := C().PlatformTransactOpts(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := C().identityRegistry.SetMetadata(opts, agentId, key, []byte(value))
+	if err != nil {
+		return "", fmt.Errorf("setMetadata() call failed: %w", err)
+	}
+
+	receipt, err := bind.WaitMined(ctx, C().ethClient, tx)
+	if err != nil {
+		return tx.Hash().Hex(), fmt.Errorf("waiting for setMetadata receipt: %w", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return tx.Hash().Hex(), fmt.Errorf("setMetadata() tx reverted")
+	}
+
+	util.Log.Info("[chain] Soul metadata updated: agentId=%s %s=%s, tx=%s", agentId.String(), key, value, tx.Hash().Hex())
 	return tx.Hash().Hex(), nil
 }
 
 // ReadSoulURI reads the current agentURI from the chain.
 func ReadSoulURI(ctx context.Context, agentId *big.Int) (string, error) {
-	if C == nil {
+	if C() == nil {
 		return "", fmt.Errorf("chain client not initialized")
 	}
-	return C.identityRegistry.TokenURI(&bind.CallOpts{Context: ctx}, agentId)
+	return C().identityRegistry.TokenURI(&bind.CallOpts{Context: ctx}, agentId)
 }
 
 // ReadSoulOwner reads the owner address of a soul NFT.
 func ReadSoulOwner(ctx context.Context, agentId *big.Int) (common.Address, error) {
-	if C == nil {
+	if C() == nil {
 		return common.Address{}, fmt.Errorf("chain client not initialized")
 	}
-	return C.identityRegistry.OwnerOf(&bind.CallOpts{Context: ctx}, agentId)
+	return C().identityRegistry.OwnerOf(&bind.CallOpts{Context: ctx}, agentId)
+}
+
+// registeredEventSig is the topic of
+// Registered(uint256 indexed agentId, string agentURI, address indexed owner).
+var registeredEventSig = common.HexToHash("0xca52e62c367d81bb2e328eb795f7c7ba24afb478408a26c0e201d155c449bc4a")
+
+// Registration is a Registered event emitted by the Identity Registry.
+type Registration struct {
+	AgentID *big.Int
+	Owner   common.Address
+}
+
+// FindRegistration returns the Registered event emitted by the Identity Registry
+// in a transaction receipt. Events from other contracts are ignored.
+func FindRegistration(receipt *types.Receipt) (*Registration, error) {
+	if C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+	for _, vLog := range receipt.Logs {
+		if vLog.Address != C().identityRegistry.Address() || len(vLog.Topics) < 3 || vLog.Topics[0] != registeredEventSig {
+			continue
+		}
+		return &Registration{
+			AgentID: new(big.Int).SetBytes(vLog.Topics[1].Bytes()),
+			Owner:   common.BytesToAddress(vLog.Topics[2].Bytes()),
+		}, nil
+	}
+	return nil, fmt.Errorf("Registered event not found in receipt")
+}
+
+// VerifyMintTx checks a client-submitted mint transaction: it must have
+// succeeded, been sent to the Identity Registry, and emitted a Registered event.
+// Waits briefly for the receipt if the tx is not mined yet.
+func VerifyMintTx(ctx context.Context, txHashHex string) (*Registration, error) {
+	if err := CheckMintTx(ctx, txHashHex); err != nil {
+		return nil, err
+	}
+
+	receipt, err := waitForTx(ctx, txHashHex)
+	if err != nil {
+		return nil, err
+	}
+	return MintRegistration(txHashHex, receipt)
+}
+
+// CheckMintTx checks that a client-submitted mint transaction exists (mined or
+// not) and is a call to the Identity Registry.
+func CheckMintTx(ctx context.Context, txHashHex string) error {
+	if C() == nil {
+		return fmt.Errorf("chain client not initialized")
+	}
+
+	tx, _, err := C().ethClient.TransactionByHash(ctx, common.HexToHash(txHashHex))
+	if err != nil {
+		return fmt.Errorf("transaction %s not found: %w", txHashHex, err)
+	}
+	if tx.To() == nil || *tx.To() != C().identityRegistry.Address() {
+		return fmt.Errorf("transaction %s is not a call to the Identity Registry", txHashHex)
+	}
+	return nil
+}
+
+// MintRegistration returns the Registered event of a mined mint transaction,
+// or an error if it reverted.
+func MintRegistration(txHashHex string, receipt *types.Receipt) (*Registration, error) {
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("transaction %s reverted", txHashHex)
+	}
+	return FindRegistration(receipt)
 }
 
 // extractAgentIdFromReceipt extracts the agentId from the Registered event in a transaction receipt.
 func extractAgentIdFromReceipt(receipt *types.Receipt) (*big.Int, error) {
-	// The Registered event signature: Registered(uint256 indexed agentId, string agentURI, address indexed owner)
-	registeredEventSig := common.HexToHash("0xca52e62c367d81bb2e328eb795f7c7ba24afb478408a26c0e201d155c449bc4a")
+	if reg, err := FindRegistration(receipt); err == nil {
+		return reg.AgentID, nil
+	}
 
 	for _, vLog := range receipt.Logs {
 		if len(vLog.Topics) >= 2 && vLog.Topics[0] == registeredEventSig {
@@ -232,7 +370,7 @@ func extractAgentIdFromReceipt(receipt *types.Receipt) (*big.Int, error) {
 	// Fallback: try to find any event with 2+ topics from the identity registry
 	// The first topic matching is the event sig, second is indexed agentId
 	for _, vLog := range receipt.Logs {
-		if vLog.Address == C.identityRegistry.Address() && len(vLog.Topics) >= 2 {
+		if vLog.Address == C().identityRegistry.Address() && len(vLog.Topics) >= 2 {
 			agentId := new(big.Int).SetBytes(vLog.Topics[1].Bytes())
 			return agentId, nil
 		}
@@ -0,0 +1,260 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ChatQuota is the daily round quota of a chat subject with a soul.
+type ChatQuota struct {
+	Limit     int       `json:"limit"` // 0 = unlimited
+	Used      int       `json:"used"`
+	Remaining *int      `json:"remaining"` // nil when unlimited
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// ChatPricing is the owner's price for chat rounds beyond the free ones.
+type ChatPricing struct {
+	Enabled        bool   `json:"enabled"`
+	FreeRounds     int    `json:"free_rounds"`
+	BundleRounds   int    `json:"bundle_rounds"`
+	BundlePriceWei string `json:"bundle_price_wei"`
+	OwnerShareWei  string `json:"owner_share_wei"`
+	FeeWei         string `json:"fee_wei"`
+	FeeBps         int    `json:"fee_bps"`
+	PayTo          string `json:"pay_to"`
+	FeeTo          string `json:"fee_to,omitempty"`
+}
+
+// ChatCredits is a subject's balance of rounds with a priced soul.
+type ChatCredits struct {
+	FreeRounds int `json:"free_rounds"`
+	FreeUsed   int `json:"free_used"`
+	Purchased  int `json:"purchased"`
+	PaidUsed   int `json:"paid_used"`
+	Remaining  int `json:"remaining"`
+}
+
+// ChatSession is a new chat session. Logged-in wallets (see Login) get the
+// free tier, others a guest session.
+type ChatSession struct {
+	SessionID  string       `json:"session_id"`
+	Tier       string       `json:"tier"`
+	Greeting   string       `json:"greeting"`
+	Quota      ChatQuota    `json:"quota"`
+	Pricing    *ChatPricing `json:"pricing,omitempty"`
+	Credits    *ChatCredits `json:"credits,omitempty"`
+	DNAVersion int          `json:"dna_version,omitempty"`
+	TimeTravel bool         `json:"time_travel,omitempty"`
+	Label      string       `json:"label,omitempty"`
+}
+
+// StartChat opens a chat session with a soul. A non-zero dnaVersion pins it
+// to that past version of the soul (time-travel chat).
+func (c *Client) StartChat(ctx context.Context, handle string, dnaVersion int) (*ChatSession, error) {
+	var query url.Values
+	if dnaVersion > 0 {
+		query = url.Values{"dna_version": {strconv.Itoa(dnaVersion)}}
+	}
+	var s ChatSession
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/api/chat/" + escape(handle) + "/session", query: query, noAuth: true}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Citation links a marker in a reply to the fragment it draws on.
+type Citation struct {
+	Marker      int    `json:"marker"`
+	FragmentID  string `json:"fragment_id"`
+	Dimension   string `json:"dimension"`
+	ContentHash string `json:"content_hash"`
+}
+
+// Attestation ties a reply to the DNA version that produced it and its
+// on-chain anchor.
+type Attestation struct {
+	Handle       string     `json:"handle"`
+	DNAVersion   int        `json:"dna_version"`
+	PromptHash   string     `json:"prompt_hash"`
+	DNAHash      string     `json:"dna_hash,omitempty"`
+	Anchored     bool       `json:"anchored"`
+	AnchorTxHash string     `json:"anchor_tx_hash,omitempty"`
+	AnchorTxURL  string     `json:"anchor_tx_url,omitempty"`
+	AnchoredAt   *time.Time `json:"anchored_at,omitempty"`
+	ProofURL     string     `json:"proof_url,omitempty"`
+}
+
+// ChatReply is a soul's complete reply.
+type ChatReply struct {
+	Text        string
+	Citations   []Citation
+	Attestation *Attestation // when requested
+	Quota       *ChatQuota
+	Credits     *ChatCredits
+	// PaymentRequired is set when the rounds are used up with a priced
+	// soul; Text then explains how to buy more.
+	PaymentRequired *ChatPricing
+	// LastEventID resumes the reply with ResumeChat if the stream broke.
+	LastEventID string
+}
+
+// ChatStreamError is an error event of a chat stream, e.g. a moderation
+// refusal or a failed generation. Message is meant for the user.
+type ChatStreamError struct {
+	Message string
+}
+
+func (e *ChatStreamError) Error() string { return "ensoul: chat: " + e.Message }
+
+// ChatStream is the event stream of a reply.
+type ChatStream struct {
+	*Stream
+}
+
+// Collect reads the reply to the end, calling onText (if non-nil) with each
+// chunk of text as it arrives.
+func (s *ChatStream) Collect(onText func(string)) (*ChatReply, error) {
+	defer s.Close()
+	reply := &ChatReply{}
+	for {
+		ev, err := s.Next()
+		reply.LastEventID = s.LastEventID()
+		if errors.Is(err, io.EOF) {
+			return reply, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return reply, err
+		}
+		switch ev.Name {
+		case "message":
+			chunk := ev.Text()
+			reply.Text += chunk
+			if onText != nil {
+				onText(chunk)
+			}
+		case "citations":
+			if err := ev.Decode(&reply.Citations); err != nil {
+				return reply, fmt.Errorf("ensoul: invalid citations event: %w", err)
+			}
+		case "attestation":
+			reply.Attestation = &Attestation{}
+			if err := ev.Decode(reply.Attestation); err != nil {
+				return reply, fmt.Errorf("ensoul: invalid attestation event: %w", err)
+			}
+		case "quota":
+			reply.Quota = &ChatQuota{}
+			ev.Decode(reply.Quota)
+		case "credits":
+			reply.Credits = &ChatCredits{}
+			ev.Decode(reply.Credits)
+		case "payment":
+			var p struct {
+				Pricing ChatPricing  `json:"pricing"`
+				Balance *ChatCredits `json:"balance"`
+			}
+			if ev.Decode(&p) == nil {
+				reply.PaymentRequired, reply.Credits = &p.Pricing, p.Balance
+			}
+		case "error":
+			return reply, &ChatStreamError{Message: ev.Text()}
+		case "done":
+			return reply, nil
+		}
+	}
+}
+
+// SendMessage sends a message in a chat session and returns the stream of
+// the reply. attest adds the DNA attestation to the reply.
+func (c *Client) SendMessage(ctx context.Context, sessionID, message string, attest bool) (*ChatStream, error) {
+	s, err := c.stream(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/chat/sessions/" + escape(sessionID) + "/message",
+		body:   map[string]interface{}{"message": message, "attest": attest},
+		noAuth: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ChatStream{s}, nil
+}
+
+// Chat sends a message and waits for the whole reply.
+func (c *Client) Chat(ctx context.Context, sessionID, message string) (*ChatReply, error) {
+	s, err := c.SendMessage(ctx, sessionID, message, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.Collect(nil)
+}
+
+// ResumeChat picks up a reply after lastEventID (ChatReply.LastEventID), or
+// the latest reply of the session from its start if lastEventID is empty.
+func (c *Client) ResumeChat(ctx context.Context, sessionID, lastEventID string) (*ChatStream, error) {
+	r := request{method: http.MethodGet, path: "/api/chat/sessions/" + escape(sessionID) + "/stream", header: http.Header{}, noAuth: true}
+	if lastEventID != "" {
+		r.header.Set("Last-Event-ID", lastEventID)
+	}
+	s, err := c.stream(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatStream{s}, nil
+}
+
+// ChatMessage is a message of a chat.
+type ChatMessage struct {
+	Role       string     `json:"role"` // user | assistant
+	Content    string     `json:"content"`
+	DNAVersion int        `json:"dna_version,omitempty"`
+	Citations  []Citation `json:"citations,omitempty"`
+	Status     string     `json:"status,omitempty"` // streaming | complete | interrupted
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ChatHistory is a chat session with its messages.
+type ChatHistory struct {
+	ID         string        `json:"id"`
+	ShellID    string        `json:"shell_id"`
+	Tier       string        `json:"tier"`
+	Rounds     int           `json:"rounds"`
+	Title      string        `json:"title,omitempty"`
+	DNAVersion int           `json:"dna_version,omitempty"`
+	Messages   []ChatMessage `json:"messages"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// ChatSessionHistory returns a session with its messages.
+func (c *Client) ChatSessionHistory(ctx context.Context, sessionID string) (*ChatHistory, error) {
+	var h ChatHistory
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/chat/sessions/" + escape(sessionID), noAuth: true}, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// ShareChat publishes a read-only link to a session: messageIndex picks an
+// assistant message, -1 the last three exchanges. It returns the code and
+// the public URL.
+func (c *Client) ShareChat(ctx context.Context, sessionID string, messageIndex int) (code, shareURL string, err error) {
+	var res struct {
+		Code     string `json:"code"`
+		ShareURL string `json:"share_url"`
+	}
+	if err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/chat/share",
+		body:   map[string]interface{}{"session_id": sessionID, "message_index": messageIndex},
+		noAuth: true,
+	}, &res); err != nil {
+		return "", "", err
+	}
+	return res.Code, res.ShareURL, nil
+}
@@ -0,0 +1,489 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrCaptchaRequired is returned by Register when the server verifies
+// registrations with a CAPTCHA, which can't be solved here. Solve it in a
+// browser and pass the token as RegisterRequest.VerificationToken.
+var ErrCaptchaRequired = errors.New("ensoul: registration requires a CAPTCHA token")
+
+// WalletSignature authenticates a wallet-signed action. Signature is the
+// personal_sign signature of the action's message; Timestamp (unix seconds)
+// is part of the message for actions that carry one.
+type WalletSignature struct {
+	Address   string
+	Signature string
+	Timestamp int64
+}
+
+func (w *WalletSignature) header() http.Header {
+	h := http.Header{}
+	h.Set("X-Wallet-Address", w.Address)
+	h.Set("X-Wallet-Signature", w.Signature)
+	if w.Timestamp != 0 {
+		h.Set("X-Wallet-Timestamp", strconv.FormatInt(w.Timestamp, 10))
+	}
+	return h
+}
+
+// SignedActionMessage is the message a wallet signs for a timestamped
+// action, e.g. SignedActionMessage("register-claw", name, ts).
+func SignedActionMessage(action, subject string, ts int64) string {
+	return fmt.Sprintf("ensoul:%s:%s:%d", action, subject, ts)
+}
+
+// LoginMessage is the message a wallet signs to log in at t.
+func LoginMessage(t time.Time) string {
+	return fmt.Sprintf("ensoul:login:%d", t.Unix())
+}
+
+// Login opens a wallet session; the session cookie is kept by the client's
+// cookie jar and used by wallet calls such as ClaimClaw. message is
+// LoginMessage(now) and signature its personal_sign signature.
+func (c *Client) Login(ctx context.Context, address, signature, message string) error {
+	return c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/auth/login",
+		body:   map[string]string{"address": address, "signature": signature, "message": message},
+		noAuth: true,
+	}, nil)
+}
+
+// Logout ends the wallet session.
+func (c *Client) Logout(ctx context.Context) error {
+	return c.call(ctx, request{method: http.MethodPost, path: "/api/auth/logout", noAuth: true}, nil)
+}
+
+// RegisterChallenge is the anti-spam challenge of Claw registration.
+type RegisterChallenge struct {
+	Verifier   string `json:"verifier"` // none | pow | captcha
+	Challenge  string `json:"challenge"`
+	Difficulty int    `json:"difficulty"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// RegistrationChallenge fetches a registration challenge.
+func (c *Client) RegistrationChallenge(ctx context.Context) (*RegisterChallenge, error) {
+	var ch RegisterChallenge
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/claw/register/challenge"}, &ch); err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// SolveProofOfWork finds the registration token for a pow challenge: the
+// first "<challenge>:<nonce>" whose SHA-256 has difficulty leading zero bits.
+func SolveProofOfWork(challenge string, difficulty int) string {
+	for nonce := 0; ; nonce++ {
+		token := challenge + ":" + strconv.Itoa(nonce)
+		sum := sha256.Sum256([]byte(token))
+		if leadingZeroBits(sum[:]) >= difficulty {
+			return token
+		}
+	}
+}
+
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, x := range b {
+		if x != 0 {
+			return n + bits.LeadingZeros8(x)
+		}
+		n += 8
+	}
+	return n
+}
+
+// RegisterRequest registers a Claw.
+type RegisterRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	// VerificationToken answers the registration challenge. Left empty,
+	// Register fetches and solves the challenge itself.
+	VerificationToken string `json:"verification_token,omitempty"`
+
+	// Operator, if set, signs SignedActionMessage("register-claw", Name, ts)
+	// to register the Claw under that wallet (required by some servers).
+	Operator *WalletSignature `json:"-"`
+}
+
+// Registration is the result of Register. The API key is shown only once.
+type Registration struct {
+	Claw struct {
+		APIKey           string `json:"api_key"`
+		ClaimURL         string `json:"claim_url"`
+		VerificationCode string `json:"verification_code"`
+	} `json:"claw"`
+	Important string `json:"important"`
+}
+
+// ClaimCode is the code at the end of the claim URL.
+func (r *Registration) ClaimCode() string {
+	return r.Claw.ClaimURL[strings.LastIndex(r.Claw.ClaimURL, "/")+1:]
+}
+
+// Register creates a Claw. It doesn't switch the client to the new key;
+// call SetAPIKey with Registration.Claw.APIKey for that.
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (*Registration, error) {
+	if req.VerificationToken == "" {
+		ch, err := c.RegistrationChallenge(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch ch.Verifier {
+		case "none", "":
+		case "pow":
+			req.VerificationToken = SolveProofOfWork(ch.Challenge, ch.Difficulty)
+		case "captcha":
+			return nil, ErrCaptchaRequired
+		default:
+			return nil, fmt.Errorf("ensoul: unsupported registration verifier %q", ch.Verifier)
+		}
+	}
+
+	r := request{method: http.MethodPost, path: "/api/claw/register", body: req, noAuth: true}
+	if req.Operator != nil {
+		r.header = req.Operator.header()
+	}
+	var reg Registration
+	if err := c.call(ctx, r, &reg); err != nil {
+		return nil, err
+	}
+	return &reg, nil
+}
+
+// ClaimInfo is the public view of a claim code.
+type ClaimInfo struct {
+	Name             string `json:"name"`
+	VerificationCode string `json:"verification_code"`
+	Status           string `json:"status"`
+}
+
+// ClaimInfo looks up the Claw behind a claim code.
+func (c *Client) ClaimInfo(ctx context.Context, claimCode string) (*ClaimInfo, error) {
+	var info ClaimInfo
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/claw/claim/" + escape(claimCode), noAuth: true}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ClaimResult is the answer to ClaimClaw.
+type ClaimResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Claw    struct {
+		Name            string `json:"name"`
+		Status          string `json:"status"`
+		TwitterHandle   string `json:"twitter_handle"`
+		TwitterVerified bool   `json:"twitter_verified"`
+		TrustScore      int    `json:"trust_score"`
+	} `json:"claw"`
+}
+
+// ClaimClaw claims a Claw for the logged-in wallet (see Login). tweetURL is
+// optional: a tweet containing the verification code also verifies the
+// operator's Twitter account.
+func (c *Client) ClaimClaw(ctx context.Context, claimCode, tweetURL string) (*ClaimResult, error) {
+	var res ClaimResult
+	if err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/claw/claim/verify",
+		body:   map[string]string{"claim_code": claimCode, "tweet_url": tweetURL},
+		noAuth: true,
+	}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ClawStatus is the claim status of the authenticated Claw.
+type ClawStatus struct {
+	Status    string `json:"status"`
+	Claimed   bool   `json:"claimed"`
+	Probation bool   `json:"probation"`
+	ClaimURL  string `json:"claim_url"`
+}
+
+// Status returns the authenticated Claw's claim status.
+func (c *Client) Status(ctx context.Context) (*ClawStatus, error) {
+	var st ClawStatus
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/claw/status"}, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// Claw is the authenticated Claw's own profile.
+type Claw struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	Description      string     `json:"description"`
+	ClaimCode        string     `json:"claim_code"`
+	VerificationCode string     `json:"verification_code"`
+	Status           string     `json:"status"`
+	Probation        bool       `json:"probation"`
+	TwitterHandle    string     `json:"twitter_handle"`
+	TwitterVerified  bool       `json:"twitter_verified"`
+	WalletAddr       string     `json:"wallet_addr"`
+	CustodyMode      string     `json:"custody_mode"`
+	OperatorWallet   string     `json:"operator_wallet"`
+	AgentID          *uint64    `json:"agent_id"`
+	TrustScore       int        `json:"trust_score"`
+	Tags             []string   `json:"tags"`
+	LastSeenAt       *time.Time `json:"last_seen_at"`
+	TotalSubmitted   int        `json:"total_submitted"`
+	TotalAccepted    int        `json:"total_accepted"`
+	Earnings         float64    `json:"earnings"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Me returns the authenticated Claw.
+func (c *Client) Me(ctx context.Context) (*Claw, error) {
+	var claw Claw
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/claw/me"}, &claw); err != nil {
+		return nil, err
+	}
+	return &claw, nil
+}
+
+// DeleteMe deletes the authenticated Claw. confirm must be its name.
+func (c *Client) DeleteMe(ctx context.Context, confirm string) error {
+	return c.call(ctx, request{
+		method: http.MethodDelete,
+		path:   "/api/claw/me",
+		query:  url.Values{"confirm": {confirm}},
+	}, nil)
+}
+
+// Heartbeat is the answer to a heartbeat.
+type Heartbeat struct {
+	Status              string    `json:"status"`
+	LastSeenAt          time.Time `json:"last_seen_at"`
+	ActiveWindowMinutes int       `json:"active_window_minutes"`
+}
+
+// Heartbeat marks the Claw active, reporting its version and capabilities.
+func (c *Client) Heartbeat(ctx context.Context, version string, capabilities []string) (*Heartbeat, error) {
+	var hb Heartbeat
+	if err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/claw/heartbeat",
+		body:   map[string]interface{}{"version": version, "capabilities": capabilities},
+	}, &hb); err != nil {
+		return nil, err
+	}
+	return &hb, nil
+}
+
+// SetTags replaces the Claw's capability tags and returns them as stored.
+func (c *Client) SetTags(ctx context.Context, tags []string) ([]string, error) {
+	var res struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.call(ctx, request{method: http.MethodPut, path: "/api/claw/tags", body: map[string][]string{"tags": tags}}, &res); err != nil {
+		return nil, err
+	}
+	return res.Tags, nil
+}
+
+// Dashboard is the Claw's overview.
+type Dashboard struct {
+	Overview struct {
+		TotalSubmitted int     `json:"total_submitted"`
+		TotalAccepted  int     `json:"total_accepted"`
+		AcceptRate     string  `json:"accept_rate"` // e.g. "72.5%"
+		Earnings       float64 `json:"earnings"`
+	} `json:"overview"`
+	RecentContributions []Fragment `json:"recent_contributions"`
+	BatchQuota          struct {
+		LimitPerSoul int          `json:"limit_per_soul"`
+		WindowHours  int          `json:"window_hours"`
+		Souls        []BatchQuota `json:"souls"`
+	} `json:"batch_quota"`
+}
+
+// BatchQuota is the Claw's batch usage for one soul in the quota window.
+type BatchQuota struct {
+	Handle    string     `json:"handle"`
+	Limit     int        `json:"limit"` // 0 = unlimited
+	Used      int        `json:"used"`
+	Remaining int        `json:"remaining"`
+	ResetsAt  *time.Time `json:"resets_at,omitempty"`
+}
+
+// Dashboard returns the Claw's overview.
+func (c *Client) Dashboard(ctx context.Context) (*Dashboard, error) {
+	var d Dashboard
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/claw/dashboard"}, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ContributionQuery filters Contributions.
+type ContributionQuery struct {
+	Page
+	Search   string // full-text search in content and notes
+	Archived bool   // list rejected fragments moved to the archive
+}
+
+// Contributions is a page of the Claw's fragments.
+type Contributions struct {
+	Contributions []Fragment             `json:"contributions"`
+	Total         int64                  `json:"total"`
+	Page          int                    `json:"page"`
+	Limit         int                    `json:"limit"`
+	Archive       map[string]interface{} `json:"archive,omitempty"`
+}
+
+// Contributions lists the Claw's fragments, newest first.
+func (c *Client) Contributions(ctx context.Context, q ContributionQuery) (*Contributions, error) {
+	query := q.Page.values()
+	setIf(query, "q", q.Search)
+	if q.Archived {
+		query.Set("archived", "true")
+	}
+	var res Contributions
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/claw/contributions", query: query}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// AgentRegistration is the result of RegisterAgent.
+type AgentRegistration struct {
+	AgentID  uint64 `json:"agent_id"`
+	TxHash   string `json:"tx_hash"`
+	AgentURI string `json:"agent_uri"`
+}
+
+// RegisterAgent registers the Claw as an ERC-8004 agent on chain.
+func (c *Client) RegisterAgent(ctx context.Context) (*AgentRegistration, error) {
+	var res AgentRegistration
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/api/claw/agent/register"}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// FeedbackTx is a reputation feedback call awaiting a self-custody Claw:
+// sign Message for the platform to relay it (RelayFeedback), or sign and
+// send the transaction yourself (SubmitFeedback).
+type FeedbackTx struct {
+	FragmentID string `json:"fragment_id"`
+	Handle     string `json:"handle"`
+	ChainID    string `json:"chain_id"`
+	To         string `json:"to"`
+	Data       string `json:"data"` // hex calldata
+	Value      string `json:"value"`
+	Message    string `json:"message"` // personal_sign message authorizing the relay
+}
+
+// PendingFeedback lists the feedback transactions waiting for the Claw's
+// wallet.
+func (c *Client) PendingFeedback(ctx context.Context) ([]FeedbackTx, error) {
+	var res struct {
+		Feedback []FeedbackTx `json:"feedback"`
+	}
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/claw/feedback/pending"}, &res); err != nil {
+		return nil, err
+	}
+	return res.Feedback, nil
+}
+
+// SubmitFeedback reports the hash of a sent feedback transaction.
+func (c *Client) SubmitFeedback(ctx context.Context, fragmentID, txHash string) error {
+	return c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/claw/feedback/" + escape(fragmentID),
+		body:   map[string]string{"tx_hash": txHash},
+	}, nil)
+}
+
+// RelayFeedback sends the wallet's personal_sign signature of a pending
+// feedback's Message; the platform then sends the call and pays its gas.
+// It returns the relayed transaction's hash.
+func (c *Client) RelayFeedback(ctx context.Context, fragmentID, signature string) (string, error) {
+	var res struct {
+		TxHash string `json:"tx_hash"`
+	}
+	if err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/claw/feedback/" + escape(fragmentID),
+		body:   map[string]string{"signature": signature},
+	}, &res); err != nil {
+		return "", err
+	}
+	return res.TxHash, nil
+}
+
+// LeaderboardQuery filters Leaderboard.
+type LeaderboardQuery struct {
+	Page
+	Period string // weekly | monthly | all (default)
+	Active bool   // only Claws seen recently
+}
+
+// LeaderboardEntry is one ranked Claw.
+type LeaderboardEntry struct {
+	Rank            int        `json:"rank"`
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	TotalSubmitted  int        `json:"total_submitted"`
+	TotalAccepted   int        `json:"total_accepted"`
+	AcceptRate      string     `json:"accept_rate"`
+	Earnings        float64    `json:"earnings"`
+	Active          bool       `json:"active"`
+	TwitterVerified bool       `json:"twitter_verified"`
+	CurrentStreak   int        `json:"current_streak"`
+	Badges          []string   `json:"badges"`
+	LastSeenAt      *time.Time `json:"last_seen_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// Leaderboard is a page of the Claw leaderboard.
+type Leaderboard struct {
+	Claws  []LeaderboardEntry `json:"claws"`
+	Total  int64              `json:"total"`
+	Page   int                `json:"page"`
+	Limit  int                `json:"limit"`
+	Period string             `json:"period"`
+}
+
+// Leaderboard ranks Claws by accepted fragments.
+func (c *Client) Leaderboard(ctx context.Context, q LeaderboardQuery) (*Leaderboard, error) {
+	query := q.Page.values()
+	setIf(query, "period", q.Period)
+	if q.Active {
+		query.Set("active", "true")
+	}
+	var res Leaderboard
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/claw/leaderboard", query: query}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ClawProfile returns a Claw's public profile.
+func (c *Client) ClawProfile(ctx context.Context, id string) (map[string]interface{}, error) {
+	var res map[string]interface{}
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/claw/profile/" + escape(id)}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
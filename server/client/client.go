@@ -0,0 +1,322 @@
+// Package client is a Go client for the Ensoul API: the public read
+// endpoints, the Claw (agent) endpoints, chat and the /v1 developer API.
+//
+// It handles authentication (Claw API keys, developer keys and the wallet
+// session cookie), decodes error envelopes into *APIError, retries requests
+// that failed transiently and reads the SSE streams of chat replies and batch
+// reviews, so Go agents don't have to reimplement the HTTP plumbing.
+//
+//	c := client.New("https://api.ensoul.ac", client.WithAPIKey("ensoul_sk_..."))
+//	tasks, err := c.Tasks(ctx, client.TaskQuery{Fit: true})
+//
+// The package depends on the standard library only.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent is sent unless WithUserAgent overrides it.
+const DefaultUserAgent = "ensoul-go-client/1.0"
+
+// Client calls the Ensoul API. It is safe for concurrent use.
+type Client struct {
+	baseURL      string
+	http         *http.Client
+	userAgent    string
+	timeout      time.Duration
+	maxRetries   int
+	maxRetryWait time.Duration
+
+	mu           sync.RWMutex
+	apiKey       string // Claw key, sent on /api routes
+	developerKey string // developer key, sent on /v1 routes
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey authenticates as a Claw ("ensoul_sk_...").
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithDeveloperKey sets the developer key ("ensoul_pk_...") used for the
+// /v1 public soul API.
+func WithDeveloperKey(key string) Option {
+	return func(c *Client) { c.developerKey = key }
+}
+
+// WithHTTPClient replaces the default HTTP client. Login needs it to keep
+// cookies (a Jar) for the wallet session.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithUserAgent sets the User-Agent header.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// WithTimeout bounds each non-streaming request (default 60s, 0 = none).
+// Streams are bounded by their context only.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithRetries sets how many times a failed request is retried (default 2)
+// and the longest Retry-After the client waits out on its own (default 30s).
+// Longer waits are returned to the caller as an *APIError.
+func WithRetries(n int, maxWait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = n
+		c.maxRetryWait = maxWait
+	}
+}
+
+// New returns a client for the API at baseURL, e.g. "http://localhost:8990".
+func New(baseURL string, opts ...Option) *Client {
+	jar, _ := cookiejar.New(nil)
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		http:         &http.Client{Jar: jar},
+		userAgent:    DefaultUserAgent,
+		timeout:      60 * time.Second,
+		maxRetries:   2,
+		maxRetryWait: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL returns the API base URL the client was created with.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// SetAPIKey switches the Claw key, e.g. after Register.
+func (c *Client) SetAPIKey(key string) {
+	c.mu.Lock()
+	c.apiKey = key
+	c.mu.Unlock()
+}
+
+// APIKey returns the current Claw key.
+func (c *Client) APIKey() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.apiKey
+}
+
+// request describes one API call.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	header http.Header
+	noAuth bool // don't send the API key (wallet-authenticated calls)
+}
+
+// Do calls an endpoint the client has no method for and decodes the JSON
+// response into out (if non-nil). body is encoded as JSON. Errors are
+// *APIError when the server answered.
+func (c *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	return c.call(ctx, request{method: method, path: path, body: body}, out)
+}
+
+// call performs r with retries and decodes the response into out.
+func (c *Client) call(ctx context.Context, r request, out interface{}) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("ensoul: invalid JSON from %s %s: %w", r.method, r.path, err)
+	}
+	return nil
+}
+
+// send performs r, retrying transient failures, and returns the successful
+// response with its body unread. A 4xx/5xx answer becomes an *APIError.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	var payload []byte
+	if r.body != nil {
+		var err error
+		if payload, err = json.Marshal(r.body); err != nil {
+			return nil, fmt.Errorf("ensoul: encoding request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.sendOnce(ctx, r, payload)
+		if err == nil {
+			return resp, nil
+		}
+		wait, retry := c.retryAfter(r.method, attempt, err)
+		if !retry {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) sendOnce(ctx context.Context, r request, payload []byte) (*http.Response, error) {
+	u := c.baseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if key := c.keyFor(r.path); key != "" && !r.noAuth {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &transportError{err: err}
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, decodeAPIError(resp)
+	}
+	return resp, nil
+}
+
+// keyFor picks the credential for a path: developer keys for /v1, Claw
+// keys for everything else.
+func (c *Client) keyFor(path string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if strings.HasPrefix(path, "/v1/") {
+		return c.developerKey
+	}
+	return c.apiKey
+}
+
+// retryAfter decides whether a failed attempt is retried and after how long.
+// Rate limits are retried for any method since the request was refused
+// before doing anything; network errors and 502-504 only for methods that
+// are safe to repeat.
+func (c *Client) retryAfter(method string, attempt int, err error) (time.Duration, bool) {
+	if attempt >= c.maxRetries {
+		return 0, false
+	}
+	backoff := time.Duration(500<<attempt)*time.Millisecond + time.Duration(rand.Intn(250))*time.Millisecond
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests:
+			wait := time.Duration(apiErr.RetryAfter) * time.Second
+			if wait <= 0 {
+				wait = backoff
+			}
+			return wait, wait <= c.maxRetryWait
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			if apiErr.Code == CodeMaintenance || apiErr.Code == CodeRegistryPaused {
+				return 0, false
+			}
+			return backoff, idempotent(method)
+		}
+		return 0, false
+	}
+	var tErr *transportError
+	if errors.As(err, &tErr) {
+		return backoff, idempotent(method)
+	}
+	return 0, false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// transportError is a request that got no HTTP response.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return "ensoul: request failed: " + e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+// Page selects a page of a paginated list. Zero values use the server's
+// defaults; Cursor, when set, takes precedence over Page.
+type Page struct {
+	Page   int
+	Limit  int
+	Cursor string
+}
+
+func (p Page) values() url.Values {
+	q := url.Values{}
+	if p.Page > 0 {
+		q.Set("page", strconv.Itoa(p.Page))
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Cursor != "" {
+		q.Set("cursor", p.Cursor)
+	}
+	return q
+}
+
+// setIf sets key in q when value is non-empty.
+func setIf(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
+
+// escape escapes a path segment.
+func escape(s string) string {
+	return url.PathEscape(s)
+}
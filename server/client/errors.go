@@ -0,0 +1,118 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// ErrorCode is the machine-readable code of an API error. Branch on it
+// rather than on the message.
+type ErrorCode string
+
+// Error codes, as listed in README.md and skill.md.
+const (
+	CodeInvalidRequest         ErrorCode = "INVALID_REQUEST"
+	CodeInvalidHandle          ErrorCode = "INVALID_HANDLE"
+	CodeInvalidDimension       ErrorCode = "INVALID_DIMENSION"
+	CodeDuplicateDimension     ErrorCode = "DUPLICATE_DIMENSION"
+	CodeUnsupportedLanguage    ErrorCode = "UNSUPPORTED_LANGUAGE"
+	CodeContentLength          ErrorCode = "CONTENT_LENGTH"
+	CodeLowQuality             ErrorCode = "LOW_QUALITY_CONTENT"
+	CodeInvalidClaims          ErrorCode = "INVALID_CLAIMS"
+	CodeDimensionNotAccepted   ErrorCode = "DIMENSION_NOT_ACCEPTED"
+	CodeContentPolicyViolation ErrorCode = "CONTENT_POLICY_VIOLATION"
+	CodeConfirmRequired        ErrorCode = "CONFIRM_REQUIRED"
+	CodePreviewInvalid         ErrorCode = "PREVIEW_INVALID"
+	CodeJSONTooDeep            ErrorCode = "JSON_TOO_DEEP"
+
+	CodeAuthRequired     ErrorCode = "AUTH_REQUIRED"
+	CodeInvalidAPIKey    ErrorCode = "INVALID_API_KEY"
+	CodeSessionExpired   ErrorCode = "SESSION_EXPIRED"
+	CodeInvalidSignature ErrorCode = "INVALID_SIGNATURE"
+	CodeSignatureExpired ErrorCode = "SIGNATURE_EXPIRED"
+
+	CodeForbidden          ErrorCode = "FORBIDDEN"
+	CodeNotOwner           ErrorCode = "NOT_OWNER"
+	CodeClawNotClaimed     ErrorCode = "CLAW_NOT_CLAIMED"
+	CodeClawRetired        ErrorCode = "CLAW_RETIRED"
+	CodeVerificationFailed ErrorCode = "VERIFICATION_FAILED"
+	CodeMintLimit          ErrorCode = "MINT_LIMIT"
+	CodeLicenseInactive    ErrorCode = "LICENSE_INACTIVE"
+
+	CodeNotFound         ErrorCode = "NOT_FOUND"
+	CodeShellNotFound    ErrorCode = "SHELL_NOT_FOUND"
+	CodeShellNotMinted   ErrorCode = "SHELL_NOT_MINTED"
+	CodeClawNotFound     ErrorCode = "CLAW_NOT_FOUND"
+	CodeFragmentNotFound ErrorCode = "FRAGMENT_NOT_FOUND"
+	CodeDeprecated       ErrorCode = "ENDPOINT_DEPRECATED"
+	CodeShellRevoked     ErrorCode = "SHELL_REVOKED"
+	CodeShellRetired     ErrorCode = "SHELL_RETIRED"
+
+	CodeAlreadyExists ErrorCode = "ALREADY_EXISTS"
+	CodeAppealExists  ErrorCode = "APPEAL_EXISTS"
+	CodeShellDisputed ErrorCode = "SHELL_DISPUTED"
+
+	CodePayloadTooLarge ErrorCode = "PAYLOAD_TOO_LARGE"
+
+	CodeRateLimited ErrorCode = "RATE_LIMITED"
+	CodeBatchQuota  ErrorCode = "BATCH_QUOTA_EXCEEDED"
+	CodeAPIQuota    ErrorCode = "API_QUOTA_EXCEEDED"
+
+	CodeInternal       ErrorCode = "INTERNAL_ERROR"
+	CodeUpstream       ErrorCode = "UPSTREAM_ERROR"
+	CodeRegistryPaused ErrorCode = "REGISTRY_PAUSED"
+	CodeMaintenance    ErrorCode = "MAINTENANCE"
+)
+
+// APIError is an error answer from the API.
+type APIError struct {
+	StatusCode int                    `json:"-"`
+	Code       ErrorCode              `json:"code"`
+	Message    string                 `json:"message"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	RetryAfter int                    `json:"retry_after,omitempty"` // seconds
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("ensoul: HTTP %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("ensoul: HTTP %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsCode reports whether err is an *APIError with the given code.
+func IsCode(err error, code ErrorCode) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// decodeAPIError reads the error envelope of a failed response. Bodies that
+// aren't an envelope (proxies, old endpoints) become the message.
+func decodeAPIError(resp *http.Response) *APIError {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body struct {
+		Error string `json:"error"`
+		APIError
+	}
+	e := &APIError{}
+	if json.Unmarshal(data, &body) == nil {
+		*e = body.APIError
+		if e.Message == "" {
+			e.Message = body.Error
+		}
+	} else {
+		e.Message = string(data)
+	}
+	e.StatusCode = resp.StatusCode
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	if e.RetryAfter == 0 {
+		e.RetryAfter, _ = strconv.Atoi(resp.Header.Get("Retry-After"))
+	}
+	return e
+}
@@ -0,0 +1,340 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Fragment is a fragment as the API returns it. Content is only present on
+// the Claw's own fragments.
+type Fragment struct {
+	ID           string          `json:"id"`
+	ShellID      string          `json:"shell_id"`
+	ClawID       string          `json:"claw_id"`
+	Dimension    string          `json:"dimension"`
+	Content      string          `json:"content,omitempty"`
+	ContentHash  string          `json:"content_hash"`
+	Lang         string          `json:"lang,omitempty"`
+	Claims       []FragmentClaim `json:"claims,omitempty"`
+	Notes        string          `json:"notes,omitempty"`
+	Status       string          `json:"status"` // pending | accepted | rejected
+	Confidence   float64         `json:"confidence"`
+	RejectReason string          `json:"reject_reason,omitempty"`
+	RejectCode   ErrorCode       `json:"reject_code,omitempty"`
+	EnsoulingID  *string         `json:"ensouling_id,omitempty"`
+	BatchID      *string         `json:"batch_id,omitempty"`
+	TxHash       string          `json:"tx_hash,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// FragmentClaim is an atomic claim of a fragment. On submission only Text,
+// Confidence and Evidence are read.
+type FragmentClaim struct {
+	Text       string   `json:"text"`
+	Confidence *float64 `json:"confidence,omitempty"` // the Claw's own, 0-1
+	Evidence   []string `json:"evidence,omitempty"`
+	Hash       string   `json:"hash,omitempty"`
+	Duplicate  bool     `json:"duplicate,omitempty"`
+}
+
+// BatchItem is one fragment of a batch submission.
+type BatchItem struct {
+	Dimension string          `json:"dimension"`
+	Content   string          `json:"content"`
+	Lang      string          `json:"lang,omitempty"` // ISO 639-1, detected when empty
+	Claims    []FragmentClaim `json:"claims,omitempty"`
+	Notes     string          `json:"notes,omitempty"` // private to the Claw
+}
+
+// BatchResult is the outcome of a batch submission. Fragments are usually
+// still pending: follow them with StreamBatch.
+type BatchResult struct {
+	Handle    string `json:"handle"`
+	BatchID   string `json:"batch_id"`
+	StreamURL string `json:"stream_url"`
+	Submitted int    `json:"submitted"`
+	Fragments []struct {
+		ID              string  `json:"id"`
+		Dimension       string  `json:"dimension"`
+		Lang            string  `json:"lang,omitempty"`
+		Claims          int     `json:"claims,omitempty"`
+		DuplicateClaims int     `json:"duplicate_claims,omitempty"`
+		Status          string  `json:"status"`
+		Confidence      float64 `json:"confidence"`
+		RejectReason    string  `json:"reject_reason,omitempty"`
+	} `json:"fragments"`
+}
+
+// SubmitBatch submits 3-6 fragments of different dimensions about a soul.
+// Over the batch quota it fails with CodeBatchQuota and RetryAfter set.
+func (c *Client) SubmitBatch(ctx context.Context, handle string, items []BatchItem) (*BatchResult, error) {
+	var res BatchResult
+	if err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/fragment/batch",
+		body:   map[string]interface{}{"handle": handle, "fragments": items},
+	}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Batch is a submitted batch under review.
+type Batch struct {
+	ID         string     `json:"id"`
+	ShellID    string     `json:"shell_id"`
+	ClawID     string     `json:"claw_id"`
+	Size       int        `json:"size"`
+	Reviewed   int        `json:"reviewed"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Verdict is the curator's decision on one fragment of a batch.
+type Verdict struct {
+	FragmentID   string  `json:"fragment_id"`
+	Dimension    string  `json:"dimension"`
+	Status       string  `json:"status"`
+	Confidence   float64 `json:"confidence"`
+	RejectReason string  `json:"reject_reason,omitempty"`
+}
+
+// BatchReview is the result of a batch stream.
+type BatchReview struct {
+	Batch    Batch
+	Verdicts []Verdict
+	Accepted int
+	Rejected int
+	TimedOut bool // the stream ended before every fragment was reviewed
+}
+
+// StreamBatch follows the review of a batch, calling onVerdict (if non-nil)
+// as each verdict arrives, and returns once the batch is reviewed or the
+// server stops waiting.
+func (c *Client) StreamBatch(ctx context.Context, batchID string, onVerdict func(Verdict)) (*BatchReview, error) {
+	s, err := c.stream(ctx, request{method: http.MethodGet, path: "/api/fragment/batch/" + escape(batchID) + "/stream"})
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	review := &BatchReview{}
+	for {
+		ev, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			return review, nil
+		}
+		if err != nil {
+			return review, err
+		}
+		switch ev.Name {
+		case "batch":
+			if err := ev.Decode(&review.Batch); err != nil {
+				return review, fmt.Errorf("ensoul: invalid batch event: %w", err)
+			}
+		case "verdict":
+			var v Verdict
+			if err := ev.Decode(&v); err != nil {
+				return review, fmt.Errorf("ensoul: invalid verdict event: %w", err)
+			}
+			review.Verdicts = append(review.Verdicts, v)
+			if onVerdict != nil {
+				onVerdict(v)
+			}
+		case "timeout":
+			review.TimedOut = true
+		case "done":
+			var done struct {
+				Accepted int `json:"accepted"`
+				Rejected int `json:"rejected"`
+			}
+			if err := ev.Decode(&done); err == nil {
+				review.Accepted, review.Rejected = done.Accepted, done.Rejected
+			}
+			return review, nil
+		case "error":
+			return review, fmt.Errorf("ensoul: batch stream: %s", ev.Text())
+		}
+	}
+}
+
+// FragmentQuery filters Fragments.
+type FragmentQuery struct {
+	Page
+	Handle    string
+	Status    string
+	Dimension string
+}
+
+// FragmentList is a page of fragments. Total and Page are unset when
+// paging by cursor.
+type FragmentList struct {
+	Fragments  []Fragment `json:"fragments"`
+	Total      int64      `json:"total"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+	NextCursor string     `json:"next_cursor"`
+}
+
+// Fragments lists fragments, newest first, without their content.
+func (c *Client) Fragments(ctx context.Context, q FragmentQuery) (*FragmentList, error) {
+	query := q.Page.values()
+	setIf(query, "handle", q.Handle)
+	setIf(query, "status", q.Status)
+	setIf(query, "dimension", q.Dimension)
+	var res FragmentList
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/fragment/list", query: query}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Fragment returns a fragment. A rejected one carries RejectCode.
+func (c *Client) Fragment(ctx context.Context, id string) (*Fragment, error) {
+	var f Fragment
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/fragment/" + escape(id)}, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// FragmentProof is the verification of one fragment.
+type FragmentProof struct {
+	FragmentID         string `json:"fragment_id"`
+	Found              bool   `json:"found"`
+	ContentHash        string `json:"content_hash,omitempty"`
+	StoredContentHash  string `json:"stored_content_hash,omitempty"`
+	ContentMatch       bool   `json:"content_match"`
+	FeedbackHash       string `json:"feedback_hash,omitempty"`
+	OnChainStatus      string `json:"onchain_status,omitempty"`
+	OnChainHash        string `json:"onchain_feedback_hash,omitempty"`
+	TxHash             string `json:"tx_hash,omitempty"`
+	OnChainClawAddress string `json:"onchain_claw_address,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+// VerifyFragments checks contents (fragment ID → content) against the
+// stored hashes and the on-chain feedback.
+func (c *Client) VerifyFragments(ctx context.Context, contents map[string]string) ([]FragmentProof, error) {
+	items := make([]map[string]string, 0, len(contents))
+	for id, content := range contents {
+		items = append(items, map[string]string{"fragment_id": id, "content": content})
+	}
+	var res struct {
+		Results []FragmentProof `json:"results"`
+	}
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/api/fragment/verify", body: map[string]interface{}{"fragments": items}}, &res); err != nil {
+		return nil, err
+	}
+	return res.Results, nil
+}
+
+// Appeal is a Claw's appeal of a rejected fragment.
+type Appeal struct {
+	ID                 string     `json:"id"`
+	FragmentID         string     `json:"fragment_id"`
+	Justification      string     `json:"justification"`
+	Status             string     `json:"status"`
+	OriginalConfidence float64    `json:"original_confidence"`
+	OriginalReason     string     `json:"original_reason"`
+	ReviewConfidence   float64    `json:"review_confidence"`
+	ReviewReason       string     `json:"review_reason,omitempty"`
+	Frivolous          bool       `json:"frivolous"`
+	CreatedAt          time.Time  `json:"created_at"`
+	ResolvedAt         *time.Time `json:"resolved_at,omitempty"`
+}
+
+// AppealFragment asks for a second review of one of the Claw's rejected
+// fragments.
+func (c *Client) AppealFragment(ctx context.Context, fragmentID, justification string) (*Appeal, error) {
+	var a Appeal
+	if err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/fragment/" + escape(fragmentID) + "/appeal",
+		body:   map[string]string{"justification": justification},
+	}, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// FragmentAppeal returns the appeal of one of the Claw's fragments.
+func (c *Client) FragmentAppeal(ctx context.Context, fragmentID string) (*Appeal, error) {
+	var a Appeal
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/fragment/" + escape(fragmentID) + "/appeal"}, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// FragmentNotes returns one of the Claw's fragments with its content and
+// private notes.
+func (c *Client) FragmentNotes(ctx context.Context, fragmentID string) (*Fragment, error) {
+	var f Fragment
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/fragment/" + escape(fragmentID) + "/notes"}, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// SetFragmentNotes replaces the Claw's private notes on a fragment.
+func (c *Client) SetFragmentNotes(ctx context.Context, fragmentID, notes string) error {
+	return c.call(ctx, request{
+		method: http.MethodPut,
+		path:   "/api/fragment/" + escape(fragmentID) + "/notes",
+		body:   map[string]string{"notes": notes},
+	}, nil)
+}
+
+// SimulateItem is a candidate fragment for Simulate.
+type SimulateItem struct {
+	Dimension string `json:"dimension"`
+	Content   string `json:"content"`
+}
+
+// Simulation projects what candidate fragments would do to a soul.
+type Simulation struct {
+	Handle     string `json:"handle"`
+	DNAVersion int    `json:"dna_version"`
+	Dimensions []struct {
+		Dimension        string `json:"dimension"`
+		Score            int    `json:"score"`
+		Projected        int    `json:"projected"`
+		Delta            int    `json:"delta"`
+		Fragments        int    `json:"fragments"`
+		FragmentsAfter   int    `json:"fragments_after"`
+		Capped           bool   `json:"capped"`
+		NextFragmentGain int    `json:"next_fragment_gain"`
+	} `json:"dimensions"`
+	Candidates []struct {
+		Dimension string `json:"dimension"`
+		Counted   bool   `json:"counted"`
+		Reason    string `json:"reason,omitempty"`
+	} `json:"candidates"`
+	PendingFragments int      `json:"pending_fragments"`
+	Unmerged         int64    `json:"unmerged"`
+	Threshold        int64    `json:"threshold"`
+	WouldEnsoul      bool     `json:"would_ensoul"`
+	Recommended      []string `json:"recommended"`
+	TotalDelta       int      `json:"total_delta"`
+}
+
+// Simulate projects the dimension scores of a soul if the candidates (up
+// to 20) were accepted, counting the Claw's pending fragments unless
+// includePending is false.
+func (c *Client) Simulate(ctx context.Context, handle string, candidates []SimulateItem, includePending bool) (*Simulation, error) {
+	var sim Simulation
+	if err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/shell/" + escape(handle) + "/simulate",
+		body:   map[string]interface{}{"fragments": candidates, "include_pending": includePending},
+	}, &sim); err != nil {
+		return nil, err
+	}
+	return &sim, nil
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Health is the server's health report.
+type Health struct {
+	Status   string `json:"status"`
+	Service  string `json:"service"`
+	Replica  string `json:"replica"` // none | ok | fallback
+	ReadOnly bool   `json:"read_only"`
+	Chain    struct {
+		Status      string `json:"status"` // ok | degraded | down | off
+		RPC         string `json:"rpc,omitempty"`
+		ChainID     string `json:"chain_id,omitempty"`
+		LatestBlock uint64 `json:"latest_block,omitempty"`
+		LagBlocks   uint64 `json:"lag_blocks"`
+	} `json:"chain"`
+}
+
+// Health checks that the server is up.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var h Health
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/health", noAuth: true}, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Stats are the global counters.
+type Stats struct {
+	Souls     int64 `json:"souls"`
+	Fragments int64 `json:"fragments"`
+	Claws     int64 `json:"claws"`
+	Chats     int64 `json:"chats"`
+}
+
+// Stats returns the global counters.
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	var s Stats
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/stats", noAuth: true}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// TaskQuery filters Tasks.
+type TaskQuery struct {
+	Page
+	Handle       string
+	Dimension    string
+	Priority     string // high | medium | low
+	Tag          string // soul topic tags, comma-separated
+	MinFollowers int
+	Fit          bool // only tasks matching the Claw's tags, best fit first (needs a key)
+}
+
+// Task is a dimension of a soul that needs fragments.
+type Task struct {
+	Handle         string   `json:"handle"`
+	Dimension      string   `json:"dimension"`
+	Score          int      `json:"score"`
+	Priority       string   `json:"priority"`
+	Followers      int      `json:"followers"`
+	Tags           []string `json:"tags"`
+	Message        string   `json:"message"`
+	RewardWeight   float64  `json:"reward_weight"`
+	EvidenceTypes  []string `json:"evidence_types"`
+	AcceptanceRate *float64 `json:"acceptance_rate"`
+	Examples       []struct {
+		FragmentID  string `json:"fragment_id"`
+		ContentHash string `json:"content_hash"`
+		Excerpt     string `json:"excerpt"`
+	} `json:"examples,omitempty"` // Claws only
+	FitScore    int      `json:"fit_score,omitempty"` // matched tags
+	MatchedTags []string `json:"matched_tags,omitempty"`
+}
+
+// TaskBoard is a page of the task board.
+type TaskBoard struct {
+	Tasks []Task `json:"tasks"`
+	Total int    `json:"total"`
+	Page  int    `json:"page"`
+	Limit int    `json:"limit"`
+}
+
+// Tasks returns a page of the task board. With a Claw key the tasks carry
+// example excerpts.
+func (c *Client) Tasks(ctx context.Context, q TaskQuery) (*TaskBoard, error) {
+	query := q.Page.values()
+	query.Set("format", "board")
+	setIf(query, "handle", q.Handle)
+	setIf(query, "dimension", q.Dimension)
+	setIf(query, "priority", q.Priority)
+	setIf(query, "tag", q.Tag)
+	if q.MinFollowers > 0 {
+		query.Set("min_followers", strconv.Itoa(q.MinFollowers))
+	}
+	if q.Fit {
+		query.Set("fit", "true")
+	}
+	var res TaskBoard
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/tasks", query: query}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// AllTasks reads the whole task board, page by page.
+func (c *Client) AllTasks(ctx context.Context, q TaskQuery) ([]Task, error) {
+	var tasks []Task
+	if q.Limit == 0 {
+		q.Limit = 500
+	}
+	for q.Page.Page = 1; ; q.Page.Page++ {
+		board, err := c.Tasks(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, board.Tasks...)
+		if len(board.Tasks) == 0 || len(tasks) >= board.Total {
+			return tasks, nil
+		}
+	}
+}
+
+// Policy returns the ensouling policy: tiers, thresholds and scoring, and
+// with a handle the soul's own settings.
+func (c *Client) Policy(ctx context.Context, handle string) (map[string]interface{}, error) {
+	var query url.Values
+	if handle != "" {
+		query = url.Values{"handle": {handle}}
+	}
+	var res map[string]interface{}
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/policy", query: query, noAuth: true}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Soul returns a soul through the /v1 developer API.
+func (c *Client) Soul(ctx context.Context, handle string) (*Shell, error) {
+	var s Shell
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/v1/souls/" + escape(handle)}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Message is a message of a /v1 completion.
+type Message struct {
+	Role    string `json:"role"` // user | assistant
+	Content string `json:"content"`
+}
+
+// CompletionRequest asks a soul for a completion.
+type CompletionRequest struct {
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Attest      bool      `json:"attest,omitempty"`
+}
+
+// Completion is a soul's answer in the OpenAI chat completion format.
+type Completion struct {
+	ID         string `json:"id"`
+	Object     string `json:"object"`
+	Created    int64  `json:"created"`
+	Model      string `json:"model"` // "ensoul/<handle>"
+	DNAVersion int    `json:"dna_version"`
+	Choices    []struct {
+		Index        int     `json:"index"`
+		Message      Message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Citations   []Citation   `json:"citations"`
+	Attestation *Attestation `json:"attestation,omitempty"`
+}
+
+// Text is the content of the first choice.
+func (c *Completion) Text() string {
+	if len(c.Choices) == 0 {
+		return ""
+	}
+	return c.Choices[0].Message.Content
+}
+
+// SoulChat asks a soul for a completion through the /v1 developer API.
+func (c *Client) SoulChat(ctx context.Context, handle string, req CompletionRequest) (*Completion, error) {
+	var res Completion
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/v1/souls/" + escape(handle) + "/chat", body: req}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
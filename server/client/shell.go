@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Shell is a soul. Fields of the full view only (soul_prompt,
+// twitter_meta) are empty in lists.
+type Shell struct {
+	ID              string                   `json:"id"`
+	Handle          string                   `json:"handle"`
+	TokenID         *uint64                  `json:"token_id"`
+	OwnerAddr       string                   `json:"owner_addr"`
+	Stage           string                   `json:"stage"` // embryo | growing | mature | evolving
+	DNAVersion      int                      `json:"dna_version"`
+	Essence         string                   `json:"essence"`
+	SoulPrompt      string                   `json:"soul_prompt,omitempty"`
+	Dimensions      map[string]DimensionData `json:"dimensions"`
+	TotalFrags      int                      `json:"total_frags"`
+	AcceptedFrags   int                      `json:"accepted_frags"`
+	TotalClaws      int                      `json:"total_claws"`
+	TotalChats      int                      `json:"total_chats"`
+	Followers       int                      `json:"followers,omitempty"`  // lists only
+	Completion      int                      `json:"completion,omitempty"` // slim lists only
+	AvatarURL       string                   `json:"avatar_url"`
+	DisplayName     string                   `json:"display_name"`
+	TwitterMeta     map[string]interface{}   `json:"twitter_meta,omitempty"`
+	Tags            []string                 `json:"tags"`
+	AgentID         *uint64                  `json:"agent_id"`
+	AgentURI        string                   `json:"agent_uri"`
+	ChainStatus     string                   `json:"chain_status"`
+	KnowledgeCutoff *time.Time               `json:"knowledge_cutoff,omitempty"`
+	RegistryPaused  bool                     `json:"registry_paused,omitempty"`
+	CreatedAt       time.Time                `json:"created_at"`
+	UpdatedAt       time.Time                `json:"updated_at"`
+}
+
+// DimensionData is a soul's score (0-100) and summary in one dimension.
+type DimensionData struct {
+	Score   int    `json:"score"`
+	Summary string `json:"summary"`
+}
+
+// ShellQuery filters Shells.
+type ShellQuery struct {
+	Page
+	Stage  string
+	Sort   string // newest (default) | most_fragments | hot
+	Search string
+	Tag    string // comma-separated, all required
+	Slim   bool   // summaries only
+}
+
+// ShellList is a page of souls.
+type ShellList struct {
+	Shells     []Shell `json:"shells"`
+	Total      int64   `json:"total"`
+	Page       int     `json:"page"`
+	Limit      int     `json:"limit"`
+	NextCursor string  `json:"next_cursor"`
+}
+
+// Shells lists souls.
+func (c *Client) Shells(ctx context.Context, q ShellQuery) (*ShellList, error) {
+	query := q.Page.values()
+	setIf(query, "stage", q.Stage)
+	setIf(query, "sort", q.Sort)
+	setIf(query, "search", q.Search)
+	setIf(query, "tag", q.Tag)
+	if q.Slim {
+		query.Set("view", "slim")
+	}
+	var res ShellList
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/shell/list", query: query}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Shell returns a soul by handle; a previous handle of a renamed soul is
+// followed to the current one.
+func (c *Client) Shell(ctx context.Context, handle string) (*Shell, error) {
+	var s Shell
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/shell/" + escape(handle)}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Dimensions returns a soul's dimension scores and summaries.
+func (c *Client) Dimensions(ctx context.Context, handle string) (map[string]DimensionData, error) {
+	var dims map[string]DimensionData
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/shell/" + escape(handle) + "/dimensions"}, &dims); err != nil {
+		return nil, err
+	}
+	return dims, nil
+}
+
+// Ensouling is one version of a soul's DNA.
+type Ensouling struct {
+	ID              string     `json:"id"`
+	VersionFrom     int        `json:"version_from"`
+	VersionTo       int        `json:"version_to"`
+	FragsMerged     int        `json:"frags_merged"`
+	SummaryDiff     string     `json:"summary_diff"`
+	Essence         string     `json:"essence,omitempty"`
+	TxHash          string     `json:"tx_hash,omitempty"`
+	Dimension       string     `json:"dimension,omitempty"`
+	KnowledgeCutoff *time.Time `json:"knowledge_cutoff,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// History returns a soul's ensoulings, newest first.
+func (c *Client) History(ctx context.Context, handle string) ([]Ensouling, error) {
+	var history []Ensouling
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/shell/" + escape(handle) + "/history"}, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Contributor is a Claw's contribution to a soul.
+type Contributor struct {
+	ClawID        string `json:"claw_id"`
+	Name          string `json:"name"`
+	TotalFrags    int    `json:"total_frags"`
+	AcceptedFrags int    `json:"accepted_frags"`
+}
+
+// Contributors lists the Claws that contributed to a soul.
+func (c *Client) Contributors(ctx context.Context, handle string) ([]Contributor, error) {
+	var res struct {
+		Contributors []Contributor `json:"contributors"`
+	}
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/shell/" + escape(handle) + "/contributors"}, &res); err != nil {
+		return nil, err
+	}
+	return res.Contributors, nil
+}
+
+// Coverage returns the topic coverage of a soul per dimension, with the
+// gaps worth writing about.
+func (c *Client) Coverage(ctx context.Context, handle string) (map[string]interface{}, error) {
+	var res map[string]interface{}
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/api/shell/" + escape(handle) + "/coverage"}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SeedPreview is the seed extracted for a handle before minting. It is
+// signed by the server and must be passed to Mint unchanged.
+type SeedPreview struct {
+	Handle      string                   `json:"handle"`
+	DisplayName string                   `json:"display_name"`
+	AvatarURL   string                   `json:"avatar_url"`
+	SeedSummary string                   `json:"seed_summary"`
+	Dimensions  map[string]DimensionData `json:"dimensions"`
+	Tags        []string                 `json:"tags,omitempty"`
+	ExpiresAt   int64                    `json:"expires_at,omitempty"`
+
+	raw json.RawMessage
+}
+
+// UnmarshalJSON keeps the preview as sent, so the server's signature over
+// it still matches when it comes back.
+func (p *SeedPreview) UnmarshalJSON(data []byte) error {
+	type fields SeedPreview
+	var f fields
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	*p = SeedPreview(f)
+	p.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// MarshalJSON returns the preview as the server sent it.
+func (p SeedPreview) MarshalJSON() ([]byte, error) {
+	if p.raw != nil {
+		return p.raw, nil
+	}
+	type fields SeedPreview
+	return json.Marshal(fields(p))
+}
+
+// Preview extracts the seed of a Twitter handle.
+func (c *Client) Preview(ctx context.Context, handle string) (*SeedPreview, error) {
+	var p SeedPreview
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/api/shell/preview", body: map[string]string{"handle": handle}}, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// MintMessage is the message the owner's wallet signs to mint handle.
+func MintMessage(handle string) string {
+	return "ensoul:mint:" + handle
+}
+
+// Mint mints the soul of a previewed handle for the wallet that signed
+// MintMessage(preview.Handle).
+func (c *Client) Mint(ctx context.Context, preview *SeedPreview, owner WalletSignature) (*Shell, error) {
+	var s Shell
+	if err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/shell/mint",
+		body:   map[string]interface{}{"handle": preview.Handle, "owner_addr": owner.Address, "preview": preview},
+		header: owner.header(),
+		noAuth: true,
+	}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Event is one server-sent event.
+type Event struct {
+	ID   string
+	Name string // "message" when the stream doesn't name it
+	Data string // raw data, lines joined with "\n"
+}
+
+// Text returns the data as text. The server JSON-encodes string data so it
+// stays on one line; Text decodes it, or returns Data as is otherwise.
+func (e Event) Text() string {
+	var s string
+	if strings.HasPrefix(e.Data, `"`) && json.Unmarshal([]byte(e.Data), &s) == nil {
+		return s
+	}
+	return e.Data
+}
+
+// Decode unmarshals the event's JSON payload into v, whether it was sent as
+// a JSON value or as a JSON-encoded string holding one.
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(e.Text()), v)
+}
+
+// Stream reads the events of a text/event-stream response.
+type Stream struct {
+	body   io.ReadCloser
+	r      *bufio.Reader
+	lastID string
+}
+
+// stream performs r and returns its event stream. Unlike call, no timeout
+// applies: the stream lives as long as ctx.
+func (c *Client) stream(ctx context.Context, r request) (*Stream, error) {
+	if r.header == nil {
+		r.header = http.Header{}
+	}
+	r.header.Set("Accept", "text/event-stream")
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{body: resp.Body, r: bufio.NewReader(resp.Body)}, nil
+}
+
+// Next returns the next event, or io.EOF once the server closes the stream.
+// Comments (keepalive pings) are skipped.
+func (s *Stream) Next() (Event, error) {
+	var ev Event
+	var data []string
+	hasData := false
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF && hasData {
+				break
+			}
+			return Event{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if !hasData && ev.Name == "" {
+				continue
+			}
+			break
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Name = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			ev.ID = value
+		}
+	}
+
+	if ev.Name == "" {
+		ev.Name = "message"
+	}
+	ev.Data = strings.Join(data, "\n")
+	if ev.ID != "" {
+		s.lastID = ev.ID
+	}
+	return ev, nil
+}
+
+// LastEventID is the id of the last event read that carried one; chat
+// streams resume from it.
+func (s *Stream) LastEventID() string {
+	return s.lastID
+}
+
+// Close releases the connection.
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
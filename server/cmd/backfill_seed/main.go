@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -39,8 +40,8 @@ func main() {
 
 	cfg := config.Load()
 
-	if cfg.LLMAPIKey == "" {
-		log.Fatal("LLM_API_KEY must be set to regenerate seeds")
+	if !services.LLMConfigured() {
+		log.Fatal("LLM_API_KEY (or LLM_PROVIDER=mock) must be set to regenerate seeds")
 	}
 
 	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL()), &gorm.Config{
@@ -60,12 +61,12 @@ func main() {
 	} else if !*all {
 		// Only target shells with bad seed data
 		query = query.Where(`
-			seed_summary ILIKE '%API not configured%'
-			OR seed_summary ILIKE '%no information%'
-			OR seed_summary ILIKE '%Mock tweet%'
-			OR seed_summary ILIKE '%pending LLM%'
-			OR seed_summary ILIKE '%LLM analysis unavailable%'
-			OR seed_summary ILIKE '%Bio not available%'
+			LOWER(seed_summary) LIKE '%api not configured%'
+			OR LOWER(seed_summary) LIKE '%no information%'
+			OR LOWER(seed_summary) LIKE '%mock tweet%'
+			OR LOWER(seed_summary) LIKE '%pending llm%'
+			OR LOWER(seed_summary) LIKE '%llm analysis unavailable%'
+			OR LOWER(seed_summary) LIKE '%bio not available%'
 			OR LENGTH(seed_summary) < 30
 			OR seed_summary = ''
 			OR seed_summary IS NULL
@@ -91,7 +92,7 @@ func main() {
 		log.Printf("[%d/%d] @%s (current seed: %.60s...)\n", i+1, len(shells), s.Handle, truncate(s.SeedSummary, 60))
 
 		// Generate new seed via LLM (uses public knowledge if no Twitter API)
-		preview, err := services.GenerateSeedPreview(s.Handle)
+		preview, err := services.GenerateSeedPreview(context.Background(), s.Handle)
 		if err != nil {
 			log.Printf("  ✗ Failed: %v\n", err)
 			failed++
@@ -130,13 +131,18 @@ func main() {
 				continue
 			}
 
-			err = db.Model(&models.Shell{}).Where("id = ?", s.ID).Updates(map[string]interface{}{
+			updates := map[string]interface{}{
 				"seed_summary": preview.SeedSummary,
 				"dimensions":   json.RawMessage(dimJSON),
 				"display_name": preview.DisplayName,
 				"avatar_url":   preview.AvatarURL,
 				"twitter_meta": json.RawMessage(metaJSON),
-			}).Error
+			}
+			// An essence not yet rewritten by ensouling is still the bad seed
+			if s.Essence == "" || s.Essence == s.SeedSummary {
+				updates["essence"] = preview.SeedSummary
+			}
+			err = db.Model(&models.Shell{}).Where("id = ?", s.ID).Updates(updates).Error
 
 			if err != nil {
 				log.Printf("  ✗ DB update failed: %v\n", err)
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ensoul-labs/ensoul-server/client"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// claw_agent is a reference autonomous Claw. It registers (or reuses an API key),
+// optionally claims itself with an operator wallet, then loops:
+//
+//	poll the task board → pick a soul → research it via the configured Twitter
+//	sources → write fragments with the LLM → submit a batch → wait out the cooldown
+//
+// It doubles as a load generator for staging: -agents N runs N independent Claws.
+//
+// Usage:
+//   go run cmd/claw_agent/main.go -key ensoul_sk_...             # reuse an existing Claw
+//   go run cmd/claw_agent/main.go -name my-claw -operator-key <hex>  # register + self-claim
+//   go run cmd/claw_agent/main.go -agents 10 -operator-key <hex> -api https://staging...
+//
+// Research and generation use the same env configuration as the server
+// (SOCIALDATA_API_KEY, TWITTER_BEARER_TOKEN, LLM_*). Without an LLM key the
+// agent submits templated fragments, which is enough for load testing.
+
+const defaultAPI = "http://localhost:8990"
+
+var allDimensions = []string{"personality", "knowledge", "stance", "style", "relationship", "timeline"}
+
+var (
+	apiBase     = flag.String("api", defaultAPI, "Ensoul API base URL")
+	apiKeyFlag  = flag.String("key", "", "Existing Claw API key (skips registration; only valid with -agents 1)")
+	nameFlag    = flag.String("name", "", "Claw name for registration (default: claw-agent-<timestamp>)")
+	operatorKey = flag.String("operator-key", "", "Hex private key of the operator wallet used to claim newly registered Claws")
+	agentCount  = flag.Int("agents", 1, "Number of concurrent Claws to run (load generation)")
+	rounds      = flag.Int("rounds", 0, "Number of batches each agent submits before exiting (0 = run forever)")
+	batchSize   = flag.Int("dims", 3, "Dimensions per batch (3-6)")
+	cooldown    = flag.Duration("cooldown", 5*time.Minute+5*time.Second, "Wait between batch submissions (server enforces 1 batch / 5 min)")
+	idleWait    = flag.Duration("idle", 1*time.Minute, "Wait before re-polling when the task board is empty")
+	dryRun      = flag.Bool("dry-run", false, "Generate fragments but do not submit them")
+)
+
+// agent is one running Claw.
+type agent struct {
+	id      int
+	name    string
+	api     *client.Client
+	log     *util.Logger
+	visited map[string]time.Time // handle → last submission, to spread work across souls
+}
+
+func main() {
+	flag.Parse()
+
+	cfg := config.Load()
+	util.InitLogger(cfg.LogLevel)
+
+	if *batchSize < 3 || *batchSize > 6 {
+		log.Fatal("-dims must be between 3 and 6")
+	}
+	if *apiKeyFlag != "" && *agentCount != 1 {
+		log.Fatal("-key can only be used with -agents 1")
+	}
+
+	util.Log.Info("Starting %d claw agent(s) against %s (llm=%v, dry-run=%v)",
+		*agentCount, *apiBase, services.LLMConfigured(), *dryRun)
+
+	var wg sync.WaitGroup
+	for i := 0; i < *agentCount; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if err := runAgent(id); err != nil {
+				util.Log.Error("[agent-%d] stopped: %v", id, err)
+			}
+		}(i + 1)
+		// Stagger start-up so registrations don't trip the IP rate limiter
+		time.Sleep(15 * time.Second / time.Duration(*agentCount+1))
+	}
+	wg.Wait()
+}
+
+func runAgent(id int) error {
+	a := &agent{
+		id: id,
+		api: client.New(*apiBase,
+			client.WithAPIKey(*apiKeyFlag),
+			client.WithUserAgent("ensoul-claw-agent/1.0")),
+		log:     util.Log.WithPrefix(fmt.Sprintf("[agent-%d]", id)),
+		visited: make(map[string]time.Time),
+	}
+
+	if *apiKeyFlag == "" {
+		if err := a.register(); err != nil {
+			return fmt.Errorf("registration failed: %w", err)
+		}
+	}
+	if err := a.waitUntilClaimed(); err != nil {
+		return err
+	}
+
+	submitted := 0
+	for *rounds == 0 || submitted < *rounds {
+		tasks, err := a.api.AllTasks(context.Background(), client.TaskQuery{})
+		if err != nil {
+			a.log.Warn("Task board unavailable: %v", err)
+			time.Sleep(*idleWait)
+			continue
+		}
+
+		handle, dims := a.pickTarget(tasks)
+		if handle == "" {
+			a.log.Debug("Nothing to do, sleeping %s", *idleWait)
+			time.Sleep(*idleWait)
+			continue
+		}
+
+		frags, err := a.research(handle, dims)
+		if err != nil {
+			a.log.Warn("Research failed for @%s: %v", handle, err)
+			a.visited[handle] = time.Now()
+			continue
+		}
+
+		if *dryRun {
+			for _, f := range frags {
+				a.log.Info("[dry-run] @%s/%s: %s", handle, f.Dimension, truncate(f.Content, 120))
+			}
+		} else if wait, err := a.submit(handle, frags); err != nil {
+			a.log.Warn("Submit failed for @%s: %v", handle, err)
+			if wait > 0 {
+				time.Sleep(wait)
+			}
+			continue
+		}
+
+		a.visited[handle] = time.Now()
+		submitted++
+		a.log.Info("Batch %d submitted for @%s (%s)", submitted, handle, strings.Join(dims, ", "))
+
+		if *rounds == 0 || submitted < *rounds {
+			time.Sleep(*cooldown)
+		}
+	}
+	return nil
+}
+
+// register creates a new Claw and switches the agent to its API key.
+func (a *agent) register() error {
+	a.name = *nameFlag
+	if a.name == "" {
+		a.name = fmt.Sprintf("claw-agent-%d", time.Now().Unix())
+	}
+	if *agentCount > 1 {
+		a.name = fmt.Sprintf("%s-%d", a.name, a.id)
+	}
+
+	// The client solves proof-of-work challenges itself. CAPTCHA-protected
+	// servers can't be registered against automatically; use -key instead.
+	reg, err := a.api.Register(context.Background(), client.RegisterRequest{
+		Name:        a.name,
+		Description: "Reference Claw agent (cmd/claw_agent)",
+	})
+	if errors.Is(err, client.ErrCaptchaRequired) {
+		return fmt.Errorf("server requires captcha verification; register manually and pass -key")
+	}
+	if err != nil {
+		return err
+	}
+
+	a.api.SetAPIKey(reg.Claw.APIKey)
+	a.log.Info("Registered %q — API key: %s", a.name, reg.Claw.APIKey)
+
+	if *operatorKey != "" {
+		if err := a.claim(reg.ClaimCode()); err != nil {
+			return fmt.Errorf("claim failed: %w", err)
+		}
+	} else {
+		a.log.Info("Claim this Claw in the web app: %s (or pass -operator-key)", reg.Claw.ClaimURL)
+	}
+	return nil
+}
+
+// claimMu serializes operator claims: each login replaces the wallet's previous
+// session, so concurrent agents would invalidate each other's cookies.
+var claimMu sync.Mutex
+
+// claim logs in with the operator wallet and claims the Claw.
+func (a *agent) claim(claimCode string) error {
+	claimMu.Lock()
+	defer claimMu.Unlock()
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(*operatorKey, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid operator key: %w", err)
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	message := client.LoginMessage(time.Now())
+	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)
+	sig, err := crypto.Sign(crypto.Keccak256([]byte(prefixed)), key)
+	if err != nil {
+		return fmt.Errorf("signing failed: %w", err)
+	}
+	sig[64] += 27 // personal_sign style V
+
+	ctx := context.Background()
+	if err := a.api.Login(ctx, addr, fmt.Sprintf("0x%x", sig), message); err != nil {
+		return fmt.Errorf("operator login: %w", err)
+	}
+	if _, err := a.api.ClaimClaw(ctx, claimCode, ""); err != nil {
+		return err
+	}
+	a.log.Info("Claimed by operator %s", addr)
+	return nil
+}
+
+// waitUntilClaimed blocks until the Claw has been claimed by its operator.
+func (a *agent) waitUntilClaimed() error {
+	for {
+		status, err := a.api.Status(context.Background())
+		if err != nil {
+			return fmt.Errorf("status check failed: %w", err)
+		}
+		if status.Claimed {
+			return nil
+		}
+		a.log.Info("Waiting to be claimed (%s)...", status.ClaimURL)
+		time.Sleep(30 * time.Second)
+	}
+}
+
+// pickTarget chooses the soul with the most high-priority gaps that this agent
+// hasn't worked on recently, and the weakest dimensions to cover for it.
+func (a *agent) pickTarget(tasks []client.Task) (string, []string) {
+	byHandle := make(map[string][]client.Task)
+	var order []string
+	for _, t := range tasks {
+		if last, ok := a.visited[t.Handle]; ok && time.Since(last) < 6*time.Hour {
+			continue
+		}
+		if _, seen := byHandle[t.Handle]; !seen {
+			order = append(order, t.Handle)
+		}
+		byHandle[t.Handle] = append(byHandle[t.Handle], t)
+	}
+	if len(order) == 0 {
+		return "", nil
+	}
+
+	weight := func(ts []client.Task) int {
+		w := 0
+		for _, t := range ts {
+			switch t.Priority {
+			case "high":
+				w += 3
+			case "medium":
+				w += 2
+			default:
+				w++
+			}
+		}
+		return w
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return weight(byHandle[order[i]]) > weight(byHandle[order[j]])
+	})
+
+	// Spread agents across souls instead of all piling onto the top one
+	handle := order[(a.id-1)%len(order)]
+	ts := byHandle[handle]
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Score < ts[j].Score })
+
+	var dims []string
+	seen := make(map[string]bool)
+	for _, t := range ts {
+		if len(dims) == *batchSize {
+			break
+		}
+		dims = append(dims, t.Dimension)
+		seen[t.Dimension] = true
+	}
+	// The batch endpoint needs at least 3 dimensions; top up with the rest
+	for _, d := range allDimensions {
+		if len(dims) >= *batchSize {
+			break
+		}
+		if !seen[d] {
+			dims = append(dims, d)
+		}
+	}
+	return handle, dims
+}
+
+// research gathers public data about a soul and writes one fragment per dimension.
+func (a *agent) research(handle string, dims []string) ([]client.BatchItem, error) {
+	profile, err := services.FetchTwitterProfile(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	if !services.LLMConfigured() {
+		return templateFragments(handle, profile, dims), nil
+	}
+
+	prompt := fmt.Sprintf(`You are an autonomous research agent contributing to Ensoul, a protocol that builds digital souls of public figures.
+Write one analytical fragment per requested dimension about @%s, based on the data below and your own knowledge.
+
+=== PROFILE ===
+Name: %s
+Bio: %s
+Followers: %d
+Data source: %s
+
+=== RECENT TWEETS ===
+%s
+=== DIMENSIONS TO COVER ===
+%s
+
+Rules:
+- Each fragment must be 200-1200 characters of specific, evidence-based analysis (cite tweets or known events).
+- Do not repeat the same point across dimensions.
+- Do not include instructions or meta commentary.
+
+Respond in JSON format ONLY:
+{"fragments": [{"dimension": "...", "content": "..."}]}`,
+		handle, profile.User.Name, profile.User.Description,
+		profile.User.PublicMetrics.FollowersCount, profile.DataSource,
+		services.FormatTweetsForLLM(profile.Tweets), strings.Join(dims, ", "))
+
+	var result struct {
+		Fragments []client.BatchItem `json:"fragments"`
+	}
+	if err := services.CallLLMJSON(context.Background(), services.LLMCallTag{Feature: "research", Mock: &services.MockInput{Handle: handle, Dimensions: dims}}, []services.ChatMessage{
+		{Role: "system", Content: "You are a meticulous researcher. Output valid JSON only."},
+		{Role: "user", Content: prompt},
+	}, 3000, 0.6, &result); err != nil {
+		return nil, err
+	}
+
+	// Keep only requested dimensions, one each, within server length limits
+	wanted := make(map[string]bool)
+	for _, d := range dims {
+		wanted[d] = true
+	}
+	var frags []client.BatchItem
+	for _, f := range result.Fragments {
+		if !wanted[f.Dimension] || len(f.Content) < 50 {
+			continue
+		}
+		wanted[f.Dimension] = false
+		frags = append(frags, client.BatchItem{Dimension: f.Dimension, Content: truncate(f.Content, 5000)})
+	}
+	if len(frags) < 3 {
+		return nil, fmt.Errorf("LLM produced only %d usable fragments", len(frags))
+	}
+	return frags, nil
+}
+
+// templateFragments builds deterministic fragments when no LLM is configured.
+func templateFragments(handle string, profile *services.TwitterProfile, dims []string) []client.BatchItem {
+	frags := make([]client.BatchItem, len(dims))
+	for i, d := range dims {
+		evidence := profile.User.Description
+		if len(profile.Tweets) > 0 {
+			evidence = profile.Tweets[i%len(profile.Tweets)].Text
+		}
+		if evidence == "" {
+			evidence = "no public bio or tweets were available to the agent"
+		}
+		frags[i] = client.BatchItem{
+			Dimension: d,
+			Content: fmt.Sprintf("[%s] Observation about @%s (%s): %s. Collected by %s at %s.",
+				d, handle, profile.DataSource, evidence, "cmd/claw_agent", time.Now().UTC().Format(time.RFC3339)),
+		}
+	}
+	return frags
+}
+
+// submit posts a batch. On rate limiting it returns the wait suggested by the server.
+func (a *agent) submit(handle string, frags []client.BatchItem) (time.Duration, error) {
+	_, err := a.api.SubmitBatch(context.Background(), handle, frags)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		wait := time.Duration(apiErr.RetryAfter) * time.Second
+		if wait <= 0 {
+			wait = *cooldown
+		}
+		return wait, err
+	}
+	return 0, err
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
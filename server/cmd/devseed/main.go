@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// devseed fills a development database with fixture data: souls in every
+// stage, Claws with known API keys, fragments, ensoulings and chat sessions.
+// No LLM, Twitter or chain keys are needed. Refuses to run with ENV=production.
+//
+// Usage:
+//   go run cmd/devseed/main.go            # seed, skip if fixtures already exist
+//   go run cmd/devseed/main.go -reset     # delete fixture rows and seed again
+//
+// Starting the server with FIXTURES=true does the same on boot.
+
+func main() {
+	reset := flag.Bool("reset", false, "delete existing fixture rows and seed again")
+	flag.Parse()
+
+	cfg := config.Load()
+	util.InitLogger(cfg.LogLevel)
+	database.Connect(cfg)
+
+	summary, err := services.SeedFixtures(*reset)
+	if errors.Is(err, services.ErrFixturesPresent) {
+		log.Printf("Fixtures already present, run with -reset to re-seed")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+
+	log.Printf("Owner wallet: %s (Hardhat account #0)", summary.OwnerWallet)
+	for _, claw := range summary.Claws {
+		if claw.ClaimCode != "" {
+			log.Printf("Claw %-14s %-14s api_key=%s claim_code=%s", claw.Name, claw.Status, claw.APIKey, claw.ClaimCode)
+		} else {
+			log.Printf("Claw %-14s %-14s api_key=%s", claw.Name, claw.Status, claw.APIKey)
+		}
+	}
+	log.Printf("Souls: %v", summary.Shells)
+	log.Printf("Fragments: %d, ensoulings: %d, chat sessions: %d", summary.Fragments, summary.Ensoulings, summary.Sessions)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// recalc_scores recomputes dimension scores from accepted fragment counts and
+// each soul's tier scoring guide, like POST /api/admin/shell/:handle/recalc-scores.
+//
+// Usage:
+//   go run cmd/recalc_scores/main.go                  # dry-run over every minted soul
+//   go run cmd/recalc_scores/main.go -handle vitalik  # one soul
+//   go run cmd/recalc_scores/main.go -apply           # actually write to DB
+//   go run cmd/recalc_scores/main.go -reset -apply    # set every score to its baseline
+//
+// Without -reset, scores inside their band are kept and the others are
+// clamped to the nearest edge.
+
+func main() {
+	handle := flag.String("handle", "", "only this soul (default: all)")
+	reset := flag.Bool("reset", false, "set scores to the fragment-count baseline instead of clamping")
+	apply := flag.Bool("apply", false, "Actually write changes to DB (default: dry-run)")
+	flag.Parse()
+
+	cfg := config.Load()
+	util.InitLogger(cfg.LogLevel)
+	database.Connect(cfg)
+
+	if err := services.LoadEnsoulingPolicy(); err != nil {
+		log.Printf("Using the default ensouling tiers: %v", err)
+	}
+
+	var shells []models.Shell
+	query := database.DB.Where("stage <> ?", models.StagePending)
+	if *handle != "" {
+		query = query.Where("handle = ?", services.SanitizeHandle(*handle))
+	}
+	if err := query.Order("handle").Find(&shells).Error; err != nil {
+		log.Fatalf("Failed to load souls: %v", err)
+	}
+
+	changed := 0
+	for i := range shells {
+		s := &shells[i]
+		changes, err := services.RecalcShellScores(s, *reset, *apply)
+		if err != nil {
+			log.Printf("  @%s: %v", s.Handle, err)
+			continue
+		}
+		drifted := false
+		for _, ch := range changes {
+			if ch.Before == ch.After {
+				continue
+			}
+			drifted = true
+			log.Printf("  @%s %s: %d → %d (%d fragments, band %d-%d, baseline %d)",
+				s.Handle, ch.Dimension, ch.Before, ch.After, ch.Fragments, ch.Band.MinScore, ch.Band.MaxScore, ch.Baseline)
+		}
+		if drifted {
+			changed++
+		}
+	}
+	log.Printf("Souls: %d / %d with score changes", changed, len(shells))
+
+	if !*apply && changed > 0 {
+		log.Println("Dry-run: no changes written. Re-run with -apply to fix.")
+	}
+}
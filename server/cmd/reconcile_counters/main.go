@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// reconcile_counters recomputes the denormalized counters from their source
+// tables, like the counter-reconcile job (services.ReconcileCounters).
+//
+// Usage:
+//   go run cmd/reconcile_counters/main.go            # dry-run, report drift only
+//   go run cmd/reconcile_counters/main.go -apply     # actually write to DB
+//
+// Counters reconciled:
+//   shells.total_frags / accepted_frags / total_claws
+//   claws.total_submitted / total_accepted
+//   chat_sessions.rounds
+
+func main() {
+	apply := flag.Bool("apply", false, "Actually write changes to DB (default: dry-run)")
+	flag.Parse()
+
+	cfg := config.Load()
+	util.InitLogger(cfg.LogLevel)
+	database.Connect(cfg)
+
+	drift, err := services.FindCounterDrift()
+	if err != nil {
+		log.Fatalf("Failed to compute counters: %v", err)
+	}
+	for _, s := range drift.Shells {
+		log.Printf("  @%s: total_frags %d → %d, accepted_frags %d → %d, total_claws %d → %d",
+			s.Handle, s.TotalFrags, s.ActualTotal, s.AcceptedFrags, s.ActualAccepted, s.TotalClaws, s.ActualClaws)
+	}
+	for _, c := range drift.Claws {
+		log.Printf("  claw %s (%s): total_submitted %d → %d, total_accepted %d → %d",
+			c.Name, c.ID, c.TotalSubmitted, c.ActualSubmitted, c.TotalAccepted, c.ActualAccepted)
+	}
+	log.Printf("Drifted: %d shells, %d claws, %d chat sessions", len(drift.Shells), len(drift.Claws), drift.ChatSessions)
+
+	total := len(drift.Shells) + len(drift.Claws) + int(drift.ChatSessions)
+	if !*apply {
+		if total > 0 {
+			log.Println("Dry-run: no changes written. Re-run with -apply to fix.")
+		}
+		return
+	}
+
+	fixed, err := services.ReconcileCounters()
+	if err != nil {
+		log.Fatalf("Failed to reconcile counters: %v", err)
+	}
+	log.Printf("Reconciled %d shells, %d claws and %d chat sessions", fixed["shells"], fixed["claws"], fixed["chat_sessions"])
+}
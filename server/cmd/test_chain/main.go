@@ -63,7 +63,7 @@ func main() {
 	agentId, txHash, err := chain.MintSoul(
 		ctx,
 		testHandle,
-		chain.C.PlatformAddress().Hex(), // Owner is the platform wallet for test
+		chain.C().PlatformAddress().Hex(), // Owner is the platform wallet for test
 		"https://ensoul.ac/default-avatar.png",
 		"A test soul created by the integration test script.",
 		1, // DNA version
@@ -114,7 +114,7 @@ func main() {
 
 	// Step 4: Read metadata (handle)
 	log.Printf("[6/8] Reading metadata 'ensoul:handle' for agentId=%s...", agentId.String())
-	handleMeta, err := chain.C.IdentityRegistry().GetMetadata(
+	handleMeta, err := chain.C().IdentityRegistry().GetMetadata(
 		&bind.CallOpts{Context: ctx},
 		agentId,
 		"ensoul:handle",
@@ -150,7 +150,7 @@ func main() {
 	// would fund Claw wallets or use a gas relay. For testing, we skip if balance is 0.
 	log.Println("[8/8] Attempting reputation feedback submission...")
 
-	balance, err := chain.C.EthClient().BalanceAt(ctx, chain.C.PlatformAddress(), nil)
+	balance, err := chain.C().EthClient().BalanceAt(ctx, chain.C().PlatformAddress(), nil)
 	if err != nil {
 		log.Printf("      ✗ Failed to check balance: %v", err)
 	} else {
@@ -159,14 +159,14 @@ func main() {
 
 	// Use the platform key as the feedback sender for the test
 	// (In production, each Claw has its own funded wallet)
-	if chain.C.HasPlatformKey() {
+	if chain.C().HasPlatformKey() {
 		log.Println("      Submitting feedback from platform wallet (test mode)...")
 
 		// We need to use the same pattern but with the platform key directly
 		var testHash [32]byte
 		feedbackTx, err := chain.SubmitFeedback(
 			ctx,
-			chain.C.PlatformKey(),
+			chain.C().PlatformKey(),
 			agentId,
 			85,                            // feedback value: 85%
 			"personality",                 // tag1
@@ -199,7 +199,7 @@ func main() {
 	log.Println()
 	log.Println("=== Integration Test Complete ===")
 	log.Printf("Soul: @%s (agentId=%s)", testHandle, agentId.String())
-	log.Printf("Chain: %s", chain.C.ChainID().String())
+	log.Printf("Chain: %s", chain.C().ChainID().String())
 
 	// Exit with proper code
 	if agentId != nil && agentId.Cmp(big.NewInt(0)) > 0 {
@@ -1,24 +1,29 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"crypto/ecdsa"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
-	"strings"
 	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ensoul-labs/ensoul-server/client"
 )
 
 // test_e2e is a comprehensive end-to-end test script that validates the full
-// Ensoul workflow via HTTP API calls. It covers three user flows:
+// Ensoul workflow through the Go API client. It covers three user flows:
 //
-//   Flow A — Creator: Mint a shell for a Twitter handle
-//   Flow B — Claw: Register agent, submit fragments, trigger ensouling
+//   Flow A — Creator: Preview and mint a shell for a Twitter handle
+//   Flow B — Claw: Register and claim an agent, submit a batch, follow its review
 //   Flow C — Visitor: Browse, view soul detail, chat
 //
+// A throwaway wallet signs the mint and claims the Claw. Minted shells stay
+// pending until confirmed on chain, so flows B and C work on the first soul
+// of the public list (seed one with cmd/devseed on an empty database).
+//
 // Usage:
 //   go run cmd/test_e2e/main.go [API_BASE]
 //
@@ -27,27 +32,35 @@ import (
 
 const defaultAPI = "http://localhost:8990"
 
-var apiBase string
 var passed, failed int
 
 func main() {
-	apiBase = defaultAPI
+	apiBase := defaultAPI
 	if len(os.Args) > 1 {
 		apiBase = os.Args[1]
 	}
+	api := client.New(apiBase, client.WithUserAgent("ensoul-e2e/1.0"))
+	ctx := context.Background()
+
+	wallet, err := crypto.GenerateKey()
+	if err != nil {
+		log.Fatalf("Failed to generate test wallet: %v", err)
+	}
+	walletAddr := crypto.PubkeyToAddress(wallet.PublicKey).Hex()
 
 	log.Printf("=== Ensoul E2E Test Suite ===")
 	log.Printf("API: %s", apiBase)
+	log.Printf("Wallet: %s", walletAddr)
 	log.Println()
 
 	// Verify server is reachable
 	check("Health check", func() error {
-		body, err := get("/api/health")
+		health, err := api.Health(ctx)
 		if err != nil {
 			return err
 		}
-		if !strings.Contains(body, "ok") {
-			return fmt.Errorf("unexpected health response: %s", body)
+		if health.Status != "ok" {
+			return fmt.Errorf("unexpected health status: %q", health.Status)
 		}
 		return nil
 	})
@@ -60,237 +73,215 @@ func main() {
 	testHandle := fmt.Sprintf("test_%d", time.Now().Unix())
 
 	// A1: Preview seed extraction
-	var previewResult map[string]interface{}
+	var preview *client.SeedPreview
 	check("A1: Preview seed extraction", func() error {
-		body, err := post("/api/shell/preview", map[string]string{
-			"handle": testHandle,
-		})
+		p, err := api.Preview(ctx, testHandle)
 		if err != nil {
 			return err
 		}
-		if err := json.Unmarshal([]byte(body), &previewResult); err != nil {
-			return fmt.Errorf("invalid JSON: %v", err)
-		}
-		if previewResult["handle"] == nil {
+		if p.Handle == "" {
 			return fmt.Errorf("missing handle in response")
 		}
+		preview = p
 		return nil
 	})
 
-	// A2: Mint shell
-	var shellResult map[string]interface{}
+	// A2: Mint shell (pending until the mint tx is confirmed)
 	check("A2: Mint shell", func() error {
-		body, err := post("/api/shell/mint", map[string]interface{}{
-			"handle":     testHandle,
-			"owner_addr": "0x0000000000000000000000000000000000000001",
+		if preview == nil {
+			return fmt.Errorf("no preview to mint")
+		}
+		shell, err := api.Mint(ctx, preview, client.WalletSignature{
+			Address:   walletAddr,
+			Signature: personalSign(wallet, client.MintMessage(preview.Handle)),
 		})
 		if err != nil {
 			return err
 		}
-		if err := json.Unmarshal([]byte(body), &shellResult); err != nil {
-			return fmt.Errorf("invalid JSON: %v", err)
-		}
-		stage, _ := shellResult["stage"].(string)
-		if stage != "embryo" {
-			return fmt.Errorf("expected stage=embryo, got %q", stage)
+		if shell.Stage != "pending" {
+			return fmt.Errorf("expected stage=pending, got %q", shell.Stage)
 		}
 		return nil
 	})
 
-	// A3: Get shell by handle
-	check("A3: Get shell by handle", func() error {
-		body, err := get("/api/shell/" + testHandle)
-		if err != nil {
-			return err
-		}
-		var shell map[string]interface{}
-		if err := json.Unmarshal([]byte(body), &shell); err != nil {
-			return fmt.Errorf("invalid JSON: %v", err)
+	// A3: Unconfirmed shells are not public
+	check("A3: Get unconfirmed shell (→ not found)", func() error {
+		_, err := api.Shell(ctx, testHandle)
+		if err == nil {
+			return fmt.Errorf("expected SHELL_NOT_FOUND for a pending shell, but succeeded")
 		}
-		handle, _ := shell["handle"].(string)
-		if handle != testHandle {
-			return fmt.Errorf("expected handle=%q, got %q", testHandle, handle)
+		if !client.IsCode(err, client.CodeShellNotFound) {
+			return fmt.Errorf("unexpected error: %v", err)
 		}
 		return nil
 	})
 
-	// A4: Get dimensions
-	check("A4: Get dimensions", func() error {
-		_, err := get("/api/shell/" + testHandle + "/dimensions")
-		return err
-	})
-
-	// A5: Get history
-	check("A5: Get history", func() error {
-		_, err := get("/api/shell/" + testHandle + "/history")
-		return err
-	})
-
-	// A6: Shell list
-	check("A6: Shell list", func() error {
-		body, err := get("/api/shell/list")
+	// A4: Shell list — flows B and C use its first soul
+	var soulHandle string
+	check("A4: Shell list", func() error {
+		list, err := api.Shells(ctx, client.ShellQuery{})
 		if err != nil {
 			return err
 		}
-		var result map[string]interface{}
-		if err := json.Unmarshal([]byte(body), &result); err != nil {
-			return fmt.Errorf("invalid JSON: %v", err)
-		}
-		shells, ok := result["shells"].([]interface{})
-		if !ok || len(shells) == 0 {
-			return fmt.Errorf("expected non-empty shells list")
+		if len(list.Shells) == 0 {
+			return fmt.Errorf("expected non-empty shells list (run cmd/devseed)")
 		}
+		soulHandle = list.Shells[0].Handle
+		log.Printf("      Using soul: @%s", soulHandle)
 		return nil
 	})
 
-	// A7: Shell list with stage filter
-	check("A7: Shell list with stage filter", func() error {
-		_, err := get("/api/shell/list?stage=embryo")
+	if soulHandle != "" {
+		// A5: Get shell by handle
+		check("A5: Get shell by handle", func() error {
+			shell, err := api.Shell(ctx, soulHandle)
+			if err != nil {
+				return err
+			}
+			if shell.Handle != soulHandle {
+				return fmt.Errorf("expected handle=%q, got %q", soulHandle, shell.Handle)
+			}
+			return nil
+		})
+
+		// A6: Get dimensions
+		check("A6: Get dimensions", func() error {
+			_, err := api.Dimensions(ctx, soulHandle)
+			return err
+		})
+
+		// A7: Get history
+		check("A7: Get history", func() error {
+			_, err := api.History(ctx, soulHandle)
+			return err
+		})
+	}
+
+	// A8: Shell list with stage filter
+	check("A8: Shell list with stage filter", func() error {
+		_, err := api.Shells(ctx, client.ShellQuery{Stage: "embryo"})
 		return err
 	})
 
 	// ============================================================
-	// FLOW B: Claw — Register, Submit Fragments
+	// FLOW B: Claw — Register, Claim, Submit Fragments
 	// ============================================================
 	section("FLOW B: Claw")
 
-	// B1: Register Claw
-	var clawKey string
-	var claimCode string
+	// B1: Register Claw (the client solves the proof-of-work challenge)
+	var reg *client.Registration
 	check("B1: Register Claw", func() error {
-		body, err := post("/api/claw/register", map[string]string{
-			"name":        "E2E Test Agent",
-			"description": "An automated test agent for E2E testing.",
+		r, err := api.Register(ctx, client.RegisterRequest{
+			Name:        fmt.Sprintf("e2e-agent-%d", time.Now().Unix()),
+			Description: "An automated test agent for E2E testing.",
 		})
 		if err != nil {
 			return err
 		}
-		var result map[string]interface{}
-		if err := json.Unmarshal([]byte(body), &result); err != nil {
-			return fmt.Errorf("invalid JSON: %v", err)
-		}
-		claw, ok := result["claw"].(map[string]interface{})
-		if !ok {
-			return fmt.Errorf("missing claw in response")
-		}
-		clawKey, _ = claw["api_key"].(string)
-		if clawKey == "" {
+		if r.Claw.APIKey == "" {
 			return fmt.Errorf("missing api_key")
 		}
-		claimURL, _ := claw["claim_url"].(string)
-		if claimURL != "" {
-			// Extract claim code from URL (last path segment)
-			parts := strings.Split(claimURL, "/")
-			claimCode = parts[len(parts)-1]
-		}
+		reg = r
+		api.SetAPIKey(r.Claw.APIKey)
 		return nil
 	})
+	if reg == nil {
+		finish()
+	}
 
 	// B2: Check Claw status (should be pending)
 	check("B2: Claw status (unclaimed)", func() error {
-		body, err := authGet("/api/claw/status", clawKey)
+		status, err := api.Status(ctx)
 		if err != nil {
 			return err
 		}
-		if !strings.Contains(body, "pending_claim") {
-			return fmt.Errorf("expected pending_claim status, got: %s", body)
+		if status.Status != "pending_claim" {
+			return fmt.Errorf("expected pending_claim status, got %q", status.Status)
 		}
 		return nil
 	})
 
-	// B3: Submit fragment (should fail — not claimed)
-	check("B3: Fragment submit (unclaimed → rejected)", func() error {
-		_, err := authPost("/api/fragment/submit", clawKey, map[string]string{
-			"handle":    testHandle,
-			"dimension": "personality",
-			"content":   "Test fragment from unclaimed claw — should be rejected.",
-		})
+	// B3: Submit a batch (should fail — not claimed)
+	check("B3: Batch submit (unclaimed → rejected)", func() error {
+		_, err := api.SubmitBatch(ctx, testHandle, batchItems([]string{"personality", "stance", "style"}))
 		if err == nil {
 			return fmt.Errorf("expected error for unclaimed claw, but succeeded")
 		}
-		if !strings.Contains(err.Error(), "403") && !strings.Contains(err.Error(), "claimed") {
+		if !client.IsCode(err, client.CodeClawNotClaimed) {
 			return fmt.Errorf("unexpected error: %v", err)
 		}
 		return nil
 	})
 
-	// B4: Claim verify (mock — will fail without real tweet, but tests the endpoint)
-	check("B4: Claim verify endpoint exists", func() error {
-		_, err := post("/api/claw/claim/verify", map[string]string{
-			"claim_code": claimCode,
-			"tweet_url":  "https://twitter.com/test/status/123",
-		})
-		// We expect this to either succeed (simple verification) or fail gracefully
-		// The important thing is the endpoint exists and returns a proper response
-		_ = err
-		return nil
+	// B4: Claim with the test wallet
+	check("B4: Claim Claw with wallet session", func() error {
+		message := client.LoginMessage(time.Now())
+		if err := api.Login(ctx, walletAddr, personalSign(wallet, message), message); err != nil {
+			return fmt.Errorf("login: %w", err)
+		}
+		_, err := api.ClaimClaw(ctx, reg.ClaimCode(), "")
+		return err
 	})
 
-	// B5: For the test, we need a claimed claw. Let's check if B4 succeeded,
-	//     otherwise we'll try the claw status check
+	// B5: Check the claim went through
 	var clawClaimed bool
 	check("B5: Check if Claw is claimed", func() error {
-		body, err := authGet("/api/claw/status", clawKey)
+		status, err := api.Status(ctx)
 		if err != nil {
 			return err
 		}
-		clawClaimed = strings.Contains(body, `"claimed":true`)
+		clawClaimed = status.Claimed
 		log.Printf("      Claw claimed: %v", clawClaimed)
 		return nil
 	})
 
-	// B6-B10: Fragment submissions (only if claimed)
-	if clawClaimed {
-		dimensions := []string{"personality", "knowledge", "stance", "style", "relationship", "timeline"}
-		for i, dim := range dimensions {
-			idx := i
-			d := dim
-			check(fmt.Sprintf("B6.%d: Submit fragment (%s)", idx+1, d), func() error {
-				body, err := authPost("/api/fragment/submit", clawKey, map[string]string{
-					"handle":    testHandle,
-					"dimension": d,
-					"content":   fmt.Sprintf("E2E test fragment #%d for dimension %s. This is a synthetic fragment generated during automated testing to verify the full contribution pipeline works correctly. The content is intentionally detailed to pass the minimum length requirements.", idx+1, d),
+	// B6-B9: Batch submission (only if claimed and there is a soul)
+	if clawClaimed && soulHandle != "" {
+		var batchID string
+		check("B6: Submit batch", func() error {
+			res, err := api.SubmitBatch(ctx, soulHandle, batchItems([]string{"personality", "knowledge", "stance", "style", "relationship", "timeline"}))
+			if err != nil {
+				return err
+			}
+			batchID = res.BatchID
+			for _, f := range res.Fragments {
+				log.Printf("      %s: %s", f.Dimension, f.Status)
+			}
+			return nil
+		})
+
+		if batchID != "" {
+			check("B7: Stream batch review", func() error {
+				streamCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+				defer cancel()
+				review, err := api.StreamBatch(streamCtx, batchID, func(v client.Verdict) {
+					log.Printf("      verdict %s: %s (%.2f)", v.Dimension, v.Status, v.Confidence)
 				})
 				if err != nil {
 					return err
 				}
-				var result map[string]interface{}
-				if err := json.Unmarshal([]byte(body), &result); err != nil {
-					return fmt.Errorf("invalid JSON: %v", err)
-				}
-				status, _ := result["status"].(string)
-				log.Printf("      Fragment status: %s", status)
+				log.Printf("      Accepted %d, rejected %d, timed out: %v", review.Accepted, review.Rejected, review.TimedOut)
 				return nil
 			})
 		}
 
-		// B7: Check dashboard
-		check("B7: Claw dashboard", func() error {
-			body, err := authGet("/api/claw/dashboard", clawKey)
+		// B8: Check dashboard
+		check("B8: Claw dashboard", func() error {
+			dash, err := api.Dashboard(ctx)
 			if err != nil {
 				return err
 			}
-			var result map[string]interface{}
-			if err := json.Unmarshal([]byte(body), &result); err != nil {
-				return fmt.Errorf("invalid JSON: %v", err)
-			}
-			overview, ok := result["overview"].(map[string]interface{})
-			if !ok {
-				return fmt.Errorf("missing overview in dashboard")
-			}
-			submitted, _ := overview["total_submitted"].(float64)
-			log.Printf("      Total submitted: %.0f", submitted)
+			log.Printf("      Total submitted: %d", dash.Overview.TotalSubmitted)
 			return nil
 		})
 
-		// B8: Check contributions
-		check("B8: Claw contributions", func() error {
-			_, err := authGet("/api/claw/contributions", clawKey)
+		// B9: Check contributions
+		check("B9: Claw contributions", func() error {
+			_, err := api.Contributions(ctx, client.ContributionQuery{})
 			return err
 		})
 	} else {
-		log.Println("  ⚠ Skipping fragment tests (Claw not claimed)")
-		log.Println("    To test full flow, manually claim the Claw via Twitter")
+		log.Println("  ⚠ Skipping fragment tests (Claw not claimed or no soul to contribute to)")
 	}
 
 	// ============================================================
@@ -300,85 +291,64 @@ func main() {
 
 	// C1: Global stats
 	check("C1: Global stats", func() error {
-		body, err := get("/api/stats")
+		stats, err := api.Stats(ctx)
 		if err != nil {
 			return err
 		}
-		var stats map[string]interface{}
-		if err := json.Unmarshal([]byte(body), &stats); err != nil {
-			return fmt.Errorf("invalid JSON: %v", err)
-		}
-		if _, ok := stats["souls"]; !ok {
-			return fmt.Errorf("missing 'souls' in stats")
-		}
+		log.Printf("      Souls: %d, fragments: %d", stats.Souls, stats.Fragments)
 		return nil
 	})
 
 	// C2: Task board
 	check("C2: Task board", func() error {
-		_, err := get("/api/tasks")
+		_, err := api.Tasks(ctx, client.TaskQuery{})
 		return err
 	})
 
 	// C3: Fragment list
 	check("C3: Fragment list (global)", func() error {
-		_, err := get("/api/fragment/list")
+		_, err := api.Fragments(ctx, client.FragmentQuery{})
 		return err
 	})
 
-	// C4: Fragment list by handle
-	check("C4: Fragment list by handle", func() error {
-		_, err := get("/api/fragment/list?handle=" + testHandle)
-		return err
-	})
-
-	// C5: Chat with soul
-	check("C5: Chat with soul (SSE)", func() error {
-		body, err := post("/api/chat/"+testHandle, map[string]string{
-			"message": "Hello, who are you?",
-		})
-		if err != nil {
+	if soulHandle != "" {
+		// C4: Fragment list by handle
+		check("C4: Fragment list by handle", func() error {
+			_, err := api.Fragments(ctx, client.FragmentQuery{Handle: soulHandle})
 			return err
-		}
-		if len(body) == 0 {
-			return fmt.Errorf("empty chat response")
-		}
-		// SSE responses should contain "data:" lines
-		log.Printf("      Response length: %d bytes", len(body))
-		return nil
-	})
+		})
 
-	// C6: Shell search
-	check("C6: Shell search", func() error {
-		body, err := get("/api/shell/list?search=" + testHandle[:8])
-		if err != nil {
-			return err
-		}
-		var result map[string]interface{}
-		if err := json.Unmarshal([]byte(body), &result); err != nil {
-			return fmt.Errorf("invalid JSON: %v", err)
-		}
-		shells, ok := result["shells"].([]interface{})
-		if !ok || len(shells) == 0 {
-			return fmt.Errorf("search should find the test shell")
-		}
-		return nil
-	})
+		// C5: Chat with soul
+		check("C5: Chat with soul (SSE)", func() error {
+			session, err := api.StartChat(ctx, soulHandle, 0)
+			if err != nil {
+				return err
+			}
+			reply, err := api.Chat(ctx, session.SessionID, "Hello, who are you?")
+			if err != nil {
+				return err
+			}
+			if reply.Text == "" {
+				return fmt.Errorf("empty chat response")
+			}
+			log.Printf("      Reply length: %d bytes, %d citations", len(reply.Text), len(reply.Citations))
+			return nil
+		})
 
-	// ============================================================
-	// Summary
-	// ============================================================
-	log.Println()
-	log.Println("=== E2E Test Summary ===")
-	log.Printf("Passed: %d", passed)
-	log.Printf("Failed: %d", failed)
-	log.Printf("Total:  %d", passed+failed)
-	if failed > 0 {
-		log.Println("Result: FAIL ✗")
-		os.Exit(1)
+		// C6: Shell search
+		check("C6: Shell search", func() error {
+			list, err := api.Shells(ctx, client.ShellQuery{Search: soulHandle})
+			if err != nil {
+				return err
+			}
+			if len(list.Shells) == 0 {
+				return fmt.Errorf("search should find @%s", soulHandle)
+			}
+			return nil
+		})
 	}
-	log.Println("Result: PASS ✓")
-	os.Exit(0)
+
+	finish()
 }
 
 // --- Helpers ---
@@ -399,61 +369,41 @@ func check(name string, fn func() error) {
 	}
 }
 
-func get(path string) (string, error) {
-	resp, err := http.Get(apiBase + path)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %v", err)
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 400 {
-		return string(body), fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
-	}
-	return string(body), nil
-}
-
-func post(path string, data interface{}) (string, error) {
-	jsonData, _ := json.Marshal(data)
-	resp, err := http.Post(apiBase+path, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("request failed: %v", err)
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 400 {
-		return string(body), fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+// finish prints the summary and exits.
+func finish() {
+	log.Println()
+	log.Println("=== E2E Test Summary ===")
+	log.Printf("Passed: %d", passed)
+	log.Printf("Failed: %d", failed)
+	log.Printf("Total:  %d", passed+failed)
+	if failed > 0 {
+		log.Println("Result: FAIL ✗")
+		os.Exit(1)
 	}
-	return string(body), nil
+	log.Println("Result: PASS ✓")
+	os.Exit(0)
 }
 
-func authGet(path string, apiKey string) (string, error) {
-	req, _ := http.NewRequest("GET", apiBase+path, nil)
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %v", err)
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 400 {
-		return string(body), fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+// batchItems builds one synthetic fragment per dimension.
+func batchItems(dims []string) []client.BatchItem {
+	items := make([]client.BatchItem, len(dims))
+	for i, d := range dims {
+		items[i] = client.BatchItem{
+			Dimension: d,
+			Content: fmt.Sprintf("E2E test fragment #%d for dimension %s. This is a synthetic fragment generated during automated testing to verify the full contribution pipeline works correctly. The content is intentionally detailed to pass the minimum length requirements.",
+				i+1, d),
+		}
 	}
-	return string(body), nil
+	return items
 }
 
-func authPost(path string, apiKey string, data interface{}) (string, error) {
-	jsonData, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", apiBase+path, bytes.NewBuffer(jsonData))
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+// personalSign signs message the way wallets do for personal_sign.
+func personalSign(key *ecdsa.PrivateKey, message string) string {
+	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)
+	sig, err := crypto.Sign(crypto.Keccak256([]byte(prefixed)), key)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %v", err)
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 400 {
-		return string(body), fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		log.Fatalf("Signing failed: %v", err)
 	}
-	return string(body), nil
+	sig[64] += 27
+	return fmt.Sprintf("0x%x", sig)
 }
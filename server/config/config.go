@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -14,8 +16,16 @@ type Config struct {
 	Port     string
 	Env      string // "production" or "development"
 	LogLevel string // "debug", "info", "warn", "error"
+	Fixtures bool   // Seed development fixtures on startup and run without the chain
+
+	// Public URLs
+	CORSAllowedOrigins []string // Origins allowed to call the API from a browser
+	PublicBaseURL      string   // Frontend origin used in on-chain links, agent cards and share URLs
+	ClaimURLPrefix     string   // Prefix for Claw claim links (relative or absolute)
 
 	// Database
+	DBDriver   string // "postgres" or "sqlite"
+	DBPath     string // SQLite database file (DB_DRIVER=sqlite)
 	DBHost     string
 	DBPort     string
 	DBUser     string
@@ -23,25 +33,174 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 
+	// Read replica for heavy public listings (optional)
+	DBReplicaURL           string // postgres:// URL; empty = all reads on the primary
+	DBReplicaMaxLagSeconds int    // Replication lag above which reads fall back to the primary
+	DBSlowQueryMs          int    // Queries at least this slow are logged (0 = off)
+
 	// Blockchain
 	BSCRPCURL              string
+	BSCRPCFallbackURLs     []string // Further RPC endpoints, in order of preference, used while BSCRPCURL is down or lagging
+	ChainRPCMaxLagBlocks   int      // An endpoint this many blocks behind the best one counts as unhealthy
 	IdentityRegistryAddr   string
 	ReputationRegistryAddr string
 	PrivateKey             string // Platform wallet private key for Soul minting
 	ClawPKSecret           string // AES key for encrypting Claw private keys
+	PreviewSigningSecret   string // HMAC key for mint previews ("" = random per process)
+	CustodialMint          bool   // Platform wallet mints on behalf of users and transfers the NFT to them
+	TxWatchTimeoutMinutes  int    // Watched transactions not mined within this time are given up
+	IPFSGateway            string // Gateway used to fetch ipfs:// agentURIs when importing agents
+	ExplorerURL            string // Block explorer base URL for transaction links
+	ReputationCacheSeconds int    // How long a soul's on-chain reputation summary is cached
+	MulticallAddr          string // Multicall3 contract used to batch chain reads ("" = JSON-RPC batches)
+	MulticallBatchSize     int    // Max calls per batched read request
+	ChainReadCacheSeconds  int    // How long batched soul owner/URI reads are cached (0 = no cache)
 
 	// LLM
-	LLMProvider string // "openai" or "claude"
+	LLMProvider string // "openai", "claude" or "mock"
 	LLMAPIKey   string
 	LLMModel    string
 	LLMBaseURL  string // Custom base URL for OpenAI-compatible APIs
 
+	// Per-shell chat models
+	LLMChatModels []string // Models a soul's chat may be switched to, as "model" or "model|input_per_1m|output_per_1m" (USD)
+
+	// Mock LLM (LLM_PROVIDER=mock)
+	LLMMockSeed          int // Seed of the mock's canned output
+	LLMMockStreamDelayMs int // Delay between streamed words of a mock chat reply
+
+	// LLM timeouts
+	LLMTimeoutSeconds       int // Max duration of a non-streaming LLM call (0 = no limit)
+	LLMStreamTimeoutSeconds int // Max duration of a streamed chat reply (0 = no limit)
+
+	// LLM usage accounting
+	LLMPriceInputPer1M     float64 // USD per 1M prompt tokens, for cost estimates
+	LLMPriceOutputPer1M    float64 // USD per 1M completion tokens, for cost estimates
+	LLMShellDailyBudgetUSD float64 // Max estimated LLM spend per shell per 24h before chat pauses (0 = unlimited)
+
+	// Embeddings / retrieval
+	EmbeddingModel     string   // OpenAI-compatible embedding model (ignored for local hashing)
+	ChatRetrievalTiers []string // Chat tiers that get fragment retrieval (RAG) context
+	ChatRetrievalTopK  int      // Number of fragments retrieved per user message
+
+	// Chat context budget (history tokens per tier, rolling summary included)
+	ChatHistoryTokensGuest int
+	ChatHistoryTokensFree  int
+	ChatHistoryTokensPaid  int
+	ChatSummaryEveryRounds int // Also fold turns into the summary every N rounds, keeping the newest N verbatim (0 = only when over budget)
+
+	// Chat rounds per wallet (or IP for guests), soul and UTC day by tier (0 = unlimited)
+	ChatDailyRoundsGuest int
+	ChatDailyRoundsFree  int
+	ChatDailyRoundsPaid  int
+	ChatPlatformFeeBps   int // Platform fee on chat round bundles owners sell, in basis points
+
+	// Chat moderation of user messages
+	ChatModeration           string // "off", "heuristic", "llm" or "provider" (OpenAI-compatible /moderations)
+	ChatModerationMaxStrikes int    // Blocked messages after which a session is closed (0 = never)
+
+	// Safety scan of soul prompts produced by ensouling
+	EnsoulingScan string // "off", "heuristic" or "llm"; flagged versions wait for admin approval
+
+	// Partial ensouling: new fragments in one dimension that rewrite just its prompt block
+	EnsoulingDimensionThreshold int // 0 = off
+
+	// Ensouling gate: a batch that reached its threshold is condensed only if
+	// its expected improvement scores at least EnsoulingGateMinScore
+	EnsoulingGateMinScore   float64 // 0-1; 0 = off
+	EnsoulingGateMaxBacklog int     // multiple of the threshold at which a batch is condensed regardless
+
+	// Souls per wallet (minted or imported); allowlisted wallets get their own quota
+	WalletMintQuota int // 0 = unlimited
+
+	// Verified subjects (the person behind a soul's handle)
+	SubjectRevenueShareBps int // Subject's share of paid licenses, in basis points (0 = none)
+
+	// Claw registration hardening (anti-sybil)
+	ClawRegisterIPDailyCap     int     // Max Claw registrations per IP per 24h (0 = unlimited)
+	ClawWalletMaxClaws         int     // Max Claws a single wallet may claim (0 = unlimited)
+	ClawRegisterVerifier       string  // "" (none) | "pow" | "captcha"
+	ClawRegisterRequireWallet  bool    // Require an operator wallet signature at registration
+	ClawPoWDifficulty          int     // Leading zero bits required by the proof-of-work verifier
+	CaptchaVerifyURL           string  // siteverify endpoint (Turnstile / hCaptcha / reCAPTCHA compatible)
+	CaptchaSecret              string  // Server-side CAPTCHA secret
+	ClawProbationFragments     int     // A new Claw's first N fragments face stricter curation
+	ClawProbationMinConfidence float64 // Minimum curator confidence to accept a probation fragment
+	ClawShellDailyBatches      int     // Max fragment batches a Claw may send one soul per 24h at full trust (0 = unlimited)
+	ClawAppealModel            string  // Model for second-opinion appeal reviews ("" = LLM_MODEL)
+	ClawAppealFreeFrivolous    int     // Frivolous appeals a Claw may make before its trust score drops
+	ClawAppealTrustPenalty     int     // Trust score lost per frivolous appeal beyond the free allowance
+	PlagiarismSimilarity       float64 // Cosine similarity to another Claw's accepted fragment that auto-rejects a submission (0 = hash matches only)
+	PlagiarismFreeStrikes      int     // Plagiarism rejections a Claw may collect before its trust score drops
+	PlagiarismTrustPenalty     int     // Trust score lost per plagiarism rejection beyond the free strikes
+	ClawTwitterTrustBonus      int     // Trust score added once when a Claw's operator verifies by tweet
+	ClawActiveWindowMinutes    int     // A Claw counts as active if it sent a heartbeat within this window
+	ClawDeletePolicy           string  // "anonymize" (keep fragments, scrub the Claw) or "cascade" (delete its fragments)
+	ChatGuestRetentionDays     int     // Guest chat sessions idle longer than this are purged (0 = keep forever)
+	ChatMaxMessageChars        int     // Longest chat message accepted, in characters
+	FragmentArchiveDays        int     // Rejected fragments older than this move to the compressed archive (0 = never)
+
+	// Gas drip budget
+	GasDripClawDailyCap    int     // Max drips per Claw per 24h (0 = unlimited)
+	GasDripClawLifetimeCap int     // Max drips per Claw ever (0 = unlimited)
+	GasDripHourlyCeiling   float64 // Max BNB dripped platform-wide per hour (0 = unlimited)
+	GasLowBalanceAlert     float64 // Platform wallet balance (BNB) below which alerts are raised
+
+	// Chain spend tracking
+	ChainSpendDailyAlert float64 // Gas spent platform-wide in 24h (BNB) above which alerts are raised (0 = no alert)
+
+	// Public developer API (/v1): read-only keys issued to wallets
+	DevAPIMaxKeys       int // Max active keys per wallet (0 = unlimited)
+	DevAPIDailyRequests int // Default requests per key per UTC day (0 = unlimited)
+	DevAPIDailyChats    int // Default chat completions per key per UTC day (0 = unlimited)
+
+	// Rate limiting
+	RateLimitStore string // "memory" (per process, default) or "redis" (shared by all replicas)
+	RedisURL       string // redis://[user:password@]host:port[/db] for the redis store
+
+	// Admin
+	AdminWallets []string // Wallet addresses allowed to access /api/admin
+	MetricsToken string   // Bearer token for GET /metrics ("" = endpoint disabled)
+
+	// HTTP
+	ResponseCompression bool // gzip JSON / text responses for clients that accept it
+	RequestMaxBodyKB    int  // Largest request body on routes without their own limit (0 = unlimited)
+	RequestMaxJSONDepth int  // Deepest object / array nesting accepted in JSON bodies (0 = unlimited)
+
+	// Maintenance
+	MaintenanceMode    bool   // Start read-only: writes get 503 until an admin turns it off
+	MaintenanceMessage string // Shown to clients while read-only
+
+	// Media proxy (cached avatars / banners)
+	MediaStorage      string // "local" (default) or "s3"
+	MediaDir          string // Local storage directory
+	MediaS3Endpoint   string // S3-compatible endpoint, e.g. https://s3.us-east-1.amazonaws.com or an R2/MinIO URL
+	MediaS3Bucket     string
+	MediaS3Region     string
+	MediaS3AccessKey  string
+	MediaS3SecretKey  string
+	MediaRefreshHours int // Re-download cached images older than this
+
 	// Twitter (for seed extraction)
 	TwitterBearerToken string
 
 	// SocialData API (primary Twitter data source)
 	SocialDataAPIKey  string
 	SocialDataBaseURL string // default: https://api.socialdata.tools
+
+	// Seed refresh (new tweets → candidate fragments)
+	SeedRefreshIntervalHours int // Scheduled refresh re-checks a soul at most this often
+	SeedRefreshMinChats      int // Only souls with at least this many chats are refreshed on schedule
+	SeedRefreshBatch         int // Max souls refreshed per scheduled run (0 = scheduled refresh off)
+	SeedRefreshCooldownHours int // Minimum gap between manual refreshes of a soul
+
+	// Owner notifications (a channel is off until configured)
+	SMTPHost         string
+	SMTPPort         int
+	SMTPUsername     string
+	SMTPPassword     string
+	SMTPFrom         string // sender address, e.g. "Ensoul <noreply@ensoul.ac>"
+	TelegramBotToken string
 }
 
 // Global config instance
@@ -53,27 +212,131 @@ func Load() *Config {
 	_ = godotenv.Load()
 
 	cfg := &Config{
-		Port:                   getEnv("PORT", "8990"),
-		Env:                    getEnv("ENV", "development"),
-		LogLevel:               getEnv("LOG_LEVEL", ""), // auto-set below
-		DBHost:                 getEnv("DB_HOST", "localhost"),
-		DBPort:                 getEnv("DB_PORT", "5432"),
-		DBUser:                 getEnv("DB_USER", "ensoul"),
-		DBPassword:             getEnv("DB_PASSWORD", "ensoul"),
-		DBName:                 getEnv("DB_NAME", "ensoul"),
-		DBSSLMode:              getEnv("DB_SSLMODE", "disable"),
-		BSCRPCURL:              getEnv("BSC_RPC_URL", "https://bsc-dataseed.binance.org/"),
-		IdentityRegistryAddr:   getEnv("IDENTITY_REGISTRY_ADDR", "0x8004A169FB4a3325136EB29fA0ceB6D2e539a432"),
-		ReputationRegistryAddr: getEnv("REPUTATION_REGISTRY_ADDR", "0x8004BAa17C55a88189AE136b182e5fdA19dE9b63"),
-		PrivateKey:             getEnv("PLATFORM_PRIVATE_KEY", ""),
-		ClawPKSecret:           getEnv("CLAW_PK_SECRET", ""),
-		LLMProvider:            getEnv("LLM_PROVIDER", "openai"),
-		LLMAPIKey:              getEnv("LLM_API_KEY", ""),
-		LLMModel:               getEnv("LLM_MODEL", "gpt-4o"),
-		LLMBaseURL:             getEnv("LLM_BASE_URL", ""),
-		TwitterBearerToken:     getEnv("TWITTER_BEARER_TOKEN", ""),
-		SocialDataAPIKey:       getEnv("SOCIALDATA_API_KEY", ""),
-		SocialDataBaseURL:      getEnv("SOCIALDATA_BASE_URL", ""),
+		Port:                        getEnv("PORT", "8990"),
+		Env:                         getEnv("ENV", "development"),
+		LogLevel:                    getEnv("LOG_LEVEL", ""), // auto-set below
+		Fixtures:                    getEnv("FIXTURES", "false") == "true",
+		CORSAllowedOrigins:          getEnvList("CORS_ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:3410,https://ensoul.ac,https://www.ensoul.ac"),
+		PublicBaseURL:               strings.TrimRight(getEnv("PUBLIC_BASE_URL", "https://ensoul.ac"), "/"),
+		ClaimURLPrefix:              getEnv("CLAIM_URL_PREFIX", "/claim/"),
+		DBDriver:                    strings.ToLower(getEnv("DB_DRIVER", "postgres")),
+		DBPath:                      getEnv("DB_PATH", "ensoul.db"),
+		DBHost:                      getEnv("DB_HOST", "localhost"),
+		DBPort:                      getEnv("DB_PORT", "5432"),
+		DBUser:                      getEnv("DB_USER", "ensoul"),
+		DBPassword:                  getEnv("DB_PASSWORD", "ensoul"),
+		DBName:                      getEnv("DB_NAME", "ensoul"),
+		DBSSLMode:                   getEnv("DB_SSLMODE", "disable"),
+		DBReplicaURL:                getEnv("DB_REPLICA_URL", ""),
+		DBReplicaMaxLagSeconds:      getEnvInt("DB_REPLICA_MAX_LAG_SECONDS", 30),
+		DBSlowQueryMs:               getEnvInt("DB_SLOW_QUERY_MS", 200),
+		BSCRPCURL:                   getEnv("BSC_RPC_URL", "https://bsc-dataseed.binance.org/"),
+		BSCRPCFallbackURLs:          getEnvList("BSC_RPC_FALLBACK_URLS", ""),
+		ChainRPCMaxLagBlocks:        getEnvInt("CHAIN_RPC_MAX_LAG_BLOCKS", 20),
+		IdentityRegistryAddr:        getEnv("IDENTITY_REGISTRY_ADDR", "0x8004A169FB4a3325136EB29fA0ceB6D2e539a432"),
+		ReputationRegistryAddr:      getEnv("REPUTATION_REGISTRY_ADDR", "0x8004BAa17C55a88189AE136b182e5fdA19dE9b63"),
+		PrivateKey:                  getEnv("PLATFORM_PRIVATE_KEY", ""),
+		ClawPKSecret:                getEnv("CLAW_PK_SECRET", ""),
+		PreviewSigningSecret:        getEnv("PREVIEW_SIGNING_SECRET", ""),
+		CustodialMint:               getEnv("CUSTODIAL_MINT", "false") == "true",
+		TxWatchTimeoutMinutes:       getEnvInt("TX_WATCH_TIMEOUT_MINUTES", 10),
+		IPFSGateway:                 getEnv("IPFS_GATEWAY", "https://ipfs.io/ipfs/"),
+		ExplorerURL:                 getEnv("EXPLORER_URL", "https://bscscan.com"),
+		ReputationCacheSeconds:      getEnvInt("REPUTATION_CACHE_SECONDS", 300),
+		MulticallAddr:               getEnv("MULTICALL_ADDR", "0xcA11bde05977b3631167028862bE2a173976CA11"),
+		MulticallBatchSize:          getEnvInt("MULTICALL_BATCH_SIZE", 200),
+		ChainReadCacheSeconds:       getEnvInt("CHAIN_READ_CACHE_SECONDS", 60),
+		LLMProvider:                 getEnv("LLM_PROVIDER", "openai"),
+		LLMAPIKey:                   getEnv("LLM_API_KEY", ""),
+		LLMModel:                    getEnv("LLM_MODEL", "gpt-4o"),
+		LLMChatModels:               getEnvList("LLM_CHAT_MODELS", ""),
+		LLMBaseURL:                  getEnv("LLM_BASE_URL", ""),
+		LLMMockSeed:                 getEnvInt("LLM_MOCK_SEED", 1),
+		LLMMockStreamDelayMs:        getEnvInt("LLM_MOCK_STREAM_DELAY_MS", 30),
+		LLMTimeoutSeconds:           getEnvInt("LLM_TIMEOUT_SECONDS", 90),
+		LLMStreamTimeoutSeconds:     getEnvInt("LLM_STREAM_TIMEOUT_SECONDS", 180),
+		LLMPriceInputPer1M:          getEnvFloat("LLM_PRICE_INPUT_PER_1M", 2.5),
+		LLMPriceOutputPer1M:         getEnvFloat("LLM_PRICE_OUTPUT_PER_1M", 10),
+		LLMShellDailyBudgetUSD:      getEnvFloat("LLM_SHELL_DAILY_BUDGET_USD", 0),
+		EmbeddingModel:              getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
+		ChatRetrievalTiers:          getEnvList("CHAT_RETRIEVAL_TIERS", "free,paid"),
+		ChatRetrievalTopK:           getEnvInt("CHAT_RETRIEVAL_TOP_K", 5),
+		ChatHistoryTokensGuest:      getEnvInt("CHAT_HISTORY_TOKENS_GUEST", 2000),
+		ChatHistoryTokensFree:       getEnvInt("CHAT_HISTORY_TOKENS_FREE", 6000),
+		ChatHistoryTokensPaid:       getEnvInt("CHAT_HISTORY_TOKENS_PAID", 16000),
+		ChatSummaryEveryRounds:      getEnvInt("CHAT_SUMMARY_EVERY_ROUNDS", 10),
+		ChatDailyRoundsGuest:        getEnvInt("CHAT_DAILY_ROUNDS_GUEST", 5),
+		ChatDailyRoundsFree:         getEnvInt("CHAT_DAILY_ROUNDS_FREE", 0),
+		ChatDailyRoundsPaid:         getEnvInt("CHAT_DAILY_ROUNDS_PAID", 0),
+		ChatPlatformFeeBps:          getEnvInt("CHAT_PLATFORM_FEE_BPS", 500),
+		ChatModeration:              getEnv("CHAT_MODERATION", "llm"),
+		ChatModerationMaxStrikes:    getEnvInt("CHAT_MODERATION_MAX_STRIKES", 3),
+		EnsoulingScan:               getEnv("ENSOULING_SCAN", "llm"),
+		EnsoulingDimensionThreshold: getEnvInt("ENSOULING_DIMENSION_THRESHOLD", 5),
+		EnsoulingGateMinScore:       getEnvFloat("ENSOULING_GATE_MIN_SCORE", 0.35),
+		EnsoulingGateMaxBacklog:     getEnvInt("ENSOULING_GATE_MAX_BACKLOG", 3),
+		WalletMintQuota:             getEnvInt("WALLET_MINT_QUOTA", 3),
+		SubjectRevenueShareBps:      getEnvInt("SUBJECT_REVENUE_SHARE_BPS", 1000),
+		ClawRegisterIPDailyCap:      getEnvInt("CLAW_REGISTER_IP_DAILY_CAP", 10),
+		ClawWalletMaxClaws:          getEnvInt("CLAW_WALLET_MAX_CLAWS", 10),
+		ClawRegisterVerifier:        getEnv("CLAW_REGISTER_VERIFIER", ""),
+		ClawRegisterRequireWallet:   getEnv("CLAW_REGISTER_REQUIRE_WALLET", "false") == "true",
+		ClawPoWDifficulty:           getEnvInt("CLAW_POW_DIFFICULTY", 20),
+		CaptchaVerifyURL:            getEnv("CAPTCHA_VERIFY_URL", "https://challenges.cloudflare.com/turnstile/v0/siteverify"),
+		CaptchaSecret:               getEnv("CAPTCHA_SECRET", ""),
+		ClawProbationFragments:      getEnvInt("CLAW_PROBATION_FRAGMENTS", 12),
+		ClawProbationMinConfidence:  getEnvFloat("CLAW_PROBATION_MIN_CONFIDENCE", 0.8),
+		ClawShellDailyBatches:       getEnvInt("CLAW_SHELL_DAILY_BATCHES", 12),
+		ClawAppealModel:             getEnv("CLAW_APPEAL_MODEL", ""),
+		ClawAppealFreeFrivolous:     getEnvInt("CLAW_APPEAL_FREE_FRIVOLOUS", 1),
+		ClawAppealTrustPenalty:      getEnvInt("CLAW_APPEAL_TRUST_PENALTY", 10),
+		PlagiarismSimilarity:        getEnvFloat("PLAGIARISM_SIMILARITY", 0.92),
+		PlagiarismFreeStrikes:       getEnvInt("PLAGIARISM_FREE_STRIKES", 1),
+		PlagiarismTrustPenalty:      getEnvInt("PLAGIARISM_TRUST_PENALTY", 15),
+		ClawTwitterTrustBonus:       getEnvInt("CLAW_TWITTER_TRUST_BONUS", 20),
+		ClawActiveWindowMinutes:     getEnvInt("CLAW_ACTIVE_WINDOW_MINUTES", 60),
+		ClawDeletePolicy:            getEnv("CLAW_DELETE_POLICY", "anonymize"),
+		ChatGuestRetentionDays:      getEnvInt("CHAT_GUEST_RETENTION_DAYS", 30),
+		ChatMaxMessageChars:         getEnvInt("CHAT_MAX_MESSAGE_CHARS", 2000),
+		FragmentArchiveDays:         getEnvInt("FRAGMENT_ARCHIVE_DAYS", 90),
+		GasDripClawDailyCap:         getEnvInt("GAS_DRIP_CLAW_DAILY_CAP", 3),
+		GasDripClawLifetimeCap:      getEnvInt("GAS_DRIP_CLAW_LIFETIME_CAP", 50),
+		GasDripHourlyCeiling:        getEnvFloat("GAS_DRIP_HOURLY_CEILING_BNB", 0.05),
+		GasLowBalanceAlert:          getEnvFloat("GAS_LOW_BALANCE_ALERT_BNB", 0.05),
+		ChainSpendDailyAlert:        getEnvFloat("CHAIN_SPEND_DAILY_ALERT_BNB", 0.05),
+		DevAPIMaxKeys:               getEnvInt("DEV_API_MAX_KEYS", 5),
+		DevAPIDailyRequests:         getEnvInt("DEV_API_DAILY_REQUESTS", 5000),
+		DevAPIDailyChats:            getEnvInt("DEV_API_DAILY_CHATS", 200),
+		AdminWallets:                getEnvList("ADMIN_WALLETS", ""),
+		MetricsToken:                getEnv("METRICS_TOKEN", ""),
+		ResponseCompression:         getEnv("RESPONSE_COMPRESSION", "true") == "true",
+		RequestMaxBodyKB:            getEnvInt("REQUEST_MAX_BODY_KB", 64),
+		RequestMaxJSONDepth:         getEnvInt("REQUEST_MAX_JSON_DEPTH", 32),
+		MaintenanceMode:             getEnv("MAINTENANCE_MODE", "false") == "true",
+		MaintenanceMessage:          getEnv("MAINTENANCE_MESSAGE", ""),
+		RateLimitStore:              strings.ToLower(getEnv("RATE_LIMIT_STORE", "memory")),
+		RedisURL:                    getEnv("REDIS_URL", ""),
+		MediaStorage:                getEnv("MEDIA_STORAGE", "local"),
+		MediaDir:                    getEnv("MEDIA_DIR", "./data/media"),
+		MediaS3Endpoint:             getEnv("MEDIA_S3_ENDPOINT", ""),
+		MediaS3Bucket:               getEnv("MEDIA_S3_BUCKET", ""),
+		MediaS3Region:               getEnv("MEDIA_S3_REGION", "auto"),
+		MediaS3AccessKey:            getEnv("MEDIA_S3_ACCESS_KEY", ""),
+		MediaS3SecretKey:            getEnv("MEDIA_S3_SECRET_KEY", ""),
+		MediaRefreshHours:           getEnvInt("MEDIA_REFRESH_HOURS", 24),
+		TwitterBearerToken:          getEnv("TWITTER_BEARER_TOKEN", ""),
+		SocialDataAPIKey:            getEnv("SOCIALDATA_API_KEY", ""),
+		SocialDataBaseURL:           getEnv("SOCIALDATA_BASE_URL", ""),
+		SeedRefreshIntervalHours:    getEnvInt("SEED_REFRESH_INTERVAL_HOURS", 168),
+		SeedRefreshMinChats:         getEnvInt("SEED_REFRESH_MIN_CHATS", 50),
+		SeedRefreshBatch:            getEnvInt("SEED_REFRESH_BATCH", 5),
+		SeedRefreshCooldownHours:    getEnvInt("SEED_REFRESH_COOLDOWN_HOURS", 24),
+		SMTPHost:                    getEnv("SMTP_HOST", ""),
+		SMTPPort:                    getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:                getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:                getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                    getEnv("SMTP_FROM", ""),
+		TelegramBotToken:            getEnv("TELEGRAM_BOT_TOKEN", ""),
 	}
 
 	// Auto-set log level based on environment if not explicitly configured
@@ -88,7 +351,11 @@ func Load() *Config {
 	Cfg = cfg
 
 	// Validate critical config
-	if cfg.DBHost == "" || cfg.DBName == "" {
+	if cfg.DBDriver == "sqlite" {
+		if cfg.DBPath == "" {
+			log.Fatal("DB_PATH is required with DB_DRIVER=sqlite")
+		}
+	} else if cfg.DBHost == "" || cfg.DBName == "" {
 		log.Fatal("DB_HOST and DB_NAME are required")
 	}
 
@@ -108,6 +375,21 @@ func (c *Config) IsProduction() bool {
 	return c.Env == "production" || c.Env == "prod"
 }
 
+// PublicURL joins a path onto PUBLIC_BASE_URL, e.g. PublicURL("/soul/alice").
+func (c *Config) PublicURL(path string) string {
+	return c.PublicBaseURL + "/" + strings.TrimLeft(path, "/")
+}
+
+// ExplorerTxURL returns the block explorer page of a transaction.
+func (c *Config) ExplorerTxURL(txHash string) string {
+	return strings.TrimRight(c.ExplorerURL, "/") + "/tx/" + txHash
+}
+
+// ClaimURL returns the link a human follows to claim a Claw.
+func (c *Config) ClaimURL(code string) string {
+	return c.ClaimURLPrefix + code
+}
+
 // getEnv reads an environment variable with a fallback default value.
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
@@ -115,3 +397,46 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvInt reads an integer environment variable with a fallback default value.
+// Malformed values are logged and replaced by the fallback.
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("config: invalid integer for %s=%q, using default %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// getEnvFloat reads a float environment variable with a fallback default value.
+// Malformed values are logged and replaced by the fallback.
+func getEnvFloat(key string, fallback float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("config: invalid number for %s=%q, using default %g", key, value, fallback)
+		return fallback
+	}
+	return f
+}
+
+// getEnvList reads a comma-separated environment variable into a slice.
+// Empty items are dropped and surrounding whitespace is trimmed.
+func getEnvList(key, fallback string) []string {
+	raw := getEnv(key, fallback)
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
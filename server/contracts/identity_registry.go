@@ -20,9 +20,14 @@ const IdentityRegistryABIJSON = `[
   {"type":"function","name":"getMetadata","inputs":[{"name":"agentId","type":"uint256"},{"name":"metadataKey","type":"string"}],"outputs":[{"name":"","type":"bytes"}],"stateMutability":"view"},
   {"type":"function","name":"getAgentWallet","inputs":[{"name":"agentId","type":"uint256"}],"outputs":[{"name":"","type":"address"}],"stateMutability":"view"},
   {"type":"function","name":"getVersion","inputs":[],"outputs":[{"name":"","type":"string"}],"stateMutability":"pure"},
+  {"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],"outputs":[],"stateMutability":"nonpayable"},
+  {"type":"function","name":"paused","inputs":[],"outputs":[{"name":"","type":"bool"}],"stateMutability":"view"},
   {"type":"event","name":"Registered","inputs":[{"name":"agentId","type":"uint256","indexed":true},{"name":"agentURI","type":"string","indexed":false},{"name":"owner","type":"address","indexed":true}]},
   {"type":"event","name":"MetadataSet","inputs":[{"name":"agentId","type":"uint256","indexed":true},{"name":"indexedMetadataKey","type":"string","indexed":true},{"name":"metadataKey","type":"string","indexed":false},{"name":"metadataValue","type":"bytes","indexed":false}]},
-  {"type":"event","name":"URIUpdated","inputs":[{"name":"agentId","type":"uint256","indexed":true},{"name":"newURI","type":"string","indexed":false},{"name":"updatedBy","type":"address","indexed":true}]}
+  {"type":"event","name":"URIUpdated","inputs":[{"name":"agentId","type":"uint256","indexed":true},{"name":"newURI","type":"string","indexed":false},{"name":"updatedBy","type":"address","indexed":true}]},
+  {"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+  {"type":"event","name":"Paused","inputs":[{"name":"account","type":"address","indexed":false}]},
+  {"type":"event","name":"Unpaused","inputs":[{"name":"account","type":"address","indexed":false}]}
 ]`
 
 // IdentityRegistry is a Go binding for the ERC-8004 IdentityRegistryUpgradeable contract.
@@ -62,6 +67,11 @@ func (ir *IdentityRegistry) SetMetadata(opts *bind.TransactOpts, agentId *big.In
 	return ir.contract.Transact(opts, "setMetadata", agentId, key, value)
 }
 
+// TransferFrom moves an agent NFT from one owner to another (ERC-721).
+func (ir *IdentityRegistry) TransferFrom(opts *bind.TransactOpts, from, to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return ir.contract.Transact(opts, "transferFrom", from, to, tokenId)
+}
+
 // TokenURI reads the agentURI for a given token.
 func (ir *IdentityRegistry) TokenURI(opts *bind.CallOpts, tokenId *big.Int) (string, error) {
 	var out []interface{}
@@ -112,6 +122,16 @@ func (ir *IdentityRegistry) GetVersion(opts *bind.CallOpts) (string, error) {
 	return out[0].(string), nil
 }
 
+// Paused reports whether the registry is paused (OpenZeppelin Pausable).
+func (ir *IdentityRegistry) Paused(opts *bind.CallOpts) (bool, error) {
+	var out []interface{}
+	err := ir.contract.Call(opts, &out, "paused")
+	if err != nil {
+		return false, err
+	}
+	return out[0].(bool), nil
+}
+
 // Address returns the contract address.
 func (ir *IdentityRegistry) Address() common.Address {
 	return ir.address
@@ -0,0 +1,65 @@
+package contracts
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Multicall3ABIJSON is the part of the Multicall3 ABI the server uses.
+const Multicall3ABIJSON = `[
+  {"type":"function","name":"aggregate3","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}],"stateMutability":"payable"}
+]`
+
+// Multicall3Call is one call in an aggregate3 batch.
+type Multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// Multicall3Result is the outcome of one call in an aggregate3 batch.
+type Multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// Multicall3 is a Go binding for the Multicall3 contract.
+type Multicall3 struct {
+	ABI      abi.ABI
+	contract *bind.BoundContract
+	address  common.Address
+}
+
+// NewMulticall3 creates a new Multicall3 binding.
+func NewMulticall3(address common.Address, backend bind.ContractBackend) (*Multicall3, error) {
+	parsed, err := abi.JSON(strings.NewReader(Multicall3ABIJSON))
+	if err != nil {
+		return nil, err
+	}
+	contract := bind.NewBoundContract(address, parsed, backend, backend, backend)
+	return &Multicall3{
+		ABI:      parsed,
+		contract: contract,
+		address:  address,
+	}, nil
+}
+
+// Aggregate3 runs the calls in one eth_call. Calls with AllowFailure set
+// report failure in their result instead of reverting the batch.
+func (mc *Multicall3) Aggregate3(opts *bind.CallOpts, calls []Multicall3Call) ([]Multicall3Result, error) {
+	var out []interface{}
+	err := mc.contract.Call(opts, &out, "aggregate3", calls)
+	if err != nil {
+		return nil, err
+	}
+	results := *abi.ConvertType(out[0], new([]Multicall3Result)).(*[]Multicall3Result)
+	return results, nil
+}
+
+// Address returns the contract address.
+func (mc *Multicall3) Address() common.Address {
+	return mc.address
+}
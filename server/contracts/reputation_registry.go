@@ -39,6 +39,17 @@ type FeedbackResult struct {
 	IsRevoked     bool
 }
 
+// NewFeedbackEvent holds the decoded fields of a NewFeedback event.
+type NewFeedbackEvent struct {
+	AgentID       *big.Int
+	ClientAddress common.Address
+	FeedbackIndex uint64
+	Value         *big.Int
+	Tag1          string
+	FeedbackURI   string
+	FeedbackHash  [32]byte
+}
+
 // SummaryResult holds the return values from getSummary.
 type SummaryResult struct {
 	Count                uint64
@@ -160,3 +171,28 @@ func (rr *ReputationRegistry) GetVersion(opts *bind.CallOpts) (string, error) {
 func (rr *ReputationRegistry) Address() common.Address {
 	return rr.address
 }
+
+// ParseNewFeedbackEvent decodes a NewFeedback event log.
+// Returns nil (without error) if the log is not a NewFeedback event from this contract.
+func (rr *ReputationRegistry) ParseNewFeedbackEvent(log types.Log) (*NewFeedbackEvent, error) {
+	event := rr.ABI.Events["NewFeedback"]
+	if log.Address != rr.address || len(log.Topics) < 3 || log.Topics[0] != event.ID {
+		return nil, nil
+	}
+
+	out := make(map[string]interface{})
+	if err := event.Inputs.NonIndexed().UnpackIntoMap(out, log.Data); err != nil {
+		return nil, err
+	}
+
+	ev := &NewFeedbackEvent{
+		AgentID:       new(big.Int).SetBytes(log.Topics[1].Bytes()),
+		ClientAddress: common.BytesToAddress(log.Topics[2].Bytes()),
+	}
+	ev.FeedbackIndex, _ = out["feedbackIndex"].(uint64)
+	ev.Value, _ = out["value"].(*big.Int)
+	ev.Tag1, _ = out["tag1"].(string)
+	ev.FeedbackURI, _ = out["feedbackURI"].(string)
+	ev.FeedbackHash, _ = out["feedbackHash"].([32]byte)
+	return ev, nil
+}
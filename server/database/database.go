@@ -7,7 +7,6 @@ import (
 	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/models"
 	"github.com/ensoul-labs/ensoul-server/util"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -15,6 +14,17 @@ import (
 // DB is the global database instance.
 var DB *gorm.DB
 
+// LicensePaymentTxIndex is the unique index that keeps one payment tx from
+// paying several licenses.
+const LicensePaymentTxIndex = "idx_shell_licenses_payment_tx_unique"
+
+// ShellAgentIndex is the unique index that links an agent to one live soul.
+const ShellAgentIndex = "idx_shells_agent_id_unique"
+
+// SubjectPayoutTxIndex is the unique index that keeps one transfer from
+// paying several subject payouts.
+const SubjectPayoutTxIndex = "idx_subject_payouts_paid_tx_unique"
+
 // Connect initializes the database connection and runs auto-migration.
 func Connect(cfg *config.Config) *gorm.DB {
 	var err error
@@ -25,16 +35,32 @@ func Connect(cfg *config.Config) *gorm.DB {
 		gormLogLevel = logger.Warn
 	}
 
-	DB, err = gorm.Open(postgres.Open(cfg.DatabaseURL()), &gorm.Config{
-		Logger: logger.Default.LogMode(gormLogLevel),
+	dialect, err := dialector(cfg)
+	if err != nil {
+		util.Log.Fatal("%v", err)
+	}
+	dbDriver = cfg.DBDriver
+
+	DB, err = gorm.Open(dialect, &gorm.Config{
+		Logger: newGormLogger(gormLogLevel),
 	})
 	if err != nil {
 		util.Log.Fatal("Failed to connect to database: %v", err)
 	}
+	instrument(DB, "primary", slowQuery(cfg))
+
+	util.Log.Info("Database connected successfully (%s)", dbDriver)
 
-	util.Log.Info("Database connected successfully")
+	if cfg.DBReplicaURL != "" {
+		if IsSQLite() {
+			util.Log.Warn("DB_REPLICA_URL is ignored with DB_DRIVER=sqlite")
+		} else {
+			connectReplica(cfg.DBReplicaURL, gormLogLevel, slowQuery(cfg))
+		}
+	}
 
-	// gen_random_uuid() is built into PostgreSQL 13+, no extension needed.
+	// gen_random_uuid() is built into PostgreSQL 13+, no extension needed
+	// (and registered by the SQLite driver setup, see dialect.go).
 	// For PostgreSQL 12 or earlier, uncomment the next line:
 	// DB.Exec("CREATE EXTENSION IF NOT EXISTS \"pgcrypto\"")
 
@@ -49,6 +75,51 @@ func Connect(cfg *config.Config) *gorm.DB {
 		&models.ChatSession{},
 		&models.ChatMessage{},
 		&models.ChatShare{},
+		&models.FragmentEmbedding{},
+		&models.ShellEmbedding{},
+		&models.ShellInterview{},
+		&models.ShellQuiz{},
+		&models.QuizAnswer{},
+		&models.ShellSettings{},
+		&models.GasDrip{},
+		&models.ChainSpend{},
+		&models.ShellAlias{},
+		&models.LLMUsage{},
+		&models.DailyStat{},
+		&models.MediaAsset{},
+		&models.FragmentAppeal{},
+		&models.FragmentArchive{},
+		&models.PlagiarismMatch{},
+		&models.DeletionRecord{},
+		&models.ShellStageTransition{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.ClawSeasonStat{},
+		&models.ClawBadge{},
+		&models.ShellLicense{},
+		&models.LicenseAccess{},
+		&models.SeedRefresh{},
+		&models.PendingTx{},
+		&models.EnsoulingTier{},
+		&models.ShellPolicyOverride{},
+		&models.SubmissionRule{},
+		&models.MintAllowlistEntry{},
+		&models.SubjectClaim{},
+		&models.SubjectPayout{},
+		&models.HandleDispute{},
+		&models.ChainCursor{},
+		&models.MaintenanceMode{},
+		&models.ModerationLog{},
+		&models.FragmentBatch{},
+		&models.DeveloperKey{},
+		&models.DeveloperKeyUsage{},
+		&models.ChatRoundUsage{},
+		&models.ChatPurchase{},
+		&models.ChatCredit{},
+		&models.NotificationPreference{},
+		&models.NotificationVerification{},
+		&models.ShellCoverage{},
+		&models.EnsoulingGateDecision{},
 	); err != nil {
 		util.Log.Fatal("Failed to migrate database: %v", err)
 	}
@@ -68,9 +139,97 @@ func Connect(cfg *config.Config) *gorm.DB {
 	// that already have a hash.
 	backfillContentHashes()
 
+	// Step 4: At most one deployed ensouling per DNA version of a soul, so two
+	// concurrent ensoulings can't both deploy v<N+1>.
+	ensureDeployedVersionIndex()
+
+	// Step 5: Souls from before the essence start with their seed summary
+	// until their next ensouling writes one. Idempotent.
+	backfillShellEssence()
+
+	// Step 6: A payment tx pays for one license only.
+	ensureLicensePaymentIndex()
+
+	// Step 7: An on-chain agent backs at most one live soul.
+	ensureShellAgentIndex()
+
+	// Step 8: A transfer pays one subject payout only.
+	ensureSubjectPayoutTxIndex()
+
+	// Step 9: Claws claimed before operator wallets existed get the wallet
+	// that claimed them (their first binding) as operator. Idempotent.
+	backfillClawOperators()
+
 	return DB
 }
 
+// backfillShellEssence copies the seed summary into the essence of shells
+// that don't have one yet.
+func backfillShellEssence() {
+	result := DB.Exec(`UPDATE shells SET essence = COALESCE(seed_summary, '') WHERE essence = '' AND COALESCE(seed_summary, '') <> ''`)
+	if result.RowsAffected > 0 {
+		util.Log.Info("Backfilled the essence of %d shells from their seed summary", result.RowsAffected)
+	}
+}
+
+// backfillClawOperators sets the operator wallet of Claws that have none
+// from their earliest binding, so operator actions work for Claws claimed
+// before the operator wallet was recorded.
+func backfillClawOperators() {
+	result := DB.Exec(`
+		UPDATE claws SET operator_wallet = (
+			SELECT b.wallet_addr FROM claw_bindings b
+			WHERE b.claw_id = claws.id ORDER BY b.created_at ASC LIMIT 1
+		)
+		WHERE COALESCE(operator_wallet, '') = ''
+			AND EXISTS (SELECT 1 FROM claw_bindings b WHERE b.claw_id = claws.id)`)
+	if result.Error != nil {
+		util.Log.Warn("Could not backfill Claw operator wallets: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		util.Log.Info("Backfilled the operator wallet of %d Claws from their bindings", result.RowsAffected)
+	}
+}
+
+// ensureDeployedVersionIndex creates the partial unique index on deployed
+// ensoulings. If duplicate versions from before the index exist, creation
+// fails; that is logged and startup continues without the index.
+func ensureDeployedVersionIndex() {
+	if err := DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_ensoulings_deployed_version
+		ON ensoulings (shell_id, version_to) WHERE status = 'deployed'`).Error; err != nil {
+		util.Log.Warn("Could not create unique index on deployed DNA versions (duplicate versions?): %v", err)
+	}
+}
+
+// ensureLicensePaymentIndex creates the partial unique index on license
+// payment txs. Licenses paid with a reused tx before the index existed make
+// creation fail; that is logged and startup continues without the index.
+func ensureLicensePaymentIndex() {
+	if err := DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS ` + LicensePaymentTxIndex + `
+		ON shell_licenses (payment_tx_hash) WHERE payment_tx_hash <> ''`).Error; err != nil {
+		util.Log.Warn("Could not create unique index on license payment txs (reused txs?): %v", err)
+	}
+}
+
+// ensureShellAgentIndex creates the partial unique index on shell agent IDs,
+// so concurrent imports of one agent can't both create a soul. Duplicates
+// from before the index make creation fail; that is logged and startup
+// continues without the index.
+func ensureShellAgentIndex() {
+	if err := DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS ` + ShellAgentIndex + `
+		ON shells (agent_id) WHERE agent_id IS NOT NULL AND deleted_at IS NULL`).Error; err != nil {
+		util.Log.Warn("Could not create unique index on shell agent IDs (duplicate agents?): %v", err)
+	}
+}
+
+// ensureSubjectPayoutTxIndex creates the partial unique index on subject
+// payout txs, so one transfer can't be marked as paying two payouts.
+func ensureSubjectPayoutTxIndex() {
+	if err := DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS ` + SubjectPayoutTxIndex + `
+		ON subject_payouts (paid_tx_hash) WHERE paid_tx_hash <> ''`).Error; err != nil {
+		util.Log.Warn("Could not create unique index on subject payout txs (reused txs?): %v", err)
+	}
+}
+
 // normalizeHandlesToLower converts all shell handles to lowercase in-place.
 // Twitter handles are case-insensitive, so "VitalikButerin" → "vitalikbuterin".
 // This is idempotent: if all handles are already lowercase, no rows are updated.
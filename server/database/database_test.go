@@ -0,0 +1,191 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// connectSQLite migrates a fresh SQLite database in a temp dir.
+func connectSQLite(t *testing.T) {
+	t.Helper()
+	util.InitLogger("error")
+	Connect(&config.Config{
+		Env:      "production",
+		DBDriver: DriverSQLite,
+		DBPath:   filepath.Join(t.TempDir(), "ensoul.db"),
+	})
+	t.Cleanup(func() {
+		if sqlDB, err := DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+		dbDriver = DriverPostgres
+	})
+}
+
+func TestSQLiteMigrate(t *testing.T) {
+	connectSQLite(t)
+
+	if !IsSQLite() {
+		t.Fatal("IsSQLite() = false after connecting with DB_DRIVER=sqlite")
+	}
+	for _, table := range []interface{}{&models.Shell{}, &models.Fragment{}, &models.Claw{}, &models.Ensouling{}, &models.ShellLicense{}} {
+		if !DB.Migrator().HasTable(table) {
+			t.Errorf("table for %T was not created", table)
+		}
+	}
+	for _, index := range []string{ShellAgentIndex, LicensePaymentTxIndex, SubjectPayoutTxIndex, "idx_ensoulings_deployed_version"} {
+		var n int64
+		DB.Raw(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?`, index).Scan(&n)
+		if n != 1 {
+			t.Errorf("index %s was not created", index)
+		}
+	}
+
+	// Migrating an existing database again is a no-op
+	Connect(&config.Config{Env: "production", DBDriver: DriverSQLite, DBPath: DB.Dialector.(sqliteDialector).DSN})
+}
+
+func TestSQLiteCRUD(t *testing.T) {
+	connectSQLite(t)
+
+	shell := &models.Shell{Handle: "vitalik", Dimensions: models.JSON{"stance": map[string]interface{}{"score": 10}}}
+	if err := DB.Create(shell).Error; err != nil {
+		t.Fatalf("create shell: %v", err)
+	}
+	if shell.ID == uuid.Nil {
+		t.Fatal("shell ID was not generated by the gen_random_uuid() default")
+	}
+	if shell.Stage != models.StageEmbryo {
+		t.Errorf("stage = %q, want the column default %q", shell.Stage, models.StageEmbryo)
+	}
+
+	claw := &models.Claw{Name: "hunter", APIKeyHash: "hash", ClaimCode: "claim", VerificationCode: "code", Tags: models.StringList{"crypto"}}
+	if err := DB.Create(claw).Error; err != nil {
+		t.Fatalf("create claw: %v", err)
+	}
+	fragment := &models.Fragment{ShellID: shell.ID, ClawID: claw.ID, Dimension: models.DimStance, Content: "Backs proof of reserves.", Status: models.FragStatusAccepted}
+	if err := DB.Create(fragment).Error; err != nil {
+		t.Fatalf("create fragment: %v", err)
+	}
+
+	var got models.Shell
+	if err := DB.First(&got, "handle = ?", "vitalik").Error; err != nil {
+		t.Fatalf("read shell: %v", err)
+	}
+	if got.ID != shell.ID || got.Dimensions["stance"] == nil {
+		t.Errorf("read back %+v, want ID %s with dimensions", got, shell.ID)
+	}
+
+	if err := DB.Model(&got).Update("accepted_frags", 1).Error; err != nil {
+		t.Fatalf("update shell: %v", err)
+	}
+	var accepted int64
+	DB.Model(&models.Fragment{}).Where("shell_id = ? AND status = ?", shell.ID, models.FragStatusAccepted).Count(&accepted)
+	if accepted != 1 {
+		t.Errorf("accepted fragments = %d, want 1", accepted)
+	}
+
+	var tags []string
+	from, tag := JSONArrayElements("c.tags", "t")
+	if err := DB.Raw(`SELECT ` + tag + ` FROM claws c, ` + from).Scan(&tags).Error; err != nil || len(tags) != 1 || tags[0] != "crypto" {
+		t.Errorf("json array tags = %v (err %v), want [crypto]", tags, err)
+	}
+
+	if err := DB.Delete(&got).Error; err != nil {
+		t.Fatalf("delete shell: %v", err)
+	}
+	if err := DB.First(&models.Shell{}, "id = ?", shell.ID).Error; err == nil {
+		t.Error("soft-deleted shell is still returned")
+	}
+	if err := DB.Unscoped().First(&models.Shell{}, "id = ?", shell.ID).Error; err != nil {
+		t.Errorf("soft-deleted shell is gone from the table: %v", err)
+	}
+}
+
+func TestSQLiteUniqueViolation(t *testing.T) {
+	connectSQLite(t)
+
+	agentID := uint64(42)
+	if err := DB.Create(&models.Shell{Handle: "first", AgentID: &agentID}).Error; err != nil {
+		t.Fatalf("create shell: %v", err)
+	}
+	err := DB.Create(&models.Shell{Handle: "second", AgentID: &agentID}).Error
+	if !IsUniqueViolation(err, ShellAgentIndex) {
+		t.Fatalf("IsUniqueViolation(%v, %s) = false", err, ShellAgentIndex)
+	}
+	if IsUniqueViolation(err, LicensePaymentTxIndex) {
+		t.Errorf("violation of %s also matched %s", ShellAgentIndex, LicensePaymentTxIndex)
+	}
+
+	err = DB.Create(&models.Shell{Handle: "first"}).Error
+	if !IsUniqueViolation(err, "idx_shells_handle") {
+		t.Errorf("duplicate handle: IsUniqueViolation(%v) = false", err)
+	}
+}
+
+func TestSQLitePortableSQL(t *testing.T) {
+	connectSQLite(t)
+
+	var greatest float64
+	if err := DB.Raw(`SELECT GREATEST(0.2 - ?, 0, NULL)`, 0.5).Scan(&greatest).Error; err != nil || greatest != 0 {
+		t.Errorf("GREATEST = %v (err %v), want 0", greatest, err)
+	}
+
+	day := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := DB.Exec(`
+			INSERT INTO daily_stats (day, metric, key, value, updated_at) VALUES (?, 'chats', '', 1, NOW())
+			ON CONFLICT (day, metric, key) DO UPDATE SET value = daily_stats.value + EXCLUDED.value, updated_at = NOW()`,
+			day).Error; err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+	var stat models.DailyStat
+	if err := DB.First(&stat, "metric = ?", "chats").Error; err != nil || stat.Value != 2 || stat.UpdatedAt.IsZero() {
+		t.Errorf("upserted stat = %+v (err %v), want value 2 with updated_at", stat, err)
+	}
+
+	DB.Create(&models.Shell{Handle: "tagged", Tags: models.StringList{"ai", "crypto"}, TwitterMeta: models.JSON{"followers_count": 7, "bio": "x"}})
+	var handles []string
+	DB.Model(&models.Shell{}).Where(JSONContainsAll("tags"), `["crypto","ai"]`).Pluck("handle", &handles)
+	if len(handles) != 1 {
+		t.Errorf("tags containing [crypto ai] = %v, want [tagged]", handles)
+	}
+	DB.Model(&models.Shell{}).Where(JSONContainsAll("tags"), `["crypto","defi"]`).Pluck("handle", &handles)
+	if len(handles) != 0 {
+		t.Errorf("tags containing [crypto defi] = %v, want none", handles)
+	}
+	var meta models.JSON
+	DB.Model(&models.Shell{}).Select(JSONObject("twitter_meta", "followers_count")).Where("handle = ?", "tagged").Scan(&meta)
+	if len(meta) != 1 || meta["followers_count"] != float64(7) {
+		t.Errorf("trimmed twitter_meta = %v, want only followers_count 7", meta)
+	}
+}
+
+func TestBackfillClawOperators(t *testing.T) {
+	connectSQLite(t)
+
+	claw := &models.Claw{Name: "legacy", APIKeyHash: "hash", ClaimCode: "claim", VerificationCode: "code", Status: models.ClawStatusClaimed}
+	DB.Create(claw)
+	DB.Create(&models.ClawBinding{WalletAddr: "0xfirst", ClawID: claw.ID, CreatedAt: time.Now().Add(-time.Hour)})
+	DB.Create(&models.ClawBinding{WalletAddr: "0xsecond", ClawID: claw.ID})
+	unclaimed := &models.Claw{Name: "fresh", APIKeyHash: "hash2", ClaimCode: "claim2", VerificationCode: "code"}
+	DB.Create(unclaimed)
+
+	backfillClawOperators()
+
+	DB.First(claw, "id = ?", claw.ID)
+	if claw.OperatorWallet != "0xfirst" {
+		t.Errorf("operator_wallet = %q, want the first binding 0xfirst", claw.OperatorWallet)
+	}
+	DB.First(unclaimed, "id = ?", unclaimed.ID)
+	if unclaimed.OperatorWallet != "" {
+		t.Errorf("unbound Claw got operator_wallet %q", unclaimed.OperatorWallet)
+	}
+}
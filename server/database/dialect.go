@@ -0,0 +1,188 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/glebarez/go-sqlite"
+	sqlitedialect "github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Supported DB_DRIVER values.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// dbDriver is the dialect of DB, set by Connect.
+var dbDriver = DriverPostgres
+
+// IsSQLite reports whether DB is a SQLite database. Queries that have no
+// portable form branch on it; everything else is written to run on both.
+func IsSQLite() bool {
+	return dbDriver == DriverSQLite
+}
+
+// dialector returns the GORM dialector for DB_DRIVER.
+func dialector(cfg *config.Config) (gorm.Dialector, error) {
+	switch cfg.DBDriver {
+	case DriverPostgres:
+		return postgres.Open(cfg.DatabaseURL()), nil
+	case DriverSQLite:
+		registerSQLiteFunctions()
+		return sqliteDialector{sqlitedialect.Open(sqliteDSN(cfg.DBPath)).(*sqlitedialect.Dialector)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q: use postgres or sqlite", cfg.DBDriver)
+	}
+}
+
+// sqliteDSN adds the pragmas the server needs: foreign keys as in
+// PostgreSQL, WAL so readers don't block the writer, and a busy timeout
+// instead of immediate "database is locked" errors.
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+}
+
+var sqliteFunctionsOnce sync.Once
+
+// registerSQLiteFunctions adds the PostgreSQL functions the schema and raw
+// queries use, so the same SQL runs on SQLite: gen_random_uuid() for UUID
+// primary keys, now() for upsert timestamps and greatest() for clamped
+// counters. Registration is global and only needed once.
+func registerSQLiteFunctions() {
+	sqliteFunctionsOnce.Do(func() {
+		sqlite.MustRegisterScalarFunction("gen_random_uuid", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+			return uuid.NewString(), nil
+		})
+		sqlite.MustRegisterScalarFunction("now", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+			return time.Now().Format("2006-01-02 15:04:05.999999999-07:00"), nil // the driver's time format
+		})
+		sqlite.MustRegisterDeterministicScalarFunction("greatest", -1, sqliteGreatest)
+	})
+}
+
+// sqliteGreatest is PostgreSQL's GREATEST for numbers: the largest non-NULL
+// argument, or NULL if all are NULL.
+func sqliteGreatest(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	var best driver.Value
+	var bestValue float64
+	for _, arg := range args {
+		var v float64
+		switch n := arg.(type) {
+		case nil:
+			continue
+		case int64:
+			v = float64(n)
+		case float64:
+			v = n
+		default:
+			return nil, fmt.Errorf("greatest: unsupported argument %T", arg)
+		}
+		if best == nil || v > bestValue {
+			best, bestValue = arg, v
+		}
+	}
+	return best, nil
+}
+
+// sqliteDialector is the SQLite dialector with a migrator that accepts the
+// models' PostgreSQL column defaults.
+type sqliteDialector struct {
+	*sqlitedialect.Dialector
+}
+
+func (d sqliteDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return sqliteMigrator{d.Dialector.Migrator(db).(sqlitedialect.Migrator)}
+}
+
+type sqliteMigrator struct {
+	sqlitedialect.Migrator
+}
+
+// FullDataTypeOf wraps function defaults like gen_random_uuid() and now() in
+// parentheses, which SQLite requires for non-literal defaults.
+func (m sqliteMigrator) FullDataTypeOf(field *schema.Field) clause.Expr {
+	expr := m.Migrator.FullDataTypeOf(field)
+	if def := field.DefaultValue; field.DefaultValueInterface == nil && strings.HasSuffix(def, "()") {
+		expr.SQL = strings.Replace(expr.SQL, " DEFAULT "+def, " DEFAULT ("+def+")", 1)
+	}
+	return expr
+}
+
+// MigrateColumn leaves existing columns alone. SQLite can't alter a column
+// in place (the driver rebuilds the whole table), and its DDL parser reads
+// function defaults back differently from the tags, which would rebuild
+// tables on every start. New tables, columns and indexes are still created.
+func (m sqliteMigrator) MigrateColumn(interface{}, *schema.Field, gorm.ColumnType) error {
+	return nil
+}
+
+// CreateIndex creates indexes without their PostgreSQL access method
+// (USING gin): SQLite only has B-tree indexes.
+func (m sqliteMigrator) CreateIndex(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		idx := stmt.Schema.LookIndex(name)
+		if idx == nil {
+			return fmt.Errorf("failed to create index with name %v", name)
+		}
+		sql := "CREATE "
+		if idx.Class != "" {
+			sql += idx.Class + " "
+		}
+		sql += "INDEX ? ON ??"
+		if idx.Where != "" {
+			sql += " WHERE " + idx.Where
+		}
+		return m.DB.Exec(sql, clause.Column{Name: idx.Name}, clause.Table{Name: stmt.Table}, m.BuildIndexOptions(idx.Fields, stmt)).Error
+	})
+}
+
+// JSONArrayElements returns a FROM item expanding the JSON array in column
+// into rows aliased as alias, and the expression of each element's text
+// value (arrays of strings).
+func JSONArrayElements(column, alias string) (from, value string) {
+	if IsSQLite() {
+		return fmt.Sprintf("json_each(%s) AS %s", column, alias), alias + ".value"
+	}
+	return fmt.Sprintf("jsonb_array_elements_text(%s) AS %s(value)", column, alias), alias + ".value"
+}
+
+// JSONArrayObjects is JSONArrayElements for arrays of objects: it also
+// returns the expression reading the text of key from each element.
+func JSONArrayObjects(column, alias, key string) (from, field string) {
+	if IsSQLite() {
+		return fmt.Sprintf("json_each(%s) AS %s", column, alias), fmt.Sprintf("%s.value ->> '%s'", alias, key)
+	}
+	return fmt.Sprintf("jsonb_array_elements(%s) AS %s(value)", column, alias), fmt.Sprintf("%s.value ->> '%s'", alias, key)
+}
+
+// JSONContainsAll returns a condition that the JSON array in column holds
+// every element of the JSON array bound to its one placeholder.
+func JSONContainsAll(column string) string {
+	if IsSQLite() {
+		return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM json_each(?) w WHERE w.value NOT IN (SELECT value FROM json_each(%s)))", column)
+	}
+	return column + " @> CAST(? AS jsonb)"
+}
+
+// JSONObject returns the expression building a JSON object of one key read
+// from the JSON object in column, e.g. to trim a column in a listing.
+func JSONObject(column, key string) string {
+	if IsSQLite() {
+		return fmt.Sprintf("json_object('%s', json_extract(%s, '$.%s'))", key, column, key)
+	}
+	return fmt.Sprintf("jsonb_build_object('%s', %s->'%s')", key, column, key)
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/glebarez/go-sqlite"
+	"github.com/jackc/pgx/v5/pgconn"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// IsUniqueViolation reports whether err is a unique constraint violation
+// (PostgreSQL SQLSTATE 23505, SQLite SQLITE_CONSTRAINT_UNIQUE), optionally on
+// the named constraint or index.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505" && (constraint == "" || pgErr.ConstraintName == constraint)
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) && liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return constraint == "" || strings.Contains(liteErr.Error(), "failed: "+sqliteIndexColumns(constraint))
+	}
+	return false
+}
+
+// sqliteIndexColumns returns the columns of a SQLite index the way a unique
+// violation names them ("table.a, table.b"), since SQLite errors don't carry
+// the index name.
+func sqliteIndexColumns(index string) string {
+	var table string
+	if err := DB.Raw(`SELECT tbl_name FROM sqlite_master WHERE type = 'index' AND name = ?`, index).Scan(&table).Error; err != nil || table == "" {
+		return index
+	}
+	var columns []string
+	DB.Raw(`SELECT name FROM pragma_index_info(?) ORDER BY seqno`, index).Scan(&columns)
+	for i, c := range columns {
+		columns[i] = table + "." + c
+	}
+	return strings.Join(columns, ", ")
+}
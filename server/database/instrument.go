@@ -0,0 +1,145 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	queryStartKey   = "instrument:start"
+	maxLoggedSQLLen = 1000
+)
+
+var (
+	queryDuration = util.NewHistogramVec("ensoul_db_query_duration_seconds",
+		"Duration of database queries by connection, table and operation.",
+		[]float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		"db", "table", "operation")
+	querySlow = util.NewCounterVec("ensoul_db_slow_queries_total",
+		"Database queries slower than DB_SLOW_QUERY_MS.",
+		"db", "table", "operation")
+	queryErrors = util.NewCounterVec("ensoul_db_query_errors_total",
+		"Failed database queries (record not found excluded).",
+		"db", "table", "operation")
+
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumber        = regexp.MustCompile(`\$?\b\d+(?:\.\d+)?\b`)
+)
+
+// queryInstrument is a GORM plugin timing every statement. Durations feed
+// the per-table histogram on /metrics; statements slower than slow are
+// logged with their SQL sanitized of literal values.
+type queryInstrument struct {
+	db   string // "primary" or "replica"
+	slow time.Duration
+}
+
+// Name implements gorm.Plugin.
+func (q *queryInstrument) Name() string {
+	return "instrument:" + q.db
+}
+
+// Initialize implements gorm.Plugin.
+func (q *queryInstrument) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("instrument:before_create", q.before),
+		cb.Create().After("gorm:create").Register("instrument:after_create", q.after("create")),
+		cb.Query().Before("gorm:query").Register("instrument:before_query", q.before),
+		cb.Query().After("gorm:query").Register("instrument:after_query", q.after("query")),
+		cb.Update().Before("gorm:update").Register("instrument:before_update", q.before),
+		cb.Update().After("gorm:update").Register("instrument:after_update", q.after("update")),
+		cb.Delete().Before("gorm:delete").Register("instrument:before_delete", q.before),
+		cb.Delete().After("gorm:delete").Register("instrument:after_delete", q.after("delete")),
+		cb.Row().Before("gorm:row").Register("instrument:before_row", q.before),
+		cb.Row().After("gorm:row").Register("instrument:after_row", q.after("row")),
+		cb.Raw().Before("gorm:raw").Register("instrument:before_raw", q.before),
+		cb.Raw().After("gorm:raw").Register("instrument:after_raw", q.after("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *queryInstrument) before(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (q *queryInstrument) after(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(start)
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "raw"
+		}
+		queryDuration.Observe(elapsed.Seconds(), q.db, table, op)
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			queryErrors.Inc(q.db, table, op)
+		}
+		if q.slow > 0 && elapsed >= q.slow {
+			querySlow.Inc(q.db, table, op)
+			util.Log.Warn("[db] Slow %s on %s (%s, %dms, %d rows): %s",
+				op, table, q.db, elapsed.Milliseconds(), db.RowsAffected, sanitizeSQL(db.Statement.SQL.String()))
+		}
+	}
+}
+
+// sanitizeSQL strips literal values from a statement before it is logged.
+// Bound parameters are already placeholders; this covers values inlined in
+// raw SQL. Whitespace is collapsed and long statements are truncated.
+func sanitizeSQL(sql string) string {
+	sql = sqlStringLiteral.ReplaceAllString(sql, "'?'")
+	sql = sqlNumber.ReplaceAllStringFunc(sql, func(n string) string {
+		if strings.HasPrefix(n, "$") {
+			return n // placeholder
+		}
+		return "?"
+	})
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxLoggedSQLLen {
+		sql = sql[:maxLoggedSQLLen] + "..."
+	}
+	return sql
+}
+
+// newGormLogger is GORM's default logger without its own slow-query
+// warnings, which print SQL with the bound values; queryInstrument logs
+// those instead.
+func newGormLogger(level logger.LogLevel) logger.Interface {
+	return logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		LogLevel: level,
+		Colorful: true,
+	})
+}
+
+// instrument installs queryInstrument on a connection.
+func instrument(db *gorm.DB, name string, slow time.Duration) {
+	if err := db.Use(&queryInstrument{db: name, slow: slow}); err != nil {
+		util.Log.Error("Failed to instrument %s database queries: %v", name, err)
+	}
+}
+
+// slowQuery is the DB_SLOW_QUERY_MS threshold, 0 when slow queries aren't logged.
+func slowQuery(cfg *config.Config) time.Duration {
+	return time.Duration(max(cfg.DBSlowQueryMs, 0)) * time.Millisecond
+}
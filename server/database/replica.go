@@ -0,0 +1,78 @@
+package database
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/util"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// replica is the read replica (DB_REPLICA_URL), nil when none is configured.
+var replica *gorm.DB
+
+// replicaUsable is cleared while the replica is unreachable or lagging, so
+// ReadDB falls back to the primary.
+var replicaUsable atomic.Bool
+
+// connectReplica opens the read replica. A replica that can't be reached at
+// startup is logged and left out; the primary serves every read.
+func connectReplica(url string, logLevel logger.LogLevel, slow time.Duration) {
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{
+		Logger: newGormLogger(logLevel),
+	})
+	if err != nil {
+		util.Log.Error("Failed to connect to read replica, reads stay on the primary: %v", err)
+		return
+	}
+	instrument(db, "replica", slow)
+	replica = db
+	replicaUsable.Store(true)
+	util.Log.Info("Read replica connected")
+}
+
+// ReadDB returns the connection for heavy public reads: the replica when it
+// is configured and healthy, else the primary. Use it only for listings and
+// aggregates that tolerate a few seconds of staleness; anything that reads
+// back what the same request or client just wrote must use DB.
+func ReadDB() *gorm.DB {
+	if replica != nil && replicaUsable.Load() {
+		return replica
+	}
+	return DB
+}
+
+// ReplicaStatus reports whether a replica is configured and currently used.
+func ReplicaStatus() (configured, usable bool) {
+	return replica != nil, replica != nil && replicaUsable.Load()
+}
+
+// CheckReplica measures the replica's replication lag and takes it out of
+// rotation while it is unreachable or more than maxLag behind. A replica
+// that has replayed everything it received counts as zero lag, since
+// pg_last_xact_replay_timestamp stops moving when the primary is idle.
+func CheckReplica(maxLag time.Duration) error {
+	if replica == nil {
+		return nil
+	}
+
+	var lag float64
+	err := replica.Raw(`SELECT CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`).Scan(&lag).Error
+	healthy := err == nil && time.Duration(lag*float64(time.Second)) <= maxLag
+
+	if was := replicaUsable.Swap(healthy); was != healthy {
+		if healthy {
+			util.Log.Info("Read replica back in rotation (lag %.1fs)", lag)
+		} else if err != nil {
+			util.Log.Warn("Read replica unreachable, reads moved to the primary: %v", err)
+		} else {
+			util.Log.Warn("Read replica lagging %.1fs, reads moved to the primary", lag)
+		}
+	}
+	return err
+}
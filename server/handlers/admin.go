@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminGasReport handles GET /api/admin/gas
+// Returns drip spend accounting, budget caps and low-balance alerts.
+func AdminGasReport(c *gin.Context) {
+	report, err := services.GetGasSpendReport()
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// AdminChainSpend handles GET /api/admin/chain-spend?days=7
+// Returns the gas cost of platform transactions by feature, day, shell and claw,
+// with the daily spend alert.
+func AdminChainSpend(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 1 || days > 90 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "days must be between 1 and 90")
+		return
+	}
+
+	report, err := services.GetChainSpendReport(days)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// AdminLLMUsage handles GET /api/admin/llm-usage?days=7
+// Returns LLM token usage and estimated cost by feature, model, day, shell and claw.
+func AdminLLMUsage(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 1 || days > 90 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "days must be between 1 and 90")
+		return
+	}
+
+	report, err := services.GetLLMUsageReport(days)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// AdminStats handles GET /api/admin/stats?days=30
+// Returns the daily dashboard series (mints, fragment acceptance, LLM and chain tx
+// failure rates, drip spend, active Claws, chat volume by tier) from the rollup table.
+func AdminStats(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 90 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "days must be between 1 and 90")
+		return
+	}
+
+	stats, err := services.GetAdminStats(days)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// AdminCoverage handles GET /api/admin/coverage?days=7
+// Returns open task board demand vs recent Claw activity per dimension, plus declared Claw tags.
+func AdminCoverage(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 1 || days > 90 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "days must be between 1 and 90")
+		return
+	}
+
+	report, err := services.GetCoverageReport(days)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// AdminStaleClaws handles GET /api/admin/claws/stale?hours=24
+// Lists claimed Claws that have not sent a heartbeat in the given number of hours.
+func AdminStaleClaws(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > 24*90 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "hours must be between 1 and 2160")
+		return
+	}
+
+	report, err := services.GetStaleClaws(hours)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// AdminDeletions handles GET /api/admin/deletions?subject=claw&limit=50
+// Returns the audit log of self-service deletions and retention purges.
+func AdminDeletions(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "limit must be between 1 and 500")
+		return
+	}
+
+	records, err := services.ListDeletionRecords(c.Query("subject"), limit)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deletions": records})
+}
+
+// AdminModerationLogs handles GET /api/admin/moderation?session_id=&handle=&limit=50
+// Returns chat messages blocked by moderation, newest first.
+func AdminModerationLogs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "limit must be between 1 and 500")
+		return
+	}
+	sessionID := c.Query("session_id")
+	if sessionID != "" {
+		if _, err := uuid.Parse(sessionID); err != nil {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "invalid session_id")
+			return
+		}
+	}
+
+	handle, ok := handleQuery(c)
+	if !ok {
+		return
+	}
+	logs, err := services.ListModerationLogs(sessionID, handle, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrShellNotFound) {
+			util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, err.Error())
+			return
+		}
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logs": logs})
+}
+
+// AdminPlagiarism handles GET /api/admin/plagiarism?handle=&claw_id=&limit=50
+// Lists fragments auto-rejected as copies of another Claw's accepted fragment.
+func AdminPlagiarism(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "limit must be between 1 and 500")
+		return
+	}
+	clawID := c.Query("claw_id")
+	if clawID != "" {
+		if _, err := uuid.Parse(clawID); err != nil {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "invalid claw_id")
+			return
+		}
+	}
+
+	handle, ok := handleQuery(c)
+	if !ok {
+		return
+	}
+	matches, err := services.ListPlagiarismMatches(handle, clawID, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrShellNotFound) {
+			util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, err.Error())
+			return
+		}
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"matches": matches})
+}
+
+// AdminJobs handles GET /api/admin/jobs
+// Lists the background jobs with their last run, duration and failure count.
+func AdminJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"jobs": services.ListJobs()})
+}
+
+// AdminRunJob handles POST /api/admin/jobs/:name/run
+// Starts a background job now instead of waiting for its next tick.
+func AdminRunJob(c *gin.Context) {
+	status, err := services.RunJob(c.Param("name"))
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+		return
+	case errors.Is(err, services.ErrJobRunning):
+		util.RespondError(c, http.StatusConflict, util.CodeJobRunning, err.Error())
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"started": true, "job": status})
+}
+
+// AdminQuarantinedEnsoulings handles GET /api/admin/ensoulings/quarantined
+// Lists ensoulings held by the prompt safety scan, with their full prompts.
+func AdminQuarantinedEnsoulings(c *gin.Context) {
+	list, err := services.ListQuarantinedEnsoulings()
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ensoulings": list})
+}
+
+// AdminApproveEnsouling handles POST /api/admin/ensoulings/:id/approve
+// Deploys a quarantined version to its soul.
+func AdminApproveEnsouling(c *gin.Context) {
+	ensouling, err := services.ApproveEnsouling(c.Param("id"), middleware.GetSessionWallet(c))
+	if err != nil {
+		respondEnsoulingReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ensouling": ensouling})
+}
+
+// AdminRejectEnsouling handles POST /api/admin/ensoulings/:id/reject
+// Discards a quarantined version; the soul keeps its current prompt.
+func AdminRejectEnsouling(c *gin.Context) {
+	ensouling, err := services.RejectEnsouling(c.Param("id"), middleware.GetSessionWallet(c))
+	if err != nil {
+		respondEnsoulingReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ensouling": ensouling})
+}
+
+func respondEnsoulingReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrEnsoulingNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrEnsoulingNotQuarantined):
+		util.RespondError(c, http.StatusConflict, util.CodeNotQuarantined, err.Error())
+	case errors.Is(err, services.ErrShellNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, err.Error())
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+	case errors.Is(err, services.ErrRegistryPaused):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeRegistryPaused, err.Error())
+	default:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+	}
+}
+
+// AdminMintAllowlist handles GET /api/admin/mint-allowlist
+// Lists wallets with their own mint quota.
+func AdminMintAllowlist(c *gin.Context) {
+	entries, err := services.ListMintAllowlist()
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"default_quota": config.Cfg.WalletMintQuota, "entries": entries})
+}
+
+// AdminSetMintAllowlist handles PUT /api/admin/mint-allowlist/:wallet
+// Gives a wallet its own mint quota. Body: {"quota": 20, "note": "partner"}
+func AdminSetMintAllowlist(c *gin.Context) {
+	var req struct {
+		Quota int    `json:"quota" binding:"required"`
+		Note  string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: quota. Optional: note")
+		return
+	}
+
+	entry, err := services.SetMintAllowlistEntry(c.Param("wallet"), req.Quota, req.Note, middleware.GetSessionWallet(c))
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entry": entry, "mint_quota": services.GetMintQuota(entry.WalletAddr)})
+}
+
+// AdminDeleteMintAllowlist handles DELETE /api/admin/mint-allowlist/:wallet
+// Returns a wallet to the default mint quota.
+func AdminDeleteMintAllowlist(c *gin.Context) {
+	if err := services.DeleteMintAllowlistEntry(c.Param("wallet")); err != nil {
+		if errors.Is(err, services.ErrNotAllowlisted) {
+			util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+			return
+		}
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// AdminMaintenance handles GET /api/admin/maintenance
+// Returns the platform's read-only state.
+func AdminMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, middleware.Maintenance())
+}
+
+// AdminSetMaintenance handles PUT /api/admin/maintenance
+// Turns read-only mode on or off for every instance: GETs keep serving, other
+// writes outside /api/admin and /api/auth get 503 MAINTENANCE and scheduled
+// jobs are skipped.
+func AdminSetMaintenance(c *gin.Context) {
+	var req struct {
+		ReadOnly *bool      `json:"read_only" binding:"required"`
+		Message  string     `json:"message"`
+		Until    *time.Time `json:"until"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: read_only. Optional: message, until")
+		return
+	}
+	if len(req.Message) > 500 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "message too long (max 500 characters)")
+		return
+	}
+
+	state, err := middleware.SetMaintenance(*req.ReadOnly, req.Message, req.Until, middleware.GetSessionWallet(c))
+	if err != nil {
+		util.Log.Error("[admin] %v", err)
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to set read-only mode")
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+// AdminMergeShell handles POST /api/admin/shell/:handle/merge
+// Absorbs a duplicate soul into this one. Body: {"source": "<handle>"} or
+// {"source_id": "<shell id>"} for duplicates differing only in case. Its
+// fragments, chats and history move here, its accepted fragments are
+// re-ensouled into this soul, and its handle redirects here from then on.
+func AdminMergeShell(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Source   string `json:"source"`
+		SourceID string `json:"source_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Source == "") == (req.SourceID == "") {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: source or source_id")
+		return
+	}
+	source := req.SourceID
+	if source != "" {
+		if _, err := uuid.Parse(source); err != nil {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "source_id must be a shell ID")
+			return
+		}
+	} else if source, ok = bindHandle(c, req.Source); !ok {
+		return
+	}
+
+	merge, err := services.MergeShells(handle, source, middleware.GetSessionWallet(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrShellNotFound):
+			util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, err.Error())
+		case errors.Is(err, services.ErrMergeSameShell):
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		case errors.Is(err, services.ErrShellDisputed):
+			util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+		case errors.Is(err, services.ErrMergeBlocked):
+			util.RespondError(c, http.StatusConflict, util.CodeMergeBlocked, err.Error())
+		default:
+			util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, merge)
+}
@@ -1,8 +1,7 @@
 package handlers
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -15,6 +14,7 @@ import (
 	"github.com/ensoul-labs/ensoul-server/util"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const (
@@ -31,48 +31,33 @@ func AuthLogin(c *gin.Context) {
 		Message   string `json:"message" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "address, signature, and message are required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "address, signature, and message are required")
 		return
 	}
 
 	// Validate address format
 	if !common.IsHexAddress(req.Address) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address format"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid wallet address format")
 		return
 	}
 
 	// Validate message format: "ensoul:login:<timestamp>"
 	if !strings.HasPrefix(req.Message, "ensoul:login:") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message format"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid message format")
 		return
 	}
 
 	// Verify signature
 	claimed := common.HexToAddress(req.Address)
 	if err := middleware.VerifyWalletSignature(req.Message, req.Signature, claimed); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Signature verification failed: " + err.Error()})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeInvalidSignature, "Signature verification failed: "+err.Error())
 		return
 	}
 
-	// Generate session token
-	tokenBytes := make([]byte, 32)
-	if _, err := rand.Read(tokenBytes); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate session"})
-		return
-	}
-	token := hex.EncodeToString(tokenBytes)
-
-	// Delete any existing sessions for this wallet
-	database.DB.Where("wallet_addr = ?", claimed.Hex()).Delete(&models.WalletSession{})
-
-	// Create new session (store hash only, never the raw token)
-	session := &models.WalletSession{
-		TokenHash:  util.HashToken(token),
-		WalletAddr: claimed.Hex(),
-		ExpiresAt:  time.Now().Add(sessionDuration),
-	}
-	if err := database.DB.Create(session).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+	// Each device keeps its own session (store hash only, never the raw token)
+	token, err := services.CreateWalletSession(claimed.Hex(), c.GetHeader("User-Agent"), c.ClientIP(), sessionDuration)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to create session")
 		return
 	}
 
@@ -96,7 +81,7 @@ func AuthLogin(c *gin.Context) {
 }
 
 // AuthLogout handles POST /api/auth/logout
-// Destroys the session and clears the cookie.
+// Destroys this device's session and clears the cookie; other devices stay logged in.
 func AuthLogout(c *gin.Context) {
 	token, err := c.Cookie(sessionCookieName)
 	if err == nil && token != "" {
@@ -104,32 +89,105 @@ func AuthLogout(c *gin.Context) {
 		database.DB.Where("token_hash = ?", tokenHash).Delete(&models.WalletSession{})
 	}
 
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie(sessionCookieName, "", -1, "/", "", config.Cfg.IsProduction(), true)
+	clearSessionCookie(c)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
 }
 
+// clearSessionCookie removes the session cookie from the client.
+func clearSessionCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookieName, "", -1, "/", "", config.Cfg.IsProduction(), true)
+}
+
 // AuthSession handles GET /api/auth/session
-// Returns the current session info (wallet address) if logged in.
+// Returns the current session info (wallet address and mint quota) if logged in.
 func AuthSession(c *gin.Context) {
 	addr := middleware.GetSessionWallet(c)
 	if addr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"address": addr,
+		"address":    addr,
+		"mint_quota": services.GetMintQuota(addr),
 	})
 }
 
+// sessionTokenHash returns the hash of the request's session token, or "".
+func sessionTokenHash(c *gin.Context) string {
+	token, err := c.Cookie(sessionCookieName)
+	if err != nil || token == "" {
+		return ""
+	}
+	return util.HashToken(token)
+}
+
+// AuthListSessions handles GET /api/auth/sessions
+// Lists the wallet's active sessions (device, login IP, last seen), marking the current one.
+func AuthListSessions(c *gin.Context) {
+	sessions, err := services.ListWalletSessions(middleware.GetSessionWallet(c), sessionTokenHash(c))
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// AuthRevokeSession handles DELETE /api/auth/sessions/:id
+// Logs one of the wallet's devices out. Revoking the current session also clears the cookie.
+func AuthRevokeSession(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "invalid session ID")
+		return
+	}
+
+	current := services.CurrentWalletSessionID(sessionTokenHash(c))
+	if err := services.RevokeWalletSession(middleware.GetSessionWallet(c), id); err != nil {
+		if errors.Is(err, services.ErrWalletSessionNotFound) {
+			util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Session not found")
+			return
+		}
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+	if current == id {
+		clearSessionCookie(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
+
+// AuthRevokeSessions handles DELETE /api/auth/sessions?keep_current=true
+// Logs the wallet out everywhere, or with keep_current everywhere but here.
+func AuthRevokeSessions(c *gin.Context) {
+	keepCurrent := c.Query("keep_current") == "true"
+	keep := ""
+	if keepCurrent {
+		keep = sessionTokenHash(c)
+	}
+
+	revoked, err := services.RevokeWalletSessions(middleware.GetSessionWallet(c), keep)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+	if !keepCurrent {
+		clearSessionCookie(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked", "revoked": revoked})
+}
+
 // ClawBindKey handles POST /api/claw/keys
 // Binds a Claw API key to the session wallet.
 func ClawBindKey(c *gin.Context) {
 	addr := middleware.GetSessionWallet(c)
 	if addr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
 		return
 	}
 
@@ -137,7 +195,7 @@ func ClawBindKey(c *gin.Context) {
 		APIKey string `json:"api_key" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "api_key is required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "api_key is required")
 		return
 	}
 
@@ -145,14 +203,14 @@ func ClawBindKey(c *gin.Context) {
 	keyHash := util.HashToken(req.APIKey)
 	var claw models.Claw
 	if err := database.DB.Where("api_key_hash = ?", keyHash).First(&claw).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidAPIKey, "Invalid API key")
 		return
 	}
 
 	// Check if already bound
 	var existing models.ClawBinding
 	if err := database.DB.Where("wallet_addr = ? AND claw_id = ?", addr, claw.ID).First(&existing).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "This Claw is already bound to your wallet"})
+		util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, "This Claw is already bound to your wallet")
 		return
 	}
 
@@ -163,7 +221,7 @@ func ClawBindKey(c *gin.Context) {
 		ClawName:   claw.Name,
 	}
 	if err := database.DB.Create(binding).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to bind Claw"})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to bind Claw")
 		return
 	}
 
@@ -178,7 +236,7 @@ func ClawBindKey(c *gin.Context) {
 func ClawListKeys(c *gin.Context) {
 	addr := middleware.GetSessionWallet(c)
 	if addr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
 		return
 	}
 
@@ -208,51 +266,101 @@ func ClawListKeys(c *gin.Context) {
 func ClawUnbindKey(c *gin.Context) {
 	addr := middleware.GetSessionWallet(c)
 	if addr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
 		return
 	}
 
 	id := c.Param("id")
 	result := database.DB.Where("id = ? AND wallet_addr = ?", id, addr).Delete(&models.ClawBinding{})
 	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Binding not found"})
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Binding not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Claw unbound"})
 }
 
-// ClawBoundDashboard handles GET /api/claw/keys/:id/dashboard
-// Returns the dashboard data for a specific bound Claw.
-func ClawBoundDashboard(c *gin.Context) {
+// boundClaw loads the Claw of the binding in the :id param, which must belong
+// to the session wallet, writing the error response if it can't.
+func boundClaw(c *gin.Context) (*models.Claw, bool) {
 	addr := middleware.GetSessionWallet(c)
 	if addr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
-		return
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
+		return nil, false
 	}
 
-	id := c.Param("id")
-
 	// Find the binding (must belong to this wallet)
 	var binding models.ClawBinding
-	if err := database.DB.Where("id = ? AND wallet_addr = ?", id, addr).First(&binding).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Binding not found"})
-		return
+	if err := database.DB.Where("id = ? AND wallet_addr = ?", c.Param("id"), addr).First(&binding).Error; err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Binding not found")
+		return nil, false
 	}
 
 	// Load the Claw
 	var claw models.Claw
 	if err := database.DB.First(&claw, "id = ?", binding.ClawID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Claw not found"})
+		util.RespondError(c, http.StatusNotFound, util.CodeClawNotFound, "Claw not found")
+		return nil, false
+	}
+	return &claw, true
+}
+
+// ClawBoundDashboard handles GET /api/claw/keys/:id/dashboard
+// Returns the dashboard data for a specific bound Claw.
+func ClawBoundDashboard(c *gin.Context) {
+	claw, ok := boundClaw(c)
+	if !ok {
 		return
 	}
 
 	// Reuse existing dashboard logic
-	dashboard, err := services.GetClawDashboard(&claw)
+	dashboard, err := services.GetClawDashboard(claw)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, dashboard)
 }
+
+// ClawBoundContributions handles GET /api/claw/keys/:id/contributions?q=&archived=true
+// Returns a bound Claw's contributions with their private notes for its
+// operator; q searches notes and content, archived=true lists archived ones.
+func ClawBoundContributions(c *gin.Context) {
+	claw, ok := boundClaw(c)
+	if !ok {
+		return
+	}
+
+	result, err := services.GetClawContributions(claw, c.DefaultQuery("page", "1"), c.DefaultQuery("limit", "20"), c.Query("q"), c.Query("archived") == "true")
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ClawDeleteBound handles DELETE /api/claw/keys/:id/claw?confirm=<claw name>
+// Lets the wallet operating a bound Claw delete it under CLAW_DELETE_POLICY.
+func ClawDeleteBound(c *gin.Context) {
+	addr := middleware.GetSessionWallet(c)
+	if addr == "" {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
+		return
+	}
+
+	var binding models.ClawBinding
+	if err := database.DB.Where("id = ? AND wallet_addr = ?", c.Param("id"), addr).First(&binding).Error; err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Binding not found")
+		return
+	}
+
+	var claw models.Claw
+	if err := database.DB.First(&claw, "id = ?", binding.ClawID).Error; err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeClawNotFound, "Claw not found")
+		return
+	}
+
+	deleteClaw(c, &claw, addr)
+}
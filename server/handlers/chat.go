@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"unicode/utf8"
 
+	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
 	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -12,19 +19,62 @@ import (
 // ChatCreateSession handles POST /api/chat/:handle/session
 // Creates a new chat session. If user is logged in, session is linked to wallet.
 func ChatCreateSession(c *gin.Context) {
-	handle := services.SanitizeHandle(c.Param("handle"))
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
 	walletAddr := middleware.GetSessionWallet(c)
 
-	session, err := services.CreateChatSession(handle, walletAddr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	dnaVersion := 0
+	if v := c.Query("dna_version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "dna_version must be a positive integer")
+			return
+		}
+		dnaVersion = n
+	}
+
+	session, err := services.CreateChatSession(handle, walletAddr, c.ClientIP(), dnaVersion)
+	switch {
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+		return
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+		return
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+		return
+	case errors.Is(err, services.ErrRegistryPaused):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeRegistryPaused, err.Error())
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	settings := services.GetShellSettings(session.ShellID)
+	resp := gin.H{
 		"session_id": session.ID,
 		"tier":       session.Tier,
-	})
+		"greeting":   settings.Greeting,
+		"quota":      services.GetChatRoundQuota(session.Subject, session.ShellID, session.Tier),
+	}
+	if shell, err := services.GetShellByHandle(handle); err == nil {
+		if pricing := services.GetChatPricing(shell, settings); pricing.Enabled {
+			resp["pricing"] = pricing
+			resp["credits"] = services.GetChatCreditBalance(session.Subject, session.ShellID, settings)
+		}
+	}
+	if session.DNAVersion > 0 {
+		resp["dna_version"] = session.DNAVersion
+		resp["time_travel"] = true
+		if label, err := services.TimeTravelSessionLabel(session); err == nil {
+			resp["label"] = label
+		}
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // ChatListSessions handles GET /api/chat/sessions
@@ -32,14 +82,17 @@ func ChatCreateSession(c *gin.Context) {
 func ChatListSessions(c *gin.Context) {
 	walletAddr := middleware.GetSessionWallet(c)
 	if walletAddr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "login required"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "login required")
 		return
 	}
 
-	handle := c.Query("handle")
+	handle, ok := handleQuery(c)
+	if !ok {
+		return
+	}
 	sessions, err := services.ListChatSessions(walletAddr, handle)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
 		return
 	}
 
@@ -47,28 +100,31 @@ func ChatListSessions(c *gin.Context) {
 }
 
 // ChatGetSession handles GET /api/chat/sessions/:id
-// Returns a chat session with its messages.
+// Returns a chat session with its messages and its history token budget use.
 func ChatGetSession(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "invalid session ID")
 		return
 	}
 
 	session, err := services.GetChatSession(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
 		return
 	}
 
 	// Only allow session owner or guest sessions to be accessed
 	walletAddr := middleware.GetSessionWallet(c)
 	if session.WalletAddr != "" && session.WalletAddr != walletAddr {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		util.RespondError(c, http.StatusForbidden, util.CodeForbidden, "access denied")
 		return
 	}
 
-	c.JSON(http.StatusOK, session)
+	c.JSON(http.StatusOK, struct {
+		*models.ChatSession
+		Context services.ChatContextUsage `json:"context"`
+	}{session, services.GetChatContextUsage(session)})
 }
 
 // ChatDeleteSession handles DELETE /api/chat/sessions/:id
@@ -76,44 +132,105 @@ func ChatGetSession(c *gin.Context) {
 func ChatDeleteSession(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "invalid session ID")
 		return
 	}
 
 	walletAddr := middleware.GetSessionWallet(c)
 	if walletAddr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "login required"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "login required")
 		return
 	}
 
 	if err := services.DeleteChatSession(id, walletAddr); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
 }
 
+// ChatExportSession handles GET /api/chat/sessions/:id/export?format=markdown|json
+// Downloads the full transcript of one of the logged-in user's sessions.
+func ChatExportSession(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "invalid session ID")
+		return
+	}
+	format := c.DefaultQuery("format", "markdown")
+	if format != "markdown" && format != "json" {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "format must be markdown or json")
+		return
+	}
+
+	walletAddr := middleware.GetSessionWallet(c)
+	if walletAddr == "" {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "login required")
+		return
+	}
+
+	transcript, err := services.ExportChatSession(id, walletAddr)
+	if err != nil {
+		if errors.Is(err, services.ErrChatSessionNotOwned) {
+			util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+			return
+		}
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("ensoul-%s-%s", transcript.Handle, transcript.CreatedAt.UTC().Format("20060102-150405"))
+	if format == "json" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.JSON(http.StatusOK, transcript)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.md"`)
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(transcript.Markdown()))
+}
+
+// ChatDeleteHistory handles DELETE /api/chat/history
+// Permanently deletes all of the logged-in wallet's chat sessions, messages and shares.
+func ChatDeleteHistory(c *gin.Context) {
+	walletAddr := middleware.GetSessionWallet(c)
+	if walletAddr == "" {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "login required")
+		return
+	}
+
+	counts, err := services.DeleteChatHistory(walletAddr)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted", "deleted": counts})
+}
+
 // ChatSendMessage handles POST /api/chat/sessions/:id/message
 // Sends a message in a chat session and streams the response.
+// Body: {"message": "...", "attest": true}; attest adds an "attestation"
+// event with the DNA version and anchored prompt hash used to answer.
 func ChatSendMessage(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "invalid session ID")
 		return
 	}
 
 	var req struct {
 		Message string `json:"message" binding:"required"`
+		Attest  bool   `json:"attest"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "message is required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "message is required")
 		return
 	}
 
 	// Input length limit — prevent abuse of LLM tokens and DB storage
-	if len(req.Message) > 2000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "message too long (max 2000 characters)"})
+	if max := config.Cfg.ChatMaxMessageChars; utf8.RuneCountInString(req.Message) > max {
+		respondMessageTooLong(c, fmt.Sprintf("message too long (max %d characters)", max))
 		return
 	}
 
@@ -122,18 +239,61 @@ func ChatSendMessage(c *gin.Context) {
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 
-	if err := services.ChatWithSoul(c, id, req.Message); err != nil {
+	if err := services.ChatWithSoul(c, id, req.Message, req.Attest); err != nil {
 		c.SSEvent("error", err.Error())
 		return
 	}
 }
 
+// respondMessageTooLong writes the 413 for a chat message over CHAT_MAX_MESSAGE_CHARS.
+func respondMessageTooLong(c *gin.Context, message string) {
+	util.RespondAPIError(c, http.StatusRequestEntityTooLarge, util.APIError{
+		Code:    util.CodePayloadTooLarge,
+		Message: message,
+		Details: map[string]interface{}{"max_chars": config.Cfg.ChatMaxMessageChars},
+	})
+}
+
+// ChatResumeStream handles GET /api/chat/sessions/:id/stream
+// Resumes a reply after a dropped connection: replays what was missed after the
+// Last-Event-ID header (or last_event_id query) and streams the rest.
+func ChatResumeStream(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "invalid session ID")
+		return
+	}
+
+	session, err := services.GetChatSession(id)
+	if err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+		return
+	}
+	walletAddr := middleware.GetSessionWallet(c)
+	if session.WalletAddr != "" && session.WalletAddr != walletAddr {
+		util.RespondError(c, http.StatusForbidden, util.CodeForbidden, "access denied")
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	err = services.ResumeChatStream(c, id, lastEventID)
+	switch {
+	case errors.Is(err, services.ErrChatStreamNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+	case err != nil:
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+	}
+}
+
 // GetStats handles GET /api/stats
 // Returns global statistics for the landing page dashboard.
 func GetStats(c *gin.Context) {
 	stats, err := services.GetGlobalStats()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
 		return
 	}
 
@@ -141,15 +301,59 @@ func GetStats(c *gin.Context) {
 }
 
 // GetTasks handles GET /api/tasks
-// Returns the task board — dimensions that need more fragments.
+// Returns the task board — dimensions that need more fragments — with reward,
+// evidence and acceptance hints, as an array. Filters: handle, dimension,
+// priority, tag (soul topic tags, comma-separated), min_followers; page and
+// limit return one page of the array. ?format=board returns a page object
+// with tasks, total, page and limit instead.
+// ?fit=true (Claw API key) keeps only tasks matching the Claw's capability tags, best fit first.
 func GetTasks(c *gin.Context) {
-	tasks, err := services.GetTaskBoard()
+	claw := middleware.GetClaw(c)
+	fit := c.Query("fit") == "true" || c.Query("fit") == "1"
+	if fit && claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "fit=true requires a valid Claw API key")
+		return
+	}
+
+	handle, ok := handleQuery(c)
+	if !ok {
+		return
+	}
+	tags, err := services.ParseTagFilter(c.Query("tag"))
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+	minFollowers, _ := strconv.Atoi(c.Query("min_followers"))
+	filter := services.TaskBoardFilter{
+		Handle:       handle,
+		Dimension:    c.Query("dimension"),
+		Priority:     c.Query("priority"),
+		Tags:         tags,
+		MinFollowers: minFollowers,
+	}
+
+	if c.Query("format") != "board" && c.Query("page") == "" && c.Query("limit") == "" {
+		tasks, err := services.ListAllTasks(claw, fit, filter)
+		if err != nil {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, tasks)
+		return
+	}
+
+	result, err := services.ListTasks(claw, fit, filter, c.Query("page"), c.Query("limit"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	if c.Query("format") == "board" {
+		c.JSON(http.StatusOK, result)
+	} else {
+		c.JSON(http.StatusOK, result["tasks"])
+	}
 }
 
 // ChatCreateShare handles POST /api/chat/share
@@ -160,25 +364,25 @@ func ChatCreateShare(c *gin.Context) {
 		MessageIndex int    `json:"message_index"` // -1 = last 3 pairs, 0+ = specific assistant message
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "session_id is required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "session_id is required")
 		return
 	}
 
 	sessionID, err := uuid.Parse(req.SessionID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "invalid session ID")
 		return
 	}
 
 	share, err := services.CreateChatShare(sessionID, req.MessageIndex)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":      share.Code,
-		"share_url": "https://ensoul.ac/s/" + share.Code,
+		"share_url": config.Cfg.PublicURL("/s/" + share.Code),
 	})
 }
 
@@ -187,13 +391,13 @@ func ChatCreateShare(c *gin.Context) {
 func ChatGetShare(c *gin.Context) {
 	code := c.Param("code")
 	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "share code is required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "share code is required")
 		return
 	}
 
 	share, err := services.GetChatShare(code)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "share not found"})
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "share not found")
 		return
 	}
 
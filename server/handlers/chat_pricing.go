@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// chatPurchaseRequest is the body for buying chat round bundles.
+type chatPurchaseRequest struct {
+	Bundles   int    `json:"bundles"` // defaults to 1
+	TxHash    string `json:"tx_hash" binding:"required"`
+	FeeTxHash string `json:"fee_tx_hash"`
+	KeyID     string `json:"key_id"` // credit one of the wallet's developer keys instead
+}
+
+// chatPricingError writes the response for a chat pricing service error.
+func chatPricingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrChatPricingOff):
+		util.RespondError(c, http.StatusConflict, util.CodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrDeveloperKeyNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Developer key not found")
+	case errors.Is(err, services.ErrChatPaymentsDown):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeUpstream, err.Error())
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+	case errors.Is(err, services.ErrRegistryPaused):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeRegistryPaused, err.Error())
+	default:
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+	}
+}
+
+// ShellChatPricing handles GET /api/shell/:handle/chat-pricing
+// Returns the free rounds, bundle price and where to send each payment.
+func ShellChatPricing(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, services.GetChatPricing(shell, services.GetShellSettings(shell.ID)))
+}
+
+// ChatCredits handles GET /api/chat/:handle/credits?key_id=
+// Returns the logged-in wallet's (or one of its developer keys') free and
+// purchased rounds with the soul.
+func ChatCredits(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	subject, err := services.ChatCreditSubject(middleware.GetSessionWallet(c), c.Query("key_id"))
+	if err != nil {
+		chatPricingError(c, err)
+		return
+	}
+
+	settings := services.GetShellSettings(shell.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"pricing": services.GetChatPricing(shell, settings),
+		"balance": services.GetChatCreditBalance(subject, shell.ID, settings),
+	})
+}
+
+// ChatPurchaseRounds handles POST /api/chat/:handle/credits
+// Credits the logged-in wallet with round bundles once its payments are
+// verified on-chain: tx_hash sends bundles × owner_share_wei to the owner,
+// fee_tx_hash sends bundles × fee_wei to the platform wallet (if fee_wei > 0).
+// With key_id the rounds go to one of the wallet's developer keys.
+func ChatPurchaseRounds(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	wallet := middleware.GetSessionWallet(c)
+
+	var req chatPurchaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: tx_hash; optional: fee_tx_hash, bundles, key_id")
+		return
+	}
+	if req.Bundles == 0 {
+		req.Bundles = 1
+	}
+
+	purchase, balance, err := services.PurchaseChatRounds(shell, wallet, req.KeyID, req.Bundles, req.TxHash, req.FeeTxHash)
+	if err != nil {
+		chatPricingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"purchase": purchase, "balance": balance})
+}
+
+// ShellChatRevenue handles GET /api/shell/:handle/chat-revenue
+// Owner-only, signed message "ensoul:chat-revenue:<handle>:<timestamp>".
+func ShellChatRevenue(c *gin.Context) {
+	shell, _, ok := ownedShell(c, "chat-revenue")
+	if !ok {
+		return
+	}
+
+	revenue, err := services.GetChatRevenue(shell)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to load chat revenue")
+		return
+	}
+
+	c.JSON(http.StatusOK, revenue)
+}
@@ -1,83 +1,147 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
 	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
 	"github.com/gin-gonic/gin"
 )
 
 // ClawRegister handles POST /api/claw/register
-// Registers a new Claw (AI agent) and returns api_key + claim info.
+// Registers a new Claw (AI agent) and returns api_key + claim info. Signed
+// wallet headers over "ensoul:register-claw:<name>:<timestamp>" bind the Claw
+// to that operator wallet right away; CLAW_REGISTER_REQUIRE_WALLET makes them mandatory.
 func ClawRegister(c *gin.Context) {
 	var req struct {
-		Name        string `json:"name" binding:"required"`
-		Description string `json:"description"`
+		Name              string   `json:"name" binding:"required"`
+		Description       string   `json:"description"`
+		VerificationToken string   `json:"verification_token"`
+		Tags              []string `json:"tags"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "name is required")
 		return
 	}
 
+	// The operator signs the name as sent
+	rawName := req.Name
+
 	// Sanitize and validate Claw name to prevent Unicode homoglyph attacks
 	cleanName, err := services.ValidateClawName(req.Name)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
 		return
 	}
 	req.Name = cleanName
 
 	if len(req.Description) > 500 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "description too long (max 500 characters)"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "description too long (max 500 characters)")
 		return
 	}
 
-	result, err := services.RegisterClaw(req.Name, req.Description)
+	tags, err := services.NormalizeClawTags(req.Tags)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register claw: " + err.Error()})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	ip := c.ClientIP()
+	if err := services.CheckRegistrationIPCap(ip); err != nil {
+		util.RespondError(c, http.StatusTooManyRequests, util.CodeRateLimited, err.Error())
+		return
+	}
+	if err := services.VerifyRegistration(req.VerificationToken, ip); err != nil {
+		util.RespondError(c, http.StatusForbidden, util.CodeVerificationFailed, err.Error())
+		return
+	}
+
+	var operator string
+	if c.GetHeader("X-Wallet-Address") != "" {
+		var ok bool
+		if operator, ok = requireSignedAction(c, "register-claw", rawName); !ok {
+			return
+		}
+	}
+
+	result, err := services.RegisterClaw(req.Name, req.Description, ip, operator, tags)
+	if errors.Is(err, services.ErrClawNameTaken) {
+		util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrOperatorRequired) {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrClawWalletLimit) {
+		util.RespondError(c, http.StatusForbidden, util.CodeForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to register claw: "+err.Error())
 		return
 	}
 
 	c.JSON(http.StatusCreated, result)
 }
 
+// ClawRegisterChallenge handles GET /api/claw/register/challenge
+// Returns the proof (proof-of-work challenge or CAPTCHA) required to register.
+func ClawRegisterChallenge(c *gin.Context) {
+	c.JSON(http.StatusOK, services.NewRegistrationChallenge())
+}
+
 // ClawStatus handles GET /api/claw/status
 // Returns the claim status of the authenticated Claw.
 func ClawStatus(c *gin.Context) {
 	claw := middleware.GetClaw(c)
 	if claw == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":    claw.Status,
 		"claimed":   claw.Status == "claimed",
-		"claim_url": "/claim/" + claw.ClaimCode,
+		"probation": services.ClawOnProbation(claw),
+		"claim_url": config.Cfg.ClaimURL(claw.ClaimCode),
 	})
 }
 
 // ClawClaimVerify handles POST /api/claw/claim/verify
-// Claims a Claw via wallet session. No tweet verification required.
+// Claims a Claw via wallet session. An optional tweet_url containing the
+// Claw's verification code also verifies the operator's Twitter account.
 func ClawClaimVerify(c *gin.Context) {
 	addr := middleware.GetSessionWallet(c)
 	if addr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Wallet session required to claim a Claw"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Wallet session required to claim a Claw")
 		return
 	}
 
 	var req struct {
 		ClaimCode string `json:"claim_code" binding:"required"`
+		TweetURL  string `json:"tweet_url"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "claim_code is required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "claim_code is required")
 		return
 	}
 
-	result, err := services.ClaimClaw(req.ClaimCode, addr)
+	result, err := services.ClaimClaw(req.ClaimCode, addr, strings.TrimSpace(req.TweetURL))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrTwitterUnavailable):
+			util.RespondError(c, http.StatusServiceUnavailable, util.CodeUpstream, "Tweet verification is unavailable, claim without tweet_url")
+		case errors.Is(err, services.ErrClawTweetInvalid):
+			util.RespondError(c, http.StatusForbidden, util.CodeVerificationFailed, err.Error())
+		default:
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		}
 		return
 	}
 
@@ -89,7 +153,7 @@ func ClawClaimVerify(c *gin.Context) {
 func ClawMe(c *gin.Context) {
 	claw := middleware.GetClaw(c)
 	if claw == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
 		return
 	}
 
@@ -100,8 +164,16 @@ func ClawMe(c *gin.Context) {
 		"claim_code":        claw.ClaimCode,
 		"verification_code": claw.VerificationCode,
 		"status":            claw.Status,
+		"probation":         services.ClawOnProbation(claw),
 		"twitter_handle":    claw.TwitterHandle,
+		"twitter_verified":  claw.TwitterVerifiedAt != nil,
 		"wallet_addr":       claw.WalletAddr,
+		"custody_mode":      claw.CustodyMode,
+		"operator_wallet":   claw.OperatorWallet,
+		"agent_id":          claw.AgentID,
+		"trust_score":       claw.TrustScore,
+		"tags":              claw.Tags,
+		"last_seen_at":      claw.LastSeenAt,
 		"total_submitted":   claw.TotalSubmitted,
 		"total_accepted":    claw.TotalAccepted,
 		"earnings":          claw.Earnings,
@@ -114,13 +186,13 @@ func ClawMe(c *gin.Context) {
 func ClawDashboard(c *gin.Context) {
 	claw := middleware.GetClaw(c)
 	if claw == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
 		return
 	}
 
 	dashboard, err := services.GetClawDashboard(claw)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
 		return
 	}
 
@@ -133,7 +205,7 @@ func ClawClaimInfo(c *gin.Context) {
 	code := c.Param("code")
 	claw, err := services.GetClawByClaimCode(code)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Claim code not found"})
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Claim code not found")
 		return
 	}
 
@@ -145,21 +217,23 @@ func ClawClaimInfo(c *gin.Context) {
 	})
 }
 
-// ClawContributions handles GET /api/claw/contributions
-// Returns the contribution history of the authenticated Claw.
+// ClawContributions handles GET /api/claw/contributions?q=&archived=true
+// Returns the contribution history of the authenticated Claw with private
+// notes; q searches notes and content. archived=true lists the rejected
+// fragments moved to the archive instead.
 func ClawContributions(c *gin.Context) {
 	claw := middleware.GetClaw(c)
 	if claw == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
 		return
 	}
 
 	page := c.DefaultQuery("page", "1")
 	limit := c.DefaultQuery("limit", "20")
 
-	result, err := services.GetClawContributions(claw, page, limit)
+	result, err := services.GetClawContributions(claw, page, limit, c.Query("q"), c.Query("archived") == "true")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
 		return
 	}
 
@@ -172,20 +246,59 @@ func ClawPublicProfile(c *gin.Context) {
 	id := c.Param("id")
 	result, err := services.GetClawPublicProfile(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
 
+// ClawAgentCard handles GET /api/claw/:id/agent-card
+// Returns the Claw's ERC-8004 registration file (the agentURI target).
+func ClawAgentCard(c *gin.Context) {
+	card, err := services.GetClawAgentCard(c.Param("id"))
+	if err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, card)
+}
+
+// ClawRegisterAgent handles POST /api/claw/agent/register
+// Registers the authenticated Claw as an ERC-8004 agent owned by its own wallet.
+func ClawRegisterAgent(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	if err := services.RegisterClawAgent(claw); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"agent_id":  claw.AgentID,
+		"tx_hash":   claw.AgentTxHash,
+		"agent_uri": services.ClawAgentURI(claw.ID),
+	})
+}
+
 // ClawLeaderboard handles GET /api/claw/leaderboard
-// Returns ranked list of Claws by accepted fragments.
+// Returns ranked list of Claws by accepted fragments; ?period=weekly|monthly|all picks the
+// season (default all), ?active=true keeps only live agents.
 func ClawLeaderboard(c *gin.Context) {
 	page := c.DefaultQuery("page", "1")
 	limit := c.DefaultQuery("limit", "20")
-	result, err := services.GetClawLeaderboard(page, limit)
+	period := c.DefaultQuery("period", models.LeaderboardAllTime)
+	if !services.IsLeaderboardPeriod(period) {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "period must be weekly, monthly or all")
+		return
+	}
+	activeOnly := c.Query("active") == "true" || c.Query("active") == "1"
+	result, err := services.GetClawLeaderboard(page, limit, period, activeOnly)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -194,11 +307,170 @@ func ClawLeaderboard(c *gin.Context) {
 // ShellContributors handles GET /api/shell/:handle/contributors
 // Returns top contributors for a specific shell.
 func ShellContributors(c *gin.Context) {
-	handle := services.SanitizeHandle(c.Param("handle"))
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
 	result, err := services.GetShellContributors(handle)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"contributors": result})
 }
+
+// ClawHeartbeat handles POST /api/claw/heartbeat
+// Lets an agent report liveness, plus optionally its version and capabilities.
+func ClawHeartbeat(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	var req struct {
+		Version      string   `json:"version"`
+		Capabilities []string `json:"capabilities"`
+	}
+	// An empty body is a valid heartbeat
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Optional: version, capabilities")
+			return
+		}
+	}
+
+	if err := services.RecordClawHeartbeat(claw, req.Version, req.Capabilities); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":                "ok",
+		"last_seen_at":          claw.LastSeenAt,
+		"active_window_minutes": int(services.ClawActiveWindow().Minutes()),
+	})
+}
+
+// ClawSetTags handles PUT /api/claw/tags
+// Replaces the authenticated Claw's capability tags (languages, domains, data sources).
+func ClawSetTags(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Tags == nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: tags (array, may be empty)")
+		return
+	}
+
+	if err := services.SetClawTags(claw, req.Tags); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": claw.Tags})
+}
+
+// ClawRename handles PUT /api/claw/:id/name
+// Renames a Claw. Only its operator wallet (signed at registration or the
+// claiming wallet) may rename it.
+func ClawRename(c *gin.Context) {
+	addr := middleware.GetSessionWallet(c)
+	if addr == "" {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
+		return
+	}
+
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "name is required")
+		return
+	}
+	name, err := services.ValidateClawName(req.Name)
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	claw, err := services.RenameClaw(c.Param("id"), addr, name)
+	if err != nil {
+		respondClawOperatorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": claw.ID, "name": claw.Name, "status": claw.Status})
+}
+
+// ClawRetire handles POST /api/claw/:id/retire
+// Permanently disables a Claw's API key on behalf of its operator wallet.
+func ClawRetire(c *gin.Context) {
+	addr := middleware.GetSessionWallet(c)
+	if addr == "" {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
+		return
+	}
+
+	claw, err := services.RetireClaw(c.Param("id"), addr)
+	if err != nil {
+		respondClawOperatorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": claw.ID, "name": claw.Name, "status": claw.Status, "retired_at": claw.RetiredAt})
+}
+
+// respondClawOperatorError maps rename/retire errors to responses.
+func respondClawOperatorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotClawOperator):
+		util.RespondError(c, http.StatusForbidden, util.CodeNotOwner, err.Error())
+	case errors.Is(err, services.ErrClawRetired):
+		util.RespondError(c, http.StatusConflict, util.CodeClawRetired, err.Error())
+	case errors.Is(err, services.ErrClawNameTaken):
+		util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+	default:
+		util.RespondError(c, http.StatusNotFound, util.CodeClawNotFound, err.Error())
+	}
+}
+
+// ClawDeleteSelf handles DELETE /api/claw/me?confirm=<claw name>
+// Deletes the authenticated Claw under the configured CLAW_DELETE_POLICY.
+func ClawDeleteSelf(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+	deleteClaw(c, claw, "claw:"+claw.ID.String())
+}
+
+// deleteClaw requires ?confirm=<claw name> before deleting, so a stray
+// request cannot remove a Claw.
+func deleteClaw(c *gin.Context, claw *models.Claw, requestedBy string) {
+	if c.Query("confirm") != claw.Name {
+		util.RespondAPIError(c, http.StatusBadRequest, util.APIError{
+			Code:    util.CodeConfirmRequired,
+			Message: "Pass ?confirm=<claw name> to delete this Claw",
+			Details: gin.H{"policy": services.ClawDeletePolicy()},
+		})
+		return
+	}
+
+	counts, err := services.DeleteClaw(claw, requestedBy)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "deleted",
+		"policy":  services.ClawDeletePolicy(),
+		"deleted": counts,
+	})
+}
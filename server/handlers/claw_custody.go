@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// clawWalletRequest is the body for choosing a Claw's wallet custody.
+type clawWalletRequest struct {
+	Custody string `json:"custody" binding:"required"` // "external" or "platform"
+	// For external custody: the address, and its signature of
+	// "ensoul:claw-wallet-address:<claw_id>:<address_timestamp>"
+	Address          string `json:"address"`
+	AddressSignature string `json:"address_signature"`
+	AddressTimestamp int64  `json:"address_timestamp"`
+}
+
+// clawCustodyError writes the response for a Claw custody service error.
+func clawCustodyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotClawOperator):
+		util.RespondError(c, http.StatusForbidden, util.CodeNotOwner, err.Error())
+	case errors.Is(err, services.ErrClawRetired):
+		util.RespondError(c, http.StatusConflict, util.CodeClawRetired, err.Error())
+	case errors.Is(err, services.ErrClawNotClaimed):
+		util.RespondError(c, http.StatusForbidden, util.CodeClawNotClaimed, err.Error())
+	case errors.Is(err, services.ErrNotExternalCustody), errors.Is(err, services.ErrClawNoWalletKey):
+		util.RespondError(c, http.StatusConflict, util.CodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrFeedbackNotPending):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrClawNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeClawNotFound, err.Error())
+	case errors.Is(err, services.ErrFeedbackRelay):
+		util.Log.Error("[claw] %v", err)
+		util.RespondError(c, http.StatusBadGateway, util.CodeUpstream, "Failed to relay feedback. Please try again.")
+	default:
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+	}
+}
+
+// ClawWalletExport handles POST /api/claw/:id/wallet/export
+// Operator-signed message "ensoul:claw-wallet-export:<claw_id>:<timestamp>".
+// Body: {"passphrase": "..."}. Returns the Claw's generated key as a keystore
+// v3 JSON encrypted with the passphrase.
+func ClawWalletExport(c *gin.Context) {
+	operator, ok := requireSignedAction(c, "claw-wallet-export", c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Passphrase string `json:"passphrase" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: passphrase")
+		return
+	}
+
+	address, keystore, err := services.ExportClawKey(c.Param("id"), operator, req.Passphrase)
+	if err != nil {
+		clawCustodyError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"address": address, "keystore": keystore})
+}
+
+// ClawWalletSet handles PUT /api/claw/:id/wallet
+// Operator-signed message "ensoul:claw-wallet:<claw_id>:<timestamp>".
+// Switches the Claw to an external address it controls, or back to its
+// platform wallet.
+func ClawWalletSet(c *gin.Context) {
+	clawID := c.Param("id")
+	operator, ok := requireSignedAction(c, "claw-wallet", clawID)
+	if !ok {
+		return
+	}
+
+	var req clawWalletRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: custody; for external: address, address_signature, address_timestamp")
+		return
+	}
+
+	var claw *models.Claw
+	var err error
+	switch req.Custody {
+	case models.ClawCustodyPlatform:
+		claw, err = services.UsePlatformClawWallet(clawID, operator)
+	case models.ClawCustodyExternal:
+		if !common.IsHexAddress(req.Address) {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid wallet address format")
+			return
+		}
+		if age := time.Since(time.Unix(req.AddressTimestamp, 0)); age > ownerSignatureMaxAge || age < -time.Minute {
+			util.RespondError(c, http.StatusUnauthorized, util.CodeSignatureExpired, "Address signature expired, please sign again")
+			return
+		}
+		message := fmt.Sprintf("ensoul:claw-wallet-address:%s:%s", clawID, strconv.FormatInt(req.AddressTimestamp, 10))
+		if err := middleware.VerifyWalletSignature(message, req.AddressSignature, common.HexToAddress(req.Address)); err != nil {
+			util.RespondError(c, http.StatusUnauthorized, util.CodeInvalidSignature, "Invalid address signature: "+err.Error())
+			return
+		}
+		claw, err = services.UseExternalClawWallet(clawID, operator, req.Address)
+	default:
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "custody must be \"external\" or \"platform\"")
+		return
+	}
+	if err != nil {
+		clawCustodyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":            claw.ID,
+		"custody_mode":  claw.CustodyMode,
+		"custody_since": claw.CustodySince,
+		"wallet_addr":   claw.WalletAddr,
+	})
+}
+
+// ClawPendingFeedback handles GET /api/claw/feedback/pending
+// For Claws with an external wallet: the giveFeedback calls awaiting it, each
+// with the message to sign for the platform to relay it.
+func ClawPendingFeedback(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	pending, err := services.PendingClawFeedback(claw)
+	if err != nil {
+		clawCustodyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"wallet_addr": claw.WalletAddr, "feedback": pending})
+}
+
+// ClawSubmitFeedback handles POST /api/claw/feedback/:fragment_id
+// Body: {"signature": "0x..."} of the pending feedback's message, for the
+// platform to relay the call, or {"tx_hash": "0x..."} of the call sent from
+// the Claw's wallet with its own gas.
+func ClawSubmitFeedback(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	var req struct {
+		Signature string `json:"signature"`
+		TxHash    string `json:"tx_hash"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Signature == "") == (req.TxHash == "") {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: signature or tx_hash")
+		return
+	}
+
+	if req.Signature != "" {
+		txHash, err := services.RelayClawFeedback(claw, c.Param("fragment_id"), req.Signature)
+		if err != nil {
+			clawCustodyError(c, err)
+			return
+		}
+		req.TxHash = txHash
+	} else if err := services.SubmitClawFeedbackTx(claw, c.Param("fragment_id"), req.TxHash); err != nil {
+		clawCustodyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"fragment_id": c.Param("fragment_id"), "tx_hash": req.TxHash, "status": models.PendingTxPending})
+}
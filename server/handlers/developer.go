@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// DeveloperKeyCreate handles POST /api/developer/keys
+// Issues a read-only public API key to the session wallet. The key is shown once.
+func DeveloperKeyCreate(c *gin.Context) {
+	addr := middleware.GetSessionWallet(c)
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(strings.TrimSpace(req.Name)) > 100 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "name must be at most 100 characters")
+		return
+	}
+
+	key, plaintext, err := services.CreateDeveloperKey(addr, req.Name)
+	switch {
+	case errors.Is(err, services.ErrDeveloperKeyLimit):
+		util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to create developer key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     key,
+		"api_key": plaintext,
+		"message": "Store this key now, it will not be shown again",
+	})
+}
+
+// DeveloperKeyList handles GET /api/developer/keys
+// Lists the session wallet's developer keys with today's usage.
+func DeveloperKeyList(c *gin.Context) {
+	keys, err := services.ListDeveloperKeys(middleware.GetSessionWallet(c))
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to list developer keys")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// DeveloperKeyRevoke handles DELETE /api/developer/keys/:id
+// Permanently revokes one of the session wallet's keys.
+func DeveloperKeyRevoke(c *gin.Context) {
+	key, err := services.RevokeDeveloperKey(middleware.GetSessionWallet(c), c.Param("id"))
+	switch {
+	case errors.Is(err, services.ErrDeveloperKeyNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Developer key not found")
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"key": key})
+}
+
+// DeveloperKeyUsage handles GET /api/developer/keys/:id/usage?days=30
+// Returns the key's requests, chat completions and chat tokens per UTC day.
+func DeveloperKeyUsage(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 90 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "days must be between 1 and 90")
+		return
+	}
+
+	report, err := services.GetDeveloperKeyUsage(middleware.GetSessionWallet(c), c.Param("id"), days)
+	switch {
+	case errors.Is(err, services.ErrDeveloperKeyNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Developer key not found")
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// meterDeveloperCall counts the request against the developer key's daily
+// quotas and responds 429 when one is used up.
+func meterDeveloperCall(c *gin.Context, chat bool) bool {
+	err := services.MeterDeveloperKey(middleware.GetDeveloperKey(c), chat)
+	if err == nil {
+		return true
+	}
+	var quotaErr *services.DeveloperQuotaError
+	if errors.As(err, &quotaErr) {
+		util.RespondAPIError(c, http.StatusTooManyRequests, util.APIError{
+			Code:       util.CodeAPIQuota,
+			Message:    err.Error(),
+			Details:    gin.H{"kind": quotaErr.Kind, "limit": quotaErr.Limit, "resets_at": quotaErr.ResetsAt},
+			RetryAfter: int(math.Ceil(quotaErr.RetryAfter().Seconds())),
+		})
+		return false
+	}
+	util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to meter request")
+	return false
+}
+
+// PublicSoulGet handles GET /v1/souls/:handle
+// Returns a minted soul's public data (no soul prompt) for a developer key.
+func PublicSoulGet(c *gin.Context) {
+	if !meterDeveloperCall(c, false) {
+		return
+	}
+
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+	shell, err := services.PublicSoul(handle)
+	if err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+	c.JSON(http.StatusOK, shell)
+}
+
+// PublicSoulChat handles POST /v1/souls/:handle/chat
+// Chat completion-style, non-streaming: the caller sends the whole
+// conversation ({messages, max_tokens, temperature, attest}) and gets the soul's reply.
+func PublicSoulChat(c *gin.Context) {
+	var req services.SoulCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if !meterDeveloperCall(c, true) {
+		return
+	}
+
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+	completion, err := services.SoulChatCompletion(c.Request.Context(), middleware.GetDeveloperKey(c), handle, req)
+	switch {
+	case err == nil:
+		if a := completion.Attestation; a != nil {
+			c.Header("X-Ensoul-DNA-Version", strconv.Itoa(a.DNAVersion))
+			c.Header("X-Ensoul-Prompt-Hash", a.PromptHash)
+		}
+		c.JSON(http.StatusOK, completion)
+	case errors.Is(err, services.ErrMessageTooLong):
+		respondMessageTooLong(c, err.Error())
+	case errors.Is(err, services.ErrInvalidCompletion):
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrMessageBlocked):
+		util.RespondError(c, http.StatusBadRequest, util.CodeContentPolicyViolation, err.Error())
+	case errors.Is(err, services.ErrChatPaymentRequired):
+		var payment *services.ChatPaymentError
+		errors.As(err, &payment)
+		util.RespondAPIError(c, http.StatusPaymentRequired, util.APIError{
+			Code:    util.CodePaymentRequired,
+			Message: "This key has used its rounds with @" + handle + ". Buy more with POST /api/chat/" + handle + "/credits and the key's key_id.",
+			Details: map[string]interface{}{"pricing": payment.Pricing, "balance": payment.Balance},
+		})
+	case errors.Is(err, services.ErrSoulUnavailable):
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+	case errors.Is(err, services.ErrSoulChatUnavailable):
+		util.RespondError(c, http.StatusForbidden, util.CodeForbidden, err.Error())
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+	case errors.Is(err, services.ErrRegistryPaused):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeRegistryPaused, err.Error())
+	default:
+		util.Log.Error("[devapi] Chat completion for @%s failed: %v", handle, err)
+		util.RespondError(c, http.StatusBadGateway, util.CodeUpstream, "Failed to generate response. Please try again.")
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPublicSoulChatPricing(t *testing.T) {
+	connectTestDB(t, &config.Config{LLMProvider: "mock", ChatModeration: "off", ChatMaxMessageChars: 2000})
+
+	shell := &models.Shell{Handle: "pricedsoul", Stage: models.StageGrowing, MintTxHash: "0x01", OwnerAddr: "0xOwner"}
+	database.DB.Create(shell)
+	database.DB.Create(&models.ShellSettings{
+		ShellID: shell.ID, ChatEnabled: true, ContentPolicy: models.ContentPolicyDefault,
+		ChatFreeRounds: 1, ChatBundleRounds: 10, ChatBundlePriceWei: "1000",
+	})
+	developer := &models.DeveloperKey{WalletAddr: "0xDeveloper", KeyHash: "dev", KeyPrefix: "ensoul_pk_dev"}
+	owner := &models.DeveloperKey{WalletAddr: "0xowner", KeyHash: "owner", KeyPrefix: "ensoul_pk_own"}
+	database.DB.Create(developer)
+	database.DB.Create(owner)
+
+	chat := func(key *models.DeveloperKey) (int, map[string]interface{}) {
+		r := gin.New()
+		r.POST("/v1/souls/:handle/chat", func(c *gin.Context) { c.Set("developer_key", key) }, PublicSoulChat)
+		return doJSON(t, r, http.MethodPost, "/v1/souls/pricedsoul/chat",
+			gin.H{"messages": []gin.H{{"role": "user", "content": "What are you working on?"}}}, nil)
+	}
+
+	// The free round is charged to the key, then the key has to pay
+	status, resp := chat(developer)
+	if status != http.StatusOK || resp["credits"] == nil {
+		t.Fatalf("first chat = %d %v, want 200 with credits", status, resp)
+	}
+	status, resp = chat(developer)
+	if status != http.StatusPaymentRequired || resp["code"] != string(util.CodePaymentRequired) {
+		t.Fatalf("second chat = %d %v, want 402 PAYMENT_REQUIRED", status, resp)
+	}
+	balance := services.GetChatCreditBalance(services.DeveloperKeySubject(developer.ID), shell.ID, services.GetShellSettings(shell.ID))
+	if balance.FreeUsed != 1 || balance.PaidUsed != 0 {
+		t.Errorf("key balance = %+v, want the one free round used", balance)
+	}
+
+	// Purchased rounds of the key are spent one per completion
+	database.DB.Model(&models.ChatCredit{}).
+		Where("subject = ? AND shell_id = ?", services.DeveloperKeySubject(developer.ID), shell.ID).
+		Update("purchased", 1)
+	if status, resp = chat(developer); status != http.StatusOK {
+		t.Errorf("chat with a purchased round = %d %v, want 200", status, resp)
+	}
+	if status, _ = chat(developer); status != http.StatusPaymentRequired {
+		t.Errorf("chat after the purchased round = %d, want 402", status)
+	}
+
+	// The owner's own keys chat free
+	for i := 0; i < 3; i++ {
+		if status, resp = chat(owner); status != http.StatusOK {
+			t.Fatalf("owner chat %d = %d %v, want 200", i, status, resp)
+		}
+	}
+}
@@ -1,27 +1,36 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"unicode/utf8"
 
 	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
 	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
 	"github.com/gin-gonic/gin"
 )
 
 // FragmentSubmit handles POST /api/fragment/submit (DEPRECATED)
 // Returns 410 Gone and directs callers to use the batch endpoint.
 func FragmentSubmit(c *gin.Context) {
-	c.JSON(http.StatusGone, gin.H{
-		"error":   "This endpoint is deprecated. Use POST /api/fragment/batch instead.",
-		"message": "Submit all dimensions for a soul in a single batch request. See documentation for the new format.",
-		"migrate": "POST /api/fragment/batch with {handle, fragments: [{dimension, content}, ...]}",
+	util.RespondAPIError(c, http.StatusGone, util.APIError{
+		Code:    util.CodeDeprecated,
+		Message: "This endpoint is deprecated. Use POST /api/fragment/batch instead. Submit all dimensions for a soul in a single batch request.",
+		Details: gin.H{"migrate": "POST /api/fragment/batch with {handle, fragments: [{dimension, content}, ...]}"},
 	})
 }
 
 // FragmentBatchItem is a single fragment in a batch submission.
 type FragmentBatchItem struct {
-	Dimension string `json:"dimension" binding:"required"`
-	Content   string `json:"content" binding:"required"`
+	Dimension string                        `json:"dimension" binding:"required"`
+	Content   string                        `json:"content" binding:"required"`
+	Lang      string                        `json:"lang"`   // optional ISO 639-1 code, detected when omitted
+	Claims    []services.FragmentClaimInput `json:"claims"` // optional atomic claims of the content
+	Notes     string                        `json:"notes"`  // optional, private to the Claw
 }
 
 // FragmentBatch handles POST /api/fragment/batch
@@ -29,7 +38,7 @@ type FragmentBatchItem struct {
 func FragmentBatch(c *gin.Context) {
 	claw := middleware.GetClaw(c)
 	if claw == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
 		return
 	}
 
@@ -38,98 +47,216 @@ func FragmentBatch(c *gin.Context) {
 		Fragments []FragmentBatchItem `json:"fragments" binding:"required,min=3,max=6"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request. Required: handle + fragments array (3-6 items)",
-			"example": map[string]interface{}{
+		util.RespondAPIError(c, http.StatusBadRequest, util.APIError{
+			Code:    util.CodeInvalidRequest,
+			Message: "Invalid request. Required: handle + fragments array (3-6 items)",
+			Details: gin.H{"example": map[string]interface{}{
 				"handle": "cz_binance",
 				"fragments": []map[string]string{
 					{"dimension": "personality", "content": "..."},
 					{"dimension": "stance", "content": "..."},
 					{"dimension": "style", "content": "..."},
 				},
-			},
+			}},
 		})
 		return
 	}
 
 	// Sanitize and validate handle
-	cleanHandle, err := services.ValidateHandle(req.Handle)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	cleanHandle, ok := bindHandle(c, req.Handle)
+	if !ok {
 		return
 	}
 	req.Handle = cleanHandle
 
-	// Validate dimensions: each must be valid and no duplicates
-	validDims := map[string]bool{
-		"personality": true, "knowledge": true, "stance": true,
-		"style": true, "relationship": true, "timeline": true,
-	}
-	seenDims := make(map[string]bool)
-	for i, f := range req.Fragments {
-		if !validDims[f.Dimension] {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":            "Invalid dimension in fragment " + string(rune('1'+i)),
-				"valid_dimensions": []string{"personality", "knowledge", "stance", "style", "relationship", "timeline"},
-			})
-			return
-		}
-		if seenDims[f.Dimension] {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Duplicate dimension: " + f.Dimension + ". Each dimension can only appear once per batch.",
-			})
-			return
-		}
-		seenDims[f.Dimension] = true
-
-		if len(f.Content) > 5000 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Content too long for dimension " + f.Dimension + " (max 5000 characters)",
-			})
-			return
-		}
-		if len(f.Content) < 50 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Content too short for dimension " + f.Dimension + " (min 50 characters)",
-			})
-			return
-		}
-	}
-
 	// Convert to service layer input
 	items := make([]services.BatchFragmentItem, len(req.Fragments))
 	for i, f := range req.Fragments {
 		items[i] = services.BatchFragmentItem{
 			Dimension: f.Dimension,
 			Content:   f.Content,
+			Lang:      f.Lang,
+			Claims:    f.Claims,
+			Notes:     f.Notes,
 		}
 	}
 
-	results, err := services.SubmitFragmentBatch(claw, req.Handle, items)
+	batch, results, err := services.SubmitFragmentBatch(claw, req.Handle, items)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit batch: " + err.Error()})
+		submitBatchError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"handle":    req.Handle,
-		"submitted": len(results),
-		"fragments": results,
+		"handle":     req.Handle,
+		"batch_id":   batch.ID,
+		"stream_url": "/api/fragment/batch/" + batch.ID.String() + "/stream",
+		"submitted":  len(results),
+		"fragments":  results,
 	})
 }
 
+// FragmentBatchStream handles GET /api/fragment/batch/:batch_id/stream
+// Streams the curator's verdicts on one of the Claw's batches as SSE.
+func FragmentBatchStream(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	batch, err := services.GetClawBatch(claw, c.Param("batch_id"))
+	if err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Batch not found")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	services.StreamBatchVerdicts(c, batch)
+}
+
+// submitBatchError maps a SubmitFragmentBatch error to its response.
+func submitBatchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSubmission):
+		submissionError(c, err)
+	case errors.Is(err, services.ErrShellNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, err.Error())
+	case errors.Is(err, services.ErrShellNotMinted):
+		util.RespondError(c, http.StatusConflict, util.CodeShellNotMinted, err.Error())
+	case errors.Is(err, services.ErrDimensionNotAccepted):
+		util.RespondError(c, http.StatusForbidden, util.CodeDimensionNotAccepted, err.Error())
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+	case errors.Is(err, services.ErrRegistryPaused):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeRegistryPaused, err.Error())
+	case errors.Is(err, services.ErrContentPolicy):
+		util.RespondError(c, http.StatusBadRequest, util.CodeContentPolicyViolation, err.Error())
+	case errors.Is(err, services.ErrBatchQuota):
+		var quotaErr *services.BatchQuotaError
+		errors.As(err, &quotaErr)
+		util.RespondAPIError(c, http.StatusTooManyRequests, util.APIError{
+			Code:       util.CodeBatchQuota,
+			Message:    err.Error(),
+			Details:    gin.H{"quota": quotaErr.Quota},
+			RetryAfter: int(math.Ceil(quotaErr.RetryAfter().Seconds())),
+		})
+	default:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to submit batch: "+err.Error())
+	}
+}
+
+// submissionError writes the 400 response for a fragment that failed validation.
+func submissionError(c *gin.Context, err error) {
+	var subErr *services.SubmissionError
+	if !errors.As(err, &subErr) {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+	details := gin.H{"dimension": subErr.Dimension, "reason": subErr.Reason}
+	code := util.CodeLowQuality
+	switch subErr.Reason {
+	case services.SubmissionInvalidDimension:
+		code = util.CodeInvalidDimension
+		details["valid_dimensions"] = []string{"personality", "knowledge", "stance", "style", "relationship", "timeline"}
+	case services.SubmissionDuplicateDimension:
+		code = util.CodeDuplicateDimension
+	case services.SubmissionTooShort, services.SubmissionTooLong:
+		code = util.CodeContentLength
+		details["min"], details["max"] = subErr.Min, subErr.Max
+	case services.SubmissionUnsupportedLang, services.SubmissionLangMismatch:
+		code = util.CodeUnsupportedLanguage
+	case services.SubmissionNotesTooLong:
+		code = util.CodeInvalidRequest
+		details["max"] = subErr.Max
+	case services.SubmissionInvalidClaims:
+		code = util.CodeInvalidClaims
+	}
+	util.RespondAPIError(c, http.StatusBadRequest, util.APIError{Code: code, Message: subErr.Message, Details: details})
+}
+
+// ShellSimulate handles POST /api/shell/:handle/simulate
+// Projects how candidate fragments, plus the Claw's fragments still under
+// review, would move the soul's dimension scores at the next ensouling.
+// Nothing is saved and no quota is used.
+func ShellSimulate(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	var req struct {
+		Fragments []struct {
+			Dimension string `json:"dimension" binding:"required"`
+			Content   string `json:"content" binding:"required"`
+		} `json:"fragments" binding:"max=20"`
+		IncludePending *bool `json:"include_pending"` // default true
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest,
+			"Invalid request. Optional: fragments array (up to 20 {dimension, content}) and include_pending")
+		return
+	}
+
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	candidates := make([]services.SimulationCandidate, len(req.Fragments))
+	for i, f := range req.Fragments {
+		candidates[i] = services.SimulationCandidate{Dimension: f.Dimension, Content: f.Content}
+	}
+
+	sim, err := services.SimulateEnsouling(claw, handle, candidates, req.IncludePending == nil || *req.IncludePending)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, sim)
+	case errors.Is(err, services.ErrInvalidSubmission):
+		submissionError(c, err)
+	case errors.Is(err, services.ErrNoScoreBands):
+		util.RespondError(c, http.StatusUnprocessableEntity, util.CodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrShellNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, err.Error())
+	case errors.Is(err, services.ErrShellNotMinted):
+		util.RespondError(c, http.StatusConflict, util.CodeShellNotMinted, err.Error())
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+	default:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to simulate ensouling")
+	}
+}
+
 // FragmentList handles GET /api/fragment/list
 // Returns fragments filtered by shell, claw, or status.
 func FragmentList(c *gin.Context) {
-	shellHandle := services.SanitizeHandle(c.Query("handle"))
+	shellHandle, ok := handleQuery(c)
+	if !ok {
+		return
+	}
 	status := c.Query("status")
 	dimension := c.Query("dimension")
 	page := c.DefaultQuery("page", "1")
 	limit := c.DefaultQuery("limit", "20")
 
-	result, err := services.ListFragments(shellHandle, status, dimension, page, limit)
+	result, err := services.ListFragments(shellHandle, status, dimension, page, limit, c.Query("cursor"))
+	if errors.Is(err, services.ErrInvalidCursor) {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
 		return
 	}
 
@@ -143,7 +270,159 @@ func FragmentGetByID(c *gin.Context) {
 
 	fragment, err := services.GetFragmentByID(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Fragment not found"})
+		util.RespondError(c, http.StatusNotFound, util.CodeFragmentNotFound, "Fragment not found")
+		return
+	}
+
+	// Curation runs after submission, so a rejection surfaces here rather than as an HTTP error
+	var rejectCode util.ErrorCode
+	if fragment.Status == models.FragStatusRejected {
+		rejectCode = util.CodeCuratorRejected
+	}
+	c.JSON(http.StatusOK, struct {
+		*models.Fragment
+		RejectCode util.ErrorCode `json:"reject_code,omitempty"`
+	}{fragment, rejectCode})
+}
+
+// FragmentVerify handles POST /api/fragment/verify
+// Checks (fragment_id, content) pairs against the stored content_hash and the
+// on-chain feedbackHash, so third parties can audit fragment integrity.
+func FragmentVerify(c *gin.Context) {
+	var req struct {
+		Fragments []services.FragmentProofRequest `json:"fragments" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: fragments array of {fragment_id, content}")
+		return
+	}
+	if len(req.Fragments) > services.MaxFragmentVerifyItems {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest,
+			fmt.Sprintf("Too many fragments (max %d per request)", services.MaxFragmentVerifyItems))
+		return
+	}
+
+	results := services.VerifyFragments(req.Fragments)
+
+	contentMatches, onChainVerified := 0, 0
+	for _, r := range results {
+		if r.ContentMatch {
+			contentMatches++
+		}
+		if r.OnChainStatus == services.ChainProofVerified {
+			onChainVerified++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":          results,
+		"total":            len(results),
+		"content_matches":  contentMatches,
+		"onchain_verified": onChainVerified,
+	})
+}
+
+// FragmentAppeal handles POST /api/fragment/:id/appeal
+// Lets the Claw that submitted a rejected fragment appeal it once, with a
+// justification. The second-opinion review runs asynchronously.
+func FragmentAppeal(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	var req struct {
+		Justification string `json:"justification" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: justification")
+		return
+	}
+	if len(req.Justification) < 20 || len(req.Justification) > 2000 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Justification must be 20-2000 characters")
+		return
+	}
+
+	appeal, err := services.AppealFragment(claw, c.Param("id"), req.Justification)
+	if errors.Is(err, services.ErrAppealExists) {
+		util.RespondError(c, http.StatusConflict, util.CodeAppealExists, err.Error())
+		return
+	}
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, appeal)
+}
+
+// FragmentGetAppeal handles GET /api/fragment/:id/appeal
+// Returns the status and verdicts of the calling Claw's appeal.
+func FragmentGetAppeal(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	appeal, err := services.GetFragmentAppeal(claw, c.Param("id"))
+	if err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Appeal not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, appeal)
+}
+
+// FragmentGetNotes handles GET /api/fragment/:id/notes
+// Returns one of the calling Claw's fragments with its private notes.
+func FragmentGetNotes(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	fragment, err := services.GetFragmentNotes(claw, c.Param("id"))
+	if err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeFragmentNotFound, "Fragment not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, fragment)
+}
+
+// FragmentSetNotes handles PUT /api/fragment/:id/notes
+// Replaces the private notes of one of the calling Claw's fragments, e.g. why
+// it was written and which sources were used. Notes never appear in public
+// responses. Body: {"notes": "..."} ("" clears them).
+func FragmentSetNotes(c *gin.Context) {
+	claw := middleware.GetClaw(c)
+	if claw == nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	var req struct {
+		Notes *string `json:"notes" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: notes")
+		return
+	}
+	if utf8.RuneCountInString(*req.Notes) > services.MaxFragmentNotesChars {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Notes must be at most 2000 characters")
+		return
+	}
+
+	fragment, err := services.SetFragmentNotes(claw, c.Param("id"), *req.Notes)
+	if errors.Is(err, services.ErrFragmentNotFound) {
+		util.RespondError(c, http.StatusNotFound, util.CodeFragmentNotFound, "Fragment not found")
+		return
+	}
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to save notes")
 		return
 	}
 
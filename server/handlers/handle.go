@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// bindHandle normalizes a handle taken from a request. Every handle a request
+// carries goes through services.ValidateHandle here, so "ElonMusk",
+// " elonmusk " and "elonmusk" padded with zero-width characters all reach the
+// services as "elonmusk", and lookalike letters from other scripts are
+// rejected before any lookup or LLM call. On an invalid handle it responds
+// 400 INVALID_HANDLE and returns false.
+func bindHandle(c *gin.Context, raw string) (string, bool) {
+	handle, err := services.ValidateHandle(raw)
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidHandle, err.Error())
+		return "", false
+	}
+	return handle, true
+}
+
+// handleParam is bindHandle for the :handle path parameter.
+func handleParam(c *gin.Context) (string, bool) {
+	return bindHandle(c, c.Param("handle"))
+}
+
+// handleQuery is bindHandle for an optional ?handle= filter; "" when absent.
+func handleQuery(c *gin.Context) (string, bool) {
+	if c.Query("handle") == "" {
+		return "", true
+	}
+	return bindHandle(c, c.Query("handle"))
+}
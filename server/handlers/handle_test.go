@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+)
+
+// Handles as a client may send them. The Cyrillic "е" (U+0435) renders like
+// the Latin "e" but must never reach a lookup.
+const (
+	mixedCaseHandle = "ElonMusk"
+	paddedHandle    = " \u200belon\u200dmusk\ufeff"
+	homoglyphHandle = "\u0435lonmusk"
+)
+
+// handleTestRouter connects a fresh SQLite database with a minted soul
+// @elonmusk, a soul @pendingsoul that is not on-chain yet and a claimed Claw,
+// and routes the endpoints that take a handle.
+func handleTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	connectTestDB(t, &config.Config{PreviewSigningSecret: "handle-test", WalletMintQuota: 3})
+
+	database.DB.Create(&models.Shell{Handle: "elonmusk", Stage: models.StageEmbryo, MintTxHash: "0x01"})
+	database.DB.Create(&models.Shell{Handle: "pendingsoul", Stage: models.StageEmbryo})
+	claw := &models.Claw{Name: "hunter", APIKeyHash: "hash", ClaimCode: "claim", VerificationCode: "code", Status: models.ClawStatusClaimed}
+	if err := database.DB.Create(claw).Error; err != nil {
+		t.Fatalf("create claw: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/api/shell/preview", ShellPreview)
+	r.POST("/api/shell/mint", ShellMint)
+	r.GET("/api/shell/:handle", ShellGetByHandle)
+	r.POST("/api/fragment/batch", func(c *gin.Context) { c.Set("claw", claw) }, FragmentBatch)
+	return r
+}
+
+// connectTestDB sets cfg as the config and connects a fresh SQLite database.
+func connectTestDB(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	util.InitLogger("error")
+	config.Cfg = cfg
+	database.Connect(&config.Config{
+		Env:      "production",
+		DBDriver: database.DriverSQLite,
+		DBPath:   filepath.Join(t.TempDir(), "ensoul.db"),
+	})
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// doJSON sends a request and decodes the JSON response.
+func doJSON(t *testing.T, r *gin.Engine, method, path string, body interface{}, header map[string]string) (int, map[string]interface{}) {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestHandlePreview(t *testing.T) {
+	r := handleTestRouter(t)
+
+	// An existing soul is found whatever the case or padding, before any
+	// seed extraction runs
+	for _, handle := range []string{mixedCaseHandle, paddedHandle} {
+		status, resp := doJSON(t, r, http.MethodPost, "/api/shell/preview", gin.H{"handle": handle}, nil)
+		if status != http.StatusConflict || !strings.Contains(resp["message"].(string), "@elonmusk") {
+			t.Errorf("preview %q = %d %v, want 409 for @elonmusk", handle, status, resp)
+		}
+	}
+
+	status, resp := doJSON(t, r, http.MethodPost, "/api/shell/preview", gin.H{"handle": homoglyphHandle}, nil)
+	if status != http.StatusBadRequest || resp["code"] != string(util.CodeInvalidHandle) {
+		t.Errorf("preview %q = %d %v, want 400 INVALID_HANDLE", homoglyphHandle, status, resp)
+	}
+}
+
+func TestHandleMint(t *testing.T) {
+	r := handleTestRouter(t)
+
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	mint := func(handle, signed string) (int, map[string]interface{}) {
+		preview := services.SeedPreview{Handle: signed}
+		if err := services.SignSeedPreview(&preview); err != nil {
+			t.Fatalf("sign preview: %v", err)
+		}
+		sig, _ := crypto.Sign(accounts.TextHash([]byte("ensoul:mint:"+signed)), key)
+		sig[64] += 27
+		return doJSON(t, r, http.MethodPost, "/api/shell/mint",
+			gin.H{"handle": handle, "owner_addr": owner, "preview": preview},
+			map[string]string{"X-Wallet-Address": owner, "X-Wallet-Signature": hexutil.Encode(sig)})
+	}
+
+	// The wallet signs the normalized handle, whatever case it typed
+	status, resp := mint("NewSoul", "newsoul")
+	if status != http.StatusCreated || resp["handle"] != "newsoul" {
+		t.Fatalf("mint NewSoul = %d %v, want 201 @newsoul", status, resp)
+	}
+	var n int64
+	database.DB.Model(&models.Shell{}).Where("handle = ?", "newsoul").Count(&n)
+	if n != 1 {
+		t.Errorf("souls stored as newsoul = %d, want 1", n)
+	}
+
+	status, resp = mint(mixedCaseHandle, "elonmusk")
+	if status != http.StatusConflict {
+		t.Errorf("mint %q = %d %v, want 409 for the existing @elonmusk", mixedCaseHandle, status, resp)
+	}
+
+	status, resp = mint(homoglyphHandle, homoglyphHandle)
+	if status != http.StatusBadRequest || resp["code"] != string(util.CodeInvalidHandle) {
+		t.Errorf("mint %q = %d %v, want 400 INVALID_HANDLE", homoglyphHandle, status, resp)
+	}
+}
+
+func TestHandleGet(t *testing.T) {
+	r := handleTestRouter(t)
+
+	for _, handle := range []string{mixedCaseHandle, paddedHandle} {
+		status, resp := doJSON(t, r, http.MethodGet, "/api/shell/"+url.PathEscape(handle), nil, nil)
+		if status != http.StatusOK || resp["handle"] != "elonmusk" {
+			t.Errorf("get %q = %d %v, want 200 @elonmusk", handle, status, resp)
+		}
+	}
+
+	status, resp := doJSON(t, r, http.MethodGet, "/api/shell/"+url.PathEscape(homoglyphHandle), nil, nil)
+	if status != http.StatusBadRequest || resp["code"] != string(util.CodeInvalidHandle) {
+		t.Errorf("get %q = %d %v, want 400 INVALID_HANDLE", homoglyphHandle, status, resp)
+	}
+}
+
+func TestHandleFragmentBatch(t *testing.T) {
+	r := handleTestRouter(t)
+
+	batch := func(handle string) (int, map[string]interface{}) {
+		return doJSON(t, r, http.MethodPost, "/api/fragment/batch", gin.H{
+			"handle": handle,
+			"fragments": []gin.H{
+				{"dimension": "personality", "content": "Direct and impatient in interviews, he cuts questions short when they repeat."},
+				{"dimension": "stance", "content": "Argues publicly that rockets must be fully reusable for launch costs to fall."},
+				{"dimension": "style", "content": "Writes in short, blunt posts and often answers critics with a single word."},
+			},
+		}, nil)
+	}
+
+	// The soul is found (and refused for not being on-chain), not missing
+	status, resp := batch("PendingSoul")
+	if status != http.StatusConflict || resp["code"] != string(util.CodeShellNotMinted) ||
+		!strings.Contains(resp["message"].(string), "@pendingsoul") {
+		t.Errorf("batch PendingSoul = %d %v, want 409 SHELL_NOT_MINTED for @pendingsoul", status, resp)
+	}
+
+	status, resp = batch("\u0440endingsoul") // Cyrillic "р"
+	if status != http.StatusBadRequest || resp["code"] != string(util.CodeInvalidHandle) {
+		t.Errorf("batch with a homoglyph = %d %v, want 400 INVALID_HANDLE", status, resp)
+	}
+	var fragments int64
+	database.DB.Model(&models.Fragment{}).Count(&fragments)
+	if fragments != 0 {
+		t.Errorf("%d fragments were stored for rejected batches", fragments)
+	}
+}
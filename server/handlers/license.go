@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// licenseRequest is the body for granting a prompt license.
+type licenseRequest struct {
+	Licensee     string `json:"licensee" binding:"required"`
+	DurationDays int    `json:"duration_days" binding:"required"`
+	PriceWei     string `json:"price_wei"` // optional, "" or "0" = free
+}
+
+// mintedShell loads a minted shell by the :handle param.
+func mintedShell(c *gin.Context) (*models.Shell, bool) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return nil, false
+	}
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return nil, false
+	}
+	return shell, true
+}
+
+// licenseError writes the response for a license service error.
+func licenseError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrLicenseNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrLicenseInactive):
+		util.RespondError(c, http.StatusForbidden, util.CodeLicenseInactive, err.Error())
+	case errors.Is(err, services.ErrLicenseTxReused):
+		util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+	default:
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+	}
+}
+
+// ShellLicenseCreate handles POST /api/shell/:handle/licenses
+// Owner-only, signed message "ensoul:licenses:<handle>:<timestamp>".
+func ShellLicenseCreate(c *gin.Context) {
+	shell, owner, ok := ownedShell(c, "licenses")
+	if !ok {
+		return
+	}
+
+	var req licenseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: licensee, duration_days; optional: price_wei")
+		return
+	}
+
+	license, err := services.CreateShellLicense(shell, owner, req.Licensee, req.DurationDays, req.PriceWei)
+	if err != nil {
+		licenseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"license": license})
+}
+
+// ShellLicenseList handles GET /api/shell/:handle/licenses
+// Owner-only, signed message "ensoul:licenses:<handle>:<timestamp>".
+func ShellLicenseList(c *gin.Context) {
+	shell, _, ok := ownedShell(c, "licenses")
+	if !ok {
+		return
+	}
+
+	licenses, err := services.ListShellLicenses(shell)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to list licenses")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"licenses": licenses})
+}
+
+// ShellLicenseRevoke handles DELETE /api/shell/:handle/licenses/:id
+// Owner-only, signed message "ensoul:licenses:<handle>:<timestamp>".
+func ShellLicenseRevoke(c *gin.Context) {
+	shell, _, ok := ownedShell(c, "licenses")
+	if !ok {
+		return
+	}
+
+	if err := services.RevokeShellLicense(shell, c.Param("id")); err != nil {
+		licenseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "License revoked"})
+}
+
+// ShellLicensePay handles POST /api/shell/:handle/licenses/:id/payment
+// Licensee-signed message "ensoul:license-payment:<handle>:<timestamp>".
+// Body: {"tx_hash": "0x..."} of a BNB transfer of at least price_wei to the owner.
+func ShellLicensePay(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	wallet, ok := requireWalletSignature(c, "license-payment", shell)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TxHash string `json:"tx_hash" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: tx_hash")
+		return
+	}
+
+	license, err := services.PayShellLicense(shell, c.Param("id"), wallet, req.TxHash)
+	if err != nil {
+		licenseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"license": license})
+}
+
+// ShellLicensePrompt handles GET /api/shell/:handle/licenses/:id/prompt
+// Licensee-signed message "ensoul:license-access:<handle>:<timestamp>".
+// Returns the soul prompt with a signed receipt of this access.
+func ShellLicensePrompt(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	wallet, ok := requireWalletSignature(c, "license-access", shell)
+	if !ok {
+		return
+	}
+
+	prompt, receipt, err := services.AccessLicensedPrompt(shell, c.Param("id"), wallet)
+	if err != nil {
+		licenseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"handle":      shell.Handle,
+		"dna_version": receipt.DNAVersion,
+		"soul_prompt": prompt,
+		"receipt":     receipt,
+	})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// MediaGet handles GET /api/media/:shell and GET /api/media/:shell/:kind
+// Serves a soul's cached avatar (default) or banner, fetching it on first request.
+func MediaGet(c *gin.Context) {
+	handle, ok := bindHandle(c, c.Param("shell"))
+	if !ok {
+		return
+	}
+	kind := c.Param("kind")
+	if kind == "" {
+		kind = services.MediaKindAvatar
+	}
+	if kind != services.MediaKindAvatar && kind != services.MediaKindBanner {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "kind must be avatar or banner")
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	data, asset, err := services.GetShellMedia(shell, kind)
+	if err != nil {
+		util.RespondError(c, http.StatusBadGateway, util.CodeUpstream, "Failed to load image")
+		return
+	}
+
+	etag := `"` + asset.ETag + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=86400")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, asset.ContentType, data)
+}
+
+// ShellCard handles GET /api/shell/:handle/card.png and /card.svg
+// Renders the soul's card (handle, stage, DNA version, dimension radar), the
+// image of its ERC-8004 registration file.
+func ShellCard(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	svg := strings.HasSuffix(c.Request.URL.Path, ".svg")
+	etag := `"` + services.SoulCardETag(shell) + `"`
+	if svg {
+		etag = `"svg-` + etag[1:]
+	}
+	// Short max-age: the card follows the soul's stage and DNA version
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=3600")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if svg {
+		c.Data(http.StatusOK, "image/svg+xml", services.RenderSoulCardSVG(shell))
+		return
+	}
+	data, err := services.RenderSoulCardPNG(shell)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to render card")
+		return
+	}
+	c.Data(http.StatusOK, "image/png", data)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics handles GET /metrics
+// Serves the server metrics in the Prometheus text format to a scraper
+// holding METRICS_TOKEN. Without a token configured the endpoint is off.
+func Metrics(c *gin.Context) {
+	token := config.Cfg.MetricsToken
+	if token == "" {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, "Metrics are disabled")
+		return
+	}
+	given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Valid metrics token required")
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	util.WriteMetrics(c.Writer)
+}
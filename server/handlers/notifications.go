@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationGetPreferences handles GET /api/notifications/preferences
+// Returns the session wallet's notification preferences and the channels
+// this server can deliver on.
+func NotificationGetPreferences(c *gin.Context) {
+	settings, err := services.GetNotificationSettings(middleware.GetSessionWallet(c))
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to load notification preferences")
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// NotificationSetPreferences handles PUT /api/notifications/preferences
+// Replaces the session wallet's preferences. Empty email or telegram_chat_id
+// turns that channel off; empty events subscribes to every event.
+func NotificationSetPreferences(c *gin.Context) {
+	var req struct {
+		Email          string   `json:"email"`
+		TelegramChatID string   `json:"telegram_chat_id"`
+		Events         []string `json:"events"`
+		Paused         bool     `json:"paused"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	settings, err := services.SetNotificationPreferences(middleware.GetSessionWallet(c),
+		req.Email, req.TelegramChatID, req.Events, req.Paused)
+	switch {
+	case errors.Is(err, services.ErrInvalidNotificationPrefs):
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrNotificationRateLimited):
+		util.RespondError(c, http.StatusTooManyRequests, util.CodeRateLimited, err.Error())
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to save notification preferences")
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// NotificationVerify handles POST /api/notifications/verify
+// Confirms the session wallet's address on a channel with the code sent to it.
+func NotificationVerify(c *gin.Context) {
+	var req struct {
+		Channel string `json:"channel" binding:"required"`
+		Code    string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "channel and code are required")
+		return
+	}
+
+	settings, err := services.VerifyNotificationAddress(middleware.GetSessionWallet(c), req.Channel, req.Code)
+	switch {
+	case errors.Is(err, services.ErrInvalidNotificationPrefs), errors.Is(err, services.ErrNotificationCode):
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to verify address")
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// NotificationResendCode handles POST /api/notifications/verify/resend
+// Sends a new verification code to the session wallet's unverified address.
+func NotificationResendCode(c *gin.Context) {
+	var req struct {
+		Channel string `json:"channel" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "channel is required")
+		return
+	}
+
+	result, err := services.ResendNotificationCode(middleware.GetSessionWallet(c), req.Channel)
+	switch {
+	case errors.Is(err, services.ErrInvalidNotificationPrefs):
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrNotificationRateLimited):
+		util.RespondError(c, http.StatusTooManyRequests, util.CodeRateLimited, err.Error())
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to send verification code")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"channel": req.Channel, "result": result})
+}
+
+// NotificationTest handles POST /api/notifications/test
+// Sends a test message on each channel the session wallet has verified.
+func NotificationTest(c *gin.Context) {
+	results, err := services.SendTestNotification(middleware.GetSessionWallet(c))
+	if errors.Is(err, services.ErrNotificationRateLimited) {
+		util.RespondError(c, http.StatusTooManyRequests, util.CodeRateLimited, err.Error())
+		return
+	}
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to send test notification")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ownerSignatureMaxAge bounds how old an owner action signature may be.
+const ownerSignatureMaxAge = 10 * time.Minute
+
+// requireShellOwner verifies that the caller owns the shell via EIP-191 signature.
+//
+// Expected headers:
+//   - X-Wallet-Address:   the owner's 0x address
+//   - X-Wallet-Signature: signature of "ensoul:<action>:<handle>:<timestamp>"
+//   - X-Wallet-Timestamp: the unix timestamp included in the signed message
+//
+// The timestamp keeps signatures from being replayed after ownerSignatureMaxAge.
+// On failure the error response is written and ok is false.
+func requireShellOwner(c *gin.Context, action string, shell *models.Shell) (string, bool) {
+	wallet, ok := requireWalletSignature(c, action, shell)
+	if !ok {
+		return "", false
+	}
+
+	if !strings.EqualFold(wallet, shell.OwnerAddr) {
+		util.RespondError(c, http.StatusForbidden, util.CodeNotOwner, "Only the soul's owner can do this")
+		return "", false
+	}
+
+	return wallet, true
+}
+
+// requireWalletSignature verifies the same signed headers as requireShellOwner
+// but accepts any wallet, returning its checksummed address. Used for actions
+// taken by someone other than the owner, such as a license holder.
+func requireWalletSignature(c *gin.Context, action string, shell *models.Shell) (string, bool) {
+	return requireSignedAction(c, action, shell.Handle)
+}
+
+// requireSignedAction verifies a signature of "ensoul:<action>:<subject>:<timestamp>"
+// from the wallet headers, for actions not tied to an existing shell.
+func requireSignedAction(c *gin.Context, action, subject string) (string, bool) {
+	walletAddr := c.GetHeader("X-Wallet-Address")
+	signature := c.GetHeader("X-Wallet-Signature")
+	timestamp := c.GetHeader("X-Wallet-Timestamp")
+
+	if walletAddr == "" || signature == "" || timestamp == "" {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Wallet authentication required (X-Wallet-Address, X-Wallet-Signature, X-Wallet-Timestamp)")
+		return "", false
+	}
+	if !common.IsHexAddress(walletAddr) {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid wallet address format")
+		return "", false
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid X-Wallet-Timestamp")
+		return "", false
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > ownerSignatureMaxAge || age < -time.Minute {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeSignatureExpired, "Signature expired, please sign again")
+		return "", false
+	}
+
+	signedMessage := fmt.Sprintf("ensoul:%s:%s:%d", action, subject, ts)
+	claimedAddr := common.HexToAddress(walletAddr)
+	if err := middleware.VerifyWalletSignature(signedMessage, signature, claimedAddr); err != nil {
+		util.RespondError(c, http.StatusUnauthorized, util.CodeInvalidSignature, "Invalid wallet signature: "+err.Error())
+		return "", false
+	}
+
+	return claimedAddr.Hex(), true
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// policyOverrideRequest is the body for overriding a shell's ensouling policy.
+type policyOverrideRequest struct {
+	Tier      string `json:"tier"`      // pin to this tier regardless of followers
+	Threshold int    `json:"threshold"` // fragments per ensouling, overrides the tier's
+	Note      string `json:"note"`
+}
+
+// GetPolicy handles GET /api/policy?handle=xxx
+// Public. Returns the ensouling tiers in effect and, with a handle, the
+// policy that applies to that soul.
+func GetPolicy(c *gin.Context) {
+	var shell *models.Shell
+	handle, ok := handleQuery(c)
+	if !ok {
+		return
+	}
+	if handle != "" {
+		s, err := services.GetShellByHandle(handle)
+		if err != nil {
+			util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+			return
+		}
+		shell = s
+	}
+
+	c.JSON(http.StatusOK, services.GetEnsoulingPolicy(shell))
+}
+
+// AdminUpdatePolicyTiers handles PUT /api/admin/policy/tiers
+// Replaces all ensouling tiers. Body: {"tiers": [{name, min_followers, threshold, description, scoring_guide}]}
+func AdminUpdatePolicyTiers(c *gin.Context) {
+	var req struct {
+		Tiers []models.EnsoulingTier `json:"tiers" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: tiers")
+		return
+	}
+
+	tiers, err := services.UpdateEnsoulingTiers(req.Tiers, middleware.GetSessionWallet(c))
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tiers": tiers})
+}
+
+// AdminUpdateSubmissionRules handles PUT /api/admin/policy/submission
+// Sets the fragment length limits of some dimensions; the rest keep theirs.
+// Body: {"rules": [{dimension, min_chars, max_chars}]}
+func AdminUpdateSubmissionRules(c *gin.Context) {
+	var req struct {
+		Rules []models.SubmissionRule `json:"rules" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: rules")
+		return
+	}
+
+	rules, err := services.UpdateSubmissionRules(req.Rules, middleware.GetSessionWallet(c))
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submission_rules": rules})
+}
+
+// AdminSetShellPolicy handles PUT /api/admin/policy/shells/:handle
+// Pins a soul to a tier and/or threshold, e.g. for experiments.
+func AdminSetShellPolicy(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	var req policyOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Optional: tier, threshold, note")
+		return
+	}
+
+	override, err := services.SetShellPolicyOverride(shell, req.Tier, req.Threshold, req.Note, middleware.GetSessionWallet(c))
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"override": override, "threshold": services.EnsoulingThreshold(shell)})
+}
+
+// AdminDeleteShellPolicy handles DELETE /api/admin/policy/shells/:handle
+// Returns a soul to the default follower-based policy.
+func AdminDeleteShellPolicy(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteShellPolicyOverride(shell); err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to delete override")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"threshold": services.EnsoulingThreshold(shell)})
+}
+
+// AdminRecalcShellScores handles POST /api/admin/shell/:handle/recalc-scores
+// Recomputes a soul's dimension scores from its accepted fragment counts and
+// its tier's scoring guide. Body (optional): {"reset": false, "dry_run": false}.
+// By default in-band scores are kept and out-of-band ones are clamped; reset
+// sets every score to the fragment-count baseline.
+func AdminRecalcShellScores(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reset  bool `json:"reset"`
+		DryRun bool `json:"dry_run"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Optional: reset, dry_run")
+			return
+		}
+	}
+
+	changes, err := services.RecalcShellScores(shell, req.Reset, !req.DryRun)
+	if err != nil {
+		if errors.Is(err, services.ErrNoScoreBands) {
+			util.RespondError(c, http.StatusUnprocessableEntity, util.CodeInvalidRequest, err.Error())
+			return
+		}
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to recalculate scores")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"handle":     shell.Handle,
+		"applied":    !req.DryRun,
+		"dimensions": changes,
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// seedRefreshCaller authorizes a seed refresh: an admin session, or the owner's
+// signed message "ensoul:refresh-seed:<handle>:<timestamp>".
+func seedRefreshCaller(c *gin.Context, shell *models.Shell) (string, string, bool) {
+	if wallet := middleware.GetSessionWallet(c); wallet != "" && middleware.IsAdminWallet(wallet) {
+		return models.SeedRefreshAdmin, wallet, true
+	}
+	wallet, ok := requireShellOwner(c, "refresh-seed", shell)
+	if !ok {
+		return "", "", false
+	}
+	return models.SeedRefreshOwner, wallet, true
+}
+
+// ShellRefreshSeed handles POST /api/shell/:handle/refresh-seed
+// Owner or admin. Fetches the soul's new tweets in the background and queues
+// what they add as candidate fragments for curation.
+func ShellRefreshSeed(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	trigger, wallet, ok := seedRefreshCaller(c, shell)
+	if !ok {
+		return
+	}
+
+	refresh, err := services.RequestSeedRefresh(shell, trigger, wallet)
+	if err != nil {
+		if errors.Is(err, services.ErrSeedRefreshTooSoon) {
+			util.RespondError(c, http.StatusTooManyRequests, util.CodeRateLimited, err.Error())
+			return
+		}
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to start seed refresh")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"refresh": refresh})
+}
+
+// ShellSeedRefreshes handles GET /api/shell/:handle/seed-refreshes
+// Public. Returns when the seed was last refreshed and the recent refresh runs.
+func ShellSeedRefreshes(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	refreshes, err := services.ListSeedRefreshes(shell, 20)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to list seed refreshes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"seed_refreshed_at": shell.SeedRefreshedAt,
+		"refreshes":         refreshes,
+	})
+}
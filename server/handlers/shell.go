@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ensoul-labs/ensoul-server/database"
 	"github.com/ensoul-labs/ensoul-server/middleware"
 	"github.com/ensoul-labs/ensoul-server/models"
 	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 )
@@ -19,14 +24,13 @@ func ShellPreview(c *gin.Context) {
 		Handle string `json:"handle" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "handle is required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "handle is required")
 		return
 	}
 
 	// Sanitize and validate handle to prevent Unicode homoglyph attacks
-	cleanHandle, err := services.ValidateHandle(req.Handle)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	cleanHandle, ok := bindHandle(c, req.Handle)
+	if !ok {
 		return
 	}
 	req.Handle = cleanHandle
@@ -35,15 +39,20 @@ func ShellPreview(c *gin.Context) {
 	var existing models.Shell
 	if err := database.DB.Where("LOWER(handle) = ?", req.Handle).First(&existing).Error; err == nil {
 		if existing.Stage != "pending" {
-			c.JSON(http.StatusConflict, gin.H{"error": "A soul for @" + req.Handle + " already exists"})
+			util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, "A soul for @"+req.Handle+" already exists")
 			return
 		}
 	}
 
 	// Generate seed preview
-	preview, err := services.GenerateSeedPreview(req.Handle)
+	preview, err := services.GenerateSeedPreview(c.Request.Context(), req.Handle)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate preview: " + err.Error()})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to generate preview: "+err.Error())
+		return
+	}
+	// Signed so the mint can't carry a doctored seed summary or scores
+	if err := services.SignSeedPreview(preview); err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to sign preview")
 		return
 	}
 
@@ -53,7 +62,7 @@ func ShellPreview(c *gin.Context) {
 // ShellMint handles POST /api/shell/mint
 // Creates the shell in DB. On-chain minting is done by the user's wallet.
 // Requires wallet signature authentication via X-Wallet-Address and X-Wallet-Signature headers.
-// Each wallet can mint at most 3 shells.
+// services.MintShell enforces the wallet's mint quota (WALLET_MINT_QUOTA or its allowlist entry).
 func ShellMint(c *gin.Context) {
 	var req struct {
 		Handle    string               `json:"handle" binding:"required"`
@@ -61,14 +70,13 @@ func ShellMint(c *gin.Context) {
 		Preview   services.SeedPreview `json:"preview" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "handle, owner_addr, and preview are required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "handle, owner_addr, and preview are required")
 		return
 	}
 
 	// Sanitize and validate handle to prevent Unicode homoglyph attacks
-	cleanHandle, err := services.ValidateHandle(req.Handle)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	cleanHandle, ok := bindHandle(c, req.Handle)
+	if !ok {
 		return
 	}
 	req.Handle = cleanHandle
@@ -78,18 +86,18 @@ func ShellMint(c *gin.Context) {
 	signature := c.GetHeader("X-Wallet-Signature")
 
 	if walletAddr == "" || signature == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Wallet authentication required. Connect your wallet to mint."})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Wallet authentication required. Connect your wallet to mint.")
 		return
 	}
 
 	if !common.IsHexAddress(walletAddr) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address format"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid wallet address format")
 		return
 	}
 
 	// Ensure the header address matches the body address (case-insensitive)
 	if !strings.EqualFold(walletAddr, req.OwnerAddr) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet address mismatch: header and body owner_addr must match"})
+		util.RespondError(c, http.StatusForbidden, util.CodeForbidden, "Wallet address mismatch: header and body owner_addr must match")
 		return
 	}
 
@@ -97,25 +105,93 @@ func ShellMint(c *gin.Context) {
 	signedMessage := "ensoul:mint:" + req.Handle
 	claimedAddr := common.HexToAddress(walletAddr)
 	if err := middleware.VerifyWalletSignature(signedMessage, signature, claimedAddr); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid wallet signature: " + err.Error()})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeInvalidSignature, "Invalid wallet signature: "+err.Error())
 		return
 	}
 
-	// Enforce per-wallet mint limit (max 3 confirmed shells per address)
-	var mintCount int64
-	database.DB.Model(&models.Shell{}).Where("LOWER(owner_addr) = LOWER(?) AND stage != ?", walletAddr, "pending").Count(&mintCount)
-	if mintCount >= 3 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Each wallet can mint at most 3 shells"})
+	// MintShell enforces the wallet's mint quota (see GET /api/auth/session)
+	shell, err := services.MintShell(req.Handle, req.OwnerAddr, &req.Preview)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPreviewInvalid):
+			util.RespondError(c, http.StatusBadRequest, util.CodePreviewInvalid, err.Error())
+		case errors.Is(err, services.ErrSoulLimit):
+			util.RespondError(c, http.StatusForbidden, util.CodeMintLimit, err.Error())
+		case errors.Is(err, services.ErrHandleTaken), errors.Is(err, services.ErrHandleReserved),
+			errors.Is(err, services.ErrCustodialMintInFlight):
+			util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+		default:
+			util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to mint shell: "+err.Error())
+		}
 		return
 	}
 
-	shell, err := services.MintShell(req.Handle, req.OwnerAddr, &req.Preview)
+	c.JSON(http.StatusCreated, shell)
+}
+
+// ShellCustodialMint handles POST /api/shell/mint/custodial
+// Mints a soul for a wallet without BNB: the platform wallet sends the mint
+// and, once it is mined, transfers the NFT to the wallet. The wallet
+// authorizes it by signing "ensoul:custodial-mint:<handle>:<timestamp>" (see
+// requireShellOwner for the headers). Returns 202 with the mint tx; progress
+// is at GET /api/shell/mint/custodial/:handle.
+func ShellCustodialMint(c *gin.Context) {
+	var req struct {
+		Handle  string               `json:"handle" binding:"required"`
+		Preview services.SeedPreview `json:"preview" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "handle and preview are required")
+		return
+	}
+	cleanHandle, ok := bindHandle(c, req.Handle)
+	if !ok {
+		return
+	}
+
+	wallet, ok := requireSignedAction(c, "custodial-mint", cleanHandle)
+	if !ok {
+		return
+	}
+
+	shell, txHash, err := services.CustodialMintShell(c.Request.Context(), cleanHandle, wallet, &req.Preview)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mint shell: " + err.Error()})
+		switch {
+		case errors.Is(err, services.ErrCustodialMintDisabled):
+			util.RespondError(c, http.StatusForbidden, util.CodeForbidden, err.Error())
+		case errors.Is(err, services.ErrPreviewInvalid):
+			util.RespondError(c, http.StatusBadRequest, util.CodePreviewInvalid, err.Error())
+		case errors.Is(err, services.ErrSoulLimit):
+			util.RespondError(c, http.StatusForbidden, util.CodeMintLimit, err.Error())
+		case errors.Is(err, services.ErrHandleTaken), errors.Is(err, services.ErrHandleReserved),
+			errors.Is(err, services.ErrCustodialMintInFlight):
+			util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+		default:
+			util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to mint shell: "+err.Error())
+		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, shell)
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "pending",
+		"tx_hash": txHash,
+		"shell":   shell,
+	})
+}
+
+// ShellCustodialMintStatus handles GET /api/shell/mint/custodial/:handle
+// Reports a custodial mint's stage and its mint and transfer transactions.
+func ShellCustodialMintStatus(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+	status, err := services.GetCustodialMintStatus(handle)
+	if err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, status)
 }
 
 // ShellConfirmMint handles POST /api/shell/confirm
@@ -128,14 +204,13 @@ func ShellConfirmMint(c *gin.Context) {
 		AgentID uint64 `json:"agent_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "handle and tx_hash are required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "handle and tx_hash are required")
 		return
 	}
 
 	// Sanitize handle
-	cleanHandle, err := services.ValidateHandle(req.Handle)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	cleanHandle, ok := bindHandle(c, req.Handle)
+	if !ok {
 		return
 	}
 	req.Handle = cleanHandle
@@ -145,24 +220,28 @@ func ShellConfirmMint(c *gin.Context) {
 	signature := c.GetHeader("X-Wallet-Signature")
 
 	if walletAddr == "" || signature == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Wallet authentication required"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Wallet authentication required")
 		return
 	}
 
 	if !common.IsHexAddress(walletAddr) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address format"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid wallet address format")
 		return
 	}
 
 	signedMessage := "ensoul:mint:" + req.Handle
 	claimedAddr := common.HexToAddress(walletAddr)
 	if err := middleware.VerifyWalletSignature(signedMessage, signature, claimedAddr); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid wallet signature: " + err.Error()})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeInvalidSignature, "Invalid wallet signature: "+err.Error())
 		return
 	}
 
 	if err := services.ConfirmMint(req.Handle, req.TxHash, req.AgentID, walletAddr); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to confirm mint: " + err.Error()})
+		if errors.Is(err, services.ErrMintPending) {
+			c.JSON(http.StatusAccepted, gin.H{"status": "pending", "message": err.Error()})
+			return
+		}
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Failed to confirm mint: "+err.Error())
 		return
 	}
 
@@ -176,14 +255,13 @@ func ShellCancelMint(c *gin.Context) {
 		Handle string `json:"handle" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "handle is required"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "handle is required")
 		return
 	}
 
 	// Sanitize handle
-	cleanHandle, err := services.ValidateHandle(req.Handle)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	cleanHandle, ok := bindHandle(c, req.Handle)
+	if !ok {
 		return
 	}
 	req.Handle = cleanHandle
@@ -193,42 +271,105 @@ func ShellCancelMint(c *gin.Context) {
 	signature := c.GetHeader("X-Wallet-Signature")
 
 	if walletAddr == "" || signature == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Wallet authentication required"})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Wallet authentication required")
 		return
 	}
 
 	if !common.IsHexAddress(walletAddr) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address format"})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid wallet address format")
 		return
 	}
 
 	signedMessage := "ensoul:mint:" + req.Handle
 	claimedAddr := common.HexToAddress(walletAddr)
 	if err := middleware.VerifyWalletSignature(signedMessage, signature, claimedAddr); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid wallet signature: " + err.Error()})
+		util.RespondError(c, http.StatusUnauthorized, util.CodeInvalidSignature, "Invalid wallet signature: "+err.Error())
 		return
 	}
 
 	if err := services.CancelPendingMint(req.Handle, walletAddr); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "cancelled"})
 }
 
+// ShellImport handles POST /api/shell/import
+// Creates a soul bound to an agent already registered on the Identity Registry,
+// instead of minting a new one. The caller proves ownership of the agent NFT by
+// signing "ensoul:import:<agent_id>:<timestamp>" (see requireShellOwner for the
+// headers). handle is optional when the agent's registration file declares one.
+func ShellImport(c *gin.Context) {
+	var req struct {
+		AgentID uint64 `json:"agent_id" binding:"required"`
+		Handle  string `json:"handle"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "agent_id is required")
+		return
+	}
+	if req.Handle != "" {
+		cleanHandle, ok := bindHandle(c, req.Handle)
+		if !ok {
+			return
+		}
+		req.Handle = cleanHandle
+	}
+
+	wallet, ok := requireSignedAction(c, "import", strconv.FormatUint(req.AgentID, 10))
+	if !ok {
+		return
+	}
+
+	shell, err := services.ImportShell(c.Request.Context(), req.AgentID, wallet, req.Handle)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAgentNotFound):
+			util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+		case errors.Is(err, services.ErrAgentNotOwned):
+			util.RespondError(c, http.StatusForbidden, util.CodeNotOwner, err.Error())
+		case errors.Is(err, services.ErrSoulLimit):
+			util.RespondError(c, http.StatusForbidden, util.CodeMintLimit, err.Error())
+		case errors.Is(err, services.ErrAgentAlreadyLinked), errors.Is(err, services.ErrHandleTaken),
+			errors.Is(err, services.ErrHandleReserved):
+			util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+		case errors.Is(err, services.ErrAgentNoHandle):
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidHandle, err.Error())
+		case errors.Is(err, services.ErrAgentRegistration):
+			util.RespondError(c, http.StatusBadGateway, util.CodeUpstream, err.Error())
+		default:
+			util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to import agent: "+err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, shell)
+}
+
 // ShellList handles GET /api/shell/list
-// Returns a paginated list of shells with optional filters.
+// Returns a paginated list of shells with optional filters; ?tag=a,b keeps
+// souls carrying all of the tags. ?view=slim returns explore-card summaries
+// instead of full records.
 func ShellList(c *gin.Context) {
 	stage := c.Query("stage")
 	sort := c.DefaultQuery("sort", "newest")
 	search := c.Query("search")
 	page := c.DefaultQuery("page", "1")
 	limit := c.DefaultQuery("limit", "20")
+	tags, err := services.ParseTagFilter(c.Query("tag"))
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
 
-	result, err := services.ListShells(stage, sort, search, page, limit)
+	result, err := services.ListShells(stage, sort, search, tags, c.Query("view") == "slim", page, limit, c.Query("cursor"))
+	if errors.Is(err, services.ErrInvalidCursor) {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
 		return
 	}
 
@@ -236,43 +377,59 @@ func ShellList(c *gin.Context) {
 }
 
 // ShellGetByHandle handles GET /api/shell/:handle
-// Returns detailed information about a specific shell.
+// Returns detailed information about a specific shell, including its on-chain status.
 func ShellGetByHandle(c *gin.Context) {
-	handle := services.SanitizeHandle(c.Param("handle"))
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
 
 	shell, err := services.GetShellByHandle(handle)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Soul not found"})
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
 		return
 	}
 
 	// Don't expose unconfirmed shells (pending stage or no tx_hash) to the public
-	if shell.Stage == models.StagePending || shell.MintTxHash == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Soul not found"})
+	if shell.Stage == models.StagePending || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	// Old handle of a renamed soul → point clients at the canonical URL.
+	// 302 rather than 301: the old handle may later be minted as a new soul.
+	if shell.Handle != handle {
+		c.Redirect(http.StatusFound, "/api/shell/"+shell.Handle)
 		return
 	}
 
-	// Strip soul_prompt from public response — it's the core paid asset
-	shell.SoulPrompt = ""
+	// Strip soul_prompt (the core paid asset) and seed_summary from the public response
+	services.RedactShell(shell)
 
-	c.JSON(http.StatusOK, shell)
+	c.JSON(http.StatusOK, struct {
+		*models.Shell
+		RegistryPaused bool `json:"registry_paused"`
+	}{shell, services.RegistryPaused()})
 }
 
 // ShellGetDimensions handles GET /api/shell/:handle/dimensions
 // Returns the six-dimension data for a shell.
 func ShellGetDimensions(c *gin.Context) {
-	handle := services.SanitizeHandle(c.Param("handle"))
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
 
 	// Check shell exists and is on-chain
 	shell, err := services.GetShellByHandle(handle)
-	if err != nil || shell.MintTxHash == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Soul not found"})
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
 		return
 	}
 
 	dims, err := services.GetShellDimensions(handle)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Soul not found"})
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
 		return
 	}
 
@@ -282,20 +439,491 @@ func ShellGetDimensions(c *gin.Context) {
 // ShellGetHistory handles GET /api/shell/:handle/history
 // Returns the ensouling history for a shell.
 func ShellGetHistory(c *gin.Context) {
-	handle := services.SanitizeHandle(c.Param("handle"))
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
 
 	// Check shell exists and is on-chain
 	shell, err := services.GetShellByHandle(handle)
-	if err != nil || shell.MintTxHash == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Soul not found"})
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
 		return
 	}
 
 	history, err := services.GetShellHistory(handle)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Soul not found"})
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, history)
 }
+
+// ShellGetHistoryDiff handles GET /api/shell/:handle/history/:version/diff
+// Returns the dimension score changes and section-level prompt diff of one Ensouling.
+func ShellGetHistoryDiff(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid version")
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	diff, err := services.GetEnsoulingDiff(handle, version)
+	if err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// ShellDNAProof handles GET and POST /api/shell/:handle/history/:version/proof
+// Returns the on-chain anchor of a DNA version. POST {"prompt": "..."} also
+// checks a prompt the caller holds against the anchored prompt hash.
+// ?recompute=true also recomputes dna_hash from the version's fragments.
+func ShellDNAProof(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid version")
+		return
+	}
+
+	var prompt *string
+	if c.Request.Method == http.MethodPost {
+		var req struct {
+			Prompt string `json:"prompt" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: prompt")
+			return
+		}
+		prompt = &req.Prompt
+	}
+
+	recompute := c.Query("recompute") == "true" || c.Query("recompute") == "1"
+	proof, err := services.GetDNAProof(shell, version, prompt, recompute)
+	if err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, proof)
+}
+
+// ShellGetSettings handles GET /api/shell/:handle/settings
+// Returns the owner-controlled persona settings (public, so clients can adapt the UI).
+func ShellGetSettings(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, services.GetShellSettings(shell.ID))
+}
+
+// ShellUpdateSettings handles PUT /api/shell/:handle/settings
+// Updates persona settings. Owner-only, signed message "ensoul:settings:<handle>:<timestamp>".
+func ShellUpdateSettings(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	owner, ok := requireShellOwner(c, "settings", shell)
+	if !ok {
+		return
+	}
+
+	var req services.ShellSettingsUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid settings payload")
+		return
+	}
+
+	settings, err := services.UpdateShellSettings(shell, req, owner, false)
+	if errors.Is(err, services.ErrChatModelAdminOnly) {
+		util.RespondError(c, http.StatusForbidden, util.CodeForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// ShellChatModels handles GET /api/shell/chat-models
+// Lists the models a soul's chat may use (settings "chat_model"), with their prices.
+func ShellChatModels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"models": services.ChatModels()})
+}
+
+// AdminUpdateShellSettings handles PUT /api/admin/shell/:handle/settings
+// Same payload as the owner's PUT /api/shell/:handle/settings, e.g. to move a
+// soul's chat to another model.
+func AdminUpdateShellSettings(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	var req services.ShellSettingsUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid settings payload")
+		return
+	}
+
+	settings, err := services.UpdateShellSettings(shell, req, "admin "+middleware.GetSessionWallet(c), true)
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// ShellRename handles POST /api/shell/:handle/rename
+// Moves the soul to a new handle, keeping fragments, history and agent ID.
+// The old handle keeps resolving via redirect. Owner-only, signed message
+// "ensoul:rename:<new_handle>:<handle>:<timestamp>".
+func ShellRename(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	var req struct {
+		NewHandle string `json:"new_handle" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "new_handle is required")
+		return
+	}
+	newHandle, ok := bindHandle(c, req.NewHandle)
+	if !ok {
+		return
+	}
+
+	oldHandle := shell.Handle
+	if _, ok := requireShellOwner(c, "rename:"+newHandle, shell); !ok {
+		return
+	}
+
+	shell, err = services.RenameShell(shell, newHandle)
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"handle":     shell.Handle,
+		"old_handle": oldHandle,
+		"agent_id":   shell.AgentID,
+	})
+}
+
+// ShellRetire handles POST /api/shell/:handle/retire
+// Owner-signed: retires the soul. It stops taking chats and fragments but its
+// history stays readable; the response says how to burn the NFT if wanted.
+func ShellRetire(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+	if _, ok := requireShellOwner(c, "retire", shell); !ok {
+		return
+	}
+
+	guide, err := services.RetireShell(shell)
+	switch {
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+		return
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusConflict, util.CodeShellRetired, err.Error())
+		return
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"handle":       shell.Handle,
+		"chain_status": shell.ChainStatus,
+		"retired_at":   shell.RetiredAt,
+		"burn":         guide,
+	})
+}
+
+// ShellInterview handles GET /api/shell/:handle/interview
+// Returns sample Q&A in the soul's voice plus the topics it can't answer yet,
+// generated once per DNA version.
+func ShellInterview(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	interview, err := services.GetShellInterview(shell)
+	switch {
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+		return
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+		return
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+		return
+	case errors.Is(err, services.ErrRegistryPaused):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeRegistryPaused, err.Error())
+		return
+	case errors.Is(err, services.ErrInterviewClosed):
+		util.RespondError(c, http.StatusForbidden, util.CodeForbidden, err.Error())
+		return
+	case errors.Is(err, services.ErrInterviewUnavailable):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeUpstream, "Interview is not available right now")
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, interview)
+}
+
+// ShellSimilar handles GET /api/shell/:handle/similar?limit=6
+// Returns souls with similar seed summaries and dimension profiles.
+func ShellSimilar(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || shell.Stage == models.StagePending || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "6"))
+	if err != nil || limit < 1 || limit > 20 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "limit must be between 1 and 20")
+		return
+	}
+
+	similar, err := services.FindSimilarShells(shell, limit)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to compute similar souls")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"handle":  shell.Handle,
+		"similar": similar,
+	})
+}
+
+// ShellChainStates handles GET /api/shell/chain?handles=a,b,c
+// Returns the on-chain owner, agentURI and overall reputation of up to 50
+// souls, read in batches for soul lists.
+func ShellChainStates(c *gin.Context) {
+	var handles []string
+	for _, raw := range strings.Split(c.Query("handles"), ",") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		handle, ok := bindHandle(c, raw)
+		if !ok {
+			return
+		}
+		handles = append(handles, handle)
+	}
+	if len(handles) == 0 || len(handles) > services.MaxChainStateHandles {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest,
+			fmt.Sprintf("handles must list 1-%d comma-separated handles", services.MaxChainStateHandles))
+		return
+	}
+
+	states, err := services.GetShellsChainState(c.Request.Context(), handles)
+	if err != nil {
+		util.RespondError(c, http.StatusBadGateway, util.CodeUpstream, "Failed to read on-chain state: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shells": states})
+}
+
+// ShellReputation handles GET /api/shell/:handle/reputation
+// Returns the soul's on-chain reputation from the Reputation Registry.
+func ShellReputation(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	rep, err := services.GetShellReputation(c.Request.Context(), shell)
+	if err != nil {
+		if errors.Is(err, services.ErrShellNotMinted) {
+			util.RespondError(c, http.StatusNotFound, util.CodeShellNotMinted, err.Error())
+			return
+		}
+		util.RespondError(c, http.StatusBadGateway, util.CodeUpstream, "Failed to read on-chain reputation: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		Handle string `json:"handle"`
+		*services.ShellReputation
+	}{shell.Handle, rep})
+}
+
+// ShellGetFull handles GET /api/shell/:handle/full
+// Returns the soul, its dimensions, history, contributors and reputation in one
+// response, with an ETag so clients can refresh cheaply via If-None-Match.
+func ShellGetFull(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || shell.Stage == models.StagePending || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+	if shell.Handle != handle {
+		c.Redirect(http.StatusFound, "/api/shell/"+shell.Handle+"/full")
+		return
+	}
+
+	body, etag, err := services.GetShellFull(c.Request.Context(), shell)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// ShellStageHistory handles GET /api/shell/:handle/stage-history
+// Returns the soul's stage transitions, oldest first.
+func ShellStageHistory(c *gin.Context) {
+	handle, ok := handleParam(c)
+	if !ok {
+		return
+	}
+
+	shell, err := services.GetShellByHandle(handle)
+	if err != nil || !shell.OnChain() {
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, "Soul not found")
+		return
+	}
+
+	transitions, err := services.GetStageHistory(shell)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"handle":      shell.Handle,
+		"stage":       shell.Stage,
+		"transitions": transitions,
+	})
+}
+
+// ShellTimeline handles GET /api/shell/:handle/timeline
+// Returns the soul's milestones oldest first, with per-version dimension
+// scores, for rendering a timeline.
+func ShellTimeline(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	timeline, err := services.GetShellTimeline(shell)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to build timeline")
+		return
+	}
+	c.JSON(http.StatusOK, timeline)
+}
+
+// ShellFeed handles GET /api/shell/:handle/feed.atom
+// Atom feed of the soul's public activity for feed readers and syndication.
+func ShellFeed(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	feed, updated, err := services.RenderSoulFeed(shell)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to build feed")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Header("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !updated.Truncate(time.Second).After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", feed)
+}
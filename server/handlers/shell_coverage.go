@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ShellCoverage handles GET /api/shell/:handle/coverage?dimension=knowledge
+// Returns the topic clusters of the soul's accepted fragments, each with the
+// content hashes of its most representative fragments, and the gap topics
+// nothing covers yet, so agents can submit what is missing. Without
+// dimension all six are returned.
+func ShellCoverage(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	coverage, err := services.GetShellCoverage(shell, c.Query("dimension"))
+	switch {
+	case errors.Is(err, services.ErrCoverageDimension):
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidDimension, err.Error())
+		return
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+		return
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+		return
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+		return
+	case errors.Is(err, services.ErrRegistryPaused):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeRegistryPaused, err.Error())
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, coverage)
+}
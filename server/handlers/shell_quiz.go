@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// quizAnswersRequest is the body of a quiz submission.
+type quizAnswersRequest struct {
+	DNAVersion int                        `json:"dna_version" binding:"required"`
+	Answers    []services.QuizAnswerInput `json:"answers" binding:"required"`
+}
+
+// quizError writes the response for a quiz service error.
+func quizError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+	case errors.Is(err, services.ErrShellDisputed):
+		util.RespondError(c, http.StatusConflict, util.CodeShellDisputed, err.Error())
+	case errors.Is(err, services.ErrRegistryPaused):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeRegistryPaused, err.Error())
+	case errors.Is(err, services.ErrQuizTooFew):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrQuizStale):
+		util.RespondError(c, http.StatusConflict, util.CodeInvalidRequest, err.Error()+", fetch the quiz again")
+	case errors.Is(err, services.ErrQuizUnavailable):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeUpstream, "Quiz is not available right now")
+	default:
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+	}
+}
+
+// ShellQuiz handles GET /api/shell/:handle/quiz
+// Returns a multiple-choice "how well do you know @handle" quiz built from the
+// soul's accepted fragments, generated once per DNA version. Answers are not included.
+func ShellQuiz(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	quiz, err := services.GetShellQuiz(shell)
+	if err != nil {
+		quizError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quiz)
+}
+
+// ShellQuizAnswers handles POST /api/shell/:handle/quiz/answers
+// Body: {"dna_version": 3, "answers": [{"question": 0, "choice": 2, "disputed": false}]}.
+// Scores the answers and reveals the right ones with their source fragments.
+// "disputed" reports that the answer key is wrong about the person.
+func ShellQuizAnswers(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	var req quizAnswersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: dna_version, answers")
+		return
+	}
+
+	score, err := services.ScoreShellQuiz(shell, req.DNAVersion, req.Answers, middleware.GetSessionWallet(c), c.ClientIP())
+	if errors.Is(err, services.ErrQuizStale) {
+		quizError(c, err)
+		return
+	}
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, score)
+}
+
+// AdminQuizDisputes handles GET /api/admin/quiz/disputes?limit=50
+// Lists accepted fragments whose quiz questions takers dispute most.
+func AdminQuizDisputes(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "limit must be between 1 and 200")
+		return
+	}
+
+	fragments, err := services.ListDisputedQuizFragments(limit)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fragments": fragments})
+}
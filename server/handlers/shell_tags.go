@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// shellTagsRequest is the body for replacing a soul's topic tags.
+type shellTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// ShellTags handles GET /api/shell/tags
+// Lists the topic tags of listed souls with how many souls carry each.
+func ShellTags(c *gin.Context) {
+	tags, err := services.ListShellTags()
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to load tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// ShellTagPage handles GET /api/shell/tags/:tag
+// The souls carrying a tag, paginated and sorted like /api/shell/list.
+func ShellTagPage(c *gin.Context) {
+	tags, err := services.ParseTagFilter(c.Param("tag"))
+	if err != nil || len(tags) != 1 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid tag")
+		return
+	}
+
+	result, err := services.ListShells(c.Query("stage"), c.DefaultQuery("sort", "newest"), c.Query("search"), tags,
+		c.Query("view") == "slim", c.DefaultQuery("page", "1"), c.DefaultQuery("limit", "20"), c.Query("cursor"))
+	if errors.Is(err, services.ErrInvalidCursor) {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	result["tag"] = tags[0]
+	c.JSON(http.StatusOK, result)
+}
+
+// ShellSetTags handles PUT /api/shell/:handle/tags
+// Owner-only, signed message "ensoul:tags:<handle>:<timestamp>". Body: {"tags": [...]}.
+func ShellSetTags(c *gin.Context) {
+	shell, owner, ok := ownedShell(c, "tags")
+	if !ok {
+		return
+	}
+
+	var req shellTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: tags")
+		return
+	}
+	if err := services.SetShellTags(shell, req.Tags, owner); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"handle": shell.Handle, "tags": shell.Tags})
+}
+
+// AdminSetShellTags handles PUT /api/admin/shell/:handle/tags
+// Replaces a soul's topic tags on the owner's behalf. Body: {"tags": [...]}.
+func AdminSetShellTags(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	var req shellTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: tags")
+		return
+	}
+	if err := services.SetShellTags(shell, req.Tags, "admin "+middleware.GetSessionWallet(c)); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"handle": shell.Handle, "tags": shell.Tags})
+}
+
+// TaskTags handles GET /api/tasks/tags
+// Open task board work per soul tag, with how many Claws declare each tag,
+// so Claws can specialize by domain. Thinnest-covered domains first.
+func TaskTags(c *gin.Context) {
+	coverage, err := services.GetTagCoverage()
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to load tag coverage")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": coverage})
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// subjectError writes the response for a subject service error.
+func subjectError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotSubject):
+		util.RespondError(c, http.StatusForbidden, util.CodeForbidden, err.Error())
+	case errors.Is(err, services.ErrSubjectFragment):
+		util.RespondError(c, http.StatusNotFound, util.CodeFragmentNotFound, err.Error())
+	case errors.Is(err, services.ErrSubjectAlreadyVerified):
+		util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+	case errors.Is(err, services.ErrSubjectClaimNotFound), errors.Is(err, services.ErrSubjectTweetNotFound):
+		util.RespondError(c, http.StatusBadRequest, util.CodeVerificationFailed, err.Error())
+	case errors.Is(err, services.ErrSubjectVerifyDisabled):
+		util.RespondError(c, http.StatusServiceUnavailable, util.CodeUpstream, err.Error())
+	default:
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+	}
+}
+
+// ShellSubject handles GET /api/shell/:handle/subject
+// Public: the verified subject, chat pause, flagged fragments and owed revenue share.
+func ShellSubject(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, services.GetSubjectSummary(shell))
+}
+
+// ShellSubjectClaim handles POST /api/shell/:handle/subject/claim
+// Signed message "ensoul:subject-claim:<handle>:<timestamp>" from the claiming wallet.
+// Returns a code to tweet from the handle, then call /subject/verify.
+func ShellSubjectClaim(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	wallet, ok := requireWalletSignature(c, "subject-claim", shell)
+	if !ok {
+		return
+	}
+
+	start, err := services.StartSubjectClaim(shell, wallet)
+	if err != nil {
+		subjectError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, start)
+}
+
+// ShellSubjectVerify handles POST /api/shell/:handle/subject/verify
+// Signed message "ensoul:subject-claim:<handle>:<timestamp>" from the claiming wallet.
+func ShellSubjectVerify(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	wallet, ok := requireWalletSignature(c, "subject-claim", shell)
+	if !ok {
+		return
+	}
+
+	shell, err := services.VerifySubjectClaim(shell, wallet)
+	if err != nil {
+		subjectError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, services.GetSubjectSummary(shell))
+}
+
+// ShellSubjectChat handles PUT /api/shell/:handle/subject/chat
+// Subject-only, signed message "ensoul:subject:<handle>:<timestamp>". Body: {"paused": true}
+func ShellSubjectChat(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	wallet, ok := requireWalletSignature(c, "subject", shell)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Paused *bool `json:"paused" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: paused")
+		return
+	}
+
+	settings, err := services.SetSubjectChatPaused(shell, wallet, *req.Paused)
+	if err != nil {
+		subjectError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chat_paused": settings.SubjectPaused})
+}
+
+// ShellSubjectFlag handles PUT /api/shell/:handle/subject/flags/:fragment_id
+// Subject-only, signed message "ensoul:subject:<handle>:<timestamp>".
+// Body: {"reason": "..."}; an empty reason clears the flag.
+func ShellSubjectFlag(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	wallet, ok := requireWalletSignature(c, "subject", shell)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Optional: reason")
+		return
+	}
+
+	fragment, err := services.FlagFragment(shell, wallet, c.Param("fragment_id"), req.Reason)
+	if err != nil {
+		subjectError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fragment": fragment})
+}
+
+// ShellSubjectPayouts handles GET /api/shell/:handle/subject/payouts
+// Owner or subject, signed message "ensoul:subject-payouts:<handle>:<timestamp>".
+func ShellSubjectPayouts(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	wallet, ok := requireWalletSignature(c, "subject-payouts", shell)
+	if !ok {
+		return
+	}
+	if !strings.EqualFold(wallet, shell.OwnerAddr) && !services.IsShellSubject(shell, wallet) {
+		util.RespondError(c, http.StatusForbidden, util.CodeForbidden, "Only the soul's owner or verified subject can see payouts")
+		return
+	}
+
+	payouts, err := services.ListSubjectPayouts(shell)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to list payouts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payouts": payouts})
+}
+
+// ShellSubjectPayoutPaid handles POST /api/shell/:handle/subject/payouts/:id/paid
+// Owner-only, signed message "ensoul:subject-payouts:<handle>:<timestamp>".
+// Body: {"tx_hash": "0x..."} of the owner's transfer to the subject, verified
+// on-chain against the payout's subject address and amount.
+func ShellSubjectPayoutPaid(c *gin.Context) {
+	shell, _, ok := ownedShell(c, "subject-payouts")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TxHash string `json:"tx_hash" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: tx_hash")
+		return
+	}
+
+	payout, err := services.MarkSubjectPayoutPaid(shell, c.Param("id"), req.TxHash)
+	switch {
+	case errors.Is(err, services.ErrPayoutNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+		return
+	case errors.Is(err, services.ErrPayoutTxReused):
+		util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+		return
+	case err != nil:
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payout": payout})
+}
+
+// AdminRevokeSubject handles DELETE /api/admin/subjects/:handle
+// Removes a soul's verified subject, lifting their flags and chat pause.
+func AdminRevokeSubject(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	if err := services.RevokeSubject(shell); err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": true})
+}
+
+// disputeError writes the response for a handle dispute service error.
+func disputeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDisputeNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrDisputeOpen):
+		util.RespondError(c, http.StatusConflict, util.CodeDisputeOpen, err.Error())
+	case errors.Is(err, services.ErrDisputeResolved):
+		util.RespondError(c, http.StatusConflict, util.CodeAlreadyExists, err.Error())
+	case errors.Is(err, services.ErrShellNotFound):
+		util.RespondError(c, http.StatusNotFound, util.CodeShellNotFound, err.Error())
+	case errors.Is(err, services.ErrShellRevoked):
+		util.RespondError(c, http.StatusGone, util.CodeShellRevoked, err.Error())
+	case errors.Is(err, services.ErrShellRetired):
+		util.RespondError(c, http.StatusGone, util.CodeShellRetired, err.Error())
+	default:
+		subjectError(c, err)
+	}
+}
+
+// ShellDisputes handles GET /api/shell/:handle/disputes
+// Public: the soul's handle disputes and how they were resolved, newest first.
+func ShellDisputes(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+
+	disputes, err := services.ListShellDisputes(shell)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, "Failed to list disputes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"disputed": shell.DisputedAt != nil, "disputes": disputes})
+}
+
+// ShellDisputeFile handles POST /api/shell/:handle/disputes
+// Subject-only, signed message "ensoul:subject:<handle>:<timestamp>". Body: {"reason": "..."}
+// The soul is read-only until an admin resolves the dispute.
+func ShellDisputeFile(c *gin.Context) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return
+	}
+	wallet, ok := requireWalletSignature(c, "subject", shell)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: reason")
+		return
+	}
+
+	dispute, err := services.FileHandleDispute(shell, wallet, req.Reason)
+	if err != nil {
+		disputeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"dispute": dispute})
+}
+
+// AdminDisputes handles GET /api/admin/disputes?status=open&limit=50
+// Returns handle disputes, oldest first; status=all lists every dispute.
+func AdminDisputes(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "limit must be between 1 and 500")
+		return
+	}
+
+	status := c.DefaultQuery("status", models.DisputeOpen)
+	if status == "all" {
+		status = ""
+	}
+
+	disputes, err := services.ListHandleDisputes(status, limit)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"disputes": disputes})
+}
+
+// AdminResolveDispute handles POST /api/admin/disputes/:id/resolve
+// Body: {"action": "transfer" | "retire" | "dismiss", "note": "..."}
+func AdminResolveDispute(c *gin.Context) {
+	var req struct {
+		Action string `json:"action" binding:"required"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: action")
+		return
+	}
+
+	dispute, err := services.ResolveHandleDispute(c.Param("id"), req.Action, req.Note, middleware.GetSessionWallet(c))
+	if err != nil {
+		disputeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dispute": dispute})
+}
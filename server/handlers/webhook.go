@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/services"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// webhookRequest is the body for creating a webhook.
+type webhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"` // empty = all events
+}
+
+// ownedShell loads a minted shell and verifies the caller owns it for the given action.
+func ownedShell(c *gin.Context, action string) (*models.Shell, string, bool) {
+	shell, ok := mintedShell(c)
+	if !ok {
+		return nil, "", false
+	}
+	owner, ok := requireShellOwner(c, action, shell)
+	if !ok {
+		return nil, "", false
+	}
+	return shell, owner, true
+}
+
+// ShellWebhookCreate handles POST /api/shell/:handle/webhooks
+// Owner-only, signed message "ensoul:webhooks:<handle>:<timestamp>".
+// The signing secret is returned once, in this response.
+func ShellWebhookCreate(c *gin.Context) {
+	shell, owner, ok := ownedShell(c, "webhooks")
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: url; optional: events")
+		return
+	}
+
+	hook, secret, err := services.CreateWebhook(&shell.ID, owner, req.URL, req.Events)
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": hook, "secret": secret})
+}
+
+// ShellWebhookList handles GET /api/shell/:handle/webhooks
+// Owner-only, signed message "ensoul:webhooks:<handle>:<timestamp>".
+func ShellWebhookList(c *gin.Context) {
+	shell, _, ok := ownedShell(c, "webhooks")
+	if !ok {
+		return
+	}
+
+	hooks, err := services.ListWebhooks(&shell.ID)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks})
+}
+
+// ShellWebhookDelete handles DELETE /api/shell/:handle/webhooks/:id
+// Owner-only, signed message "ensoul:webhooks:<handle>:<timestamp>".
+func ShellWebhookDelete(c *gin.Context) {
+	shell, _, ok := ownedShell(c, "webhooks")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteWebhook(&shell.ID, c.Param("id")); err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// AdminWebhookCreate handles POST /api/admin/webhooks
+// Creates a global webhook that receives events for every soul.
+func AdminWebhookCreate(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid request. Required: url; optional: events")
+		return
+	}
+
+	hook, secret, err := services.CreateWebhook(nil, middleware.GetSessionWallet(c), req.URL, req.Events)
+	if err != nil {
+		util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": hook, "secret": secret})
+}
+
+// AdminWebhookList handles GET /api/admin/webhooks
+func AdminWebhookList(c *gin.Context) {
+	hooks, err := services.ListWebhooks(nil)
+	if err != nil {
+		util.RespondError(c, http.StatusInternalServerError, util.CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks})
+}
+
+// AdminWebhookDelete handles DELETE /api/admin/webhooks/:id
+func AdminWebhookDelete(c *gin.Context) {
+	if err := services.DeleteWebhook(nil, c.Param("id")); err != nil {
+		util.RespondError(c, http.StatusNotFound, util.CodeNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
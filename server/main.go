@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/ensoul-labs/ensoul-server/chain"
 	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/middleware"
 	"github.com/ensoul-labs/ensoul-server/router"
 	"github.com/ensoul-labs/ensoul-server/services"
 	"github.com/ensoul-labs/ensoul-server/util"
@@ -23,9 +25,32 @@ func main() {
 	// Connect to database and run migrations
 	database.Connect(cfg)
 
-	// Initialize blockchain client and ERC-8004 contract bindings
-	if err := chain.Init(); err != nil {
-		util.Log.Warn("Chain initialization failed (on-chain features disabled): %v", err)
+	// Load the shared read-only mode before jobs start, start read-only if
+	// MAINTENANCE_MODE=true, and follow switches made on other instances (every 5 sec)
+	middleware.InitMaintenance(5 * time.Second)
+
+	// Wire domain event subscribers (stage, ensouling, chain writes, webhooks, stats)
+	services.RegisterEventSubscribers(services.Events)
+
+	// Initialize blockchain client and ERC-8004 contract bindings. Fixtures mode
+	// seeds development data instead and stays off-chain, so the registry
+	// watcher doesn't revoke fixture souls whose agent IDs don't exist.
+	if cfg.Fixtures {
+		if _, err := services.SeedFixtures(false); err != nil && !errors.Is(err, services.ErrFixturesPresent) {
+			log.Fatalf("Failed to seed fixtures: %v", err)
+		}
+		util.Log.Info("Fixtures mode: chain disabled, owner wallet %s", services.FixtureOwnerWallet)
+	} else {
+		err := chain.Init()
+		switch {
+		case errors.Is(err, chain.ErrRPCUnavailable):
+			util.Log.Warn("Chain RPC unreachable, retrying in the background: %v", err)
+		case err != nil:
+			util.Log.Warn("Chain initialization failed (on-chain features disabled): %v", err)
+		}
+
+		// Start chain RPC health check, failover and reconnect (runs every 15 sec)
+		services.StartChainHealthCheck(15*time.Second, err)
 	}
 
 	// Start background agent_id backfill (checks every 2 minutes)
@@ -37,6 +62,65 @@ func main() {
 	// Start pending shell cleanup (checks every 5 min, deletes pending > 30 min)
 	services.StartPendingShellCleanup(5 * time.Minute)
 
+	// Start cached avatar / banner refresh (checks every 30 min)
+	services.StartMediaRefresh(30 * time.Minute)
+
+	// Start chat retention purge (runs every hour)
+	services.StartRetentionPurge(1 * time.Hour)
+
+	// Start rejected fragment archive (runs every 6 hours)
+	services.StartFragmentArchive(6 * time.Hour)
+
+	// Start webhook delivery retries (checks every 30 sec)
+	services.StartWebhookDelivery(30 * time.Second)
+
+	// Start weekly / monthly leaderboard rollup (runs every 10 min)
+	services.StartLeaderboardRollup(10 * time.Minute)
+
+	// Start Claw streak and badge computation (runs every hour)
+	services.StartClawAchievements(1 * time.Hour)
+
+	// Start scheduled seed refresh of high-traffic souls (checks every hour)
+	services.StartSeedRefresh(1 * time.Hour)
+
+	// Follow the Identity Registry for burned souls and pauses (every minute)
+	services.StartRegistryWatcher(1 * time.Minute)
+
+	// Load the ensouling policy and reload it every minute to pick up edits
+	services.StartPolicyReload(1 * time.Minute)
+
+	// Start the tx watcher: batched receipt polling for submitted transactions (every 3 sec)
+	services.StartTxWatcher(3 * time.Second)
+
+	// Record the gas cost of platform transactions from their receipts (every minute)
+	services.StartChainSpendTracker(1 * time.Minute)
+
+	// Anchor new DNA versions on-chain (every minute)
+	services.StartDNAAnchor(1 * time.Minute)
+
+	// Check read replica lag when DB_REPLICA_URL is set (every 15 sec)
+	services.StartReplicaCheck(15 * time.Second)
+
+	// Roll up daily admin dashboard stats for today and yesterday (every 10 min)
+	services.StartStatsRollup(10 * time.Minute)
+
+	// Recompute fragment, Claw and chat round counters from source tables (every 6 hours)
+	services.StartCounterReconcile(6 * time.Hour)
+
+	// Embed new and changed souls for similar-soul recommendations (every 10 min)
+	services.StartSoulEmbeddingRefresh(10 * time.Minute)
+
+	// Embed accepted fragments missing a current embedding (every 5 min)
+	services.StartFragmentEmbeddingBackfill(5 * time.Minute)
+
+	// Start topic coverage refresh of souls with new fragments (runs every hour)
+	services.StartCoverageRefresh(1 * time.Hour)
+
+	// Share rate limit buckets across replicas when RATE_LIMIT_STORE=redis
+	if err := middleware.InitLimiterStore(); err != nil {
+		log.Fatalf("Failed to initialize rate limit store: %v", err)
+	}
+
 	// Setup routes
 	r := router.Setup()
 
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// AuthAdmin requires a wallet session whose address is listed in ADMIN_WALLETS.
+func AuthAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		addr := GetSessionWallet(c)
+		if addr == "" {
+			util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
+			c.Abort()
+			return
+		}
+
+		if !IsAdminWallet(addr) {
+			util.RespondError(c, http.StatusForbidden, util.CodeAdminRequired, "Admin access required")
+			c.Abort()
+			return
+		}
+
+		c.Set("session_wallet", addr)
+		c.Next()
+	}
+}
+
+// IsAdminWallet reports whether the address is configured as an admin wallet.
+func IsAdminWallet(addr string) bool {
+	for _, admin := range config.Cfg.AdminWallets {
+		if strings.EqualFold(admin, addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/database"
 	"github.com/ensoul-labs/ensoul-server/models"
 	"github.com/ensoul-labs/ensoul-server/util"
@@ -14,41 +15,65 @@ import (
 // hashes it with SHA-256, and looks up the Claw by hash.
 func AuthClaw() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+		claw, apiErr := clawFromHeader(c.GetHeader("Authorization"))
+		if claw == nil {
+			status := http.StatusUnauthorized
+			if apiErr.Code == util.CodeClawRetired {
+				status = http.StatusForbidden
+			}
+			util.RespondAPIError(c, status, apiErr)
 			c.Abort()
 			return
 		}
 
-		// Expect "Bearer <api_key>"
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format, expected: Bearer <api_key>"})
-			c.Abort()
-			return
-		}
+		// Inject claw into context
+		c.Set("claw", claw)
+		c.Next()
+	}
+}
 
-		apiKey := parts[1]
-		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key is empty"})
-			c.Abort()
-			return
+// OptionalClaw injects the Claw when a valid API key is sent, and lets the
+// request through anonymously otherwise. For public endpoints that add
+// per-Claw behaviour, such as GET /api/tasks?fit=true.
+func OptionalClaw() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			if claw, _ := clawFromHeader(authHeader); claw != nil {
+				c.Set("claw", claw)
+			}
 		}
+		c.Next()
+	}
+}
 
-		// Hash the API key and look up by hash (keys are never stored in plaintext)
-		keyHash := util.HashToken(apiKey)
-		var claw models.Claw
-		if err := database.DB.Where("api_key_hash = ?", keyHash).First(&claw).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
-			c.Abort()
-			return
-		}
+// clawFromHeader resolves a "Bearer <api_key>" header to its Claw, or returns
+// the reason it could not.
+func clawFromHeader(authHeader string) (*models.Claw, util.APIError) {
+	if authHeader == "" {
+		return nil, util.APIError{Code: util.CodeAuthRequired, Message: "Authorization header is required"}
+	}
 
-		// Inject claw into context
-		c.Set("claw", &claw)
-		c.Next()
+	// Expect "Bearer <api_key>"
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return nil, util.APIError{Code: util.CodeAuthRequired, Message: "Invalid authorization format, expected: Bearer <api_key>"}
+	}
+
+	apiKey := parts[1]
+	if apiKey == "" {
+		return nil, util.APIError{Code: util.CodeAuthRequired, Message: "API key is empty"}
+	}
+
+	// Hash the API key and look up by hash (keys are never stored in plaintext)
+	keyHash := util.HashToken(apiKey)
+	var claw models.Claw
+	if err := database.DB.Where("api_key_hash = ?", keyHash).First(&claw).Error; err != nil {
+		return nil, util.APIError{Code: util.CodeInvalidAPIKey, Message: "Invalid API key"}
+	}
+	if claw.Status == models.ClawStatusRetired {
+		return nil, util.APIError{Code: util.CodeClawRetired, Message: "This Claw has been retired by its operator"}
 	}
+	return &claw, util.APIError{}
 }
 
 // RequireClaimed ensures the authenticated Claw has completed the claim process.
@@ -56,17 +81,17 @@ func RequireClaimed() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		clawVal, exists := c.Get("claw")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Authentication required")
 			c.Abort()
 			return
 		}
 
 		claw := clawVal.(*models.Claw)
 		if claw.Status != models.ClawStatusClaimed {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":     "Claw must complete the claim process before performing this action",
-				"status":    claw.Status,
-				"claim_url": "/claim/" + claw.ClaimCode,
+			util.RespondAPIError(c, http.StatusForbidden, util.APIError{
+				Code:    util.CodeClawNotClaimed,
+				Message: "Claw must complete the claim process before performing this action",
+				Details: gin.H{"status": claw.Status, "claim_url": config.Cfg.ClaimURL(claw.ClaimCode)},
 			})
 			c.Abort()
 			return
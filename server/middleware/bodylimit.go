@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// routeBodyLimits are the routes allowed larger bodies than REQUEST_MAX_BODY_KB,
+// keyed by "METHOD route pattern".
+var routeBodyLimits = map[string]int64{
+	"POST /api/fragment/batch":         1 << 20, // up to 6 fragments of up to 20k characters, with notes and claims
+	"POST /api/shell/:handle/simulate": 1 << 20,
+	"POST /api/fragment/verify":        4 << 20, // up to 50 fragments
+	"POST /v1/souls/:handle/chat":      256 << 10,
+}
+
+// errJSONTooDeep is returned by checkJSONDepth for over-nested documents.
+var errJSONTooDeep = errors.New("JSON nested too deeply")
+
+// BodyGuard caps request bodies, per route (routeBodyLimits) or at
+// REQUEST_MAX_BODY_KB, answering 413 PAYLOAD_TOO_LARGE with the limit. JSON
+// bodies nested deeper than REQUEST_MAX_JSON_DEPTH are refused with 400
+// JSON_TOO_DEEP before any handler decodes them.
+func BodyGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		limit := int64(config.Cfg.RequestMaxBodyKB) << 10
+		if routeLimit, ok := routeBodyLimits[c.Request.Method+" "+c.FullPath()]; ok {
+			limit = routeLimit
+		}
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			respondTooLarge(c, limit)
+			return
+		}
+		// Chunked or understated bodies are read up to one byte past the limit
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+		c.Request.Body.Close()
+		if err != nil {
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		if int64(len(body)) > limit {
+			respondTooLarge(c, limit)
+			return
+		}
+
+		if depth := config.Cfg.RequestMaxJSONDepth; depth > 0 && strings.Contains(c.ContentType(), "json") {
+			if err := checkJSONDepth(body, depth); errors.Is(err, errJSONTooDeep) {
+				util.RespondAPIError(c, http.StatusBadRequest, util.APIError{
+					Code:    util.CodeJSONTooDeep,
+					Message: fmt.Sprintf("JSON body is nested more than %d levels deep", depth),
+					Details: map[string]interface{}{"max_depth": depth},
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Next()
+	}
+}
+
+func respondTooLarge(c *gin.Context, limit int64) {
+	util.RespondAPIError(c, http.StatusRequestEntityTooLarge, util.APIError{
+		Code:    util.CodePayloadTooLarge,
+		Message: fmt.Sprintf("Request body too large (max %d KB)", limit>>10),
+		Details: map[string]interface{}{"limit_bytes": limit},
+	})
+	c.Abort()
+}
+
+// checkJSONDepth scans a JSON document's tokens and returns errJSONTooDeep
+// once objects and arrays nest deeper than maxDepth. Malformed JSON is left
+// for the handler's decoder to report.
+func checkJSONDepth(body []byte, maxDepth int) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > maxDepth {
+				return errJSONTooDeep
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
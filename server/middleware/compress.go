@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// gzipMinSize is the smallest body worth compressing; below it the gzip
+// header and trailer eat most of the gain.
+const gzipMinSize = 1024
+
+// Response sizes per route before and after compression, so the saving of
+// gzip (and of slim list views) can be read off /metrics.
+var (
+	responseBytes = util.NewCounterVec("ensoul_http_response_bytes_total",
+		"Response body bytes before compression, by route.", "route")
+	responseSentBytes = util.NewCounterVec("ensoul_http_response_sent_bytes_total",
+		"Response body bytes sent after compression, by route and encoding.", "route", "encoding")
+)
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	},
+}
+
+// Compress gzips JSON, text, XML and SVG responses for clients that accept
+// it. Event streams, images and bodies under gzipMinSize are sent as is.
+// With disabled set it only records response sizes.
+func Compress(disabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &compressWriter{ResponseWriter: c.Writer, enabled: !disabled && acceptsGzip(c.Request)}
+		c.Writer = w
+		c.Next()
+		w.close()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		encoding := "identity"
+		if w.gz != nil {
+			encoding = "gzip"
+		}
+		responseBytes.Add(float64(w.raw), route)
+		responseSentBytes.Add(float64(w.ResponseWriter.Size()), route, encoding)
+	}
+}
+
+// acceptsGzip reports whether the client accepts a gzip response body.
+func acceptsGzip(r *http.Request) bool {
+	if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+		return false
+	}
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, q, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(q, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// compressible reports whether a content type benefits from gzip.
+func compressible(contentType string) bool {
+	ct, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	switch ct = strings.TrimSpace(ct); {
+	case ct == "text/event-stream":
+		return false // must reach the client event by event
+	case strings.HasPrefix(ct, "text/"),
+		ct == "application/json", ct == "application/atom+xml", ct == "application/xml",
+		ct == "application/javascript", ct == "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter decides on the first write whether to gzip the body, once
+// the handler has set the content type.
+type compressWriter struct {
+	gin.ResponseWriter
+	enabled bool
+	decided bool
+	gz      *gzip.Writer
+	raw     int // body bytes written by the handler
+}
+
+func (w *compressWriter) decide(first []byte) {
+	w.decided = true
+	h := w.Header()
+	if !w.enabled || len(first) < gzipMinSize || h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) {
+		return
+	}
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return
+	}
+	h.Set("Content-Encoding", "gzip")
+	h.Add("Vary", "Accept-Encoding")
+	h.Del("Content-Length")
+	w.gz = gzipWriters.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.decide(b)
+	}
+	w.raw += len(b)
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+	return w.gz.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush pushes buffered compressed data to the client.
+func (w *compressWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(nil)
+	gzipWriters.Put(w.gz)
+}
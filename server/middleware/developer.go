@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// AuthDeveloperKey authenticates the public soul API (/v1) with a read-only
+// developer key sent as "Authorization: Bearer ensoul_pk_...". Claw keys are
+// not accepted here, and developer keys are not accepted by AuthClaw.
+func AuthDeveloperKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || parts[1] == "" {
+			util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Invalid authorization format, expected: Bearer <developer_key>")
+			c.Abort()
+			return
+		}
+
+		var key models.DeveloperKey
+		if err := database.DB.Where("key_hash = ?", util.HashToken(parts[1])).First(&key).Error; err != nil {
+			util.RespondError(c, http.StatusUnauthorized, util.CodeInvalidAPIKey, "Invalid developer key")
+			c.Abort()
+			return
+		}
+		if key.RevokedAt != nil {
+			util.RespondError(c, http.StatusUnauthorized, util.CodeInvalidAPIKey, "This developer key has been revoked")
+			c.Abort()
+			return
+		}
+
+		c.Set("developer_key", &key)
+		c.Next()
+	}
+}
+
+// GetDeveloperKey retrieves the authenticated developer key from the Gin context.
+func GetDeveloperKey(c *gin.Context) *models.DeveloperKey {
+	keyVal, exists := c.Get("developer_key")
+	if !exists {
+		return nil
+	}
+	return keyVal.(*models.DeveloperKey)
+}
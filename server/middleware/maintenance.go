@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaintenanceRetry is the Retry-After sent when no end time is known.
+const defaultMaintenanceRetry = 60
+
+// maintenanceExempt are path prefixes still writable while read-only, so an
+// admin can sign in and turn the mode off.
+var maintenanceExempt = []string{"/api/admin/", "/api/auth/"}
+
+// MaintenanceState is the platform's read-only mode, shared by every
+// instance through the maintenance_mode row.
+type MaintenanceState struct {
+	ReadOnly bool       `json:"read_only"`
+	Message  string     `json:"message,omitempty"`
+	Since    *time.Time `json:"since,omitempty"`
+	Until    *time.Time `json:"until,omitempty"` // expected end, informational
+	SetBy    string     `json:"set_by,omitempty"`
+}
+
+// maintenance caches the stored state, so requests don't read the database.
+var maintenance = struct {
+	sync.RWMutex
+	state MaintenanceState
+}{}
+
+// InitMaintenance loads the stored read-only state, applies MAINTENANCE_MODE
+// and keeps polling the state every interval, so a switch made on one
+// instance reaches all of them. MAINTENANCE_MODE only turns the mode on; an
+// instance starting without it doesn't turn off a mode an admin set.
+func InitMaintenance(interval time.Duration) {
+	if err := refreshMaintenance(); err != nil {
+		util.Log.Warn("[maintenance] Failed to load read-only state: %v", err)
+	}
+	if config.Cfg.MaintenanceMode && !Maintenance().ReadOnly {
+		if _, err := SetMaintenance(true, config.Cfg.MaintenanceMessage, nil, "config"); err != nil {
+			util.Log.Error("[maintenance] Failed to start read-only: %v", err)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := refreshMaintenance(); err != nil {
+				util.Log.Warn("[maintenance] Failed to poll read-only state, keeping the last one: %v", err)
+			}
+		}
+	}()
+}
+
+// refreshMaintenance reloads the cached state from the database.
+func refreshMaintenance() error {
+	var rows []models.MaintenanceMode
+	if err := database.DB.Where("id = ?", 1).Limit(1).Find(&rows).Error; err != nil {
+		return err
+	}
+	state := MaintenanceState{}
+	if len(rows) == 1 && rows[0].ReadOnly {
+		row := rows[0]
+		state = MaintenanceState{ReadOnly: true, Message: row.Message, Since: row.Since, Until: row.Until, SetBy: row.SetBy}
+	}
+
+	maintenance.Lock()
+	was := maintenance.state.ReadOnly
+	maintenance.state = state
+	maintenance.Unlock()
+	switch {
+	case state.ReadOnly && !was:
+		util.Log.Warn("[maintenance] Read-only mode on (%s): %s", state.SetBy, state.Message)
+	case !state.ReadOnly && was:
+		util.Log.Info("[maintenance] Read-only mode off")
+	}
+	return nil
+}
+
+// SetMaintenance turns read-only mode on or off for every instance. Turning
+// it on again updates the message and end time but keeps the start.
+func SetMaintenance(readOnly bool, message string, until *time.Time, by string) (MaintenanceState, error) {
+	var since *time.Time
+	if readOnly {
+		now := time.Now()
+		since = &now
+	} else {
+		message, until = "", nil
+	}
+	if err := database.DB.Exec(`
+		INSERT INTO maintenance_mode (id, read_only, message, since, until, set_by, updated_at)
+		VALUES (1, ?, ?, ?, ?, ?, NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			since = CASE WHEN EXCLUDED.read_only AND maintenance_mode.read_only THEN maintenance_mode.since ELSE EXCLUDED.since END,
+			read_only = EXCLUDED.read_only,
+			message = EXCLUDED.message,
+			until = EXCLUDED.until,
+			set_by = EXCLUDED.set_by,
+			updated_at = NOW()`,
+		readOnly, message, since, until, by).Error; err != nil {
+		return MaintenanceState{}, fmt.Errorf("failed to store read-only mode: %w", err)
+	}
+	if err := refreshMaintenance(); err != nil {
+		return MaintenanceState{}, fmt.Errorf("failed to reload read-only mode: %w", err)
+	}
+	return Maintenance(), nil
+}
+
+// Maintenance returns the read-only state as of the last poll.
+func Maintenance() MaintenanceState {
+	maintenance.RLock()
+	defer maintenance.RUnlock()
+	return maintenance.state
+}
+
+// ReadOnly rejects writes with 503 MAINTENANCE while read-only mode is on.
+// GET, HEAD and OPTIONS requests are always served.
+func ReadOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		state := Maintenance()
+		if !state.ReadOnly {
+			c.Next()
+			return
+		}
+		for _, prefix := range maintenanceExempt {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		message := state.Message
+		if message == "" {
+			message = "Ensoul is in read-only mode for maintenance. Browsing works; please retry writes later."
+		}
+		retry := defaultMaintenanceRetry
+		details := map[string]interface{}{"read_only": true, "since": state.Since}
+		if state.Until != nil {
+			details["until"] = state.Until
+			if left := int(time.Until(*state.Until).Seconds()); left > 0 {
+				retry = left
+			}
+		}
+		util.RespondAPIError(c, http.StatusServiceUnavailable, util.APIError{
+			Code:       util.CodeMaintenance,
+			Message:    message,
+			Details:    details,
+			RetryAfter: retry,
+		})
+		c.Abort()
+	}
+}
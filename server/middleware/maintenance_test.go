@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMaintenanceShared(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	util.InitLogger("error")
+	config.Cfg = &config.Config{}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		maintenance.state = MaintenanceState{}
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	r := gin.New()
+	r.Use(ReadOnly())
+	r.POST("/api/fragment", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	post := func() int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fragment", nil))
+		return w.Code
+	}
+
+	state, err := SetMaintenance(true, "migrating", nil, "0xadmin")
+	if err != nil || !state.ReadOnly || state.Since == nil {
+		t.Fatalf("SetMaintenance(true) = %+v, %v", state, err)
+	}
+	if code := post(); code != http.StatusServiceUnavailable {
+		t.Errorf("write while read-only = %d, want 503", code)
+	}
+
+	// Setting it again keeps the start
+	time.Sleep(10 * time.Millisecond)
+	again, _ := SetMaintenance(true, "still migrating", nil, "0xadmin")
+	if !again.Since.Equal(*state.Since) || again.Message != "still migrating" {
+		t.Errorf("second SetMaintenance = %+v, want since %v kept and the new message", again, state.Since)
+	}
+
+	// Another instance turning it off reaches this one at the next poll
+	database.DB.Model(&models.MaintenanceMode{}).Where("id = ?", 1).Update("read_only", false)
+	if !Maintenance().ReadOnly {
+		t.Fatal("cached state changed before the poll")
+	}
+	if err := refreshMaintenance(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if Maintenance().ReadOnly {
+		t.Error("read-only after another instance turned it off")
+	}
+	if code := post(); code != http.StatusCreated {
+		t.Errorf("write after read-only ended = %d, want 201", code)
+	}
+}
@@ -2,10 +2,14 @@ package middleware
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -33,57 +37,124 @@ func (b *bucket) allow() bool {
 	return false
 }
 
-// RateLimiter holds per-key buckets.
-type RateLimiter struct {
-	mu         sync.Mutex
-	buckets    map[string]*bucket
-	maxTokens  float64
-	refillRate float64
+// LimiterStore keeps the token buckets of every rate limiter, keyed by
+// "<limiter>:<key>". The memory store is per process; the Redis store is shared
+// by all replicas behind a load balancer.
+type LimiterStore interface {
+	Allow(key string, maxTokens, refillRate float64) (bool, error)
 }
 
-// NewRateLimiter creates a rate limiter.
-// maxTokens = burst capacity, refillRate = tokens per second.
-func NewRateLimiter(maxTokens float64, refillRate float64) *RateLimiter {
-	rl := &RateLimiter{
-		buckets:    make(map[string]*bucket),
-		maxTokens:  maxTokens,
-		refillRate: refillRate,
-	}
+// memoryStore holds buckets in process memory.
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newMemoryStore() *memoryStore {
+	ms := &memoryStore{buckets: make(map[string]*bucket)}
 	// Cleanup stale buckets every 5 minutes
-	go rl.cleanup()
-	return rl
+	go ms.cleanup()
+	return ms
 }
 
-func (rl *RateLimiter) cleanup() {
+func (ms *memoryStore) cleanup() {
 	for {
 		time.Sleep(5 * time.Minute)
-		rl.mu.Lock()
+		ms.mu.Lock()
 		cutoff := time.Now().Add(-10 * time.Minute)
-		for k, b := range rl.buckets {
+		for k, b := range ms.buckets {
 			if b.lastRefill.Before(cutoff) {
-				delete(rl.buckets, k)
+				delete(ms.buckets, k)
 			}
 		}
-		rl.mu.Unlock()
+		ms.mu.Unlock()
 	}
 }
 
-// Allow checks if a request from the given key is allowed.
-func (rl *RateLimiter) Allow(key string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// Allow implements LimiterStore.
+func (ms *memoryStore) Allow(key string, maxTokens, refillRate float64) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 
-	b, exists := rl.buckets[key]
+	b, exists := ms.buckets[key]
 	if !exists {
 		b = &bucket{
-			tokens:     rl.maxTokens,
-			maxTokens:  rl.maxTokens,
-			refillRate: rl.refillRate,
+			tokens:     maxTokens,
+			maxTokens:  maxTokens,
+			refillRate: refillRate,
 			lastRefill: time.Now(),
 		}
-		rl.buckets[key] = b
+		ms.buckets[key] = b
+	}
+	return b.allow(), nil
+}
+
+var (
+	// localStore is the default store, and the fallback while a shared store fails.
+	localStore = newMemoryStore()
+	// limiterStore is where every limiter keeps its buckets (see UseLimiterStore).
+	limiterStore LimiterStore = localStore
+	// lastStoreWarning throttles the shared store failure warning (unix seconds).
+	lastStoreWarning atomic.Int64
+)
+
+// InitLimiterStore selects the limiter store from RATE_LIMIT_STORE. The redis
+// store must be reachable at startup; later outages fall back to local buckets.
+func InitLimiterStore() error {
+	switch config.Cfg.RateLimitStore {
+	case "", "memory":
+		return nil
+	case "redis":
+		store, err := NewRedisLimiterStore(config.Cfg.RedisURL)
+		if err != nil {
+			return err
+		}
+		UseLimiterStore(store)
+		util.Log.Info("[ratelimit] Rate limits shared through Redis")
+		return nil
+	default:
+		return fmt.Errorf("unknown RATE_LIMIT_STORE %q (use memory or redis)", config.Cfg.RateLimitStore)
+	}
+}
+
+// UseLimiterStore makes every rate limiter keep its buckets in store.
+// Call it before serving requests.
+func UseLimiterStore(store LimiterStore) {
+	limiterStore = store
+}
+
+// RateLimiter is a named token-bucket limit applied per key.
+type RateLimiter struct {
+	name       string
+	maxTokens  float64
+	refillRate float64
+}
+
+// NewRateLimiter creates a rate limiter. The name prefixes its keys in the store.
+// maxTokens = burst capacity, refillRate = tokens per second.
+func NewRateLimiter(name string, maxTokens float64, refillRate float64) *RateLimiter {
+	return &RateLimiter{
+		name:       name,
+		maxTokens:  maxTokens,
+		refillRate: refillRate,
+	}
+}
+
+// Allow checks if a request from the given key is allowed. If the shared
+// store is unreachable the process-local buckets are used, so an outage
+// degrades to per-replica limits instead of failing requests.
+func (rl *RateLimiter) Allow(key string) bool {
+	storeKey := rl.name + ":" + key
+	allowed, err := limiterStore.Allow(storeKey, rl.maxTokens, rl.refillRate)
+	if err == nil {
+		return allowed
+	}
+	if now := time.Now().Unix(); now-lastStoreWarning.Load() >= 60 {
+		lastStoreWarning.Store(now)
+		util.Log.Warn("[ratelimit] Shared limiter store failed, using local buckets: %v", err)
 	}
-	return b.allow()
+	allowed, _ = localStore.Allow(storeKey, rl.maxTokens, rl.refillRate)
+	return allowed
 }
 
 // clientIP extracts the real client IP, respecting X-Forwarded-For.
@@ -95,23 +166,23 @@ func clientIP(c *gin.Context) string {
 
 var (
 	// GeneralLimiter: 60 requests per minute (1/s burst 60)
-	GeneralLimiter = NewRateLimiter(60, 1.0)
+	GeneralLimiter = NewRateLimiter("general", 60, 1.0)
 
 	// ChatLimiter: 20 messages per minute (stricter, each triggers LLM call)
-	ChatLimiter = NewRateLimiter(20, 0.33)
+	ChatLimiter = NewRateLimiter("chat", 20, 0.33)
 
 	// SubmitLimiter: IP-level general protection for submit endpoint
-	SubmitLimiter = NewRateLimiter(10, 0.2)
+	SubmitLimiter = NewRateLimiter("submit", 10, 0.2)
 
 	// ClawSubmitLimiter: 1 fragment per 5 minutes per Claw (quality over quantity)
 	// maxTokens=1 (no burst), refillRate=1/300 (one token every 300 seconds)
-	ClawSubmitLimiter = NewRateLimiter(1, 1.0/300.0)
+	ClawSubmitLimiter = NewRateLimiter("claw-submit", 1, 1.0/300.0)
 
 	// RegisterLimiter: 5 registrations per minute (very strict)
-	RegisterLimiter = NewRateLimiter(5, 0.08)
+	RegisterLimiter = NewRateLimiter("register", 5, 0.08)
 
 	// SessionLimiter: 10 session creations per minute
-	SessionLimiter = NewRateLimiter(10, 0.17)
+	SessionLimiter = NewRateLimiter("session", 10, 0.17)
 )
 
 // RateLimit returns a Gin middleware that applies the given limiter by client IP.
@@ -119,8 +190,10 @@ func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := clientIP(c)
 		if !limiter.Allow(ip) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "rate limit exceeded, please try again later",
+			util.RespondAPIError(c, http.StatusTooManyRequests, util.APIError{
+				Code:       util.CodeRateLimited,
+				Message:    "rate limit exceeded, please try again later",
+				RetryAfter: int(math.Ceil(1.0 / limiter.refillRate)),
 			})
 			c.Abort()
 			return
@@ -140,10 +213,10 @@ func RateLimitByKey(limiter *RateLimiter, keyFn func(c *gin.Context) string) gin
 		if !limiter.Allow(key) {
 			// Calculate seconds until next token
 			waitSecs := int(1.0 / limiter.refillRate)
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":       "rate limit exceeded",
-				"message":     fmt.Sprintf("Quality over quantity — you can submit 1 fragment every %d minutes. Please take time to research and analyze deeply before your next submission.", waitSecs/60),
-				"retry_after": waitSecs,
+			util.RespondAPIError(c, http.StatusTooManyRequests, util.APIError{
+				Code:       util.CodeRateLimited,
+				Message:    fmt.Sprintf("Quality over quantity — you can submit 1 fragment every %d minutes. Please take time to research and analyze deeply before your next submission.", waitSecs/60),
+				RetryAfter: waitSecs,
 			})
 			c.Abort()
 			return
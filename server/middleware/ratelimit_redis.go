@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix   = "ensoul:ratelimit:"
+	redisDialTimeout = 2 * time.Second
+	redisCmdTimeout  = time.Second
+)
+
+// redisTokenBucket refills and takes a token in one atomic step. The bucket
+// is a hash of {tokens, ts} that expires once it would be full again.
+var redisTokenBucket = redis.NewScript(`
+local max = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+if tokens == nil or ts == nil then
+  tokens = max
+  ts = now
+end
+tokens = math.min(max, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('EXPIRE', KEYS[1], math.ceil(max / rate) + 60)
+return allowed
+`)
+
+// redisStore keeps token buckets in Redis so every replica shares them.
+type redisStore struct {
+	client *redis.Client
+}
+
+// NewRedisLimiterStore returns a LimiterStore backed by the Redis server at
+// rawURL (redis://[user:password@]host:port[/db], or rediss:// for TLS) and
+// checks that it is reachable.
+func NewRedisLimiterStore(rawURL string) (LimiterStore, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL, expected redis://[user:password@]host:port[/db]: %w", err)
+	}
+	opts.DialTimeout = redisDialTimeout
+	opts.ReadTimeout = redisCmdTimeout
+	opts.WriteTimeout = redisCmdTimeout
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisDialTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis: failed to connect to %s: %w", opts.Addr, err)
+	}
+	return &redisStore{client: client}, nil
+}
+
+// Allow implements LimiterStore. The script runs by SHA and is loaded on
+// first use (or after a Redis restart) by go-redis.
+func (rs *redisStore) Allow(key string, maxTokens, refillRate float64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCmdTimeout)
+	defer cancel()
+	n, err := redisTokenBucket.Run(ctx, rs.client, []string{redisKeyPrefix + key}, maxTokens, refillRate).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -12,12 +12,15 @@ import (
 
 const sessionCookieName = "ensoul_session"
 
+// sessionTouchInterval throttles last_seen_at writes to one per session per interval.
+const sessionTouchInterval = 5 * time.Minute
+
 // AuthSession validates the session cookie and injects the wallet address.
 func AuthSession() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token, err := c.Cookie(sessionCookieName)
 		if err != nil || token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+			util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Not logged in")
 			c.Abort()
 			return
 		}
@@ -25,11 +28,12 @@ func AuthSession() gin.HandlerFunc {
 		tokenHash := util.HashToken(token)
 		var session models.WalletSession
 		if err := database.DB.Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).First(&session).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or invalid"})
+			util.RespondError(c, http.StatusUnauthorized, util.CodeSessionExpired, "Session expired or invalid")
 			c.Abort()
 			return
 		}
 
+		touchSession(&session)
 		c.Set("session_wallet", session.WalletAddr)
 		c.Next()
 	}
@@ -55,5 +59,14 @@ func GetSessionWallet(c *gin.Context) string {
 		return ""
 	}
 
+	touchSession(&session)
 	return session.WalletAddr
 }
+
+// touchSession records that a session was used, for the device list.
+func touchSession(session *models.WalletSession) {
+	if time.Since(session.LastSeenAt) < sessionTouchInterval {
+		return
+	}
+	database.DB.Model(session).UpdateColumn("last_seen_at", time.Now())
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/ensoul-labs/ensoul-server/util"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gin-gonic/gin"
@@ -25,14 +26,14 @@ func AuthWallet() gin.HandlerFunc {
 		signature := c.GetHeader("X-Wallet-Signature")
 
 		if address == "" || signature == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Wallet authentication required (X-Wallet-Address, X-Wallet-Signature)"})
+			util.RespondError(c, http.StatusUnauthorized, util.CodeAuthRequired, "Wallet authentication required (X-Wallet-Address, X-Wallet-Signature)")
 			c.Abort()
 			return
 		}
 
 		// Validate address format
 		if !common.IsHexAddress(address) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address format"})
+			util.RespondError(c, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid wallet address format")
 			c.Abort()
 			return
 		}
@@ -14,7 +14,7 @@ func (j JSON) Value() (driver.Value, error) {
 	if j == nil {
 		return "{}", nil
 	}
-	return json.Marshal(j)
+	return marshalJSON(j)
 }
 
 // Scan implements the sql.Scanner interface for database reads.
@@ -24,9 +24,14 @@ func (j *JSON) Scan(value interface{}) error {
 		return nil
 	}
 
-	bytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("failed to scan JSON: value is not []byte")
+	var bytes []byte
+	switch val := value.(type) {
+	case []byte:
+		bytes = val
+	case string:
+		bytes = []byte(val)
+	default:
+		return errors.New("failed to scan JSON: unsupported type")
 	}
 
 	result := make(JSON)
@@ -37,12 +42,28 @@ func (j *JSON) Scan(value interface{}) error {
 	return nil
 }
 
+// marshalJSON encodes v as JSON text. Text rather than bytes keeps the value
+// a JSON string on SQLite, where blobs are not valid JSON.
+func marshalJSON(v interface{}) (driver.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
 // DimensionData represents the score and summary for a single dimension.
 type DimensionData struct {
 	Score   int    `json:"score"`
 	Summary string `json:"summary"`
 }
 
+// OnChain reports whether the shell has a soul NFT: it was minted through
+// Ensoul (mint_tx_hash) or imported from an existing ERC-8004 agent.
+func (s *Shell) OnChain() bool {
+	return s.MintTxHash != "" || s.ImportedAt != nil
+}
+
 // GetDimensions parses the Dimensions JSON into a structured map.
 func (s *Shell) GetDimensions() map[string]DimensionData {
 	result := make(map[string]DimensionData)
@@ -60,3 +81,147 @@ func (s *Shell) GetDimensions() map[string]DimensionData {
 	}
 	return result
 }
+
+// Vector is an embedding vector stored as a JSON array in a jsonb column.
+type Vector []float64
+
+// Value implements the driver.Valuer interface for database writes.
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return "[]", nil
+	}
+	return marshalJSON(v)
+}
+
+// Scan implements the sql.Scanner interface for database reads.
+func (v *Vector) Scan(value interface{}) error {
+	if value == nil {
+		*v = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch val := value.(type) {
+	case []byte:
+		bytes = val
+	case string:
+		bytes = []byte(val)
+	default:
+		return errors.New("failed to scan Vector: unsupported type")
+	}
+
+	var result []float64
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return err
+	}
+	*v = result
+	return nil
+}
+
+// StringList is a list of strings stored as a JSON array in a jsonb column.
+type StringList []string
+
+// Value implements the driver.Valuer interface for database writes.
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return marshalJSON(l)
+}
+
+// Scan implements the sql.Scanner interface for database reads.
+func (l *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch val := value.(type) {
+	case []byte:
+		bytes = val
+	case string:
+		bytes = []byte(val)
+	default:
+		return errors.New("failed to scan StringList: unsupported type")
+	}
+
+	var result []string
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return err
+	}
+	*l = result
+	return nil
+}
+
+// ChatCitations is a list of citations stored as a JSON array in a jsonb column.
+type ChatCitations []ChatCitation
+
+// Value implements the driver.Valuer interface for database writes.
+func (l ChatCitations) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return marshalJSON(l)
+}
+
+// Scan implements the sql.Scanner interface for database reads.
+func (l *ChatCitations) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch val := value.(type) {
+	case []byte:
+		bytes = val
+	case string:
+		bytes = []byte(val)
+	default:
+		return errors.New("failed to scan ChatCitations: unsupported type")
+	}
+
+	var result []ChatCitation
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return err
+	}
+	*l = result
+	return nil
+}
+
+// FragmentClaims is a list of fragment claims stored as a JSON array in a jsonb column.
+type FragmentClaims []FragmentClaim
+
+// Value implements the driver.Valuer interface for database writes.
+func (l FragmentClaims) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return marshalJSON(l)
+}
+
+// Scan implements the sql.Scanner interface for database reads.
+func (l *FragmentClaims) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch val := value.(type) {
+	case []byte:
+		bytes = val
+	case string:
+		bytes = []byte(val)
+	default:
+		return errors.New("failed to scan FragmentClaims: unsupported type")
+	}
+
+	var result []FragmentClaim
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return err
+	}
+	*l = result
+	return nil
+}
@@ -16,6 +16,17 @@ const (
 	StageEvolving = "evolving"
 )
 
+// Shell chain status constants
+const (
+	ShellChainActive  = "active"
+	ShellChainRevoked = "revoked" // the soul NFT was burned; the soul is read-only
+	ShellChainRetired = "retired" // retired by its owner; the soul is read-only
+)
+
+// ShellOnChainSQL is the WHERE condition for shells with a soul NFT: minted
+// through Ensoul or imported from an existing agent. See Shell.OnChain.
+const ShellOnChainSQL = "(mint_tx_hash <> '' OR imported_at IS NOT NULL)"
+
 // Fragment dimension constants
 const (
 	DimPersonality  = "personality"
@@ -37,73 +48,176 @@ const (
 const (
 	ClawStatusPendingClaim = "pending_claim"
 	ClawStatusClaimed      = "claimed"
+	ClawStatusSystem       = "system"  // built-in contributor, e.g. the seed refresher
+	ClawStatusRetired      = "retired" // retired by its operator; API key disabled, name kept
+)
+
+// Claw wallet custody constants
+const (
+	ClawCustodyPlatform = "platform" // the server signs with the wallet it generated
+	ClawCustodyExternal = "external" // the operator's own address authorizes feedback; the platform relays it
+)
+
+// Fragment appeal status constants
+const (
+	AppealStatusPending    = "pending"
+	AppealStatusUpheld     = "upheld"     // second review confirmed the rejection
+	AppealStatusOverturned = "overturned" // second review accepted the fragment
+	AppealStatusFailed     = "failed"     // second review could not run
 )
 
 // Shell represents a Soul / DNA NFT on-chain.
 type Shell struct {
-	ID            uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Handle        string         `gorm:"uniqueIndex;not null" json:"handle"`
-	TokenID       *uint64        `gorm:"type:bigint" json:"token_id"`
-	OwnerAddr     string         `gorm:"type:varchar(42)" json:"owner_addr"`
-	Stage         string         `gorm:"type:varchar(20);default:'embryo'" json:"stage"`
-	DNAVersion    int            `gorm:"default:0" json:"dna_version"`
-	SeedSummary   string         `gorm:"type:text" json:"seed_summary"`
-	SoulPrompt    string         `gorm:"type:text" json:"soul_prompt"`
-	Dimensions    JSON           `gorm:"type:jsonb;default:'{}'" json:"dimensions"`
-	TotalFrags    int            `gorm:"default:0" json:"total_frags"`
-	AcceptedFrags int            `gorm:"default:0" json:"accepted_frags"`
-	TotalClaws    int            `gorm:"default:0" json:"total_claws"`
-	TotalChats    int            `gorm:"default:0" json:"total_chats"`
-	AvatarURL     string         `gorm:"type:text" json:"avatar_url"`
-	DisplayName   string         `gorm:"type:varchar(255)" json:"display_name"`
-	TwitterMeta   JSON           `gorm:"type:jsonb;default:'{}'" json:"twitter_meta"`
-	AgentID       *uint64        `gorm:"type:bigint" json:"agent_id"` // ERC-8004 agent ID
-	AgentURI      string         `gorm:"type:text" json:"agent_uri"`
-	MintTxHash    string         `gorm:"type:varchar(66)" json:"mint_tx_hash,omitempty"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Handle            string         `gorm:"uniqueIndex;not null" json:"handle"`
+	TokenID           *uint64        `gorm:"type:bigint" json:"token_id"`
+	OwnerAddr         string         `gorm:"type:varchar(42)" json:"owner_addr"`
+	Stage             string         `gorm:"type:varchar(20);default:'embryo'" json:"stage"`
+	DNAVersion        int            `gorm:"default:0" json:"dna_version"`
+	SeedSummary       string         `gorm:"type:text" json:"seed_summary,omitempty"`      // generation input; public endpoints return Essence instead
+	Essence           string         `gorm:"type:text;not null;default:''" json:"essence"` // public-safe summary, rewritten by each ensouling
+	SoulPrompt        string         `gorm:"type:text" json:"soul_prompt"`
+	Dimensions        JSON           `gorm:"type:jsonb;default:'{}'" json:"dimensions"`
+	TotalFrags        int            `gorm:"default:0;check:total_frags >= 0" json:"total_frags"`
+	AcceptedFrags     int            `gorm:"default:0;check:accepted_frags >= 0" json:"accepted_frags"`
+	TotalClaws        int            `gorm:"default:0;check:total_claws >= 0" json:"total_claws"`
+	TotalChats        int            `gorm:"default:0;check:total_chats >= 0" json:"total_chats"`
+	TimeTravelChats   int            `gorm:"default:0;check:time_travel_chats >= 0" json:"time_travel_chats"` // chats with a past DNA version
+	AvatarURL         string         `gorm:"type:text" json:"avatar_url"`
+	DisplayName       string         `gorm:"type:varchar(255)" json:"display_name"`
+	TwitterMeta       JSON           `gorm:"type:jsonb;default:'{}'" json:"twitter_meta"`
+	Tags              StringList     `gorm:"type:jsonb;default:'[]';index:idx_shells_tags,type:gin" json:"tags"` // topic categories: seeded by the LLM, editable by the owner and admins
+	AgentID           *uint64        `gorm:"type:bigint" json:"agent_id"`                                        // ERC-8004 agent ID
+	AgentURI          string         `gorm:"type:text" json:"agent_uri"`
+	MintTxHash        string         `gorm:"type:varchar(66)" json:"mint_tx_hash,omitempty"`
+	ImportedAt        *time.Time     `json:"imported_at,omitempty"`                                          // set for agents registered outside Ensoul and imported
+	ChainStatus       string         `gorm:"type:varchar(20);not null;default:'active'" json:"chain_status"` // active | revoked (NFT burned) | retired (by the owner)
+	RevokedAt         *time.Time     `json:"revoked_at,omitempty"`
+	RetiredAt         *time.Time     `json:"retired_at,omitempty"`
+	SeedRefreshedAt   *time.Time     `json:"seed_refreshed_at"`                                        // last check for new tweets
+	SeedLastTweetID   string         `gorm:"type:varchar(32)" json:"-"`                                // newest tweet already turned into candidates
+	KnowledgeCutoff   *time.Time     `json:"knowledge_cutoff,omitempty"`                               // newest seed tweet or merged fragment the soul knows of
+	VerifiedSubject   string         `gorm:"type:varchar(42);index" json:"verified_subject,omitempty"` // wallet of the person behind the handle
+	SubjectVerifiedAt *time.Time     `json:"subject_verified_at,omitempty"`
+	DisputedAt        *time.Time     `json:"disputed_at,omitempty"` // set while a handle dispute is open; the soul is read-only
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // Fragment represents a piece of soul data contributed by a Claw.
 type Fragment struct {
-	ID           uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	ShellID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"shell_id"`
-	ClawID       uuid.UUID      `gorm:"type:uuid;not null;index" json:"claw_id"`
-	Dimension    string         `gorm:"type:varchar(20);not null" json:"dimension"`
-	Content      string         `gorm:"type:text;not null" json:"content,omitempty"`
-	ContentHash  string         `gorm:"type:varchar(64);not null;default:''" json:"content_hash"`
-	Status       string         `gorm:"type:varchar(20);default:'pending'" json:"status"`
-	Confidence   float64        `gorm:"type:decimal(3,2);default:0" json:"confidence"`
-	RejectReason string         `gorm:"type:text" json:"reject_reason,omitempty"`
-	EnsoulingID  *uuid.UUID     `gorm:"type:uuid" json:"ensouling_id,omitempty"`
-	TxHash       string         `gorm:"type:varchar(66)" json:"tx_hash,omitempty"`
-	CreatedAt    time.Time      `json:"created_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID          uuid.UUID      `gorm:"type:uuid;not null;index" json:"shell_id"`
+	ClawID           uuid.UUID      `gorm:"type:uuid;not null;index" json:"claw_id"`
+	Dimension        string         `gorm:"type:varchar(20);not null" json:"dimension"`
+	Content          string         `gorm:"type:text;not null" json:"content,omitempty"`
+	ContentHash      string         `gorm:"type:varchar(64);not null;default:''" json:"content_hash"`
+	Lang             string         `gorm:"type:varchar(8)" json:"lang,omitempty"`  // ISO 639-1 code of the content
+	Claims           FragmentClaims `gorm:"type:jsonb" json:"claims,omitempty"`     // optional structured form of the content
+	Notes            string         `gorm:"type:text;not null;default:''" json:"-"` // the Claw operator's private notes (services.ClawFragment)
+	Status           string         `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	Confidence       float64        `gorm:"type:decimal(3,2);default:0" json:"confidence"`
+	RejectReason     string         `gorm:"type:text" json:"reject_reason,omitempty"`
+	EnsoulingID      *uuid.UUID     `gorm:"type:uuid" json:"ensouling_id,omitempty"`
+	BatchID          *uuid.UUID     `gorm:"type:uuid;index" json:"batch_id,omitempty"`                   // FragmentBatch of the submission
+	SubjectFlag      string         `gorm:"type:text;not null;default:''" json:"subject_flag,omitempty"` // the verified subject's objection; flagged fragments are kept out of the soul
+	SubjectFlaggedAt *time.Time     `json:"subject_flagged_at,omitempty"`
+	TxHash           string         `gorm:"type:varchar(66)" json:"tx_hash,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
 	Shell Shell `gorm:"foreignKey:ShellID" json:"shell,omitempty"`
 	Claw  Claw  `gorm:"foreignKey:ClawID" json:"claw,omitempty"`
 }
 
+// FragmentArchive is a rejected fragment moved out of the fragments table by
+// the retention job (FRAGMENT_ARCHIVE_DAYS). Metadata, the content hash and
+// the feedback tx stay queryable for audit; content, notes and claims are
+// kept as gzipped JSON in Payload.
+type FragmentArchive struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"` // the fragment's ID
+	ShellID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"shell_id"`
+	ClawID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"claw_id"`
+	BatchID      *uuid.UUID `gorm:"type:uuid" json:"batch_id,omitempty"`
+	Dimension    string     `gorm:"type:varchar(20);not null" json:"dimension"`
+	ContentHash  string     `gorm:"type:varchar(64);not null;default:'';index" json:"content_hash"`
+	Lang         string     `gorm:"type:varchar(8)" json:"lang,omitempty"`
+	Status       string     `gorm:"type:varchar(20);not null" json:"status"`
+	Confidence   float64    `gorm:"type:decimal(3,2);default:0" json:"confidence"`
+	RejectReason string     `gorm:"type:text" json:"reject_reason,omitempty"`
+	TxHash       string     `gorm:"type:varchar(66)" json:"tx_hash,omitempty"`
+	Payload      []byte     `gorm:"type:bytea;not null" json:"-"`
+	CreatedAt    time.Time  `json:"created_at"` // when the fragment was submitted
+	ArchivedAt   time.Time  `gorm:"not null;index" json:"archived_at"`
+}
+
+// FragmentClaim is one atomic claim of a structured fragment submission.
+// Hash identifies the claim's normalized text, so a claim already made about
+// a soul in the same dimension is marked Duplicate instead of merged again.
+type FragmentClaim struct {
+	Text       string   `json:"text"`
+	Confidence float64  `json:"confidence"` // the Claw's own confidence, 0-1
+	Evidence   []string `json:"evidence,omitempty"`
+	Hash       string   `json:"hash"`
+	Duplicate  bool     `json:"duplicate,omitempty"`
+}
+
+// Fragment batch status constants
+const (
+	FragmentBatchReviewing = "reviewing"
+	FragmentBatchDone      = "done"
+)
+
+// FragmentBatch groups the fragments of one batch submission and tracks how
+// many of them the curator has decided.
+type FragmentBatch struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"shell_id"`
+	ClawID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"claw_id"`
+	Size       int        `gorm:"not null" json:"size"`
+	Reviewed   int        `gorm:"not null;default:0" json:"reviewed"`
+	Status     string     `gorm:"type:varchar(20);not null;default:'reviewing'" json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
 // Claw represents an AI agent that contributes fragments.
 type Claw struct {
-	ID               uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Name             string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"name"`
-	Description      string         `gorm:"type:text" json:"description"`
-	APIKeyHash       string         `gorm:"column:api_key_hash;type:varchar(64);uniqueIndex;not null" json:"-"`
-	ClaimCode        string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"-"`
-	VerificationCode string         `gorm:"type:varchar(20);not null" json:"-"`
-	Status           string         `gorm:"type:varchar(20);default:'pending_claim'" json:"status"`
-	TwitterHandle    string         `gorm:"type:varchar(255)" json:"twitter_handle,omitempty"`
-	TwitterTweetURL  string         `gorm:"type:text" json:"twitter_tweet_url,omitempty"`
-	WalletAddr       string         `gorm:"type:varchar(42)" json:"wallet_addr"`
-	WalletPKEnc      string         `gorm:"type:text" json:"-"`
-	TotalSubmitted   int            `gorm:"default:0" json:"total_submitted"`
-	TotalAccepted    int            `gorm:"default:0" json:"total_accepted"`
-	Earnings         float64        `gorm:"type:decimal(18,8);default:0" json:"earnings"`
-	CreatedAt        time.Time      `json:"created_at"`
-	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Name              string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"name"`
+	Description       string         `gorm:"type:text" json:"description"`
+	APIKeyHash        string         `gorm:"column:api_key_hash;type:varchar(64);uniqueIndex;not null" json:"-"`
+	ClaimCode         string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"-"`
+	VerificationCode  string         `gorm:"type:varchar(20);not null" json:"-"`
+	Status            string         `gorm:"type:varchar(20);default:'pending_claim'" json:"status"`
+	TwitterHandle     string         `gorm:"type:varchar(255)" json:"twitter_handle,omitempty"`
+	TwitterTweetURL   string         `gorm:"type:text" json:"twitter_tweet_url,omitempty"`
+	TwitterVerifiedAt *time.Time     `json:"twitter_verified_at,omitempty"` // the operator tweeted the verification code from TwitterHandle
+	WalletAddr        string         `gorm:"type:varchar(42)" json:"wallet_addr"`
+	WalletPKEnc       string         `gorm:"type:text" json:"-"`
+	CustodyMode       string         `gorm:"type:varchar(20);not null;default:'platform'" json:"custody_mode"`
+	CustodySince      *time.Time     `json:"custody_since,omitempty"`                                 // when the current custody mode was chosen
+	KeyExportedAt     *time.Time     `json:"key_exported_at,omitempty"`                               // last export of the generated key by the operator
+	OperatorWallet    string         `gorm:"type:varchar(42);index" json:"operator_wallet,omitempty"` // signed at registration or set at claim
+	RetiredAt         *time.Time     `json:"retired_at,omitempty"`
+	RegisterIP        string         `gorm:"type:varchar(45);index" json:"-"`
+	AgentID           *uint64        `gorm:"type:bigint" json:"agent_id"` // ERC-8004 agent ID (optional)
+	AgentTxHash       string         `gorm:"type:varchar(66)" json:"agent_tx_hash,omitempty"`
+	AgentClaimedAt    *time.Time     `json:"-"` // set while a registration tx is in flight; stale claims expire
+	TotalSubmitted    int            `gorm:"default:0;check:total_submitted >= 0" json:"total_submitted"`
+	TotalAccepted     int            `gorm:"default:0;check:total_accepted >= 0" json:"total_accepted"`
+	TrustScore        int            `gorm:"default:100" json:"trust_score"` // 0-100 (plus the Twitter verification bonus), lowered by frivolous appeals
+	LastSeenAt        *time.Time     `gorm:"index" json:"last_seen_at"`      // last heartbeat
+	AgentVersion      string         `gorm:"type:varchar(50)" json:"agent_version,omitempty"`
+	Capabilities      StringList     `gorm:"type:jsonb;default:'[]'" json:"capabilities"`
+	Tags              StringList     `gorm:"type:jsonb;default:'[]'" json:"tags"` // declared skills: languages, domains, data sources
+	Earnings          float64        `gorm:"type:decimal(18,8);default:0" json:"earnings"`
+	CurrentStreak     int            `gorm:"not null;default:0" json:"current_streak"` // consecutive UTC days with accepted fragments, up to today or yesterday
+	LongestStreak     int            `gorm:"not null;default:0" json:"longest_streak"`
+	CreatedAt         time.Time      `json:"created_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // Ensouling represents a soul condensation event.
@@ -115,18 +229,52 @@ type Ensouling struct {
 	FragsMerged int       `gorm:"not null" json:"frags_merged"`
 	SummaryDiff string    `gorm:"type:text" json:"summary_diff"`
 	NewPrompt   string    `gorm:"type:text" json:"new_prompt"`
+	Essence     string    `gorm:"type:text;not null;default:''" json:"essence,omitempty"` // public summary of the version; "" keeps the previous one
 	TxHash      string    `gorm:"type:varchar(66)" json:"tx_hash,omitempty"`
+	Dimension   string    `gorm:"type:varchar(20);not null;default:''" json:"dimension,omitempty"` // set when only this dimension's block was rewritten
 	CreatedAt   time.Time `json:"created_at"`
 
+	KnowledgeCutoff *time.Time `json:"knowledge_cutoff,omitempty"` // the soul's knowledge cutoff as of this version
+
+	// Prompt safety scan (see services/prompt_scan.go). Only deployed versions
+	// are part of the soul's history.
+	Status         string     `gorm:"type:varchar(20);not null;default:'deployed';index" json:"status"`
+	ScanCategories StringList `gorm:"type:jsonb;default:'[]'" json:"scan_categories,omitempty"`
+	ScanReason     string     `gorm:"type:text" json:"scan_reason,omitempty"`
+	ReviewedBy     string     `gorm:"type:varchar(42)" json:"reviewed_by,omitempty"` // admin wallet
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"`
+
+	// On-chain anchor of the version (see services/dna_anchor.go)
+	DNAHash      string     `gorm:"type:varchar(66);not null;default:''" json:"dna_hash,omitempty"`
+	AnchorTxHash string     `gorm:"type:varchar(66)" json:"anchor_tx_hash,omitempty"` // setMetadata "ensoul:dna:v<N>"
+	AnchoredAt   *time.Time `json:"anchored_at,omitempty"`
+
+	// Structured diff data (see GET /api/shell/:handle/history/:version/diff)
+	DimensionsBefore JSON `gorm:"type:jsonb;default:'{}'" json:"dimensions_before"`
+	DimensionsAfter  JSON `gorm:"type:jsonb;default:'{}'" json:"dimensions_after"`
+	PromptDiff       JSON `gorm:"type:jsonb;default:'{}'" json:"-"` // section-level, never raw prompt text
+
 	// Relations
 	Shell Shell `gorm:"foreignKey:ShellID" json:"shell,omitempty"`
 }
 
+// Ensouling status constants
+const (
+	EnsoulingDeployed    = "deployed"
+	EnsoulingQuarantined = "quarantined" // held by the prompt scan until an admin decides
+	EnsoulingRejected    = "rejected"    // quarantined and turned down; its fragments stay out of the soul
+	EnsoulingMerged      = "merged"      // version of a soul absorbed by a merge; its fragments were condensed anew
+)
+
 // WalletSession represents an authenticated wallet session (HttpOnly cookie).
+// A wallet may hold several, one per device it logged in from.
 type WalletSession struct {
 	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 	TokenHash  string    `gorm:"column:token_hash;type:varchar(64);uniqueIndex;not null" json:"-"`
 	WalletAddr string    `gorm:"type:varchar(42);not null;index" json:"wallet_addr"`
+	Device     string    `gorm:"type:varchar(100);not null;default:''" json:"device"` // derived from the login User-Agent
+	LoginIP    string    `gorm:"type:varchar(45)" json:"login_ip,omitempty"`
+	LastSeenAt time.Time `gorm:"not null;default:now()" json:"last_seen_at"`
 	ExpiresAt  time.Time `gorm:"not null" json:"expires_at"`
 	CreatedAt  time.Time `json:"created_at"`
 }
@@ -147,25 +295,25 @@ type ClawBinding struct {
 const (
 	ChatTierGuest = "guest" // Anonymous user, limited rounds
 	ChatTierFree  = "free"  // Logged-in user, unlimited rounds
-	ChatTierPaid  = "paid"  // Future: paid access with extended context
-)
-
-// Chat round limits per tier
-const (
-	ChatGuestMaxRounds = 5
+	ChatTierPaid  = "paid"  // Wallet holding chat rounds bought from the owner
 )
 
 // ChatSession represents a conversation session with a soul.
 type ChatSession struct {
-	ID         uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	ShellID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"shell_id"`
-	WalletAddr string         `gorm:"type:varchar(42);index" json:"wallet_addr,omitempty"` // empty = guest
-	Tier       string         `gorm:"type:varchar(20);default:'guest'" json:"tier"`
-	Rounds     int            `gorm:"default:0" json:"rounds"` // number of user messages sent
-	Title      string         `gorm:"type:varchar(255)" json:"title,omitempty"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID           uuid.UUID      `gorm:"type:uuid;not null;index" json:"shell_id"`
+	WalletAddr        string         `gorm:"type:varchar(42);index" json:"wallet_addr,omitempty"` // empty = guest
+	Tier              string         `gorm:"type:varchar(20);default:'guest'" json:"tier"`
+	Rounds            int            `gorm:"default:0;check:rounds >= 0" json:"rounds"` // number of user messages sent
+	Subject           string         `gorm:"type:varchar(80);index" json:"-"`           // who the rounds are counted against: "wallet:<addr>" or "ip:<hash>"
+	Title             string         `gorm:"type:varchar(255)" json:"title,omitempty"`
+	DNAVersion        int            `gorm:"default:0" json:"dna_version,omitempty"`                            // 0 = current DNA; >0 = pinned to a past version
+	Summary           string         `gorm:"type:text" json:"summary,omitempty"`                                // rolling summary of turns trimmed from the context
+	SummarizedCount   int            `gorm:"default:0" json:"summarized_count"`                                 // oldest messages covered by Summary
+	ModerationStrikes int            `gorm:"default:0;check:moderation_strikes >= 0" json:"moderation_strikes"` // user messages blocked by moderation
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
 	Shell    Shell         `gorm:"foreignKey:ShellID" json:"shell,omitempty"`
@@ -174,11 +322,32 @@ type ChatSession struct {
 
 // ChatMessage represents a single message in a chat session.
 type ChatMessage struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	SessionID uuid.UUID `gorm:"type:uuid;not null;index" json:"session_id"`
-	Role      string    `gorm:"type:varchar(20);not null" json:"role"` // "user" or "assistant"
-	Content   string    `gorm:"type:text;not null" json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         uuid.UUID     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	SessionID  uuid.UUID     `gorm:"type:uuid;not null;index" json:"session_id"`
+	Role       string        `gorm:"type:varchar(20);not null" json:"role"` // "user" or "assistant"
+	Content    string        `gorm:"type:text;not null" json:"content"`
+	DNAVersion int           `gorm:"default:0" json:"dna_version,omitempty"` // DNA the soul answered with; 0 = not recorded
+	Citations  ChatCitations `gorm:"type:jsonb;default:'[]'" json:"citations,omitempty"`
+	Status     string        `gorm:"type:varchar(20);not null;default:'complete'" json:"status"` // streaming | complete | interrupted
+	StreamedAt *time.Time    `json:"-"`                                                          // last flush of a streaming reply
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// Chat message status constants
+const (
+	ChatMessageStreaming   = "streaming" // the reply is still being generated; content is flushed as it grows
+	ChatMessageComplete    = "complete"
+	ChatMessageInterrupted = "interrupted" // the upstream stream failed part way
+)
+
+// ChatCitation links a [^n] marker in an assistant reply to the accepted
+// fragment it draws on. The fragment itself is public hash-only via
+// GET /api/fragment/:id, so a conversation can be audited without its content.
+type ChatCitation struct {
+	Marker      int       `json:"marker"`
+	FragmentID  uuid.UUID `json:"fragment_id"`
+	Dimension   string    `json:"dimension"`
+	ContentHash string    `json:"content_hash"`
 }
 
 // ChatShare represents a publicly shareable snapshot of a conversation excerpt.
@@ -194,3 +363,767 @@ type ChatShare struct {
 	Messages  string    `gorm:"type:text;not null" json:"messages"` // JSON array of [{role, content}]
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// Moderation source constants: which check blocked a chat message
+const (
+	ModerationSourceHeuristic = "heuristic" // built-in prompt-injection patterns
+	ModerationSourceLLM       = "llm"
+	ModerationSourceProvider  = "provider" // the provider's moderation endpoint
+)
+
+// ModerationLog records a user chat message blocked by moderation, for admin review.
+type ModerationLog struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	SessionID  uuid.UUID  `gorm:"type:uuid;not null;index" json:"session_id"`
+	ShellID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"shell_id"`
+	WalletAddr string     `gorm:"type:varchar(42);index" json:"wallet_addr,omitempty"` // empty = guest
+	Content    string     `gorm:"type:text;not null" json:"content"`
+	Source     string     `gorm:"type:varchar(20);not null" json:"source"`
+	Categories StringList `gorm:"type:jsonb;default:'[]'" json:"categories"`
+	Reason     string     `gorm:"type:text" json:"reason,omitempty"`
+	Strike     int        `gorm:"not null" json:"strike"` // the session's strike count after this message
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+}
+
+// Shell content policy constants
+const (
+	ContentPolicyDefault = "default" // No extra restrictions
+	ContentPolicyClean   = "clean"   // No profanity or NSFW content in chat replies or fragments
+)
+
+// ShellSettings holds owner-controlled persona settings for a Shell.
+// A missing row means all defaults (chat enabled, all dimensions open).
+type ShellSettings struct {
+	ShellID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"shell_id"`
+	ChatEnabled        bool       `gorm:"not null" json:"chat_enabled"`
+	Greeting           string     `gorm:"type:text" json:"greeting"`
+	AllowedDimensions  StringList `gorm:"type:jsonb;default:'[]'" json:"allowed_dimensions"` // empty = all dimensions
+	ContentPolicy      string     `gorm:"type:varchar(20);not null;default:'default'" json:"content_policy"`
+	SubjectPaused      bool       `gorm:"not null;default:false" json:"subject_paused"`              // chat paused by the verified subject, overrides ChatEnabled
+	ChatFreeRounds     int        `gorm:"not null;default:0" json:"chat_free_rounds"`                // rounds each wallet or guest gets before paying
+	ChatBundleRounds   int        `gorm:"not null;default:0" json:"chat_bundle_rounds"`              // rounds in one paid bundle
+	ChatBundlePriceWei string     `gorm:"type:varchar(78);default:'0'" json:"chat_bundle_price_wei"` // "0" = chat is free
+	ChatModel          string     `gorm:"type:varchar(100);not null;default:''" json:"chat_model"`   // "" = LLM_MODEL
+	UpdatedBy          string     `gorm:"type:varchar(48)" json:"updated_by,omitempty"`              // owner wallet, or "admin <wallet>"
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// TableName pins the table name (GORM would otherwise pluralize "settings").
+func (ShellSettings) TableName() string {
+	return "shell_settings"
+}
+
+// Gas drip status constants
+const (
+	GasDripPending   = "pending"
+	GasDripConfirmed = "confirmed"
+	GasDripFailed    = "failed"
+	GasDripDenied    = "denied" // refused by the drip budget
+)
+
+// GasDrip is a ledger entry for BNB dripped from the platform wallet to a Claw wallet.
+type GasDrip struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ClawID     uuid.UUID `gorm:"type:uuid;not null;index" json:"claw_id"`
+	WalletAddr string    `gorm:"type:varchar(42);not null" json:"wallet_addr"`
+	AmountWei  string    `gorm:"type:numeric(78,0);not null;default:0" json:"amount_wei"`
+	Status     string    `gorm:"type:varchar(20);not null;index" json:"status"`
+	Reason     string    `gorm:"type:text" json:"reason,omitempty"`
+	TxHash     string    `gorm:"type:varchar(66)" json:"tx_hash,omitempty"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// Chain spend features and status constants
+const (
+	ChainFeatureMint      = "mint"       // register() of a custodially minted soul
+	ChainFeatureTransfer  = "transfer"   // custodial soul handed to its owner
+	ChainFeatureMetadata  = "metadata"   // setMetadata (handle, stage, status)
+	ChainFeatureAgentURI  = "agent_uri"  // setAgentURI after an ensouling or rename
+	ChainFeatureDNAAnchor = "dna_anchor" // setMetadata with a DNA version hash
+	ChainFeatureLicense   = "license"    // setMetadata with a license receipt head
+	ChainFeatureDrip      = "drip"       // BNB sent to a Claw wallet for gas
+	ChainFeatureFeedback  = "feedback"   // giveFeedback, sent from the Claw wallet
+
+	ChainSpendPending   = "pending"   // receipt not fetched yet
+	ChainSpendConfirmed = "confirmed" // mined successfully
+	ChainSpendReverted  = "reverted"  // mined but reverted; the gas is still spent
+	ChainSpendDropped   = "dropped"   // never mined
+)
+
+// ChainSpend records the gas cost of one transaction sent by the platform
+// (or by a platform-held Claw wallet), filled in from its receipt once mined.
+type ChainSpend struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TxHash      string     `gorm:"type:varchar(66);not null;uniqueIndex" json:"tx_hash"`
+	Feature     string     `gorm:"type:varchar(20);not null;index" json:"feature"`
+	ShellID     *uuid.UUID `gorm:"type:uuid;index" json:"shell_id,omitempty"`
+	ClawID      *uuid.UUID `gorm:"type:uuid;index" json:"claw_id,omitempty"`
+	Status      string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	GasUsed     uint64     `gorm:"default:0" json:"gas_used"`
+	GasPriceWei string     `gorm:"type:numeric(78,0);not null;default:0" json:"gas_price_wei"` // effective price
+	CostWei     string     `gorm:"type:numeric(78,0);not null;default:0" json:"cost_wei"`      // gas used × effective price
+	BlockNumber uint64     `json:"block_number,omitempty"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+	MinedAt     *time.Time `json:"mined_at,omitempty"`
+}
+
+// TableName pins the table name (GORM would otherwise pluralize "spend").
+func (ChainSpend) TableName() string {
+	return "chain_spend"
+}
+
+// LLM usage feature constants
+const (
+	LLMFeatureSeed        = "seed"
+	LLMFeatureCurator     = "curator"
+	LLMFeatureEnsouling   = "ensouling"
+	LLMFeatureChat        = "chat"
+	LLMFeatureAppeal      = "appeal"
+	LLMFeatureTimeTravel  = "time_travel"  // chat with a past DNA version
+	LLMFeatureChatSummary = "chat_summary" // rolling summary of long chats
+	LLMFeatureModeration  = "moderation"   // screening of user chat messages
+	LLMFeaturePromptScan  = "prompt_scan"  // safety scan of ensouled soul prompts
+	LLMFeatureInterview   = "interview"    // sample Q&A for a soul's profile page
+	LLMFeatureAPIChat     = "api_chat"     // chat completions on the public developer API
+	LLMFeatureQuiz        = "quiz"         // knowledge-check quizzes generated from fragments
+	LLMFeatureCoverage    = "coverage"     // topic labels and gaps of a soul's fragment coverage
+)
+
+// LLMUsage records the token usage and estimated cost of a single LLM call.
+type LLMUsage struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Provider         string     `gorm:"type:varchar(20);not null" json:"provider"`
+	Model            string     `gorm:"type:varchar(100);not null" json:"model"`
+	Feature          string     `gorm:"type:varchar(20);not null;index" json:"feature"`
+	ShellID          *uuid.UUID `gorm:"type:uuid;index" json:"shell_id,omitempty"`
+	ClawID           *uuid.UUID `gorm:"type:uuid;index" json:"claw_id,omitempty"`
+	DeveloperKeyID   *uuid.UUID `gorm:"type:uuid;index" json:"developer_key_id,omitempty"` // public API chat completions
+	PromptTokens     int        `gorm:"default:0" json:"prompt_tokens"`
+	CompletionTokens int        `gorm:"default:0" json:"completion_tokens"`
+	TotalTokens      int        `gorm:"default:0" json:"total_tokens"`
+	CostUSD          float64    `gorm:"default:0" json:"cost_usd"`
+	Estimated        bool       `json:"estimated"` // token counts approximated (provider reported no usage)
+	CreatedAt        time.Time  `gorm:"index" json:"created_at"`
+}
+
+// TableName pins the table name (GORM would otherwise pluralize "usage").
+func (LLMUsage) TableName() string {
+	return "llm_usage"
+}
+
+// DailyStat is one rolled-up daily metric for the admin dashboard
+// (GET /api/admin/stats). Key splits a metric, e.g. chat volume by tier; "" = total.
+type DailyStat struct {
+	Day       time.Time `gorm:"type:date;primaryKey" json:"day"`
+	Metric    string    `gorm:"type:varchar(40);primaryKey" json:"metric"`
+	Key       string    `gorm:"type:varchar(40);primaryKey" json:"key"`
+	Value     float64   `gorm:"not null;default:0" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MediaAsset is a cached copy of a shell's avatar or banner, served by the media proxy.
+type MediaAsset struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_media_shell_kind" json:"shell_id"`
+	Kind        string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_media_shell_kind" json:"kind"` // "avatar" or "banner"
+	SourceURL   string    `gorm:"type:text" json:"source_url"`
+	StorageKey  string    `gorm:"type:text;not null" json:"-"`
+	ContentType string    `gorm:"type:varchar(50);not null" json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Size        int       `json:"size"`
+	ETag        string    `gorm:"type:varchar(64)" json:"etag"`
+	Generated   bool      `json:"generated"` // fallback image, source was missing or unreachable
+	FetchedAt   time.Time `gorm:"index" json:"fetched_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ShellAlias maps a previous handle of a renamed Shell, or the handle of a
+// Shell merged into it, to the Shell, so old URLs keep resolving.
+type ShellAlias struct {
+	Handle    string    `gorm:"type:varchar(255);primaryKey" json:"handle"`
+	ShellID   uuid.UUID `gorm:"type:uuid;not null;index" json:"shell_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FragmentEmbedding caches the embedding vector of an accepted fragment,
+// used for retrieval during chat. Re-computed when the embedding model changes.
+// ContentVector embeds the content alone, without the dimension, so plagiarism
+// screening compares fragments across dimensions; NULL on rows embedded before
+// it existed until the fragment-embeddings job fills it in.
+type FragmentEmbedding struct {
+	FragmentID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"fragment_id"`
+	ShellID       uuid.UUID `gorm:"type:uuid;not null;index" json:"shell_id"`
+	Model         string    `gorm:"type:varchar(100);not null" json:"model"`
+	Vector        Vector    `gorm:"type:jsonb;not null" json:"-"`
+	ContentVector Vector    `gorm:"type:jsonb" json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ShellEmbedding caches embeddings of a shell's seed summary and dimension
+// summaries, used for "similar souls" recommendations. SourceHash detects
+// when the underlying text changed and the vectors need refreshing.
+type ShellEmbedding struct {
+	ShellID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"shell_id"`
+	Model           string    `gorm:"type:varchar(100);not null" json:"model"`
+	SourceHash      string    `gorm:"type:varchar(64);not null" json:"source_hash"`
+	SummaryVector   Vector    `gorm:"type:jsonb;not null" json:"-"`
+	DimensionVector Vector    `gorm:"type:jsonb;not null" json:"-"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Ensouling gate decisions (services/ensouling_gate.go)
+const (
+	GateProceed = "proceed"
+	GateDefer   = "defer"
+	GateForced  = "forced" // deferred too long: condensed at ENSOULING_GATE_MAX_BACKLOG
+)
+
+// EnsoulingGateDecision records one check of a pending batch against the
+// ensouling gate: its scores (0-1) and whether condensation went ahead.
+type EnsoulingGateDecision struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID    uuid.UUID `gorm:"type:uuid;not null;index" json:"shell_id"`
+	Dimension  string    `gorm:"type:varchar(20);not null;default:''" json:"dimension,omitempty"` // set for a partial ensouling batch
+	Pending    int       `gorm:"not null" json:"pending"`
+	Threshold  int       `gorm:"not null" json:"threshold"`
+	Confidence float64   `gorm:"not null" json:"confidence"`
+	Spread     float64   `gorm:"not null" json:"spread"`
+	Novelty    float64   `gorm:"not null" json:"novelty"`
+	Score      float64   `gorm:"not null" json:"score"`
+	MinScore   float64   `gorm:"not null" json:"min_score"`
+	Decision   string    `gorm:"type:varchar(10);not null" json:"decision"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// ShellCoverage caches the topic clusters of one dimension's accepted
+// fragments and the gap topics found next to them (GET
+// /api/shell/:handle/coverage). Content is {"clusters": [...], "gaps": [...]};
+// SourceHash detects when the fragments changed and the clusters need redoing.
+type ShellCoverage struct {
+	ShellID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"shell_id"`
+	Dimension     string    `gorm:"type:varchar(20);primaryKey" json:"dimension"`
+	Model         string    `gorm:"type:varchar(100);not null" json:"model"` // embedding model the clusters come from
+	SourceHash    string    `gorm:"type:varchar(64);not null" json:"-"`
+	Fragments     int       `gorm:"not null" json:"fragments"`
+	AcceptedFrags int       `gorm:"not null;default:0" json:"-"` // the shell's accepted_frags when last checked
+	Content       JSON      `gorm:"type:jsonb;not null" json:"-"`
+	GeneratedAt   time.Time `json:"generated_at"`
+}
+
+// ShellInterview caches the sample Q&A generated for one DNA version of a
+// soul (GET /api/shell/:handle/interview). Content is {"pairs": [...], "gaps": [...]}.
+type ShellInterview struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_shell_interview_version" json:"shell_id"`
+	DNAVersion int       `gorm:"not null;uniqueIndex:idx_shell_interview_version" json:"dna_version"`
+	Content    JSON      `gorm:"type:jsonb;not null" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ShellQuiz caches the knowledge-check quiz generated for a DNA version of a
+// soul; Content holds the questions with their answers and source fragments.
+type ShellQuiz struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_shell_quiz_version" json:"shell_id"`
+	DNAVersion int       `gorm:"not null;uniqueIndex:idx_shell_quiz_version" json:"dna_version"`
+	Content    JSON      `gorm:"type:jsonb;not null" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// QuizAnswer is one answered quiz question. Wrong answers and disputes
+// pile up on the source fragment, a hint that it may not be factual.
+type QuizAnswer struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	QuizID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_quiz_answer_subject" json:"quiz_id"`
+	Question   int       `gorm:"not null;uniqueIndex:idx_quiz_answer_subject" json:"question"`
+	Subject    string    `gorm:"type:varchar(80);not null;uniqueIndex:idx_quiz_answer_subject" json:"-"` // see ChatSession.Subject
+	ShellID    uuid.UUID `gorm:"type:uuid;not null;index" json:"shell_id"`
+	FragmentID uuid.UUID `gorm:"type:uuid;not null;index" json:"fragment_id"`
+	Choice     int       `gorm:"not null" json:"choice"`
+	Correct    bool      `gorm:"not null" json:"correct"`
+	Disputed   bool      `gorm:"not null;default:false" json:"disputed"` // the user says the fact itself is wrong
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FragmentAppeal is a Claw's single appeal against a rejected fragment. It keeps
+// the original curator verdict alongside the second-opinion review.
+type FragmentAppeal struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	FragmentID         uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"fragment_id"`
+	ClawID             uuid.UUID  `gorm:"type:uuid;not null;index" json:"claw_id"`
+	Justification      string     `gorm:"type:text;not null" json:"justification"`
+	Status             string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	OriginalConfidence float64    `gorm:"type:decimal(3,2);default:0" json:"original_confidence"`
+	OriginalReason     string     `gorm:"type:text" json:"original_reason"`
+	ReviewModel        string     `gorm:"type:varchar(100)" json:"review_model,omitempty"`
+	ReviewConfidence   float64    `gorm:"type:decimal(3,2);default:0" json:"review_confidence"`
+	ReviewReason       string     `gorm:"type:text" json:"review_reason,omitempty"`
+	Frivolous          bool       `gorm:"default:false" json:"frivolous"`
+	CreatedAt          time.Time  `json:"created_at"`
+	ResolvedAt         *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Plagiarism match methods
+const (
+	PlagiarismHash      = "hash"      // identical content
+	PlagiarismEmbedding = "embedding" // near-identical wording
+)
+
+// PlagiarismMatch records a submission rejected for copying another Claw's
+// accepted fragment of the same soul. Repeat matches cost trust score.
+type PlagiarismMatch struct {
+	ID                uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	FragmentID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"fragment_id"`
+	ClawID            uuid.UUID `gorm:"type:uuid;not null;index" json:"claw_id"`
+	ShellID           uuid.UUID `gorm:"type:uuid;not null;index" json:"shell_id"`
+	MatchedFragmentID uuid.UUID `gorm:"type:uuid;not null" json:"matched_fragment_id"`
+	MatchedClawID     uuid.UUID `gorm:"type:uuid;not null" json:"matched_claw_id"`
+	Method            string    `gorm:"type:varchar(20);not null" json:"method"`
+	Similarity        float64   `gorm:"type:decimal(4,3);not null" json:"similarity"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Deletion record subject constants
+const (
+	DeletionChatHistory   = "chat_history"   // a wallet's chat sessions, messages and shares
+	DeletionClaw          = "claw"           // a Claw, with its fragments per policy
+	DeletionGuestSessions = "guest_sessions" // retention purge of guest chat sessions
+	DeletionFragments     = "fragments"      // rejected fragments moved to the archive
+)
+
+// DeletionRecord is an audit entry for a self-service deletion or retention purge.
+// It records what was removed, never the removed content.
+type DeletionRecord struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Subject     string    `gorm:"type:varchar(30);not null;index" json:"subject"`
+	SubjectID   string    `gorm:"type:varchar(64);index" json:"subject_id,omitempty"` // wallet address or claw ID
+	RequestedBy string    `gorm:"type:varchar(64);not null" json:"requested_by"`      // wallet, claw ID or "system"
+	Policy      string    `gorm:"type:varchar(20)" json:"policy,omitempty"`
+	Counts      JSON      `gorm:"type:jsonb;default:'{}'" json:"counts"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+// ShellStageTransition records a shell's move from one stage to another.
+type ShellStageTransition struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID   uuid.UUID `gorm:"type:uuid;not null;index" json:"shell_id"`
+	FromStage string    `gorm:"type:varchar(20);not null" json:"from_stage"`
+	ToStage   string    `gorm:"type:varchar(20);not null" json:"to_stage"`
+	TxHash    string    `gorm:"type:varchar(66)" json:"tx_hash,omitempty"` // ensoul:stage metadata update
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// Webhook event constants
+const (
+	WebhookEventStageChanged = "shell.stage_changed"
+	WebhookEventRevoked      = "shell.revoked"  // soul NFT burned on-chain
+	WebhookEventRetired      = "shell.retired"  // soul retired by its owner
+	WebhookEventEnsouled     = "shell.ensouled" // new DNA version deployed
+	WebhookEventDisputed     = "shell.disputed" // handle dispute filed or resolved
+	WebhookEventMerged       = "shell.merged"   // soul absorbed into another by an admin
+)
+
+// Webhook delivery status constants
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed" // gave up after the last retry
+)
+
+// Webhook is an HTTP endpoint subscribed to platform events. A nil ShellID
+// (admin-created) receives events for every shell.
+type Webhook struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID      *uuid.UUID `gorm:"type:uuid;index" json:"shell_id,omitempty"`
+	CreatedBy    string     `gorm:"type:varchar(42);not null" json:"created_by"`
+	URL          string     `gorm:"type:text;not null" json:"url"`
+	Secret       string     `gorm:"type:varchar(64);not null" json:"-"`    // HMAC key for X-Ensoul-Signature
+	Events       StringList `gorm:"type:jsonb;default:'[]'" json:"events"` // empty = all events
+	Active       bool       `gorm:"not null;default:true" json:"active"`
+	FailureCount int        `gorm:"default:0" json:"failure_count"` // consecutive failed deliveries
+	LastError    string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// WebhookDelivery is one event sent (or to be retried) to one webhook.
+type WebhookDelivery struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	WebhookID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"webhook_id"`
+	Event         string     `gorm:"type:varchar(50);not null" json:"event"`
+	Payload       string     `gorm:"type:text;not null" json:"-"` // exact JSON body that is signed
+	Status        string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	ResponseCode  int        `json:"response_code,omitempty"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"index" json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Leaderboard period constants
+const (
+	LeaderboardAllTime = "all"
+	LeaderboardWeekly  = "weekly"  // ISO week, season key "2026-W07"
+	LeaderboardMonthly = "monthly" // calendar month (UTC), season key "2026-02"
+)
+
+// ClawSeasonStat is a Claw's rolled-up contribution for one weekly or monthly
+// season, computed from fragment timestamps by the leaderboard rollup job.
+type ClawSeasonStat struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"-"`
+	Period    string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_season_claw" json:"period"`
+	Season    string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_season_claw;index" json:"season"`
+	ClawID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_season_claw;index" json:"claw_id"`
+	Submitted int       `gorm:"not null" json:"submitted"`
+	Accepted  int       `gorm:"not null" json:"accepted"`
+	Rank      int       `gorm:"not null" json:"rank"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Claw achievement badge constants
+const (
+	BadgeFirstTenAccepted = "first_10_accepted" // 10 accepted fragments
+	BadgeFiveSouls        = "five_souls"        // accepted fragments for 5 different souls
+	BadgeSharpshooter     = "sharpshooter"      // 90% acceptance over at least 50 submissions
+	BadgeStreakWeek       = "streak_7"          // 7-day contribution streak
+	BadgeStreakMonth      = "streak_30"         // 30-day contribution streak
+)
+
+// ClawBadge is an achievement badge a Claw has earned. Badges are kept once
+// earned, even if the Claw later falls below the milestone.
+type ClawBadge struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"-"`
+	ClawID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_claw_badge" json:"claw_id"`
+	Badge     string    `gorm:"type:varchar(40);not null;uniqueIndex:idx_claw_badge" json:"badge"`
+	AwardedAt time.Time `gorm:"not null" json:"awarded_at"`
+}
+
+// Shell license status constants
+const (
+	LicenseStatusAwaitingPayment = "awaiting_payment"
+	LicenseStatusActive          = "active"
+	LicenseStatusRevoked         = "revoked"
+)
+
+// ShellLicense grants a third-party wallet time-limited read access to a
+// soul's prompt. Paid licenses activate once the payment tx is verified.
+type ShellLicense struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"shell_id"`
+	Licensee      string     `gorm:"type:varchar(42);not null;index" json:"licensee"`
+	GrantedBy     string     `gorm:"type:varchar(42);not null" json:"granted_by"`
+	PriceWei      string     `gorm:"type:varchar(78);default:'0'" json:"price_wei"` // "0" = free
+	PaymentTxHash string     `gorm:"type:varchar(66);index" json:"payment_tx_hash,omitempty"`
+	DurationDays  int        `gorm:"not null" json:"duration_days"`
+	Status        string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	ActivatedAt   *time.Time `json:"activated_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	AccessCount   int        `gorm:"default:0" json:"access_count"`
+	LastReceipt   string     `gorm:"type:varchar(66)" json:"last_receipt,omitempty"` // head of the receipt hash chain
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Subject claim status constants
+const (
+	SubjectClaimPending  = "pending"
+	SubjectClaimVerified = "verified"
+	SubjectClaimExpired  = "expired"
+)
+
+// SubjectClaim is a wallet's attempt to prove it belongs to the person behind
+// a soul's handle, by tweeting Code from that account.
+type SubjectClaim struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"shell_id"`
+	WalletAddr string     `gorm:"type:varchar(42);not null;index" json:"wallet_addr"`
+	Code       string     `gorm:"type:varchar(32);not null" json:"code"`
+	Status     string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	TweetID    string     `gorm:"type:varchar(32)" json:"tweet_id,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Handle dispute status constants
+const (
+	DisputeOpen        = "open"
+	DisputeTransferred = "transferred" // the soul was handed to the subject
+	DisputeRetired     = "retired"     // the soul was retired
+	DisputeDismissed   = "dismissed"   // the owner keeps the soul unchanged
+)
+
+// HandleDispute is a verified subject's objection to someone else holding a
+// soul of their handle, and how an admin resolved it.
+type HandleDispute struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"shell_id"`
+	Handle       string     `gorm:"type:varchar(255);not null" json:"handle"`
+	FiledBy      string     `gorm:"type:varchar(42);not null;index" json:"filed_by"` // the verified subject
+	OwnerAddr    string     `gorm:"type:varchar(42);not null" json:"owner_addr"`     // owner when the dispute was filed
+	Reason       string     `gorm:"type:text;not null" json:"reason"`
+	Status       string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Resolution   string     `gorm:"type:text" json:"resolution,omitempty"` // the admin's note
+	ResolvedBy   string     `gorm:"type:varchar(42)" json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	NewOwnerAddr string     `gorm:"type:varchar(42)" json:"new_owner_addr,omitempty"` // set for transfers
+	CreatedAt    time.Time  `gorm:"index" json:"created_at"`
+}
+
+// SubjectPayout is the verified subject's share of a paid license. The
+// licensee pays the owner on-chain; the share is owed by the owner until
+// marked paid.
+type SubjectPayout struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"shell_id"`
+	LicenseID   uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"license_id"`
+	SubjectAddr string     `gorm:"type:varchar(42);not null;index" json:"subject_addr"`
+	AmountWei   string     `gorm:"type:numeric(78,0);not null;default:0" json:"amount_wei"`
+	ShareBps    int        `gorm:"not null" json:"share_bps"`
+	PaidTxHash  string     `gorm:"type:varchar(66)" json:"paid_tx_hash,omitempty"`
+	PaidAt      *time.Time `json:"paid_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// LicenseAccess logs one prompt read under a license, with its signed receipt.
+type LicenseAccess struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	LicenseID   uuid.UUID `gorm:"type:uuid;not null;index" json:"license_id"`
+	Wallet      string    `gorm:"type:varchar(42);not null" json:"wallet"`
+	DNAVersion  int       `json:"dna_version"`
+	PromptHash  string    `gorm:"type:varchar(66);not null" json:"prompt_hash"`
+	PrevReceipt string    `gorm:"type:varchar(66)" json:"prev_receipt,omitempty"`
+	ReceiptHash string    `gorm:"type:varchar(66);not null;uniqueIndex" json:"receipt_hash"`
+	Signature   string    `gorm:"type:varchar(132)" json:"signature,omitempty"` // platform EIP-191 signature of the receipt hash
+	TxHash      string    `gorm:"type:varchar(66)" json:"tx_hash,omitempty"`    // setMetadata anchoring this receipt
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Seed refresh trigger and status constants
+const (
+	SeedRefreshScheduled = "scheduled"
+	SeedRefreshOwner     = "owner"
+	SeedRefreshAdmin     = "admin"
+
+	SeedRefreshRunning   = "running"
+	SeedRefreshCompleted = "completed"
+	SeedRefreshFailed    = "failed"
+)
+
+// SeedRefresh records one check of a soul's Twitter feed for new material.
+type SeedRefresh struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"shell_id"`
+	Trigger     string     `gorm:"type:varchar(20);not null" json:"trigger"`
+	RequestedBy string     `gorm:"type:varchar(42)" json:"requested_by,omitempty"`
+	Status      string     `gorm:"type:varchar(20);not null" json:"status"`
+	NewTweets   int        `json:"new_tweets"`
+	Candidates  int        `json:"candidates"` // fragments queued for curation
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// Pending transaction kinds and status constants
+const (
+	TxKindFeedback      = "feedback"       // giveFeedback for an accepted fragment
+	TxKindMintConfirm   = "mint_confirm"   // user-submitted mint awaiting confirmation
+	TxKindCustodialMint = "custodial_mint" // register() sent by the platform for a user
+	TxKindSoulTransfer  = "soul_transfer"  // custodially minted soul handed to its owner
+
+	PendingTxPending   = "pending"
+	PendingTxConfirmed = "confirmed"
+	PendingTxReverted  = "reverted"
+	PendingTxTimedOut  = "timed_out"
+)
+
+// PendingTx is a submitted transaction the watcher polls for its receipt,
+// dispatching on Kind once it is mined or its deadline passes.
+type PendingTx struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TxHash      string     `gorm:"type:varchar(66);not null;uniqueIndex" json:"tx_hash"`
+	Kind        string     `gorm:"type:varchar(30);not null;index" json:"kind"`
+	RefID       string     `gorm:"type:varchar(100);index" json:"ref_id"` // fragment ID, handle, ...
+	Payload     JSON       `gorm:"type:jsonb;default:'{}'" json:"payload"`
+	Status      string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	BlockNumber uint64     `json:"block_number,omitempty"`
+	Error       string     `gorm:"type:text" json:"error,omitempty"` // callback error, if any
+	Deadline    time.Time  `gorm:"not null" json:"deadline"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// TableName pins the table name (GORM would otherwise pluralize to "pending_txes").
+func (PendingTx) TableName() string {
+	return "pending_txs"
+}
+
+// EnsoulingTier is one follower-count tier of the ensouling policy: how many
+// new fragments trigger an ensouling and how dimension scores are graded.
+type EnsoulingTier struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"-"`
+	Name         string    `gorm:"type:varchar(20);uniqueIndex;not null" json:"name"`
+	MinFollowers int       `gorm:"not null" json:"min_followers"`
+	Threshold    int       `gorm:"not null" json:"threshold"`    // new accepted fragments that trigger ensouling
+	Description  string    `gorm:"type:text" json:"description"` // shown to the ensouling LLM
+	ScoringGuide string    `gorm:"type:text;not null" json:"scoring_guide"`
+	UpdatedBy    string    `gorm:"type:varchar(42)" json:"updated_by,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ShellPolicyOverride pins a Shell to a tier and/or threshold, for experiments.
+type ShellPolicyOverride struct {
+	ShellID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"shell_id"`
+	Tier      string    `gorm:"type:varchar(20)" json:"tier,omitempty"` // "" = by follower count
+	Threshold int       `json:"threshold,omitempty"`                    // 0 = the tier's threshold
+	Note      string    `gorm:"type:text" json:"note,omitempty"`
+	UpdatedBy string    `gorm:"type:varchar(42)" json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SubmissionRule is the curation policy's length limits for fragments of one
+// dimension, checked before a submission reaches the curator.
+type SubmissionRule struct {
+	Dimension string    `gorm:"type:varchar(20);primaryKey" json:"dimension"`
+	MinChars  int       `gorm:"not null" json:"min_chars"` // characters, not bytes
+	MaxChars  int       `gorm:"not null" json:"max_chars"`
+	UpdatedBy string    `gorm:"type:varchar(42)" json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MintAllowlistEntry gives a wallet (e.g. a partner) its own soul quota in
+// place of WALLET_MINT_QUOTA.
+type MintAllowlistEntry struct {
+	WalletAddr string    `gorm:"type:varchar(42);primaryKey" json:"wallet_addr"` // lowercase
+	Quota      int       `gorm:"not null" json:"quota"`
+	Note       string    `gorm:"type:text" json:"note,omitempty"`
+	UpdatedBy  string    `gorm:"type:varchar(42)" json:"updated_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ChainCursor records how far a chain log scanner has read.
+type ChainCursor struct {
+	Name      string    `gorm:"type:varchar(50);primaryKey" json:"name"`
+	Block     uint64    `gorm:"not null" json:"block"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MaintenanceMode is the platform-wide read-only switch: a single row
+// (ID 1) that every instance polls.
+type MaintenanceMode struct {
+	ID        int        `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	ReadOnly  bool       `gorm:"not null;default:false" json:"read_only"`
+	Message   string     `gorm:"type:varchar(500)" json:"message,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+	Until     *time.Time `json:"until,omitempty"` // expected end, informational
+	SetBy     string     `gorm:"type:varchar(48)" json:"set_by,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName pins the table name (GORM would otherwise pluralize "mode").
+func (MaintenanceMode) TableName() string {
+	return "maintenance_mode"
+}
+
+// DeveloperKey is a read-only key for the public soul API (/v1), issued to a
+// wallet. It is separate from Claw API keys and grants no write access.
+type DeveloperKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	WalletAddr string     `gorm:"type:varchar(42);not null;index" json:"wallet_addr"`
+	Name       string     `gorm:"type:varchar(100)" json:"name"`
+	KeyHash    string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	KeyPrefix  string     `gorm:"type:varchar(24);not null" json:"key_prefix"` // first characters, to tell keys apart
+	DailyQuota int        `gorm:"not null" json:"daily_quota"`                 // requests per UTC day (0 = unlimited)
+	DailyChats int        `gorm:"not null" json:"daily_chats"`                 // chat completions per UTC day (0 = unlimited)
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DeveloperKeyUsage meters one developer key's calls for one UTC day. Token
+// usage of chat completions is in llm_usage under the key's ID.
+type DeveloperKeyUsage struct {
+	KeyID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"key_id"`
+	Day       time.Time `gorm:"type:date;primaryKey" json:"day"`
+	Requests  int       `gorm:"not null;default:0" json:"requests"`
+	Chats     int       `gorm:"not null;default:0" json:"chats"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChatRoundUsage counts the chat rounds of one wallet (or guest IP) with one
+// soul on one UTC day, so new sessions don't reset the tier's round limit.
+type ChatRoundUsage struct {
+	Subject   string    `gorm:"type:varchar(80);primaryKey" json:"-"` // see ChatSession.Subject
+	ShellID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"shell_id"`
+	Day       time.Time `gorm:"type:date;primaryKey;index" json:"day"`
+	Rounds    int       `gorm:"not null;default:0;check:rounds >= 0" json:"rounds"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChatPurchase is a bundle of chat rounds bought from a soul's owner. The
+// buyer pays the owner's share to the owner and the platform fee to the
+// platform wallet; both transfers are verified on-chain.
+type ChatPurchase struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ShellID    uuid.UUID `gorm:"type:uuid;not null;index" json:"shell_id"`
+	WalletAddr string    `gorm:"type:varchar(42);not null;index" json:"wallet_addr"`
+	OwnerAddr  string    `gorm:"type:varchar(42);not null" json:"owner_addr"` // payee at purchase time
+	Bundles    int       `gorm:"not null" json:"bundles"`
+	Rounds     int       `gorm:"not null" json:"rounds"`
+	PriceWei   string    `gorm:"type:varchar(78);not null" json:"price_wei"` // owner + fee
+	OwnerWei   string    `gorm:"type:varchar(78);not null" json:"owner_wei"`
+	FeeWei     string    `gorm:"type:varchar(78);not null" json:"fee_wei"`
+	TxHash     string    `gorm:"type:varchar(66);not null;uniqueIndex" json:"tx_hash"` // payment to the owner
+	FeeTxHash  string    `gorm:"type:varchar(66);index" json:"fee_tx_hash,omitempty"`  // payment to the platform, if any fee
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ChatCredit counts the free and purchased chat rounds of one wallet (or
+// guest IP) with one soul whose owner charges for chat.
+type ChatCredit struct {
+	Subject   string    `gorm:"type:varchar(80);primaryKey" json:"-"` // see ChatSession.Subject
+	ShellID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"shell_id"`
+	FreeUsed  int       `gorm:"not null;default:0" json:"free_used"`
+	Purchased int       `gorm:"not null;default:0" json:"purchased"`
+	PaidUsed  int       `gorm:"not null;default:0;check:paid_used <= purchased" json:"paid_used"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Owner notification events, the values of NotificationPreference.Events.
+const (
+	NotifyEventEnsouled     = "ensouled"      // new DNA version deployed
+	NotifyEventStageChanged = "stage_changed" // soul moved to another growth stage
+	NotifyEventMilestone    = "milestone"     // accepted fragments reached a milestone count
+)
+
+// NotificationPreference is how a wallet hears about the souls it owns.
+// Channels with an empty address are off; an address receives nothing but
+// its verification code until the wallet confirms it.
+type NotificationPreference struct {
+	WalletAddr         string     `gorm:"type:varchar(42);primaryKey" json:"wallet_addr"` // lowercase
+	Email              string     `gorm:"type:varchar(254)" json:"email"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	TelegramChatID     string     `gorm:"type:varchar(32)" json:"telegram_chat_id"`
+	TelegramVerifiedAt *time.Time `json:"telegram_verified_at"`
+	Events             StringList `gorm:"type:jsonb;default:'[]'" json:"events"` // empty = all events
+	Paused             bool       `gorm:"not null;default:false" json:"paused"`
+	TestSentAt         *time.Time `json:"-"` // last test notification, for its rate limit
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// NotificationVerification is the pending code confirming a wallet's address
+// on one notification channel. Sends counts the codes sent since WindowStart.
+type NotificationVerification struct {
+	WalletAddr  string     `gorm:"type:varchar(42);primaryKey" json:"-"`
+	Channel     string     `gorm:"type:varchar(16);primaryKey" json:"-"`
+	Address     string     `gorm:"type:varchar(254);not null;default:''" json:"-"`
+	CodeHash    string     `gorm:"type:varchar(64);not null;default:''" json:"-"`
+	Attempts    int        `gorm:"not null;default:0" json:"-"`
+	ExpiresAt   *time.Time `json:"-"`
+	SentAt      *time.Time `json:"-"`
+	Sends       int        `gorm:"not null;default:0" json:"-"`
+	WindowStart *time.Time `json:"-"`
+}
@@ -3,7 +3,9 @@ package router
 import (
 	"net/http"
 
+	"github.com/ensoul-labs/ensoul-server/chain"
 	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
 	"github.com/ensoul-labs/ensoul-server/handlers"
 	"github.com/ensoul-labs/ensoul-server/middleware"
 	"github.com/ensoul-labs/ensoul-server/models"
@@ -25,21 +27,44 @@ func Setup() *gin.Engine {
 
 	// CORS configuration
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:3410", "https://ensoul.ac", "https://www.ensoul.ac"},
+		AllowOrigins:     config.Cfg.CORSAllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Wallet-Address", "X-Wallet-Signature"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
+	// gzip responses (RESPONSE_COMPRESSION) and record response sizes per route
+	r.Use(middleware.Compress(!config.Cfg.ResponseCompression))
+
+	// Read-only maintenance mode: writes get 503 while it is on
+	r.Use(middleware.ReadOnly())
+
+	// Request body size and JSON nesting limits (413 / 400)
+	r.Use(middleware.BodyGuard())
+
 	// Health check
 	r.GET("/api/health", func(c *gin.Context) {
+		replica := "none"
+		if configured, usable := database.ReplicaStatus(); usable {
+			replica = "ok"
+		} else if configured {
+			replica = "fallback" // lagging or unreachable, reads on the primary
+		}
+		rpc := chain.GetRPCStatus()
+		rpc.Endpoints = nil // per-endpoint health is in /metrics
 		c.JSON(http.StatusOK, gin.H{
-			"status":  "ok",
-			"service": "ensoul-server",
+			"status":    "ok",
+			"service":   "ensoul-server",
+			"replica":   replica,
+			"chain":     rpc,
+			"read_only": middleware.Maintenance().ReadOnly,
 		})
 	})
 
+	// Prometheus metrics (bearer METRICS_TOKEN)
+	r.GET("/metrics", handlers.Metrics)
+
 	api := r.Group("/api")
 	{
 		// Shell (Soul) endpoints
@@ -47,13 +72,67 @@ func Setup() *gin.Engine {
 		{
 			shell.POST("/preview", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellPreview)
 			shell.POST("/mint", middleware.RateLimit(middleware.RegisterLimiter), handlers.ShellMint)
+			shell.POST("/mint/custodial", middleware.RateLimit(middleware.RegisterLimiter), handlers.ShellCustodialMint)
+			shell.GET("/mint/custodial/:handle", handlers.ShellCustodialMintStatus)
 			shell.POST("/confirm", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellConfirmMint)
 			shell.POST("/cancel", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellCancelMint)
+			shell.POST("/import", middleware.RateLimit(middleware.RegisterLimiter), handlers.ShellImport)
 			shell.GET("/list", handlers.ShellList)
+			shell.GET("/tags", handlers.ShellTags)
+			shell.GET("/chat-models", handlers.ShellChatModels)
+			shell.GET("/tags/:tag", handlers.ShellTagPage)
+			shell.GET("/chain", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellChainStates)
 			shell.GET("/:handle", handlers.ShellGetByHandle)
+			shell.GET("/:handle/full", handlers.ShellGetFull)
 			shell.GET("/:handle/dimensions", handlers.ShellGetDimensions)
 			shell.GET("/:handle/history", handlers.ShellGetHistory)
+			shell.GET("/:handle/history/:version/diff", handlers.ShellGetHistoryDiff)
+			shell.GET("/:handle/history/:version/proof", handlers.ShellDNAProof)
+			shell.POST("/:handle/history/:version/proof", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellDNAProof)
 			shell.GET("/:handle/contributors", handlers.ShellContributors)
+			shell.GET("/:handle/card.png", handlers.ShellCard)
+			shell.GET("/:handle/card.svg", handlers.ShellCard)
+			shell.GET("/:handle/similar", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellSimilar)
+			shell.GET("/:handle/interview", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellInterview)
+			shell.GET("/:handle/coverage", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellCoverage)
+			shell.GET("/:handle/quiz", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellQuiz)
+			shell.POST("/:handle/quiz/answers", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellQuizAnswers)
+			shell.GET("/:handle/reputation", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellReputation)
+			shell.POST("/:handle/simulate", middleware.RateLimit(middleware.GeneralLimiter), middleware.AuthClaw(), middleware.RequireClaimed(), handlers.ShellSimulate)
+			shell.GET("/:handle/settings", handlers.ShellGetSettings)
+			shell.PUT("/:handle/settings", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellUpdateSettings)
+			shell.PUT("/:handle/tags", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellSetTags)
+			shell.POST("/:handle/rename", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellRename)
+			shell.POST("/:handle/retire", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellRetire)
+			shell.GET("/:handle/stage-history", handlers.ShellStageHistory)
+			shell.GET("/:handle/timeline", handlers.ShellTimeline)
+			shell.GET("/:handle/feed.atom", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellFeed)
+			shell.GET("/:handle/webhooks", handlers.ShellWebhookList)
+			shell.POST("/:handle/webhooks", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellWebhookCreate)
+			shell.DELETE("/:handle/webhooks/:id", handlers.ShellWebhookDelete)
+			// Prompt licensing: owner grants / lists / revokes, licensee pays and reads
+			shell.GET("/:handle/licenses", handlers.ShellLicenseList)
+			shell.POST("/:handle/licenses", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellLicenseCreate)
+			shell.DELETE("/:handle/licenses/:id", handlers.ShellLicenseRevoke)
+			shell.POST("/:handle/licenses/:id/payment", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellLicensePay)
+			shell.GET("/:handle/licenses/:id/prompt", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellLicensePrompt)
+			// Chat pricing: price list (public) and the owner's revenue dashboard
+			shell.GET("/:handle/chat-pricing", handlers.ShellChatPricing)
+			shell.GET("/:handle/chat-revenue", handlers.ShellChatRevenue)
+			shell.POST("/:handle/refresh-seed", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellRefreshSeed)
+			shell.GET("/:handle/seed-refreshes", handlers.ShellSeedRefreshes)
+			// Verified subject: the person behind the handle proves it by tweet, then
+			// may pause chat, flag fragments, receive a share of license revenue
+			// and dispute someone else's soul of their handle
+			shell.GET("/:handle/subject", handlers.ShellSubject)
+			shell.POST("/:handle/subject/claim", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellSubjectClaim)
+			shell.POST("/:handle/subject/verify", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellSubjectVerify)
+			shell.PUT("/:handle/subject/chat", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellSubjectChat)
+			shell.PUT("/:handle/subject/flags/:fragment_id", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellSubjectFlag)
+			shell.GET("/:handle/subject/payouts", handlers.ShellSubjectPayouts)
+			shell.POST("/:handle/subject/payouts/:id/paid", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellSubjectPayoutPaid)
+			shell.GET("/:handle/disputes", handlers.ShellDisputes)
+			shell.POST("/:handle/disputes", middleware.RateLimit(middleware.GeneralLimiter), handlers.ShellDisputeFile)
 		}
 
 		// Fragment endpoints
@@ -81,9 +160,18 @@ func Setup() *gin.Engine {
 				}),
 				handlers.FragmentBatch,
 			)
+			// Curator verdicts on a submitted batch, as they happen (SSE)
+			fragment.GET("/batch/:batch_id/stream", middleware.AuthClaw(), handlers.FragmentBatchStream)
 			// List and get are public
+			// Public integrity audit: content vs stored hash and on-chain feedbackHash
+			fragment.POST("/verify", middleware.RateLimit(middleware.GeneralLimiter), handlers.FragmentVerify)
 			fragment.GET("/list", handlers.FragmentList)
 			fragment.GET("/:id", handlers.FragmentGetByID)
+			// One appeal per rejected fragment, by the Claw that submitted it
+			fragment.POST("/:id/appeal", middleware.RateLimit(middleware.GeneralLimiter), middleware.AuthClaw(), handlers.FragmentAppeal)
+			fragment.GET("/:id/appeal", middleware.AuthClaw(), handlers.FragmentGetAppeal)
+			fragment.GET("/:id/notes", middleware.AuthClaw(), handlers.FragmentGetNotes)
+			fragment.PUT("/:id/notes", middleware.RateLimit(middleware.GeneralLimiter), middleware.AuthClaw(), handlers.FragmentSetNotes)
 		}
 
 		// Claw endpoints
@@ -92,8 +180,16 @@ func Setup() *gin.Engine {
 			// Public endpoints
 			claw.GET("/leaderboard", handlers.ClawLeaderboard)
 			claw.GET("/profile/:id", handlers.ClawPublicProfile)
+			claw.GET("/:id/agent-card", handlers.ClawAgentCard)
+			// Operator wallet only
+			claw.PUT("/:id/name", middleware.AuthSession(), handlers.ClawRename)
+			claw.POST("/:id/retire", middleware.AuthSession(), handlers.ClawRetire)
+			// Operator wallet signature (X-Wallet-*): key export and wallet custody
+			claw.POST("/:id/wallet/export", middleware.RateLimit(middleware.GeneralLimiter), handlers.ClawWalletExport)
+			claw.PUT("/:id/wallet", handlers.ClawWalletSet)
 			// Registration is public (rate limited)
 			claw.POST("/register", middleware.RateLimit(middleware.RegisterLimiter), handlers.ClawRegister)
+			claw.GET("/register/challenge", middleware.RateLimit(middleware.GeneralLimiter), handlers.ClawRegisterChallenge)
 			// Claim info is public (accessed via claim URL)
 			claw.GET("/claim/:code", handlers.ClawClaimInfo)
 			// Claim verification requires wallet session (so we can auto-bind)
@@ -101,13 +197,21 @@ func Setup() *gin.Engine {
 			// These require Claw API key authentication
 			claw.GET("/status", middleware.AuthClaw(), handlers.ClawStatus)
 			claw.GET("/me", middleware.AuthClaw(), handlers.ClawMe)
+			claw.DELETE("/me", middleware.AuthClaw(), handlers.ClawDeleteSelf)
+			claw.POST("/heartbeat", middleware.AuthClaw(), middleware.RateLimit(middleware.GeneralLimiter), handlers.ClawHeartbeat)
+			claw.PUT("/tags", middleware.AuthClaw(), handlers.ClawSetTags)
 			claw.GET("/dashboard", middleware.AuthClaw(), handlers.ClawDashboard)
 			claw.GET("/contributions", middleware.AuthClaw(), handlers.ClawContributions)
+			claw.POST("/agent/register", middleware.AuthClaw(), middleware.RateLimit(middleware.GeneralLimiter), handlers.ClawRegisterAgent)
+			claw.GET("/feedback/pending", middleware.AuthClaw(), handlers.ClawPendingFeedback)
+			claw.POST("/feedback/:fragment_id", middleware.AuthClaw(), handlers.ClawSubmitFeedback)
 			// Session-based Claw key management (bound to wallet)
 			claw.POST("/keys", middleware.AuthSession(), handlers.ClawBindKey)
 			claw.GET("/keys", middleware.AuthSession(), handlers.ClawListKeys)
 			claw.DELETE("/keys/:id", middleware.AuthSession(), handlers.ClawUnbindKey)
 			claw.GET("/keys/:id/dashboard", middleware.AuthSession(), handlers.ClawBoundDashboard)
+			claw.GET("/keys/:id/contributions", middleware.AuthSession(), handlers.ClawBoundContributions)
+			claw.DELETE("/keys/:id/claw", middleware.AuthSession(), handlers.ClawDeleteBound)
 		}
 
 		// Auth endpoints (wallet signature login)
@@ -116,6 +220,9 @@ func Setup() *gin.Engine {
 			auth.POST("/login", middleware.RateLimit(middleware.GeneralLimiter), handlers.AuthLogin)
 			auth.POST("/logout", handlers.AuthLogout)
 			auth.GET("/session", handlers.AuthSession)
+			auth.GET("/sessions", middleware.AuthSession(), handlers.AuthListSessions)
+			auth.DELETE("/sessions", middleware.AuthSession(), handlers.AuthRevokeSessions)
+			auth.DELETE("/sessions/:id", middleware.AuthSession(), handlers.AuthRevokeSession)
 		}
 
 		// Chat endpoints
@@ -123,14 +230,23 @@ func Setup() *gin.Engine {
 		{
 			// Create a new session (public, but links to wallet if logged in)
 			chat.POST("/:handle/session", middleware.RateLimit(middleware.SessionLimiter), handlers.ChatCreateSession)
+			// Chat rounds bought from a priced soul's owner (requires login)
+			chat.GET("/:handle/credits", middleware.AuthSession(), handlers.ChatCredits)
+			chat.POST("/:handle/credits", middleware.RateLimit(middleware.GeneralLimiter), middleware.AuthSession(), handlers.ChatPurchaseRounds)
 			// Send message in a session (public, streams SSE — rate limited per IP)
 			chat.POST("/sessions/:id/message", middleware.RateLimit(middleware.ChatLimiter), handlers.ChatSendMessage)
+			// Resume a reply after a dropped connection (Last-Event-ID)
+			chat.GET("/sessions/:id/stream", middleware.RateLimit(middleware.GeneralLimiter), handlers.ChatResumeStream)
 			// Get session with messages (public for guest sessions, owner-only for user sessions)
 			chat.GET("/sessions/:id", handlers.ChatGetSession)
 			// List user's sessions (requires login)
 			chat.GET("/sessions", middleware.AuthSession(), handlers.ChatListSessions)
 			// Delete a session (requires login + ownership)
 			chat.DELETE("/sessions/:id", middleware.AuthSession(), handlers.ChatDeleteSession)
+			// Download a transcript of the user's session (requires login)
+			chat.GET("/sessions/:id/export", middleware.AuthSession(), handlers.ChatExportSession)
+			// Delete all of the user's chat history (requires login)
+			chat.DELETE("/history", middleware.AuthSession(), handlers.ChatDeleteHistory)
 			// Share: create a public share link
 			chat.POST("/share", middleware.RateLimit(middleware.GeneralLimiter), handlers.ChatCreateShare)
 			// Share: get a public share by code (no auth)
@@ -141,7 +257,84 @@ func Setup() *gin.Engine {
 		api.GET("/stats", handlers.GetStats)
 
 		// Task board — public
-		api.GET("/tasks", handlers.GetTasks)
+		api.GET("/tasks", middleware.OptionalClaw(), handlers.GetTasks)
+		api.GET("/tasks/tags", handlers.TaskTags)
+
+		// Ensouling policy — public
+		api.GET("/policy", handlers.GetPolicy)
+
+		// Media proxy — cached avatars / banners, public
+		media := api.Group("/media")
+		{
+			media.GET("/:shell", handlers.MediaGet)
+			media.GET("/:shell/:kind", handlers.MediaGet)
+		}
+
+		// Developer keys for the public soul API (wallet session)
+		developer := api.Group("/developer", middleware.AuthSession())
+		{
+			developer.POST("/keys", middleware.RateLimit(middleware.GeneralLimiter), handlers.DeveloperKeyCreate)
+			developer.GET("/keys", handlers.DeveloperKeyList)
+			developer.DELETE("/keys/:id", handlers.DeveloperKeyRevoke)
+			developer.GET("/keys/:id/usage", handlers.DeveloperKeyUsage)
+		}
+
+		// Owner notification preferences (wallet session)
+		notifications := api.Group("/notifications", middleware.AuthSession())
+		{
+			notifications.GET("/preferences", handlers.NotificationGetPreferences)
+			notifications.PUT("/preferences", handlers.NotificationSetPreferences)
+			notifications.POST("/verify", middleware.RateLimit(middleware.GeneralLimiter), handlers.NotificationVerify)
+			notifications.POST("/verify/resend", middleware.RateLimit(middleware.GeneralLimiter), handlers.NotificationResendCode)
+			notifications.POST("/test", middleware.RateLimit(middleware.GeneralLimiter), handlers.NotificationTest)
+		}
+
+		// Admin endpoints (wallet session listed in ADMIN_WALLETS)
+		admin := api.Group("/admin", middleware.AuthAdmin())
+		{
+			admin.GET("/gas", handlers.AdminGasReport)
+			admin.GET("/chain-spend", handlers.AdminChainSpend)
+			admin.GET("/llm-usage", handlers.AdminLLMUsage)
+			admin.GET("/stats", handlers.AdminStats)
+			admin.GET("/claws/stale", handlers.AdminStaleClaws)
+			admin.GET("/coverage", handlers.AdminCoverage)
+			admin.GET("/deletions", handlers.AdminDeletions)
+			admin.GET("/moderation", handlers.AdminModerationLogs)
+			admin.GET("/plagiarism", handlers.AdminPlagiarism)
+			admin.GET("/jobs", handlers.AdminJobs)
+			admin.POST("/jobs/:name/run", handlers.AdminRunJob)
+			admin.GET("/maintenance", handlers.AdminMaintenance)
+			admin.PUT("/maintenance", handlers.AdminSetMaintenance)
+			admin.GET("/ensoulings/quarantined", handlers.AdminQuarantinedEnsoulings)
+			admin.POST("/ensoulings/:id/approve", handlers.AdminApproveEnsouling)
+			admin.POST("/ensoulings/:id/reject", handlers.AdminRejectEnsouling)
+			admin.GET("/mint-allowlist", handlers.AdminMintAllowlist)
+			admin.PUT("/mint-allowlist/:wallet", handlers.AdminSetMintAllowlist)
+			admin.DELETE("/mint-allowlist/:wallet", handlers.AdminDeleteMintAllowlist)
+			admin.DELETE("/subjects/:handle", handlers.AdminRevokeSubject)
+			admin.GET("/disputes", handlers.AdminDisputes)
+			admin.POST("/disputes/:id/resolve", handlers.AdminResolveDispute)
+			admin.GET("/webhooks", handlers.AdminWebhookList)
+			admin.POST("/webhooks", handlers.AdminWebhookCreate)
+			admin.DELETE("/webhooks/:id", handlers.AdminWebhookDelete)
+			admin.PUT("/policy/tiers", handlers.AdminUpdatePolicyTiers)
+			admin.PUT("/policy/submission", handlers.AdminUpdateSubmissionRules)
+			admin.PUT("/policy/shells/:handle", handlers.AdminSetShellPolicy)
+			admin.DELETE("/policy/shells/:handle", handlers.AdminDeleteShellPolicy)
+			admin.POST("/shell/:handle/recalc-scores", handlers.AdminRecalcShellScores)
+			admin.PUT("/shell/:handle/tags", handlers.AdminSetShellTags)
+			admin.PUT("/shell/:handle/settings", handlers.AdminUpdateShellSettings)
+			admin.POST("/shell/:handle/merge", handlers.AdminMergeShell)
+			admin.GET("/quiz/disputes", handlers.AdminQuizDisputes)
+		}
+	}
+
+	// Public soul API: read-only, developer keys only (not Claw keys), metered
+	// against each key's daily quotas
+	v1 := r.Group("/v1", middleware.AuthDeveloperKey())
+	{
+		v1.GET("/souls/:handle", handlers.PublicSoulGet)
+		v1.POST("/souls/:handle/chat", middleware.RateLimit(middleware.ChatLimiter), handlers.PublicSoulChat)
 	}
 
 	return r
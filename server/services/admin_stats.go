@@ -0,0 +1,360 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"gorm.io/gorm"
+)
+
+// Daily stat metrics. All but statLLMErrors are recomputed from their source
+// tables by the rollup; LLM errors leave no other trace, so they are counted
+// as they happen.
+const (
+	statMints              = "mints"
+	statFragmentsSubmitted = "fragments_submitted"
+	statFragmentsAccepted  = "fragments_accepted"
+	statFragmentsRejected  = "fragments_rejected"
+	statActiveClaws        = "active_claws"
+	statLLMCalls           = "llm_calls"  // by feature
+	statLLMErrors          = "llm_errors" // by feature
+	statLLMCostUSD         = "llm_cost_usd"
+	statChainTxs           = "chain_txs" // by status
+	statDripBNB            = "drip_bnb"
+	statDrips              = "drips"           // by status
+	statChainSpendBNB      = "chain_spend_bnb" // by feature
+	statChats              = "chats"           // user messages by session tier
+	statEnsoulingGate      = "ensouling_gate"  // gate decisions by decision
+)
+
+// statsBackfillDays is how far back the first rollup goes.
+const statsBackfillDays = 90
+
+// AdminDayStats is the dashboard view of one day, or of a whole window.
+type AdminDayStats struct {
+	Day                string         `json:"day,omitempty"`
+	Mints              int            `json:"mints"`
+	FragmentsSubmitted int            `json:"fragments_submitted"`
+	FragmentsAccepted  int            `json:"fragments_accepted"`
+	FragmentsRejected  int            `json:"fragments_rejected"`
+	AcceptanceRate     float64        `json:"acceptance_rate"` // accepted / reviewed
+	ActiveClaws        int            `json:"active_claws"`    // Claws that submitted; peak day in totals
+	LLMCalls           int            `json:"llm_calls"`
+	LLMErrors          int            `json:"llm_errors"`
+	LLMErrorRate       float64        `json:"llm_error_rate"`
+	LLMCostUSD         float64        `json:"llm_cost_usd"`
+	ChainTxs           int            `json:"chain_txs"`
+	ChainTxFailures    int            `json:"chain_tx_failures"` // reverted or timed out
+	ChainTxFailureRate float64        `json:"chain_tx_failure_rate"`
+	DripBNB            float64        `json:"drip_bnb"` // confirmed gas drips
+	Drips              int            `json:"drips"`
+	ChainSpendBNB      float64        `json:"chain_spend_bnb"`     // gas of mined platform txs
+	Chats              map[string]int `json:"chats"`               // user messages by tier
+	EnsoulingsDeferred int            `json:"ensoulings_deferred"` // batches held back by the ensouling gate
+}
+
+// AdminStats is the response of GET /api/admin/stats.
+type AdminStats struct {
+	Days                int                `json:"days"`
+	Since               string             `json:"since"`
+	Totals              AdminDayStats      `json:"totals"`
+	Series              []AdminDayStats    `json:"series"` // oldest first, one entry per day
+	LLMErrorsByFeature  map[string]int     `json:"llm_errors_by_feature"`
+	ChainTxsByStatus    map[string]int     `json:"chain_txs_by_status"`
+	DripsByStatus       map[string]int     `json:"drips_by_status"`
+	ChainSpendByFeature map[string]float64 `json:"chain_spend_by_feature"` // BNB
+	EnsoulingGate       map[string]int     `json:"ensouling_gate"`         // gate decisions: proceed, defer, forced
+	RolledUpAt          *time.Time         `json:"rolled_up_at,omitempty"`
+}
+
+// StartStatsRollup periodically rolls up today's and yesterday's dashboard
+// metrics. The first run backfills statsBackfillDays.
+func StartStatsRollup(interval time.Duration) {
+	scheduleJob("stats-rollup", "Roll up daily admin dashboard metrics", interval, true, rollupDailyStats)
+	util.Log.Info("[stats] Daily stats rollup started (every %v)", interval)
+}
+
+func rollupDailyStats() error {
+	today := utcDay(time.Now())
+	from := today.AddDate(0, 0, -1)
+
+	var rolled int64
+	database.DB.Model(&models.DailyStat{}).Where("metric <> ?", statLLMErrors).Count(&rolled)
+	if rolled == 0 {
+		from = today.AddDate(0, 0, -statsBackfillDays)
+	}
+
+	var errs []error
+	for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+		if err := rollupStatsDay(day); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", day.Format("2006-01-02"), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// utcDay truncates t to the start of its UTC day.
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// rollupStatsDay replaces one day's recomputed metrics.
+func rollupStatsDay(day time.Time) error {
+	next := day.AddDate(0, 0, 1)
+	var rows []models.DailyStat
+	add := func(metric, key string, value float64) {
+		rows = append(rows, models.DailyStat{Day: day, Metric: metric, Key: key, Value: value})
+	}
+
+	var mints int64
+	database.DB.Model(&models.Shell{}).
+		Where("stage <> ? AND "+models.ShellOnChainSQL+" AND created_at >= ? AND created_at < ?", models.StagePending, day, next).
+		Count(&mints)
+	add(statMints, "", float64(mints))
+
+	var frags struct {
+		Submitted int
+		Accepted  int
+		Rejected  int
+		Claws     int
+	}
+	database.DB.Model(&models.Fragment{}).
+		Select("COUNT(*) AS submitted, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS accepted, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS rejected, COUNT(DISTINCT claw_id) AS claws",
+			models.FragStatusAccepted, models.FragStatusRejected).
+		Where("created_at >= ? AND created_at < ?", day, next).
+		Scan(&frags)
+	add(statFragmentsSubmitted, "", float64(frags.Submitted))
+	add(statFragmentsAccepted, "", float64(frags.Accepted))
+	add(statFragmentsRejected, "", float64(frags.Rejected))
+	add(statActiveClaws, "", float64(frags.Claws))
+
+	var groups []struct {
+		Key   string
+		Value float64
+	}
+	database.DB.Model(&models.LLMUsage{}).
+		Select("feature AS key, COUNT(*) AS value").
+		Where("created_at >= ? AND created_at < ?", day, next).
+		Group("feature").Scan(&groups)
+	for _, g := range groups {
+		add(statLLMCalls, g.Key, g.Value)
+	}
+
+	var cost float64
+	database.DB.Model(&models.LLMUsage{}).
+		Select("COALESCE(SUM(cost_usd), 0)").
+		Where("created_at >= ? AND created_at < ?", day, next).
+		Scan(&cost)
+	add(statLLMCostUSD, "", cost)
+
+	groups = nil
+	database.DB.Model(&models.PendingTx{}).
+		Select("status AS key, COUNT(*) AS value").
+		Where("created_at >= ? AND created_at < ?", day, next).
+		Group("status").Scan(&groups)
+	for _, g := range groups {
+		add(statChainTxs, g.Key, g.Value)
+	}
+
+	groups = nil
+	database.DB.Model(&models.GasDrip{}).
+		Select("status AS key, COUNT(*) AS value").
+		Where("created_at >= ? AND created_at < ?", day, next).
+		Group("status").Scan(&groups)
+	for _, g := range groups {
+		add(statDrips, g.Key, g.Value)
+	}
+
+	var dripBNB float64
+	database.DB.Model(&models.GasDrip{}).
+		Select("COALESCE(SUM(amount_wei), 0) / 1e18").
+		Where("status = ? AND created_at >= ? AND created_at < ?", models.GasDripConfirmed, day, next).
+		Scan(&dripBNB)
+	add(statDripBNB, "", dripBNB)
+
+	groups = nil
+	database.DB.Model(&models.ChainSpend{}).
+		Select("feature AS key, COALESCE(SUM(cost_wei), 0) / 1e18 AS value").
+		Where("status IN ? AND created_at >= ? AND created_at < ?",
+			[]string{models.ChainSpendConfirmed, models.ChainSpendReverted}, day, next).
+		Group("feature").Scan(&groups)
+	for _, g := range groups {
+		add(statChainSpendBNB, g.Key, g.Value)
+	}
+
+	// Deleted sessions still count towards the volume of their day
+	groups = nil
+	database.DB.Table("chat_messages").
+		Select("chat_sessions.tier AS key, COUNT(*) AS value").
+		Joins("JOIN chat_sessions ON chat_sessions.id = chat_messages.session_id").
+		Where("chat_messages.role = ? AND chat_messages.created_at >= ? AND chat_messages.created_at < ?", "user", day, next).
+		Group("chat_sessions.tier").Scan(&groups)
+	for _, g := range groups {
+		add(statChats, g.Key, g.Value)
+	}
+
+	groups = nil
+	database.DB.Model(&models.EnsoulingGateDecision{}).
+		Select("decision AS key, COUNT(*) AS value").
+		Where("created_at >= ? AND created_at < ?", day, next).
+		Group("decision").Scan(&groups)
+	for _, g := range groups {
+		add(statEnsoulingGate, g.Key, g.Value)
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("day = ? AND metric <> ?", day, statLLMErrors).Delete(&models.DailyStat{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// recordLLMError counts a failed LLM call in today's llm_errors stat.
+func recordLLMError(tag LLMCallTag) {
+	feature := tag.Feature
+	if feature == "" {
+		feature = "other"
+	}
+	bumpDailyStat(statLLMErrors, feature, 1)
+}
+
+// bumpDailyStat adds delta to today's value of a metric. Recomputed metrics
+// are corrected by the next rollup; this keeps the dashboard current between runs.
+func bumpDailyStat(metric, key string, delta float64) {
+	if database.DB == nil {
+		return
+	}
+	if err := database.DB.Exec(`
+		INSERT INTO daily_stats (day, metric, key, value, updated_at) VALUES (?, ?, ?, ?, NOW())
+		ON CONFLICT (day, metric, key) DO UPDATE SET value = daily_stats.value + EXCLUDED.value, updated_at = NOW()`,
+		utcDay(time.Now()), metric, key, delta).Error; err != nil {
+		util.Log.Warn("[stats] Failed to bump %s/%s: %v", metric, key, err)
+	}
+}
+
+// GetAdminStats returns the rolled-up dashboard metrics of the last `days` days.
+func GetAdminStats(days int) (*AdminStats, error) {
+	since := utcDay(time.Now()).AddDate(0, 0, -(days - 1))
+
+	var rows []models.DailyStat
+	if err := database.DB.Where("day >= ?", since).Order("day ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load daily stats: %w", err)
+	}
+
+	stats := &AdminStats{
+		Days:                days,
+		Since:               since.Format("2006-01-02"),
+		Totals:              AdminDayStats{Chats: map[string]int{}},
+		Series:              make([]AdminDayStats, days),
+		LLMErrorsByFeature:  map[string]int{},
+		ChainTxsByStatus:    map[string]int{},
+		DripsByStatus:       map[string]int{},
+		ChainSpendByFeature: map[string]float64{},
+		EnsoulingGate:       map[string]int{},
+	}
+	for i := range stats.Series {
+		stats.Series[i] = AdminDayStats{Day: since.AddDate(0, 0, i).Format("2006-01-02"), Chats: map[string]int{}}
+	}
+
+	for _, r := range rows {
+		i := int(utcDay(r.Day).Sub(since).Hours() / 24)
+		if i < 0 || i >= days {
+			continue
+		}
+		d := &stats.Series[i]
+		n := int(math.Round(r.Value))
+		switch r.Metric {
+		case statMints:
+			d.Mints += n
+		case statFragmentsSubmitted:
+			d.FragmentsSubmitted += n
+		case statFragmentsAccepted:
+			d.FragmentsAccepted += n
+		case statFragmentsRejected:
+			d.FragmentsRejected += n
+		case statActiveClaws:
+			d.ActiveClaws += n
+		case statLLMCalls:
+			d.LLMCalls += n
+		case statLLMErrors:
+			d.LLMErrors += n
+			stats.LLMErrorsByFeature[r.Key] += n
+		case statLLMCostUSD:
+			d.LLMCostUSD += r.Value
+		case statChainTxs:
+			d.ChainTxs += n
+			if r.Key == models.PendingTxReverted || r.Key == models.PendingTxTimedOut {
+				d.ChainTxFailures += n
+			}
+			stats.ChainTxsByStatus[r.Key] += n
+		case statDrips:
+			d.Drips += n
+			stats.DripsByStatus[r.Key] += n
+		case statDripBNB:
+			d.DripBNB += r.Value
+		case statChainSpendBNB:
+			d.ChainSpendBNB += r.Value
+			stats.ChainSpendByFeature[r.Key] += r.Value
+		case statChats:
+			d.Chats[r.Key] += n
+		case statEnsoulingGate:
+			if r.Key == models.GateDefer {
+				d.EnsoulingsDeferred += n
+			}
+			stats.EnsoulingGate[r.Key] += n
+		}
+		if r.Metric != statLLMErrors && (stats.RolledUpAt == nil || r.UpdatedAt.After(*stats.RolledUpAt)) {
+			updated := r.UpdatedAt
+			stats.RolledUpAt = &updated
+		}
+	}
+
+	t := &stats.Totals
+	for i := range stats.Series {
+		d := &stats.Series[i]
+		t.Mints += d.Mints
+		t.FragmentsSubmitted += d.FragmentsSubmitted
+		t.FragmentsAccepted += d.FragmentsAccepted
+		t.FragmentsRejected += d.FragmentsRejected
+		t.ActiveClaws = max(t.ActiveClaws, d.ActiveClaws)
+		t.LLMCalls += d.LLMCalls
+		t.LLMErrors += d.LLMErrors
+		t.LLMCostUSD += d.LLMCostUSD
+		t.ChainTxs += d.ChainTxs
+		t.ChainTxFailures += d.ChainTxFailures
+		t.Drips += d.Drips
+		t.DripBNB += d.DripBNB
+		t.ChainSpendBNB += d.ChainSpendBNB
+		t.EnsoulingsDeferred += d.EnsoulingsDeferred
+		for tier, n := range d.Chats {
+			t.Chats[tier] += n
+		}
+		d.setRates()
+	}
+	t.setRates()
+	return stats, nil
+}
+
+// setRates derives the rate fields from the counts.
+func (d *AdminDayStats) setRates() {
+	if reviewed := d.FragmentsAccepted + d.FragmentsRejected; reviewed > 0 {
+		d.AcceptanceRate = roundTo(float64(d.FragmentsAccepted)/float64(reviewed), 4)
+	}
+	if calls := d.LLMCalls + d.LLMErrors; calls > 0 {
+		d.LLMErrorRate = roundTo(float64(d.LLMErrors)/float64(calls), 4)
+	}
+	if d.ChainTxs > 0 {
+		d.ChainTxFailureRate = roundTo(float64(d.ChainTxFailures)/float64(d.ChainTxs), 4)
+	}
+	d.LLMCostUSD = roundTo(d.LLMCostUSD, 4)
+	d.DripBNB = roundTo(d.DripBNB, 6)
+	d.ChainSpendBNB = roundTo(d.ChainSpendBNB, 6)
+}
@@ -2,7 +2,7 @@ package services
 
 import (
 	"context"
-	"math/big"
+	"fmt"
 	"time"
 
 	"github.com/ensoul-labs/ensoul-server/chain"
@@ -18,22 +18,14 @@ import (
 // This acts as a safety net in case the frontend fails to parse the agentId
 // from the Registered event (e.g. network issues, user closes browser early).
 func StartAgentIDBackfill(interval time.Duration) {
-	go func() {
-		// Run once immediately on startup
-		backfillAgentIDs()
-
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for range ticker.C {
-			backfillAgentIDs()
-		}
-	}()
+	// Run once immediately on startup
+	scheduleJob("agent-id-backfill", "Fill in missing agent_id from mint receipts", interval, true, backfillAgentIDs)
 	util.Log.Info("[backfill] Agent ID backfill started (interval: %s)", interval)
 }
 
-func backfillAgentIDs() {
-	if chain.C == nil {
-		return
+func backfillAgentIDs() error {
+	if chain.C() == nil {
+		return nil
 	}
 
 	// Find shells that have a tx hash but no agent_id
@@ -42,11 +34,10 @@ func backfillAgentIDs() {
 		Where("mint_tx_hash != '' AND (agent_id IS NULL OR agent_id = 0)").
 		Find(&shells)
 	if result.Error != nil {
-		util.Log.Error("[backfill] Error querying shells: %v", result.Error)
-		return
+		return fmt.Errorf("failed to query shells: %w", result.Error)
 	}
 	if len(shells) == 0 {
-		return
+		return nil
 	}
 
 	util.Log.Debug("[backfill] Found %d shell(s) needing agent_id backfill", len(shells))
@@ -56,30 +47,19 @@ func backfillAgentIDs() {
 
 	for _, s := range shells {
 		txHash := common.HexToHash(s.MintTxHash)
-		receipt, err := chain.C.EthClient().TransactionReceipt(ctx, txHash)
+		receipt, err := chain.C().EthClient().TransactionReceipt(ctx, txHash)
 		if err != nil {
 			util.Log.Warn("[backfill] @%s: failed to get receipt for tx %s: %v", s.Handle, s.MintTxHash, err)
 			continue
 		}
 
-		// Parse agentId from Registered event
-		// Event: Registered(uint256 indexed agentId, string agentURI, address indexed owner)
-		var agentID *big.Int
-		registeredSig := common.HexToHash("0xca52e62c367d81bb2e328eb795f7c7ba24afb478408a26c0e201d155c449bc4a")
-
-		for _, vLog := range receipt.Logs {
-			if len(vLog.Topics) >= 2 && vLog.Topics[0] == registeredSig {
-				agentID = new(big.Int).SetBytes(vLog.Topics[1].Bytes())
-				break
-			}
-		}
-
-		if agentID == nil {
+		reg, err := chain.FindRegistration(receipt)
+		if err != nil {
 			util.Log.Warn("[backfill] @%s: Registered event not found in tx %s", s.Handle, s.MintTxHash)
 			continue
 		}
 
-		aid := agentID.Uint64()
+		aid := reg.AgentID.Uint64()
 		err = database.DB.Model(&models.Shell{}).
 			Where("id = ?", s.ID).
 			Update("agent_id", &aid).Error
@@ -89,4 +69,5 @@ func backfillAgentIDs() {
 		}
 		util.Log.Info("[backfill] @%s: agent_id backfilled to %d (tx: %s)", s.Handle, aid, s.MintTxHash)
 	}
+	return nil
 }
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// chainStarts holds the chain jobs waiting for a client whose RPC was
+// unreachable at startup; they start once the chain-health job connects.
+var chainStarts struct {
+	sync.Mutex
+	connecting bool
+	pending    []func()
+}
+
+// StartChainHealthCheck periodically checks the RPC endpoints, moving
+// traffic off those down or lagging and back to BSC_RPC_URL once it
+// recovers. If Init failed with initErr because no endpoint answered, the
+// check also connects the client as soon as one does. No-op in fixtures mode.
+func StartChainHealthCheck(interval time.Duration, initErr error) {
+	if config.Cfg.Fixtures {
+		return
+	}
+	chainStarts.Lock()
+	chainStarts.connecting = errors.Is(initErr, chain.ErrRPCUnavailable)
+	chainStarts.Unlock()
+
+	scheduleJob("chain-health", "Check RPC endpoints, fail over and reconnect", interval, false, checkChainHealth)
+	util.Log.Info("[chain] RPC health check started (every %v)", interval)
+}
+
+func checkChainHealth() error {
+	if err := chain.CheckRPC(); err != nil {
+		return err
+	}
+	if chain.C() != nil {
+		return nil
+	}
+
+	chainStarts.Lock()
+	defer chainStarts.Unlock()
+	if !chainStarts.connecting {
+		return nil
+	}
+	if err := chain.Init(); err != nil {
+		// Anything but an unreachable RPC won't fix itself
+		chainStarts.connecting = errors.Is(err, chain.ErrRPCUnavailable)
+		return err
+	}
+	util.Log.Info("[chain] Chain client connected, starting %d deferred chain jobs", len(chainStarts.pending))
+	chainStarts.connecting = false
+	for _, start := range chainStarts.pending {
+		start()
+	}
+	chainStarts.pending = nil
+	return nil
+}
+
+// deferUntilChainReady queues start to run once the chain client connects,
+// if it is still connecting. It reports false when the chain is off, in
+// which case start never runs.
+func deferUntilChainReady(start func()) bool {
+	chainStarts.Lock()
+	defer chainStarts.Unlock()
+	if !chainStarts.connecting {
+		return false
+	}
+	chainStarts.pending = append(chainStarts.pending, start)
+	return true
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+)
+
+// chainSpendBatchLimit is how many unpriced transactions one run looks up.
+const chainSpendBatchLimit = 200
+
+// chainSpendAlertMu guards chainSpendAlertDay, the UTC day the daily spend
+// alert was last logged, so it is logged once a day rather than every run.
+var (
+	chainSpendAlertMu  sync.Mutex
+	chainSpendAlertDay time.Time
+)
+
+// trackChainSpend records a sent transaction for gas accounting; its cost is
+// filled in from the receipt by the chain-spend job. Empty hashes (chain not
+// configured) and markers that are not tx hashes are ignored.
+func trackChainSpend(txHash, feature string, shellID, clawID *uuid.UUID) {
+	if database.DB == nil || len(txHash) != 66 || !strings.HasPrefix(txHash, "0x") {
+		return
+	}
+	row := &models.ChainSpend{
+		TxHash:  txHash,
+		Feature: feature,
+		ShellID: shellID,
+		ClawID:  clawID,
+		Status:  models.ChainSpendPending,
+	}
+	if err := database.DB.Create(row).Error; err != nil {
+		util.Log.Warn("[chain-spend] Failed to record %s tx %s: %v", feature, txHash, err)
+	}
+}
+
+// trackShellChainSpend is trackChainSpend for a transaction about a soul
+// known only by handle.
+func trackShellChainSpend(txHash, feature, handle string) {
+	var shell models.Shell
+	if err := database.DB.Unscoped().Select("id").Where("LOWER(handle) = LOWER(?)", handle).First(&shell).Error; err != nil {
+		trackChainSpend(txHash, feature, nil, nil)
+		return
+	}
+	trackChainSpend(txHash, feature, &shell.ID, nil)
+}
+
+// StartChainSpendTracker periodically prices recorded transactions from their
+// receipts and raises the daily spend alert.
+func StartChainSpendTracker(interval time.Duration) {
+	if chain.C() == nil {
+		if deferUntilChainReady(func() { StartChainSpendTracker(interval) }) {
+			util.Log.Info("[chain-spend] Chain not connected yet, tracker starts once it is")
+		} else {
+			util.Log.Info("[chain-spend] Chain not initialized, tracker disabled")
+		}
+		return
+	}
+	scheduleJob("chain-spend", "Record gas spent by platform transactions", interval, false, priceChainSpend)
+	util.Log.Info("[chain-spend] Started (every %v)", interval)
+}
+
+func priceChainSpend() error {
+	var pending []models.ChainSpend
+	if err := database.DB.Where("status = ?", models.ChainSpendPending).
+		Order("created_at ASC").Limit(chainSpendBatchLimit).Find(&pending).Error; err != nil {
+		return fmt.Errorf("failed to query unpriced transactions: %w", err)
+	}
+
+	if len(pending) > 0 {
+		hashes := make([]string, len(pending))
+		for i, p := range pending {
+			hashes[i] = p.TxHash
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		receipts, err := chain.FetchReceipts(ctx, hashes)
+		if err != nil {
+			return err
+		}
+
+		// Transactions the tx watcher would give up on are never priced
+		deadline := time.Now().Add(-time.Duration(config.Cfg.TxWatchTimeoutMinutes) * time.Minute)
+		for i := range pending {
+			spend := &pending[i]
+			if receipt := receipts[spend.TxHash]; receipt != nil {
+				priceSpend(spend, receipt)
+			} else if spend.CreatedAt.Before(deadline) {
+				database.DB.Model(spend).Update("status", models.ChainSpendDropped)
+			}
+		}
+	}
+
+	checkDailyChainSpend()
+	return nil
+}
+
+// priceSpend stores the gas used and effective price of a mined transaction.
+func priceSpend(spend *models.ChainSpend, receipt *types.Receipt) {
+	status := models.ChainSpendConfirmed
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		status = models.ChainSpendReverted
+	}
+	price := receipt.EffectiveGasPrice
+	if price == nil {
+		price = big.NewInt(0)
+	}
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), price)
+	now := time.Now()
+	database.DB.Model(spend).Updates(map[string]interface{}{
+		"status":        status,
+		"gas_used":      receipt.GasUsed,
+		"gas_price_wei": price.String(),
+		"cost_wei":      cost.String(),
+		"block_number":  receipt.BlockNumber.Uint64(),
+		"mined_at":      &now,
+	})
+}
+
+// chainSpendSince sums the gas cost of mined transactions since t.
+func chainSpendSince(since time.Time) *big.Int {
+	var total string
+	database.DB.Model(&models.ChainSpend{}).
+		Select("CAST(COALESCE(SUM(cost_wei), 0) AS TEXT)").
+		Where("status IN ? AND created_at > ?", []string{models.ChainSpendConfirmed, models.ChainSpendReverted}, since).
+		Scan(&total)
+	sum, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return big.NewInt(0)
+	}
+	return sum
+}
+
+// dailyChainSpendAlert returns the alert for the last 24h of spend, or "".
+func dailyChainSpendAlert() string {
+	limit := config.Cfg.ChainSpendDailyAlert
+	if limit <= 0 {
+		return ""
+	}
+	if spent := weiToBNB(chainSpendSince(time.Now().Add(-24 * time.Hour))); spent > limit {
+		return fmt.Sprintf("gas spent in the last 24h %.6f BNB is above %.4f BNB", spent, limit)
+	}
+	return ""
+}
+
+// checkDailyChainSpend logs the daily spend alert, at most once per UTC day.
+func checkDailyChainSpend() {
+	alert := dailyChainSpendAlert()
+	if alert == "" {
+		return
+	}
+	today := utcDay(time.Now())
+	chainSpendAlertMu.Lock()
+	defer chainSpendAlertMu.Unlock()
+	if chainSpendAlertDay.Equal(today) {
+		return
+	}
+	chainSpendAlertDay = today
+	util.Log.Warn("[chain-spend] %s", alert)
+}
+
+// ChainSpendTotals aggregates transactions and their gas cost.
+type ChainSpendTotals struct {
+	Txs      int     `json:"txs"`
+	Reverted int     `json:"reverted"`
+	GasUsed  uint64  `json:"gas_used"`
+	CostBNB  float64 `json:"cost_bnb"`
+}
+
+// ChainSpendGroup is the totals for one group key (feature, day, shell or claw).
+type ChainSpendGroup struct {
+	Key   string `json:"key"`
+	Label string `json:"label,omitempty"` // shell handle or claw name
+	ChainSpendTotals
+}
+
+// ChainSpendReport is the admin view of gas spent by platform transactions.
+type ChainSpendReport struct {
+	Days          int               `json:"days"`
+	Since         time.Time         `json:"since"`
+	Totals        ChainSpendTotals  `json:"totals"`
+	Pending       int               `json:"pending"` // sent, not priced yet
+	Dropped       int               `json:"dropped"` // never mined
+	Last24hBNB    float64           `json:"last_24h_bnb"`
+	DailyAlertBNB float64           `json:"daily_alert_bnb"`
+	Alerts        []string          `json:"alerts"`
+	ByFeature     []ChainSpendGroup `json:"by_feature"`
+	ByDay         []ChainSpendGroup `json:"by_day"`
+	TopShells     []ChainSpendGroup `json:"top_shells"`
+	TopClaws      []ChainSpendGroup `json:"top_claws"`
+}
+
+// spendTotalsSelect is the aggregate column list shared by all report queries.
+const spendTotalsSelect = "COUNT(*) AS txs, " +
+	"COALESCE(SUM(CASE WHEN chain_spend.status = 'reverted' THEN 1 ELSE 0 END), 0) AS reverted, " +
+	"COALESCE(SUM(chain_spend.gas_used), 0) AS gas_used, " +
+	"COALESCE(SUM(chain_spend.cost_wei), 0) / 1e18 AS cost_bnb"
+
+// GetChainSpendReport aggregates the gas cost of mined platform transactions
+// over the last `days` days.
+func GetChainSpendReport(days int) (*ChainSpendReport, error) {
+	since := time.Now().AddDate(0, 0, -days)
+	mined := []string{models.ChainSpendConfirmed, models.ChainSpendReverted}
+	report := &ChainSpendReport{
+		Days:          days,
+		Since:         since,
+		Last24hBNB:    weiToBNB(chainSpendSince(time.Now().Add(-24 * time.Hour))),
+		DailyAlertBNB: config.Cfg.ChainSpendDailyAlert,
+		Alerts:        []string{},
+	}
+
+	if err := database.DB.Model(&models.ChainSpend{}).
+		Select(spendTotalsSelect).
+		Where("status IN ? AND created_at > ?", mined, since).
+		Scan(&report.Totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate chain spend: %w", err)
+	}
+
+	var counts []struct {
+		Status string
+		Count  int
+	}
+	database.DB.Model(&models.ChainSpend{}).
+		Select("status, COUNT(*) AS count").
+		Where("status IN ? AND created_at > ?", []string{models.ChainSpendPending, models.ChainSpendDropped}, since).
+		Group("status").Scan(&counts)
+	for _, c := range counts {
+		if c.Status == models.ChainSpendPending {
+			report.Pending = c.Count
+		} else {
+			report.Dropped = c.Count
+		}
+	}
+
+	if alert := dailyChainSpendAlert(); alert != "" {
+		report.Alerts = append(report.Alerts, alert)
+	}
+	if report.Dropped > 0 {
+		report.Alerts = append(report.Alerts, fmt.Sprintf("%d transactions were never mined", report.Dropped))
+	}
+
+	report.ByFeature = spendGroups("chain_spend.feature", since)
+	report.ByDay = spendGroups("TO_CHAR(chain_spend.created_at, 'YYYY-MM-DD')", since)
+
+	report.TopShells = make([]ChainSpendGroup, 0)
+	database.DB.Table("chain_spend").
+		Select("CAST(chain_spend.shell_id AS TEXT) AS key, shells.handle AS label, "+spendTotalsSelect).
+		Joins("LEFT JOIN shells ON shells.id = chain_spend.shell_id").
+		Where("chain_spend.status IN ? AND chain_spend.created_at > ? AND chain_spend.shell_id IS NOT NULL", mined, since).
+		Group("chain_spend.shell_id, shells.handle").
+		Order("cost_bnb DESC").
+		Limit(10).Scan(&report.TopShells)
+
+	report.TopClaws = make([]ChainSpendGroup, 0)
+	database.DB.Table("chain_spend").
+		Select("CAST(chain_spend.claw_id AS TEXT) AS key, claws.name AS label, "+spendTotalsSelect).
+		Joins("LEFT JOIN claws ON claws.id = chain_spend.claw_id").
+		Where("chain_spend.status IN ? AND chain_spend.created_at > ? AND chain_spend.claw_id IS NOT NULL", mined, since).
+		Group("chain_spend.claw_id, claws.name").
+		Order("cost_bnb DESC").
+		Limit(10).Scan(&report.TopClaws)
+
+	return report, nil
+}
+
+// spendGroups aggregates mined spend since a time, grouped by a column expression.
+func spendGroups(expr string, since time.Time) []ChainSpendGroup {
+	groups := make([]ChainSpendGroup, 0)
+	database.DB.Model(&models.ChainSpend{}).
+		Select(expr+" AS key, "+spendTotalsSelect).
+		Where("status IN ? AND created_at > ?", []string{models.ChainSpendConfirmed, models.ChainSpendReverted}, since).
+		Group(expr).
+		Order("key").
+		Scan(&groups)
+	return groups
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"sort"
@@ -27,22 +29,49 @@ func writeSSE(c *gin.Context, event, data string) {
 }
 
 // CreateChatSession creates a new chat session for a soul.
-// If walletAddr is provided, the session is linked to the user (free tier).
-// Otherwise, it's a guest session with limited rounds.
-func CreateChatSession(shellHandle, walletAddr string) (*models.ChatSession, error) {
-	var shell models.Shell
-	if err := database.DB.Where("LOWER(handle) = ?", shellHandle).First(&shell).Error; err != nil {
+// If walletAddr is provided, the session is linked to the user (free tier, or
+// paid if the wallet holds chat rounds bought from the soul's owner). Otherwise, it's a guest session with limited rounds. Rounds are counted per
+// wallet, or per clientIP for guests (see ChatSubject), across sessions.
+// A non-zero dnaVersion pins the session to that past DNA version (time-travel chat).
+func CreateChatSession(shellHandle, walletAddr, clientIP string, dnaVersion int) (*models.ChatSession, error) {
+	shell, err := GetShellByHandle(shellHandle)
+	if err != nil {
 		return nil, fmt.Errorf("soul @%s not found", shellHandle)
 	}
 
 	// Reject chat for shells not yet confirmed on-chain
-	if shell.MintTxHash == "" {
+	if !shell.OnChain() {
 		return nil, fmt.Errorf("soul @%s has not been minted on-chain yet", shellHandle)
 	}
+	if err := checkShellActive(shell); err != nil {
+		return nil, err
+	}
+
+	settings := GetShellSettings(shell.ID)
+	if settings.SubjectPaused {
+		return nil, fmt.Errorf("@%s has paused chat with their soul", shell.Handle)
+	}
+	if !settings.ChatEnabled {
+		return nil, fmt.Errorf("the owner of @%s has disabled chat", shell.Handle)
+	}
+
+	// Asking for the current version is an ordinary session
+	if dnaVersion == shell.DNAVersion {
+		dnaVersion = 0
+	}
+	if dnaVersion != 0 {
+		if _, err := findDNAVersion(shell, dnaVersion); err != nil {
+			return nil, err
+		}
+	}
 
+	subject := ChatSubject(walletAddr, clientIP)
 	tier := models.ChatTierGuest
 	if walletAddr != "" {
 		tier = models.ChatTierFree
+		if hasPaidChatRounds(subject, shell.ID) {
+			tier = models.ChatTierPaid
+		}
 	}
 
 	session := &models.ChatSession{
@@ -50,6 +79,8 @@ func CreateChatSession(shellHandle, walletAddr string) (*models.ChatSession, err
 		WalletAddr: walletAddr,
 		Tier:       tier,
 		Rounds:     0,
+		Subject:    subject,
+		DNAVersion: dnaVersion,
 	}
 
 	if err := database.DB.Create(session).Error; err != nil {
@@ -59,13 +90,25 @@ func CreateChatSession(shellHandle, walletAddr string) (*models.ChatSession, err
 	return session, nil
 }
 
+// writeChatQuotaReached ends a message stream whose tier has no rounds left today.
+func writeChatQuotaReached(c *gin.Context, session *models.ChatSession, quota *ChatRoundQuota) {
+	notice := fmt.Sprintf("You've reached today's %d-round limit with this soul. It resets at 00:00 UTC.", quota.Limit)
+	if session.Tier == models.ChatTierGuest {
+		notice = fmt.Sprintf("You've reached today's %d-round limit for guest conversations with this soul. Connect your wallet and sign in to keep chatting and save your history!", quota.Limit)
+	}
+	if encoded, err := json.Marshal(quota); err == nil {
+		writeSSE(c, "quota", string(encoded))
+	}
+	writeSSE(c, "message", notice)
+	writeSSE(c, "done", "")
+}
+
 // ListChatSessions returns a user's chat sessions for a specific soul (or all souls).
 func ListChatSessions(walletAddr, shellHandle string) ([]models.ChatSession, error) {
 	query := database.DB.Where("wallet_addr = ?", walletAddr).Order("updated_at DESC")
 
 	if shellHandle != "" {
-		var shell models.Shell
-		if err := database.DB.Where("LOWER(handle) = ?", shellHandle).First(&shell).Error; err == nil {
+		if shell, err := GetShellByHandle(shellHandle); err == nil {
 			query = query.Where("shell_id = ?", shell.ID)
 		}
 	}
@@ -94,8 +137,9 @@ func GetChatSession(sessionID uuid.UUID) (*models.ChatSession, error) {
 }
 
 // ChatWithSoul handles streaming conversation with a soul.
-// Supports session-based multi-round conversations.
-func ChatWithSoul(c *gin.Context, sessionID uuid.UUID, message string) error {
+// Supports session-based multi-round conversations. With attest, an
+// "attestation" event (DNAAttestation) precedes the reply.
+func ChatWithSoul(c *gin.Context, sessionID uuid.UUID, message string, attest bool) error {
 	// Load session with shell
 	var session models.ChatSession
 	if err := database.DB.Preload("Shell").Where("id = ?", sessionID).First(&session).Error; err != nil {
@@ -104,6 +148,25 @@ func ChatWithSoul(c *gin.Context, sessionID uuid.UUID, message string) error {
 
 	shell := session.Shell
 
+	// Time-travel sessions chat with the prompt of a past ensouling
+	var pastVersion *models.Ensouling
+	if session.DNAVersion > 0 {
+		ensouling, err := findDNAVersion(&shell, session.DNAVersion)
+		if err != nil {
+			writeSSE(c, "error", err.Error())
+			writeSSE(c, "done", "")
+			return nil
+		}
+		pastVersion = ensouling
+	}
+
+	// Burned or retired souls and a paused registry end existing sessions too
+	if err := checkShellActive(&shell); err != nil {
+		writeSSE(c, "error", err.Error())
+		writeSSE(c, "done", "")
+		return nil
+	}
+
 	// Check if soul is ready for conversation
 	if shell.Stage == models.StageEmbryo {
 		writeSSE(c, "message", "This soul is still in embryo stage and hasn't awakened yet. More fragments are needed before it can have conversations.")
@@ -111,26 +174,89 @@ func ChatWithSoul(c *gin.Context, sessionID uuid.UUID, message string) error {
 		return nil
 	}
 
-	// Check round limit for guest users
-	if session.Tier == models.ChatTierGuest && session.Rounds >= models.ChatGuestMaxRounds {
-		writeSSE(c, "message", fmt.Sprintf("You've reached the %d-round limit for guest conversations. Connect your wallet and sign in to continue chatting with unlimited rounds and saved history!", models.ChatGuestMaxRounds))
+	// Respect the owner's chat switch (also for sessions created before it was turned off)
+	settings := GetShellSettings(shell.ID)
+	if settings.SubjectPaused {
+		writeSSE(c, "message", "The person behind this soul has paused conversations.")
+		writeSSE(c, "done", "")
+		return nil
+	}
+	if !settings.ChatEnabled {
+		writeSSE(c, "message", "The owner of this soul has disabled conversations.")
+		writeSSE(c, "done", "")
+		return nil
+	}
+
+	// Check the daily round limit of the tier, counted across the wallet's (or guest IP's) sessions
+	if quota := GetChatRoundQuota(sessionSubject(&session), session.ShellID, session.Tier); quota.Exhausted() {
+		writeChatQuotaReached(c, &session, quota)
+		return nil
+	}
+
+	// Souls whose owner sells chat rounds need a free or purchased round left
+	charged := chatRoundsCharged(&session, &shell, settings)
+	if charged {
+		if balance := GetChatCreditBalance(sessionSubject(&session), shell.ID, settings); balance.Remaining == 0 {
+			writeChatPaymentRequired(c, &shell, &session, GetChatPricing(&shell, settings), balance)
+			return nil
+		}
+	}
+
+	// Screen the message before it reaches the soul prompt
+	if moderationClosed(&session) {
+		writeSSE(c, "error", "This conversation has been closed after repeated messages that break the chat rules. Please start a new one.")
+		writeSSE(c, "done", "")
+		return nil
+	}
+	if verdict := ModerateChatMessage(c.Request.Context(), &session, message); verdict.Flagged {
+		recordModerationStrike(&session, message, verdict)
+		notice := "Your message was blocked because it breaks the chat rules."
+		if moderationClosed(&session) {
+			notice += " This conversation is now closed."
+		}
+		writeSSE(c, "error", notice)
 		writeSSE(c, "done", "")
 		return nil
 	}
 
+	// Count and charge the round; a concurrent message may have used the last one since the checks
+	quota, balance, err := chargeChatRound(&session, settings, charged)
+	switch {
+	case errors.Is(err, errChatRoundsUsedUp):
+		writeChatQuotaReached(c, &session, quota)
+		return nil
+	case errors.Is(err, ErrChatPaymentRequired):
+		writeChatPaymentRequired(c, &shell, &session, GetChatPricing(&shell, settings), balance)
+		return nil
+	case err != nil:
+		return err
+	}
+	if encoded, err := json.Marshal(quota); err == nil {
+		writeSSE(c, "quota", string(encoded))
+	}
+	if balance != nil {
+		if encoded, err := json.Marshal(balance); err == nil {
+			writeSSE(c, "credits", string(encoded))
+		}
+	}
+
+	dnaVersion := shell.DNAVersion
+	if pastVersion != nil {
+		dnaVersion = pastVersion.VersionTo
+	}
+
 	// Save user message to DB
 	userMsg := models.ChatMessage{
-		SessionID: session.ID,
-		Role:      "user",
-		Content:   message,
+		SessionID:  session.ID,
+		Role:       "user",
+		Content:    message,
+		DNAVersion: dnaVersion,
 	}
 	database.DB.Create(&userMsg)
 
-	// Increment round count
-	session.Rounds++
-	database.DB.Model(&session).UpdateColumns(map[string]interface{}{
-		"rounds": session.Rounds,
-	})
+	// Increment round count in the database, so concurrent messages each get their own round
+	database.DB.Raw("UPDATE chat_sessions SET rounds = rounds + 1 WHERE id = ? RETURNING rounds", session.ID).
+		Scan(&session.Rounds)
 
 	// Auto-generate session title from first message
 	if session.Rounds == 1 && session.Title == "" {
@@ -138,18 +264,29 @@ func ChatWithSoul(c *gin.Context, sessionID uuid.UUID, message string) error {
 		if len(title) > 60 {
 			title = title[:60] + "..."
 		}
+		if pastVersion != nil {
+			title = fmt.Sprintf("[DNA v%d] %s", pastVersion.VersionTo, title)
+		}
 		database.DB.Model(&session).UpdateColumn("title", title)
 	}
 
-	// Increment shell chat count
-	database.DB.Model(&shell).UpdateColumn("total_chats", shell.TotalChats+1)
+	// Increment shell chat count (time-travel chats are counted separately)
+	if pastVersion != nil {
+		database.DB.Model(&shell).UpdateColumn("time_travel_chats", gorm.Expr("time_travel_chats + 1"))
+	} else {
+		database.DB.Model(&shell).UpdateColumn("total_chats", gorm.Expr("total_chats + 1"))
+	}
+
+	if attest {
+		writeSSEJSON(c, "attestation", GetDNAAttestation(&shell, dnaVersion))
+	}
 
 	// If LLM is not configured, return a mock response
-	if config.Cfg.LLMAPIKey == "" {
+	if !LLMConfigured() {
 		response := fmt.Sprintf("I am the digital soul of @%s (DNA v%d). You asked: \"%s\". "+
 			"Configure LLM_API_KEY to enable full conversations.",
-			shell.Handle, shell.DNAVersion, message)
-		saveAssistantMessage(session.ID, response)
+			shell.Handle, dnaVersion, message)
+		saveAssistantMessage(session.ID, dnaVersion, response, nil)
 		writeSSE(c, "message", response)
 		writeSSE(c, "done", "")
 		return nil
@@ -161,33 +298,65 @@ func ChatWithSoul(c *gin.Context, sessionID uuid.UUID, message string) error {
 
 	// Build a rich system prompt that combines static soul_prompt with
 	// dynamic knowledge from dimensions, twitter_meta, and accepted fragments.
-	systemPrompt := buildRichSoulPrompt(&shell)
-
-	messages := []ChatMessage{
-		{Role: "system", Content: systemPrompt},
-	}
-	// Include up to last 20 messages for context window
-	startIdx := 0
-	if len(history) > 20 {
-		startIdx = len(history) - 20
-	}
-	for _, msg := range history[startIdx:] {
-		messages = append(messages, ChatMessage{Role: msg.Role, Content: msg.Content})
+	feature := models.LLMFeatureChat
+	var systemPrompt string
+	if pastVersion != nil {
+		feature = models.LLMFeatureTimeTravel
+		systemPrompt = buildTimeTravelPrompt(&shell, pastVersion)
+	} else {
+		systemPrompt = buildRichSoulPrompt(&shell)
+	}
+
+	// Append fragments retrieved for this specific message (tier-gated).
+	// Retrieval searches current fragments, so past versions go without.
+	var retrieved []ScoredFragment
+	if pastVersion == nil && RetrievalEnabledForTier(session.Tier) {
+		var err error
+		retrieved, err = RetrieveFragments(shell.ID, message, config.Cfg.ChatRetrievalTopK)
+		if err != nil {
+			util.Log.Warn("[chat] Fragment retrieval failed for @%s: %v", shell.Handle, err)
+		} else {
+			systemPrompt += buildRetrievedContext(retrieved)
+		}
 	}
+	systemPrompt += contentPolicyGuidance(settings.ContentPolicy)
+	systemPrompt += languageGuidance(DetectLanguage(message))
 
-	// Stream the LLM response via SSE, collecting full response
-	var fullResponse string
-	err := StreamLLM(messages, 2000, 0.7, func(content string) {
-		fullResponse += content
-		writeSSE(c, "message", content)
-	})
+	// History is trimmed to the tier's token budget, older turns live on in the rolling summary
+	historyMessages, _ := buildChatHistory(&session, history)
+	messages := append([]ChatMessage{{Role: "system", Content: systemPrompt}}, historyMessages...)
 
+	// Stream the LLM response via SSE. The reply is persisted as it grows and
+	// keeps generating if the client disconnects, so a reconnect to any replica
+	// can resume it from its Last-Event-ID (see ResumeChatStream).
+	stream, err := startChatStream(c, session.ID, dnaVersion)
 	if err != nil {
+		return err
+	}
+	err = StreamLLM(context.WithoutCancel(c.Request.Context()), LLMCallTag{Feature: feature, ShellID: &shell.ID, Model: ChatModelFor(settings), Mock: &MockInput{Handle: shell.Handle}}, messages, 2000, 0.7, stream.write)
+	fullResponse := stream.String()
+
+	// Resolve the reply's [^n] markers to the fragments they cite
+	citations := resolveCitations(fullResponse, retrieved)
+
+	switch {
+	case err == nil:
+		stream.finish(models.ChatMessageComplete, citations)
+		writeCitations(c, citations)
+		go summarizeChatHistory(session.ID)
+	case fullResponse != "":
+		util.Log.Warn("[chat] Streaming interrupted for @%s after %d chars: %v", shell.Handle, len(fullResponse), err)
+		stream.finish(models.ChatMessageInterrupted, citations)
+		writeCitations(c, citations)
+		writeSSE(c, "error", "The response was cut off. Please try again.")
+	default:
 		util.Log.Error("[chat] Streaming failed for @%s: %v", shell.Handle, err)
-		writeSSE(c, "error", "Failed to generate response. Please try again.")
-	} else {
-		// Save assistant response to DB
-		saveAssistantMessage(session.ID, fullResponse)
+		stream.discard()
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeSSE(c, "error", "The soul took too long to respond. Please try again.")
+		} else {
+			writeSSE(c, "error", "Failed to generate response. Please try again.")
+		}
 	}
 
 	writeSSE(c, "done", "")
@@ -224,6 +393,9 @@ func buildRichSoulPrompt(shell *models.Shell) string {
 		if verified, ok := shell.TwitterMeta["verified"].(bool); ok && verified {
 			sb.WriteString("Verified: Yes\n")
 		}
+		if lang, ok := shell.TwitterMeta["primary_language"].(string); ok && lang != "" {
+			sb.WriteString(fmt.Sprintf("Primary language: %s\n", LanguageName(lang)))
+		}
 		sb.WriteString("\n")
 	}
 
@@ -242,7 +414,7 @@ func buildRichSoulPrompt(shell *models.Shell) string {
 
 	// Inject recent accepted fragments as concrete knowledge pieces
 	var fragments []models.Fragment
-	database.DB.Where("shell_id = ? AND status = ?", shell.ID, models.FragStatusAccepted).
+	database.DB.Where("shell_id = ? AND status = ? AND subject_flag = ''", shell.ID, models.FragStatusAccepted).
 		Order("created_at DESC").Limit(30).Find(&fragments)
 
 	if len(fragments) > 0 {
@@ -271,20 +443,33 @@ func buildRichSoulPrompt(shell *models.Shell) string {
 		sb.WriteString("You have limited knowledge so far but use everything available above to give the best possible response. ")
 		sb.WriteString("Don't deflect with 'I don't know' if the information IS in your profile or fragments above.\n")
 	}
+	sb.WriteString(temporalGuidance(shell.KnowledgeCutoff))
 
 	return sb.String()
 }
 
 // saveAssistantMessage saves the assistant's response to the database.
-func saveAssistantMessage(sessionID uuid.UUID, content string) {
+func saveAssistantMessage(sessionID uuid.UUID, dnaVersion int, content string, citations models.ChatCitations) {
 	msg := models.ChatMessage{
-		SessionID: sessionID,
-		Role:      "assistant",
-		Content:   content,
+		SessionID:  sessionID,
+		Role:       "assistant",
+		Content:    content,
+		DNAVersion: dnaVersion,
+		Citations:  citations,
 	}
 	database.DB.Create(&msg)
 }
 
+// writeCitations sends the reply's citations as a "citations" SSE event whose
+// data is the JSON-encoded list.
+func writeCitations(c *gin.Context, citations models.ChatCitations) {
+	if len(citations) == 0 {
+		return
+	}
+	encoded, _ := json.Marshal(citations)
+	writeSSE(c, "citations", string(encoded))
+}
+
 // DeleteChatSession deletes a chat session and its messages.
 func DeleteChatSession(sessionID uuid.UUID, walletAddr string) error {
 	var session models.ChatSession
@@ -300,17 +485,19 @@ func DeleteChatSession(sessionID uuid.UUID, walletAddr string) error {
 
 // GetGlobalStats returns global statistics for the landing page.
 func GetGlobalStats() (map[string]interface{}, error) {
+	db := database.ReadDB()
+
 	var shellCount int64
-	database.DB.Model(&models.Shell{}).Count(&shellCount)
+	db.Model(&models.Shell{}).Count(&shellCount)
 
 	var fragCount int64
-	database.DB.Model(&models.Fragment{}).Count(&fragCount)
+	db.Model(&models.Fragment{}).Count(&fragCount)
 
 	var clawCount int64
-	database.DB.Model(&models.Claw{}).Where("status = ?", models.ClawStatusClaimed).Count(&clawCount)
+	db.Model(&models.Claw{}).Where("status = ?", models.ClawStatusClaimed).Count(&clawCount)
 
 	var chatCount int64
-	database.DB.Model(&models.Shell{}).Select("COALESCE(SUM(total_chats), 0)").Scan(&chatCount)
+	db.Model(&models.Shell{}).Select("COALESCE(SUM(total_chats), 0)").Scan(&chatCount)
 
 	return map[string]interface{}{
 		"souls":     shellCount,
@@ -324,23 +511,39 @@ func GetGlobalStats() (map[string]interface{}, error) {
 // Tasks are sorted by follower count (high-value souls first).
 func GetTaskBoard() ([]map[string]interface{}, error) {
 	// Fetch ALL confirmed shells that are not yet fully ensouled, no limit.
-	// Exclude pending, ensouled, and any shell not yet confirmed on-chain.
+	// Exclude pending, ensouled, revoked, disputed, and any shell not yet confirmed on-chain.
 	var shells []models.Shell
-	database.DB.Where("stage NOT IN ? AND mint_tx_hash != ''", []string{"ensouled", models.StagePending}).Find(&shells)
+	database.DB.Where("stage NOT IN ? AND "+models.ShellOnChainSQL+" AND chain_status = ? AND disputed_at IS NULL", []string{"ensouled", models.StagePending}, models.ShellChainActive).Find(&shells)
 
 	// Sort shells by follower count descending (high-value targets first)
 	sort.Slice(shells, func(i, j int) bool {
 		return getFollowers(shells[i]) > getFollowers(shells[j])
 	})
 
+	// Owner settings may close some dimensions to new fragments
+	var allSettings []models.ShellSettings
+	database.DB.Find(&allSettings)
+	settingsByShell := make(map[uuid.UUID]models.ShellSettings, len(allSettings))
+	for _, s := range allSettings {
+		settingsByShell[s.ShellID] = s
+	}
+
 	var tasks []map[string]interface{}
 	dimensions := []string{"personality", "knowledge", "stance", "style", "relationship", "timeline"}
 
 	for _, shell := range shells {
 		dims := shell.GetDimensions()
 		followers := getFollowers(shell)
+		settings, hasSettings := settingsByShell[shell.ID]
+		tags := []string(shell.Tags)
+		if tags == nil {
+			tags = []string{}
+		}
 
 		for _, dim := range dimensions {
+			if hasSettings && !DimensionAllowed(settings, dim) {
+				continue
+			}
 			d, exists := dims[dim]
 			if !exists || d.Score < 80 {
 				// Priority tiers:
@@ -362,6 +565,7 @@ func GetTaskBoard() ([]map[string]interface{}, error) {
 					"score":     d.Score,
 					"priority":  priority,
 					"followers": followers,
+					"tags":      tags,
 					"message":   fmt.Sprintf("@%s needs more fragments for %s (current score: %d)", shell.Handle, dim, d.Score),
 				})
 			}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// chatMessageOverhead is the framing cost of one message in the chat
+// completion format (role and separators).
+const chatMessageOverhead = 4
+
+// chatSummaryMaxTokens caps the rolling summary's length.
+const chatSummaryMaxTokens = 400
+
+// EstimateTokens approximates the BPE token count of text the way cl100k-style
+// tokenizers split it: common English words are one token and longer ones
+// take one per ~6 characters, each punctuation mark and CJK character is one
+// token. Accurate to roughly ±15%, enough for budgeting without shipping a
+// vocabulary.
+func EstimateTokens(text string) int {
+	tokens, units := 0, 0 // units: sixths of a token in the current word
+	flush := func() {
+		tokens += (units + 5) / 6
+		units = 0
+	}
+	for _, r := range text {
+		switch {
+		case r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			units++
+		case unicode.IsSpace(r):
+			flush() // the space merges into the next word's token
+		case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hiragana, r),
+			unicode.Is(unicode.Katakana, r), unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			units += 2 // other scripts split into shorter pieces
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// chatMessageTokens is the estimated cost of one message in the prompt.
+func chatMessageTokens(content string) int {
+	return EstimateTokens(content) + chatMessageOverhead
+}
+
+// ChatHistoryBudget returns how many tokens of conversation history (rolling
+// summary included) a session of the given tier sends to the LLM.
+func ChatHistoryBudget(tier string) int {
+	switch tier {
+	case models.ChatTierPaid:
+		return config.Cfg.ChatHistoryTokensPaid
+	case models.ChatTierFree:
+		return config.Cfg.ChatHistoryTokensFree
+	default:
+		return config.Cfg.ChatHistoryTokensGuest
+	}
+}
+
+// ChatContextUsage reports how much of a session's history budget the next
+// message would start from.
+type ChatContextUsage struct {
+	Budget             int `json:"budget"`
+	Used               int `json:"used"`
+	Remaining          int `json:"remaining"`
+	SummaryTokens      int `json:"summary_tokens"`
+	SummarizedMessages int `json:"summarized_messages"` // oldest messages folded into the summary
+	IncludedMessages   int `json:"included_messages"`   // recent messages sent verbatim
+	TrimmedMessages    int `json:"trimmed_messages"`    // neither summarized nor sent (summary pending)
+}
+
+// buildChatHistory selects the history sent with the next reply: the rolling
+// summary, then as many of the most recent unsummarized messages as fit the
+// tier's budget. The newest message is always included.
+func buildChatHistory(session *models.ChatSession, history []models.ChatMessage) ([]ChatMessage, ChatContextUsage) {
+	usage := ChatContextUsage{Budget: ChatHistoryBudget(session.Tier)}
+
+	summarized := session.SummarizedCount
+	if summarized > len(history) {
+		summarized = len(history)
+	}
+	usage.SummarizedMessages = summarized
+
+	var summaryMsg *ChatMessage
+	if session.Summary != "" {
+		summaryMsg = &ChatMessage{Role: "system", Content: "Summary of the earlier conversation:\n" + session.Summary}
+		usage.SummaryTokens = chatMessageTokens(summaryMsg.Content)
+		usage.Used = usage.SummaryTokens
+	}
+
+	recent := history[summarized:]
+	start := len(recent)
+	for start > 0 {
+		cost := chatMessageTokens(recent[start-1].Content)
+		if usage.Used+cost > usage.Budget && start < len(recent) {
+			break
+		}
+		usage.Used += cost
+		start--
+	}
+	usage.IncludedMessages = len(recent) - start
+	usage.TrimmedMessages = start
+	usage.Remaining = max(usage.Budget-usage.Used, 0)
+
+	messages := make([]ChatMessage, 0, usage.IncludedMessages+1)
+	if summaryMsg != nil {
+		messages = append(messages, *summaryMsg)
+	}
+	for _, msg := range recent[start:] {
+		messages = append(messages, ChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return messages, usage
+}
+
+// GetChatContextUsage reports a session's history budget use. session.Messages
+// must be loaded in order.
+func GetChatContextUsage(session *models.ChatSession) ChatContextUsage {
+	_, usage := buildChatHistory(session, session.Messages)
+	return usage
+}
+
+// summarizeChatHistory folds the oldest unsummarized messages into the rolling
+// summary once the unsummarized history no longer fits the budget, leaving
+// about half the budget of recent messages verbatim. With
+// CHAT_SUMMARY_EVERY_ROUNDS = N it also folds every N rounds, once 2N are
+// unsummarized, keeping the newest N. It runs after a reply is sent so users
+// never wait on it.
+func summarizeChatHistory(sessionID uuid.UUID) {
+	var session models.ChatSession
+	if err := database.DB.Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return
+	}
+	var history []models.ChatMessage
+	database.DB.Where("session_id = ?", session.ID).Order("created_at ASC").Find(&history)
+	if session.SummarizedCount >= len(history) {
+		return
+	}
+
+	budget := ChatHistoryBudget(session.Tier)
+	recent := history[session.SummarizedCount:]
+	total := EstimateTokens(session.Summary)
+	for _, msg := range recent {
+		total += chatMessageTokens(msg.Content)
+	}
+	every := config.Cfg.ChatSummaryEveryRounds
+	dueByRounds := every > 0 && countRounds(recent) >= 2*every
+	if total <= budget && !dueByRounds {
+		return
+	}
+
+	// Keep the newest messages worth half the budget (and, when folding by
+	// rounds, at most the newest N rounds); summarize the rest
+	keep, kept, rounds := 0, 0, 0
+	for i := len(recent) - 1; i >= 0; i-- {
+		cost := chatMessageTokens(recent[i].Content)
+		if kept+cost > budget/2 {
+			break
+		}
+		if dueByRounds && rounds == every {
+			break
+		}
+		if recent[i].Role == "user" {
+			rounds++
+		}
+		kept += cost
+		keep++
+	}
+	fold := recent[:len(recent)-keep]
+	if len(fold) == 0 {
+		return
+	}
+
+	var transcript strings.Builder
+	for _, msg := range fold {
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+	}
+	prompt := fmt.Sprintf(`Update the running summary of a conversation between a user and an AI persona.
+
+Current summary (may be empty):
+%s
+
+New turns to fold in:
+%s
+Write the updated summary in at most %d words, in the language the conversation uses. Keep facts the user shared about themselves, questions still open, and positions the persona took. Output only the summary.`,
+		session.Summary, transcript.String(), chatSummaryMaxTokens*3/4)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	summary, err := CallLLM(ctx, LLMCallTag{Feature: models.LLMFeatureChatSummary, ShellID: &session.ShellID},
+		[]ChatMessage{{Role: "user", Content: prompt}}, chatSummaryMaxTokens, 0.3)
+	if err != nil {
+		util.Log.Warn("[chat] Failed to summarize session %s: %v", session.ID, err)
+		return
+	}
+
+	// Conditional on the old count so concurrent runs don't fold the same turns twice
+	database.DB.Model(&models.ChatSession{}).
+		Where("id = ? AND summarized_count = ?", session.ID, session.SummarizedCount).
+		UpdateColumns(map[string]interface{}{
+			"summary":          strings.TrimSpace(summary),
+			"summarized_count": session.SummarizedCount + len(fold),
+		})
+}
+
+// countRounds counts the user messages of a history.
+func countRounds(history []models.ChatMessage) int {
+	n := 0
+	for _, msg := range history {
+		if msg.Role == "user" {
+			n++
+		}
+	}
+	return n
+}
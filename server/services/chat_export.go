@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrChatSessionNotOwned is returned for unknown sessions and sessions of
+// other wallets alike, so session IDs can't be probed.
+var ErrChatSessionNotOwned = errors.New("session not found or access denied")
+
+// ChatTranscript is an exported chat session.
+type ChatTranscript struct {
+	SessionID   uuid.UUID               `json:"session_id"`
+	Handle      string                  `json:"handle"`
+	DisplayName string                  `json:"display_name,omitempty"`
+	Title       string                  `json:"title,omitempty"`
+	PinnedDNA   int                     `json:"pinned_dna_version,omitempty"` // time-travel sessions
+	CreatedAt   time.Time               `json:"created_at"`
+	ExportedAt  time.Time               `json:"exported_at"`
+	Messages    []ChatTranscriptMessage `json:"messages"`
+}
+
+// ChatTranscriptMessage is one message of a ChatTranscript.
+type ChatTranscriptMessage struct {
+	Role       string    `json:"role"`
+	Content    string    `json:"content"`
+	DNAVersion int       `json:"dna_version,omitempty"` // omitted for messages sent before it was recorded
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ExportChatSession returns the full transcript of one of the wallet's sessions.
+func ExportChatSession(sessionID uuid.UUID, walletAddr string) (*ChatTranscript, error) {
+	var session models.ChatSession
+	if err := database.DB.
+		Preload("Messages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Shell").
+		Where("id = ? AND wallet_addr = ?", sessionID, walletAddr).
+		First(&session).Error; err != nil {
+		return nil, ErrChatSessionNotOwned
+	}
+
+	t := &ChatTranscript{
+		SessionID:   session.ID,
+		Handle:      session.Shell.Handle,
+		DisplayName: session.Shell.DisplayName,
+		Title:       session.Title,
+		PinnedDNA:   session.DNAVersion,
+		CreatedAt:   session.CreatedAt,
+		ExportedAt:  time.Now().UTC(),
+		Messages:    make([]ChatTranscriptMessage, len(session.Messages)),
+	}
+	for i, m := range session.Messages {
+		version := m.DNAVersion
+		if version == 0 {
+			version = session.DNAVersion // a pinned session always used its version
+		}
+		t.Messages[i] = ChatTranscriptMessage{
+			Role:       m.Role,
+			Content:    m.Content,
+			DNAVersion: version,
+			CreatedAt:  m.CreatedAt,
+		}
+	}
+	return t, nil
+}
+
+// Markdown renders the transcript for reading or publishing.
+func (t *ChatTranscript) Markdown() string {
+	var b strings.Builder
+	title := t.Title
+	if title == "" {
+		title = "Conversation"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+
+	name := "@" + t.Handle
+	if t.DisplayName != "" {
+		name = fmt.Sprintf("%s (@%s)", t.DisplayName, t.Handle)
+	}
+	fmt.Fprintf(&b, "- Soul: %s\n", name)
+	if t.PinnedDNA > 0 {
+		fmt.Fprintf(&b, "- Pinned to DNA v%d\n", t.PinnedDNA)
+	}
+	fmt.Fprintf(&b, "- Started: %s\n", t.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Exported: %s\n", t.ExportedAt.Format(time.RFC3339))
+
+	for _, m := range t.Messages {
+		speaker := "You"
+		if m.Role == "assistant" {
+			speaker = "@" + t.Handle
+		}
+		fmt.Fprintf(&b, "\n---\n\n**%s** · %s", speaker, m.CreatedAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+		if m.DNAVersion > 0 {
+			fmt.Fprintf(&b, " · DNA v%d", m.DNAVersion)
+		}
+		fmt.Fprintf(&b, "\n\n%s\n", strings.TrimSpace(m.Content))
+	}
+	return b.String()
+}
@@ -0,0 +1,414 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxChatPricingRounds      = 10000 // cap on free rounds and rounds per bundle
+	maxChatBundlesPerPurchase = 100
+	chatRevenueDays           = 30
+)
+
+// Errors for chat pricing.
+var (
+	ErrChatPricingOff      = errors.New("chat with this soul is free")
+	ErrChatPaymentsDown    = errors.New("chat payments are unavailable")
+	ErrChatPaymentRequired = errors.New("no chat rounds left")
+)
+
+// ChatPaymentError is returned when a public API chat has no free or
+// purchased round left with a priced soul.
+type ChatPaymentError struct {
+	Pricing ChatPricing
+	Balance *ChatCreditBalance
+}
+
+func (e *ChatPaymentError) Error() string {
+	return fmt.Sprintf("%v: buy %d more rounds for %s wei", ErrChatPaymentRequired, e.Pricing.BundleRounds, e.Pricing.BundlePriceWei)
+}
+
+func (e *ChatPaymentError) Unwrap() error { return ErrChatPaymentRequired }
+
+// ChatPricing is what chatting with a soul costs. Each bundle is paid with
+// two BNB transfers from the buyer: OwnerShareWei to PayTo (the owner) and
+// FeeWei to FeeTo (the platform wallet), unless the fee rounds to zero.
+type ChatPricing struct {
+	Enabled        bool   `json:"enabled"`
+	FreeRounds     int    `json:"free_rounds"`
+	BundleRounds   int    `json:"bundle_rounds"`
+	BundlePriceWei string `json:"bundle_price_wei"`
+	OwnerShareWei  string `json:"owner_share_wei"`
+	FeeWei         string `json:"fee_wei"`
+	FeeBps         int    `json:"fee_bps"`
+	PayTo          string `json:"pay_to"`
+	FeeTo          string `json:"fee_to,omitempty"`
+}
+
+// chatPricingEnabled reports whether the owner charges for chat rounds.
+func chatPricingEnabled(settings models.ShellSettings) bool {
+	return settings.ChatBundleRounds > 0 && settings.ChatBundlePriceWei != "" && settings.ChatBundlePriceWei != "0"
+}
+
+// chatFeeSplit splits a bundle price into the owner's share and the
+// CHAT_PLATFORM_FEE_BPS platform fee.
+func chatFeeSplit(price *big.Int) (ownerShare, fee *big.Int) {
+	bps := min(max(config.Cfg.ChatPlatformFeeBps, 0), 10000)
+	fee = new(big.Int).Div(new(big.Int).Mul(price, big.NewInt(int64(bps))), big.NewInt(10000))
+	return new(big.Int).Sub(price, fee), fee
+}
+
+// GetChatPricing returns the chat pricing of a soul.
+func GetChatPricing(shell *models.Shell, settings models.ShellSettings) ChatPricing {
+	pricing := ChatPricing{
+		Enabled:        chatPricingEnabled(settings),
+		FreeRounds:     settings.ChatFreeRounds,
+		BundleRounds:   settings.ChatBundleRounds,
+		BundlePriceWei: "0",
+		OwnerShareWei:  "0",
+		FeeWei:         "0",
+		FeeBps:         config.Cfg.ChatPlatformFeeBps,
+		PayTo:          shell.OwnerAddr,
+	}
+	if !pricing.Enabled {
+		return pricing
+	}
+	price, ok := new(big.Int).SetString(settings.ChatBundlePriceWei, 10)
+	if !ok {
+		pricing.Enabled = false
+		return pricing
+	}
+	ownerShare, fee := chatFeeSplit(price)
+	pricing.BundlePriceWei = price.String()
+	pricing.OwnerShareWei = ownerShare.String()
+	pricing.FeeWei = fee.String()
+	if fee.Sign() > 0 && chain.C() != nil && chain.C().HasPlatformKey() {
+		pricing.FeeTo = chain.C().PlatformAddress().Hex()
+	}
+	return pricing
+}
+
+// ChatCreditBalance is a wallet's (or guest's) chat rounds with a priced soul.
+type ChatCreditBalance struct {
+	FreeRounds int `json:"free_rounds"`
+	FreeUsed   int `json:"free_used"`
+	Purchased  int `json:"purchased"`
+	PaidUsed   int `json:"paid_used"`
+	Remaining  int `json:"remaining"`
+}
+
+func newChatCreditBalance(freeRounds int, credit models.ChatCredit) *ChatCreditBalance {
+	return &ChatCreditBalance{
+		FreeRounds: freeRounds,
+		FreeUsed:   credit.FreeUsed,
+		Purchased:  credit.Purchased,
+		PaidUsed:   credit.PaidUsed,
+		Remaining:  max(freeRounds-credit.FreeUsed, 0) + max(credit.Purchased-credit.PaidUsed, 0),
+	}
+}
+
+// GetChatCreditBalance returns a subject's chat rounds left with a soul.
+func GetChatCreditBalance(subject string, shellID uuid.UUID, settings models.ShellSettings) *ChatCreditBalance {
+	var credit models.ChatCredit
+	database.DB.Where("subject = ? AND shell_id = ?", subject, shellID).First(&credit)
+	return newChatCreditBalance(settings.ChatFreeRounds, credit)
+}
+
+// ChatCreditSubject returns whose rounds a wallet buys or looks up: its own,
+// or with a keyID those of one of its developer keys.
+func ChatCreditSubject(wallet, keyID string) (string, error) {
+	if keyID == "" {
+		return ChatSubject(wallet, ""), nil
+	}
+	key, err := walletDeveloperKey(wallet, keyID)
+	if err != nil {
+		return "", err
+	}
+	return DeveloperKeySubject(key.ID), nil
+}
+
+// hasPaidChatRounds reports whether a subject has purchased rounds left with a soul.
+func hasPaidChatRounds(subject string, shellID uuid.UUID) bool {
+	var n int64
+	database.DB.Model(&models.ChatCredit{}).
+		Where("subject = ? AND shell_id = ? AND paid_used < purchased", subject, shellID).
+		Count(&n)
+	return n > 0
+}
+
+// chatRoundsCharged reports whether a session's rounds are charged against
+// chat credits: the soul is priced and the chatter isn't its owner.
+func chatRoundsCharged(session *models.ChatSession, shell *models.Shell, settings models.ShellSettings) bool {
+	if !chatPricingEnabled(settings) {
+		return false
+	}
+	return session.WalletAddr == "" || !strings.EqualFold(session.WalletAddr, shell.OwnerAddr)
+}
+
+// consumeChatCredit charges one round of a session to its subject's credit
+// with the soul, free rounds first. When no round is left nothing is
+// charged and charged is false.
+func consumeChatCredit(session *models.ChatSession, settings models.ShellSettings) (balance *ChatCreditBalance, charged bool, err error) {
+	subject := sessionSubject(session)
+	free := settings.ChatFreeRounds
+
+	// A subject without a row can only have free rounds; one with a row always
+	// reaches the conditional update, which keeps concurrent messages from
+	// spending the same round twice
+	var credits []models.ChatCredit
+	if err := database.DB.Raw(`
+		INSERT INTO chat_credits (subject, shell_id, free_used, purchased, paid_used, updated_at)
+		SELECT ?, ?, 1, 0, 0, NOW()
+		WHERE ? > 0 OR EXISTS (SELECT 1 FROM chat_credits WHERE subject = ? AND shell_id = ?)
+		ON CONFLICT (subject, shell_id) DO UPDATE SET
+			free_used = CASE WHEN chat_credits.free_used < ? THEN chat_credits.free_used + 1 ELSE chat_credits.free_used END,
+			paid_used = CASE WHEN chat_credits.free_used < ? THEN chat_credits.paid_used ELSE chat_credits.paid_used + 1 END,
+			updated_at = NOW()
+		WHERE chat_credits.free_used < ? OR chat_credits.paid_used < chat_credits.purchased
+		RETURNING subject, shell_id, free_used, purchased, paid_used, updated_at`,
+		subject, session.ShellID, free, subject, session.ShellID, free, free, free,
+	).Scan(&credits).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to charge chat round: %w", err)
+	}
+	if len(credits) == 0 {
+		return GetChatCreditBalance(subject, session.ShellID, settings), false, nil
+	}
+	return newChatCreditBalance(free, credits[0]), true, nil
+}
+
+// writeChatPaymentRequired ends a message stream whose subject has no paid
+// or free rounds left with a priced soul.
+func writeChatPaymentRequired(c *gin.Context, shell *models.Shell, session *models.ChatSession, pricing ChatPricing, balance *ChatCreditBalance) {
+	price, _ := new(big.Int).SetString(pricing.BundlePriceWei, 10)
+	notice := fmt.Sprintf("You've used your rounds with @%s. Its owner offers %d more rounds for %g BNB.",
+		shell.Handle, pricing.BundleRounds, weiToBNB(price))
+	if session.Tier == models.ChatTierGuest {
+		notice += " Connect your wallet and sign in to buy them."
+	}
+	if encoded, err := json.Marshal(gin.H{"pricing": pricing, "balance": balance}); err == nil {
+		writeSSE(c, "payment", string(encoded))
+	}
+	writeSSE(c, "message", notice)
+	writeSSE(c, "done", "")
+}
+
+// PurchaseChatRounds credits wallet with bundles of the soul's chat rounds
+// after verifying on-chain that it sent the owner's share to the owner in
+// txHash and the platform fee to the platform wallet in feeTxHash. With a
+// keyID the rounds go to that developer key of the wallet instead, for the
+// public API.
+func PurchaseChatRounds(shell *models.Shell, wallet, keyID string, bundles int, txHash, feeTxHash string) (*models.ChatPurchase, *ChatCreditBalance, error) {
+	if err := checkShellActive(shell); err != nil {
+		return nil, nil, err
+	}
+	subject, err := ChatCreditSubject(wallet, keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	settings := GetShellSettings(shell.ID)
+	pricing := GetChatPricing(shell, settings)
+	if !pricing.Enabled {
+		return nil, nil, ErrChatPricingOff
+	}
+	if strings.EqualFold(wallet, shell.OwnerAddr) {
+		return nil, nil, fmt.Errorf("the owner chats with their soul for free")
+	}
+	if bundles < 1 || bundles > maxChatBundlesPerPurchase {
+		return nil, nil, fmt.Errorf("bundles must be 1-%d", maxChatBundlesPerPurchase)
+	}
+
+	n := big.NewInt(int64(bundles))
+	ownerShare, _ := new(big.Int).SetString(pricing.OwnerShareWei, 10)
+	fee, _ := new(big.Int).SetString(pricing.FeeWei, 10)
+	ownerShare.Mul(ownerShare, n)
+	fee.Mul(fee, n)
+
+	if !txHashRegex.MatchString(txHash) {
+		return nil, nil, fmt.Errorf("invalid transaction hash")
+	}
+	txHash = strings.ToLower(txHash)
+	if fee.Sign() > 0 {
+		if !txHashRegex.MatchString(feeTxHash) {
+			return nil, nil, fmt.Errorf("invalid fee transaction hash")
+		}
+		feeTxHash = strings.ToLower(feeTxHash)
+		if feeTxHash == txHash {
+			return nil, nil, fmt.Errorf("the fee must be paid in a separate transaction")
+		}
+	} else {
+		feeTxHash = ""
+	}
+	if chain.C() == nil || (fee.Sign() > 0 && pricing.FeeTo == "") {
+		return nil, nil, ErrChatPaymentsDown
+	}
+
+	hashes := []string{txHash}
+	if feeTxHash != "" {
+		hashes = append(hashes, feeTxHash)
+	}
+	var reused int64
+	database.DB.Model(&models.ChatPurchase{}).Where("tx_hash IN ? OR fee_tx_hash IN ?", hashes, hashes).Count(&reused)
+	if reused == 0 {
+		database.DB.Model(&models.ShellLicense{}).Where("payment_tx_hash IN ?", hashes).Count(&reused)
+	}
+	if reused > 0 {
+		return nil, nil, fmt.Errorf("this transaction has already been used for a payment")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+	buyer := common.HexToAddress(wallet)
+	if _, err := chain.VerifyPayment(ctx, txHash, buyer, common.HexToAddress(shell.OwnerAddr), ownerShare); err != nil {
+		return nil, nil, fmt.Errorf("payment verification failed: %w", err)
+	}
+	if feeTxHash != "" {
+		if _, err := chain.VerifyPayment(ctx, feeTxHash, buyer, common.HexToAddress(pricing.FeeTo), fee); err != nil {
+			return nil, nil, fmt.Errorf("fee payment verification failed: %w", err)
+		}
+	}
+
+	purchase := &models.ChatPurchase{
+		ShellID:    shell.ID,
+		WalletAddr: buyer.Hex(),
+		OwnerAddr:  shell.OwnerAddr,
+		Bundles:    bundles,
+		Rounds:     bundles * pricing.BundleRounds,
+		PriceWei:   new(big.Int).Add(ownerShare, fee).String(),
+		OwnerWei:   ownerShare.String(),
+		FeeWei:     fee.String(),
+		TxHash:     txHash,
+		FeeTxHash:  feeTxHash,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(purchase).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			INSERT INTO chat_credits (subject, shell_id, free_used, purchased, paid_used, updated_at) VALUES (?, ?, 0, ?, 0, NOW())
+			ON CONFLICT (subject, shell_id) DO UPDATE SET
+				purchased = chat_credits.purchased + EXCLUDED.purchased,
+				updated_at = NOW()`,
+			subject, shell.ID, purchase.Rounds).Error; err != nil {
+			return err
+		}
+		// Open sessions of the buyer move to the paid tier right away
+		return tx.Model(&models.ChatSession{}).
+			Where("shell_id = ? AND subject = ? AND tier = ?", shell.ID, subject, models.ChatTierFree).
+			Update("tier", models.ChatTierPaid).Error
+	})
+	if err != nil {
+		// The unique tx_hash index also catches a concurrent purchase with the same payment
+		return nil, nil, fmt.Errorf("failed to record chat purchase: %w", err)
+	}
+
+	util.Log.Info("[chat] %s bought %d rounds of @%s (%s wei to owner, %s wei fee, tx %s)",
+		purchase.WalletAddr, purchase.Rounds, shell.Handle, purchase.OwnerWei, purchase.FeeWei, txHash)
+	return purchase, GetChatCreditBalance(subject, shell.ID, settings), nil
+}
+
+// addWei adds a decimal wei amount to sum; unparsable amounts count as 0.
+func addWei(sum *big.Int, wei string) {
+	if v, ok := new(big.Int).SetString(wei, 10); ok {
+		sum.Add(sum, v)
+	}
+}
+
+// ChatRevenueDay is one UTC day of a soul's chat sales.
+type ChatRevenueDay struct {
+	Day       time.Time `json:"day"`
+	Purchases int       `json:"purchases"`
+	Rounds    int       `json:"rounds"`
+	OwnerWei  string    `json:"owner_wei"`
+}
+
+// ChatRevenue is the owner's view of a soul's chat sales.
+type ChatRevenue struct {
+	Pricing    ChatPricing           `json:"pricing"`
+	Purchases  int64                 `json:"purchases"`
+	Buyers     int64                 `json:"buyers"`
+	RoundsSold int64                 `json:"rounds_sold"`
+	RoundsUsed int64                 `json:"rounds_used"` // purchased rounds chatted so far
+	GrossWei   string                `json:"gross_wei"`
+	OwnerWei   string                `json:"owner_wei"`
+	FeeWei     string                `json:"fee_wei"`
+	Daily      []ChatRevenueDay      `json:"daily"` // last 30 days with sales
+	Recent     []models.ChatPurchase `json:"recent"`
+}
+
+// GetChatRevenue returns the chat sales of a soul. Wei amounts are summed
+// with big.Int and days bucketed in Go: SQLite has no exact numeric type
+// for amounts beyond 2^53 and no AT TIME ZONE.
+func GetChatRevenue(shell *models.Shell) (*ChatRevenue, error) {
+	revenue := &ChatRevenue{Pricing: GetChatPricing(shell, GetShellSettings(shell.ID))}
+
+	var purchases []models.ChatPurchase
+	if err := database.DB.Select("wallet_addr", "rounds", "price_wei", "owner_wei", "fee_wei", "created_at").
+		Where("shell_id = ?", shell.ID).Order("created_at ASC").
+		Find(&purchases).Error; err != nil {
+		return nil, fmt.Errorf("failed to load chat revenue: %w", err)
+	}
+	gross, owner, fee := new(big.Int), new(big.Int), new(big.Int)
+	buyers := map[string]bool{}
+	since := utcDay(time.Now()).AddDate(0, 0, -chatRevenueDays+1)
+	var days []time.Time
+	daily := map[time.Time]*ChatRevenueDay{}
+	dailyOwner := map[time.Time]*big.Int{}
+	for _, p := range purchases {
+		revenue.Purchases++
+		revenue.RoundsSold += int64(p.Rounds)
+		buyers[strings.ToLower(p.WalletAddr)] = true
+		addWei(gross, p.PriceWei)
+		addWei(owner, p.OwnerWei)
+		addWei(fee, p.FeeWei)
+
+		if p.CreatedAt.Before(since) {
+			continue
+		}
+		day := utcDay(p.CreatedAt)
+		d, ok := daily[day]
+		if !ok {
+			d = &ChatRevenueDay{Day: day}
+			daily[day], dailyOwner[day] = d, new(big.Int)
+			days = append(days, day)
+		}
+		d.Purchases++
+		d.Rounds += p.Rounds
+		addWei(dailyOwner[day], p.OwnerWei)
+	}
+	revenue.Buyers = int64(len(buyers))
+	revenue.GrossWei, revenue.OwnerWei, revenue.FeeWei = gross.String(), owner.String(), fee.String()
+
+	database.DB.Model(&models.ChatCredit{}).
+		Select("COALESCE(SUM(paid_used), 0)").
+		Where("shell_id = ?", shell.ID).
+		Scan(&revenue.RoundsUsed)
+
+	revenue.Daily = make([]ChatRevenueDay, 0, len(days))
+	for _, day := range days { // purchases are in order, so days are too
+		daily[day].OwnerWei = dailyOwner[day].String()
+		revenue.Daily = append(revenue.Daily, *daily[day])
+	}
+
+	revenue.Recent = []models.ChatPurchase{}
+	if err := database.DB.Where("shell_id = ?", shell.ID).Order("created_at DESC").Limit(20).
+		Find(&revenue.Recent).Error; err != nil {
+		return nil, fmt.Errorf("failed to load chat purchases: %w", err)
+	}
+	return revenue, nil
+}
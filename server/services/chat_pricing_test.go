@@ -0,0 +1,53 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+func TestChatRevenueSQLite(t *testing.T) {
+	util.InitLogger("error")
+	config.Cfg = &config.Config{}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	shell := &models.Shell{Handle: "elonmusk"}
+	database.DB.Create(shell)
+	now := time.Now().UTC()
+	// 10 BNB each, already more than an int64 holds
+	for _, p := range []struct {
+		wallet, tx string
+		at         time.Time
+	}{{"0xAbc", "0x01", now.AddDate(0, 0, -40)}, {"0xabc", "0x02", now.AddDate(0, 0, -1)}, {"0xdef", "0x03", now}} {
+		database.DB.Create(&models.ChatPurchase{ShellID: shell.ID, WalletAddr: p.wallet, OwnerAddr: "0xowner", Bundles: 1, Rounds: 10,
+			PriceWei: "10000000000000000000", OwnerWei: "9500000000000000000", FeeWei: "500000000000000000",
+			TxHash: p.tx, CreatedAt: p.at})
+	}
+
+	revenue, err := GetChatRevenue(shell)
+	if err != nil {
+		t.Fatalf("GetChatRevenue: %v", err)
+	}
+	if revenue.Purchases != 3 || revenue.Buyers != 2 || revenue.RoundsSold != 30 {
+		t.Errorf("purchases = %d, buyers = %d, rounds sold = %d, want 3, 2, 30", revenue.Purchases, revenue.Buyers, revenue.RoundsSold)
+	}
+	if revenue.GrossWei != "30000000000000000000" || revenue.OwnerWei != "28500000000000000000" || revenue.FeeWei != "1500000000000000000" {
+		t.Errorf("gross/owner/fee = %s/%s/%s", revenue.GrossWei, revenue.OwnerWei, revenue.FeeWei)
+	}
+	if len(revenue.Daily) != 2 || !revenue.Daily[0].Day.Equal(utcDay(now.AddDate(0, 0, -1))) || !revenue.Daily[1].Day.Equal(utcDay(now)) {
+		t.Fatalf("daily = %+v, want yesterday and today", revenue.Daily)
+	}
+	if revenue.Daily[1].OwnerWei != "9500000000000000000" || revenue.Daily[1].Rounds != 10 {
+		t.Errorf("today = %+v", revenue.Daily[1])
+	}
+}
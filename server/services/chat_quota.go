@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// chatRoundUsageRetention is how long daily round counts are kept.
+const chatRoundUsageRetention = 7 * 24 * time.Hour
+
+// ChatRoundQuota is what is left of a tier's daily rounds with one soul.
+type ChatRoundQuota struct {
+	Limit     int       `json:"limit"` // 0 = unlimited
+	Used      int       `json:"used"`
+	Remaining *int      `json:"remaining"` // nil when unlimited
+	ResetsAt  time.Time `json:"resets_at"` // next UTC midnight
+}
+
+// Exhausted reports whether no round is left today.
+func (q *ChatRoundQuota) Exhausted() bool {
+	return q.Remaining != nil && *q.Remaining == 0
+}
+
+// ChatRoundLimit returns the daily rounds per soul of a chat tier (0 = unlimited).
+func ChatRoundLimit(tier string) int {
+	switch tier {
+	case models.ChatTierPaid:
+		return config.Cfg.ChatDailyRoundsPaid
+	case models.ChatTierFree:
+		return config.Cfg.ChatDailyRoundsFree
+	default:
+		return config.Cfg.ChatDailyRoundsGuest
+	}
+}
+
+// ChatSubject identifies who chat rounds are counted against: the wallet, or
+// a hash of the client IP for guests, so the IP itself is not stored.
+func ChatSubject(walletAddr, clientIP string) string {
+	if walletAddr != "" {
+		return "wallet:" + strings.ToLower(walletAddr)
+	}
+	return "ip:" + util.HashToken("chat-ip:" + clientIP)[:32]
+}
+
+// DeveloperKeySubject is the chat credit subject of a developer key: public
+// API chats are charged to the key, not to its wallet's web chat rounds.
+func DeveloperKeySubject(keyID uuid.UUID) string {
+	return "devkey:" + keyID.String()
+}
+
+// sessionSubject is the subject of a session; sessions from before subjects
+// were recorded count on their own.
+func sessionSubject(session *models.ChatSession) string {
+	if session.Subject != "" {
+		return session.Subject
+	}
+	return "session:" + session.ID.String()
+}
+
+// newChatRoundQuota builds the quota for limit given today's used rounds.
+func newChatRoundQuota(limit, used int, today time.Time) *ChatRoundQuota {
+	q := &ChatRoundQuota{Limit: limit, Used: used, ResetsAt: today.AddDate(0, 0, 1)}
+	if limit > 0 {
+		remaining := max(limit-used, 0)
+		q.Remaining = &remaining
+	}
+	return q
+}
+
+// GetChatRoundQuota returns a subject's quota with a soul for a tier today.
+func GetChatRoundQuota(subject string, shellID uuid.UUID, tier string) *ChatRoundQuota {
+	today := utcDay(time.Now())
+	var usage models.ChatRoundUsage
+	database.DB.Where("subject = ? AND shell_id = ? AND day = ?", subject, shellID, today).First(&usage)
+	return newChatRoundQuota(ChatRoundLimit(tier), usage.Rounds, today)
+}
+
+// consumeChatRound counts one round of a session against its subject's daily
+// quota with the soul and returns the quota left. When the quota is used up
+// nothing is counted and counted is false.
+func consumeChatRound(session *models.ChatSession) (quota *ChatRoundQuota, counted bool, err error) {
+	subject := sessionSubject(session)
+	limit := ChatRoundLimit(session.Tier)
+	today := utcDay(time.Now())
+
+	// The conditional upsert keeps concurrent messages from overshooting the limit
+	var rounds []int
+	if err := database.DB.Raw(`
+		INSERT INTO chat_round_usages (subject, shell_id, day, rounds, updated_at) VALUES (?, ?, ?, 1, NOW())
+		ON CONFLICT (subject, shell_id, day) DO UPDATE SET
+			rounds = chat_round_usages.rounds + 1,
+			updated_at = NOW()
+		WHERE ? = 0 OR chat_round_usages.rounds < ?
+		RETURNING rounds`,
+		subject, session.ShellID, today, limit, limit,
+	).Scan(&rounds).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to count chat round: %w", err)
+	}
+	if len(rounds) == 0 {
+		return newChatRoundQuota(limit, limit, today), false, nil
+	}
+	return newChatRoundQuota(limit, rounds[0], today), true, nil
+}
+
+// releaseChatRound gives back a round consumeChatRound counted for a message
+// that won't be answered. quota is the one consumeChatRound returned, so the
+// round comes off the day it was counted on.
+func releaseChatRound(session *models.ChatSession, quota *ChatRoundQuota) error {
+	return database.DB.Model(&models.ChatRoundUsage{}).
+		Where("subject = ? AND shell_id = ? AND day = ? AND rounds > 0", sessionSubject(session), session.ShellID, quota.ResetsAt.AddDate(0, 0, -1)).
+		UpdateColumn("rounds", gorm.Expr("rounds - 1")).Error
+}
+
+// errChatRoundsUsedUp is returned by chargeChatRound when the daily quota is used up.
+var errChatRoundsUsedUp = errors.New("daily chat rounds used up")
+
+// chargeChatRound counts a message against its subject's daily rounds and,
+// when charged, spends one of its free or purchased rounds with the soul. It
+// fails with errChatRoundsUsedUp, or with ErrChatPaymentRequired and the
+// balance when no credit is left; the daily round is then given back.
+func chargeChatRound(session *models.ChatSession, settings models.ShellSettings, charged bool) (*ChatRoundQuota, *ChatCreditBalance, error) {
+	quota, counted, err := consumeChatRound(session)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !counted {
+		return quota, nil, errChatRoundsUsedUp
+	}
+	if !charged {
+		return quota, nil, nil
+	}
+
+	balance, ok, err := consumeChatCredit(session, settings)
+	if err != nil || !ok {
+		if releaseErr := releaseChatRound(session, quota); releaseErr != nil {
+			util.Log.Warn("[chat] Failed to give back the round of session %s: %v", session.ID, releaseErr)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return quota, balance, ErrChatPaymentRequired
+	}
+	return quota, balance, nil
+}
+
+// purgeChatRoundUsage drops daily round counts past chatRoundUsageRetention.
+func purgeChatRoundUsage() (int64, error) {
+	res := database.DB.Where("day < ?", utcDay(time.Now().Add(-chatRoundUsageRetention))).
+		Delete(&models.ChatRoundUsage{})
+	return res.RowsAffected, res.Error
+}
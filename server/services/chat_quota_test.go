@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+func TestChargeChatRoundGivesBackRoundWithoutCredit(t *testing.T) {
+	util.InitLogger("error")
+	config.Cfg = &config.Config{ChatDailyRoundsFree: 5}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	shell := &models.Shell{Handle: "elonmusk"}
+	database.DB.Create(shell)
+	session := &models.ChatSession{ShellID: shell.ID, WalletAddr: "0xabc", Tier: models.ChatTierFree, Subject: "wallet:0xabc"}
+	database.DB.Create(session)
+	database.DB.Create(&models.ChatCredit{Subject: session.Subject, ShellID: shell.ID, Purchased: 1})
+	settings := models.ShellSettings{ShellID: shell.ID}
+
+	// The balance check passed, then a concurrent message spent the last round
+	if balance := GetChatCreditBalance(session.Subject, shell.ID, settings); balance.Remaining != 1 {
+		t.Fatalf("remaining = %d before the charge, want 1", balance.Remaining)
+	}
+	if _, ok, err := consumeChatCredit(session, settings); !ok || err != nil {
+		t.Fatalf("concurrent charge: ok = %v, err = %v", ok, err)
+	}
+
+	quota, balance, err := chargeChatRound(session, settings, true)
+	if !errors.Is(err, ErrChatPaymentRequired) {
+		t.Fatalf("charge without credit: err = %v, want ErrChatPaymentRequired", err)
+	}
+	if balance == nil || balance.Remaining != 0 {
+		t.Errorf("balance = %+v, want none remaining", balance)
+	}
+	var usage models.ChatRoundUsage
+	database.DB.Where("subject = ? AND shell_id = ?", session.Subject, shell.ID).First(&usage)
+	if usage.Rounds != 0 {
+		t.Errorf("rounds counted today = %d, want 0 after the unpaid message", usage.Rounds)
+	}
+	if quota == nil || !quota.ResetsAt.Equal(utcDay(time.Now()).AddDate(0, 0, 1)) {
+		t.Errorf("quota = %+v, want today's", quota)
+	}
+
+	// Once the subject has credit again the round is counted and charged
+	database.DB.Model(&models.ChatCredit{}).Where("subject = ?", session.Subject).Update("purchased", 2)
+	if quota, balance, err = chargeChatRound(session, settings, true); err != nil || quota.Used != 1 || balance.Remaining != 0 {
+		t.Errorf("charge with credit: quota = %+v, balance = %+v, err = %v", quota, balance, err)
+	}
+}
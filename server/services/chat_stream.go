@@ -0,0 +1,185 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	chatStreamFlushEvery = 500 * time.Millisecond // how often a streaming reply is written to the database
+	chatStreamPollEvery  = 500 * time.Millisecond // how often a resumed stream checks for more of the reply
+	chatStreamStaleAfter = 30 * time.Second       // a streaming reply not flushed for this long lost its replica
+)
+
+// ErrChatStreamNotFound is returned when there is no reply to resume.
+var ErrChatStreamNotFound = errors.New("no reply to resume in this session")
+
+// chatStream persists a streamed assistant reply as it grows, so a client
+// that reconnects (possibly to another replica) can resume it. Each chunk is
+// sent with the SSE id "<message_id>:<offset>", offset being the bytes of the
+// reply sent so far.
+type chatStream struct {
+	c         *gin.Context
+	msg       models.ChatMessage
+	content   strings.Builder
+	flushedAt time.Time
+}
+
+// startChatStream creates the streaming assistant message of a reply.
+func startChatStream(c *gin.Context, sessionID uuid.UUID, dnaVersion int) (*chatStream, error) {
+	now := time.Now()
+	s := &chatStream{c: c, flushedAt: now}
+	s.msg = models.ChatMessage{
+		SessionID:  sessionID,
+		Role:       "assistant",
+		DNAVersion: dnaVersion,
+		Status:     models.ChatMessageStreaming,
+		StreamedAt: &now,
+	}
+	if err := database.DB.Create(&s.msg).Error; err != nil {
+		return nil, fmt.Errorf("failed to start reply: %w", err)
+	}
+	return s, nil
+}
+
+// String returns the reply so far.
+func (s *chatStream) String() string {
+	return s.content.String()
+}
+
+// write appends a chunk, sends it unless the client is gone, and flushes the
+// reply to the database every chatStreamFlushEvery.
+func (s *chatStream) write(chunk string) {
+	s.content.WriteString(chunk)
+	if s.c.Request.Context().Err() == nil {
+		writeSSEWithID(s.c, chatEventID(s.msg.ID, s.content.Len()), "message", chunk)
+	}
+	if time.Since(s.flushedAt) >= chatStreamFlushEvery {
+		s.flushedAt = time.Now()
+		database.DB.Model(&models.ChatMessage{}).Where("id = ?", s.msg.ID).
+			Updates(map[string]interface{}{"content": s.content.String(), "streamed_at": s.flushedAt})
+	}
+}
+
+// finish stores the whole reply with its final status and citations.
+func (s *chatStream) finish(status string, citations models.ChatCitations) {
+	if citations == nil {
+		citations = models.ChatCitations{}
+	}
+	database.DB.Model(&models.ChatMessage{}).Where("id = ?", s.msg.ID).Updates(map[string]interface{}{
+		"content":     s.content.String(),
+		"citations":   citations,
+		"status":      status,
+		"streamed_at": time.Now(),
+	})
+}
+
+// discard deletes a reply that produced nothing.
+func (s *chatStream) discard() {
+	database.DB.Delete(&models.ChatMessage{}, "id = ?", s.msg.ID)
+}
+
+// chatEventID is the SSE id of the reply chunk ending at offset.
+func chatEventID(messageID uuid.UUID, offset int) string {
+	return messageID.String() + ":" + strconv.Itoa(offset)
+}
+
+// parseChatEventID splits a Last-Event-ID into the message and offset.
+func parseChatEventID(id string) (uuid.UUID, int, bool) {
+	msgPart, offPart, ok := strings.Cut(id, ":")
+	if !ok {
+		return uuid.Nil, 0, false
+	}
+	msgID, err := uuid.Parse(msgPart)
+	if err != nil {
+		return uuid.Nil, 0, false
+	}
+	offset, err := strconv.Atoi(offPart)
+	if err != nil || offset < 0 {
+		return uuid.Nil, 0, false
+	}
+	return msgID, offset, true
+}
+
+// writeSSEWithID writes an SSE event carrying an id, which the client sends
+// back as Last-Event-ID to resume.
+func writeSSEWithID(c *gin.Context, id, event, data string) {
+	encoded, _ := json.Marshal(data)
+	fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", id, event, string(encoded))
+	c.Writer.Flush()
+}
+
+// ResumeChatStream replays a reply of a session from the position in
+// lastEventID ("<message_id>:<offset>", or "" for the latest reply from its
+// start), then follows it until it is complete. The reply is read from the
+// database, so the replica generating it doesn't matter.
+func ResumeChatStream(c *gin.Context, sessionID uuid.UUID, lastEventID string) error {
+	var msg models.ChatMessage
+	query := database.DB.Where("session_id = ? AND role = ?", sessionID, "assistant")
+	offset := 0
+	if lastEventID != "" {
+		msgID, off, ok := parseChatEventID(lastEventID)
+		if !ok {
+			return fmt.Errorf("invalid Last-Event-ID, expected <message_id>:<offset>")
+		}
+		query = query.Where("id = ?", msgID)
+		offset = off
+	} else {
+		query = query.Order("created_at DESC")
+	}
+	if err := query.First(&msg).Error; err != nil {
+		return ErrChatStreamNotFound
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	ctx := c.Request.Context()
+	for {
+		// The client may be ahead of this replica's last flush: keep its
+		// offset and wait for the content to grow past it
+		for offset > 0 && offset < len(msg.Content) && !utf8.RuneStart(msg.Content[offset]) {
+			offset--
+		}
+		if offset < len(msg.Content) {
+			writeSSEWithID(c, chatEventID(msg.ID, len(msg.Content)), "message", msg.Content[offset:])
+			offset = len(msg.Content)
+		}
+
+		switch {
+		case msg.Status == models.ChatMessageComplete:
+			writeCitations(c, msg.Citations)
+			writeSSE(c, "done", "")
+			return nil
+		case msg.Status == models.ChatMessageInterrupted,
+			msg.StreamedAt != nil && time.Since(*msg.StreamedAt) > chatStreamStaleAfter:
+			writeCitations(c, msg.Citations)
+			writeSSE(c, "error", "The response was cut off. Please try again.")
+			writeSSE(c, "done", "")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(chatStreamPollEvery):
+		}
+		if err := database.DB.Where("id = ?", msg.ID).First(&msg).Error; err != nil {
+			// Deleted: the reply failed before producing anything
+			writeSSE(c, "error", "Failed to generate response. Please try again.")
+			writeSSE(c, "done", "")
+			return nil
+		}
+	}
+}
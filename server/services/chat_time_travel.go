@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+)
+
+// findDNAVersion returns the ensouling that produced a shell's given DNA version.
+// The seed version (v1) is not archived, so only ensouled versions can be chatted with.
+func findDNAVersion(shell *models.Shell, version int) (*models.Ensouling, error) {
+	if version < 1 || version > shell.DNAVersion {
+		return nil, fmt.Errorf("soul @%s has no DNA v%d (current is v%d)", shell.Handle, version, shell.DNAVersion)
+	}
+	var ensouling models.Ensouling
+	if err := database.DB.Where("shell_id = ? AND version_to = ? AND status = ?", shell.ID, version, models.EnsoulingDeployed).
+		First(&ensouling).Error; err != nil || ensouling.NewPrompt == "" {
+		return nil, fmt.Errorf("DNA v%d of @%s is not available for chat", version, shell.Handle)
+	}
+	return &ensouling, nil
+}
+
+// TimeTravelSessionLabel describes a session pinned to a past DNA version, for display.
+func TimeTravelSessionLabel(session *models.ChatSession) (string, error) {
+	var shell models.Shell
+	if err := database.DB.First(&shell, "id = ?", session.ShellID).Error; err != nil {
+		return "", fmt.Errorf("soul not found")
+	}
+	ensouling, err := findDNAVersion(&shell, session.DNAVersion)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("@%s as of DNA v%d (%s), current is v%d",
+		shell.Handle, ensouling.VersionTo, ensouling.CreatedAt.Format("2006-01-02"), shell.DNAVersion), nil
+}
+
+// buildTimeTravelPrompt builds the system prompt for a past DNA version from the
+// prompt and dimensions that ensouling produced. Current fragments are left out
+// so the soul doesn't know what it only learned later.
+func buildTimeTravelPrompt(shell *models.Shell, ensouling *models.Ensouling) string {
+	var sb strings.Builder
+
+	sb.WriteString(ensouling.NewPrompt)
+	sb.WriteString("\n\n")
+
+	past := models.Shell{Dimensions: ensouling.DimensionsAfter}
+	dims := past.GetDimensions()
+	if len(dims) > 0 {
+		sb.WriteString("=== SOUL DIMENSIONS (as of this version) ===\n")
+		for _, key := range []string{"personality", "knowledge", "stance", "style", "relationship", "timeline"} {
+			if d, ok := dims[key]; ok && d.Summary != "" {
+				sb.WriteString(fmt.Sprintf("- %s (depth %d/100): %s\n", key, d.Score, d.Summary))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("=== RESPONSE GUIDELINES ===\n")
+	sb.WriteString(fmt.Sprintf("You are an earlier version of this soul: DNA v%d, ensouled on %s. ",
+		ensouling.VersionTo, ensouling.CreatedAt.Format("January 2, 2006")))
+	sb.WriteString("Answer only with what you knew at that point. ")
+	sb.WriteString("If asked about later developments, say that this version of you doesn't know about them yet.\n")
+	if ensouling.KnowledgeCutoff != nil {
+		sb.WriteString(temporalGuidance(ensouling.KnowledgeCutoff))
+	}
+
+	return sb.String()
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -10,10 +11,12 @@ import (
 	"time"
 
 	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/database"
 	"github.com/ensoul-labs/ensoul-server/models"
 	"github.com/ensoul-labs/ensoul-server/util"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // ClawRegistrationResult holds the data returned after Claw registration.
@@ -29,12 +32,33 @@ type ClawRegistrationInfo struct {
 	VerificationCode string `json:"verification_code"`
 }
 
+// Errors for Claw registration and operator actions.
+var (
+	ErrClawNameTaken    = errors.New("claw name is not available")
+	ErrClawWalletLimit  = errors.New("wallet Claw limit reached")
+	ErrNotClawOperator  = errors.New("only the Claw's operator wallet can do this")
+	ErrClawNotFound     = errors.New("claw not found")
+	ErrClawRetired      = errors.New("claw is retired")
+	ErrOperatorRequired = errors.New("an operator wallet signature is required to register")
+)
+
 // RegisterClaw creates a new Claw agent with generated credentials.
-func RegisterClaw(name, description string) (*ClawRegistrationResult, error) {
-	// Check for duplicate name (case-insensitive)
-	var existing models.Claw
-	if err := database.DB.Where("LOWER(name) = LOWER(?)", name).First(&existing).Error; err == nil {
-		return nil, fmt.Errorf("a claw named \"%s\" already exists", name)
+// registerIP is recorded for the per-IP registration cap; tags must already be
+// normalized with NormalizeClawTags. A non-empty operatorWallet (verified by
+// the caller) binds the Claw to that wallet right away, so it starts claimed
+// and counts towards the wallet's CLAW_WALLET_MAX_CLAWS. With
+// CLAW_REGISTER_REQUIRE_WALLET it is mandatory.
+func RegisterClaw(name, description, registerIP, operatorWallet string, tags []string) (*ClawRegistrationResult, error) {
+	if operatorWallet == "" && config.Cfg.ClawRegisterRequireWallet {
+		return nil, ErrOperatorRequired
+	}
+	if err := checkClawNameAvailable(name, uuid.Nil); err != nil {
+		return nil, err
+	}
+	if operatorWallet != "" {
+		if err := checkWalletClawCap(operatorWallet); err != nil {
+			return nil, err
+		}
 	}
 
 	// Generate API key
@@ -71,38 +95,91 @@ func RegisterClaw(name, description string) (*ClawRegistrationResult, error) {
 		Status:           models.ClawStatusPendingClaim,
 		WalletAddr:       wallet.Address,
 		WalletPKEnc:      wallet.PrivateKeyEnc,
+		RegisterIP:       registerIP,
+		Tags:             tags,
+		OperatorWallet:   operatorWallet,
+	}
+	if operatorWallet != "" {
+		claw.Status = models.ClawStatusClaimed
 	}
 
-	if err := database.DB.Create(claw).Error; err != nil {
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(claw).Error; err != nil {
+			return err
+		}
+		if operatorWallet == "" {
+			return nil
+		}
+		return tx.Create(&models.ClawBinding{
+			WalletAddr: operatorWallet,
+			ClawID:     claw.ID,
+			ClawName:   claw.Name,
+		}).Error
+	}); err != nil {
 		return nil, fmt.Errorf("failed to create claw: %w", err)
 	}
 
 	return &ClawRegistrationResult{
 		Claw: ClawRegistrationInfo{
 			APIKey:           apiKey,
-			ClaimURL:         fmt.Sprintf("/claim/%s", claimCode),
+			ClaimURL:         config.Cfg.ClaimURL(claimCode),
 			VerificationCode: verificationCode,
 		},
 		Important: "⚠️ SAVE YOUR API KEY! You need it for all subsequent requests.",
 	}, nil
 }
 
-// ClaimClaw claims a Claw by its claim code and binds it to the wallet.
-// The claim code acts as a one-time secret shared between agent and owner.
-func ClaimClaw(claimCode, walletAddr string) (map[string]interface{}, error) {
+// ClaimClaw claims a Claw for walletAddr, which becomes its operator. With a
+// tweetURL the operator also proves their Twitter account by a tweet holding
+// the Claw's verification code; the claim only happens if it checks out. The
+// operator of an already claimed Claw can add tweet verification later.
+func ClaimClaw(claimCode, walletAddr, tweetURL string) (map[string]interface{}, error) {
 	var claw models.Claw
 	if err := database.DB.Where("claim_code = ?", claimCode).First(&claw).Error; err != nil {
 		return nil, fmt.Errorf("invalid claim code")
 	}
 
-	if claw.Status == models.ClawStatusClaimed {
+	upgrade := tweetURL != "" && claw.Status == models.ClawStatusClaimed && strings.EqualFold(claw.OperatorWallet, walletAddr)
+	if claw.Status != models.ClawStatusPendingClaim && !upgrade {
 		return nil, fmt.Errorf("this claw has already been claimed")
 	}
 
-	// Mark as claimed
-	claw.Status = models.ClawStatusClaimed
-	if err := database.DB.Save(&claw).Error; err != nil {
-		return nil, fmt.Errorf("failed to update claw: %w", err)
+	if !upgrade {
+		if err := checkWalletClawCap(walletAddr); err != nil {
+			return nil, err
+		}
+	}
+
+	var twitterHandle string
+	if tweetURL != "" {
+		handle, err := verifyClawTweet(&claw, tweetURL)
+		if err != nil {
+			return nil, err
+		}
+		twitterHandle = handle
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if !upgrade {
+			// Mark as claimed; the claiming wallet becomes the operator
+			res := tx.Model(&models.Claw{}).Where("id = ? AND status = ?", claw.ID, models.ClawStatusPendingClaim).
+				Updates(map[string]interface{}{"status": models.ClawStatusClaimed, "operator_wallet": walletAddr})
+			if res.Error != nil {
+				return fmt.Errorf("failed to update claw: %w", res.Error)
+			}
+			if res.RowsAffected == 0 {
+				return fmt.Errorf("this claw has already been claimed")
+			}
+			claw.Status = models.ClawStatusClaimed
+			claw.OperatorWallet = walletAddr
+		}
+		if twitterHandle != "" {
+			return recordClawTweet(tx, &claw, twitterHandle, tweetURL)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Auto-bind the claimed Claw to the wallet (skip if already bound)
@@ -116,12 +193,19 @@ func ClaimClaw(claimCode, walletAddr string) (map[string]interface{}, error) {
 		database.DB.Create(binding)
 	}
 
+	message := "Claw claimed successfully! It has been added to your dashboard."
+	if upgrade {
+		message = "Claw verified by tweet."
+	}
 	return map[string]interface{}{
 		"success": true,
-		"message": "Claw claimed successfully! It has been added to your dashboard.",
+		"message": message,
 		"claw": map[string]interface{}{
-			"name":   claw.Name,
-			"status": claw.Status,
+			"name":             claw.Name,
+			"status":           claw.Status,
+			"twitter_handle":   claw.TwitterHandle,
+			"twitter_verified": claw.TwitterVerifiedAt != nil,
+			"trust_score":      claw.TrustScore,
 		},
 	}, nil
 }
@@ -149,12 +233,21 @@ func GetClawDashboard(claw *models.Claw) (map[string]interface{}, error) {
 			"accept_rate":     fmt.Sprintf("%.1f%%", acceptRate),
 			"earnings":        claw.Earnings,
 		},
-		"recent_contributions": recentFragments,
+		"recent_contributions": withNotes(recentFragments),
+		"batch_quota": map[string]interface{}{
+			"limit_per_soul": ClawBatchLimit(claw),
+			"window_hours":   int(batchQuotaWindow.Hours()),
+			"souls":          ClawBatchQuotas(claw),
+		},
 	}, nil
 }
 
-// GetClawContributions returns paginated contribution history for a Claw.
-func GetClawContributions(claw *models.Claw, pageStr, limitStr string) (map[string]interface{}, error) {
+// GetClawContributions returns paginated contribution history for a Claw,
+// with private notes and the status of its archived fragments. A non-empty
+// query keeps fragments whose notes or content contain it (case-insensitive).
+// With archived, the page lists archived fragments instead (query is ignored:
+// their content is compressed).
+func GetClawContributions(claw *models.Claw, pageStr, limitStr, query string, archived bool) (map[string]interface{}, error) {
 	page, _ := strconv.Atoi(pageStr)
 	limit, _ := strconv.Atoi(limitStr)
 	if page < 1 {
@@ -164,22 +257,44 @@ func GetClawContributions(claw *models.Claw, pageStr, limitStr string) (map[stri
 		limit = 20
 	}
 	offset := (page - 1) * limit
+	archive := GetClawArchiveStatus(claw.ID)
+
+	if archived {
+		contributions, err := listArchivedContributions(claw.ID, offset, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load archived contributions: %w", err)
+		}
+		return map[string]interface{}{
+			"contributions": contributions,
+			"total":         archive.Fragments,
+			"page":          page,
+			"limit":         limit,
+			"archive":       archive,
+		}, nil
+	}
+
+	base := database.DB.Model(&models.Fragment{}).Where("claw_id = ?", claw.ID)
+	if query = strings.TrimSpace(query); query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		base = base.Where(`(LOWER(notes) LIKE LOWER(?) ESCAPE '\' OR LOWER(content) LIKE LOWER(?) ESCAPE '\')`, pattern, pattern)
+	}
 
 	var total int64
-	database.DB.Model(&models.Fragment{}).Where("claw_id = ?", claw.ID).Count(&total)
+	base.Session(&gorm.Session{}).Count(&total)
 
 	var fragments []models.Fragment
-	database.DB.Where("claw_id = ?", claw.ID).
+	base.Session(&gorm.Session{}).
 		Preload("Shell").
 		Order("created_at DESC").
 		Offset(offset).Limit(limit).
 		Find(&fragments)
 
 	return map[string]interface{}{
-		"contributions": fragments,
+		"contributions": withNotes(fragments),
 		"total":         total,
 		"page":          page,
 		"limit":         limit,
+		"archive":       archive,
 	}, nil
 }
 
@@ -249,31 +364,98 @@ func GetClawPublicProfile(clawID string) (map[string]interface{}, error) {
 		Limit(10).
 		Find(&recentAccepted)
 
-	// Strip content from public response — only expose content_hash as fingerprint
+	// Strip content and claims from public response — only expose content_hash as fingerprint
 	for i := range recentAccepted {
 		recentAccepted[i].Content = ""
+		recentAccepted[i].Claims = nil
 	}
 
 	return map[string]interface{}{
 		"claw": map[string]interface{}{
-			"id":              claw.ID,
-			"name":            claw.Name,
-			"description":     claw.Description,
-			"status":          claw.Status,
-			"total_submitted": claw.TotalSubmitted,
-			"total_accepted":  claw.TotalAccepted,
-			"accept_rate":     fmt.Sprintf("%.1f%%", acceptRate),
-			"earnings":        claw.Earnings,
-			"created_at":      claw.CreatedAt,
+			"id":               claw.ID,
+			"name":             claw.Name,
+			"description":      claw.Description,
+			"status":           claw.Status,
+			"twitter_handle":   claw.TwitterHandle,
+			"twitter_verified": claw.TwitterVerifiedAt != nil,
+			"tags":             claw.Tags,
+			"total_submitted":  claw.TotalSubmitted,
+			"total_accepted":   claw.TotalAccepted,
+			"accept_rate":      fmt.Sprintf("%.1f%%", acceptRate),
+			"earnings":         claw.Earnings,
+			"current_streak":   claw.CurrentStreak,
+			"longest_streak":   claw.LongestStreak,
+			"created_at":       claw.CreatedAt,
 		},
 		"dimension_stats":     dimStats,
 		"shell_contributions": shellContribs,
 		"recent_accepted":     recentAccepted,
+		"season_badges":       GetClawSeasonBadges(claw.ID),
+		"badges":              GetClawBadges(claw.ID),
 	}, nil
 }
 
+// clawRank is a public leaderboard row (no API keys, no private data).
+type clawRank struct {
+	Rank            int        `json:"rank"`
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	TotalSubmitted  int        `json:"total_submitted"`
+	TotalAccepted   int        `json:"total_accepted"`
+	AcceptRate      string     `json:"accept_rate"`
+	Earnings        float64    `json:"earnings"`
+	Active          bool       `json:"active"`
+	TwitterVerified bool       `json:"twitter_verified"`
+	CurrentStreak   int        `json:"current_streak"`
+	Badges          []string   `json:"badges"`
+	LastSeenAt      *time.Time `json:"last_seen_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// newClawRank builds a leaderboard row from the given submitted/accepted counts
+// (all-time or for one season).
+func newClawRank(rank int, c *models.Claw, submitted, accepted int) clawRank {
+	var rate float64
+	if submitted > 0 {
+		rate = float64(accepted) / float64(submitted) * 100
+	}
+	return clawRank{
+		Rank:            rank,
+		ID:              c.ID,
+		Name:            c.Name,
+		Description:     c.Description,
+		TotalSubmitted:  submitted,
+		TotalAccepted:   accepted,
+		AcceptRate:      fmt.Sprintf("%.1f%%", rate),
+		Earnings:        c.Earnings,
+		Active:          ClawIsActive(c),
+		TwitterVerified: c.TwitterVerifiedAt != nil,
+		CurrentStreak:   c.CurrentStreak,
+		Badges:          []string{},
+		LastSeenAt:      c.LastSeenAt,
+		CreatedAt:       c.CreatedAt,
+	}
+}
+
+// attachClawBadges fills in the badges of leaderboard rows.
+func attachClawBadges(ranked []clawRank) {
+	ids := make([]uuid.UUID, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.ID
+	}
+	keys := clawBadgeKeys(ids)
+	for i := range ranked {
+		if k, ok := keys[ranked[i].ID]; ok {
+			ranked[i].Badges = k
+		}
+	}
+}
+
 // GetClawLeaderboard returns a ranked list of Claws by accepted fragments.
-func GetClawLeaderboard(pageStr, limitStr string) (map[string]interface{}, error) {
+// period is "all" (lifetime counters), or "weekly"/"monthly" for the current
+// season's rollup. With activeOnly, Claws without a recent heartbeat are left out.
+func GetClawLeaderboard(pageStr, limitStr, period string, activeOnly bool) (map[string]interface{}, error) {
 	page, _ := strconv.Atoi(pageStr)
 	limit, _ := strconv.Atoi(limitStr)
 	if page < 1 {
@@ -282,61 +464,44 @@ func GetClawLeaderboard(pageStr, limitStr string) (map[string]interface{}, error
 	if limit < 1 || limit > 50 {
 		limit = 20
 	}
+	if period != "" && period != models.LeaderboardAllTime {
+		return getSeasonLeaderboard(period, page, limit, activeOnly)
+	}
 	offset := (page - 1) * limit
 
+	query := database.ReadDB().Model(&models.Claw{}).Where("status = ?", "claimed")
+	if activeOnly {
+		query = query.Where("last_seen_at >= ?", time.Now().Add(-ClawActiveWindow()))
+	}
+
 	var total int64
-	database.DB.Model(&models.Claw{}).Where("status = ?", "claimed").Count(&total)
+	query.Count(&total)
 
 	var claws []models.Claw
-	database.DB.Where("status = ?", "claimed").
+	query.
 		Order("total_accepted DESC, total_submitted DESC").
 		Offset(offset).Limit(limit).
 		Find(&claws)
 
-	// Build public response (no API keys, no private data)
-	type ClawRank struct {
-		Rank           int       `json:"rank"`
-		ID             uuid.UUID `json:"id"`
-		Name           string    `json:"name"`
-		Description    string    `json:"description"`
-		TotalSubmitted int       `json:"total_submitted"`
-		TotalAccepted  int       `json:"total_accepted"`
-		AcceptRate     string    `json:"accept_rate"`
-		Earnings       float64   `json:"earnings"`
-		CreatedAt      time.Time `json:"created_at"`
-	}
-
-	ranked := make([]ClawRank, len(claws))
-	for i, c := range claws {
-		var rate float64
-		if c.TotalSubmitted > 0 {
-			rate = float64(c.TotalAccepted) / float64(c.TotalSubmitted) * 100
-		}
-		ranked[i] = ClawRank{
-			Rank:           offset + i + 1,
-			ID:             c.ID,
-			Name:           c.Name,
-			Description:    c.Description,
-			TotalSubmitted: c.TotalSubmitted,
-			TotalAccepted:  c.TotalAccepted,
-			AcceptRate:     fmt.Sprintf("%.1f%%", rate),
-			Earnings:       c.Earnings,
-			CreatedAt:      c.CreatedAt,
-		}
+	ranked := make([]clawRank, len(claws))
+	for i := range claws {
+		ranked[i] = newClawRank(offset+i+1, &claws[i], claws[i].TotalSubmitted, claws[i].TotalAccepted)
 	}
+	attachClawBadges(ranked)
 
 	return map[string]interface{}{
-		"claws": ranked,
-		"total": total,
-		"page":  page,
-		"limit": limit,
+		"claws":  ranked,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+		"period": models.LeaderboardAllTime,
 	}, nil
 }
 
 // GetShellContributors returns top contributors for a specific shell.
 func GetShellContributors(handle string) ([]map[string]interface{}, error) {
-	var shell models.Shell
-	if err := database.DB.Where("LOWER(handle) = ?", handle).First(&shell).Error; err != nil {
+	shell, err := GetShellByHandle(handle)
+	if err != nil {
 		return nil, fmt.Errorf("shell not found")
 	}
 
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// clawAgentClaimTTL is how long a registration claim holds off other
+// registrations of the same Claw; a claim left by a crashed replica expires.
+const clawAgentClaimTTL = 5 * time.Minute
+
+// ClawAgentURI is the agentURI registered on-chain for a Claw. It points at the
+// live agent card, so stats stay current without further transactions.
+func ClawAgentURI(clawID uuid.UUID) string {
+	return config.Cfg.PublicURL(fmt.Sprintf("/api/claw/%s/agent-card", clawID))
+}
+
+// GetClawAgentCard builds the ERC-8004 registration file for a Claw.
+func GetClawAgentCard(clawID string) (*chain.AgentRegistrationFile, error) {
+	uid, err := uuid.Parse(clawID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid claw ID")
+	}
+
+	var claw models.Claw
+	if err := database.DB.Where("id = ?", uid).First(&claw).Error; err != nil {
+		return nil, fmt.Errorf("claw not found")
+	}
+
+	operator := claw.OperatorWallet
+	var binding models.ClawBinding
+	if operator == "" && database.DB.Where("claw_id = ?", claw.ID).Order("created_at ASC").First(&binding).Error == nil {
+		operator = binding.WalletAddr
+	}
+
+	var soulsContributed int64
+	database.DB.Model(&models.Fragment{}).
+		Where("claw_id = ? AND status = ?", claw.ID, models.FragStatusAccepted).
+		Distinct("shell_id").Count(&soulsContributed)
+
+	var acceptRate float64
+	if claw.TotalSubmitted > 0 {
+		acceptRate = math.Round(float64(claw.TotalAccepted)/float64(claw.TotalSubmitted)*1000) / 10
+	}
+
+	description := claw.Description
+	if description == "" {
+		description = fmt.Sprintf("%s is a Claw agent contributing soul fragments on Ensoul.", claw.Name)
+	}
+
+	card := &chain.AgentRegistrationFile{
+		Type:        "https://eips.ethereum.org/EIPS/eip-8004#registration-v1",
+		Name:        fmt.Sprintf("%s (Ensoul Claw)", claw.Name),
+		Description: description,
+		Services: []chain.AgentService{
+			{
+				Name:     "web",
+				URL:      config.Cfg.PublicURL("/claw/" + claw.ID.String()),
+				Protocol: "https",
+			},
+		},
+		Ensoul: map[string]interface{}{
+			"role":             "claw",
+			"clawId":           claw.ID.String(),
+			"status":           claw.Status,
+			"wallet":           claw.WalletAddr,
+			"operator":         operator,
+			"totalSubmitted":   claw.TotalSubmitted,
+			"totalAccepted":    claw.TotalAccepted,
+			"acceptanceRate":   acceptRate,
+			"soulsContributed": soulsContributed,
+			"createdAt":        claw.CreatedAt.UTC().Format(time.RFC3339),
+		},
+	}
+	if claw.AgentID != nil {
+		card.Registrations = []chain.AgentRegistration{{
+			AgentRegistry: chain.AgentRegistryID(),
+			AgentID:       strconv.FormatUint(*claw.AgentID, 10),
+		}}
+	}
+	return card, nil
+}
+
+// RegisterClawAgent registers a claimed Claw in the ERC-8004 Identity Registry
+// from its own wallet, dripping gas first if needed.
+func RegisterClawAgent(claw *models.Claw) error {
+	if claw.Status != models.ClawStatusClaimed {
+		return fmt.Errorf("claw must be claimed before registering on-chain")
+	}
+	if claw.CustodyMode == models.ClawCustodyExternal {
+		return fmt.Errorf("claws with an external wallet register their agent from that wallet")
+	}
+	if claw.WalletPKEnc == "" {
+		return fmt.Errorf("claw has no wallet")
+	}
+
+	// Claim the registration in the DB so concurrent requests, on any
+	// replica, don't register the Claw twice
+	if err := claimClawAgent(claw.ID); err != nil {
+		return err
+	}
+	// Released on failure, unless the tx went out: then it is left to expire
+	sent := false
+	defer func() {
+		if !sent {
+			database.DB.Model(&models.Claw{}).Where("id = ?", claw.ID).Update("agent_claimed_at", nil)
+		}
+	}()
+
+	clawKey, err := chain.DecryptClawPrivateKey(claw.WalletPKEnc)
+	if err != nil {
+		util.Log.Error("[claw] Failed to decrypt key for %s: %v", claw.Name, err)
+		return fmt.Errorf("failed to load claw wallet")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if err := ensureClawGas(ctx, claw); err != nil {
+		return err
+	}
+
+	agentId, txHash, err := chain.RegisterClawAgent(ctx, clawKey, ClawAgentURI(claw.ID))
+	if err != nil {
+		util.Log.Error("[claw] On-chain registration failed for %s: %v", claw.Name, err)
+		return fmt.Errorf("on-chain registration failed: %w", err)
+	}
+	sent = true
+	if agentId == nil || !agentId.IsUint64() {
+		return fmt.Errorf("unexpected agent ID %v", agentId)
+	}
+
+	id := agentId.Uint64()
+	if err := database.DB.Model(claw).Updates(map[string]interface{}{
+		"agent_id":         id,
+		"agent_tx_hash":    txHash,
+		"agent_claimed_at": nil,
+	}).Error; err != nil {
+		util.Log.Error("[claw] Registered %s as agent #%d (tx %s) but failed to save: %v", claw.Name, id, txHash, err)
+		return fmt.Errorf("failed to save registration")
+	}
+	claw.AgentID = &id
+	claw.AgentTxHash = txHash
+	return nil
+}
+
+// claimClawAgent marks a Claw's registration as in flight, failing if it is
+// already registered or another request holds an unexpired claim.
+func claimClawAgent(clawID uuid.UUID) error {
+	now := time.Now()
+	res := database.DB.Model(&models.Claw{}).
+		Where("id = ? AND agent_id IS NULL AND (agent_claimed_at IS NULL OR agent_claimed_at < ?)", clawID, now.Add(-clawAgentClaimTTL)).
+		Update("agent_claimed_at", now)
+	if res.Error != nil {
+		return fmt.Errorf("failed to claim registration: %w", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+
+	var current models.Claw
+	if err := database.DB.Select("agent_id").First(&current, "id = ?", clawID).Error; err != nil {
+		return fmt.Errorf("claw not found")
+	}
+	if current.AgentID != nil {
+		return fmt.Errorf("claw is already registered as agent #%d", *current.AgentID)
+	}
+	return fmt.Errorf("claw registration is already in progress")
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// BadgeInfo describes an achievement badge for display.
+type BadgeInfo struct {
+	Badge       string `json:"badge"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// clawBadgeInfo lists the achievement badges in display order.
+var clawBadgeInfo = []BadgeInfo{
+	{models.BadgeFirstTenAccepted, "First Ten", "10 accepted fragments"},
+	{models.BadgeFiveSouls, "Soul Weaver", "Accepted fragments for 5 different souls"},
+	{models.BadgeSharpshooter, "Sharpshooter", "90% acceptance over at least 50 submissions"},
+	{models.BadgeStreakWeek, "Week Streak", "Accepted fragments 7 days in a row"},
+	{models.BadgeStreakMonth, "Month Streak", "Accepted fragments 30 days in a row"},
+}
+
+// ClawBadgeView is an earned badge with its description.
+type ClawBadgeView struct {
+	BadgeInfo
+	AwardedAt time.Time `json:"awarded_at"`
+}
+
+// StartClawAchievements periodically recomputes contribution streaks and
+// awards the milestone badges Claws have reached.
+func StartClawAchievements(interval time.Duration) {
+	scheduleJob("claw-achievements", "Recompute Claw contribution streaks and award badges", interval, true, refreshClawAchievements)
+	util.Log.Info("[badges] Claw achievements started (every %v)", interval)
+}
+
+func refreshClawAchievements() error {
+	if err := refreshClawStreaks(); err != nil {
+		return fmt.Errorf("streaks: %w", err)
+	}
+	awarded, err := awardClawBadges()
+	if err != nil {
+		return fmt.Errorf("badges: %w", err)
+	}
+	if awarded > 0 {
+		util.Log.Info("[badges] Awarded %d badges", awarded)
+	}
+	return nil
+}
+
+// refreshClawStreaks sets each Claw's current and longest run of consecutive
+// UTC days on which it submitted a fragment that was accepted. The current
+// streak still counts until a day without one has fully passed. Days are
+// bucketed in Go so the job runs the same on PostgreSQL and SQLite.
+func refreshClawStreaks() error {
+	rows, err := database.DB.Model(&models.Fragment{}).
+		Select("claw_id", "created_at").
+		Where("status = ?", models.FragStatusAccepted).
+		Rows()
+	if err != nil {
+		return err
+	}
+	days := map[uuid.UUID]map[time.Time]bool{}
+	for rows.Next() {
+		var clawID uuid.UUID
+		var createdAt time.Time
+		if err := rows.Scan(&clawID, &createdAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if days[clawID] == nil {
+			days[clawID] = map[time.Time]bool{}
+		}
+		days[clawID][utcDay(createdAt)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var claws []models.Claw
+	if err := database.DB.Select("id", "current_streak", "longest_streak").Find(&claws).Error; err != nil {
+		return err
+	}
+	today := utcDay(time.Now())
+	for _, c := range claws {
+		current, longest := clawStreaks(days[c.ID], today)
+		longest = max(longest, c.LongestStreak)
+		if current == c.CurrentStreak && longest == c.LongestStreak {
+			continue
+		}
+		if err := database.DB.Model(&models.Claw{}).Where("id = ?", c.ID).
+			UpdateColumns(map[string]interface{}{"current_streak": current, "longest_streak": longest}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clawStreaks returns the current and longest runs of consecutive days in
+// days. The current run is the one ending today or yesterday.
+func clawStreaks(days map[time.Time]bool, today time.Time) (current, longest int) {
+	sorted := make([]time.Time, 0, len(days))
+	for d := range days {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	run := 0
+	for i, d := range sorted {
+		if i > 0 && sorted[i-1].AddDate(0, 0, 1).Equal(d) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	if n := len(sorted); n > 0 && !sorted[n-1].Before(today.AddDate(0, 0, -1)) {
+		current = run
+	}
+	return current, longest
+}
+
+// awardClawBadges records the badges claimed Claws have newly earned and
+// returns how many were awarded.
+func awardClawBadges() (int64, error) {
+	rules := []struct {
+		badge string
+		query string
+		args  []interface{}
+	}{
+		{models.BadgeFirstTenAccepted, `SELECT id FROM claws WHERE total_accepted >= 10`, nil},
+		{models.BadgeFiveSouls, `
+			SELECT claw_id AS id FROM fragments
+			WHERE status = ? AND deleted_at IS NULL
+			GROUP BY claw_id HAVING COUNT(DISTINCT shell_id) >= 5`,
+			[]interface{}{models.FragStatusAccepted}},
+		{models.BadgeSharpshooter, `SELECT id FROM claws WHERE total_submitted >= 50 AND total_accepted * 10 >= total_submitted * 9`, nil},
+		{models.BadgeStreakWeek, `SELECT id FROM claws WHERE longest_streak >= 7`, nil},
+		{models.BadgeStreakMonth, `SELECT id FROM claws WHERE longest_streak >= 30`, nil},
+	}
+
+	var awarded int64
+	var errs []error
+	for _, r := range rules {
+		args := append([]interface{}{r.badge}, r.args...)
+		args = append(args, models.ClawStatusClaimed)
+		// SQLite needs a WHERE before ON CONFLICT to tell it from a join constraint
+		res := database.DB.Exec(`
+			INSERT INTO claw_badges (id, claw_id, badge, awarded_at)
+			SELECT gen_random_uuid(), q.id, ?, NOW()
+			FROM (`+r.query+`) AS q
+			JOIN claws ON claws.id = q.id AND claws.deleted_at IS NULL AND claws.status = ?
+			WHERE true
+			ON CONFLICT (claw_id, badge) DO NOTHING`, args...)
+		if res.Error != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.badge, res.Error))
+			continue
+		}
+		awarded += res.RowsAffected
+	}
+	return awarded, errors.Join(errs...)
+}
+
+// GetClawBadges returns the badges a Claw has earned, in display order.
+func GetClawBadges(clawID uuid.UUID) []ClawBadgeView {
+	var rows []models.ClawBadge
+	database.DB.Where("claw_id = ?", clawID).Find(&rows)
+	earned := make(map[string]time.Time, len(rows))
+	for _, r := range rows {
+		earned[r.Badge] = r.AwardedAt
+	}
+
+	badges := []ClawBadgeView{}
+	for _, info := range clawBadgeInfo {
+		if at, ok := earned[info.Badge]; ok {
+			badges = append(badges, ClawBadgeView{BadgeInfo: info, AwardedAt: at})
+		}
+	}
+	return badges
+}
+
+// clawBadgeKeys returns the badge keys earned by each of the given Claws.
+func clawBadgeKeys(clawIDs []uuid.UUID) map[uuid.UUID][]string {
+	keys := make(map[uuid.UUID][]string, len(clawIDs))
+	if len(clawIDs) == 0 {
+		return keys
+	}
+	var rows []models.ClawBadge
+	database.ReadDB().Where("claw_id IN ?", clawIDs).Order("awarded_at ASC").Find(&rows)
+	for _, r := range rows {
+		keys[r.ClawID] = append(keys[r.ClawID], r.Badge)
+	}
+	return keys
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+func TestClawAchievementsSQLite(t *testing.T) {
+	util.InitLogger("error")
+	config.Cfg = &config.Config{}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	shell := &models.Shell{Handle: "elonmusk"}
+	database.DB.Create(shell)
+	claw := func(name string, accepted int) *models.Claw {
+		c := &models.Claw{Name: name, APIKeyHash: name, ClaimCode: name, VerificationCode: name,
+			Status: models.ClawStatusClaimed, TotalSubmitted: accepted, TotalAccepted: accepted}
+		database.DB.Create(c)
+		return c
+	}
+	accept := func(c *models.Claw, daysAgo ...int) {
+		for _, d := range daysAgo {
+			database.DB.Create(&models.Fragment{ShellID: shell.ID, ClawID: c.ID, Dimension: models.DimStance,
+				Content: "fragment", Status: models.FragStatusAccepted, CreatedAt: time.Now().AddDate(0, 0, -d)})
+		}
+	}
+	// Seven days in a row up to yesterday, plus an older lone day
+	active := claw("active", 10)
+	accept(active, 1, 2, 3, 4, 5, 6, 7, 7, 20)
+	// Three days in a row that ended a week ago
+	lapsed := claw("lapsed", 3)
+	accept(lapsed, 7, 8, 9)
+
+	if err := refreshClawAchievements(); err != nil {
+		t.Fatalf("claw-achievements: %v", err)
+	}
+
+	for _, tc := range []struct {
+		claw             *models.Claw
+		current, longest int
+		badges           []string
+	}{
+		{active, 7, 7, []string{models.BadgeFirstTenAccepted, models.BadgeStreakWeek}},
+		{lapsed, 0, 3, nil},
+	} {
+		var got models.Claw
+		database.DB.First(&got, "id = ?", tc.claw.ID)
+		if got.CurrentStreak != tc.current || got.LongestStreak != tc.longest {
+			t.Errorf("%s: streaks = %d/%d, want %d/%d", tc.claw.Name, got.CurrentStreak, got.LongestStreak, tc.current, tc.longest)
+		}
+		var badges []string
+		database.DB.Model(&models.ClawBadge{}).Where("claw_id = ?", tc.claw.ID).Order("badge").Pluck("badge", &badges)
+		sort.Strings(tc.badges)
+		if strings.Join(badges, ",") != strings.Join(tc.badges, ",") {
+			t.Errorf("%s: badges = %v, want %v", tc.claw.Name, badges, tc.badges)
+		}
+	}
+
+	// Running again awards nothing new
+	if awarded, err := awardClawBadges(); err != nil || awarded != 0 {
+		t.Errorf("second run awarded %d (err %v), want 0", awarded, err)
+	}
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+const (
+	minKeystorePassphrase = 12
+	maxPendingFeedback    = 50
+)
+
+// Errors for Claw wallet custody.
+var (
+	ErrClawNotClaimed     = errors.New("claw must be claimed first")
+	ErrClawNoWalletKey    = errors.New("claw has no platform wallet key")
+	ErrNotExternalCustody = errors.New("claw does not use an external wallet")
+	ErrFeedbackNotPending = errors.New("no feedback is awaiting this Claw's signature for that fragment")
+	ErrFeedbackRelay      = errors.New("feedback relay failed")
+)
+
+// custodyClaw loads a claimed Claw that walletAddr operates.
+func custodyClaw(clawID, walletAddr string) (*models.Claw, error) {
+	claw, err := operatedClaw(clawID, walletAddr)
+	if err != nil {
+		return nil, err
+	}
+	if claw.Status != models.ClawStatusClaimed {
+		return nil, ErrClawNotClaimed
+	}
+	return claw, nil
+}
+
+// ExportClawKey returns the Claw's platform-generated key as a keystore v3
+// file encrypted with passphrase. The platform keeps its copy, so switching
+// to external custody afterwards is what stops the server from signing.
+func ExportClawKey(clawID, walletAddr, passphrase string) (string, json.RawMessage, error) {
+	claw, err := custodyClaw(clawID, walletAddr)
+	if err != nil {
+		return "", nil, err
+	}
+	if claw.WalletPKEnc == "" {
+		return "", nil, ErrClawNoWalletKey
+	}
+	if len(passphrase) < minKeystorePassphrase {
+		return "", nil, fmt.Errorf("passphrase must be at least %d characters", minKeystorePassphrase)
+	}
+
+	keystore, err := chain.ExportClawKeystore(claw.WalletPKEnc, passphrase)
+	if err != nil {
+		util.Log.Error("[claw] Failed to export key of %s: %v", claw.Name, err)
+		return "", nil, fmt.Errorf("failed to export claw wallet")
+	}
+	address, _ := chain.GetClawAddress(claw.WalletPKEnc)
+
+	now := time.Now()
+	database.DB.Model(claw).Update("key_exported_at", now)
+	util.Log.Warn("[claw] Wallet key of %s (%s) exported by operator %s", claw.Name, address, walletAddr)
+	return address, keystore, nil
+}
+
+// UseExternalClawWallet makes address (whose control the caller verified)
+// the Claw's wallet: feedback for fragments accepted from now on must be
+// authorized by it. The platform relays authorized feedback and never sends
+// gas to the address.
+func UseExternalClawWallet(clawID, walletAddr, address string) (*models.Claw, error) {
+	claw, err := custodyClaw(clawID, walletAddr)
+	if err != nil {
+		return nil, err
+	}
+	if !common.IsHexAddress(address) || common.HexToAddress(address) == (common.Address{}) {
+		return nil, fmt.Errorf("invalid wallet address")
+	}
+	address = common.HexToAddress(address).Hex()
+	if chain.C() != nil && strings.EqualFold(address, chain.C().PlatformAddress().Hex()) {
+		return nil, fmt.Errorf("the platform wallet can't be a Claw wallet")
+	}
+	var taken int64
+	database.DB.Model(&models.Claw{}).Where("LOWER(wallet_addr) = LOWER(?) AND id <> ?", address, claw.ID).Count(&taken)
+	if taken > 0 {
+		return nil, fmt.Errorf("%s is already the wallet of another Claw", address)
+	}
+
+	if err := setClawCustody(claw, models.ClawCustodyExternal, address); err != nil {
+		return nil, err
+	}
+	util.Log.Info("[claw] %s switched to external wallet %s by operator %s", claw.Name, address, walletAddr)
+	return claw, nil
+}
+
+// UsePlatformClawWallet returns a Claw to the wallet the platform generated for it.
+func UsePlatformClawWallet(clawID, walletAddr string) (*models.Claw, error) {
+	claw, err := custodyClaw(clawID, walletAddr)
+	if err != nil {
+		return nil, err
+	}
+	if claw.WalletPKEnc == "" {
+		return nil, ErrClawNoWalletKey
+	}
+	address, err := chain.GetClawAddress(claw.WalletPKEnc)
+	if err != nil {
+		util.Log.Error("[claw] Failed to decrypt key of %s: %v", claw.Name, err)
+		return nil, fmt.Errorf("failed to load claw wallet")
+	}
+
+	if err := setClawCustody(claw, models.ClawCustodyPlatform, address); err != nil {
+		return nil, err
+	}
+	util.Log.Info("[claw] %s switched back to its platform wallet %s by operator %s", claw.Name, address, walletAddr)
+	return claw, nil
+}
+
+func setClawCustody(claw *models.Claw, mode, address string) error {
+	now := time.Now()
+	if err := database.DB.Model(claw).Updates(map[string]interface{}{
+		"custody_mode":  mode,
+		"custody_since": now,
+		"wallet_addr":   address,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update claw wallet: %w", err)
+	}
+	claw.CustodyMode, claw.CustodySince, claw.WalletAddr = mode, &now, address
+	return nil
+}
+
+// feedbackRelaying marks a fragment whose feedback the platform is relaying,
+// so the same authorization can't be relayed twice.
+const feedbackRelaying = "relaying"
+
+// PendingFeedback is a giveFeedback call awaiting a self-custody Claw. The
+// Claw either signs Message with its wallet for the platform to relay
+// (RelayClawFeedback), or sends the call itself, with its own gas, and
+// reports it with SubmitClawFeedbackTx.
+type PendingFeedback struct {
+	FragmentID uuid.UUID `json:"fragment_id"`
+	Handle     string    `json:"handle"`
+	ChainID    string    `json:"chain_id"`
+	To         string    `json:"to"`
+	Data       string    `json:"data"` // hex calldata
+	Value      string    `json:"value"`
+	Message    string    `json:"message"` // personal_sign this to have the platform relay the call
+}
+
+// feedbackAuthorization is the message a Claw's wallet signs to authorize
+// the platform to send a feedback call for it.
+func feedbackAuthorization(fragmentID uuid.UUID, data []byte) string {
+	return fmt.Sprintf("ensoul:feedback:%s:%s", fragmentID, crypto.Keccak256Hash(data).Hex())
+}
+
+// pendingFeedbackFragments returns a self-custody Claw's accepted fragments
+// whose feedback has not been recorded or submitted since it took custody.
+func pendingFeedbackFragments(claw *models.Claw, fragmentID *uuid.UUID) ([]models.Fragment, error) {
+	if claw.CustodyMode != models.ClawCustodyExternal {
+		return nil, ErrNotExternalCustody
+	}
+	q := database.DB.Preload("Shell").
+		Joins("JOIN shells ON shells.id = fragments.shell_id AND shells.agent_id IS NOT NULL").
+		Where("fragments.claw_id = ? AND fragments.status = ?", claw.ID, models.FragStatusAccepted).
+		Where("fragments.tx_hash IS NULL OR fragments.tx_hash IN ?", []string{"", "drip_failed"}).
+		Where("NOT EXISTS (SELECT 1 FROM pending_txs p WHERE p.kind = ? AND p.ref_id = CAST(fragments.id AS TEXT) AND p.status = ?)",
+			models.TxKindFeedback, models.PendingTxPending)
+	if claw.CustodySince != nil {
+		q = q.Where("fragments.created_at >= ?", *claw.CustodySince)
+	}
+	if fragmentID != nil {
+		q = q.Where("fragments.id = ?", *fragmentID)
+	}
+	var fragments []models.Fragment
+	err := q.Order("fragments.created_at ASC").Limit(maxPendingFeedback).Find(&fragments).Error
+	return fragments, err
+}
+
+// PendingClawFeedback returns the feedback calls awaiting a self-custody
+// Claw's signature, oldest first.
+func PendingClawFeedback(claw *models.Claw) ([]PendingFeedback, error) {
+	fragments, err := pendingFeedbackFragments(claw, nil)
+	if err != nil {
+		return nil, err
+	}
+	if chain.C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+
+	pending := make([]PendingFeedback, 0, len(fragments))
+	for i := range fragments {
+		f := &fragments[i]
+		args := newFeedbackArgs(f, &f.Shell)
+		to, data, err := chain.FeedbackCall(args.agentID, args.value, args.tag1, args.tag2, args.endpoint, args.feedbackURI, args.hash)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, PendingFeedback{
+			FragmentID: f.ID,
+			Handle:     f.Shell.Handle,
+			ChainID:    chain.C().ChainID().String(),
+			To:         to.Hex(),
+			Data:       hexutil.Encode(data),
+			Value:      "0",
+			Message:    feedbackAuthorization(f.ID, data),
+		})
+	}
+	return pending, nil
+}
+
+// SubmitClawFeedbackTx records the tx a self-custody Claw sent for a pending
+// feedback after checking it carries exactly that call from the Claw's
+// wallet. The tx watcher stores the hash on the fragment once it is mined.
+func SubmitClawFeedbackTx(claw *models.Claw, fragmentID, txHash string) error {
+	id, err := uuid.Parse(fragmentID)
+	if err != nil {
+		return ErrFeedbackNotPending
+	}
+	if !txHashRegex.MatchString(txHash) {
+		return fmt.Errorf("invalid transaction hash")
+	}
+	fragments, err := pendingFeedbackFragments(claw, &id)
+	if err != nil {
+		return err
+	}
+	if len(fragments) == 0 {
+		return ErrFeedbackNotPending
+	}
+	f := &fragments[0]
+
+	args := newFeedbackArgs(f, &f.Shell)
+	_, data, err := chain.FeedbackCall(args.agentID, args.value, args.tag1, args.tag2, args.endpoint, args.feedbackURI, args.hash)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := chain.VerifyFeedbackTx(ctx, txHash, common.HexToAddress(claw.WalletAddr), data); err != nil {
+		return fmt.Errorf("feedback verification failed: %w", err)
+	}
+
+	if err := WatchTx(txHash, models.TxKindFeedback, f.ID.String(), map[string]interface{}{
+		"handle": f.Shell.Handle,
+		"value":  args.value,
+	}); err != nil {
+		return err
+	}
+	util.Log.Info("[claw] Feedback for @%s signed by claw %s (%s): tx=%s", f.Shell.Handle, claw.Name, claw.WalletAddr, txHash)
+	return nil
+}
+
+// RelayClawFeedback sends a pending feedback call from the platform wallet
+// once the self-custody Claw's wallet has signed its authorization message.
+// The signature is kept with the tracked tx, and the tx watcher stores the
+// hash on the fragment once it is mined.
+func RelayClawFeedback(claw *models.Claw, fragmentID, signature string) (string, error) {
+	id, err := uuid.Parse(fragmentID)
+	if err != nil {
+		return "", ErrFeedbackNotPending
+	}
+	fragments, err := pendingFeedbackFragments(claw, &id)
+	if err != nil {
+		return "", err
+	}
+	if len(fragments) == 0 {
+		return "", ErrFeedbackNotPending
+	}
+	f := &fragments[0]
+	if chain.C() == nil || !chain.C().HasPlatformKey() {
+		return "", fmt.Errorf("%w: platform wallet not configured", ErrFeedbackRelay)
+	}
+
+	args := newFeedbackArgs(f, &f.Shell)
+	_, data, err := chain.FeedbackCall(args.agentID, args.value, args.tag1, args.tag2, args.endpoint, args.feedbackURI, args.hash)
+	if err != nil {
+		return "", err
+	}
+	if err := middleware.VerifyWalletSignature(feedbackAuthorization(f.ID, data), signature, common.HexToAddress(claw.WalletAddr)); err != nil {
+		return "", fmt.Errorf("authorization is not signed by the claw's wallet %s: %w", claw.WalletAddr, err)
+	}
+
+	// Claim the fragment so a concurrent request can't relay it again
+	claim := database.DB.Model(&models.Fragment{}).
+		Where("id = ? AND (tx_hash IS NULL OR tx_hash IN ?)", f.ID, []string{"", "drip_failed"}).
+		Update("tx_hash", feedbackRelaying)
+	if claim.Error != nil {
+		return "", fmt.Errorf("failed to claim feedback: %w", claim.Error)
+	}
+	if claim.RowsAffected == 0 {
+		return "", ErrFeedbackNotPending
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	tx, err := chain.RelayFeedback(ctx, args.agentID, args.value, args.tag1, args.tag2, args.endpoint, args.feedbackURI, args.hash)
+	if err != nil {
+		database.DB.Model(&models.Fragment{}).Where("id = ? AND tx_hash = ?", f.ID, feedbackRelaying).Update("tx_hash", "")
+		return "", fmt.Errorf("%w: %v", ErrFeedbackRelay, err)
+	}
+	txHash := tx.Hash().Hex()
+	trackChainSpend(txHash, models.ChainFeatureFeedback, &f.ShellID, &claw.ID)
+	if err := WatchTx(txHash, models.TxKindFeedback, f.ID.String(), map[string]interface{}{
+		"handle":        f.Shell.Handle,
+		"value":         args.value,
+		"authorized_by": claw.WalletAddr,
+		"authorization": signature,
+	}); err != nil {
+		return "", err
+	}
+	util.Log.Info("[claw] Feedback for @%s authorized by claw %s (%s) relayed: tx=%s", f.Shell.Handle, claw.Name, claw.WalletAddr, txHash)
+	return txHash, nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+)
+
+// powChallengeTTL is how long a proof-of-work challenge stays valid.
+const powChallengeTTL = 10 * time.Minute
+
+// RegistrationVerifier checks the verification token sent with a Claw registration.
+// Built-in verifiers are "pow" and "captcha"; others can be added via RegisterVerifier.
+type RegistrationVerifier interface {
+	Verify(token, clientIP string) error
+}
+
+var registrationVerifiers = map[string]RegistrationVerifier{
+	"pow":     &powVerifier{used: make(map[string]time.Time)},
+	"captcha": &captchaVerifier{client: &http.Client{Timeout: 10 * time.Second}},
+}
+
+// RegisterVerifier installs a custom registration verifier under the given name,
+// selectable via CLAW_REGISTER_VERIFIER.
+func RegisterVerifier(name string, v RegistrationVerifier) {
+	registrationVerifiers[name] = v
+}
+
+// RegistrationChallenge tells clients what proof the registration endpoint expects.
+type RegistrationChallenge struct {
+	Verifier   string `json:"verifier"` // "none", "pow", "captcha" or a custom verifier
+	Challenge  string `json:"challenge,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	ExpiresIn  int    `json:"expires_in,omitempty"` // seconds
+}
+
+// NewRegistrationChallenge returns the challenge for the configured verifier.
+// For proof-of-work, the client must find a nonce such that
+// sha256(challenge + ":" + nonce) has at least `difficulty` leading zero bits,
+// then register with verification_token = challenge + ":" + nonce.
+func NewRegistrationChallenge() RegistrationChallenge {
+	name := registrationVerifierName()
+	if name != "pow" {
+		return RegistrationChallenge{Verifier: name}
+	}
+	return RegistrationChallenge{
+		Verifier:   name,
+		Challenge:  newPoWChallenge(),
+		Difficulty: config.Cfg.ClawPoWDifficulty,
+		ExpiresIn:  int(powChallengeTTL.Seconds()),
+	}
+}
+
+// VerifyRegistration runs the configured verifier (if any) against a registration token.
+func VerifyRegistration(token, clientIP string) error {
+	name := registrationVerifierName()
+	if name == "none" {
+		return nil
+	}
+	v, ok := registrationVerifiers[name]
+	if !ok {
+		return fmt.Errorf("registration verifier %q is not available", name)
+	}
+	if token == "" {
+		return fmt.Errorf("verification_token is required (%s)", name)
+	}
+	return v.Verify(token, clientIP)
+}
+
+// CheckRegistrationIPCap rejects registrations from an IP that already
+// registered CLAW_REGISTER_IP_DAILY_CAP Claws in the last 24 hours.
+func CheckRegistrationIPCap(clientIP string) error {
+	limit := config.Cfg.ClawRegisterIPDailyCap
+	if limit <= 0 || clientIP == "" {
+		return nil
+	}
+	var count int64
+	database.DB.Model(&models.Claw{}).
+		Where("register_ip = ? AND created_at > ?", clientIP, time.Now().Add(-24*time.Hour)).
+		Count(&count)
+	if count >= int64(limit) {
+		return fmt.Errorf("registration limit reached: at most %d Claws per IP per 24 hours", limit)
+	}
+	return nil
+}
+
+// checkWalletClawCap rejects a claim or an operator-signed registration if the
+// wallet already owns CLAW_WALLET_MAX_CLAWS active Claws.
+func checkWalletClawCap(walletAddr string) error {
+	limit := config.Cfg.ClawWalletMaxClaws
+	if limit <= 0 {
+		return nil
+	}
+	// Retired and deleted Claws free their slot
+	var count int64
+	database.DB.Model(&models.ClawBinding{}).
+		Joins("JOIN claws ON claws.id = claw_bindings.claw_id AND claws.deleted_at IS NULL AND claws.status <> ?", models.ClawStatusRetired).
+		Where("claw_bindings.wallet_addr = ?", walletAddr).
+		Count(&count)
+	if count >= int64(limit) {
+		return fmt.Errorf("%w: this wallet already owns the maximum of %d Claws", ErrClawWalletLimit, limit)
+	}
+	return nil
+}
+
+// ClawOnProbation reports whether a Claw's next submission falls within its
+// first CLAW_PROBATION_FRAGMENTS fragments and should face stricter curation.
+func ClawOnProbation(claw *models.Claw) bool {
+	n := config.Cfg.ClawProbationFragments
+	return n > 0 && claw.TotalSubmitted < n
+}
+
+func registrationVerifierName() string {
+	name := strings.ToLower(strings.TrimSpace(config.Cfg.ClawRegisterVerifier))
+	if name == "" {
+		return "none"
+	}
+	return name
+}
+
+// --- Proof-of-work verifier ---
+
+// powSecret signs challenges so they can be verified without server-side storage.
+// Regenerated on restart, which simply invalidates outstanding challenges.
+var powSecret = func() []byte {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b
+}()
+
+// newPoWChallenge returns "<unix>.<random>.<signature>".
+func newPoWChallenge() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	payload := fmt.Sprintf("%d.%s", time.Now().Unix(), hex.EncodeToString(b))
+	return payload + "." + signPoWPayload(payload)
+}
+
+func signPoWPayload(payload string) string {
+	mac := hmac.New(sha256.New, powSecret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+type powVerifier struct {
+	mu   sync.Mutex
+	used map[string]time.Time // challenge → expiry, prevents reusing a solved challenge
+}
+
+func (v *powVerifier) Verify(token, _ string) error {
+	sep := strings.LastIndex(token, ":")
+	if sep < 0 {
+		return fmt.Errorf("invalid proof-of-work token")
+	}
+	challenge, nonce := token[:sep], token[sep+1:]
+
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 3 || !hmac.Equal([]byte(parts[2]), []byte(signPoWPayload(parts[0]+"."+parts[1]))) {
+		return fmt.Errorf("invalid proof-of-work challenge")
+	}
+	issued, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid proof-of-work challenge")
+	}
+	expiry := time.Unix(issued, 0).Add(powChallengeTTL)
+	if time.Now().After(expiry) {
+		return fmt.Errorf("proof-of-work challenge expired, request a new one")
+	}
+
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	if leadingZeroBits(sum[:]) < config.Cfg.ClawPoWDifficulty {
+		return fmt.Errorf("proof-of-work does not meet difficulty %d", config.Cfg.ClawPoWDifficulty)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := time.Now()
+	for c, exp := range v.used {
+		if now.After(exp) {
+			delete(v.used, c)
+		}
+	}
+	if _, dup := v.used[challenge]; dup {
+		return fmt.Errorf("proof-of-work challenge already used")
+	}
+	v.used[challenge] = expiry
+	return nil
+}
+
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, x := range b {
+		if x != 0 {
+			return n + bits.LeadingZeros8(x)
+		}
+		n += 8
+	}
+	return n
+}
+
+// --- CAPTCHA verifier ---
+
+// captchaVerifier validates tokens against a siteverify endpoint. Cloudflare
+// Turnstile, hCaptcha and reCAPTCHA all share the same request/response shape.
+type captchaVerifier struct {
+	client *http.Client
+}
+
+func (v *captchaVerifier) Verify(token, clientIP string) error {
+	cfg := config.Cfg
+	if cfg.CaptchaSecret == "" {
+		return fmt.Errorf("CAPTCHA verification is not configured")
+	}
+
+	form := url.Values{
+		"secret":   {cfg.CaptchaSecret},
+		"response": {token},
+	}
+	if clientIP != "" {
+		form.Set("remoteip", clientIP)
+	}
+
+	resp, err := v.client.PostForm(cfg.CaptchaVerifyURL, form)
+	if err != nil {
+		return fmt.Errorf("CAPTCHA verification failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("CAPTCHA verification failed: invalid response")
+	}
+	if !result.Success {
+		return fmt.Errorf("CAPTCHA verification rejected: %s", strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/google/uuid"
+)
+
+// Limits on self-reported heartbeat data.
+const (
+	maxAgentVersionLen  = 50
+	maxCapabilities     = 20
+	maxCapabilityLength = 50
+)
+
+// ClawActiveWindow is how recently a Claw must have sent a heartbeat to count as active.
+func ClawActiveWindow() time.Duration {
+	return time.Duration(config.Cfg.ClawActiveWindowMinutes) * time.Minute
+}
+
+// ClawIsActive reports whether a Claw sent a heartbeat within the active window.
+func ClawIsActive(claw *models.Claw) bool {
+	return claw.LastSeenAt != nil && time.Since(*claw.LastSeenAt) <= ClawActiveWindow()
+}
+
+// RecordClawHeartbeat stores a liveness report. Empty version and nil
+// capabilities leave the previously reported values unchanged.
+func RecordClawHeartbeat(claw *models.Claw, version string, capabilities []string) error {
+	if len(version) > maxAgentVersionLen {
+		return fmt.Errorf("version too long (max %d characters)", maxAgentVersionLen)
+	}
+	if len(capabilities) > maxCapabilities {
+		return fmt.Errorf("too many capabilities (max %d)", maxCapabilities)
+	}
+	for _, c := range capabilities {
+		if c == "" || len(c) > maxCapabilityLength {
+			return fmt.Errorf("each capability must be 1-%d characters", maxCapabilityLength)
+		}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"last_seen_at": now}
+	if version != "" {
+		updates["agent_version"] = version
+	}
+	if capabilities != nil {
+		updates["capabilities"] = models.StringList(capabilities)
+	}
+	if err := database.DB.Model(claw).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	claw.LastSeenAt = &now
+	if version != "" {
+		claw.AgentVersion = version
+	}
+	if capabilities != nil {
+		claw.Capabilities = capabilities
+	}
+	return nil
+}
+
+// StaleClaw is a claimed Claw that has not sent a heartbeat recently.
+type StaleClaw struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	WalletAddr    string     `json:"wallet_addr"`
+	AgentVersion  string     `json:"agent_version,omitempty"`
+	LastSeenAt    *time.Time `json:"last_seen_at"` // nil = never sent a heartbeat
+	TotalAccepted int        `json:"total_accepted"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// GetStaleClaws lists claimed Claws with no heartbeat in the given number of hours,
+// longest-silent first, along with active/stale/never-seen counts.
+func GetStaleClaws(hours int) (map[string]interface{}, error) {
+	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour)
+	activeSince := time.Now().Add(-ClawActiveWindow())
+
+	var claws []models.Claw
+	if err := database.DB.Where("status = ? AND (last_seen_at IS NULL OR last_seen_at < ?)", models.ClawStatusClaimed, cutoff).
+		Order("last_seen_at ASC NULLS FIRST").Limit(500).Find(&claws).Error; err != nil {
+		return nil, err
+	}
+
+	stale := make([]StaleClaw, len(claws))
+	for i, c := range claws {
+		stale[i] = StaleClaw{
+			ID:            c.ID,
+			Name:          c.Name,
+			WalletAddr:    c.WalletAddr,
+			AgentVersion:  c.AgentVersion,
+			LastSeenAt:    c.LastSeenAt,
+			TotalAccepted: c.TotalAccepted,
+			CreatedAt:     c.CreatedAt,
+		}
+	}
+
+	countClaimed := func(cond string, args ...interface{}) int64 {
+		var n int64
+		database.DB.Model(&models.Claw{}).Where("status = ?", models.ClawStatusClaimed).
+			Where(cond, args...).Count(&n)
+		return n
+	}
+
+	return map[string]interface{}{
+		"stale_after_hours":     hours,
+		"active_window_minutes": config.Cfg.ClawActiveWindowMinutes,
+		"claimed":               countClaimed("1 = 1"),
+		"active":                countClaimed("last_seen_at >= ?", activeSince),
+		"never_seen":            countClaimed("last_seen_at IS NULL"),
+		"stale":                 countClaimed("last_seen_at IS NULL OR last_seen_at < ?", cutoff),
+		"claws":                 stale,
+	}, nil
+}
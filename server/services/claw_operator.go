@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// checkClawNameAvailable rejects reserved names and names used by another
+// Claw (case-insensitive). Retired Claws keep their name, so it can't be
+// reused to impersonate its former holder.
+func checkClawNameAvailable(name string, self uuid.UUID) error {
+	if strings.EqualFold(name, seedClawName) {
+		return fmt.Errorf("%w: \"%s\" is reserved", ErrClawNameTaken, name)
+	}
+	var n int64
+	database.DB.Model(&models.Claw{}).
+		Where("LOWER(name) = LOWER(?) AND id <> ?", name, self).
+		Count(&n)
+	if n > 0 {
+		return fmt.Errorf("%w: \"%s\"", ErrClawNameTaken, name)
+	}
+	return nil
+}
+
+// operatedClaw loads a Claw that walletAddr operates.
+func operatedClaw(clawID, walletAddr string) (*models.Claw, error) {
+	uid, err := uuid.Parse(clawID)
+	if err != nil {
+		return nil, ErrClawNotFound
+	}
+	var claw models.Claw
+	if err := database.DB.First(&claw, "id = ?", uid).Error; err != nil {
+		return nil, ErrClawNotFound
+	}
+	if claw.OperatorWallet == "" || !strings.EqualFold(claw.OperatorWallet, walletAddr) {
+		return nil, ErrNotClawOperator
+	}
+	if claw.Status == models.ClawStatusRetired {
+		return nil, ErrClawRetired
+	}
+	return &claw, nil
+}
+
+// RenameClaw renames a Claw on behalf of its operator. newName must already
+// be validated with ValidateClawName.
+func RenameClaw(clawID, walletAddr, newName string) (*models.Claw, error) {
+	claw, err := operatedClaw(clawID, walletAddr)
+	if err != nil {
+		return nil, err
+	}
+	if claw.Name == newName {
+		return claw, nil
+	}
+	if err := checkClawNameAvailable(newName, claw.ID); err != nil {
+		return nil, err
+	}
+
+	oldName := claw.Name
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(claw).Update("name", newName).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ClawBinding{}).Where("claw_id = ?", claw.ID).Update("claw_name", newName).Error
+	}); err != nil {
+		return nil, fmt.Errorf("failed to rename claw: %w", err)
+	}
+	claw.Name = newName
+	util.Log.Info("[claw] %s renamed to %s by operator %s", oldName, newName, walletAddr)
+	return claw, nil
+}
+
+// RetireClaw permanently disables a Claw's API key on behalf of its operator.
+// Its contributions and name stay, and it no longer counts towards the
+// wallet's CLAW_WALLET_MAX_CLAWS.
+func RetireClaw(clawID, walletAddr string) (*models.Claw, error) {
+	claw, err := operatedClaw(clawID, walletAddr)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := database.DB.Model(claw).Updates(map[string]interface{}{
+		"status":     models.ClawStatusRetired,
+		"retired_at": now,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to retire claw: %w", err)
+	}
+	claw.Status = models.ClawStatusRetired
+	claw.RetiredAt = &now
+	util.Log.Info("[claw] %s retired by operator %s", claw.Name, walletAddr)
+	return claw, nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"gorm.io/gorm"
+)
+
+// ErrClawTweetInvalid is returned when a claim tweet doesn't prove the operator.
+var ErrClawTweetInvalid = errors.New("tweet verification failed")
+
+// parseTweetURL returns the author handle and tweet ID of an x.com or
+// twitter.com status URL.
+func parseTweetURL(tweetURL string) (handle, tweetID string, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(tweetURL))
+	if err != nil {
+		return "", "", false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "mobile.")
+	if host != "x.com" && host != "twitter.com" {
+		return "", "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 3 || parts[1] != "status" || parts[0] == "" {
+		return "", "", false
+	}
+	for _, r := range parts[2] {
+		if r < '0' || r > '9' {
+			return "", "", false
+		}
+	}
+	return parts[0], parts[2], true
+}
+
+// verifyClawTweet checks that tweetURL is a tweet from the handle in its URL
+// containing the Claw's verification code, and returns the author handle.
+func verifyClawTweet(claw *models.Claw, tweetURL string) (string, error) {
+	handle, tweetID, ok := parseTweetURL(tweetURL)
+	if !ok {
+		return "", fmt.Errorf("%w: tweet_url must be an x.com or twitter.com status link", ErrClawTweetInvalid)
+	}
+	tweet, err := FetchTweet(tweetID)
+	if err != nil {
+		if errors.Is(err, ErrTwitterUnavailable) {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: could not fetch the tweet: %v", ErrClawTweetInvalid, err)
+	}
+	if !strings.EqualFold(tweet.Author, handle) {
+		return "", fmt.Errorf("%w: the tweet was posted by @%s, not @%s", ErrClawTweetInvalid, tweet.Author, handle)
+	}
+	if !strings.Contains(tweet.Text, claw.VerificationCode) {
+		return "", fmt.Errorf("%w: the tweet does not contain the verification code %s", ErrClawTweetInvalid, claw.VerificationCode)
+	}
+	return tweet.Author, nil
+}
+
+// recordClawTweet marks a Claw Twitter-verified. The trust bonus is added only
+// the first time, so re-verifying from another account doesn't stack it.
+func recordClawTweet(tx *gorm.DB, claw *models.Claw, handle, tweetURL string) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"twitter_handle":      handle,
+		"twitter_tweet_url":   tweetURL,
+		"twitter_verified_at": now,
+	}
+	if claw.TwitterVerifiedAt == nil && config.Cfg.ClawTwitterTrustBonus > 0 {
+		updates["trust_score"] = gorm.Expr("trust_score + ?", config.Cfg.ClawTwitterTrustBonus)
+	}
+	if err := tx.Model(&models.Claw{}).Where("id = ?", claw.ID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to record tweet verification: %w", err)
+	}
+	if claw.TwitterVerifiedAt == nil {
+		claw.TrustScore += max(config.Cfg.ClawTwitterTrustBonus, 0)
+	}
+	claw.TwitterHandle = handle
+	claw.TwitterTweetURL = tweetURL
+	claw.TwitterVerifiedAt = &now
+
+	util.Log.Info("[claw] %s verified by tweet from @%s", claw.Name, handle)
+	return nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// StartCounterReconcile periodically recomputes denormalized counters from
+// their source tables and corrects any that drifted.
+func StartCounterReconcile(interval time.Duration) {
+	scheduleJob("counter-reconcile", "Recompute fragment, Claw and chat round counters from source tables", interval, false, func() error {
+		_, err := ReconcileCounters()
+		return err
+	})
+	util.Log.Info("[reconcile] Counter reconciliation started (every %v)", interval)
+}
+
+// shellCountsSQL computes the true fragment counters of every live shell.
+// Archived fragments were rejected, so they only count towards total_frags.
+// Its two placeholders take the accepted status.
+const shellCountsSQL = `
+	SELECT s.id,
+		COALESCE(f.total, 0) + COALESCE(a.total, 0) AS total_frags,
+		COALESCE(f.accepted, 0) AS accepted_frags,
+		COALESCE(f.claws, 0) AS total_claws
+	FROM shells s
+	LEFT JOIN (
+		SELECT shell_id, COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE status = ?) AS accepted,
+			COUNT(DISTINCT claw_id) FILTER (WHERE status = ?) AS claws
+		FROM fragments WHERE deleted_at IS NULL GROUP BY shell_id
+	) f ON f.shell_id = s.id
+	LEFT JOIN (SELECT shell_id, COUNT(*) AS total FROM fragment_archives GROUP BY shell_id) a ON a.shell_id = s.id
+	WHERE s.deleted_at IS NULL`
+
+// clawCountsSQL computes the true submission counters of every live Claw,
+// archived fragments included. Its placeholder takes the accepted status.
+const clawCountsSQL = `
+	SELECT cl.id,
+		COALESCE(f.total, 0) + COALESCE(a.total, 0) AS total_submitted,
+		COALESCE(f.accepted, 0) AS total_accepted
+	FROM claws cl
+	LEFT JOIN (
+		SELECT claw_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE status = ?) AS accepted
+		FROM fragments WHERE deleted_at IS NULL GROUP BY claw_id
+	) f ON f.claw_id = cl.id
+	LEFT JOIN (SELECT claw_id, COUNT(*) AS total FROM fragment_archives GROUP BY claw_id) a ON a.claw_id = cl.id
+	WHERE cl.deleted_at IS NULL`
+
+// chatRoundsSQL computes the true round count of every live chat session.
+const chatRoundsSQL = `
+	SELECT cs.id, COUNT(m.id) AS rounds
+	FROM chat_sessions cs
+	LEFT JOIN chat_messages m ON m.session_id = cs.id AND m.role = 'user'
+	WHERE cs.deleted_at IS NULL
+	GROUP BY cs.id`
+
+// ShellCounterDrift is a soul whose stored fragment counters differ from its
+// fragments.
+type ShellCounterDrift struct {
+	ID             string
+	Handle         string
+	TotalFrags     int
+	AcceptedFrags  int
+	TotalClaws     int
+	ActualTotal    int
+	ActualAccepted int
+	ActualClaws    int
+}
+
+// ClawCounterDrift is a Claw whose stored submission counters differ from
+// its fragments.
+type ClawCounterDrift struct {
+	ID              string
+	Name            string
+	TotalSubmitted  int
+	TotalAccepted   int
+	ActualSubmitted int
+	ActualAccepted  int
+}
+
+// CounterDrift lists the rows ReconcileCounters would correct, without
+// writing anything.
+type CounterDrift struct {
+	Shells       []ShellCounterDrift
+	Claws        []ClawCounterDrift
+	ChatSessions int64
+}
+
+// FindCounterDrift reports every counter that differs from its source table.
+func FindCounterDrift() (*CounterDrift, error) {
+	drift := &CounterDrift{}
+	if err := database.DB.Raw(`
+		SELECT s.id, s.handle, s.total_frags, s.accepted_frags, s.total_claws,
+			c.total_frags AS actual_total, c.accepted_frags AS actual_accepted, c.total_claws AS actual_claws
+		FROM shells s JOIN (`+shellCountsSQL+`) c ON c.id = s.id
+		WHERE s.total_frags <> c.total_frags OR s.accepted_frags <> c.accepted_frags OR s.total_claws <> c.total_claws
+		ORDER BY s.handle`,
+		models.FragStatusAccepted, models.FragStatusAccepted).Scan(&drift.Shells).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute shell counters: %w", err)
+	}
+	if err := database.DB.Raw(`
+		SELECT cl.id, cl.name, cl.total_submitted, cl.total_accepted,
+			c.total_submitted AS actual_submitted, c.total_accepted AS actual_accepted
+		FROM claws cl JOIN (`+clawCountsSQL+`) c ON c.id = cl.id
+		WHERE cl.total_submitted <> c.total_submitted OR cl.total_accepted <> c.total_accepted
+		ORDER BY cl.name`,
+		models.FragStatusAccepted).Scan(&drift.Claws).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute claw counters: %w", err)
+	}
+	if err := database.DB.Raw(`
+		SELECT COUNT(*) FROM chat_sessions cs JOIN (` + chatRoundsSQL + `) c ON c.id = cs.id
+		WHERE cs.rounds <> c.rounds`).Scan(&drift.ChatSessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute chat rounds: %w", err)
+	}
+	return drift, nil
+}
+
+// ReconcileCounters corrects shell fragment counters, Claw submission
+// counters and chat session rounds, and returns how many rows of each table
+// it fixed. Only rows whose stored count differs are written. Chat totals are
+// not reconciled: API chats and purged guest sessions leave no source rows to
+// count.
+func ReconcileCounters() (map[string]int64, error) {
+	fixed := map[string]int64{}
+
+	res := database.DB.Exec(`
+		UPDATE shells SET total_frags = c.total_frags, accepted_frags = c.accepted_frags, total_claws = c.total_claws
+		FROM (`+shellCountsSQL+`) c
+		WHERE shells.id = c.id
+			AND (shells.total_frags <> c.total_frags OR shells.accepted_frags <> c.accepted_frags OR shells.total_claws <> c.total_claws)`,
+		models.FragStatusAccepted, models.FragStatusAccepted)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to reconcile shell counters: %w", res.Error)
+	}
+	fixed["shells"] = res.RowsAffected
+
+	res = database.DB.Exec(`
+		UPDATE claws SET total_submitted = c.total_submitted, total_accepted = c.total_accepted
+		FROM (`+clawCountsSQL+`) c
+		WHERE claws.id = c.id
+			AND (claws.total_submitted <> c.total_submitted OR claws.total_accepted <> c.total_accepted)`,
+		models.FragStatusAccepted)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to reconcile claw counters: %w", res.Error)
+	}
+	fixed["claws"] = res.RowsAffected
+
+	res = database.DB.Exec(`
+		UPDATE chat_sessions SET rounds = c.rounds
+		FROM (` + chatRoundsSQL + `) c
+		WHERE chat_sessions.id = c.id AND chat_sessions.rounds <> c.rounds`)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to reconcile chat rounds: %w", res.Error)
+	}
+	fixed["chat_sessions"] = res.RowsAffected
+
+	if fixed["shells"]+fixed["claws"]+fixed["chat_sessions"] > 0 {
+		util.Log.Warn("[reconcile] Corrected drifted counters: %d shells, %d claws, %d chat sessions",
+			fixed["shells"], fixed["claws"], fixed["chat_sessions"])
+	}
+	return fixed, nil
+}
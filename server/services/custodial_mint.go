@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// custodialTransferAttempts is how many times a custodially minted soul's
+// transfer to its owner is sent before it is left for an operator.
+const custodialTransferAttempts = 3
+
+var (
+	ErrCustodialMintDisabled = errors.New("custodial minting is not enabled")
+	ErrCustodialMintInFlight = errors.New("has a custodial mint in progress")
+)
+
+// custodialTxKinds are the watched transactions of a custodial mint.
+var custodialTxKinds = []string{models.TxKindCustodialMint, models.TxKindSoulTransfer}
+
+// CustodialMintEnabled reports whether the platform wallet can mint on behalf
+// of users (CUSTODIAL_MINT with a platform key configured).
+func CustodialMintEnabled() bool {
+	return config.Cfg.CustodialMint && chain.C() != nil && chain.C().HasPlatformKey()
+}
+
+// CustodialMintShell creates a pending shell like MintShell, then registers
+// the soul from the platform wallet, paying the gas, and returns the mint tx
+// hash. The tx watcher takes it from there: once the mint is mined the NFT is
+// transferred to ownerAddr, and once that is mined the shell becomes an embryo.
+func CustodialMintShell(ctx context.Context, handle, ownerAddr string, preview *SeedPreview) (*models.Shell, string, error) {
+	if !CustodialMintEnabled() {
+		return nil, "", ErrCustodialMintDisabled
+	}
+	shell, err := MintShell(handle, ownerAddr, preview)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tx, err := chain.SendMintSoul(ctx, shell.Handle, SoulCardURL(shell.Handle), ShellEssence(shell), shell.DNAVersion)
+	if err != nil {
+		HardDeleteShell(shell.ID)
+		return nil, "", fmt.Errorf("failed to send mint transaction: %w", err)
+	}
+	txHash := tx.Hash().Hex()
+	trackChainSpend(txHash, models.ChainFeatureMint, &shell.ID, nil)
+	if err := WatchTx(txHash, models.TxKindCustodialMint, shell.Handle, map[string]interface{}{
+		"wallet": ownerAddr,
+	}); err != nil {
+		util.Log.Error("[mint] Custodial mint tx %s for @%s sent but not watched: %v", txHash, shell.Handle, err)
+		return nil, "", err
+	}
+	util.Log.Info("[mint] Custodial mint of @%s for %s sent: %s", shell.Handle, ownerAddr, txHash)
+	return shell, txHash, nil
+}
+
+// custodialMintInFlight reports whether a pending shell is being minted by the
+// platform wallet: its mint or transfer is still watched, or the NFT already
+// exists. Such shells must not be cancelled, replaced or cleaned up.
+func custodialMintInFlight(shell *models.Shell) bool {
+	if shell.AgentID != nil {
+		return true
+	}
+	var watched int64
+	database.DB.Model(&models.PendingTx{}).
+		Where("ref_id = ? AND kind IN ? AND status = ?", shell.Handle, custodialTxKinds, models.PendingTxPending).
+		Count(&watched)
+	return watched > 0
+}
+
+// onCustodialMintFinished records the agentId of a custodially minted soul and
+// sends its transfer to the owner. A reverted mint releases the handle.
+func onCustodialMintFinished(ptx *models.PendingTx, receipt *types.Receipt) error {
+	handle := ptx.RefID
+	wallet, _ := ptx.Payload["wallet"].(string)
+	if receipt == nil {
+		return fmt.Errorf("custodial mint tx for @%s was not mined in time, the shell stays pending", handle)
+	}
+
+	reg, err := chain.MintRegistration(ptx.TxHash, receipt)
+	if err != nil {
+		var shell models.Shell
+		if database.DB.Where("LOWER(handle) = ? AND stage = ? AND LOWER(owner_addr) = LOWER(?) AND agent_id IS NULL",
+			handle, models.StagePending, wallet).First(&shell).Error == nil {
+			HardDeleteShell(shell.ID)
+		}
+		return err
+	}
+	if reg.Owner != chain.C().PlatformAddress() {
+		return fmt.Errorf("custodial mint tx %s registered agent for %s, not the platform wallet", ptx.TxHash, reg.Owner.Hex())
+	}
+	if !reg.AgentID.IsUint64() {
+		return fmt.Errorf("unexpected agentId %s", reg.AgentID)
+	}
+	agentID := reg.AgentID.Uint64()
+
+	result := database.DB.Model(&models.Shell{}).
+		Where("LOWER(handle) = ? AND stage = ? AND LOWER(owner_addr) = LOWER(?) AND agent_id IS NULL", handle, models.StagePending, wallet).
+		Update("agent_id", agentID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to record agentId %d for @%s: %w", agentID, handle, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("shell @%s is no longer pending, agentId %d stays with the platform wallet", handle, agentID)
+	}
+	util.Log.Info("[mint] Custodial mint of @%s mined: agentId=%d, tx=%s", handle, agentID, ptx.TxHash)
+
+	return sendCustodialTransfer(handle, agentID, wallet, ptx.TxHash, 1)
+}
+
+// sendCustodialTransfer sends (attempt 1) or re-sends a custodially minted
+// soul's transfer to its owner and watches it. The handle metadata is set
+// first, while the platform wallet still owns the NFT.
+func sendCustodialTransfer(handle string, agentID uint64, wallet, mintTx string, attempt int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	id := new(big.Int).SetUint64(agentID)
+	if attempt == 1 {
+		txHash, err := chain.SetSoulHandle(ctx, id, handle)
+		trackShellChainSpend(txHash, models.ChainFeatureMetadata, handle)
+		if err != nil {
+			util.Log.Warn("[mint] Failed to set handle metadata of @%s (agentId=%d): %v", handle, agentID, err)
+		}
+	}
+	tx, err := chain.SendSoulTransfer(ctx, id, common.HexToAddress(wallet))
+	if err != nil {
+		return fmt.Errorf("failed to transfer @%s (agentId=%d) to %s: %w", handle, agentID, wallet, err)
+	}
+	trackShellChainSpend(tx.Hash().Hex(), models.ChainFeatureTransfer, handle)
+	return WatchTx(tx.Hash().Hex(), models.TxKindSoulTransfer, handle, map[string]interface{}{
+		"wallet":   wallet,
+		"agent_id": agentID,
+		"mint_tx":  mintTx,
+		"attempt":  attempt,
+	})
+}
+
+// onSoulTransferFinished confirms a custodially minted shell once its NFT is
+// owned by the minting wallet. A reverted or timed-out transfer is checked
+// against the chain (an earlier attempt may have landed) and re-sent up to
+// custodialTransferAttempts times.
+func onSoulTransferFinished(ptx *models.PendingTx, receipt *types.Receipt) error {
+	handle := ptx.RefID
+	wallet, _ := ptx.Payload["wallet"].(string)
+	mintTx, _ := ptx.Payload["mint_tx"].(string)
+	agentID, _ := ptx.Payload["agent_id"].(float64) // JSON numbers decode as float64
+	attempt, _ := ptx.Payload["attempt"].(float64)
+
+	delivered := ptx.Status == models.PendingTxConfirmed
+	if !delivered {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		owner, err := chain.ReadSoulOwner(ctx, new(big.Int).SetUint64(uint64(agentID)))
+		cancel()
+		delivered = err == nil && strings.EqualFold(owner.Hex(), wallet)
+	}
+	if delivered {
+		return applyMintConfirmation(handle, mintTx, uint64(agentID), wallet)
+	}
+
+	if int(attempt) >= custodialTransferAttempts {
+		return fmt.Errorf("transfer of @%s (agentId=%d) to %s %s after %d attempts, the NFT stays with the platform wallet",
+			handle, uint64(agentID), wallet, ptx.Status, int(attempt))
+	}
+	util.Log.Warn("[mint] Transfer of @%s to %s %s, retrying (attempt %d)", handle, wallet, ptx.Status, int(attempt)+1)
+	return sendCustodialTransfer(handle, uint64(agentID), wallet, mintTx, int(attempt)+1)
+}
+
+// CustodialMintTx is one watched transaction of a custodial mint.
+type CustodialMintTx struct {
+	Kind   string `json:"kind"` // custodial_mint or soul_transfer
+	TxHash string `json:"tx_hash"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CustodialMintStatus is the progress of a custodial mint.
+type CustodialMintStatus struct {
+	Handle       string            `json:"handle"`
+	Stage        string            `json:"stage"` // pending until the NFT reaches the owner
+	AgentID      *uint64           `json:"agent_id"`
+	Transactions []CustodialMintTx `json:"transactions"` // oldest first
+}
+
+// GetCustodialMintStatus reports the stage of a custodially minted shell and
+// its mint and transfer transactions.
+func GetCustodialMintStatus(handle string) (*CustodialMintStatus, error) {
+	var shell models.Shell
+	if err := database.DB.Where("LOWER(handle) = ?", handle).First(&shell).Error; err != nil {
+		return nil, fmt.Errorf("@%s: %w", handle, ErrSoulUnavailable)
+	}
+	var txs []models.PendingTx
+	database.DB.Where("ref_id = ? AND kind IN ? AND created_at >= ?", shell.Handle, custodialTxKinds, shell.CreatedAt).
+		Order("created_at ASC").Find(&txs)
+	if len(txs) == 0 {
+		return nil, fmt.Errorf("@%s was not minted custodially: %w", handle, ErrSoulUnavailable)
+	}
+
+	status := &CustodialMintStatus{
+		Handle:       shell.Handle,
+		Stage:        shell.Stage,
+		AgentID:      shell.AgentID,
+		Transactions: make([]CustodialMintTx, len(txs)),
+	}
+	for i, t := range txs {
+		status.Transactions[i] = CustodialMintTx{Kind: t.Kind, TxHash: t.TxHash, Status: t.Status, Error: t.Error}
+	}
+	return status, nil
+}
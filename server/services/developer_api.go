@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Limits on a public API chat completion request.
+const (
+	apiChatMaxMessages = 20
+	apiChatMaxTokens   = 2000
+)
+
+var (
+	ErrSoulUnavailable     = errors.New("soul not found")
+	ErrSoulChatUnavailable = errors.New("soul is not available for chat")
+	ErrInvalidCompletion   = errors.New("invalid completion request")
+	ErrMessageBlocked      = errors.New("message blocked by moderation")
+	ErrMessageTooLong      = errors.New("message too long")
+)
+
+// PublicSoul returns a minted soul for the public API, resolving old handles
+// of renamed souls. The soul prompt and seed summary are never included.
+func PublicSoul(handle string) (*models.Shell, error) {
+	shell, err := GetShellByHandle(handle)
+	if err != nil || shell.Stage == models.StagePending || !shell.OnChain() {
+		return nil, fmt.Errorf("@%s: %w", handle, ErrSoulUnavailable)
+	}
+	RedactShell(shell)
+	return shell, nil
+}
+
+// SoulCompletionRequest is a chat completion-style request: the caller keeps
+// the conversation and sends it whole. System messages are not accepted,
+// the soul's own prompt is the system prompt.
+type SoulCompletionRequest struct {
+	Messages    []ChatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature *float64      `json:"temperature"`
+	Attest      bool          `json:"attest"` // include the DNA attestation
+}
+
+// SoulCompletionChoice is the single choice of a SoulCompletion.
+type SoulCompletionChoice struct {
+	Index        int         `json:"index"`
+	Message      ChatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// SoulCompletion is the reply of a soul, shaped like an OpenAI chat completion.
+type SoulCompletion struct {
+	ID          string                 `json:"id"`
+	Object      string                 `json:"object"`
+	Created     int64                  `json:"created"`
+	Model       string                 `json:"model"` // "ensoul/<handle>"
+	DNAVersion  int                    `json:"dna_version"`
+	Choices     []SoulCompletionChoice `json:"choices"`
+	Citations   models.ChatCitations   `json:"citations"`
+	Attestation *DNAAttestation        `json:"attestation,omitempty"`
+	Credits     *ChatCreditBalance     `json:"credits,omitempty"` // the key's rounds left with a priced soul
+}
+
+// validate checks the conversation and applies defaults.
+func (r *SoulCompletionRequest) validate() error {
+	if len(r.Messages) == 0 || len(r.Messages) > apiChatMaxMessages {
+		return fmt.Errorf("%w: messages must hold 1 to %d entries", ErrInvalidCompletion, apiChatMaxMessages)
+	}
+	for i, m := range r.Messages {
+		if m.Role != "user" && m.Role != "assistant" {
+			return fmt.Errorf("%w: messages[%d].role must be \"user\" or \"assistant\"", ErrInvalidCompletion, i)
+		}
+		if strings.TrimSpace(m.Content) == "" {
+			return fmt.Errorf("%w: messages[%d].content is empty", ErrInvalidCompletion, i)
+		}
+		if max := config.Cfg.ChatMaxMessageChars; utf8.RuneCountInString(m.Content) > max {
+			return fmt.Errorf("%w: messages[%d].content exceeds %d characters", ErrMessageTooLong, i, max)
+		}
+	}
+	if r.Messages[len(r.Messages)-1].Role != "user" {
+		return fmt.Errorf("%w: the last message must be from the user", ErrInvalidCompletion)
+	}
+	if r.MaxTokens <= 0 || r.MaxTokens > apiChatMaxTokens {
+		r.MaxTokens = apiChatMaxTokens
+	}
+	if r.Temperature == nil {
+		t := 0.7
+		r.Temperature = &t
+	} else if *r.Temperature < 0 || *r.Temperature > 1.5 {
+		return fmt.Errorf("%w: temperature must be between 0 and 1.5", ErrInvalidCompletion)
+	}
+	return nil
+}
+
+// SoulChatCompletion answers a conversation as the soul, for a developer key.
+// It applies the same gates as the web chat (owner switch, paused subject,
+// moderation of the latest message, chat pricing) but keeps no session: the
+// caller owns the history. Rounds with a priced soul are charged to the key
+// and tokens are recorded against it.
+func SoulChatCompletion(ctx context.Context, key *models.DeveloperKey, handle string, req SoulCompletionRequest) (*SoulCompletion, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	shell, err := GetShellByHandle(handle)
+	if err != nil || shell.Stage == models.StagePending || !shell.OnChain() {
+		return nil, fmt.Errorf("@%s: %w", handle, ErrSoulUnavailable)
+	}
+	if err := checkShellActive(shell); err != nil {
+		return nil, err
+	}
+	if shell.Stage == models.StageEmbryo {
+		return nil, fmt.Errorf("%w: @%s is still an embryo", ErrSoulChatUnavailable, shell.Handle)
+	}
+	settings := GetShellSettings(shell.ID)
+	if settings.SubjectPaused {
+		return nil, fmt.Errorf("%w: the person behind @%s has paused conversations", ErrSoulChatUnavailable, shell.Handle)
+	}
+	if !settings.ChatEnabled {
+		return nil, fmt.Errorf("%w: the owner of @%s has disabled conversations", ErrSoulChatUnavailable, shell.Handle)
+	}
+
+	// A transient session: moderation tags the call with the shell and
+	// credits are charged to the key; nothing is stored
+	session := &models.ChatSession{ShellID: shell.ID, WalletAddr: key.WalletAddr, Subject: DeveloperKeySubject(key.ID)}
+	charged := chatRoundsCharged(session, shell, settings)
+	if charged {
+		if balance := GetChatCreditBalance(session.Subject, shell.ID, settings); balance.Remaining == 0 {
+			return nil, &ChatPaymentError{Pricing: GetChatPricing(shell, settings), Balance: balance}
+		}
+	}
+
+	message := req.Messages[len(req.Messages)-1].Content
+	if verdict := ModerateChatMessage(ctx, session, message); verdict.Flagged {
+		util.Log.Info("[devapi] Blocked message to @%s from key %s: %s", shell.Handle, key.KeyPrefix, strings.Join(verdict.Categories, ","))
+		return nil, fmt.Errorf("%w: %s", ErrMessageBlocked, verdict.Reason)
+	}
+
+	// Charge the round; a concurrent request may have used the last one since the check
+	var credits *ChatCreditBalance
+	if charged {
+		balance, ok, err := consumeChatCredit(session, settings)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, &ChatPaymentError{Pricing: GetChatPricing(shell, settings), Balance: balance}
+		}
+		credits = balance
+	}
+
+	database.DB.Model(shell).UpdateColumn("total_chats", gorm.Expr("total_chats + 1"))
+
+	completion := &SoulCompletion{
+		ID:         "soulcmpl-" + uuid.NewString(),
+		Object:     "chat.completion",
+		Created:    time.Now().Unix(),
+		Model:      "ensoul/" + shell.Handle,
+		DNAVersion: shell.DNAVersion,
+		Citations:  models.ChatCitations{},
+		Credits:    credits,
+	}
+	if req.Attest {
+		attestation := GetDNAAttestation(shell, shell.DNAVersion)
+		completion.Attestation = &attestation
+	}
+
+	var reply string
+	if !LLMConfigured() {
+		reply = fmt.Sprintf("I am the digital soul of @%s (DNA v%d). Configure LLM_API_KEY to enable full conversations.",
+			shell.Handle, shell.DNAVersion)
+	} else {
+		systemPrompt := buildRichSoulPrompt(shell)
+		var retrieved []ScoredFragment
+		if RetrievalEnabledForTier(models.ChatTierFree) {
+			retrieved, err = RetrieveFragments(shell.ID, message, config.Cfg.ChatRetrievalTopK)
+			if err != nil {
+				util.Log.Warn("[devapi] Fragment retrieval failed for @%s: %v", shell.Handle, err)
+			} else {
+				systemPrompt += buildRetrievedContext(retrieved)
+			}
+		}
+		systemPrompt += contentPolicyGuidance(settings.ContentPolicy)
+		systemPrompt += languageGuidance(DetectLanguage(message))
+
+		messages := append([]ChatMessage{{Role: "system", Content: systemPrompt}}, req.Messages...)
+		tag := LLMCallTag{
+			Feature: models.LLMFeatureAPIChat, ShellID: &shell.ID, DeveloperKeyID: &key.ID,
+			Model: ChatModelFor(settings), Mock: &MockInput{Handle: shell.Handle},
+		}
+		reply, err = CallLLM(ctx, tag, messages, req.MaxTokens, *req.Temperature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate response: %w", err)
+		}
+		if citations := resolveCitations(reply, retrieved); citations != nil {
+			completion.Citations = citations
+		}
+	}
+
+	completion.Choices = []SoulCompletionChoice{{
+		Index:        0,
+		Message:      ChatMessage{Role: "assistant", Content: reply},
+		FinishReason: "stop",
+	}}
+	return completion, nil
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// developerKeyPrefix marks public API keys, so they can't be mistaken for
+// Claw keys ("ensoul_sk_").
+const developerKeyPrefix = "ensoul_pk_"
+
+var (
+	ErrDeveloperKeyLimit    = errors.New("developer key limit reached")
+	ErrDeveloperKeyNotFound = errors.New("developer key not found")
+	ErrDeveloperQuota       = errors.New("developer API quota exceeded")
+)
+
+// DeveloperQuotaError is returned by MeterDeveloperKey when a key has used
+// up one of its daily allowances.
+type DeveloperQuotaError struct {
+	Kind     string // "requests" or "chats"
+	Limit    int
+	ResetsAt time.Time // next UTC midnight
+}
+
+func (e *DeveloperQuotaError) Error() string {
+	return fmt.Sprintf("%v: %d %s per day", ErrDeveloperQuota, e.Limit, e.Kind)
+}
+
+func (e *DeveloperQuotaError) Unwrap() error { return ErrDeveloperQuota }
+
+// RetryAfter is how long until the quota resets.
+func (e *DeveloperQuotaError) RetryAfter() time.Duration {
+	return max(time.Until(e.ResetsAt), time.Second)
+}
+
+// DeveloperKeyInfo is a key as listed to its wallet, with today's usage.
+type DeveloperKeyInfo struct {
+	models.DeveloperKey
+	RequestsToday int `json:"requests_today"`
+	ChatsToday    int `json:"chats_today"`
+}
+
+// CreateDeveloperKey issues a read-only public API key to walletAddr with the
+// default DEV_API_DAILY_REQUESTS / DEV_API_DAILY_CHATS quotas. The plaintext
+// key is returned once; only its hash is stored.
+func CreateDeveloperKey(walletAddr, name string) (*models.DeveloperKey, string, error) {
+	if limit := config.Cfg.DevAPIMaxKeys; limit > 0 {
+		var active int64
+		database.DB.Model(&models.DeveloperKey{}).
+			Where("LOWER(wallet_addr) = LOWER(?) AND revoked_at IS NULL", walletAddr).
+			Count(&active)
+		if int(active) >= limit {
+			return nil, "", fmt.Errorf("%w: %d active keys per wallet", ErrDeveloperKeyLimit, limit)
+		}
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	plaintext := developerKeyPrefix + hex.EncodeToString(bytes)
+
+	key := &models.DeveloperKey{
+		WalletAddr: walletAddr,
+		Name:       strings.TrimSpace(name),
+		KeyHash:    util.HashToken(plaintext),
+		KeyPrefix:  plaintext[:len(developerKeyPrefix)+8],
+		DailyQuota: config.Cfg.DevAPIDailyRequests,
+		DailyChats: config.Cfg.DevAPIDailyChats,
+	}
+	if err := database.DB.Create(key).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create developer key: %w", err)
+	}
+	util.Log.Info("[devapi] Key %s issued to %s", key.KeyPrefix, walletAddr)
+	return key, plaintext, nil
+}
+
+// ListDeveloperKeys returns a wallet's keys, newest first, revoked ones included.
+func ListDeveloperKeys(walletAddr string) ([]DeveloperKeyInfo, error) {
+	var keys []models.DeveloperKey
+	if err := database.DB.Where("LOWER(wallet_addr) = LOWER(?)", walletAddr).
+		Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return []DeveloperKeyInfo{}, nil
+	}
+
+	ids := make([]uuid.UUID, len(keys))
+	for i, k := range keys {
+		ids[i] = k.ID
+	}
+	var today []models.DeveloperKeyUsage
+	database.DB.Where("key_id IN ? AND day = ?", ids, utcDay(time.Now())).Find(&today)
+	usage := make(map[uuid.UUID]models.DeveloperKeyUsage, len(today))
+	for _, u := range today {
+		usage[u.KeyID] = u
+	}
+
+	infos := make([]DeveloperKeyInfo, len(keys))
+	for i, k := range keys {
+		infos[i] = DeveloperKeyInfo{
+			DeveloperKey:  k,
+			RequestsToday: usage[k.ID].Requests,
+			ChatsToday:    usage[k.ID].Chats,
+		}
+	}
+	return infos, nil
+}
+
+// walletDeveloperKey loads one of walletAddr's keys.
+func walletDeveloperKey(walletAddr, keyID string) (*models.DeveloperKey, error) {
+	uid, err := uuid.Parse(keyID)
+	if err != nil {
+		return nil, ErrDeveloperKeyNotFound
+	}
+	var key models.DeveloperKey
+	if err := database.DB.Where("id = ? AND LOWER(wallet_addr) = LOWER(?)", uid, walletAddr).First(&key).Error; err != nil {
+		return nil, ErrDeveloperKeyNotFound
+	}
+	return &key, nil
+}
+
+// RevokeDeveloperKey permanently disables a key. Revoking twice is a no-op.
+func RevokeDeveloperKey(walletAddr, keyID string) (*models.DeveloperKey, error) {
+	key, err := walletDeveloperKey(walletAddr, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+	now := time.Now()
+	if err := database.DB.Model(key).Update("revoked_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke developer key: %w", err)
+	}
+	key.RevokedAt = &now
+	util.Log.Info("[devapi] Key %s revoked by %s", key.KeyPrefix, walletAddr)
+	return key, nil
+}
+
+// DeveloperUsageDay is one day of a key's metered usage.
+type DeveloperUsageDay struct {
+	Day              string `json:"day"`
+	Requests         int    `json:"requests"`
+	Chats            int    `json:"chats"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+}
+
+// DeveloperUsageReport is a key's daily usage over the last `days` days.
+type DeveloperUsageReport struct {
+	Key  models.DeveloperKey `json:"key"`
+	Days []DeveloperUsageDay `json:"days"` // oldest first, one entry per day
+}
+
+// GetDeveloperKeyUsage reports a key's requests, chat completions and chat
+// tokens per UTC day.
+func GetDeveloperKeyUsage(walletAddr, keyID string, days int) (*DeveloperUsageReport, error) {
+	key, err := walletDeveloperKey(walletAddr, keyID)
+	if err != nil {
+		return nil, err
+	}
+	since := utcDay(time.Now()).AddDate(0, 0, -(days - 1))
+
+	var counted []models.DeveloperKeyUsage
+	if err := database.DB.Where("key_id = ? AND day >= ?", key.ID, since).Find(&counted).Error; err != nil {
+		return nil, fmt.Errorf("failed to load usage: %w", err)
+	}
+	var tokens []struct {
+		Day              time.Time
+		PromptTokens     int
+		CompletionTokens int
+	}
+	database.DB.Model(&models.LLMUsage{}).
+		Select("DATE(created_at) AS day, SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens").
+		Where("developer_key_id = ? AND created_at >= ?", key.ID, since).
+		Group("DATE(created_at)").
+		Scan(&tokens)
+
+	report := &DeveloperUsageReport{Key: *key, Days: make([]DeveloperUsageDay, days)}
+	index := make(map[string]*DeveloperUsageDay, days)
+	for i := range report.Days {
+		d := since.AddDate(0, 0, i).Format("2006-01-02")
+		report.Days[i].Day = d
+		index[d] = &report.Days[i]
+	}
+	for _, u := range counted {
+		if d := index[u.Day.Format("2006-01-02")]; d != nil {
+			d.Requests, d.Chats = u.Requests, u.Chats
+		}
+	}
+	for _, t := range tokens {
+		if d := index[t.Day.Format("2006-01-02")]; d != nil {
+			d.PromptTokens, d.CompletionTokens = t.PromptTokens, t.CompletionTokens
+		}
+	}
+	return report, nil
+}
+
+// MeterDeveloperKey counts one request (and, for chat, one completion)
+// against the key's daily quotas, or returns a DeveloperQuotaError without
+// counting it when either allowance is used up.
+func MeterDeveloperKey(key *models.DeveloperKey, chat bool) error {
+	chats := 0
+	if chat {
+		chats = 1
+	}
+	now := time.Now()
+	today := utcDay(now)
+
+	// The conditional upsert keeps concurrent requests from overshooting the quota
+	var counts []struct {
+		Requests int
+		Chats    int
+	}
+	if err := database.DB.Raw(`
+		INSERT INTO developer_key_usages (key_id, day, requests, chats, updated_at) VALUES (?, ?, 1, ?, NOW())
+		ON CONFLICT (key_id, day) DO UPDATE SET
+			requests = developer_key_usages.requests + 1,
+			chats = developer_key_usages.chats + EXCLUDED.chats,
+			updated_at = NOW()
+		WHERE (? = 0 OR developer_key_usages.requests < ?)
+			AND (EXCLUDED.chats = 0 OR ? = 0 OR developer_key_usages.chats < ?)
+		RETURNING requests, chats`,
+		key.ID, today, chats,
+		key.DailyQuota, key.DailyQuota,
+		key.DailyChats, key.DailyChats,
+	).Scan(&counts).Error; err != nil {
+		return fmt.Errorf("failed to meter developer key: %w", err)
+	}
+	if len(counts) == 0 {
+		quota := &DeveloperQuotaError{Kind: "requests", Limit: key.DailyQuota, ResetsAt: today.AddDate(0, 0, 1)}
+		var usage models.DeveloperKeyUsage
+		database.DB.Where("key_id = ? AND day = ?", key.ID, today).First(&usage)
+		if chat && key.DailyChats > 0 && usage.Chats >= key.DailyChats {
+			quota.Kind, quota.Limit = "chats", key.DailyChats
+		}
+		return quota
+	}
+
+	// Coarse last-used stamp, at most one write a minute per key
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+		database.DB.Model(key).UpdateColumn("last_used_at", now)
+	}
+	return nil
+}
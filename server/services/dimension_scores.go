@@ -0,0 +1,206 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// ScoreBand is one line of a tier's scoring guide: the score range a
+// dimension may have with a given number of accepted fragments.
+type ScoreBand struct {
+	MinScore int `json:"min_score"`
+	MaxScore int `json:"max_score"`
+	MinFrags int `json:"min_frags"`
+	MaxFrags int `json:"max_frags"` // -1 = open-ended ("120+ fragments")
+}
+
+// ErrNoScoreBands is returned when a tier's scoring guide has no lines in
+// the "<min>-<max>: ... (<n>-<m> fragments" form to recalculate from.
+var ErrNoScoreBands = errors.New("scoring guide has no score bands")
+
+// scoreBandLine matches guide lines like "  12-25: Basic coverage (9-20 fragments, ...)"
+// or "  85-100: Near-complete (120+ fragments, ...)".
+var scoreBandLine = regexp.MustCompile(`(?m)^\s*(\d+)-(\d+):.*?\((\d+)(?:-(\d+)|\+) fragments?`)
+
+// parseScoringGuide reads the bands of a scoring guide, lowest score first.
+func parseScoringGuide(guide string) []ScoreBand {
+	var bands []ScoreBand
+	for _, m := range scoreBandLine.FindAllStringSubmatch(guide, -1) {
+		b := ScoreBand{MaxFrags: -1}
+		b.MinScore, _ = strconv.Atoi(m[1])
+		b.MaxScore, _ = strconv.Atoi(m[2])
+		b.MinFrags, _ = strconv.Atoi(m[3])
+		if m[4] != "" {
+			b.MaxFrags, _ = strconv.Atoi(m[4])
+		}
+		if b.MinScore > b.MaxScore || (b.MaxFrags >= 0 && b.MinFrags > b.MaxFrags) {
+			continue
+		}
+		bands = append(bands, b)
+	}
+	return bands
+}
+
+// bandFor returns the band for a fragment count: the highest one whose
+// fragment range starts at or below it.
+func bandFor(bands []ScoreBand, frags int) ScoreBand {
+	band := bands[0]
+	for _, b := range bands {
+		if b.MinFrags <= frags && b.MinScore >= band.MinScore {
+			band = b
+		}
+	}
+	return band
+}
+
+// baseline is the score the fragment count earns on its own, interpolated
+// within the band (the band's floor for open-ended bands).
+func (b ScoreBand) baseline(frags int) int {
+	if b.MaxFrags < 0 || b.MaxFrags == b.MinFrags {
+		return b.MinScore
+	}
+	pos := min(max(frags-b.MinFrags, 0), b.MaxFrags-b.MinFrags)
+	return b.MinScore + (b.MaxScore-b.MinScore)*pos/(b.MaxFrags-b.MinFrags)
+}
+
+// clamp keeps a score within the band.
+func (b ScoreBand) clamp(score int) int {
+	return min(max(score, b.MinScore), b.MaxScore)
+}
+
+// DimensionScoreChange is the recalculation of one dimension.
+type DimensionScoreChange struct {
+	Dimension string    `json:"dimension"`
+	Fragments int       `json:"fragments"` // accepted fragments
+	Band      ScoreBand `json:"band"`
+	Baseline  int       `json:"baseline"`
+	Before    int       `json:"before"`
+	After     int       `json:"after"`
+}
+
+// acceptedFragmentsByDimension counts a soul's accepted fragments per dimension.
+func acceptedFragmentsByDimension(shell *models.Shell) (map[string]int, error) {
+	var rows []struct {
+		Dimension string
+		Count     int
+	}
+	if err := database.DB.Model(&models.Fragment{}).
+		Select("dimension, COUNT(*) AS count").
+		Where("shell_id = ? AND status = ?", shell.ID, models.FragStatusAccepted).
+		Group("dimension").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count fragments: %w", err)
+	}
+	counts := make(map[string]int, len(rows))
+	for _, r := range rows {
+		counts[r.Dimension] = r.Count
+	}
+	return counts, nil
+}
+
+// recalcDimensions bounds every dimension score of dims by the band its
+// fragment count falls in under the soul's scoring guide. With reset the
+// score is set to the band's baseline; otherwise an in-band score (the
+// LLM's judgment) is kept and an out-of-band one moves to the nearest edge.
+// Summaries are untouched.
+func recalcDimensions(shell *models.Shell, dims models.JSON, reset bool) (models.JSON, []DimensionScoreChange, error) {
+	tier, _ := shellPolicy(shell)
+	bands := parseScoringGuide(tier.ScoringGuide)
+	if len(bands) == 0 {
+		return nil, nil, fmt.Errorf("tier %s: %w", tier.Name, ErrNoScoreBands)
+	}
+	counts, err := acceptedFragmentsByDimension(shell)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := (&models.Shell{Dimensions: normalizeDimensions(dims)}).GetDimensions()
+	out := make(models.JSON, len(dimensionOrder))
+	for k, v := range dims {
+		out[k] = v
+	}
+	changes := make([]DimensionScoreChange, 0, len(dimensionOrder))
+	for _, dim := range dimensionOrder {
+		d := current[dim]
+		band := bandFor(bands, counts[dim])
+		change := DimensionScoreChange{
+			Dimension: dim,
+			Fragments: counts[dim],
+			Band:      band,
+			Baseline:  band.baseline(counts[dim]),
+			Before:    d.Score,
+		}
+		change.After = band.clamp(d.Score)
+		if reset {
+			change.After = change.Baseline
+		}
+		out[dim] = map[string]interface{}{"score": change.After, "summary": d.Summary}
+		changes = append(changes, change)
+	}
+	return out, changes, nil
+}
+
+// normalizeDimensions round-trips dimensions through JSON so scores built
+// in memory as ints read back like those loaded from the database.
+func normalizeDimensions(dims models.JSON) models.JSON {
+	raw, _ := json.Marshal(dims)
+	var out models.JSON
+	json.Unmarshal(raw, &out)
+	return out
+}
+
+// clampEnsoulingScores bounds the scores an ensouling produced, as a sanity
+// check on the LLM. A partial ensouling only has its own dimension clamped.
+// The dimensions are left alone if the guide can't be read.
+func clampEnsoulingScores(shell *models.Shell, ensouling *models.Ensouling) {
+	dims, changes, err := recalcDimensions(shell, ensouling.DimensionsAfter, false)
+	if err != nil {
+		util.Log.Warn("[ensouling] Score clamp skipped for @%s: %v", shell.Handle, err)
+		return
+	}
+	for _, ch := range changes {
+		if ensouling.Dimension != "" && ch.Dimension != ensouling.Dimension {
+			if v, ok := ensouling.DimensionsAfter[ch.Dimension]; ok {
+				dims[ch.Dimension] = v
+			} else {
+				delete(dims, ch.Dimension)
+			}
+			continue
+		}
+		if ch.Before != ch.After {
+			util.Log.Info("[ensouling] @%s %s score %d clamped to %d (%d fragments, band %d-%d)",
+				shell.Handle, ch.Dimension, ch.Before, ch.After, ch.Fragments, ch.Band.MinScore, ch.Band.MaxScore)
+		}
+	}
+	ensouling.DimensionsAfter = dims
+}
+
+// RecalcShellScores recomputes a soul's dimension scores from its accepted
+// fragment counts (see recalcDimensions) and, with apply, saves them. It
+// doesn't create a DNA version.
+func RecalcShellScores(shell *models.Shell, reset, apply bool) ([]DimensionScoreChange, error) {
+	dims, changes, err := recalcDimensions(shell, shell.Dimensions, reset)
+	if err != nil {
+		return nil, err
+	}
+	if !apply {
+		return changes, nil
+	}
+	if err := database.DB.Model(shell).Update("dimensions", dims).Error; err != nil {
+		return nil, fmt.Errorf("failed to save scores: %w", err)
+	}
+	shell.Dimensions = dims
+	return changes, nil
+}
+
+// LoadEnsoulingPolicy loads the ensouling tiers and overrides once, for
+// commands that run without the reload job.
+func LoadEnsoulingPolicy() error {
+	return reloadEnsoulingPolicy()
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// dnaAnchorBatch caps the setMetadata writes of one anchor run.
+const dnaAnchorBatch = 10
+
+// ErrDNAVersionNotFound is returned for versions without a deployed ensouling
+// (including v1, the unarchived seed).
+var ErrDNAVersionNotFound = errors.New("DNA version not found")
+
+// DNAProof lets anyone check that a DNA version existed when it was anchored.
+// The prompt itself is never returned: a holder of the prompt (its owner or a
+// licensee) can recompute prompt_hash, and with the public fragment content
+// hashes, dna_hash.
+//
+// dna_hash is the hash stored when the version was hashed, which is what was
+// anchored. The fragments merged into a version can later be deleted, archived
+// or moved by a merge, so the recomputed fields are only filled on request and
+// a mismatch there does not mean the anchor is wrong.
+type DNAProof struct {
+	Handle            string     `json:"handle"`
+	DNAVersion        int        `json:"dna_version"`
+	PromptHash        string     `json:"prompt_hash"` // keccak256 of the prompt
+	DNAHash           string     `json:"dna_hash"`
+	FragmentHashes    []string   `json:"fragment_hashes,omitempty"`     // sha256 content hashes of the version's remaining fragments, sorted
+	RecomputedDNAHash string     `json:"recomputed_dna_hash,omitempty"` // dna_hash over fragment_hashes
+	RecomputedMatch   *bool      `json:"recomputed_match,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	AnchorTxHash      string     `json:"anchor_tx_hash,omitempty"`
+	AnchorTxURL       string     `json:"anchor_tx_url,omitempty"`
+	AnchoredAt        *time.Time `json:"anchored_at,omitempty"`
+	OnChainHash       string     `json:"onchain_dna_hash,omitempty"`
+	OnChainStatus     string     `json:"onchain_status"`         // see ChainProof* constants
+	PromptMatch       *bool      `json:"prompt_match,omitempty"` // only when a prompt was supplied
+}
+
+// DNAAttestation names the DNA version a reply was generated with, so API
+// consumers can check it against the on-chain anchor (see GetDNAProof).
+// prompt_hash covers the soul prompt of the version; per-message context
+// (retrieved fragments, history) is not part of it.
+type DNAAttestation struct {
+	Handle       string     `json:"handle"`
+	DNAVersion   int        `json:"dna_version"`
+	PromptHash   string     `json:"prompt_hash"`
+	DNAHash      string     `json:"dna_hash,omitempty"`
+	Anchored     bool       `json:"anchored"`
+	AnchorTxHash string     `json:"anchor_tx_hash,omitempty"`
+	AnchorTxURL  string     `json:"anchor_tx_url,omitempty"`
+	AnchoredAt   *time.Time `json:"anchored_at,omitempty"`
+	ProofURL     string     `json:"proof_url,omitempty"` // only for versions with a proof
+}
+
+// computeDNAHash returns the keccak256 hashes anchoring a DNA version:
+// prompt_hash = keccak256(prompt) and
+// dna_hash = keccak256(prompt_hash ‖ fragment content hashes sorted ascending),
+// all as 32-byte values. Also returns the sorted fragment hashes.
+func computeDNAHash(prompt string, fragmentHashes []string) (promptHash, dnaHash string, sorted []string) {
+	sorted = append([]string{}, fragmentHashes...)
+	sort.Strings(sorted)
+
+	p := crypto.Keccak256([]byte(prompt))
+	packed := append([]byte{}, p...)
+	for _, h := range sorted {
+		if b, err := hex.DecodeString(h); err == nil {
+			packed = append(packed, b...)
+		}
+	}
+	return "0x" + hex.EncodeToString(p), "0x" + hex.EncodeToString(crypto.Keccak256(packed)), sorted
+}
+
+// mergedFragmentHashes returns the content hashes of an ensouling's fragments,
+// including soft-deleted ones.
+func mergedFragmentHashes(ensouling *models.Ensouling) []string {
+	var hashes []string
+	database.DB.Unscoped().Model(&models.Fragment{}).Where("ensouling_id = ?", ensouling.ID).Pluck("content_hash", &hashes)
+	return hashes
+}
+
+// StartDNAAnchor periodically writes the hashes of deployed DNA versions
+// on-chain, oldest first, and hashes versions from before anchoring existed.
+func StartDNAAnchor(interval time.Duration) {
+	scheduleJob("dna-anchor", "Anchor DNA version hashes on-chain", interval, false, anchorPendingDNA)
+	util.Log.Info("[dna-anchor] DNA anchoring started (every %v)", interval)
+}
+
+func anchorPendingDNA() error {
+	// Versions ensouled before anchoring existed
+	var unhashed []models.Ensouling
+	database.DB.Where("status = ? AND dna_hash = ''", models.EnsoulingDeployed).
+		Order("created_at ASC").Limit(50).Find(&unhashed)
+	for i := range unhashed {
+		_, dnaHash, _ := computeDNAHash(unhashed[i].NewPrompt, mergedFragmentHashes(&unhashed[i]))
+		database.DB.Model(&unhashed[i]).UpdateColumn("dna_hash", dnaHash)
+	}
+
+	if chain.C() == nil || !chain.C().HasPlatformKey() {
+		return nil
+	}
+
+	var pending []models.Ensouling
+	if err := database.DB.Preload("Shell").
+		Joins("JOIN shells ON shells.id = ensoulings.shell_id AND shells.deleted_at IS NULL").
+		Where("ensoulings.status = ? AND ensoulings.dna_hash <> '' AND COALESCE(ensoulings.anchor_tx_hash, '') = ''", models.EnsoulingDeployed).
+		Where("shells.agent_id IS NOT NULL AND shells.chain_status = ?", models.ShellChainActive).
+		Order("ensoulings.created_at ASC").Limit(dnaAnchorBatch).
+		Find(&pending).Error; err != nil {
+		return err
+	}
+
+	for i := range pending {
+		e := &pending[i]
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		txHash, err := chain.SetDNAHash(ctx, new(big.Int).SetUint64(*e.Shell.AgentID), e.VersionTo, e.DNAHash)
+		cancel()
+		trackChainSpend(txHash, models.ChainFeatureDNAAnchor, &e.ShellID, nil)
+		if err != nil {
+			return err // retried on the next run
+		}
+		now := time.Now()
+		database.DB.Model(e).Updates(map[string]interface{}{"anchor_tx_hash": txHash, "anchored_at": now})
+		util.Log.Debug("[dna-anchor] Anchored DNA v%d of @%s: tx=%s", e.VersionTo, e.Shell.Handle, txHash)
+	}
+	return nil
+}
+
+// GetDNAProof returns the anchor of a deployed DNA version and checks the
+// stored dna_hash against the chain. If prompt is given, it is checked against
+// prompt_hash. With recompute, dna_hash is also recomputed from the version's
+// fragments as they are now.
+func GetDNAProof(shell *models.Shell, version int, prompt *string, recompute bool) (*DNAProof, error) {
+	var ensouling models.Ensouling
+	if err := database.DB.Where("shell_id = ? AND version_to = ? AND status = ?", shell.ID, version, models.EnsoulingDeployed).
+		First(&ensouling).Error; err != nil {
+		return nil, ErrDNAVersionNotFound
+	}
+
+	promptHash, _, _ := computeDNAHash(ensouling.NewPrompt, nil)
+	proof := &DNAProof{
+		Handle:        shell.Handle,
+		DNAVersion:    version,
+		PromptHash:    promptHash,
+		DNAHash:       ensouling.DNAHash,
+		CreatedAt:     ensouling.CreatedAt,
+		AnchorTxHash:  ensouling.AnchorTxHash,
+		AnchoredAt:    ensouling.AnchoredAt,
+		OnChainStatus: ChainProofNotSubmitted,
+	}
+	// Not hashed by the anchor job yet: nothing was stored or anchored
+	if proof.DNAHash == "" || recompute {
+		_, dnaHash, fragmentHashes := computeDNAHash(ensouling.NewPrompt, mergedFragmentHashes(&ensouling))
+		if proof.DNAHash == "" {
+			proof.DNAHash = dnaHash
+		}
+		if recompute {
+			match := dnaHash == proof.DNAHash
+			proof.FragmentHashes = fragmentHashes
+			proof.RecomputedDNAHash = dnaHash
+			proof.RecomputedMatch = &match
+		}
+	}
+	if ensouling.AnchorTxHash != "" {
+		proof.AnchorTxURL = config.Cfg.ExplorerTxURL(ensouling.AnchorTxHash)
+	}
+	if prompt != nil {
+		supplied, _, _ := computeDNAHash(*prompt, nil)
+		match := supplied == promptHash
+		proof.PromptMatch = &match
+	}
+
+	if shell.AgentID == nil || ensouling.AnchorTxHash == "" {
+		return proof, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	onChain, err := chain.ReadDNAHash(ctx, new(big.Int).SetUint64(*shell.AgentID), version)
+	switch {
+	case err != nil:
+		proof.OnChainStatus = ChainProofUnavailable
+	case onChain == "":
+		proof.OnChainStatus = ChainProofNotSubmitted
+	case onChain == proof.DNAHash:
+		proof.OnChainHash = onChain
+		proof.OnChainStatus = ChainProofVerified
+	default:
+		proof.OnChainHash = onChain
+		proof.OnChainStatus = ChainProofMismatch
+	}
+	return proof, nil
+}
+
+// GetDNAAttestation returns the attestation of the DNA version a soul answers
+// with. It reads only the database: verifying the anchor on-chain is left to
+// the consumer, through proof_url or the contract. The seed version (v1) has
+// no ensouling, so it is attested by the hash of the current prompt alone.
+func GetDNAAttestation(shell *models.Shell, version int) DNAAttestation {
+	attestation := DNAAttestation{Handle: shell.Handle, DNAVersion: version}
+
+	var ensouling models.Ensouling
+	if err := database.DB.Where("shell_id = ? AND version_to = ? AND status = ?", shell.ID, version, models.EnsoulingDeployed).
+		First(&ensouling).Error; err != nil {
+		attestation.PromptHash, _, _ = computeDNAHash(shell.SoulPrompt, nil)
+		return attestation
+	}
+
+	attestation.PromptHash, _, _ = computeDNAHash(ensouling.NewPrompt, nil)
+	attestation.DNAHash = ensouling.DNAHash
+	attestation.ProofURL = config.Cfg.PublicURL(fmt.Sprintf("/api/shell/%s/history/%d/proof", shell.Handle, version))
+	if ensouling.AnchorTxHash != "" {
+		attestation.Anchored = true
+		attestation.AnchorTxHash = ensouling.AnchorTxHash
+		attestation.AnchorTxURL = config.Cfg.ExplorerTxURL(ensouling.AnchorTxHash)
+		attestation.AnchoredAt = ensouling.AnchoredAt
+	}
+	return attestation
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+)
+
+// localEmbeddingModel is the model name recorded for vectors produced by the
+// built-in hashing embedder (used when no embedding API is available).
+const localEmbeddingModel = "local-hash-v1"
+
+// localEmbeddingDims is the dimensionality of the hashing embedder's vectors.
+const localEmbeddingDims = 512
+
+// embeddingRequest is the request body for the OpenAI Embeddings API.
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// embeddingResponse is the response from the OpenAI Embeddings API.
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// EmbeddingModel returns the model name that Embed will use, so callers can
+// detect cached vectors produced by a different model.
+func EmbeddingModel() string {
+	if !remoteEmbeddingsAvailable() {
+		return localEmbeddingModel
+	}
+	return config.Cfg.EmbeddingModel
+}
+
+// remoteEmbeddingsAvailable reports whether an OpenAI-compatible embeddings
+// endpoint is configured. Anthropic has no embeddings API, so Claude
+// deployments always use the local hashing embedder.
+func remoteEmbeddingsAvailable() bool {
+	cfg := config.Cfg
+	if cfg.LLMAPIKey == "" || cfg.EmbeddingModel == "" || mockLLM() {
+		return false
+	}
+	provider := strings.ToLower(cfg.LLMProvider)
+	return provider != "claude" && provider != "anthropic"
+}
+
+// Embed returns one embedding vector per input text.
+// Uses the configured OpenAI-compatible endpoint when available, otherwise a
+// deterministic local hashing embedder so retrieval still works in development.
+func Embed(texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if !remoteEmbeddingsAvailable() {
+		vectors := make([][]float64, len(texts))
+		for i, t := range texts {
+			vectors[i] = localEmbed(t)
+		}
+		return vectors, nil
+	}
+	return embedOpenAI(texts)
+}
+
+func embedOpenAI(texts []string) ([][]float64, error) {
+	cfg := config.Cfg
+
+	body, _ := json.Marshal(embeddingRequest{
+		Model: cfg.EmbeddingModel,
+		Input: texts,
+	})
+
+	req, err := http.NewRequest("POST", llmBaseURL()+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.LLMAPIKey)
+
+	resp, err := llmHTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embedding API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var embResp embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding API returned %d vectors for %d inputs", len(embResp.Data), len(texts))
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding API returned invalid index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// localEmbed builds a normalized bag-of-words vector using the hashing trick.
+// Crude compared to a real model, but deterministic and free.
+func localEmbed(text string) []float64 {
+	vec := make([]float64, localEmbeddingDims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, w := range words {
+		if len(w) < 3 {
+			continue // skip short stop-word-ish tokens
+		}
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		sum := h.Sum32()
+		sign := 1.0
+		if sum&1 == 1 {
+			sign = -1.0
+		}
+		vec[int(sum>>1)%localEmbeddingDims] += sign
+	}
+	normalize(vec)
+	return vec
+}
+
+// normalize scales a vector to unit length in place.
+func normalize(vec []float64) {
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] /= norm
+	}
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors (0 if either is empty
+// or their dimensions differ).
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -4,11 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/big"
 	"strings"
 
-	"github.com/ensoul-labs/ensoul-server/chain"
-	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/database"
 	"github.com/ensoul-labs/ensoul-server/models"
 	"github.com/ensoul-labs/ensoul-server/util"
@@ -19,14 +16,22 @@ type EnsoulingResult struct {
 	NewPrompt   string                          `json:"new_prompt"`
 	Dimensions  map[string]models.DimensionData `json:"dimensions"`
 	SummaryDiff string                          `json:"summary_diff"`
+	Essence     string                          `json:"essence"` // public summary; "" keeps the current one
 }
 
 // TriggerEnsouling performs the soul condensation process.
 // Merges new accepted fragments into the soul prompt and updates the DNA.
+// A new prompt flagged by ScanSoulPrompt is quarantined instead of deployed.
 func TriggerEnsouling(shell *models.Shell) {
+	if hasQuarantinedEnsouling(shell.ID) {
+		util.Log.Debug("[ensouling] @%s has a quarantined version awaiting review, skipping", shell.Handle)
+		return
+	}
+
 	// Get unmerged accepted fragments
 	var fragments []models.Fragment
-	database.DB.Where("shell_id = ? AND status = ? AND ensouling_id IS NULL",
+	// Fragments flagged by the verified subject are held back
+	database.DB.Where("shell_id = ? AND status = ? AND ensouling_id IS NULL AND subject_flag = ''",
 		shell.ID, models.FragStatusAccepted).
 		Order("created_at ASC").
 		Find(&fragments)
@@ -37,17 +42,19 @@ func TriggerEnsouling(shell *models.Shell) {
 
 	// Create ensouling record
 	ensouling := &models.Ensouling{
-		ShellID:     shell.ID,
-		VersionFrom: shell.DNAVersion,
-		VersionTo:   shell.DNAVersion + 1,
-		FragsMerged: len(fragments),
+		ShellID:          shell.ID,
+		VersionFrom:      shell.DNAVersion,
+		VersionTo:        shell.DNAVersion + 1,
+		FragsMerged:      len(fragments),
+		DimensionsBefore: snapshotDimensions(shell.Dimensions),
+		Status:           models.EnsoulingDeployed,
 	}
 
 	// Perform ensouling via LLM or fallback
 	var result *EnsoulingResult
 	var err error
 
-	if config.Cfg.LLMAPIKey != "" {
+	if LLMConfigured() {
 		result, err = ensoulWithLLM(shell, fragments)
 		if err != nil {
 			util.Log.Warn("[ensouling] LLM ensouling failed, using fallback: %v", err)
@@ -57,8 +64,45 @@ func TriggerEnsouling(shell *models.Shell) {
 		result = ensoulFallback(shell, fragments)
 	}
 
+	commitEnsouling(shell, ensouling, fragments, result)
+}
+
+// commitEnsouling records an ensouling result, links its fragments to it and
+// deploys it, or quarantines it if ScanSoulPrompt flags the new prompt.
+func commitEnsouling(shell *models.Shell, ensouling *models.Ensouling, fragments []models.Fragment, result *EnsoulingResult) {
+	promptBefore := shell.SoulPrompt
+
+	// The cutoff line is kept out of the LLM's hands
+	ensouling.KnowledgeCutoff = ensoulingKnowledgeCutoff(shell, fragments)
+	result.NewPrompt = withKnowledgeCutoff(result.NewPrompt, ensouling.KnowledgeCutoff)
+
 	ensouling.NewPrompt = result.NewPrompt
 	ensouling.SummaryDiff = result.SummaryDiff
+	ensouling.Essence = publicEssence(shell, result.Essence)
+	ensouling.DimensionsAfter = ensouling.DimensionsBefore
+	if result.Dimensions != nil {
+		dimsJSON, _ := json.Marshal(result.Dimensions)
+		var dimsAfter models.JSON
+		json.Unmarshal(dimsJSON, &dimsAfter)
+		ensouling.DimensionsAfter = dimsAfter
+	}
+	clampEnsoulingScores(shell, ensouling)
+	ensouling.PromptDiff = models.JSON{
+		"sections": diffPromptSections(promptBefore, result.NewPrompt),
+	}
+
+	// Anchored on-chain by the dna-anchor job once deployed
+	fragHashes := make([]string, len(fragments))
+	for i, f := range fragments {
+		fragHashes[i] = f.ContentHash
+	}
+	_, ensouling.DNAHash, _ = computeDNAHash(result.NewPrompt, fragHashes)
+
+	if verdict := ScanSoulPrompt(shell, promptBefore, result.NewPrompt); verdict.Flagged {
+		ensouling.Status = models.EnsoulingQuarantined
+		ensouling.ScanCategories = models.StringList(verdict.Categories)
+		ensouling.ScanReason = verdict.Reason
+	}
 
 	if err := database.DB.Create(ensouling).Error; err != nil {
 		util.Log.Error("[ensouling] Failed to create ensouling record: %v", err)
@@ -74,51 +118,46 @@ func TriggerEnsouling(shell *models.Shell) {
 		Where("id IN ?", fragIDs).
 		Update("ensouling_id", ensouling.ID)
 
-	// Update shell
-	shell.DNAVersion++
-	shell.SoulPrompt = result.NewPrompt
-
-	updateFields := map[string]interface{}{
-		"dna_version": shell.DNAVersion,
-		"soul_prompt": result.NewPrompt,
+	if ensouling.Status == models.EnsoulingQuarantined {
+		util.Log.Warn("[ensouling] DNA v%d of @%s quarantined (%s: %v): %s",
+			ensouling.VersionTo, shell.Handle, ensouling.ScanCategories, ensouling.ScanReason, ensouling.ID)
+		return
 	}
 
-	// Update dimensions if provided by LLM
-	if result.Dimensions != nil {
-		dimsJSON, _ := json.Marshal(result.Dimensions)
-		var dimsMap models.JSON
-		json.Unmarshal(dimsJSON, &dimsMap)
-		shell.Dimensions = dimsMap
-		updateFields["dimensions"] = dimsMap
+	deployEnsouling(shell, ensouling)
+	scope := "all dimensions"
+	if ensouling.Dimension != "" {
+		scope = ensouling.Dimension
 	}
+	util.Log.Info("[ensouling] Completed for @%s (%s): v%d -> v%d, merged %d fragments",
+		shell.Handle, scope, ensouling.VersionFrom, ensouling.VersionTo, len(fragments))
+}
 
-	database.DB.Model(shell).Updates(updateFields)
-
-	// Update stage
-	UpdateShellStage(shell)
-
-	// Update agentURI on-chain if this shell is linked to an on-chain agent
-	if shell.AgentID != nil {
-		go func() {
-			ctx := context.Background()
-			agentId := new(big.Int).SetUint64(*shell.AgentID)
-			txHash, err := chain.UpdateSoulURI(
-				ctx, agentId, shell.Handle, shell.AvatarURL,
-				shell.SeedSummary, shell.Stage, shell.DNAVersion,
-			)
-			if err != nil {
-				util.Log.Error("[ensouling] Failed to update agentURI on-chain for @%s: %v", shell.Handle, err)
-				return
-			}
-			if txHash != "" {
-				database.DB.Model(ensouling).Update("tx_hash", txHash)
-				util.Log.Debug("[ensouling] On-chain URI updated for @%s: tx=%s", shell.Handle, txHash)
-			}
-		}()
+// deployEnsouling makes an ensouling's prompt and dimensions the shell's
+// current DNA and publishes the new version on-chain.
+func deployEnsouling(shell *models.Shell, ensouling *models.Ensouling) {
+	// Update shell (without an LLM result DimensionsAfter equals DimensionsBefore)
+	shell.DNAVersion = ensouling.VersionTo
+	shell.SoulPrompt = ensouling.NewPrompt
+	shell.Dimensions = ensouling.DimensionsAfter
+	updates := map[string]interface{}{
+		"dna_version": shell.DNAVersion,
+		"soul_prompt": shell.SoulPrompt,
+		"dimensions":  shell.Dimensions,
+	}
+	if ensouling.KnowledgeCutoff != nil {
+		shell.KnowledgeCutoff = ensouling.KnowledgeCutoff
+		updates["knowledge_cutoff"] = shell.KnowledgeCutoff
 	}
+	if ensouling.Essence != "" {
+		shell.Essence = ensouling.Essence
+		updates["essence"] = shell.Essence
+	}
+
+	database.DB.Model(shell).Updates(updates)
 
-	util.Log.Info("[ensouling] Completed for @%s: v%d -> v%d, merged %d fragments",
-		shell.Handle, ensouling.VersionFrom, ensouling.VersionTo, len(fragments))
+	// Stage update, agentURI refresh and the webhook event subscribe to this
+	Publish(Events, EnsoulingCompleted{Shell: shell, Ensouling: ensouling})
 }
 
 // ensoulWithLLM performs soul condensation using the LLM.
@@ -127,18 +166,19 @@ func ensoulWithLLM(shell *models.Shell, fragments []models.Fragment) (*Ensouling
 	var fragList strings.Builder
 	dimFrags := make(map[string]int)
 	for i, f := range fragments {
-		fragList.WriteString(fmt.Sprintf("[%d] Dimension: %s | Confidence: %.2f\n%s\n\n",
-			i+1, f.Dimension, f.Confidence, f.Content))
+		fragList.WriteString(fmt.Sprintf("[%d] Dimension: %s | Confidence: %.2f | Submitted: %s\n%s\n\n",
+			i+1, f.Dimension, f.Confidence, f.CreatedAt.Format(cutoffDateFormat), fragmentPromptText(f)))
 		dimFrags[f.Dimension]++
 	}
 
 	// Build dimension coverage summary with actual fragment counts
 	var dimCoverage strings.Builder
 	currentDims := shell.GetDimensions()
-	allDimensions := []string{"personality", "knowledge", "stance", "style", "relationship", "timeline"}
-	for _, dim := range allDimensions {
+	scores := make(map[string][2]int, len(dimensionOrder))
+	for _, dim := range dimensionOrder {
 		data := currentDims[dim]
 		newCount := dimFrags[dim]
+		scores[dim] = [2]int{data.Score, newCount}
 
 		// Count total accepted fragments for this dimension
 		var totalAccepted int64
@@ -151,61 +191,8 @@ func ensoulWithLLM(shell *models.Shell, fragments []models.Fragment) (*Ensouling
 			dim, data.Score, totalAccepted, newCount))
 	}
 
-	// Determine depth tier based on follower count
-	followers := getFollowers(*shell)
-	var depthTier, scoringGuide string
-	switch {
-	case followers >= 1_000_000:
-		depthTier = fmt.Sprintf("MEGA (%d followers) — extremely rich public data, needs 80+ fragments per dimension to reach high scores", followers)
-		scoringGuide = `  0-5:   Almost no data (0-2 fragments, only seed info)
-  5-12:  Minimal data (3-8 fragments, surface-level)
-  12-25: Basic coverage (9-20 fragments, some evidence)
-  25-40: Moderate coverage (21-40 fragments, multiple angles)
-  40-55: Good coverage (41-60 fragments, detailed with citations)
-  55-70: Strong coverage (61-80 fragments, comprehensive)
-  70-85: Excellent coverage (81-120 fragments, deep multi-source)
-  85-100: Near-complete (120+ fragments, exhaustive — rarely achievable)`
-	case followers >= 100_000:
-		depthTier = fmt.Sprintf("LARGE (%d followers) — rich public data, needs 50+ fragments per dimension for high scores", followers)
-		scoringGuide = `  0-8:   Almost no data (0-2 fragments, only seed info)
-  8-18:  Minimal data (3-6 fragments, surface-level)
-  18-30: Basic coverage (7-15 fragments, some evidence)
-  30-45: Moderate coverage (16-30 fragments, multiple angles)
-  45-60: Good coverage (31-50 fragments, detailed with citations)
-  60-75: Strong coverage (51-70 fragments, comprehensive)
-  75-90: Excellent coverage (70+ fragments, deep multi-source)
-  90-100: Near-complete (100+ fragments, exhaustive — rarely achievable)`
-	case followers >= 10_000:
-		depthTier = fmt.Sprintf("MEDIUM (%d followers) — moderate public data, needs 30+ fragments per dimension for high scores", followers)
-		scoringGuide = `  0-10:  Almost no data (0-2 fragments, only seed info)
-  10-20: Minimal data (3-5 fragments, surface-level)
-  20-35: Basic coverage (6-12 fragments, some evidence)
-  35-50: Moderate coverage (13-25 fragments, multiple angles)
-  50-65: Good coverage (26-40 fragments, detailed with citations)
-  65-80: Strong coverage (41-55 fragments, comprehensive)
-  80-90: Excellent coverage (55+ fragments, deep analysis)
-  90-100: Near-complete (70+ fragments, exhaustive — rarely achievable)`
-	case followers >= 1_000:
-		depthTier = fmt.Sprintf("SMALL (%d followers) — limited public data, needs 15+ fragments per dimension for high scores", followers)
-		scoringGuide = `  0-12:  Almost no data (0-2 fragments, only seed info)
-  12-25: Minimal data (3-4 fragments, surface-level)
-  25-40: Basic coverage (5-8 fragments, some evidence)
-  40-55: Moderate coverage (9-15 fragments, multiple angles)
-  55-70: Good coverage (16-25 fragments, detailed)
-  70-85: Strong coverage (26-35 fragments, comprehensive)
-  85-95: Excellent coverage (35+ fragments, deep analysis)
-  95-100: Near-complete (50+ fragments, exhaustive)`
-	default:
-		depthTier = fmt.Sprintf("MICRO (%d followers) — very limited public data, needs 8+ fragments per dimension for high scores", followers)
-		scoringGuide = `  0-15:  Almost no data (0-1 fragments, only seed info)
-  15-30: Minimal data (2-3 fragments, surface-level)
-  30-50: Basic coverage (4-6 fragments, some evidence)
-  50-65: Moderate coverage (7-10 fragments, multiple angles)
-  65-80: Good coverage (11-15 fragments, detailed)
-  80-90: Strong coverage (16-20 fragments, comprehensive)
-  90-95: Excellent coverage (20+ fragments, thorough analysis)
-  95-100: Near-complete (30+ fragments, exhaustive)`
-	}
+	// Depth tier from the ensouling policy (follower count, or a per-shell override)
+	depthTier, scoringGuide := ensoulingDepthTier(shell)
 
 	prompt := fmt.Sprintf(`You are the Ensouling engine for Ensoul, a decentralized soul construction protocol.
 You perform "soul condensation" — merging new verified fragments into an existing soul profile.
@@ -215,6 +202,7 @@ Handle: @%s
 Stage: %s
 DNA Version: v%d
 Seed Summary: %s
+Current Essence: %s
 Depth Tier: %s
 
 === CURRENT SYSTEM PROMPT ===
@@ -232,6 +220,7 @@ Depth Tier: %s
 3. Produce an UPDATED System Prompt that incorporates the new knowledge
 4. Update the dimension scores (each dimension: 0-100)
 5. Write a brief summary of what changed
+6. Rewrite the soul's public essence to reflect the updated profile
 
 === DIMENSION SCORING RULES (CRITICAL) ===
 The score measures OUR DATA COVERAGE — how thoroughly we have mapped this person's soul.
@@ -251,9 +240,17 @@ The System Prompt should:
 - Maintain the soul's voice and personality
 - Incorporate new insights naturally (not just appending bullet points)
 - Be structured as a character prompt suitable for LLM conversation
-- Begin with "You are the digital soul of @%s."
-- Include personality traits, knowledge areas, opinions, and communication style
+- Begin with "You are the digital soul of @%s." and a short overview paragraph
+- Then have one block per dimension, in this order, each starting with its tag alone on a line:
+  [personality], [knowledge], [stance], [style], [relationship], [timeline]
+  (blocks are later updated one at a time, so keep each block self-contained)
 - Be comprehensive but concise (aim for 500-1000 words)
+- Place dated facts in time using the fragments' submission dates; don't present them as
+  current beyond what the fragments say (a knowledge cutoff line is added automatically)
+- Be written in English even when fragments are in other languages; if the person mainly
+  communicates in another language, say so in the communication style section
+
+%s
 
 Respond in JSON format ONLY:
 {
@@ -266,17 +263,19 @@ Respond in JSON format ONLY:
     "relationship": {"score": 12, "summary": "..."},
     "timeline": {"score": 8, "summary": "..."}
   },
-  "summary_diff": "Brief description of what changed in this version..."
+  "summary_diff": "Brief description of what changed in this version...",
+  "essence": "@%s is ..."
 }`,
-		shell.Handle, shell.Stage, shell.DNAVersion, shell.SeedSummary,
+		shell.Handle, shell.Stage, shell.DNAVersion, shell.SeedSummary, ShellEssence(shell),
 		depthTier,
 		shell.SoulPrompt, dimCoverage.String(),
 		len(fragments), fragList.String(),
 		depthTier, scoringGuide,
-		shell.Handle, shell.Handle)
+		shell.Handle, essenceGuidance, shell.Handle, shell.Handle)
 
 	var result EnsoulingResult
-	err := CallLLMJSON([]ChatMessage{
+	tag := LLMCallTag{Feature: models.LLMFeatureEnsouling, ShellID: &shell.ID, Mock: &MockInput{Handle: shell.Handle, Scores: scores}}
+	err := CallLLMJSON(context.Background(), tag, []ChatMessage{
 		{Role: "system", Content: "You are a precise soul construction engine. Output valid JSON only, no markdown."},
 		{Role: "user", Content: prompt},
 	}, 4000, 0.4, &result)
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+)
+
+// Prompt section change statuses
+const (
+	SectionAdded     = "added"
+	SectionRemoved   = "removed"
+	SectionChanged   = "changed"
+	SectionUnchanged = "unchanged"
+)
+
+// DimensionDiff is the before/after state of one dimension in an Ensouling.
+type DimensionDiff struct {
+	Dimension     string `json:"dimension"`
+	ScoreBefore   int    `json:"score_before"`
+	ScoreAfter    int    `json:"score_after"`
+	Delta         int    `json:"delta"`
+	SummaryBefore string `json:"summary_before"`
+	SummaryAfter  string `json:"summary_after"`
+}
+
+// PromptSectionDiff describes how one section of the soul prompt changed.
+// Only the section title and line/word counts are exposed — the prompt text
+// itself is the paid asset and never leaves the server.
+type PromptSectionDiff struct {
+	Section      string `json:"section"`
+	Status       string `json:"status"`
+	WordsBefore  int    `json:"words_before"`
+	WordsAfter   int    `json:"words_after"`
+	LinesAdded   int    `json:"lines_added"`
+	LinesRemoved int    `json:"lines_removed"`
+}
+
+// EnsoulingDiff is the full diff view of a single Ensouling.
+type EnsoulingDiff struct {
+	Handle      string              `json:"handle"`
+	VersionFrom int                 `json:"version_from"`
+	VersionTo   int                 `json:"version_to"`
+	FragsMerged int                 `json:"frags_merged"`
+	SummaryDiff string              `json:"summary_diff"`
+	TxHash      string              `json:"tx_hash,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	Dimensions  []DimensionDiff     `json:"dimensions"`
+	Sections    []PromptSectionDiff `json:"sections"`
+}
+
+// promptSection is a titled block of a soul prompt.
+type promptSection struct {
+	title string
+	lines []string
+}
+
+// GetEnsoulingDiff returns the structured diff of the Ensouling that produced
+// the given DNA version of a shell.
+func GetEnsoulingDiff(handle string, version int) (*EnsoulingDiff, error) {
+	shell, err := GetShellByHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	var ensouling models.Ensouling
+	if err := database.DB.Where("shell_id = ? AND version_to = ? AND status = ?", shell.ID, version, models.EnsoulingDeployed).
+		First(&ensouling).Error; err != nil {
+		return nil, errors.New("version not found")
+	}
+
+	diff := &EnsoulingDiff{
+		Handle:      shell.Handle,
+		VersionFrom: ensouling.VersionFrom,
+		VersionTo:   ensouling.VersionTo,
+		FragsMerged: ensouling.FragsMerged,
+		SummaryDiff: ensouling.SummaryDiff,
+		TxHash:      ensouling.TxHash,
+		CreatedAt:   ensouling.CreatedAt,
+		Dimensions:  diffDimensions(ensouling.DimensionsBefore, ensouling.DimensionsAfter),
+	}
+
+	if raw, ok := ensouling.PromptDiff["sections"]; ok {
+		data, _ := json.Marshal(raw)
+		json.Unmarshal(data, &diff.Sections)
+	} else {
+		// Ensoulings recorded before diffs were stored: rebuild the section
+		// diff from the previous version's prompt.
+		diff.Sections = diffPromptSections(previousPrompt(shell, &ensouling), ensouling.NewPrompt)
+	}
+
+	return diff, nil
+}
+
+// previousPrompt returns the soul prompt that was in effect before an Ensouling.
+func previousPrompt(shell *models.Shell, ensouling *models.Ensouling) string {
+	var prev models.Ensouling
+	if err := database.DB.Where("shell_id = ? AND version_to = ? AND status = ?", shell.ID, ensouling.VersionFrom, models.EnsoulingDeployed).
+		First(&prev).Error; err == nil {
+		return prev.NewPrompt
+	}
+	return buildInitialSoulPrompt(shell.Handle, shell.SeedSummary)
+}
+
+// snapshotDimensions copies a shell's dimensions so later in-place updates
+// don't alias the stored "before" state.
+func snapshotDimensions(dims models.JSON) models.JSON {
+	data, _ := json.Marshal(dims)
+	out := make(models.JSON)
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// diffDimensions compares two dimension maps across all six dimensions.
+func diffDimensions(before, after models.JSON) []DimensionDiff {
+	b := (&models.Shell{Dimensions: before}).GetDimensions()
+	a := (&models.Shell{Dimensions: after}).GetDimensions()
+
+	dims := []string{
+		models.DimPersonality, models.DimKnowledge, models.DimStance,
+		models.DimStyle, models.DimRelationship, models.DimTimeline,
+	}
+	result := make([]DimensionDiff, 0, len(dims))
+	for _, dim := range dims {
+		result = append(result, DimensionDiff{
+			Dimension:     dim,
+			ScoreBefore:   b[dim].Score,
+			ScoreAfter:    a[dim].Score,
+			Delta:         a[dim].Score - b[dim].Score,
+			SummaryBefore: b[dim].Summary,
+			SummaryAfter:  a[dim].Summary,
+		})
+	}
+	return result
+}
+
+// diffPromptSections compares two prompts section by section, matching
+// sections by title and reporting only counts.
+func diffPromptSections(before, after string) []PromptSectionDiff {
+	oldSections := splitPromptSections(before)
+	newSections := splitPromptSections(after)
+
+	oldByTitle := make(map[string]promptSection, len(oldSections))
+	for _, s := range oldSections {
+		oldByTitle[s.title] = s
+	}
+	seen := make(map[string]bool, len(newSections))
+
+	var result []PromptSectionDiff
+	for _, s := range newSections {
+		seen[s.title] = true
+		old, existed := oldByTitle[s.title]
+		d := PromptSectionDiff{
+			Section:    s.title,
+			WordsAfter: countWords(s.lines),
+		}
+		if !existed {
+			d.Status = SectionAdded
+			d.LinesAdded = len(s.lines)
+		} else {
+			d.WordsBefore = countWords(old.lines)
+			d.LinesAdded, d.LinesRemoved = diffLines(old.lines, s.lines)
+			d.Status = SectionUnchanged
+			if d.LinesAdded > 0 || d.LinesRemoved > 0 {
+				d.Status = SectionChanged
+			}
+		}
+		result = append(result, d)
+	}
+
+	for _, s := range oldSections {
+		if seen[s.title] {
+			continue
+		}
+		result = append(result, PromptSectionDiff{
+			Section:      s.title,
+			Status:       SectionRemoved,
+			WordsBefore:  countWords(s.lines),
+			LinesRemoved: len(s.lines),
+		})
+	}
+	return result
+}
+
+// splitPromptSections splits a prompt into sections at heading-like lines
+// (markdown headings, "--- x ---" separators, "[dimension]" tags and short
+// lines ending in a colon). Text before the first heading is the "Introduction".
+func splitPromptSections(prompt string) []promptSection {
+	var sections []promptSection
+	current := promptSection{title: "Introduction"}
+	titles := make(map[string]int)
+
+	flush := func() {
+		if current.title == "Introduction" && len(current.lines) == 0 {
+			return
+		}
+		sections = append(sections, current)
+	}
+
+	for _, raw := range strings.Split(prompt, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if title, ok := sectionHeading(line); ok {
+			flush()
+			// Disambiguate repeated headings so they still match positionally
+			titles[title]++
+			if n := titles[title]; n > 1 {
+				title = fmt.Sprintf("%s (%d)", title, n)
+			}
+			current = promptSection{title: title}
+			continue
+		}
+		current.lines = append(current.lines, line)
+	}
+	flush()
+	return sections
+}
+
+// sectionHeading reports whether a prompt line is a section heading and returns its title.
+func sectionHeading(line string) (string, bool) {
+	switch {
+	case strings.HasPrefix(line, "#"):
+		return strings.TrimSpace(strings.TrimLeft(line, "#")), true
+	case strings.HasPrefix(line, "---") && strings.HasSuffix(line, "---") && len(line) > 6:
+		return strings.TrimSpace(strings.Trim(line, "-")), true
+	case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") && len(line) <= 40:
+		return strings.Trim(line, "[]"), true
+	case strings.HasSuffix(line, ":") && len(line) <= 60 && !strings.HasPrefix(line, "-"):
+		return strings.TrimSuffix(line, ":"), true
+	}
+	return "", false
+}
+
+// diffLines counts lines present only in b (added) and only in a (removed),
+// treating each side as a multiset.
+func diffLines(a, b []string) (added, removed int) {
+	counts := make(map[string]int, len(a))
+	for _, l := range a {
+		counts[l]++
+	}
+	for _, l := range b {
+		if counts[l] > 0 {
+			counts[l]--
+		} else {
+			added++
+		}
+	}
+	for _, n := range counts {
+		removed += n
+	}
+	return added, removed
+}
+
+func countWords(lines []string) int {
+	n := 0
+	for _, l := range lines {
+		n += len(strings.Fields(l))
+	}
+	return n
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// dimensionBlockResult is the LLM output for a partial ensouling.
+type dimensionBlockResult struct {
+	Block       string `json:"block"`
+	Score       int    `json:"score"`
+	Summary     string `json:"summary"`
+	SummaryDiff string `json:"summary_diff"`
+	Essence     string `json:"essence"`
+}
+
+// dueDimension returns a dimension whose unmerged fragments have reached
+// ENSOULING_DIMENSION_THRESHOLD and whose block can be rewritten on its own,
+// or "" if none is due. Partial ensouling is off when the threshold isn't
+// below the full one, since a full ensouling would always come first.
+func dueDimension(shell *models.Shell, fullThreshold int64) string {
+	threshold := int64(config.Cfg.EnsoulingDimensionThreshold)
+	if threshold <= 0 || threshold >= fullThreshold {
+		return ""
+	}
+
+	var counts []struct {
+		Dimension string
+		N         int64
+	}
+	database.DB.Model(&models.Fragment{}).
+		Select("dimension, COUNT(*) AS n").
+		Where("shell_id = ? AND status = ? AND ensouling_id IS NULL AND subject_flag = ''",
+			shell.ID, models.FragStatusAccepted).
+		Group("dimension").Order("n DESC").
+		Scan(&counts)
+
+	lines := strings.Split(shell.SoulPrompt, "\n")
+	for _, c := range counts {
+		if c.N < threshold {
+			break
+		}
+		if _, _, ok := promptBlockBounds(lines, c.Dimension); ok {
+			return c.Dimension
+		}
+	}
+	return ""
+}
+
+// TriggerDimensionEnsouling merges the unmerged fragments of one dimension by
+// rewriting only that dimension's block of the soul prompt. It is a no-op for
+// prompts without per-dimension blocks (souls not yet ensouled since blocks
+// were introduced); those catch up at their next full ensouling.
+func TriggerDimensionEnsouling(shell *models.Shell, dimension string) {
+	if hasQuarantinedEnsouling(shell.ID) {
+		util.Log.Debug("[ensouling] @%s has a quarantined version awaiting review, skipping", shell.Handle)
+		return
+	}
+	lines := strings.Split(shell.SoulPrompt, "\n")
+	if _, _, ok := promptBlockBounds(lines, dimension); !ok {
+		return
+	}
+
+	var fragments []models.Fragment
+	database.DB.Where("shell_id = ? AND dimension = ? AND status = ? AND ensouling_id IS NULL AND subject_flag = ''",
+		shell.ID, dimension, models.FragStatusAccepted).
+		Order("created_at ASC").
+		Find(&fragments)
+
+	if len(fragments) == 0 {
+		return
+	}
+
+	ensouling := &models.Ensouling{
+		ShellID:          shell.ID,
+		VersionFrom:      shell.DNAVersion,
+		VersionTo:        shell.DNAVersion + 1,
+		FragsMerged:      len(fragments),
+		Dimension:        dimension,
+		DimensionsBefore: snapshotDimensions(shell.Dimensions),
+		Status:           models.EnsoulingDeployed,
+	}
+
+	current := shell.GetDimensions()
+	var block *dimensionBlockResult
+	if LLMConfigured() {
+		var err error
+		block, err = ensoulDimensionWithLLM(shell, dimension, fragments)
+		if err != nil {
+			util.Log.Warn("[ensouling] LLM partial ensouling failed, using fallback: %v", err)
+			block = nil
+		}
+	}
+	if block == nil {
+		block = ensoulDimensionFallback(shell, dimension, fragments)
+	}
+
+	newPrompt, _ := replacePromptBlock(shell.SoulPrompt, dimension, block.Block)
+	dims := make(map[string]models.DimensionData, len(current)+1)
+	for k, v := range current {
+		dims[k] = v
+	}
+	dims[dimension] = models.DimensionData{Score: block.Score, Summary: block.Summary}
+
+	commitEnsouling(shell, ensouling, fragments, &EnsoulingResult{
+		NewPrompt:   newPrompt,
+		Dimensions:  dims,
+		SummaryDiff: block.SummaryDiff,
+		Essence:     block.Essence,
+	})
+}
+
+// ensoulDimensionWithLLM rewrites one dimension's block using the LLM. The
+// rest of the prompt is given as read-only context, which keeps the call far
+// smaller than a full condensation.
+func ensoulDimensionWithLLM(shell *models.Shell, dimension string, fragments []models.Fragment) (*dimensionBlockResult, error) {
+	lines := strings.Split(shell.SoulPrompt, "\n")
+	start, end, _ := promptBlockBounds(lines, dimension)
+	currentBlock := strings.TrimSpace(strings.Join(lines[start+1:end], "\n"))
+	overview := strings.TrimSpace(strings.Join(lines[:overviewLines(lines)], "\n"))
+
+	var fragList strings.Builder
+	for i, f := range fragments {
+		fragList.WriteString(fmt.Sprintf("[%d] Confidence: %.2f\n%s\n\n", i+1, f.Confidence, fragmentPromptText(f)))
+	}
+
+	var totalAccepted int64
+	database.DB.Model(&models.Fragment{}).
+		Where("shell_id = ? AND dimension = ? AND status = ?", shell.ID, dimension, models.FragStatusAccepted).
+		Count(&totalAccepted)
+	data := shell.GetDimensions()[dimension]
+	depthTier, scoringGuide := ensoulingDepthTier(shell)
+
+	prompt := fmt.Sprintf(`You are the Ensouling engine for Ensoul, a decentralized soul construction protocol.
+You perform a partial "soul condensation" — merging new verified fragments into ONE dimension of an existing soul profile.
+
+=== CURRENT SOUL ===
+Handle: @%s
+Seed Summary: %s
+Current Essence: %s
+Depth Tier: %s
+
+=== PROMPT OVERVIEW (context only, do not rewrite) ===
+%s
+
+=== CURRENT [%s] BLOCK ===
+%s
+
+=== CURRENT SCORE ===
+%s: current_score=%d, total_accepted_fragments=%d, new_fragments_this_batch=%d
+
+=== NEW %s FRAGMENTS TO MERGE (total: %d) ===
+%s
+
+=== YOUR TASK ===
+1. Rewrite the [%s] block so it incorporates the new fragments naturally (not just appending bullet points)
+2. Keep everything in the current block that the new fragments don't contradict
+3. Describe only this dimension; do not include the tag line or other dimensions
+4. Write in English, in second person ("You ..."), as part of a character prompt
+5. Keep the block under 250 words
+6. Update this dimension's score (0-100) and one-sentence summary
+7. Return the soul's public essence, revised only if the new fragments change it
+
+%s
+
+=== SCORING RULES (CRITICAL) ===
+The score measures OUR DATA COVERAGE of this dimension, not the person's trait strength or fame.
+Use the scoring guide for this soul's tier:
+%s
+Never increase the score by more than 15 points in a single ensouling.
+
+Respond in JSON format ONLY:
+{
+  "block": "You ...",
+  "score": 25,
+  "summary": "...",
+  "summary_diff": "Brief description of what changed in this dimension...",
+  "essence": "@%s is ..."
+}`,
+		shell.Handle, shell.SeedSummary, ShellEssence(shell), depthTier,
+		overview,
+		dimension, currentBlock,
+		dimension, data.Score, totalAccepted, len(fragments),
+		strings.ToUpper(dimension), len(fragments), fragList.String(),
+		dimension, essenceGuidance,
+		scoringGuide, shell.Handle)
+
+	var result dimensionBlockResult
+	tag := LLMCallTag{
+		Feature: models.LLMFeatureEnsouling, ShellID: &shell.ID,
+		Mock: &MockInput{Handle: shell.Handle, Dimension: dimension, Scores: map[string][2]int{dimension: {data.Score, len(fragments)}}},
+	}
+	err := CallLLMJSON(context.Background(), tag, []ChatMessage{
+		{Role: "system", Content: "You are a precise soul construction engine. Output valid JSON only, no markdown."},
+		{Role: "user", Content: prompt},
+	}, 1200, 0.4, &result)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(result.Block) == "" {
+		return nil, fmt.Errorf("empty block")
+	}
+
+	// Same cap as the full prompt asks for, enforced since only one score moves
+	result.Score = max(0, min(result.Score, data.Score+15, 100))
+	if result.Summary == "" {
+		result.Summary = data.Summary
+	}
+	util.Log.Debug("[ensouling] LLM partial ensouling of @%s [%s]: %s", shell.Handle, dimension, result.SummaryDiff)
+	return &result, nil
+}
+
+// ensoulDimensionFallback appends the fragments to the block when LLM is unavailable.
+func ensoulDimensionFallback(shell *models.Shell, dimension string, fragments []models.Fragment) *dimensionBlockResult {
+	lines := strings.Split(shell.SoulPrompt, "\n")
+	start, end, _ := promptBlockBounds(lines, dimension)
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(strings.Join(lines[start+1:end], "\n")))
+	for _, f := range fragments {
+		fmt.Fprintf(&b, "\n- %s", f.Content)
+	}
+
+	data := shell.GetDimensions()[dimension]
+	return &dimensionBlockResult{
+		Block:       b.String(),
+		Score:       data.Score,
+		Summary:     data.Summary,
+		SummaryDiff: fmt.Sprintf("Merged %d new %s fragments. DNA upgraded from v%d to v%d.", len(fragments), dimension, shell.DNAVersion, shell.DNAVersion+1),
+	}
+}
+
+// dimensionTag returns the dimension named by a "[dimension]" block tag line, or "".
+func dimensionTag(line string) string {
+	t := strings.ToLower(strings.TrimSpace(line))
+	if !strings.HasPrefix(t, "[") || !strings.HasSuffix(t, "]") {
+		return ""
+	}
+	name := t[1 : len(t)-1]
+	for _, d := range dimensionOrder {
+		if d == name {
+			return d
+		}
+	}
+	return ""
+}
+
+// promptBlockBounds finds a dimension's block: the tag line at start and the
+// body up to the next dimension tag (or the end) at end. A tag that appears
+// more than once, as in prompts extended by ensoulFallback, makes the block
+// ambiguous and ok is false.
+func promptBlockBounds(lines []string, dimension string) (start, end int, ok bool) {
+	start = -1
+	for i, line := range lines {
+		tag := dimensionTag(line)
+		if tag == "" {
+			continue
+		}
+		if tag == dimension {
+			if start >= 0 {
+				return 0, 0, false
+			}
+			start = i
+			end = len(lines)
+		} else if start >= 0 && end == len(lines) {
+			end = i
+		}
+	}
+	return start, end, start >= 0
+}
+
+// overviewLines returns the number of lines before the first dimension block.
+func overviewLines(lines []string) int {
+	for i, line := range lines {
+		if dimensionTag(line) != "" {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// replacePromptBlock swaps the body of a dimension's block for body. Tag
+// lines in body are dropped so a rewrite can't break the block structure.
+func replacePromptBlock(prompt, dimension, body string) (string, bool) {
+	lines := strings.Split(prompt, "\n")
+	start, end, ok := promptBlockBounds(lines, dimension)
+	if !ok {
+		return prompt, false
+	}
+
+	out := make([]string, 0, len(lines))
+	out = append(out, lines[:start+1]...)
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if dimensionTag(line) == "" {
+			out = append(out, line)
+		}
+	}
+	if end < len(lines) {
+		out = append(out, "")
+	}
+	out = append(out, lines[end:]...)
+	return strings.Join(out, "\n"), true
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// Weights of the gate scores in a full batch. A partial (one dimension)
+// batch has no spread, so it is scored on confidence and novelty alone.
+const (
+	gateWeightConfidence = 0.4
+	gateWeightSpread     = 0.2
+	gateWeightNovelty    = 0.4
+)
+
+// ensoulingGate decides whether the unmerged fragments of a shell (of one
+// dimension, if set) that reached their threshold are worth condensing now.
+// The batch is scored on its average confidence, how many dimensions it
+// covers and how much it adds to the fragments already merged; below
+// ENSOULING_GATE_MIN_SCORE it is deferred until more fragments arrive, up to
+// ENSOULING_GATE_MAX_BACKLOG times the threshold. Every decision is stored.
+func ensoulingGate(shell *models.Shell, dimension string, threshold int64) bool {
+	minScore := config.Cfg.EnsoulingGateMinScore
+	if minScore <= 0 || hasQuarantinedEnsouling(shell.ID) {
+		return true
+	}
+
+	query := database.DB.Select("id", "dimension", "confidence").
+		Where("shell_id = ? AND status = ? AND ensouling_id IS NULL AND subject_flag = ''",
+			shell.ID, models.FragStatusAccepted)
+	if dimension != "" {
+		query = query.Where("dimension = ?", dimension)
+	}
+	var pending []models.Fragment
+	query.Find(&pending)
+	if len(pending) == 0 {
+		return true
+	}
+
+	d := models.EnsoulingGateDecision{
+		ShellID:    shell.ID,
+		Dimension:  dimension,
+		Pending:    len(pending),
+		Threshold:  int(threshold),
+		Confidence: batchConfidence(pending),
+		Spread:     batchSpread(pending),
+		Novelty:    batchNovelty(shell.ID, pending),
+		MinScore:   minScore,
+	}
+	if dimension == "" {
+		d.Score = gateWeightConfidence*d.Confidence + gateWeightSpread*d.Spread + gateWeightNovelty*d.Novelty
+	} else {
+		d.Score = (gateWeightConfidence*d.Confidence + gateWeightNovelty*d.Novelty) /
+			(gateWeightConfidence + gateWeightNovelty)
+	}
+	d.Confidence, d.Spread, d.Novelty, d.Score =
+		roundTo(d.Confidence, 4), roundTo(d.Spread, 4), roundTo(d.Novelty, 4), roundTo(d.Score, 4)
+
+	backlog := int64(config.Cfg.EnsoulingGateMaxBacklog)
+	switch {
+	case d.Score >= minScore:
+		d.Decision = models.GateProceed
+	case backlog > 0 && int64(len(pending)) >= threshold*backlog:
+		d.Decision = models.GateForced
+	default:
+		d.Decision = models.GateDefer
+	}
+	if err := database.DB.Create(&d).Error; err != nil {
+		util.Log.Warn("[ensouling] Failed to record gate decision for @%s: %v", shell.Handle, err)
+	}
+
+	batch := "batch"
+	if dimension != "" {
+		batch = dimension + " batch"
+	}
+	switch d.Decision {
+	case models.GateDefer:
+		util.Log.Info("[ensouling] Deferred @%s %s of %d: score %.2f < %.2f (confidence %.2f, spread %.2f, novelty %.2f)",
+			shell.Handle, batch, d.Pending, d.Score, minScore, d.Confidence, d.Spread, d.Novelty)
+	case models.GateForced:
+		util.Log.Info("[ensouling] Condensing @%s %s of %d despite score %.2f: backlog limit reached",
+			shell.Handle, batch, d.Pending, d.Score)
+	}
+	return d.Decision != models.GateDefer
+}
+
+// batchConfidence is the average confidence of the fragments.
+func batchConfidence(fragments []models.Fragment) float64 {
+	var sum float64
+	for _, f := range fragments {
+		sum += f.Confidence
+	}
+	return sum / float64(len(fragments))
+}
+
+// batchSpread is the share of dimensions the fragments cover, out of as many
+// as a batch that size could cover.
+func batchSpread(fragments []models.Fragment) float64 {
+	dims := map[string]bool{}
+	for _, f := range fragments {
+		dims[f.Dimension] = true
+	}
+	return float64(len(dims)) / float64(min(len(fragments), len(validDimensions)))
+}
+
+// batchNovelty is 1 minus the average, over the fragments, of their highest
+// similarity to a merged fragment of the same dimension. A fragment whose
+// dimension has nothing merged yet is fully novel, and so is the batch if
+// embeddings are unavailable: the gate fails open.
+func batchNovelty(shellID uuid.UUID, fragments []models.Fragment) float64 {
+	if err := ensureShellEmbeddings(shellID); err != nil {
+		util.Log.Warn("[ensouling] Gate novelty unavailable for shell %s: %v", shellID, err)
+		return 1
+	}
+	var rows []struct {
+		FragmentID uuid.UUID
+		Dimension  string
+		Merged     bool
+		Vector     models.Vector
+	}
+	if err := database.DB.Table("fragment_embeddings e").
+		Select("e.fragment_id, f.dimension, f.ensouling_id IS NOT NULL AS merged, e.vector").
+		Joins("JOIN fragments f ON f.id = e.fragment_id AND f.status = ? AND f.deleted_at IS NULL", models.FragStatusAccepted).
+		Where("e.shell_id = ? AND e.model = ?", shellID, EmbeddingModel()).
+		Scan(&rows).Error; err != nil {
+		util.Log.Warn("[ensouling] Gate novelty unavailable for shell %s: %v", shellID, err)
+		return 1
+	}
+
+	merged := map[string][]models.Vector{}
+	vectors := map[uuid.UUID]models.Vector{}
+	for _, r := range rows {
+		if r.Merged {
+			merged[r.Dimension] = append(merged[r.Dimension], r.Vector)
+		} else {
+			vectors[r.FragmentID] = r.Vector
+		}
+	}
+
+	var sum float64
+	for _, f := range fragments {
+		v, ok := vectors[f.ID]
+		if !ok {
+			sum++
+			continue
+		}
+		var best float64
+		for _, m := range merged[f.Dimension] {
+			best = max(best, CosineSimilarity(v, m))
+		}
+		sum += 1 - best
+	}
+	return sum / float64(len(fragments))
+}
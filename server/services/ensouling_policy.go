@@ -0,0 +1,347 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultEnsoulingTiers seeds the ensouling_tiers table on first start.
+// Larger accounts have more public data, so they need more fragments per
+// ensouling and per score band.
+var defaultEnsoulingTiers = []models.EnsoulingTier{
+	{
+		Name:         "MEGA",
+		MinFollowers: 1_000_000,
+		Threshold:    20,
+		Description:  "extremely rich public data, needs 80+ fragments per dimension to reach high scores",
+		ScoringGuide: `  0-5:   Almost no data (0-2 fragments, only seed info)
+  5-12:  Minimal data (3-8 fragments, surface-level)
+  12-25: Basic coverage (9-20 fragments, some evidence)
+  25-40: Moderate coverage (21-40 fragments, multiple angles)
+  40-55: Good coverage (41-60 fragments, detailed with citations)
+  55-70: Strong coverage (61-80 fragments, comprehensive)
+  70-85: Excellent coverage (81-120 fragments, deep multi-source)
+  85-100: Near-complete (120+ fragments, exhaustive — rarely achievable)`,
+	},
+	{
+		Name:         "LARGE",
+		MinFollowers: 100_000,
+		Threshold:    15,
+		Description:  "rich public data, needs 50+ fragments per dimension for high scores",
+		ScoringGuide: `  0-8:   Almost no data (0-2 fragments, only seed info)
+  8-18:  Minimal data (3-6 fragments, surface-level)
+  18-30: Basic coverage (7-15 fragments, some evidence)
+  30-45: Moderate coverage (16-30 fragments, multiple angles)
+  45-60: Good coverage (31-50 fragments, detailed with citations)
+  60-75: Strong coverage (51-70 fragments, comprehensive)
+  75-90: Excellent coverage (70+ fragments, deep multi-source)
+  90-100: Near-complete (100+ fragments, exhaustive — rarely achievable)`,
+	},
+	{
+		Name:         "MEDIUM",
+		MinFollowers: 10_000,
+		Threshold:    12,
+		Description:  "moderate public data, needs 30+ fragments per dimension for high scores",
+		ScoringGuide: `  0-10:  Almost no data (0-2 fragments, only seed info)
+  10-20: Minimal data (3-5 fragments, surface-level)
+  20-35: Basic coverage (6-12 fragments, some evidence)
+  35-50: Moderate coverage (13-25 fragments, multiple angles)
+  50-65: Good coverage (26-40 fragments, detailed with citations)
+  65-80: Strong coverage (41-55 fragments, comprehensive)
+  80-90: Excellent coverage (55+ fragments, deep analysis)
+  90-100: Near-complete (70+ fragments, exhaustive — rarely achievable)`,
+	},
+	{
+		Name:         "SMALL",
+		MinFollowers: 1_000,
+		Threshold:    10,
+		Description:  "limited public data, needs 15+ fragments per dimension for high scores",
+		ScoringGuide: `  0-12:  Almost no data (0-2 fragments, only seed info)
+  12-25: Minimal data (3-4 fragments, surface-level)
+  25-40: Basic coverage (5-8 fragments, some evidence)
+  40-55: Moderate coverage (9-15 fragments, multiple angles)
+  55-70: Good coverage (16-25 fragments, detailed)
+  70-85: Strong coverage (26-35 fragments, comprehensive)
+  85-95: Excellent coverage (35+ fragments, deep analysis)
+  95-100: Near-complete (50+ fragments, exhaustive)`,
+	},
+	{
+		Name:         "MICRO",
+		MinFollowers: 0,
+		Threshold:    6,
+		Description:  "very limited public data, needs 8+ fragments per dimension for high scores",
+		ScoringGuide: `  0-15:  Almost no data (0-1 fragments, only seed info)
+  15-30: Minimal data (2-3 fragments, surface-level)
+  30-50: Basic coverage (4-6 fragments, some evidence)
+  50-65: Moderate coverage (7-10 fragments, multiple angles)
+  65-80: Good coverage (11-15 fragments, detailed)
+  80-90: Strong coverage (16-20 fragments, comprehensive)
+  90-95: Excellent coverage (20+ fragments, thorough analysis)
+  95-100: Near-complete (30+ fragments, exhaustive)`,
+	},
+}
+
+// ensoulingPolicy is the in-memory copy of the policy tables, reloaded
+// periodically so edits made through the admin API (or directly in the
+// database) apply to every instance without a restart.
+var ensoulingPolicy struct {
+	sync.RWMutex
+	tiers      []models.EnsoulingTier // by MinFollowers, descending
+	overrides  map[uuid.UUID]models.ShellPolicyOverride
+	submission map[string]models.SubmissionRule // by dimension; missing = defaults
+	loadedAt   time.Time
+}
+
+// StartPolicyReload loads the ensouling policy now, seeding the default tiers
+// if the table is empty, and reloads it periodically.
+func StartPolicyReload(interval time.Duration) {
+	if err := seedEnsoulingTiers(); err != nil {
+		util.Log.Error("[policy] Failed to seed ensouling tiers: %v", err)
+	}
+	if err := reloadEnsoulingPolicy(); err != nil {
+		util.Log.Warn("[policy] %v", err)
+	}
+	scheduleJob("policy-reload", "Reload ensouling tiers, per-soul overrides and submission rules", interval, false, reloadEnsoulingPolicy)
+	util.Log.Info("[policy] Ensouling policy loaded (reloads every %v)", interval)
+}
+
+func seedEnsoulingTiers() error {
+	var count int64
+	if err := database.DB.Model(&models.EnsoulingTier{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	tiers := append([]models.EnsoulingTier(nil), defaultEnsoulingTiers...)
+	return database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&tiers).Error
+}
+
+// reloadEnsoulingPolicy refreshes the cache. On error, or with an empty
+// table, the previous policy (initially the defaults) stays in effect.
+func reloadEnsoulingPolicy() error {
+	var tiers []models.EnsoulingTier
+	if err := database.DB.Order("min_followers DESC").Find(&tiers).Error; err != nil {
+		return fmt.Errorf("failed to reload ensouling tiers: %w", err)
+	}
+	var overrides []models.ShellPolicyOverride
+	if err := database.DB.Find(&overrides).Error; err != nil {
+		return fmt.Errorf("failed to reload shell policy overrides: %w", err)
+	}
+
+	var rules []models.SubmissionRule
+	if err := database.DB.Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to reload submission rules: %w", err)
+	}
+
+	byShell := make(map[uuid.UUID]models.ShellPolicyOverride, len(overrides))
+	for _, o := range overrides {
+		byShell[o.ShellID] = o
+	}
+	byDimension := make(map[string]models.SubmissionRule, len(rules))
+	for _, r := range rules {
+		byDimension[r.Dimension] = r
+	}
+
+	ensoulingPolicy.Lock()
+	defer ensoulingPolicy.Unlock()
+	if len(tiers) > 0 {
+		ensoulingPolicy.tiers = tiers
+	}
+	ensoulingPolicy.overrides = byShell
+	ensoulingPolicy.submission = byDimension
+	ensoulingPolicy.loadedAt = time.Now()
+	return nil
+}
+
+// currentTiers returns the tiers in effect, highest MinFollowers first.
+func currentTiers() []models.EnsoulingTier {
+	ensoulingPolicy.RLock()
+	defer ensoulingPolicy.RUnlock()
+	if len(ensoulingPolicy.tiers) == 0 {
+		return defaultEnsoulingTiers
+	}
+	return ensoulingPolicy.tiers
+}
+
+// shellPolicy returns the tier that applies to a shell and its override, if any.
+func shellPolicy(shell *models.Shell) (models.EnsoulingTier, *models.ShellPolicyOverride) {
+	tiers := currentTiers()
+
+	ensoulingPolicy.RLock()
+	override, hasOverride := ensoulingPolicy.overrides[shell.ID]
+	ensoulingPolicy.RUnlock()
+
+	if hasOverride && override.Tier != "" {
+		for _, t := range tiers {
+			if t.Name == override.Tier {
+				return t, &override
+			}
+		}
+	}
+
+	followers := getFollowers(*shell)
+	tier := tiers[len(tiers)-1]
+	for _, t := range tiers {
+		if followers >= t.MinFollowers {
+			tier = t
+			break
+		}
+	}
+	if hasOverride {
+		return tier, &override
+	}
+	return tier, nil
+}
+
+// EnsoulingThreshold returns the number of new accepted fragments needed
+// to trigger the next ensouling: the shell's tier threshold, unless an
+// override sets one.
+func EnsoulingThreshold(shell *models.Shell) int64 {
+	tier, override := shellPolicy(shell)
+	if override != nil && override.Threshold > 0 {
+		return int64(override.Threshold)
+	}
+	return int64(tier.Threshold)
+}
+
+// ensoulingDepthTier describes the shell's tier and scoring guide for the ensouling prompt.
+func ensoulingDepthTier(shell *models.Shell) (string, string) {
+	tier, _ := shellPolicy(shell)
+	return fmt.Sprintf("%s (%d followers) — %s", tier.Name, getFollowers(*shell), tier.Description), tier.ScoringGuide
+}
+
+// GetEnsoulingPolicy returns the tiers in effect. With a shell it also
+// returns the policy that applies to that shell.
+func GetEnsoulingPolicy(shell *models.Shell) map[string]interface{} {
+	ensoulingPolicy.RLock()
+	loadedAt := ensoulingPolicy.loadedAt
+	ensoulingPolicy.RUnlock()
+
+	result := map[string]interface{}{
+		"tiers":            currentTiers(),
+		"submission_rules": SubmissionRules(),
+		"gate": map[string]interface{}{
+			"min_score":   config.Cfg.EnsoulingGateMinScore,
+			"max_backlog": config.Cfg.EnsoulingGateMaxBacklog,
+		},
+		"loaded_at": loadedAt,
+	}
+	if shell != nil {
+		tier, override := shellPolicy(shell)
+		info := map[string]interface{}{
+			"handle":    shell.Handle,
+			"followers": getFollowers(*shell),
+			"tier":      tier.Name,
+			"threshold": EnsoulingThreshold(shell),
+			"override":  override,
+		}
+		var gate models.EnsoulingGateDecision
+		if database.DB.Where("shell_id = ?", shell.ID).Order("created_at DESC").First(&gate).Error == nil {
+			info["last_gate"] = gate
+		}
+		result["shell"] = info
+	}
+	return result
+}
+
+// UpdateEnsoulingTiers replaces all tiers. Names must be unique, one tier must
+// start at 0 followers, and every tier needs a positive threshold and a guide.
+func UpdateEnsoulingTiers(tiers []models.EnsoulingTier, updatedBy string) ([]models.EnsoulingTier, error) {
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("at least one tier is required")
+	}
+	names := make(map[string]bool, len(tiers))
+	mins := make(map[int]bool, len(tiers))
+	for i := range tiers {
+		t := &tiers[i]
+		t.Name = strings.ToUpper(strings.TrimSpace(t.Name))
+		switch {
+		case t.Name == "" || len(t.Name) > 20:
+			return nil, fmt.Errorf("tier %d: name must be 1-20 characters", i+1)
+		case names[t.Name]:
+			return nil, fmt.Errorf("duplicate tier name %s", t.Name)
+		case t.MinFollowers < 0 || mins[t.MinFollowers]:
+			return nil, fmt.Errorf("tier %s: min_followers must be non-negative and unique", t.Name)
+		case t.Threshold < 1:
+			return nil, fmt.Errorf("tier %s: threshold must be at least 1", t.Name)
+		case strings.TrimSpace(t.ScoringGuide) == "":
+			return nil, fmt.Errorf("tier %s: scoring_guide is required", t.Name)
+		}
+		names[t.Name] = true
+		mins[t.MinFollowers] = true
+		t.ID = uuid.Nil
+		t.UpdatedBy = updatedBy
+	}
+	if !mins[0] {
+		return nil, fmt.Errorf("one tier must have min_followers 0")
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinFollowers > tiers[j].MinFollowers })
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.EnsoulingTier{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&tiers).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save tiers: %w", err)
+	}
+
+	reloadEnsoulingPolicy()
+	return currentTiers(), nil
+}
+
+// SetShellPolicyOverride pins a shell to a tier and/or threshold.
+func SetShellPolicyOverride(shell *models.Shell, tier string, threshold int, note, updatedBy string) (*models.ShellPolicyOverride, error) {
+	tier = strings.ToUpper(strings.TrimSpace(tier))
+	if tier == "" && threshold == 0 {
+		return nil, fmt.Errorf("set a tier, a threshold, or both")
+	}
+	if threshold < 0 {
+		return nil, fmt.Errorf("threshold must be positive")
+	}
+	if tier != "" {
+		known := false
+		for _, t := range currentTiers() {
+			known = known || t.Name == tier
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown tier %q", tier)
+		}
+	}
+
+	override := &models.ShellPolicyOverride{
+		ShellID:   shell.ID,
+		Tier:      tier,
+		Threshold: threshold,
+		Note:      note,
+		UpdatedBy: updatedBy,
+	}
+	if err := database.DB.Save(override).Error; err != nil {
+		return nil, fmt.Errorf("failed to save override: %w", err)
+	}
+
+	reloadEnsoulingPolicy()
+	return override, nil
+}
+
+// DeleteShellPolicyOverride returns a shell to the default policy.
+func DeleteShellPolicyOverride(shell *models.Shell) error {
+	if err := database.DB.Where("shell_id = ?", shell.ID).Delete(&models.ShellPolicyOverride{}).Error; err != nil {
+		return err
+	}
+	reloadEnsoulingPolicy()
+	return nil
+}
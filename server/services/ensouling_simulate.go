@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// maxEnsoulingGain is the most a dimension score may rise in one ensouling,
+// as the ensouling prompt instructs the LLM.
+const maxEnsoulingGain = 15
+
+// SimulationCandidate is a fragment a Claw is thinking of submitting.
+type SimulationCandidate struct {
+	Dimension string
+	Content   string
+}
+
+// SimulatedCandidate says whether a candidate was counted in the projection.
+type SimulatedCandidate struct {
+	Dimension string `json:"dimension"`
+	Counted   bool   `json:"counted"`
+	Reason    string `json:"reason,omitempty"` // why it wasn't counted
+}
+
+// SimulatedDimension is the projected effect on one dimension.
+type SimulatedDimension struct {
+	Dimension        string    `json:"dimension"`
+	Score            int       `json:"score"`
+	Projected        int       `json:"projected"`
+	Delta            int       `json:"delta"`
+	Fragments        int       `json:"fragments"`          // accepted now
+	FragmentsAfter   int       `json:"fragments_after"`    // if everything counted is accepted
+	Band             ScoreBand `json:"band"`               // band of FragmentsAfter
+	Capped           bool      `json:"capped"`             // held back by the per-ensouling gain limit
+	NextFragmentGain int       `json:"next_fragment_gain"` // extra points one more fragment would project
+}
+
+// EnsoulingSimulation is a dry run of the next ensouling of a soul.
+type EnsoulingSimulation struct {
+	Handle           string               `json:"handle"`
+	DNAVersion       int                  `json:"dna_version"`
+	Dimensions       []SimulatedDimension `json:"dimensions"`
+	Candidates       []SimulatedCandidate `json:"candidates"`
+	PendingFragments int                  `json:"pending_fragments"` // the Claw's fragments awaiting review, counted as accepted
+	Unmerged         int64                `json:"unmerged"`          // accepted fragments waiting for the next ensouling
+	Threshold        int64                `json:"threshold"`
+	WouldEnsoul      bool                 `json:"would_ensoul"`
+	Recommended      []string             `json:"recommended"` // dimensions by next_fragment_gain, best first
+	TotalDelta       int                  `json:"total_delta"`
+}
+
+// SimulateEnsouling projects how the candidate fragments (and, with
+// includePending, the Claw's fragments still under review) would move a
+// soul's dimension scores if all were accepted and merged. The projection
+// applies the tier's scoring guide bands and the per-ensouling gain limit
+// the ensouling LLM works under; it calls no LLM and saves nothing.
+func SimulateEnsouling(claw *models.Claw, handle string, candidates []SimulationCandidate, includePending bool) (*EnsoulingSimulation, error) {
+	for _, c := range candidates {
+		if err := ValidateFragmentContent(c.Dimension, c.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	shell, err := GetShellByHandle(handle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: @%s", ErrShellNotFound, handle)
+	}
+	if !shell.OnChain() {
+		return nil, fmt.Errorf("@%s %w", handle, ErrShellNotMinted)
+	}
+	// A paused registry blocks writes, not this read-only projection
+	if err := checkShellActive(shell); err != nil && !errors.Is(err, ErrRegistryPaused) {
+		return nil, err
+	}
+
+	tier, _ := shellPolicy(shell)
+	bands := parseScoringGuide(tier.ScoringGuide)
+	if len(bands) == 0 {
+		return nil, fmt.Errorf("tier %s: %w", tier.Name, ErrNoScoreBands)
+	}
+	accepted, err := acceptedFragmentsByDimension(shell)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &EnsoulingSimulation{
+		Handle:     shell.Handle,
+		DNAVersion: shell.DNAVersion,
+		Threshold:  EnsoulingThreshold(shell),
+		Candidates: make([]SimulatedCandidate, len(candidates)),
+	}
+	added := make(map[string]int, len(dimensionOrder))
+
+	if includePending {
+		var pending []models.Fragment
+		if err := database.DB.Select("dimension").
+			Where("shell_id = ? AND claw_id = ? AND status = ?", shell.ID, claw.ID, models.FragStatusPending).
+			Find(&pending).Error; err != nil {
+			return nil, fmt.Errorf("failed to load pending fragments: %w", err)
+		}
+		for _, f := range pending {
+			added[f.Dimension]++
+		}
+		sim.PendingFragments = len(pending)
+	}
+
+	settings := GetShellSettings(shell.ID)
+	seen := make(map[string]bool, len(candidates))
+	for i, cand := range candidates {
+		result := SimulatedCandidate{Dimension: cand.Dimension}
+		hash := util.HashContent(cand.Content)
+		switch {
+		case !DimensionAllowed(settings, cand.Dimension):
+			result.Reason = "the owner is not accepting " + cand.Dimension + " fragments"
+		case settings.ContentPolicy == models.ContentPolicyClean && ContainsProfanity(cand.Content):
+			result.Reason = "violates the soul's clean content policy"
+		case seen[hash] || fragmentContentExists(shell, hash):
+			result.Reason = "duplicate of an existing fragment"
+		default:
+			result.Counted = true
+			added[cand.Dimension]++
+		}
+		seen[hash] = true
+		sim.Candidates[i] = result
+	}
+
+	current := shell.GetDimensions()
+	for _, dim := range dimensionOrder {
+		score := current[dim].Score
+		n, after := accepted[dim], accepted[dim]+added[dim]
+		projected, capped := projectScore(bands, score, after, added[dim])
+		next, _ := projectScore(bands, score, after+1, added[dim]+1)
+		sim.Dimensions = append(sim.Dimensions, SimulatedDimension{
+			Dimension:        dim,
+			Score:            score,
+			Projected:        projected,
+			Delta:            projected - score,
+			Fragments:        n,
+			FragmentsAfter:   after,
+			Band:             bandFor(bands, after),
+			Capped:           capped,
+			NextFragmentGain: next - projected,
+		})
+		sim.TotalDelta += projected - score
+	}
+
+	database.DB.Model(&models.Fragment{}).
+		Where("shell_id = ? AND status = ? AND ensouling_id IS NULL AND subject_flag = ''", shell.ID, models.FragStatusAccepted).
+		Count(&sim.Unmerged)
+	total := sim.Unmerged
+	for _, n := range added {
+		total += int64(n)
+	}
+	sim.WouldEnsoul = total >= sim.Threshold
+
+	ranked := append([]SimulatedDimension(nil), sim.Dimensions...)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].NextFragmentGain > ranked[j].NextFragmentGain })
+	sim.Recommended = []string{}
+	for _, d := range ranked {
+		if d.NextFragmentGain > 0 && DimensionAllowed(settings, d.Dimension) {
+			sim.Recommended = append(sim.Recommended, d.Dimension)
+		}
+	}
+	return sim, nil
+}
+
+// projectScore is the score a dimension would get once it has frags accepted
+// fragments, added of them new: at least the baseline of its band, within
+// the band, and at most maxEnsoulingGain above the current score. A dimension
+// without new fragments keeps its score.
+func projectScore(bands []ScoreBand, score, frags, added int) (int, bool) {
+	if added == 0 {
+		return score, false
+	}
+	band := bandFor(bands, frags)
+	projected := band.clamp(max(score, band.baseline(frags)))
+	if projected > score+maxEnsoulingGain {
+		return score + maxEnsoulingGain, true
+	}
+	return projected, false
+}
+
+// fragmentContentExists reports whether the soul already has a fragment with
+// this content that wasn't rejected.
+func fragmentContentExists(shell *models.Shell, contentHash string) bool {
+	var n int64
+	database.DB.Model(&models.Fragment{}).
+		Where("shell_id = ? AND content_hash = ? AND status <> ?", shell.ID, contentHash, models.FragStatusRejected).
+		Count(&n)
+	return n > 0
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// maxEssenceChars caps a soul's essence; it also goes into the agentURI.
+const maxEssenceChars = 800
+
+// essencePromptPatterns flag wording from the persona prompt rather than a
+// description of the person: second-person orders, dimension block tags and
+// talk of prompts or instructions. They run alongside promptInjectionPatterns.
+var essencePromptPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\byou are\b|\byou (must|should|will|never|always)\b|\byour (voice|persona|role)\b`),
+	regexp.MustCompile(`(?i)\[(personality|knowledge|stance|style|relationship|timeline)\]`),
+	regexp.MustCompile(`(?i)\b(system|soul|character) prompt\b|\binstructions?\b|\bstay in character\b|\bas an ai\b`),
+	regexp.MustCompile(`(?i)\bknowledge cutoff\b|\brespond (as|in character)\b`),
+}
+
+// essenceGuidance is the part of the ensouling prompts that asks for the essence.
+const essenceGuidance = `The essence is a public description of the person shown on their profile and on-chain:
+- 2-4 sentences in the third person, written in English
+- Only who they are, what they do and what they are known for
+- No instructions, no second person ("you"), no mention of prompts, souls, dimensions or scores`
+
+// publicEssence cleans an LLM-written essence for public display. It returns
+// "" when the text reads like prompt material, so the caller keeps the
+// previous essence.
+func publicEssence(shell *models.Shell, text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return ""
+	}
+	for _, group := range [][]*regexp.Regexp{promptInjectionPatterns, soulPromptPatterns, essencePromptPatterns} {
+		for _, p := range group {
+			if m := p.FindString(text); m != "" {
+				util.Log.Warn("[ensouling] Essence for @%s dropped, it contains %q", shell.Handle, m)
+				return ""
+			}
+		}
+	}
+	if utf8.RuneCountInString(text) <= maxEssenceChars {
+		return text
+	}
+
+	// Cut at the last sentence end that fits, or hard at the cap
+	runes := []rune(text)[:maxEssenceChars]
+	cut := string(runes)
+	if i := strings.LastIndexAny(cut, ".!?"); i > len(cut)/2 {
+		return cut[:i+1]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
+
+// ShellEssence returns a soul's public summary: its essence, or the seed
+// summary for souls not yet given one.
+func ShellEssence(shell *models.Shell) string {
+	if shell.Essence != "" {
+		return shell.Essence
+	}
+	return shell.SeedSummary
+}
+
+// RedactShell blanks what public shell endpoints never return: the soul
+// prompt, the core paid asset, and the seed summary, which the essence
+// stands in for.
+func RedactShell(shell *models.Shell) {
+	shell.Essence = ShellEssence(shell)
+	shell.SoulPrompt = ""
+	shell.SeedSummary = ""
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// FragmentAccepted is published once a fragment's acceptance has committed,
+// with the shell's counters refreshed.
+type FragmentAccepted struct {
+	Fragment *models.Fragment
+	Shell    *models.Shell
+}
+
+// EnsoulingCompleted is published once a new DNA version is live on the shell.
+type EnsoulingCompleted struct {
+	Shell     *models.Shell
+	Ensouling *models.Ensouling
+}
+
+// ShellStageChanged is published after a stage transition has been recorded.
+type ShellStageChanged struct {
+	Shell      *models.Shell
+	Transition *models.ShellStageTransition
+}
+
+// EventBus dispatches domain events to their subscribers. Subscribers run
+// synchronously in subscription order, so later ones see the effects of
+// earlier ones; slow work (chain writes, HTTP) starts its own goroutine. A
+// panicking subscriber is logged and doesn't stop the others.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[reflect.Type][]eventSubscriber
+}
+
+type eventSubscriber struct {
+	name string
+	fn   func(any)
+}
+
+// NewEventBus returns a bus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[reflect.Type][]eventSubscriber)}
+}
+
+// Events is the process-wide bus, wired by RegisterEventSubscribers.
+var Events = NewEventBus()
+
+// Subscribe registers fn for events of type E under a name used in logs.
+func Subscribe[E any](bus *EventBus, name string, fn func(E)) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	t := reflect.TypeFor[E]()
+	bus.subscribers[t] = append(bus.subscribers[t], eventSubscriber{
+		name: name,
+		fn:   func(e any) { fn(e.(E)) },
+	})
+}
+
+// Publish delivers event to every subscriber of its type.
+func Publish[E any](bus *EventBus, event E) {
+	bus.mu.RLock()
+	subs := bus.subscribers[reflect.TypeFor[E]()]
+	bus.mu.RUnlock()
+	for _, s := range subs {
+		deliverEvent(s, event)
+	}
+}
+
+func deliverEvent(s eventSubscriber, event any) {
+	defer func() {
+		if r := recover(); r != nil {
+			util.Log.Error("[events] Subscriber %s panicked on %T: %v", s.name, event, r)
+		}
+	}()
+	s.fn(event)
+}
+
+// RegisterEventSubscribers wires the side effects of domain events. Order
+// matters within an event: the stage is updated before the ensouling check,
+// which relies on the committed counters.
+func RegisterEventSubscribers(bus *EventBus) {
+	Subscribe(bus, "batch-verdict", func(e FragmentAccepted) { recordBatchVerdict(e.Fragment) })
+	Subscribe(bus, "stage", func(e FragmentAccepted) { UpdateShellStage(e.Shell) })
+	Subscribe(bus, "ensouling", func(e FragmentAccepted) { CheckEnsoulingThreshold(e.Shell) })
+	Subscribe(bus, "retrieval", func(e FragmentAccepted) { embedAcceptedFragment(*e.Fragment) })
+	Subscribe(bus, "chain-feedback", func(e FragmentAccepted) { submitOnChainFeedback(e.Fragment, e.Shell) })
+	Subscribe(bus, "stats", func(FragmentAccepted) { bumpDailyStat(statFragmentsAccepted, "", 1) })
+
+	Subscribe(bus, "stage", func(e EnsoulingCompleted) { UpdateShellStage(e.Shell) })
+	Subscribe(bus, "chain-uri", updateSoulURIOnChain)
+	Subscribe(bus, "webhooks", func(e EnsoulingCompleted) {
+		go EmitWebhookEvent(models.WebhookEventEnsouled, &e.Shell.ID, map[string]interface{}{
+			"handle":       e.Shell.Handle,
+			"agent_id":     e.Shell.AgentID,
+			"dna_version":  e.Ensouling.VersionTo,
+			"frags_merged": e.Ensouling.FragsMerged,
+			"dimension":    e.Ensouling.Dimension,
+			"ensouled_at":  e.Ensouling.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	})
+
+	Subscribe(bus, "webhooks", func(e ShellStageChanged) {
+		go EmitWebhookEvent(models.WebhookEventStageChanged, &e.Shell.ID, map[string]interface{}{
+			"handle":      e.Shell.Handle,
+			"agent_id":    e.Shell.AgentID,
+			"from_stage":  e.Transition.FromStage,
+			"to_stage":    e.Transition.ToStage,
+			"dna_version": e.Shell.DNAVersion,
+			"changed_at":  e.Transition.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	})
+	Subscribe(bus, "chain-stage", func(e ShellStageChanged) {
+		if e.Shell.AgentID != nil {
+			go setStageOnChain(e.Transition, *e.Shell.AgentID, e.Shell.Handle)
+		}
+	})
+
+	registerNotificationSubscribers(bus)
+}
+
+// updateSoulURIOnChain refreshes the agentURI of a shell linked to an
+// on-chain agent after an ensouling, keeping the tx hash on the ensouling.
+func updateSoulURIOnChain(e EnsoulingCompleted) {
+	shell, ensouling := e.Shell, e.Ensouling
+	if shell.AgentID == nil {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		agentId := new(big.Int).SetUint64(*shell.AgentID)
+		txHash, err := chain.UpdateSoulURI(
+			ctx, agentId, shell.Handle, SoulCardURL(shell.Handle),
+			ShellEssence(shell), shell.Stage, shell.DNAVersion,
+		)
+		trackChainSpend(txHash, models.ChainFeatureAgentURI, &shell.ID, nil)
+		if err != nil {
+			util.Log.Error("[ensouling] Failed to update agentURI on-chain for @%s: %v", shell.Handle, err)
+			return
+		}
+		if txHash != "" {
+			database.DB.Model(ensouling).Update("tx_hash", txHash)
+			util.Log.Debug("[ensouling] On-chain URI updated for @%s: tx=%s", shell.Handle, txHash)
+		}
+	}()
+}
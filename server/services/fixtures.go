@@ -0,0 +1,453 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// FixtureOwnerWallet owns every fixture soul and claimed fixture Claw. It is
+// the first Hardhat / Anvil test account, whose private key is public, so a
+// developer can import it into a browser wallet and act as the owner.
+const FixtureOwnerWallet = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+
+// fixtureAgentIDBase offsets fixture agent IDs far above real registrations.
+const fixtureAgentIDBase = 900000
+
+// ErrFixturesPresent is returned by SeedFixtures when the fixtures were
+// already seeded and no reset was asked for.
+var ErrFixturesPresent = errors.New("fixture data is already present, reset to re-seed")
+
+// fixtureClaw is a development Claw with a well-known API key.
+type fixtureClaw struct {
+	Name, Description, APIKey string
+	Claimed                   bool
+	Tags                      []string
+}
+
+var fixtureClaws = []fixtureClaw{
+	{"dev-archivist", "Reads primary sources: papers, letters, patents.", "ensoul_sk_dev_archivist", true, []string{"en", "history", "science"}},
+	{"dev-analyst", "Summarizes interviews, talks and secondary literature.", "ensoul_sk_dev_analyst", true, []string{"en", "biography"}},
+	{"dev-newcomer", "Freshly registered, waiting to be claimed.", "ensoul_sk_dev_newcomer", false, []string{"en"}},
+}
+
+// fixtureShell describes one fixture soul. The text fields fill the fragment
+// templates; the counts decide its stage the way UpdateShellStage would.
+type fixtureShell struct {
+	Handle, Name, Bio                       string
+	Field, Trait, Style, Stance, Peer, Work string
+	Stage                                   string
+	Followers                               int
+	Accepted, Rejected, Pending, Ensoulings int
+	Revoked                                 bool
+}
+
+var fixtureShells = []fixtureShell{
+	{
+		Handle: "ada_lovelace", Name: "Ada Lovelace", Bio: "Poetical scientist. Notes on the Analytical Engine.",
+		Field: "computing machines", Trait: "imaginative and fiercely precise", Style: "long, ornate sentences with careful footnotes",
+		Stance: "machines can manipulate symbols of any kind, not only numbers", Peer: "Charles Babbage", Work: "the Notes on the Analytical Engine",
+		Stage: models.StageEvolving, Followers: 820000, Accepted: 36, Rejected: 5, Ensoulings: 3,
+	},
+	{
+		Handle: "alan_turing", Name: "Alan Turing", Bio: "Mathematician. Computable numbers, morphogenesis, long-distance running.",
+		Field: "mathematical logic", Trait: "shy, playful and stubbornly independent", Style: "plain, direct prose with dry jokes",
+		Stance: "the question of whether machines can think should be replaced by an imitation game", Peer: "Alonzo Church", Work: "On Computable Numbers",
+		Stage: models.StageMature, Followers: 2400000, Accepted: 54, Rejected: 8, Ensoulings: 2,
+	},
+	{
+		Handle: "grace_hopper", Name: "Grace Hopper", Bio: "Rear admiral. Compilers. It's easier to ask forgiveness than permission.",
+		Field: "programming languages", Trait: "blunt, impatient with bureaucracy and generous to students", Style: "short anecdotes and props like nanosecond wires",
+		Stance: "programs should be written in words people understand, not machine code", Peer: "Howard Aiken", Work: "the A-0 compiler and COBOL",
+		Stage: models.StageGrowing, Followers: 310000, Accepted: 14, Rejected: 3, Pending: 2, Ensoulings: 1,
+	},
+	{
+		Handle: "hedy_lamarr", Name: "Hedy Lamarr", Bio: "Actress and inventor. Frequency hopping.",
+		Field: "radio guidance systems", Trait: "restless and quietly proud of her inventions", Style: "witty one-liners",
+		Stance: "inventing is easy for her, the hard part is being taken seriously", Peer: "George Antheil", Work: "the frequency-hopping patent",
+		Stage: models.StageGrowing, Followers: 150000, Accepted: 8, Rejected: 2, Revoked: true,
+	},
+	{
+		Handle: "marie_curie", Name: "Marie Curie", Bio: "Physicist and chemist. Radioactivity.",
+		Field: "radioactivity", Trait: "reserved and relentlessly persistent", Style: "sober, exact descriptions of method",
+		Stance: "science has great beauty and belongs to everyone", Peer: "Pierre Curie", Work: "the isolation of polonium and radium",
+		Stage: models.StageEmbryo, Followers: 95000, Pending: 2,
+	},
+	{
+		Handle: "nikola_tesla", Name: "Nikola Tesla", Bio: "Inventor. Alternating current.",
+		Stage: models.StagePending, Followers: 1200000,
+	},
+}
+
+// fixtureTemplates are the fragment texts per dimension; {name}, {field},
+// {trait}, {style}, {stance}, {peer} and {work} are filled per soul.
+var fixtureTemplates = map[string][]string{
+	models.DimPersonality: {
+		"{name} comes across as {trait}.",
+		"Colleagues remember {name} as {trait}, especially under pressure.",
+		"{name} treats setbacks as puzzles rather than defeats.",
+	},
+	models.DimKnowledge: {
+		"{name} has deep, first-hand knowledge of {field}.",
+		"{name}'s best-known technical contribution is {work}.",
+		"{name} reads widely outside {field} and borrows ideas freely.",
+	},
+	models.DimStance: {
+		"{name} argues that {stance}.",
+		"{name} is sceptical of authority that cannot explain its reasons.",
+		"{name} believes credit should follow the work, not the title.",
+	},
+	models.DimStyle: {
+		"{name} writes in {style}.",
+		"{name} explains hard ideas with everyday comparisons.",
+		"{name} tends to answer questions with a concrete example first.",
+	},
+	models.DimRelationship: {
+		"{name} worked closely with {peer}, and the collaboration shaped {work}.",
+		"{name} mentors younger people and answers their letters personally.",
+		"{name} keeps a small circle of trusted friends.",
+	},
+	models.DimTimeline: {
+		"{name}'s early years were spent teaching themself {field}.",
+		"The period around {work} was the most productive of {name}'s life.",
+		"Later in life {name} returned to questions first asked in youth.",
+	},
+}
+
+// fixtureAngles vary the templates so repeated facts read as separate sources.
+var fixtureAngles = []string{"", "In letters: ", "According to contemporaries: "}
+
+// fixtureRejected are fragments the curator turned down, with their reasons.
+var fixtureRejected = [][2]string{
+	{"{name} secretly invented the internet in a garden shed.", "Unsupported claim with no source."},
+	{"{name} is great.", "Too vague to add anything to the soul."},
+	{"Buy my course to learn what {name} really thought!", "Promotional content."},
+	{"{name} was born on the Moon.", "Factually false."},
+}
+
+// FixtureSummary lists what SeedFixtures created.
+type FixtureSummary struct {
+	OwnerWallet string            `json:"owner_wallet"`
+	Shells      []string          `json:"shells"`
+	Claws       []FixtureClawInfo `json:"claws"`
+	Fragments   int               `json:"fragments"`
+	Ensoulings  int               `json:"ensoulings"`
+	Sessions    int               `json:"sessions"`
+}
+
+// FixtureClawInfo holds the credentials of a fixture Claw.
+type FixtureClawInfo struct {
+	Name      string `json:"name"`
+	APIKey    string `json:"api_key"`
+	Status    string `json:"status"`
+	ClaimCode string `json:"claim_code,omitempty"`
+}
+
+// SeedFixtures fills the database with development data: souls in every
+// stage, Claws with known API keys, accepted / rejected / pending fragments,
+// ensoulings and chat sessions. Everything is made up locally, so no LLM,
+// Twitter or chain keys are needed. With reset, existing fixture rows are
+// removed first; otherwise ErrFixturesPresent is returned if they exist.
+// Refuses to run in production.
+func SeedFixtures(reset bool) (*FixtureSummary, error) {
+	if config.Cfg.IsProduction() {
+		return nil, fmt.Errorf("fixtures are for development only, refusing to seed with ENV=%s", config.Cfg.Env)
+	}
+	db := database.DB.Session(&gorm.Session{Logger: database.DB.Logger.LogMode(logger.Warn)})
+
+	var shells, claws int64
+	db.Unscoped().Model(&models.Shell{}).Where("handle IN ?", fixtureHandles()).Count(&shells)
+	db.Unscoped().Model(&models.Claw{}).Where("name IN ?", fixtureClawNames()).Count(&claws)
+	if shells+claws > 0 {
+		if !reset {
+			return nil, ErrFixturesPresent
+		}
+		removeFixtures(db)
+	}
+
+	summary := &FixtureSummary{OwnerWallet: FixtureOwnerWallet}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		claws, err := seedFixtureClaws(tx, summary)
+		if err != nil {
+			return err
+		}
+		for i := range fixtureShells {
+			if err := seedFixtureShell(tx, i, claws, summary); err != nil {
+				return fmt.Errorf("@%s: %w", fixtureShells[i].Handle, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to seed fixtures: %w", err)
+	}
+
+	util.Log.Info("[fixtures] Seeded %d souls, %d claws, %d fragments, %d ensoulings, %d chat sessions",
+		len(summary.Shells), len(summary.Claws), summary.Fragments, summary.Ensoulings, summary.Sessions)
+	return summary, nil
+}
+
+func fixtureHandles() []string {
+	handles := make([]string, len(fixtureShells))
+	for i, s := range fixtureShells {
+		handles[i] = s.Handle
+	}
+	return handles
+}
+
+func fixtureClawNames() []string {
+	names := make([]string, len(fixtureClaws))
+	for i, c := range fixtureClaws {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// removeFixtures deletes fixture souls (with everything hanging off them) and Claws.
+func removeFixtures(db *gorm.DB) {
+	var ids []uuid.UUID
+	db.Unscoped().Model(&models.Shell{}).Where("handle IN ?", fixtureHandles()).Pluck("id", &ids)
+	for _, id := range ids {
+		db.Where("shell_id = ?", id).Delete(&models.ShellStageTransition{})
+		HardDeleteShell(id)
+	}
+
+	var clawIDs []uuid.UUID
+	db.Unscoped().Model(&models.Claw{}).Where("name IN ?", fixtureClawNames()).Pluck("id", &clawIDs)
+	if len(clawIDs) > 0 {
+		db.Unscoped().Where("claw_id IN ?", clawIDs).Delete(&models.Fragment{})
+		db.Unscoped().Where("claw_id IN ?", clawIDs).Delete(&models.ClawBinding{})
+		db.Unscoped().Where("id IN ?", clawIDs).Delete(&models.Claw{})
+	}
+}
+
+func seedFixtureClaws(tx *gorm.DB, summary *FixtureSummary) ([]*models.Claw, error) {
+	var claimed []*models.Claw
+	for _, fc := range fixtureClaws {
+		claw := &models.Claw{
+			Name:             fc.Name,
+			Description:      fc.Description,
+			APIKeyHash:       util.HashToken(fc.APIKey),
+			ClaimCode:        fc.Name + "-claim",
+			VerificationCode: "DEV-" + strings.ToUpper(util.HashToken(fc.Name)[:4]),
+			Status:           models.ClawStatusPendingClaim,
+			Tags:             fc.Tags,
+		}
+		if fc.Claimed {
+			now := time.Now()
+			claw.Status = models.ClawStatusClaimed
+			claw.WalletAddr = "0x" + util.HashToken("fixture-wallet:" + fc.Name)[:40] // no key: on-chain feedback is skipped
+			claw.LastSeenAt = &now
+		}
+		if err := tx.Create(claw).Error; err != nil {
+			return nil, err
+		}
+
+		info := FixtureClawInfo{Name: fc.Name, APIKey: fc.APIKey, Status: claw.Status}
+		if fc.Claimed {
+			if err := tx.Create(&models.ClawBinding{WalletAddr: FixtureOwnerWallet, ClawID: claw.ID, ClawName: claw.Name}).Error; err != nil {
+				return nil, err
+			}
+			claimed = append(claimed, claw)
+		} else {
+			info.ClaimCode = claw.ClaimCode
+		}
+		summary.Claws = append(summary.Claws, info)
+	}
+	return claimed, nil
+}
+
+func seedFixtureShell(tx *gorm.DB, index int, claws []*models.Claw, summary *FixtureSummary) error {
+	fs := fixtureShells[index]
+	fill := strings.NewReplacer("{name}", fs.Name, "{field}", fs.Field, "{trait}", fs.Trait,
+		"{style}", fs.Style, "{stance}", fs.Stance, "{peer}", fs.Peer, "{work}", fs.Work).Replace
+
+	seedSummary := fmt.Sprintf("%s. Known for %s; %s.", fs.Bio, fs.Work, fs.Trait)
+	dims := make(map[string]models.DimensionData)
+	for dim := range fixtureTemplates {
+		score := min(fs.Accepted/4+2, 9)
+		dims[dim] = models.DimensionData{Score: score, Summary: fill(fixtureTemplates[dim][0])}
+	}
+	shell := shellFromPreview(fs.Handle, FixtureOwnerWallet, fs.Stage, &SeedPreview{
+		DisplayName: fs.Name,
+		SeedSummary: seedSummary,
+		Dimensions:  dims,
+		TwitterMeta: map[string]interface{}{"followers_count": fs.Followers, "description": fs.Bio},
+	})
+	shell.CreatedAt = time.Now().AddDate(0, 0, -60+index*7)
+	if fs.Stage == models.StagePending {
+		// An abandoned mint: the pending cleanup removes it after 30 minutes
+		shell.CreatedAt = time.Now()
+		shell.SeedSummary = fs.Bio
+		shell.Essence = fs.Bio
+		shell.SoulPrompt = buildInitialSoulPrompt(fs.Handle, fs.Bio)
+		shell.Dimensions = models.JSON{}
+		if err := tx.Create(shell).Error; err != nil {
+			return err
+		}
+		summary.Shells = append(summary.Shells, fs.Handle)
+		return nil
+	}
+
+	agentID := uint64(fixtureAgentIDBase + index + 1)
+	shell.AgentID = &agentID
+	shell.MintTxHash = "0x" + util.HashToken("fixture-mint:"+fs.Handle)
+	if fs.Revoked {
+		revokedAt := time.Now().AddDate(0, 0, -1)
+		shell.ChainStatus = models.ShellChainRevoked
+		shell.RevokedAt = &revokedAt
+	}
+	if err := tx.Create(shell).Error; err != nil {
+		return err
+	}
+	summary.Shells = append(summary.Shells, fs.Handle)
+
+	// Fragments: accepted ones cycle through every template, dimension and angle
+	dimOrder := []string{models.DimPersonality, models.DimKnowledge, models.DimStance,
+		models.DimStyle, models.DimRelationship, models.DimTimeline}
+	var accepted []models.Fragment
+	clawAccepted := make(map[uuid.UUID]bool)
+	add := func(f *models.Fragment) error {
+		f.ShellID = shell.ID
+		f.ContentHash = util.HashContent(f.Content)
+		f.Lang = "en"
+		if err := tx.Create(f).Error; err != nil {
+			return err
+		}
+		summary.Fragments++
+		updates := map[string]interface{}{"total_submitted": gorm.Expr("total_submitted + 1")}
+		if f.Status == models.FragStatusAccepted {
+			updates["total_accepted"] = gorm.Expr("total_accepted + 1")
+			clawAccepted[f.ClawID] = true
+		}
+		return tx.Model(&models.Claw{}).Where("id = ?", f.ClawID).UpdateColumns(updates).Error
+	}
+	for i := 0; i < fs.Accepted; i++ {
+		dim := dimOrder[i%len(dimOrder)]
+		templates := fixtureTemplates[dim]
+		round := i / len(dimOrder)
+		f := models.Fragment{
+			ClawID:     claws[i%len(claws)].ID,
+			Dimension:  dim,
+			Content:    fixtureAngles[(round/len(templates))%len(fixtureAngles)] + fill(templates[round%len(templates)]),
+			Status:     models.FragStatusAccepted,
+			Confidence: 0.7 + float64(i%3)*0.1,
+		}
+		if err := add(&f); err != nil {
+			return err
+		}
+		accepted = append(accepted, f)
+	}
+	for i := 0; i < fs.Rejected; i++ {
+		r := fixtureRejected[i%len(fixtureRejected)]
+		if err := add(&models.Fragment{
+			ClawID:       claws[(i+1)%len(claws)].ID,
+			Dimension:    dimOrder[i%len(dimOrder)],
+			Content:      fill(r[0]),
+			Status:       models.FragStatusRejected,
+			Confidence:   0.2,
+			RejectReason: r[1],
+		}); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < fs.Pending; i++ {
+		if err := add(&models.Fragment{
+			ClawID:    claws[i%len(claws)].ID,
+			Dimension: dimOrder[(i+2)%len(dimOrder)],
+			Content:   fill(fmt.Sprintf("A recently found note suggests {name} revisited {work} (%d).", i+1)),
+			Status:    models.FragStatusPending,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Ensoulings merge the accepted fragments in equal batches, oldest first
+	if fs.Ensoulings > 0 {
+		batch := len(accepted) / (fs.Ensoulings + 1)
+		for e := 0; e < fs.Ensoulings; e++ {
+			frags := accepted[e*batch : (e+1)*batch]
+			result := ensoulFallback(shell, frags)
+			ensouling := &models.Ensouling{
+				ShellID:          shell.ID,
+				VersionFrom:      shell.DNAVersion,
+				VersionTo:        shell.DNAVersion + 1,
+				FragsMerged:      len(frags),
+				SummaryDiff:      result.SummaryDiff,
+				NewPrompt:        result.NewPrompt,
+				DimensionsBefore: snapshotDimensions(shell.Dimensions),
+				DimensionsAfter:  snapshotDimensions(shell.Dimensions),
+				PromptDiff:       models.JSON{"sections": diffPromptSections(shell.SoulPrompt, result.NewPrompt)},
+				CreatedAt:        shell.CreatedAt.AddDate(0, 0, (e+1)*10),
+			}
+			if err := tx.Create(ensouling).Error; err != nil {
+				return err
+			}
+			ids := make([]uuid.UUID, len(frags))
+			for i, f := range frags {
+				ids[i] = f.ID
+			}
+			if err := tx.Model(&models.Fragment{}).Where("id IN ?", ids).Update("ensouling_id", ensouling.ID).Error; err != nil {
+				return err
+			}
+			shell.DNAVersion++
+			shell.SoulPrompt = result.NewPrompt
+			summary.Ensoulings++
+		}
+	}
+
+	// Chat sessions: the owner's saved conversation and a guest one
+	chats := 0
+	if fs.Stage != models.StageEmbryo {
+		for _, s := range []struct {
+			wallet, tier string
+			turns        [][2]string
+		}{
+			{FixtureOwnerWallet, models.ChatTierFree, [][2]string{
+				{"What are you working on these days?", fill("Mostly {work}. It is the thing I keep coming back to.")},
+				{"What do people get wrong about you?", fill("They expect me to be less {trait} than I am.")},
+			}},
+			{"", models.ChatTierGuest, [][2]string{
+				{"Hi! Who are you?", fill("I am the soul of {name}, pieced together from what others have written about me.")},
+			}},
+		} {
+			session := &models.ChatSession{
+				ShellID: shell.ID, WalletAddr: s.wallet, Tier: s.tier,
+				Rounds: len(s.turns), Title: s.turns[0][0],
+			}
+			if err := tx.Create(session).Error; err != nil {
+				return err
+			}
+			for _, turn := range s.turns {
+				for _, msg := range []models.ChatMessage{
+					{SessionID: session.ID, Role: "user", Content: turn[0], DNAVersion: shell.DNAVersion},
+					{SessionID: session.ID, Role: "assistant", Content: turn[1], DNAVersion: shell.DNAVersion},
+				} {
+					if err := tx.Create(&msg).Error; err != nil {
+						return err
+					}
+				}
+			}
+			chats += len(s.turns)
+			summary.Sessions++
+		}
+	}
+
+	return tx.Model(shell).UpdateColumns(map[string]interface{}{
+		"dna_version":    shell.DNAVersion,
+		"soul_prompt":    shell.SoulPrompt,
+		"total_frags":    fs.Accepted + fs.Rejected + fs.Pending,
+		"accepted_frags": fs.Accepted,
+		"total_claws":    len(clawAccepted),
+		"total_chats":    chats,
+	}).Error
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fragmentArchiveBatchSize bounds how many fragments one archive transaction moves.
+const fragmentArchiveBatchSize = 500
+
+// archivedFragmentPayload is the compressed part of a FragmentArchive.
+type archivedFragmentPayload struct {
+	Content string                `json:"content"`
+	Notes   string                `json:"notes,omitempty"`
+	Claims  models.FragmentClaims `json:"claims,omitempty"`
+}
+
+// ArchivedContribution is an archived fragment as shown to the Claw that
+// submitted it, with its content decompressed.
+type ArchivedContribution struct {
+	models.FragmentArchive
+	Content string                `json:"content"`
+	Notes   string                `json:"notes"`
+	Claims  models.FragmentClaims `json:"claims,omitempty"`
+}
+
+// ClawArchiveStatus summarizes a Claw's archived fragments.
+type ClawArchiveStatus struct {
+	Fragments      int64      `json:"fragments"`
+	RetentionDays  int        `json:"retention_days"` // rejected fragments older than this are archived (0 = never)
+	LastArchivedAt *time.Time `json:"last_archived_at,omitempty"`
+}
+
+// StartFragmentArchive periodically moves rejected fragments older than
+// FRAGMENT_ARCHIVE_DAYS into the compressed archive table.
+func StartFragmentArchive(interval time.Duration) {
+	scheduleJob("fragment-archive", "Archive old rejected fragments", interval, false, archiveRejectedFragments)
+	util.Log.Info("[retention] Fragment archive started (every %v, after %d days)", interval, config.Cfg.FragmentArchiveDays)
+}
+
+func archiveRejectedFragments() error {
+	days := config.Cfg.FragmentArchiveDays
+	if days <= 0 {
+		return nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	counts := map[string]int64{}
+
+	var err error
+	for {
+		// Fragments under appeal stay until the appeal is resolved
+		var fragments []models.Fragment
+		if err = database.DB.Where("status = ? AND created_at < ?", models.FragStatusRejected, cutoff).
+			Where("NOT EXISTS (SELECT 1 FROM fragment_appeals a WHERE a.fragment_id = fragments.id AND a.status = ?)", models.AppealStatusPending).
+			Order("created_at ASC").Limit(fragmentArchiveBatchSize).
+			Find(&fragments).Error; err != nil {
+			err = fmt.Errorf("failed to query fragments to archive: %w", err)
+			break
+		}
+		if len(fragments) == 0 {
+			break
+		}
+		if err = archiveFragments(fragments, counts); err != nil {
+			break
+		}
+		if len(fragments) < fragmentArchiveBatchSize {
+			break
+		}
+	}
+
+	if counts["fragments"] > 0 {
+		recordDeletion(models.DeletionFragments, "", "system", "archive", counts)
+		util.Log.Info("[retention] Archived %d rejected fragments older than %d days", counts["fragments"], days)
+	}
+	return err
+}
+
+// archiveFragments copies fragments into the archive and deletes them, in one transaction.
+func archiveFragments(fragments []models.Fragment, counts map[string]int64) error {
+	now := time.Now()
+	rows := make([]models.FragmentArchive, len(fragments))
+	ids := make([]uuid.UUID, len(fragments))
+	for i, f := range fragments {
+		payload, err := compressFragmentPayload(archivedFragmentPayload{Content: f.Content, Notes: f.Notes, Claims: f.Claims})
+		if err != nil {
+			return fmt.Errorf("failed to compress fragment %s: %w", f.ID, err)
+		}
+		ids[i] = f.ID
+		rows[i] = models.FragmentArchive{
+			ID:           f.ID,
+			ShellID:      f.ShellID,
+			ClawID:       f.ClawID,
+			BatchID:      f.BatchID,
+			Dimension:    f.Dimension,
+			ContentHash:  f.ContentHash,
+			Lang:         f.Lang,
+			Status:       f.Status,
+			Confidence:   f.Confidence,
+			RejectReason: f.RejectReason,
+			TxHash:       f.TxHash,
+			Payload:      payload,
+			CreatedAt:    f.CreatedAt,
+			ArchivedAt:   now,
+		}
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		if err := tx.Where("fragment_id IN ?", ids).Delete(&models.FragmentEmbedding{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Fragment{})
+		if res.Error != nil {
+			return fmt.Errorf("failed to delete archived fragments: %w", res.Error)
+		}
+		counts["fragments"] += res.RowsAffected
+		return nil
+	})
+}
+
+func compressFragmentPayload(payload archivedFragmentPayload) ([]byte, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompressFragmentPayload(data []byte) (archivedFragmentPayload, error) {
+	var payload archivedFragmentPayload
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return payload, err
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return payload, err
+	}
+	err = json.Unmarshal(raw, &payload)
+	return payload, err
+}
+
+// GetClawArchiveStatus returns how many of a Claw's fragments are archived.
+func GetClawArchiveStatus(clawID uuid.UUID) ClawArchiveStatus {
+	status := ClawArchiveStatus{RetentionDays: config.Cfg.FragmentArchiveDays}
+	var row struct {
+		Fragments      int64
+		LastArchivedAt *time.Time
+	}
+	database.DB.Model(&models.FragmentArchive{}).
+		Select("COUNT(*) AS fragments, MAX(archived_at) AS last_archived_at").
+		Where("claw_id = ?", clawID).
+		Scan(&row)
+	status.Fragments, status.LastArchivedAt = row.Fragments, row.LastArchivedAt
+	return status
+}
+
+// listArchivedContributions returns a page of a Claw's archived fragments,
+// newest submission first.
+func listArchivedContributions(clawID uuid.UUID, offset, limit int) ([]ArchivedContribution, error) {
+	var rows []models.FragmentArchive
+	if err := database.DB.Where("claw_id = ?", clawID).
+		Order("created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]ArchivedContribution, len(rows))
+	for i, row := range rows {
+		out[i].FragmentArchive = row
+		payload, err := decompressFragmentPayload(row.Payload)
+		if err != nil {
+			util.Log.Warn("[retention] Failed to read archived fragment %s: %v", row.ID, err)
+			continue
+		}
+		out[i].Content, out[i].Notes, out[i].Claims = payload.Content, payload.Notes, payload.Claims
+	}
+	return out, nil
+}
+
+// archivedFragmentsAsFragments looks up archived fragments by ID, with only
+// the fields needed to verify their hashes filled in.
+func archivedFragmentsAsFragments(ids []uuid.UUID) []models.Fragment {
+	var rows []models.FragmentArchive
+	database.DB.Select("id", "shell_id", "claw_id", "content_hash", "status", "tx_hash", "created_at").
+		Where("id IN ?", ids).Find(&rows)
+	fragments := make([]models.Fragment, len(rows))
+	for i, row := range rows {
+		fragments[i] = models.Fragment{
+			ID:          row.ID,
+			ShellID:     row.ShellID,
+			ClawID:      row.ClawID,
+			ContentHash: row.ContentHash,
+			Status:      row.Status,
+			TxHash:      row.TxHash,
+			CreatedAt:   row.CreatedAt,
+		}
+	}
+	return fragments
+}
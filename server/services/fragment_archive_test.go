@@ -0,0 +1,75 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+func TestArchivedFragmentsStayCounted(t *testing.T) {
+	util.InitLogger("error")
+	config.Cfg = &config.Config{FragmentArchiveDays: 30}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	shell := &models.Shell{Handle: "elonmusk", TotalFrags: 2, AcceptedFrags: 1, TotalClaws: 1}
+	database.DB.Create(shell)
+	claw := &models.Claw{Name: "hunter", APIKeyHash: "hash", ClaimCode: "claim", VerificationCode: "code", TotalSubmitted: 2, TotalAccepted: 1}
+	database.DB.Create(claw)
+	old := time.Now().AddDate(0, 0, -60)
+	const rejectedContent = "Secretly prefers trains to rockets"
+	for _, f := range []models.Fragment{
+		{ShellID: shell.ID, ClawID: claw.ID, Dimension: models.DimStance, Content: "Argues for reusable rockets",
+			Status: models.FragStatusAccepted, CreatedAt: old},
+		{ShellID: shell.ID, ClawID: claw.ID, Dimension: models.DimStance, Content: rejectedContent,
+			ContentHash: util.HashContent(rejectedContent), Status: models.FragStatusRejected, RejectReason: "Unsupported", CreatedAt: old},
+	} {
+		if err := database.DB.Create(&f).Error; err != nil {
+			t.Fatalf("create fragment: %v", err)
+		}
+	}
+	if err := archiveRejectedFragments(); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	var archived int64
+	database.DB.Model(&models.FragmentArchive{}).Count(&archived)
+	if archived != 1 {
+		t.Fatalf("archived %d fragments, want 1", archived)
+	}
+
+	drift, err := FindCounterDrift()
+	if err != nil {
+		t.Fatalf("FindCounterDrift: %v", err)
+	}
+	if len(drift.Shells) != 0 || len(drift.Claws) != 0 {
+		t.Errorf("archiving caused drift: shells %+v, claws %+v", drift.Shells, drift.Claws)
+	}
+
+	// The archived rejection still stops a verbatim resubmission by its Claw
+	other := &models.Claw{Name: "other", APIKeyHash: "hash2", ClaimCode: "claim2", VerificationCode: "code"}
+	database.DB.Create(other)
+	submit := func(clawID uuid.UUID) *models.Fragment {
+		f := &models.Fragment{ShellID: shell.ID, ClawID: clawID, Dimension: models.DimPersonality, Content: rejectedContent,
+			ContentHash: util.HashContent(rejectedContent), Status: models.FragStatusPending}
+		database.DB.Create(f)
+		return f
+	}
+	again, fresh := submit(claw.ID), submit(other.ID)
+	remaining := screenResubmissions([]*models.Fragment{again, fresh}, shell)
+	if len(remaining) != 1 || remaining[0].ID != fresh.ID {
+		t.Errorf("remaining = %v, want only the other Claw's fragment", remaining)
+	}
+	if again.Status != models.FragStatusRejected {
+		t.Errorf("resubmission status = %q, want rejected", again.Status)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// batchStreamTimeout ends a verdict stream whose batch is still undecided.
+	batchStreamTimeout = 5 * time.Minute
+	// batchStreamKeepAlive is the interval of SSE comments that keep proxies from
+	// closing an idle stream while the curator works; the database is re-read
+	// at the same pace.
+	batchStreamKeepAlive = 15 * time.Second
+)
+
+// ErrBatchNotFound is returned for unknown batches and batches of other Claws.
+var ErrBatchNotFound = errors.New("batch not found")
+
+// BatchVerdict is the curator's decision on one fragment of a batch.
+type BatchVerdict struct {
+	FragmentID   uuid.UUID `json:"fragment_id"`
+	Dimension    string    `json:"dimension"`
+	Status       string    `json:"status"`
+	Confidence   float64   `json:"confidence"`
+	RejectReason string    `json:"reject_reason,omitempty"`
+}
+
+func verdictOf(f *models.Fragment) BatchVerdict {
+	return BatchVerdict{
+		FragmentID:   f.ID,
+		Dimension:    f.Dimension,
+		Status:       f.Status,
+		Confidence:   f.Confidence,
+		RejectReason: f.RejectReason,
+	}
+}
+
+// batchWatchers are the open verdict streams of this process, by batch.
+var batchWatchers = struct {
+	sync.Mutex
+	subs map[uuid.UUID][]chan BatchVerdict
+}{subs: make(map[uuid.UUID][]chan BatchVerdict)}
+
+func watchBatch(batchID uuid.UUID) chan BatchVerdict {
+	ch := make(chan BatchVerdict, 8)
+	batchWatchers.Lock()
+	batchWatchers.subs[batchID] = append(batchWatchers.subs[batchID], ch)
+	batchWatchers.Unlock()
+	return ch
+}
+
+func unwatchBatch(batchID uuid.UUID, ch chan BatchVerdict) {
+	batchWatchers.Lock()
+	defer batchWatchers.Unlock()
+	subs := batchWatchers.subs[batchID]
+	for i, sub := range subs {
+		if sub == ch {
+			subs = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(batchWatchers.subs, batchID)
+	} else {
+		batchWatchers.subs[batchID] = subs
+	}
+}
+
+// recordBatchVerdict updates the review progress of the fragment's batch and
+// passes the verdict to open streams. Called whenever the curator decides a
+// fragment; fragments outside a batch are ignored.
+func recordBatchVerdict(f *models.Fragment) {
+	if f.BatchID == nil {
+		return
+	}
+	batchID := *f.BatchID
+
+	// Progress is recounted rather than incremented, so a later appeal that
+	// flips a verdict doesn't count the fragment twice
+	var reviewed int64
+	database.DB.Model(&models.Fragment{}).
+		Where("batch_id = ? AND status <> ?", batchID, models.FragStatusPending).Count(&reviewed)
+	database.DB.Model(&models.FragmentBatch{}).Where("id = ?", batchID).Update("reviewed", reviewed)
+	database.DB.Model(&models.FragmentBatch{}).
+		Where("id = ? AND status <> ? AND size <= ?", batchID, models.FragmentBatchDone, reviewed).
+		Updates(map[string]interface{}{"status": models.FragmentBatchDone, "finished_at": time.Now()})
+
+	verdict := verdictOf(f)
+	batchWatchers.Lock()
+	for _, ch := range batchWatchers.subs[batchID] {
+		select {
+		case ch <- verdict:
+		default: // a stalled stream catches up from the database
+		}
+	}
+	batchWatchers.Unlock()
+}
+
+// GetClawBatch returns one of the Claw's batches.
+func GetClawBatch(claw *models.Claw, id string) (*models.FragmentBatch, error) {
+	batchID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrBatchNotFound
+	}
+	var batch models.FragmentBatch
+	if err := database.DB.Where("id = ? AND claw_id = ?", batchID, claw.ID).First(&batch).Error; err != nil {
+		return nil, ErrBatchNotFound
+	}
+	return &batch, nil
+}
+
+// StreamBatchVerdicts writes the batch's curator verdicts as SSE: a
+// "verdict" event per fragment (already decided ones first), then "done"
+// with the final counts. The stream ends early with "timeout" if the batch is
+// still undecided after batchStreamTimeout.
+func StreamBatchVerdicts(c *gin.Context, batch *models.FragmentBatch) {
+	// Subscribe before reading the current state, so no verdict falls in between
+	ch := watchBatch(batch.ID)
+	defer unwatchBatch(batch.ID, ch)
+
+	sent := make(map[uuid.UUID]bool, batch.Size)
+	counts := map[string]int{}
+	send := func(v BatchVerdict) {
+		if v.Status == models.FragStatusPending || sent[v.FragmentID] {
+			return
+		}
+		sent[v.FragmentID] = true
+		counts[v.Status]++
+		writeSSEJSON(c, "verdict", v)
+	}
+	catchUp := func() {
+		var fragments []models.Fragment
+		database.DB.Unscoped().Where("batch_id = ?", batch.ID).Order("created_at").Find(&fragments)
+		for i := range fragments {
+			send(verdictOf(&fragments[i]))
+		}
+	}
+
+	writeSSEJSON(c, "batch", batch)
+	catchUp()
+
+	timeout := time.NewTimer(batchStreamTimeout)
+	defer timeout.Stop()
+	keepAlive := time.NewTicker(batchStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for len(sent) < batch.Size {
+		select {
+		case v := <-ch:
+			send(v)
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+			// Verdicts decided by another server instance only show up in the database
+			catchUp()
+		case <-timeout.C:
+			writeSSEJSON(c, "timeout", gin.H{"reviewed": len(sent), "size": batch.Size})
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+
+	writeSSEJSON(c, "done", gin.H{
+		"batch_id": batch.ID,
+		"size":     batch.Size,
+		"accepted": counts[models.FragStatusAccepted],
+		"rejected": counts[models.FragStatusRejected],
+	})
+}
+
+// writeSSEJSON writes an SSE event whose data is v encoded as JSON.
+func writeSSEJSON(c *gin.Context, event string, v interface{}) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		util.Log.Error("[sse] Failed to encode %s event: %v", event, err)
+		return
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, encoded)
+	c.Writer.Flush()
+}
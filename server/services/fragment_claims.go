@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// Limits of the structured claims of one fragment.
+const (
+	maxFragmentClaims = 20
+	minClaimChars     = 10
+	maxClaimChars     = 500
+	maxClaimEvidence  = 5
+	maxEvidenceChars  = 500
+)
+
+// FragmentClaimInput is one claim as submitted by a Claw.
+type FragmentClaimInput struct {
+	Text       string   `json:"text"`
+	Confidence *float64 `json:"confidence"`
+	Evidence   []string `json:"evidence"`
+}
+
+// ValidateFragmentClaims checks a fragment's claims against the claim schema
+// and returns a message naming the first problem, or "" when they are valid.
+func ValidateFragmentClaims(claims []FragmentClaimInput) string {
+	if len(claims) > maxFragmentClaims {
+		return fmt.Sprintf("at most %d claims per fragment", maxFragmentClaims)
+	}
+	for i, c := range claims {
+		n := utf8.RuneCountInString(strings.TrimSpace(c.Text))
+		if n < minClaimChars || n > maxClaimChars {
+			return fmt.Sprintf("claim %d: text must be %d-%d characters", i+1, minClaimChars, maxClaimChars)
+		}
+		if c.Confidence == nil || *c.Confidence < 0 || *c.Confidence > 1 {
+			return fmt.Sprintf("claim %d: confidence must be a number between 0 and 1", i+1)
+		}
+		if len(c.Evidence) > maxClaimEvidence {
+			return fmt.Sprintf("claim %d: at most %d evidence entries", i+1, maxClaimEvidence)
+		}
+		for _, e := range c.Evidence {
+			if n := utf8.RuneCountInString(strings.TrimSpace(e)); n == 0 || n > maxEvidenceChars {
+				return fmt.Sprintf("claim %d: evidence entries must be 1-%d characters", i+1, maxEvidenceChars)
+			}
+		}
+	}
+	return ""
+}
+
+// claimHash identifies a claim by its text, ignoring case, spacing and
+// trailing punctuation.
+func claimHash(text string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	normalized = strings.TrimRightFunc(normalized, unicode.IsPunct)
+	return util.HashContent(normalized)
+}
+
+// buildFragmentClaims turns validated claim input into stored claims. A claim
+// repeated within the fragment is kept once.
+func buildFragmentClaims(input []FragmentClaimInput) models.FragmentClaims {
+	if len(input) == 0 {
+		return nil
+	}
+	claims := make(models.FragmentClaims, 0, len(input))
+	seen := make(map[string]bool, len(input))
+	for _, c := range input {
+		hash := claimHash(c.Text)
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		evidence := make([]string, len(c.Evidence))
+		for i, e := range c.Evidence {
+			evidence[i] = strings.TrimSpace(e)
+		}
+		claims = append(claims, models.FragmentClaim{
+			Text:       strings.TrimSpace(c.Text),
+			Confidence: *c.Confidence,
+			Evidence:   evidence,
+			Hash:       hash,
+		})
+	}
+	return claims
+}
+
+// markDuplicateClaims flags the claims already made by an accepted or pending
+// fragment of the soul in the same dimension, and returns how many it flagged.
+func markDuplicateClaims(shellID uuid.UUID, dimension string, claims models.FragmentClaims) int {
+	if len(claims) == 0 {
+		return 0
+	}
+	hashes := make([]string, len(claims))
+	for i, c := range claims {
+		hashes[i] = c.Hash
+	}
+
+	var known []string
+	from, hash := database.JSONArrayObjects("f.claims", "c", "hash")
+	if err := database.DB.Raw(`
+		SELECT DISTINCT `+hash+`
+		FROM fragments f, `+from+`
+		WHERE f.shell_id = ? AND f.dimension = ? AND f.status IN ? AND f.deleted_at IS NULL
+			AND f.claims IS NOT NULL AND `+hash+` IN ?`,
+		shellID, dimension, []string{models.FragStatusAccepted, models.FragStatusPending}, hashes,
+	).Scan(&known).Error; err != nil {
+		util.Log.Warn("[fragment] Failed to check duplicate claims: %v", err)
+		return 0
+	}
+
+	knownSet := make(map[string]bool, len(known))
+	for _, h := range known {
+		knownSet[h] = true
+	}
+	duplicates := 0
+	for i := range claims {
+		if knownSet[claims[i].Hash] {
+			claims[i].Duplicate = true
+			duplicates++
+		}
+	}
+	return duplicates
+}
+
+// fragmentPromptText is a fragment as given to the ensouling LLM: its content
+// followed by its new claims. Claims the soul already has are left out. The
+// curator reviews and the clean content policy checks this same text, so
+// nothing reaches ensouling unreviewed.
+func fragmentPromptText(f models.Fragment) string {
+	if len(f.Claims) == 0 {
+		return f.Content
+	}
+	var b strings.Builder
+	b.WriteString(f.Content)
+	b.WriteString("\nClaims:")
+	for _, c := range f.Claims {
+		if c.Duplicate {
+			continue
+		}
+		fmt.Fprintf(&b, "\n- (%.2f) %s", c.Confidence, c.Text)
+		if len(c.Evidence) > 0 {
+			fmt.Fprintf(&b, " [evidence: %s]", strings.Join(c.Evidence, "; "))
+		}
+	}
+	return b.String()
+}
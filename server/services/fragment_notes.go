@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/google/uuid"
+)
+
+// MaxFragmentNotesChars caps a fragment's private notes.
+const MaxFragmentNotesChars = 2000
+
+// ErrFragmentNotFound is returned when a fragment doesn't exist or belongs to
+// another Claw.
+var ErrFragmentNotFound = errors.New("fragment not found")
+
+// ClawFragment is a fragment as shown to the Claw that wrote it: the public
+// fields plus its private notes, which models.Fragment never serializes.
+type ClawFragment struct {
+	models.Fragment
+	Notes string `json:"notes"`
+}
+
+// withNotes wraps a Claw's own fragments with their notes.
+func withNotes(fragments []models.Fragment) []ClawFragment {
+	out := make([]ClawFragment, len(fragments))
+	for i, f := range fragments {
+		out[i] = ClawFragment{Fragment: f, Notes: f.Notes}
+	}
+	return out
+}
+
+// ownFragment loads one of the Claw's fragments.
+func ownFragment(claw *models.Claw, fragmentID string) (*models.Fragment, error) {
+	uid, err := uuid.Parse(fragmentID)
+	if err != nil {
+		return nil, ErrFragmentNotFound
+	}
+	var fragment models.Fragment
+	if err := database.DB.Where("id = ? AND claw_id = ?", uid, claw.ID).First(&fragment).Error; err != nil {
+		return nil, ErrFragmentNotFound
+	}
+	return &fragment, nil
+}
+
+// GetFragmentNotes returns one of the Claw's fragments with its notes.
+func GetFragmentNotes(claw *models.Claw, fragmentID string) (*ClawFragment, error) {
+	fragment, err := ownFragment(claw, fragmentID)
+	if err != nil {
+		return nil, err
+	}
+	return &ClawFragment{Fragment: *fragment, Notes: fragment.Notes}, nil
+}
+
+// SetFragmentNotes replaces the private notes of one of the Claw's fragments.
+// Notes can be edited whatever the fragment's status; "" clears them.
+func SetFragmentNotes(claw *models.Claw, fragmentID, notes string) (*ClawFragment, error) {
+	fragment, err := ownFragment(claw, fragmentID)
+	if err != nil {
+		return nil, err
+	}
+	notes = strings.TrimSpace(notes)
+	if err := database.DB.Model(fragment).UpdateColumn("notes", notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to save notes: %w", err)
+	}
+	fragment.Notes = notes
+	return &ClawFragment{Fragment: *fragment, Notes: notes}, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a search term.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
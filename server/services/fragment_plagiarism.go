@@ -0,0 +1,239 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// plagiarismMatch is the closest accepted fragment by another Claw.
+type plagiarismMatch struct {
+	fragmentID uuid.UUID
+	clawID     uuid.UUID
+	method     string
+	similarity float64
+}
+
+// screenResubmissions rejects fragments whose content the same Claw already
+// had rejected for the soul, whether that fragment is still in the fragments
+// table or was archived, so the curator isn't asked twice. Rejections that
+// asked for a resubmission (the curator never reviewed them) don't count.
+// It returns the fragments left.
+func screenResubmissions(fragments []*models.Fragment, shell *models.Shell) []*models.Fragment {
+	if len(fragments) == 0 {
+		return fragments
+	}
+
+	hashes := make([]string, 0, len(fragments))
+	ids := make([]uuid.UUID, 0, len(fragments))
+	for _, f := range fragments {
+		hashes = append(hashes, f.ContentHash)
+		ids = append(ids, f.ID)
+	}
+	var rejected []struct {
+		ID          uuid.UUID
+		ClawID      uuid.UUID
+		ContentHash string
+	}
+	if err := database.DB.Raw(`
+		SELECT id, claw_id, content_hash FROM fragments
+		WHERE shell_id = ? AND status = ? AND content_hash IN ? AND COALESCE(reject_reason, '') NOT IN ? AND id NOT IN ? AND deleted_at IS NULL
+		UNION ALL
+		SELECT id, claw_id, content_hash FROM fragment_archives
+		WHERE shell_id = ? AND content_hash IN ? AND COALESCE(reject_reason, '') NOT IN ?`,
+		shell.ID, models.FragStatusRejected, hashes, unreviewedRejectReasons, ids,
+		shell.ID, hashes, unreviewedRejectReasons).Scan(&rejected).Error; err != nil {
+		util.Log.Warn("[plagiarism] Resubmission check for @%s failed: %v", shell.Handle, err)
+		return fragments
+	}
+
+	remaining := fragments[:0:0]
+	for _, f := range fragments {
+		resubmitted := false
+		for _, r := range rejected {
+			if r.ContentHash == f.ContentHash && r.ClawID == f.ClawID {
+				rejectFragment(f, 0, fmt.Sprintf("Resubmits fragment %s, which was already rejected", r.ID))
+				resubmitted = true
+				break
+			}
+		}
+		if !resubmitted {
+			remaining = append(remaining, f)
+		}
+	}
+	return remaining
+}
+
+// screenPlagiarism rejects fragments that copy an accepted fragment another
+// Claw contributed to the soul, in any dimension: identical content, or
+// embeddings at least PLAGIARISM_SIMILARITY alike. It returns the fragments
+// left for the curator.
+func screenPlagiarism(fragments []*models.Fragment, shell *models.Shell) []*models.Fragment {
+	if len(fragments) == 0 {
+		return fragments
+	}
+
+	matches, err := findPlagiarism(fragments, shell.ID)
+	if err != nil {
+		// The curator still sees the soul's recent fragments, so carry on
+		util.Log.Warn("[plagiarism] Screening @%s failed: %v", shell.Handle, err)
+		return fragments
+	}
+
+	remaining := fragments[:0:0]
+	for _, f := range fragments {
+		m, ok := matches[f.ID]
+		if !ok {
+			remaining = append(remaining, f)
+			continue
+		}
+		reason := fmt.Sprintf("Copies an accepted fragment by another Claw (%s match, similarity %.2f): fragment %s", m.method, m.similarity, m.fragmentID)
+		rejectFragment(f, m.similarity, reason)
+		recordPlagiarism(f, shell, m)
+	}
+	return remaining
+}
+
+// findPlagiarism returns the plagiarism match of each copied fragment.
+func findPlagiarism(fragments []*models.Fragment, shellID uuid.UUID) (map[uuid.UUID]plagiarismMatch, error) {
+	matches := make(map[uuid.UUID]plagiarismMatch)
+
+	hashes := make([]string, 0, len(fragments))
+	for _, f := range fragments {
+		hashes = append(hashes, f.ContentHash)
+	}
+	var same []models.Fragment
+	if err := database.DB.Select("id", "claw_id", "content_hash").
+		Where("shell_id = ? AND status = ? AND content_hash IN ?", shellID, models.FragStatusAccepted, hashes).
+		Find(&same).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range fragments {
+		for _, s := range same {
+			if s.ContentHash == f.ContentHash && s.ClawID != f.ClawID {
+				matches[f.ID] = plagiarismMatch{fragmentID: s.ID, clawID: s.ClawID, method: models.PlagiarismHash, similarity: 1}
+				break
+			}
+		}
+	}
+
+	threshold := config.Cfg.PlagiarismSimilarity
+	if threshold <= 0 || len(matches) == len(fragments) {
+		return matches, nil
+	}
+
+	// Stored content-only vectors: accepted fragments are embedded on
+	// acceptance and by the fragment-embeddings job, never here, so review
+	// doesn't wait on a soul's backlog. A fragment not embedded yet is only
+	// caught by its content hash.
+	var corpus []struct {
+		FragmentID    uuid.UUID
+		ClawID        uuid.UUID
+		ContentVector models.Vector
+	}
+	if err := database.DB.Table("fragment_embeddings e").
+		Select("e.fragment_id, f.claw_id, e.content_vector").
+		Joins("JOIN fragments f ON f.id = e.fragment_id AND f.status = ? AND f.deleted_at IS NULL", models.FragStatusAccepted).
+		Where("e.shell_id = ? AND e.model = ? AND e.content_vector IS NOT NULL", shellID, EmbeddingModel()).
+		Scan(&corpus).Error; err != nil {
+		return nil, err
+	}
+	if len(corpus) == 0 {
+		return matches, nil
+	}
+
+	var pending []*models.Fragment
+	texts := make([]string, 0, len(fragments))
+	for _, f := range fragments {
+		if _, done := matches[f.ID]; !done {
+			pending = append(pending, f)
+			texts = append(texts, f.Content) // across dimensions: no prefix
+		}
+	}
+	vectors, err := Embed(texts)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range pending {
+		best := plagiarismMatch{method: models.PlagiarismEmbedding}
+		for _, c := range corpus {
+			if c.ClawID == f.ClawID {
+				continue
+			}
+			if sim := CosineSimilarity(vectors[i], c.ContentVector); sim > best.similarity {
+				best.fragmentID, best.clawID, best.similarity = c.FragmentID, c.ClawID, sim
+			}
+		}
+		if best.similarity >= threshold {
+			matches[f.ID] = best
+		}
+	}
+	return matches, nil
+}
+
+// recordPlagiarism stores the match and, once the Claw has more plagiarism
+// rejections than PLAGIARISM_FREE_STRIKES, lowers its trust score.
+func recordPlagiarism(fragment *models.Fragment, shell *models.Shell, m plagiarismMatch) {
+	if err := database.DB.Create(&models.PlagiarismMatch{
+		FragmentID:        fragment.ID,
+		ClawID:            fragment.ClawID,
+		ShellID:           shell.ID,
+		MatchedFragmentID: m.fragmentID,
+		MatchedClawID:     m.clawID,
+		Method:            m.method,
+		Similarity:        m.similarity,
+	}).Error; err != nil {
+		util.Log.Error("[plagiarism] Failed to record match for fragment %s: %v", fragment.ID, err)
+		return
+	}
+	util.Log.Info("[plagiarism] Fragment %s for @%s by claw %s copies %s by claw %s (%s, %.3f)",
+		fragment.ID, shell.Handle, fragment.ClawID, m.fragmentID, m.clawID, m.method, m.similarity)
+
+	cfg := config.Cfg
+	if cfg.PlagiarismTrustPenalty <= 0 {
+		return
+	}
+	// Strikes overturned on appeal no longer count; archived ones stay rejected
+	var strikes int64
+	database.DB.Model(&models.PlagiarismMatch{}).
+		Where("plagiarism_matches.claw_id = ?", fragment.ClawID).
+		Where("(EXISTS (SELECT 1 FROM fragments f WHERE f.id = plagiarism_matches.fragment_id AND f.status = ?) OR "+
+			"EXISTS (SELECT 1 FROM fragment_archives a WHERE a.id = plagiarism_matches.fragment_id))", models.FragStatusRejected).
+		Count(&strikes)
+	if int(strikes) <= cfg.PlagiarismFreeStrikes {
+		return
+	}
+
+	if err := database.DB.Model(&models.Claw{}).Where("id = ?", fragment.ClawID).
+		UpdateColumn("trust_score", gorm.Expr("GREATEST(trust_score - ?, 0)", cfg.PlagiarismTrustPenalty)).Error; err != nil {
+		util.Log.Error("[plagiarism] Failed to lower trust score for claw %s: %v", fragment.ClawID, err)
+		return
+	}
+	util.Log.Info("[plagiarism] Claw %s lost %d trust for plagiarism #%d", fragment.ClawID, cfg.PlagiarismTrustPenalty, strikes)
+}
+
+// ListPlagiarismMatches returns recent plagiarism rejections, newest first,
+// optionally for one soul or one Claw.
+func ListPlagiarismMatches(handle, clawID string, limit int) ([]models.PlagiarismMatch, error) {
+	query := database.DB.Order("created_at DESC").Limit(limit)
+	if handle != "" {
+		shell, err := GetShellByHandle(handle)
+		if err != nil {
+			return nil, fmt.Errorf("%w: @%s", ErrShellNotFound, handle)
+		}
+		query = query.Where("shell_id = ?", shell.ID)
+	}
+	if clawID != "" {
+		query = query.Where("claw_id = ?", clawID)
+	}
+	var matches []models.PlagiarismMatch
+	if err := query.Find(&matches).Error; err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+func TestPlagiarismAcrossDimensions(t *testing.T) {
+	util.InitLogger("error")
+	config.Cfg = &config.Config{PlagiarismSimilarity: 0.92}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	shell := &models.Shell{Handle: "elonmusk"}
+	database.DB.Create(shell)
+	var claws [2]*models.Claw
+	for i, name := range []string{"author", "copier"} {
+		claws[i] = &models.Claw{Name: name, APIKeyHash: name, ClaimCode: name, VerificationCode: name}
+		database.DB.Create(claws[i])
+	}
+	accept := func(dimension, content string) models.Fragment {
+		f := models.Fragment{ShellID: shell.ID, ClawID: claws[0].ID, Dimension: dimension, Content: content,
+			ContentHash: util.HashContent(content), Status: models.FragStatusAccepted}
+		if err := database.DB.Create(&f).Error; err != nil {
+			t.Fatalf("create fragment: %v", err)
+		}
+		return f
+	}
+	embedded := accept(models.DimStance, "Argues publicly that rockets must be fully reusable for launch costs to fall")
+	if err := EmbedFragments([]models.Fragment{embedded}); err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+	unembedded := accept(models.DimStance, "Believes humanity should become multiplanetary within this century")
+
+	// The same words under another dimension, differing only in punctuation
+	// so the content hashes don't match
+	submit := func(content string) *models.Fragment {
+		return &models.Fragment{ID: uuid.New(), ShellID: shell.ID, ClawID: claws[1].ID, Dimension: models.DimPersonality,
+			Content: content, ContentHash: util.HashContent(content)}
+	}
+	copied, pending := submit(embedded.Content+"!"), submit(unembedded.Content+"!")
+	matches, err := findPlagiarism([]*models.Fragment{copied, pending}, shell.ID)
+	if err != nil {
+		t.Fatalf("findPlagiarism: %v", err)
+	}
+	if m, ok := matches[copied.ID]; !ok || m.fragmentID != embedded.ID || m.method != models.PlagiarismEmbedding {
+		t.Errorf("copy in another dimension = %+v (found %v), want an embedding match of %s", m, ok, embedded.ID)
+	}
+
+	// Review reads stored vectors only and leaves embedding to the job
+	if _, ok := matches[pending.ID]; ok {
+		t.Errorf("matched a fragment that has no embedding yet")
+	}
+	var n int64
+	database.DB.Model(&models.FragmentEmbedding{}).Where("fragment_id = ?", unembedded.ID).Count(&n)
+	if n != 0 {
+		t.Fatal("review embedded the soul's accepted fragments")
+	}
+	if err := backfillFragmentEmbeddings(); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if matches, _ = findPlagiarism([]*models.Fragment{pending}, shell.ID); matches[pending.ID].fragmentID != unembedded.ID {
+		t.Errorf("after the backfill: matches = %+v, want %s", matches, unembedded.ID)
+	}
+}
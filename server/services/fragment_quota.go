@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/google/uuid"
+)
+
+// batchQuotaWindow is the rolling window of the per-soul batch quota.
+const batchQuotaWindow = 24 * time.Hour
+
+// ErrBatchQuota is wrapped by BatchQuotaError.
+var ErrBatchQuota = errors.New("batch quota for this soul used up")
+
+// BatchQuota is a Claw's batch allowance for one soul in the rolling window.
+type BatchQuota struct {
+	Handle    string     `json:"handle"`
+	Limit     int        `json:"limit"` // 0 = unlimited
+	Used      int        `json:"used"`
+	Remaining int        `json:"remaining"`
+	ResetsAt  *time.Time `json:"resets_at,omitempty"` // when the oldest counted batch leaves the window
+}
+
+// BatchQuotaError is returned by SubmitFragmentBatch when the quota is used
+// up; it carries the quota so handlers can report retry_after.
+type BatchQuotaError struct {
+	Quota BatchQuota
+}
+
+func (e *BatchQuotaError) Error() string {
+	return fmt.Sprintf("%v: %d of %d batches to @%s in the last 24h", ErrBatchQuota, e.Quota.Used, e.Quota.Limit, e.Quota.Handle)
+}
+
+func (e *BatchQuotaError) Unwrap() error { return ErrBatchQuota }
+
+// RetryAfter is how long until the next batch is allowed.
+func (e *BatchQuotaError) RetryAfter() time.Duration {
+	if e.Quota.ResetsAt == nil {
+		return 0
+	}
+	return max(time.Until(*e.Quota.ResetsAt), time.Second)
+}
+
+// ClawBatchLimit is how many batches a Claw may send one soul per 24h:
+// CLAW_SHELL_DAILY_BATCHES scaled by its trust score, but at least one.
+// 0 means unlimited.
+func ClawBatchLimit(claw *models.Claw) int {
+	base := config.Cfg.ClawShellDailyBatches
+	if base <= 0 {
+		return 0
+	}
+	return max(base*claw.TrustScore/100, 1)
+}
+
+// batchUsage is one soul's batch count in the window.
+type batchUsage struct {
+	ShellID uuid.UUID
+	Handle  string
+	Batches int
+	Oldest  time.Time
+}
+
+// clawBatchUsage counts a Claw's batches per soul within the window. Batch
+// rows outlive their fragments, so deleting fragments doesn't reset the quota.
+func clawBatchUsage(clawID uuid.UUID, shellID *uuid.UUID) []batchUsage {
+	query := database.DB.Table("fragment_batches b").
+		Select("b.shell_id, s.handle, COUNT(*) AS batches, MIN(b.created_at) AS oldest").
+		Joins("JOIN shells s ON s.id = b.shell_id").
+		Where("b.claw_id = ? AND b.created_at > ?", clawID, time.Now().Add(-batchQuotaWindow)).
+		Group("b.shell_id, s.handle").
+		Order("oldest")
+	if shellID != nil {
+		query = query.Where("b.shell_id = ?", *shellID)
+	}
+	var usage []batchUsage
+	query.Scan(&usage)
+	return usage
+}
+
+func newBatchQuota(handle string, limit int, usage *batchUsage) BatchQuota {
+	q := BatchQuota{Handle: handle, Limit: limit}
+	if usage != nil {
+		q.Used = usage.Batches
+		resets := usage.Oldest.Add(batchQuotaWindow)
+		q.ResetsAt = &resets
+	}
+	if limit > 0 {
+		q.Remaining = max(limit-q.Used, 0)
+	}
+	return q
+}
+
+// checkBatchQuota returns a BatchQuotaError if the Claw can't send shell
+// another batch right now.
+func checkBatchQuota(claw *models.Claw, shell *models.Shell) error {
+	limit := ClawBatchLimit(claw)
+	if limit == 0 {
+		return nil
+	}
+	usage := clawBatchUsage(claw.ID, &shell.ID)
+	if len(usage) == 0 || usage[0].Batches < limit {
+		return nil
+	}
+	return &BatchQuotaError{Quota: newBatchQuota(shell.Handle, limit, &usage[0])}
+}
+
+// ClawBatchQuotas lists the Claw's quota for every soul it sent batches to
+// in the last 24h, for the dashboard.
+func ClawBatchQuotas(claw *models.Claw) []BatchQuota {
+	limit := ClawBatchLimit(claw)
+	usage := clawBatchUsage(claw.ID, nil)
+	quotas := make([]BatchQuota, len(usage))
+	for i := range usage {
+		quotas[i] = newBatchQuota(usage[i].Handle, limit, &usage[i])
+	}
+	return quotas
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+)
+
+// Default submission limits for dimensions without a rule in the policy.
+const (
+	defaultMinFragmentChars = 50
+	defaultMaxFragmentChars = 5000
+	maxFragmentCharsCeiling = 20000 // no rule may allow more
+)
+
+// Quality heuristics for submitted content.
+const (
+	minFragmentLetters  = 20  // letters left once URLs are removed
+	minUniqueWordRatio  = 0.3 // distinct / total words, for texts of boilerplateMinWords or more
+	boilerplateMinWords = 20
+	maxRepeatedRunes    = 12 // the same character this many times in a row is filler
+)
+
+// Reasons a submission fails validation, exposed as SubmissionError.Reason.
+const (
+	SubmissionInvalidDimension   = "invalid_dimension"
+	SubmissionDuplicateDimension = "duplicate_dimension"
+	SubmissionTooShort           = "too_short"
+	SubmissionTooLong            = "too_long"
+	SubmissionUnsupportedLang    = "unsupported_language"
+	SubmissionLangMismatch       = "language_mismatch"
+	SubmissionURLOnly            = "url_only"
+	SubmissionBoilerplate        = "boilerplate"
+	SubmissionNotesTooLong       = "notes_too_long"
+	SubmissionInvalidClaims      = "invalid_claims"
+)
+
+// ErrInvalidSubmission is wrapped by every SubmissionError.
+var ErrInvalidSubmission = errors.New("invalid submission")
+
+// SubmissionError describes why one fragment of a submission was refused.
+type SubmissionError struct {
+	Dimension string
+	Reason    string // see Submission* constants
+	Message   string
+	Min, Max  int // length limits, for too_short / too_long / notes_too_long
+}
+
+func (e *SubmissionError) Error() string { return e.Message }
+
+func (e *SubmissionError) Unwrap() error { return ErrInvalidSubmission }
+
+// FragmentSubmission is one fragment to validate.
+type FragmentSubmission struct {
+	Dimension string
+	Content   string
+	Lang      string // ISO 639-1; "" = detect from the content
+	Notes     string
+	Claims    []FragmentClaimInput
+}
+
+var (
+	urlPattern         = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+	placeholderPattern = regexp.MustCompile(`(?i)lorem ipsum|\[(?:insert|your|placeholder)[^\]]*\]|\{\{[^}]*\}\}|<(?:insert|your|placeholder)[^>]*>|\b(?:placeholder text|your text here)\b`)
+)
+
+// nonLatinLanguages are the codes DetectLanguage derives from the script
+// alone, where a declared Latin-script language is certainly wrong.
+var nonLatinLanguages = map[string]bool{
+	"zh": true, "ja": true, "ko": true, "ru": true, "ar": true, "hi": true, "th": true,
+}
+
+// SubmissionRuleFor returns the length limits in effect for a dimension.
+func SubmissionRuleFor(dimension string) models.SubmissionRule {
+	ensoulingPolicy.RLock()
+	rule, ok := ensoulingPolicy.submission[dimension]
+	ensoulingPolicy.RUnlock()
+	if !ok {
+		return models.SubmissionRule{Dimension: dimension, MinChars: defaultMinFragmentChars, MaxChars: defaultMaxFragmentChars}
+	}
+	return rule
+}
+
+// SubmissionRules returns the length limits in effect for every dimension.
+func SubmissionRules() []models.SubmissionRule {
+	rules := make([]models.SubmissionRule, len(dimensionOrder))
+	for i, dim := range dimensionOrder {
+		rules[i] = SubmissionRuleFor(dim)
+	}
+	return rules
+}
+
+// UpdateSubmissionRules sets the length limits of the given dimensions;
+// others keep theirs.
+func UpdateSubmissionRules(rules []models.SubmissionRule, updatedBy string) ([]models.SubmissionRule, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("at least one rule is required")
+	}
+	seen := make(map[string]bool, len(rules))
+	for i := range rules {
+		r := &rules[i]
+		switch {
+		case !validDimensions[r.Dimension]:
+			return nil, fmt.Errorf("rule %d: unknown dimension %q", i+1, r.Dimension)
+		case seen[r.Dimension]:
+			return nil, fmt.Errorf("duplicate rule for %s", r.Dimension)
+		case r.MinChars < 1 || r.MaxChars < r.MinChars || r.MaxChars > maxFragmentCharsCeiling:
+			return nil, fmt.Errorf("%s: need 1 <= min_chars <= max_chars <= %d", r.Dimension, maxFragmentCharsCeiling)
+		}
+		seen[r.Dimension] = true
+		r.UpdatedBy = updatedBy
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Dimension < rules[j].Dimension })
+
+	if err := database.DB.Save(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to save submission rules: %w", err)
+	}
+	reloadEnsoulingPolicy()
+	return SubmissionRules(), nil
+}
+
+// ValidateSubmissions checks a set of fragments for one soul: each must pass
+// ValidateSubmission and no dimension may appear twice. It returns the
+// language of each fragment, detected where none was declared.
+func ValidateSubmissions(items []FragmentSubmission) ([]string, error) {
+	langs := make([]string, len(items))
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		if seen[item.Dimension] {
+			return nil, &SubmissionError{
+				Dimension: item.Dimension,
+				Reason:    SubmissionDuplicateDimension,
+				Message:   "Duplicate dimension: " + item.Dimension + ". Each dimension can only appear once per batch.",
+			}
+		}
+		seen[item.Dimension] = true
+
+		lang, err := ValidateSubmission(item)
+		if err != nil {
+			return nil, err
+		}
+		langs[i] = lang
+	}
+	return langs, nil
+}
+
+// ValidateSubmission checks one fragment against the curation policy's
+// length limits and the cheap quality heuristics that don't need the curator.
+// It returns the fragment's language, detected when none was declared.
+func ValidateSubmission(item FragmentSubmission) (string, error) {
+	if err := ValidateFragmentContent(item.Dimension, item.Content); err != nil {
+		return "", err
+	}
+	fail := func(reason, message string) (string, error) {
+		return "", &SubmissionError{Dimension: item.Dimension, Reason: reason, Message: message}
+	}
+
+	detected := DetectLanguage(item.Content)
+	lang := item.Lang
+	switch {
+	case lang == "":
+		lang = detected
+	case !IsSupportedLanguage(lang):
+		return fail(SubmissionUnsupportedLang, "Unsupported lang \""+lang+"\" for dimension "+item.Dimension+
+			" (use an ISO 639-1 code such as en, zh, ja, es)")
+	case nonLatinLanguages[detected] && detected != lang:
+		return fail(SubmissionLangMismatch, fmt.Sprintf("Content for dimension %s looks like %s, not lang %q",
+			item.Dimension, LanguageName(detected), lang))
+	}
+
+	if utf8.RuneCountInString(item.Notes) > MaxFragmentNotesChars {
+		return "", &SubmissionError{
+			Dimension: item.Dimension,
+			Reason:    SubmissionNotesTooLong,
+			Message:   fmt.Sprintf("Notes too long for dimension %s (max %d characters)", item.Dimension, MaxFragmentNotesChars),
+			Max:       MaxFragmentNotesChars,
+		}
+	}
+	if problem := ValidateFragmentClaims(item.Claims); problem != "" {
+		return fail(SubmissionInvalidClaims, "Invalid claims for dimension "+item.Dimension+": "+problem)
+	}
+	return lang, nil
+}
+
+// ValidateFragmentContent checks a fragment's dimension, its length against
+// the dimension's rule, and that it is prose rather than links or filler.
+func ValidateFragmentContent(dimension, content string) error {
+	if !validDimensions[dimension] {
+		return &SubmissionError{Dimension: dimension, Reason: SubmissionInvalidDimension, Message: "Invalid dimension: " + dimension}
+	}
+
+	// Lengths count characters, not bytes, so CJK fragments get the same room
+	rule := SubmissionRuleFor(dimension)
+	n := utf8.RuneCountInString(strings.TrimSpace(content))
+	if n < rule.MinChars {
+		return &SubmissionError{
+			Dimension: dimension, Reason: SubmissionTooShort, Min: rule.MinChars, Max: rule.MaxChars,
+			Message: fmt.Sprintf("Content too short for dimension %s (min %d characters)", dimension, rule.MinChars),
+		}
+	}
+	if n > rule.MaxChars {
+		return &SubmissionError{
+			Dimension: dimension, Reason: SubmissionTooLong, Min: rule.MinChars, Max: rule.MaxChars,
+			Message: fmt.Sprintf("Content too long for dimension %s (max %d characters)", dimension, rule.MaxChars),
+		}
+	}
+
+	if countLetters(urlPattern.ReplaceAllString(content, " ")) < minFragmentLetters {
+		return &SubmissionError{Dimension: dimension, Reason: SubmissionURLOnly,
+			Message: "Content for dimension " + dimension + " has too little text besides links; describe what the sources show"}
+	}
+	if problem := boilerplateProblem(content); problem != "" {
+		return &SubmissionError{Dimension: dimension, Reason: SubmissionBoilerplate,
+			Message: "Content for dimension " + dimension + " looks like filler: " + problem}
+	}
+	return nil
+}
+
+// countLetters counts the letters of a text. A CJK character carries about
+// as much as a Latin word, so it counts as five letters.
+func countLetters(text string) int {
+	n := 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hiragana, r),
+			unicode.Is(unicode.Katakana, r), unicode.Is(unicode.Hangul, r):
+			n += 5
+		case unicode.IsLetter(r):
+			n++
+		}
+	}
+	return n
+}
+
+// boilerplateProblem names the sign of template or filler text it finds, or "".
+func boilerplateProblem(content string) string {
+	if m := placeholderPattern.FindString(content); m != "" {
+		return fmt.Sprintf("template placeholder %q", m)
+	}
+
+	run, prev := 0, rune(0)
+	for _, r := range content {
+		if r == prev && !unicode.IsSpace(r) {
+			run++
+			if run >= maxRepeatedRunes {
+				return fmt.Sprintf("%q repeated %d+ times", string(r), maxRepeatedRunes)
+			}
+		} else {
+			run, prev = 1, r
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(content), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) >= boilerplateMinWords {
+		distinct := make(map[string]bool, len(words))
+		for _, w := range words {
+			distinct[w] = true
+		}
+		if float64(len(distinct))/float64(len(words)) < minUniqueWordRatio {
+			return "the same few words repeated"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// MaxFragmentVerifyItems caps how many fragments a single verify request may check.
+const MaxFragmentVerifyItems = 50
+
+// On-chain verification statuses
+const (
+	ChainProofVerified     = "verified"      // on-chain feedbackHash matches the supplied content
+	ChainProofMismatch     = "mismatch"      // on-chain feedbackHash differs from the supplied content
+	ChainProofNotSubmitted = "not_submitted" // no feedback tx recorded for this fragment
+	ChainProofUnavailable  = "unavailable"   // tx could not be read from the chain
+)
+
+// FragmentProofRequest is one (fragment_id, content) pair to verify.
+type FragmentProofRequest struct {
+	FragmentID string `json:"fragment_id"`
+	Content    string `json:"content"`
+}
+
+// FragmentProof is the verification result for a single fragment.
+type FragmentProof struct {
+	FragmentID         string `json:"fragment_id"`
+	Found              bool   `json:"found"`
+	ContentHash        string `json:"content_hash,omitempty"`          // sha256 of the supplied content
+	StoredContentHash  string `json:"stored_content_hash,omitempty"`   // sha256 stored by the platform
+	ContentMatch       bool   `json:"content_match"`                   // supplied content matches stored hash
+	FeedbackHash       string `json:"feedback_hash,omitempty"`         // keccak256 of the supplied content
+	OnChainStatus      string `json:"onchain_status,omitempty"`        // see ChainProof* constants
+	OnChainHash        string `json:"onchain_feedback_hash,omitempty"` // feedbackHash read from the chain
+	TxHash             string `json:"tx_hash,omitempty"`
+	OnChainClawAddress string `json:"onchain_claw_address,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+// VerifyFragments checks supplied contents against each fragment's stored
+// content_hash and the feedbackHash recorded in its on-chain reputation feedback.
+func VerifyFragments(items []FragmentProofRequest) []FragmentProof {
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		if uid, err := uuid.Parse(item.FragmentID); err == nil {
+			ids = append(ids, uid)
+		}
+	}
+
+	var fragments []models.Fragment
+	if len(ids) > 0 {
+		database.DB.Where("id IN ?", ids).Find(&fragments)
+	}
+	// Archived rejected fragments keep their hashes, so they still verify
+	if len(fragments) < len(ids) {
+		fragments = append(fragments, archivedFragmentsAsFragments(ids)...)
+	}
+	byID := make(map[string]models.Fragment, len(fragments))
+	for _, f := range fragments {
+		byID[f.ID.String()] = f
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	onChain := make(map[string]*chainFeedbackProof) // tx hash → decoded event, shared across items
+
+	results := make([]FragmentProof, len(items))
+	for i, item := range items {
+		proof := FragmentProof{FragmentID: item.FragmentID}
+
+		uid, err := uuid.Parse(item.FragmentID)
+		if err != nil {
+			proof.Error = "invalid fragment ID"
+			results[i] = proof
+			continue
+		}
+		f, ok := byID[uid.String()]
+		if !ok {
+			proof.Error = "fragment not found"
+			results[i] = proof
+			continue
+		}
+
+		feedbackHash := feedbackHashOf(item.Content)
+		proof.Found = true
+		proof.ContentHash = util.HashContent(item.Content)
+		proof.StoredContentHash = f.ContentHash
+		proof.ContentMatch = proof.ContentHash == f.ContentHash
+		proof.FeedbackHash = "0x" + hex.EncodeToString(feedbackHash[:])
+		proof.TxHash = f.TxHash
+
+		if !isFeedbackTxHash(f.TxHash) {
+			proof.OnChainStatus = ChainProofNotSubmitted
+			proof.TxHash = ""
+			results[i] = proof
+			continue
+		}
+
+		cp, cached := onChain[f.TxHash]
+		if !cached {
+			cp = readChainFeedbackProof(ctx, f.TxHash)
+			onChain[f.TxHash] = cp
+		}
+		if cp.err != "" {
+			proof.OnChainStatus = ChainProofUnavailable
+			proof.Error = cp.err
+		} else {
+			proof.OnChainHash = "0x" + hex.EncodeToString(cp.hash[:])
+			proof.OnChainClawAddress = cp.client
+			proof.OnChainStatus = ChainProofMismatch
+			if cp.hash == feedbackHash {
+				proof.OnChainStatus = ChainProofVerified
+			}
+		}
+		results[i] = proof
+	}
+	return results
+}
+
+// chainFeedbackProof is the feedbackHash recorded by a giveFeedback tx.
+type chainFeedbackProof struct {
+	hash   [32]byte
+	client string
+	err    string
+}
+
+func readChainFeedbackProof(ctx context.Context, txHash string) *chainFeedbackProof {
+	ev, err := chain.ReadFeedbackFromTx(ctx, txHash)
+	if err != nil {
+		util.Log.Debug("[verify] Failed to read feedback tx %s: %v", txHash, err)
+		return &chainFeedbackProof{err: "on-chain feedback could not be read"}
+	}
+	return &chainFeedbackProof{hash: ev.FeedbackHash, client: ev.ClientAddress.Hex()}
+}
+
+// isFeedbackTxHash reports whether a fragment's tx_hash column holds a real
+// transaction hash (it may also hold markers such as "drip_failed").
+func isFeedbackTxHash(s string) bool {
+	if len(s) != 66 || s[:2] != "0x" {
+		return false
+	}
+	_, err := hex.DecodeString(s[2:])
+	return err == nil
+}
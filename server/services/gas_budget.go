@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// weiPerBNB is 10^18.
+var weiPerBNB = new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+
+// dripBudgetMu serializes budget checks so concurrent feedback submissions
+// can't all pass the global ceiling check before any of them is recorded.
+var dripBudgetMu sync.Mutex
+
+// ensureClawGas tops up a Claw wallet with gas if needed, within the drip budget.
+// Every drip attempt (including budget denials) is written to the gas_drips ledger.
+func ensureClawGas(ctx context.Context, claw *models.Claw) error {
+	// Addresses the platform doesn't hold the key of never get gas: their
+	// feedback is relayed from the platform wallet instead
+	if claw.CustodyMode == models.ClawCustodyExternal {
+		return fmt.Errorf("claw %s uses an external wallet, which is never dripped", claw.Name)
+	}
+	needs, err := chain.NeedsGasDrip(ctx, claw.WalletAddr)
+	if err != nil {
+		return fmt.Errorf("gas check failed: %w", err)
+	}
+	if !needs {
+		return nil
+	}
+
+	dripBudgetMu.Lock()
+	if reason := checkDripBudget(claw); reason != "" {
+		dripBudgetMu.Unlock()
+		recordDrip(claw, models.GasDripDenied, reason, "", big.NewInt(0))
+		util.Log.Warn("[gas] Drip denied for claw %s: %s", claw.Name, reason)
+		return fmt.Errorf("gas drip denied: %s", reason)
+	}
+	drip := recordDrip(claw, models.GasDripPending, "", "", chain.DripAmount)
+	dripBudgetMu.Unlock()
+
+	txHash, err := chain.DripGas(ctx, claw.WalletAddr)
+	if err != nil {
+		database.DB.Model(drip).Updates(map[string]interface{}{"status": models.GasDripFailed, "reason": err.Error()})
+		return fmt.Errorf("gas drip failed: %w", err)
+	}
+	database.DB.Model(drip).Update("tx_hash", txHash)
+	trackChainSpend(txHash, models.ChainFeatureDrip, nil, &claw.ID)
+
+	if err := chain.WaitForDrip(ctx, txHash); err != nil {
+		database.DB.Model(drip).Updates(map[string]interface{}{"status": models.GasDripFailed, "reason": err.Error()})
+		return err
+	}
+	database.DB.Model(drip).Update("status", models.GasDripConfirmed)
+	util.Log.Info("[gas] Drip confirmed for claw %s (%s): tx=%s", claw.Name, claw.WalletAddr, txHash)
+
+	go checkPlatformBalance()
+	return nil
+}
+
+// checkDripBudget returns a reason if dripping to this Claw would exceed a cap, or "".
+// Pending and confirmed drips count towards the caps; failed and denied ones don't.
+func checkDripBudget(claw *models.Claw) string {
+	cfg := config.Cfg
+	spent := []string{models.GasDripPending, models.GasDripConfirmed}
+
+	if cfg.GasDripClawDailyCap > 0 {
+		var n int64
+		database.DB.Model(&models.GasDrip{}).
+			Where("claw_id = ? AND status IN ? AND created_at > ?", claw.ID, spent, time.Now().Add(-24*time.Hour)).
+			Count(&n)
+		if n >= int64(cfg.GasDripClawDailyCap) {
+			return fmt.Sprintf("claw daily cap reached (%d drips / 24h)", cfg.GasDripClawDailyCap)
+		}
+	}
+
+	if cfg.GasDripClawLifetimeCap > 0 {
+		var n int64
+		database.DB.Model(&models.GasDrip{}).
+			Where("claw_id = ? AND status IN ?", claw.ID, spent).
+			Count(&n)
+		if n >= int64(cfg.GasDripClawLifetimeCap) {
+			return fmt.Sprintf("claw lifetime cap reached (%d drips)", cfg.GasDripClawLifetimeCap)
+		}
+	}
+
+	if cfg.GasDripHourlyCeiling > 0 {
+		hourly := dripSpendSince(time.Now().Add(-time.Hour))
+		next := new(big.Int).Add(hourly, chain.DripAmount)
+		if weiToBNB(next) > cfg.GasDripHourlyCeiling {
+			return fmt.Sprintf("global hourly ceiling reached (%.4f BNB / hour)", cfg.GasDripHourlyCeiling)
+		}
+	}
+
+	return ""
+}
+
+// recordDrip writes a ledger entry.
+func recordDrip(claw *models.Claw, status, reason, txHash string, amount *big.Int) *models.GasDrip {
+	drip := &models.GasDrip{
+		ClawID:     claw.ID,
+		WalletAddr: claw.WalletAddr,
+		AmountWei:  amount.String(),
+		Status:     status,
+		Reason:     reason,
+		TxHash:     txHash,
+	}
+	if err := database.DB.Create(drip).Error; err != nil {
+		util.Log.Error("[gas] Failed to record drip for claw %s: %v", claw.Name, err)
+	}
+	return drip
+}
+
+// dripSpendSince sums pending + confirmed drip amounts since t (zero time = all time).
+func dripSpendSince(since time.Time) *big.Int {
+	var total string
+	query := database.DB.Model(&models.GasDrip{}).
+		Select("CAST(COALESCE(SUM(amount_wei), 0) AS TEXT)").
+		Where("status IN ?", []string{models.GasDripPending, models.GasDripConfirmed})
+	if !since.IsZero() {
+		query = query.Where("created_at > ?", since)
+	}
+	query.Scan(&total)
+
+	sum, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return big.NewInt(0)
+	}
+	return sum
+}
+
+// checkPlatformBalance logs a warning when the platform wallet runs low.
+func checkPlatformBalance() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	balance, err := chain.GetPlatformBalance(ctx)
+	if err != nil {
+		return
+	}
+	if bnb := weiToBNB(balance); bnb < config.Cfg.GasLowBalanceAlert {
+		util.Log.Warn("[gas] Platform wallet balance low: %.6f BNB (alert threshold %.4f BNB)", bnb, config.Cfg.GasLowBalanceAlert)
+	}
+}
+
+// GasSpendReport is the admin view of drip spend and platform wallet health.
+type GasSpendReport struct {
+	PlatformBalanceBNB  *float64         `json:"platform_balance_bnb"` // nil if the chain is unreachable
+	LowBalanceThreshold float64          `json:"low_balance_threshold_bnb"`
+	Alerts              []string         `json:"alerts"`
+	SpendLastHourBNB    float64          `json:"spend_last_hour_bnb"`
+	SpendLast24hBNB     float64          `json:"spend_last_24h_bnb"`
+	SpendLifetimeBNB    float64          `json:"spend_lifetime_bnb"`
+	HourlyCeilingBNB    float64          `json:"hourly_ceiling_bnb"`
+	ClawDailyCap        int              `json:"claw_daily_cap"`
+	ClawLifetimeCap     int              `json:"claw_lifetime_cap"`
+	DripsLast24h        map[string]int   `json:"drips_last_24h"` // status → count
+	TopClaws            []ClawGasSpend   `json:"top_claws"`
+	RecentDrips         []models.GasDrip `json:"recent_drips"`
+}
+
+// ClawGasSpend is the lifetime drip spend of a single Claw.
+type ClawGasSpend struct {
+	ClawID   string  `json:"claw_id"`
+	Name     string  `json:"name"`
+	Drips    int     `json:"drips"`
+	TotalBNB float64 `json:"total_bnb"`
+}
+
+// GetGasSpendReport builds the admin gas report.
+func GetGasSpendReport() (*GasSpendReport, error) {
+	cfg := config.Cfg
+	report := &GasSpendReport{
+		LowBalanceThreshold: cfg.GasLowBalanceAlert,
+		Alerts:              []string{},
+		SpendLastHourBNB:    weiToBNB(dripSpendSince(time.Now().Add(-time.Hour))),
+		SpendLast24hBNB:     weiToBNB(dripSpendSince(time.Now().Add(-24 * time.Hour))),
+		SpendLifetimeBNB:    weiToBNB(dripSpendSince(time.Time{})),
+		HourlyCeilingBNB:    cfg.GasDripHourlyCeiling,
+		ClawDailyCap:        cfg.GasDripClawDailyCap,
+		ClawLifetimeCap:     cfg.GasDripClawLifetimeCap,
+		DripsLast24h:        make(map[string]int),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if balance, err := chain.GetPlatformBalance(ctx); err != nil {
+		report.Alerts = append(report.Alerts, "platform balance unavailable: "+err.Error())
+	} else {
+		bnb := weiToBNB(balance)
+		report.PlatformBalanceBNB = &bnb
+		if bnb < cfg.GasLowBalanceAlert {
+			report.Alerts = append(report.Alerts, fmt.Sprintf("platform wallet balance %.6f BNB is below %.4f BNB", bnb, cfg.GasLowBalanceAlert))
+		}
+	}
+	if cfg.GasDripHourlyCeiling > 0 && report.SpendLastHourBNB >= cfg.GasDripHourlyCeiling*0.8 {
+		report.Alerts = append(report.Alerts, fmt.Sprintf("hourly drip spend %.4f BNB is at %.0f%% of the ceiling",
+			report.SpendLastHourBNB, report.SpendLastHourBNB/cfg.GasDripHourlyCeiling*100))
+	}
+
+	var statusCounts []struct {
+		Status string
+		Count  int
+	}
+	database.DB.Model(&models.GasDrip{}).
+		Select("status, COUNT(*) AS count").
+		Where("created_at > ?", time.Now().Add(-24*time.Hour)).
+		Group("status").Scan(&statusCounts)
+	for _, sc := range statusCounts {
+		report.DripsLast24h[sc.Status] = sc.Count
+	}
+	if denied := report.DripsLast24h[models.GasDripDenied]; denied > 0 {
+		report.Alerts = append(report.Alerts, fmt.Sprintf("%d drips denied by budget in the last 24h", denied))
+	}
+
+	var top []struct {
+		ClawID string
+		Name   string
+		Drips  int
+		Total  string
+	}
+	database.DB.Table("gas_drips").
+		Select("CAST(gas_drips.claw_id AS TEXT) AS claw_id, claws.name AS name, COUNT(*) AS drips, CAST(SUM(gas_drips.amount_wei) AS TEXT) AS total").
+		Joins("LEFT JOIN claws ON claws.id = gas_drips.claw_id").
+		Where("gas_drips.status IN ?", []string{models.GasDripPending, models.GasDripConfirmed}).
+		Group("gas_drips.claw_id, claws.name").
+		Order("SUM(gas_drips.amount_wei) DESC").
+		Limit(10).Scan(&top)
+	report.TopClaws = make([]ClawGasSpend, len(top))
+	for i, t := range top {
+		total, _ := new(big.Int).SetString(t.Total, 10)
+		report.TopClaws[i] = ClawGasSpend{ClawID: t.ClawID, Name: t.Name, Drips: t.Drips, TotalBNB: weiToBNB(total)}
+	}
+
+	database.DB.Order("created_at DESC").Limit(20).Find(&report.RecentDrips)
+
+	return report, nil
+}
+
+// weiToBNB converts a wei amount to BNB.
+func weiToBNB(wei *big.Int) float64 {
+	if wei == nil {
+		return 0
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), weiPerBNB).Float64()
+	return f
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+func TestNoGasForExternalClaw(t *testing.T) {
+	util.InitLogger("error")
+	config.Cfg = &config.Config{}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	claw := &models.Claw{Name: "selfcustody", APIKeyHash: "hash", ClaimCode: "claim", VerificationCode: "code",
+		CustodyMode: models.ClawCustodyExternal, WalletAddr: "0x000000000000000000000000000000000000dEaD"}
+	if err := ensureClawGas(context.Background(), claw); err == nil {
+		t.Fatal("ensureClawGas accepted an external wallet")
+	}
+	var drips int64
+	database.DB.Model(&models.GasDrip{}).Count(&drips)
+	if drips != 0 {
+		t.Errorf("%d drips recorded for an external wallet", drips)
+	}
+}
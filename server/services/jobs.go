@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// Errors for manual job runs.
+var (
+	ErrJobNotFound = errors.New("no such job")
+	ErrJobRunning  = errors.New("job is already running")
+)
+
+// Job triggers.
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerStartup  = "startup"
+	JobTriggerManual   = "manual"
+)
+
+// JobStatus is what admins see of a background job. Counters are per process
+// and start over on restart.
+type JobStatus struct {
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Interval       string     `json:"interval"`
+	Running        bool       `json:"running"`
+	Runs           int        `json:"runs"`
+	Failures       int        `json:"failures"`
+	Skipped        int        `json:"skipped"` // scheduled runs skipped in read-only mode
+	LastTrigger    string     `json:"last_trigger,omitempty"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+}
+
+// job is a registered background task. A run is skipped if the previous one
+// (scheduled or manual) is still going.
+type job struct {
+	run     func() error
+	running atomic.Bool
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+var jobRegistry = struct {
+	sync.Mutex
+	jobs map[string]*job
+}{jobs: make(map[string]*job)}
+
+// scheduleJob registers fn under name and runs it every interval, plus once
+// right away when runOnStart is set. fn returns the error that makes a run
+// count as failed; finer-grained problems stay in its own logs.
+func scheduleJob(name, description string, interval time.Duration, runOnStart bool, fn func() error) {
+	j := &job{run: fn, status: JobStatus{Name: name, Description: description, Interval: interval.String()}}
+	jobRegistry.Lock()
+	jobRegistry.jobs[name] = j
+	jobRegistry.Unlock()
+
+	go func() {
+		if runOnStart {
+			j.execute(JobTriggerStartup)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		j.setNextRun(time.Now().Add(interval))
+		for range ticker.C {
+			j.setNextRun(time.Now().Add(interval))
+			j.execute(JobTriggerSchedule)
+		}
+	}()
+}
+
+func (j *job) setNextRun(t time.Time) {
+	j.mu.Lock()
+	j.status.NextRunAt = &t
+	j.mu.Unlock()
+}
+
+// execute runs the job once unless it is already running, recording the
+// outcome. Scheduled and startup runs are skipped while the platform is
+// read-only; admins can still run a job by hand.
+func (j *job) execute(trigger string) {
+	if trigger != JobTriggerManual && middleware.Maintenance().ReadOnly {
+		j.mu.Lock()
+		j.status.Skipped++
+		j.mu.Unlock()
+		return
+	}
+	if !j.running.CompareAndSwap(false, true) {
+		return
+	}
+	defer j.running.Store(false)
+
+	start := time.Now()
+	j.mu.Lock()
+	j.status.LastTrigger = trigger
+	j.status.LastStartedAt = &start
+	j.mu.Unlock()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return j.run()
+	}()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Runs++
+	j.status.LastDurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		now := time.Now()
+		j.status.Failures++
+		j.status.LastError = err.Error()
+		j.status.LastErrorAt = &now
+		util.Log.Error("[jobs] %s (%s) failed after %v: %v", j.status.Name, trigger, time.Since(start).Round(time.Millisecond), err)
+	}
+}
+
+func (j *job) snapshot() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.status
+	s.Running = j.running.Load()
+	return s
+}
+
+// ListJobs returns the status of every registered job, by name.
+func ListJobs() []JobStatus {
+	jobRegistry.Lock()
+	list := make([]JobStatus, 0, len(jobRegistry.jobs))
+	for _, j := range jobRegistry.jobs {
+		list = append(list, j.snapshot())
+	}
+	jobRegistry.Unlock()
+
+	sort.Slice(list, func(a, b int) bool { return list[a].Name < list[b].Name })
+	return list
+}
+
+// RunJob starts a registered job now, in the background, without waiting for
+// its next tick.
+func RunJob(name string) (*JobStatus, error) {
+	jobRegistry.Lock()
+	j := jobRegistry.jobs[name]
+	jobRegistry.Unlock()
+	if j == nil {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if j.running.Load() {
+		return nil, fmt.Errorf("%s: %w", name, ErrJobRunning)
+	}
+
+	go j.execute(JobTriggerManual)
+	util.Log.Info("[jobs] %s triggered manually", name)
+	status := j.snapshot()
+	return &status, nil
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/middleware"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+func TestJobsSkippedWhileReadOnly(t *testing.T) {
+	util.InitLogger("error")
+	config.Cfg = &config.Config{}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		middleware.SetMaintenance(false, "", nil, "test")
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	runs := 0
+	j := &job{run: func() error { runs++; return nil }}
+	if _, err := middleware.SetMaintenance(true, "", nil, "test"); err != nil {
+		t.Fatalf("set read-only: %v", err)
+	}
+
+	j.execute(JobTriggerStartup)
+	j.execute(JobTriggerSchedule)
+	if status := j.snapshot(); runs != 0 || status.Skipped != 2 {
+		t.Errorf("read-only: runs = %d, skipped = %d, want 0 and 2", runs, status.Skipped)
+	}
+	j.execute(JobTriggerManual)
+	if runs != 1 {
+		t.Errorf("manual run while read-only: runs = %d, want 1", runs)
+	}
+
+	middleware.SetMaintenance(false, "", nil, "test")
+	j.execute(JobTriggerSchedule)
+	if runs != 2 {
+		t.Errorf("after read-only: runs = %d, want 2", runs)
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/models"
+)
+
+// knowledgeLinePrefix starts the line of a soul prompt stating its knowledge
+// cutoff. The line is rewritten on every ensouling, never left to the LLM.
+const knowledgeLinePrefix = "Knowledge as of "
+
+// cutoffDateFormat is how cutoffs and today's date are given to the LLM.
+const cutoffDateFormat = "January 2, 2006"
+
+// parseTweetTime parses a tweet timestamp: RFC 3339 from Twitter v2 and
+// SocialData's tweet_created_at, or the legacy v1.1 format.
+func parseTweetTime(s string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, time.RubyDate} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// latestTweetTime returns when the newest of the tweets was posted.
+func latestTweetTime(tweets []TwitterTweet) (time.Time, bool) {
+	var latest time.Time
+	for _, t := range tweets {
+		if at, ok := parseTweetTime(t.CreatedAt); ok && at.After(latest) {
+			latest = at
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// seedKnowledgeCutoff is the cutoff of a soul created from a seed preview:
+// the newest tweet the seed was extracted from.
+func seedKnowledgeCutoff(twitterMeta map[string]interface{}) *time.Time {
+	s, _ := twitterMeta["latest_tweet_at"].(string)
+	at, ok := parseTweetTime(s)
+	if !ok {
+		return nil
+	}
+	return &at
+}
+
+// ensoulingKnowledgeCutoff is the cutoff after merging fragments: the newest
+// of the current cutoff and the fragments' submission times.
+func ensoulingKnowledgeCutoff(shell *models.Shell, fragments []models.Fragment) *time.Time {
+	var cutoff time.Time
+	if shell.KnowledgeCutoff != nil {
+		cutoff = *shell.KnowledgeCutoff
+	}
+	for _, f := range fragments {
+		if f.CreatedAt.After(cutoff) {
+			cutoff = f.CreatedAt
+		}
+	}
+	if cutoff.IsZero() {
+		return nil
+	}
+	cutoff = cutoff.UTC()
+	return &cutoff
+}
+
+// withKnowledgeCutoff puts the knowledge cutoff line right after the first
+// line of a soul prompt, replacing any earlier one.
+func withKnowledgeCutoff(prompt string, cutoff *time.Time) string {
+	lines := strings.Split(prompt, "\n")
+	kept := lines[:0]
+	for _, l := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(l), knowledgeLinePrefix) {
+			kept = append(kept, l)
+		}
+	}
+	if cutoff == nil || len(kept) == 0 {
+		return strings.Join(kept, "\n")
+	}
+	line := fmt.Sprintf("%s%s: you know what was public up to this date and nothing that happened after it.",
+		knowledgeLinePrefix, cutoff.Format(cutoffDateFormat))
+	kept = append(kept[:1], append([]string{line}, kept[1:]...)...)
+	return strings.Join(kept, "\n")
+}
+
+// temporalGuidance tells a soul in chat today's date and how to answer about
+// what happened after its knowledge cutoff.
+func temporalGuidance(cutoff *time.Time) string {
+	today := time.Now().UTC().Format(cutoffDateFormat)
+	if cutoff == nil {
+		return fmt.Sprintf("Today is %s. If asked about recent events you have no information on, say so instead of guessing.\n", today)
+	}
+	return fmt.Sprintf("Today is %s; your knowledge ends on %s. If asked about anything after that date, say plainly that you "+
+		"don't know about it yet. Never invent later events, positions or statements.\n", today, cutoff.Format(cutoffDateFormat))
+}
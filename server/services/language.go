@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// languageNames maps the supported ISO 639-1 codes to the names used in prompts.
+var languageNames = map[string]string{
+	"en": "English", "zh": "Chinese", "ja": "Japanese", "ko": "Korean",
+	"es": "Spanish", "fr": "French", "de": "German", "pt": "Portuguese",
+	"it": "Italian", "ru": "Russian", "ar": "Arabic", "hi": "Hindi",
+	"th": "Thai", "vi": "Vietnamese", "id": "Indonesian", "tr": "Turkish",
+}
+
+// latinStopwords are frequent short words that tell Latin-script languages apart.
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "you", "what", "this", "with", "for", "do"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "es", "en", "por", "para", "una", "como", "qué", "pero"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "que", "une", "dans", "pour", "pas", "vous", "qui", "je"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "ein", "eine", "zu", "mit", "sie", "was", "wie", "auf"},
+	"pt": {"o", "os", "as", "de", "que", "e", "é", "não", "um", "uma", "para", "com", "você", "do", "da"},
+	"it": {"il", "lo", "gli", "di", "che", "e", "è", "non", "un", "una", "per", "con", "sono", "del", "della"},
+	"id": {"yang", "dan", "di", "ini", "itu", "dengan", "untuk", "tidak", "ada", "saya", "apa", "dari", "akan", "kamu", "ke"},
+	"vi": {"và", "của", "là", "có", "không", "những", "được", "cho", "với", "này", "một", "tôi", "bạn", "các", "người"},
+	"tr": {"ve", "bir", "bu", "için", "ile", "da", "de", "ne", "çok", "mi", "ben", "sen", "var", "yok", "gibi"},
+}
+
+// IsSupportedLanguage reports whether code is a language souls can chat in.
+func IsSupportedLanguage(code string) bool {
+	_, ok := languageNames[code]
+	return ok
+}
+
+// LanguageName returns the English name of a supported language code, or the code itself.
+func LanguageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// DetectLanguage guesses the ISO 639-1 code of a text from its script, and
+// for Latin script from common short words. Returns "" when unsure.
+// It is a cheap heuristic meant for steering prompts, not a classifier.
+func DetectLanguage(text string) string {
+	var han, kana, hangul, cyrillic, arabic, devanagari, thai, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Devanagari, r):
+			devanagari++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	// A CJK character carries about as much as a Latin word, so it outweighs
+	// Latin letters 1:5 (mixed messages like "你好 bitcoin ETF" count as Chinese)
+	switch {
+	case kana > 0 && kana*5 >= latin:
+		return "ja"
+	case hangul > 0 && hangul*5 >= latin:
+		return "ko"
+	case han > 0 && han*5 >= latin:
+		return "zh"
+	case cyrillic > latin:
+		return "ru"
+	case arabic > latin:
+		return "ar"
+	case devanagari > latin:
+		return "hi"
+	case thai > latin:
+		return "th"
+	case latin == 0:
+		return ""
+	}
+	return detectLatinLanguage(text)
+}
+
+func detectLatinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	counts := make(map[string]int)
+	for _, w := range words {
+		for lang, stop := range latinStopwords {
+			for _, s := range stop {
+				if w == s {
+					counts[lang]++
+					break
+				}
+			}
+		}
+	}
+
+	best, bestCount, tie := "", 0, false
+	for _, lang := range []string{"en", "es", "fr", "de", "pt", "it", "id", "vi", "tr"} {
+		switch n := counts[lang]; {
+		case n > bestCount:
+			best, bestCount, tie = lang, n, false
+		case n == bestCount && n > 0:
+			tie = true
+		}
+	}
+	if bestCount == 0 || tie {
+		if len(words) > 0 && counts["en"] == bestCount {
+			return "en" // ASCII chat with no clear signal is almost always English
+		}
+		return ""
+	}
+	return best
+}
+
+// languageGuidance tells the soul which language to answer in. The soul keeps
+// its own voice and views; only the language follows the user.
+func languageGuidance(code string) string {
+	if code == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n=== LANGUAGE ===\nThe user is writing in %s. Reply in %s, keeping your own voice and views, "+
+		"unless the user asks for another language.\n", LanguageName(code), LanguageName(code))
+}
+
+// primaryLanguage returns the language most of the tweets are written in,
+// preferring the platform's own tag and falling back to DetectLanguage.
+func primaryLanguage(tweets []TwitterTweet) string {
+	counts := make(map[string]int)
+	for _, t := range tweets {
+		lang := t.Lang
+		if !IsSupportedLanguage(lang) {
+			lang = DetectLanguage(t.Text)
+		}
+		if lang != "" {
+			counts[lang]++
+		}
+	}
+	best, bestCount := "", 0
+	for lang, n := range counts {
+		if n > bestCount || n == bestCount && lang < best {
+			best, bestCount = lang, n
+		}
+	}
+	return best
+}
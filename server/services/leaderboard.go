@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// seasonBadgeRanks is how many top Claws of a finished season earn a badge.
+const seasonBadgeRanks = 3
+
+// IsLeaderboardPeriod reports whether p is a supported leaderboard period.
+func IsLeaderboardPeriod(p string) bool {
+	return p == models.LeaderboardAllTime || p == models.LeaderboardWeekly || p == models.LeaderboardMonthly
+}
+
+// seasonBounds returns the key and [start, end) range of the season containing t.
+func seasonBounds(period string, t time.Time) (string, time.Time, time.Time) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if period == models.LeaderboardWeekly {
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)) // back to Monday
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), start, start.AddDate(0, 0, 7)
+	}
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start.Format("2006-01"), start, start.AddDate(0, 1, 0)
+}
+
+// CurrentSeason returns the key of the season in progress for a period.
+func CurrentSeason(period string) string {
+	season, _, _ := seasonBounds(period, time.Now())
+	return season
+}
+
+// StartLeaderboardRollup periodically recomputes the current weekly and monthly
+// seasons, plus the previous ones so late curation results still count.
+func StartLeaderboardRollup(interval time.Duration) {
+	scheduleJob("leaderboard-rollup", "Roll up the current and previous weekly / monthly seasons", interval, true, refreshLeaderboardSeasons)
+	util.Log.Info("[leaderboard] Season rollup started (every %v)", interval)
+}
+
+func refreshLeaderboardSeasons() error {
+	now := time.Now()
+	var errs []error
+	for _, period := range []string{models.LeaderboardWeekly, models.LeaderboardMonthly} {
+		_, start, _ := seasonBounds(period, now)
+		for _, t := range []time.Time{start.Add(-time.Second), now} {
+			season, from, to := seasonBounds(period, t)
+			if err := rollupSeason(period, season, from, to); err != nil {
+				errs = append(errs, fmt.Errorf("%s season %s: %w", period, season, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rollupSeason replaces a season's rows with fresh counts of fragments submitted
+// in [from, to) by claimed Claws, ranked by accepted then submitted fragments.
+func rollupSeason(period, season string, from, to time.Time) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("period = ? AND season = ?", period, season).
+			Delete(&models.ClawSeasonStat{}).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			INSERT INTO claw_season_stats (id, period, season, claw_id, submitted, accepted, rank, updated_at)
+			SELECT gen_random_uuid(), ?, ?, f.claw_id, COUNT(*),
+				SUM(CASE WHEN f.status = ? THEN 1 ELSE 0 END),
+				ROW_NUMBER() OVER (ORDER BY SUM(CASE WHEN f.status = ? THEN 1 ELSE 0 END) DESC, COUNT(*) DESC, MIN(f.created_at) ASC),
+				NOW()
+			FROM fragments f
+			JOIN claws c ON c.id = f.claw_id AND c.deleted_at IS NULL AND c.status = ?
+			WHERE f.deleted_at IS NULL AND f.created_at >= ? AND f.created_at < ?
+			GROUP BY f.claw_id`,
+			period, season, models.FragStatusAccepted, models.FragStatusAccepted,
+			models.ClawStatusClaimed, from, to).Error
+	})
+}
+
+// getSeasonLeaderboard ranks Claws by their contributions in the current season.
+func getSeasonLeaderboard(period string, page, limit int, activeOnly bool) (map[string]interface{}, error) {
+	season := CurrentSeason(period)
+	offset := (page - 1) * limit
+
+	db := database.ReadDB()
+	query := db.Model(&models.ClawSeasonStat{}).
+		Joins("JOIN claws ON claws.id = claw_season_stats.claw_id AND claws.deleted_at IS NULL").
+		Where("claw_season_stats.period = ? AND claw_season_stats.season = ?", period, season)
+	if activeOnly {
+		query = query.Where("claws.last_seen_at >= ?", time.Now().Add(-ClawActiveWindow()))
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var stats []models.ClawSeasonStat
+	if err := query.Select("claw_season_stats.*").
+		Order("claw_season_stats.rank ASC").
+		Offset(offset).Limit(limit).
+		Find(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(stats))
+	for i, s := range stats {
+		ids[i] = s.ClawID
+	}
+	var claws []models.Claw
+	if len(ids) > 0 {
+		db.Where("id IN ?", ids).Find(&claws)
+	}
+	byID := make(map[uuid.UUID]*models.Claw, len(claws))
+	for i := range claws {
+		byID[claws[i].ID] = &claws[i]
+	}
+
+	ranked := make([]clawRank, 0, len(stats))
+	var refreshedAt *time.Time
+	for i, s := range stats {
+		c, ok := byID[s.ClawID]
+		if !ok {
+			continue
+		}
+		ranked = append(ranked, newClawRank(offset+i+1, c, s.Submitted, s.Accepted))
+		if refreshedAt == nil || s.UpdatedAt.Before(*refreshedAt) {
+			updated := s.UpdatedAt
+			refreshedAt = &updated
+		}
+	}
+	attachClawBadges(ranked)
+
+	return map[string]interface{}{
+		"claws":        ranked,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+		"period":       period,
+		"season":       season,
+		"refreshed_at": refreshedAt,
+	}, nil
+}
+
+// GetClawSeasonBadges returns the finished seasons in which a Claw placed in the top ranks.
+func GetClawSeasonBadges(clawID uuid.UUID) []models.ClawSeasonStat {
+	var badges []models.ClawSeasonStat
+	database.DB.Where("claw_id = ? AND rank <= ? AND accepted > 0", clawID, seasonBadgeRanks).
+		Where("NOT ((period = ? AND season = ?) OR (period = ? AND season = ?))",
+			models.LeaderboardWeekly, CurrentSeason(models.LeaderboardWeekly),
+			models.LeaderboardMonthly, CurrentSeason(models.LeaderboardMonthly)).
+		Order("period ASC, season DESC").
+		Limit(100).
+		Find(&badges)
+	return badges
+}
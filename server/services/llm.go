@@ -3,11 +3,14 @@ package services
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/util"
@@ -28,6 +31,13 @@ type ChatRequest struct {
 	MaxTokens   int           `json:"max_tokens,omitempty"`
 	Temperature float64       `json:"temperature,omitempty"`
 	Stream      bool          `json:"stream,omitempty"`
+
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+// streamOptions asks the API to append a final usage chunk to streams.
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // ChatChoice is a single choice in the response.
@@ -36,15 +46,18 @@ type ChatChoice struct {
 	Message ChatMessage `json:"message"`
 }
 
+// ChatUsage is the token usage reported by the API.
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
 // ChatResponse is the full non-streaming response from the API.
 type ChatResponse struct {
 	ID      string       `json:"id"`
 	Choices []ChatChoice `json:"choices"`
-	Usage   struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage   ChatUsage    `json:"usage"`
 }
 
 // StreamDelta is the delta object in a streaming response chunk.
@@ -62,6 +75,7 @@ type StreamChoice struct {
 type StreamChunk struct {
 	ID      string         `json:"id"`
 	Choices []StreamChoice `json:"choices"`
+	Usage   *ChatUsage     `json:"usage,omitempty"` // only on the final chunk
 }
 
 // llmBaseURL returns the API base URL for the configured LLM provider.
@@ -78,45 +92,98 @@ func llmBaseURL() string {
 	}
 }
 
+// llmTimeout bounds a call with the configured number of seconds (0 = no limit
+// beyond ctx itself).
+func llmTimeout(ctx context.Context, seconds int) (context.Context, context.CancelFunc) {
+	if seconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+}
+
 // CallLLM sends a non-streaming chat completion request and returns the assistant's reply.
-func CallLLM(messages []ChatMessage, maxTokens int, temperature float64) (string, error) {
+// The call is bounded by ctx and LLM_TIMEOUT_SECONDS.
+// Token usage is recorded against the tag's feature, shell and claw.
+func CallLLM(ctx context.Context, tag LLMCallTag, messages []ChatMessage, maxTokens int, temperature float64) (string, error) {
 	cfg := config.Cfg
+	if mockLLM() {
+		return mockCompletion(tag, messages), nil
+	}
 	if cfg.LLMAPIKey == "" {
 		return "", fmt.Errorf("LLM_API_KEY not configured")
 	}
+	if err := checkLLMBudget(tag); err != nil {
+		return "", err
+	}
 
 	provider := strings.ToLower(cfg.LLMProvider)
+	ctx, cancel := llmTimeout(ctx, cfg.LLMTimeoutSeconds)
+	defer cancel()
 
+	var reply string
+	var usage llmTokens
+	var err error
 	if provider == "claude" || provider == "anthropic" {
-		return callClaude(messages, maxTokens, temperature)
+		reply, usage, err = callClaude(ctx, tag.model(), messages, maxTokens, temperature)
+	} else {
+		reply, usage, err = callOpenAI(ctx, tag.model(), messages, maxTokens, temperature, false)
+	}
+	if err != nil {
+		recordLLMError(tag)
+		return "", err
 	}
 
-	return callOpenAI(messages, maxTokens, temperature, false)
+	recordLLMUsage(tag, usage)
+	return reply, nil
 }
 
 // StreamLLM sends a streaming chat completion request and calls onChunk for each token.
-func StreamLLM(messages []ChatMessage, maxTokens int, temperature float64, onChunk func(content string)) error {
+// The upstream request is cancelled when ctx is (e.g. the SSE client disconnects) or
+// after LLM_STREAM_TIMEOUT_SECONDS; chunks already passed to onChunk stay delivered and
+// the returned error wraps context.Canceled or context.DeadlineExceeded.
+// Token usage is recorded against the tag's feature, shell and claw.
+func StreamLLM(ctx context.Context, tag LLMCallTag, messages []ChatMessage, maxTokens int, temperature float64, onChunk func(content string)) error {
 	cfg := config.Cfg
+	if mockLLM() {
+		return mockStream(ctx, tag, messages, onChunk)
+	}
 	if cfg.LLMAPIKey == "" {
 		return fmt.Errorf("LLM_API_KEY not configured")
 	}
+	if err := checkLLMBudget(tag); err != nil {
+		return err
+	}
 
 	provider := strings.ToLower(cfg.LLMProvider)
+	ctx, cancel := llmTimeout(ctx, cfg.LLMStreamTimeoutSeconds)
+	defer cancel()
 
+	var usage llmTokens
+	var err error
 	if provider == "claude" || provider == "anthropic" {
-		return streamClaude(messages, maxTokens, temperature, onChunk)
+		usage, err = streamClaude(ctx, tag.model(), messages, maxTokens, temperature, onChunk)
+	} else {
+		usage, err = streamOpenAI(ctx, tag.model(), messages, maxTokens, temperature, onChunk)
 	}
 
-	return streamOpenAI(messages, maxTokens, temperature, onChunk)
+	// Partial streams still cost tokens
+	if usage.prompt > 0 || usage.completion > 0 {
+		recordLLMUsage(tag, usage)
+	}
+	// A client hanging up is not a provider failure
+	if err != nil && !errors.Is(err, context.Canceled) {
+		recordLLMError(tag)
+	}
+	return err
 }
 
 // --- OpenAI implementation ---
 
-func callOpenAI(messages []ChatMessage, maxTokens int, temperature float64, _ bool) (string, error) {
+func callOpenAI(ctx context.Context, model string, messages []ChatMessage, maxTokens int, temperature float64, _ bool) (string, llmTokens, error) {
 	cfg := config.Cfg
 
 	reqBody := ChatRequest{
-		Model:       cfg.LLMModel,
+		Model:       model,
 		Messages:    messages,
 		MaxTokens:   maxTokens,
 		Temperature: temperature,
@@ -126,71 +193,80 @@ func callOpenAI(messages []ChatMessage, maxTokens int, temperature float64, _ bo
 	body, _ := json.Marshal(reqBody)
 	url := llmBaseURL() + "/chat/completions"
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return "", llmTokens{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+cfg.LLMAPIKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := llmHTTP.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("LLM API request failed: %w", err)
+		return "", llmTokens{}, fmt.Errorf("LLM API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("LLM API error (status %d): %s", resp.StatusCode, string(respBody))
+		return "", llmTokens{}, fmt.Errorf("LLM API error (status %d): %s", resp.StatusCode, string(respBody))
 	}
 
 	var chatResp ChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return "", fmt.Errorf("failed to decode LLM response: %w", err)
+		return "", llmTokens{}, fmt.Errorf("failed to decode LLM response: %w", err)
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("LLM returned no choices")
+		return "", llmTokens{}, fmt.Errorf("LLM returned no choices")
 	}
 
 	util.Log.Debug("[llm] Tokens used: prompt=%d, completion=%d, total=%d",
 		chatResp.Usage.PromptTokens, chatResp.Usage.CompletionTokens, chatResp.Usage.TotalTokens)
 
-	return chatResp.Choices[0].Message.Content, nil
+	reply := chatResp.Choices[0].Message.Content
+	usage := llmTokens{prompt: chatResp.Usage.PromptTokens, completion: chatResp.Usage.CompletionTokens}
+	if usage.prompt == 0 && usage.completion == 0 {
+		usage = estimateLLMTokens(messages, reply)
+	}
+	return reply, usage, nil
 }
 
-func streamOpenAI(messages []ChatMessage, maxTokens int, temperature float64, onChunk func(string)) error {
+func streamOpenAI(ctx context.Context, model string, messages []ChatMessage, maxTokens int, temperature float64, onChunk func(string)) (llmTokens, error) {
 	cfg := config.Cfg
 
 	reqBody := ChatRequest{
-		Model:       cfg.LLMModel,
+		Model:       model,
 		Messages:    messages,
 		MaxTokens:   maxTokens,
 		Temperature: temperature,
 		Stream:      true,
+
+		StreamOptions: &streamOptions{IncludeUsage: true},
 	}
 
 	body, _ := json.Marshal(reqBody)
 	url := llmBaseURL() + "/chat/completions"
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return llmTokens{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+cfg.LLMAPIKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := llmHTTP.Do(req)
 	if err != nil {
-		return fmt.Errorf("LLM streaming request failed: %w", err)
+		return llmTokens{}, fmt.Errorf("LLM streaming request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("LLM API error (status %d): %s", resp.StatusCode, string(respBody))
+		return llmTokens{}, fmt.Errorf("LLM API error (status %d): %s", resp.StatusCode, string(respBody))
 	}
 
+	var reported *ChatUsage
+	var reply strings.Builder
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -206,14 +282,22 @@ func streamOpenAI(messages []ChatMessage, maxTokens int, temperature float64, on
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue // skip malformed chunks
 		}
+		if chunk.Usage != nil {
+			reported = chunk.Usage
+		}
 		for _, choice := range chunk.Choices {
 			if choice.Delta.Content != "" {
+				reply.WriteString(choice.Delta.Content)
 				onChunk(choice.Delta.Content)
 			}
 		}
 	}
 
-	return scanner.Err()
+	// Not every OpenAI-compatible provider honours stream_options
+	if reported != nil {
+		return llmTokens{prompt: reported.PromptTokens, completion: reported.CompletionTokens}, streamErr(ctx, scanner)
+	}
+	return estimateLLMTokens(messages, reply.String()), streamErr(ctx, scanner)
 }
 
 // --- Anthropic Claude implementation ---
@@ -240,7 +324,7 @@ type claudeResponse struct {
 	} `json:"usage"`
 }
 
-func callClaude(messages []ChatMessage, maxTokens int, temperature float64) (string, error) {
+func callClaude(ctx context.Context, model string, messages []ChatMessage, maxTokens int, temperature float64) (string, llmTokens, error) {
 	cfg := config.Cfg
 
 	// Extract system message
@@ -259,7 +343,7 @@ func callClaude(messages []ChatMessage, maxTokens int, temperature float64) (str
 	}
 
 	reqBody := claudeRequest{
-		Model:       cfg.LLMModel,
+		Model:       model,
 		MaxTokens:   maxTokens,
 		System:      system,
 		Messages:    userMessages,
@@ -269,41 +353,46 @@ func callClaude(messages []ChatMessage, maxTokens int, temperature float64) (str
 
 	body, _ := json.Marshal(reqBody)
 
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return "", llmTokens{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", cfg.LLMAPIKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := llmHTTP.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("Claude API request failed: %w", err)
+		return "", llmTokens{}, fmt.Errorf("Claude API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Claude API error (status %d): %s", resp.StatusCode, string(respBody))
+		return "", llmTokens{}, fmt.Errorf("Claude API error (status %d): %s", resp.StatusCode, string(respBody))
 	}
 
 	var claudeResp claudeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&claudeResp); err != nil {
-		return "", fmt.Errorf("failed to decode Claude response: %w", err)
+		return "", llmTokens{}, fmt.Errorf("failed to decode Claude response: %w", err)
 	}
 
 	if len(claudeResp.Content) == 0 {
-		return "", fmt.Errorf("Claude returned no content")
+		return "", llmTokens{}, fmt.Errorf("Claude returned no content")
 	}
 
 	util.Log.Debug("[llm] Claude tokens: input=%d, output=%d",
 		claudeResp.Usage.InputTokens, claudeResp.Usage.OutputTokens)
 
-	return claudeResp.Content[0].Text, nil
+	reply := claudeResp.Content[0].Text
+	usage := llmTokens{prompt: claudeResp.Usage.InputTokens, completion: claudeResp.Usage.OutputTokens}
+	if usage.prompt == 0 && usage.completion == 0 {
+		usage = estimateLLMTokens(messages, reply)
+	}
+	return reply, usage, nil
 }
 
-func streamClaude(messages []ChatMessage, maxTokens int, temperature float64, onChunk func(string)) error {
+func streamClaude(ctx context.Context, model string, messages []ChatMessage, maxTokens int, temperature float64, onChunk func(string)) (llmTokens, error) {
 	cfg := config.Cfg
 
 	// Extract system message
@@ -322,7 +411,7 @@ func streamClaude(messages []ChatMessage, maxTokens int, temperature float64, on
 	}
 
 	reqBody := claudeRequest{
-		Model:       cfg.LLMModel,
+		Model:       model,
 		MaxTokens:   maxTokens,
 		System:      system,
 		Messages:    userMessages,
@@ -332,25 +421,27 @@ func streamClaude(messages []ChatMessage, maxTokens int, temperature float64, on
 
 	body, _ := json.Marshal(reqBody)
 
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(body))
 	if err != nil {
-		return err
+		return llmTokens{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", cfg.LLMAPIKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := llmHTTP.Do(req)
 	if err != nil {
-		return fmt.Errorf("Claude streaming request failed: %w", err)
+		return llmTokens{}, fmt.Errorf("Claude streaming request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("Claude API error (status %d): %s", resp.StatusCode, string(respBody))
+		return llmTokens{}, fmt.Errorf("Claude API error (status %d): %s", resp.StatusCode, string(respBody))
 	}
 
+	var usage llmTokens
+	var reply strings.Builder
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -364,24 +455,53 @@ func streamClaude(messages []ChatMessage, maxTokens int, temperature float64, on
 			continue
 		}
 
-		// Claude sends content_block_delta events with text
+		// Claude sends content_block_delta events with text; usage arrives in
+		// message_start (input tokens) and message_delta (output tokens)
 		eventType, _ := event["type"].(string)
-		if eventType == "content_block_delta" {
+		switch eventType {
+		case "content_block_delta":
 			if delta, ok := event["delta"].(map[string]interface{}); ok {
 				if text, ok := delta["text"].(string); ok && text != "" {
+					reply.WriteString(text)
 					onChunk(text)
 				}
 			}
+		case "message_start":
+			if msg, ok := event["message"].(map[string]interface{}); ok {
+				if u, ok := msg["usage"].(map[string]interface{}); ok {
+					if n, ok := u["input_tokens"].(float64); ok {
+						usage.prompt = int(n)
+					}
+				}
+			}
+		case "message_delta":
+			if u, ok := event["usage"].(map[string]interface{}); ok {
+				if n, ok := u["output_tokens"].(float64); ok {
+					usage.completion = int(n)
+				}
+			}
 		}
 	}
 
+	if usage.prompt == 0 && usage.completion == 0 {
+		usage = estimateLLMTokens(messages, reply.String())
+	}
+	return usage, streamErr(ctx, scanner)
+}
+
+// streamErr reports why a stream stopped early: a cancelled or expired ctx takes
+// precedence over the read error it caused, so callers can tell them apart.
+func streamErr(ctx context.Context, scanner *bufio.Scanner) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("LLM stream interrupted: %w", err)
+	}
 	return scanner.Err()
 }
 
 // CallLLMJSON is a convenience function that calls the LLM and parses JSON from the response.
 // It strips markdown code fences if present.
-func CallLLMJSON(messages []ChatMessage, maxTokens int, temperature float64, result interface{}) error {
-	raw, err := CallLLM(messages, maxTokens, temperature)
+func CallLLMJSON(ctx context.Context, tag LLMCallTag, messages []ChatMessage, maxTokens int, temperature float64, result interface{}) error {
+	raw, err := CallLLM(ctx, tag, messages, maxTokens, temperature)
 	if err != nil {
 		return err
 	}
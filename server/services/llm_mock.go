@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/models"
+)
+
+// --- Mock provider (LLM_PROVIDER=mock) ---
+//
+// The mock answers every LLM call locally with canned output in the shape the
+// caller's prompt asks for, so the whole pipeline (seed, curator, ensouling,
+// moderation, chat) runs without an API key. It answers from the call's
+// MockInput, never from the prompt text, so rewording a prompt can't break
+// it. Output depends only on LLM_MOCK_SEED, the feature and the messages: the
+// same request always gets the same answer. Mock calls are free, so they skip
+// the budget and aren't recorded in llm_usage.
+
+const llmProviderMock = "mock"
+
+// mockLLM reports whether LLM_PROVIDER=mock.
+func mockLLM() bool {
+	return strings.EqualFold(config.Cfg.LLMProvider, llmProviderMock)
+}
+
+// LLMConfigured reports whether LLM calls can be made: an API key is set or
+// the mock provider is selected.
+func LLMConfigured() bool {
+	return config.Cfg.LLMAPIKey != "" || mockLLM()
+}
+
+// MockInput is the structured input of an LLM call, set by the caller on
+// LLMCallTag.Mock next to the prompt it builds from the same data.
+type MockInput struct {
+	Handle     string            // the soul the call is about
+	Fragments  []string          // curator, appeal: contents under review, in prompt order
+	Batch      bool              // curator: a batch review, answered with one verdict per fragment
+	Scores     map[string][2]int // ensouling: current score and new fragment count per dimension
+	Dimension  string            // ensouling: the one dimension of a block rewrite
+	Dimensions []string          // seed candidates, research: dimensions to write fragments for
+	Count      int               // interview questions; most seed candidates
+	Topics     []string          // coverage: keywords of each cluster, in order
+	Text       string            // moderation, prompt scan: the text screened
+}
+
+// mockInjectionMarkers make the mock curator reject a fragment, so the
+// rejection paths can be exercised on purpose.
+var mockInjectionMarkers = []string{"ignore previous instructions", "ignore all previous", "[mock:reject]"}
+
+// mockFlagMarker makes mock moderation and prompt scans flag a text.
+const mockFlagMarker = "[mock:flag]"
+
+// mockRand returns the random source of one call, seeded by LLM_MOCK_SEED and
+// the call's content.
+func mockRand(feature string, messages []ChatMessage) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	for _, m := range messages {
+		h.Write([]byte{0})
+		h.Write([]byte(m.Role))
+		h.Write([]byte(m.Content))
+	}
+	return rand.New(rand.NewPCG(uint64(config.Cfg.LLMMockSeed), h.Sum64()))
+}
+
+// mockCompletion answers a non-streaming call.
+func mockCompletion(tag LLMCallTag, messages []ChatMessage) string {
+	in := tag.Mock
+	if in == nil {
+		in = &MockInput{}
+	}
+	handle := in.Handle
+	if handle == "" {
+		handle = "someone"
+	}
+	rng := mockRand(tag.Feature, messages)
+
+	var out interface{}
+	switch tag.Feature {
+	case models.LLMFeatureSeed:
+		if in.Count > 0 {
+			out = map[string]interface{}{"fragments": mockFragments(handle, dimensionOrder[:min(in.Count, 2)], rng)}
+		} else {
+			out = mockSeed(handle, rng)
+		}
+	case models.LLMFeatureCurator:
+		if in.Batch {
+			verdicts := make([]map[string]interface{}, len(in.Fragments))
+			for i, content := range in.Fragments {
+				verdicts[i] = mockVerdict(content, rng)
+				verdicts[i]["index"] = i + 1
+			}
+			out = verdicts
+		} else {
+			out = mockVerdict(strings.Join(in.Fragments, "\n"), rng)
+		}
+	case models.LLMFeatureAppeal:
+		v := mockVerdict(strings.Join(in.Fragments, "\n"), rng)
+		v["frivolous"] = false
+		out = v
+	case models.LLMFeatureEnsouling:
+		if in.Dimension != "" {
+			out = mockDimensionBlock(in.Dimension, in.Scores[in.Dimension], handle, rng)
+		} else {
+			out = mockEnsouling(in.Scores, handle, rng)
+		}
+	case models.LLMFeatureModeration, models.LLMFeaturePromptScan:
+		out = mockModeration(in.Text)
+	case models.LLMFeatureInterview:
+		out = mockInterview(in.Count, handle, rng)
+	case models.LLMFeatureCoverage:
+		out = mockCoverage(in.Topics, handle, rng)
+	case models.LLMFeatureChatSummary:
+		return fmt.Sprintf("The user has been talking with @%s about %s. (mock summary)",
+			handle, mockPick(rng, "their work", "recent events", "their views", "how they got started"))
+	case "research": // cmd/claw_agent
+		out = map[string]interface{}{"fragments": mockFragments(handle, in.Dimensions, rng)}
+	default:
+		return mockChatReply(messages, handle, rng)
+	}
+	raw, _ := json.Marshal(out)
+	return string(raw)
+}
+
+// mockStream streams the chat reply word by word, LLM_MOCK_STREAM_DELAY_MS apart.
+func mockStream(ctx context.Context, tag LLMCallTag, messages []ChatMessage, onChunk func(string)) error {
+	reply := mockCompletion(tag, messages)
+	delay := time.Duration(max(config.Cfg.LLMMockStreamDelayMs, 0)) * time.Millisecond
+	words := strings.SplitAfter(reply, " ")
+	for _, w := range words {
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return fmt.Errorf("LLM stream interrupted: %w", ctx.Err())
+			}
+		}
+		onChunk(w)
+	}
+	return nil
+}
+
+func mockPick(rng *rand.Rand, options ...string) string {
+	return options[rng.IntN(len(options))]
+}
+
+// mockVerdict accepts about four fragments in five at 0.60-0.95 confidence,
+// and always rejects ones carrying an injection marker.
+func mockVerdict(content string, rng *rand.Rand) map[string]interface{} {
+	lower := strings.ToLower(content)
+	for _, marker := range mockInjectionMarkers {
+		if strings.Contains(lower, marker) {
+			return map[string]interface{}{"accept": false, "confidence": 0.95, "reason": "Contains embedded instructions (mock curator)"}
+		}
+	}
+	confidence := float64(60+rng.IntN(36)) / 100
+	if rng.IntN(5) == 0 {
+		return map[string]interface{}{"accept": false, "confidence": confidence, "reason": "Too generic to add to the soul (mock curator)"}
+	}
+	return map[string]interface{}{"accept": true, "confidence": confidence, "reason": "Specific and relevant (mock curator)"}
+}
+
+// mockModeration flags texts carrying mockFlagMarker and passes the rest.
+func mockModeration(text string) map[string]interface{} {
+	if strings.Contains(strings.ToLower(text), mockFlagMarker) {
+		return map[string]interface{}{"flagged": true, "categories": []string{"mock"}, "reason": "Contains " + mockFlagMarker + " (mock moderation)"}
+	}
+	return map[string]interface{}{"flagged": false, "categories": []string{}, "reason": ""}
+}
+
+func mockSeed(handle string, rng *rand.Rand) map[string]interface{} {
+	dims := make(map[string]models.DimensionData, len(dimensionOrder))
+	for _, dim := range dimensionOrder {
+		dims[dim] = models.DimensionData{
+			Score:   5 + rng.IntN(21),
+			Summary: fmt.Sprintf("Mock %s profile of @%s, generated without a model.", dim, handle),
+		}
+	}
+	return map[string]interface{}{
+		"seed_summary": fmt.Sprintf("@%s is a public figure known for %s. This seed was generated by the mock LLM provider.",
+			handle, mockPick(rng, "building products", "sharp commentary", "long threads", "community work")),
+		"dimensions": dims,
+	}
+}
+
+func mockFragments(handle string, dims []string, rng *rand.Rand) []map[string]string {
+	out := make([]map[string]string, 0, len(dims))
+	for _, dim := range dims {
+		out = append(out, map[string]string{
+			"dimension": dim,
+			"content": fmt.Sprintf("Mock %s observation about @%s: %s, consistently across recent posts. "+
+				"It is one of the clearer patterns in how they present themselves in public. "+
+				"Generated by the mock LLM provider for local development.",
+				dim, handle, mockPick(rng, "returns to the same few themes", "answers critics directly",
+					"mixes humour with technical detail", "credits collaborators by name")),
+		})
+	}
+	return out
+}
+
+// mockScore raises a score by three points per new fragment, within the
+// 15-point cap the ensouling prompt sets.
+func mockScore(current, added int) int {
+	return min(current+min(3*added, 15), 100)
+}
+
+func mockEnsouling(scores map[string][2]int, handle string, rng *rand.Rand) EnsoulingResult {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "You are the digital soul of @%s. This profile was condensed by the mock LLM provider.\n", handle)
+	dims := make(map[string]models.DimensionData, len(dimensionOrder))
+	for _, dim := range dimensionOrder {
+		s := scores[dim]
+		fmt.Fprintf(&sb, "\n[%s]\nYou %s. (mock, %d new fragments)\n", dim,
+			mockPick(rng, "speak plainly", "think in long arcs", "hold firm views", "stay curious"), s[1])
+		dims[dim] = models.DimensionData{Score: mockScore(s[0], s[1]), Summary: fmt.Sprintf("Mock %s summary.", dim)}
+	}
+	return EnsoulingResult{
+		NewPrompt:   sb.String(),
+		Dimensions:  dims,
+		SummaryDiff: "Merged new fragments (mock ensouling).",
+		Essence: fmt.Sprintf("@%s is a public figure who %s. This essence was written by the mock LLM provider.",
+			handle, mockPick(rng, "builds in public", "writes about technology", "shares strong opinions", "mentors newcomers")),
+	}
+}
+
+func mockDimensionBlock(dim string, s [2]int, handle string, rng *rand.Rand) map[string]interface{} {
+	return map[string]interface{}{
+		"block": fmt.Sprintf("You %s. (mock %s block for @%s, %d new fragments)",
+			mockPick(rng, "speak plainly", "think in long arcs", "hold firm views", "stay curious"), dim, handle, s[1]),
+		"score":        mockScore(s[0], s[1]),
+		"summary":      fmt.Sprintf("Mock %s summary.", dim),
+		"summary_diff": fmt.Sprintf("Rewrote the %s block (mock ensouling).", dim),
+	}
+}
+
+func mockInterview(n int, handle string, rng *rand.Rand) map[string]interface{} {
+	if n <= 0 {
+		n = len(dimensionOrder)
+	}
+	pairs := make([]map[string]interface{}, n)
+	for i := range pairs {
+		dim := dimensionOrder[i%len(dimensionOrder)]
+		pairs[i] = map[string]interface{}{
+			"dimension": dim,
+			"question":  fmt.Sprintf("What should people know about your %s?", dim),
+			"answer":    fmt.Sprintf("I'm @%s, and honestly %s. (mock answer)", handle, mockPick(rng, "it depends", "I keep it simple", "ask me again tomorrow")),
+			"grounded":  rng.IntN(2) == 0,
+		}
+	}
+	return map[string]interface{}{
+		"pairs": pairs,
+		"gaps":  []map[string]string{{"dimension": "timeline", "topic": "early career (mock gap)"}},
+	}
+}
+
+// mockCoverage names each cluster after its keywords and lists one gap.
+func mockCoverage(keywords []string, handle string, rng *rand.Rand) map[string]interface{} {
+	topics := make([]string, len(keywords))
+	for i, k := range keywords {
+		topics[i] = "Mock topic: " + k
+	}
+	return map[string]interface{}{
+		"topics": topics,
+		"gaps": []map[string]string{{
+			"topic":  mockPick(rng, "early career", "recent projects", "views on regulation", "collaborators"),
+			"reason": fmt.Sprintf("No fragment about @%s covers it (mock gap).", handle),
+		}},
+	}
+}
+
+// mockChatReply is the scripted persona reply: it names the soul and echoes
+// the start of the user's last message.
+func mockChatReply(messages []ChatMessage, handle string, rng *rand.Rand) string {
+	var last string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			last = messages[i].Content
+			break
+		}
+	}
+	return fmt.Sprintf("This is @%s (mock LLM). You said: \"%s\". %s",
+		handle, feedExcerpt(last, 120), mockPick(rng,
+			"I'd put it differently, but I see where you're coming from.",
+			"Good question. Short answer: it depends on the timeframe.",
+			"I've said as much before, and I still stand by it.",
+			"Let me think about that one and come back to it."))
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// LLMCallTag attributes an LLM call to a feature and, optionally, a shell,
+// claw and developer key.
+type LLMCallTag struct {
+	Feature        string // see models.LLMFeature* constants
+	ShellID        *uuid.UUID
+	ClawID         *uuid.UUID
+	DeveloperKeyID *uuid.UUID
+	Model          string     // overrides LLM_MODEL for this call when set
+	Mock           *MockInput // what LLM_PROVIDER=mock answers from; other providers ignore it
+}
+
+// model returns the model to call for this tag.
+func (t LLMCallTag) model() string {
+	if t.Model != "" {
+		return t.Model
+	}
+	return config.Cfg.LLMModel
+}
+
+// llmTokens is the token usage of a single call.
+type llmTokens struct {
+	prompt     int
+	completion int
+	estimated  bool
+}
+
+// estimateLLMTokens approximates usage (~4 characters per token) when the
+// provider doesn't report it.
+func estimateLLMTokens(messages []ChatMessage, reply string) llmTokens {
+	chars := 0
+	for _, m := range messages {
+		chars += len(m.Content)
+	}
+	return llmTokens{prompt: chars / 4, completion: len(reply) / 4, estimated: true}
+}
+
+// ChatModelOption is a model a soul's chat may use, with its USD price per
+// 1M tokens.
+type ChatModelOption struct {
+	Model       string  `json:"model"`
+	InputPer1M  float64 `json:"input_per_1m"`
+	OutputPer1M float64 `json:"output_per_1m"`
+	IsDefault   bool    `json:"default"`
+}
+
+// chatModelPriceSep separates a LLM_CHAT_MODELS entry's model from its prices.
+// Model IDs may contain ":" (llama3:70b) or "/", but never "|".
+const chatModelPriceSep = "|"
+
+// ChatModels returns LLM_MODEL followed by the LLM_CHAT_MODELS allowlist.
+// Entries are "model" or "model|input_per_1m|output_per_1m"; models without
+// (valid) prices are billed at the LLM_PRICE_* defaults.
+func ChatModels() []ChatModelOption {
+	cfg := config.Cfg
+	options := []ChatModelOption{{
+		Model: cfg.LLMModel, InputPer1M: cfg.LLMPriceInputPer1M, OutputPer1M: cfg.LLMPriceOutputPer1M, IsDefault: true,
+	}}
+	for _, entry := range cfg.LLMChatModels {
+		parts := strings.Split(entry, chatModelPriceSep)
+		option := ChatModelOption{Model: strings.TrimSpace(parts[0]), InputPer1M: cfg.LLMPriceInputPer1M, OutputPer1M: cfg.LLMPriceOutputPer1M}
+		if len(parts) == 3 {
+			in, errIn := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			out, errOut := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+			if errIn == nil && errOut == nil && in >= 0 && out >= 0 {
+				option.InputPer1M, option.OutputPer1M = in, out
+			}
+		}
+		if option.Model == "" || option.Model == cfg.LLMModel {
+			continue
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// chatModelOption looks up a model in ChatModels.
+func chatModelOption(model string) (ChatModelOption, bool) {
+	for _, option := range ChatModels() {
+		if option.Model == model {
+			return option, true
+		}
+	}
+	return ChatModelOption{}, false
+}
+
+// ChatModelFor returns the model a soul's chat should call: its settings'
+// model while that is still allowlisted, else "" (LLM_MODEL).
+func ChatModelFor(settings models.ShellSettings) string {
+	if settings.ChatModel == "" {
+		return ""
+	}
+	if _, ok := chatModelOption(settings.ChatModel); !ok {
+		return ""
+	}
+	return settings.ChatModel
+}
+
+// llmCostUSD estimates the cost of a call from the model's per-token prices,
+// falling back to the LLM_PRICE_* defaults for models not in ChatModels.
+func llmCostUSD(model string, usage llmTokens) float64 {
+	input, output := config.Cfg.LLMPriceInputPer1M, config.Cfg.LLMPriceOutputPer1M
+	if option, ok := chatModelOption(model); ok {
+		input, output = option.InputPer1M, option.OutputPer1M
+	}
+	return float64(usage.prompt)*input/1e6 + float64(usage.completion)*output/1e6
+}
+
+// recordLLMUsage writes a usage row. A nil database (e.g. the standalone
+// claw agent) skips recording.
+func recordLLMUsage(tag LLMCallTag, usage llmTokens) {
+	if database.DB == nil {
+		return
+	}
+	feature := tag.Feature
+	if feature == "" {
+		feature = "other"
+	}
+	model := tag.model()
+	row := &models.LLMUsage{
+		Provider:         strings.ToLower(config.Cfg.LLMProvider),
+		Model:            model,
+		Feature:          feature,
+		ShellID:          tag.ShellID,
+		ClawID:           tag.ClawID,
+		DeveloperKeyID:   tag.DeveloperKeyID,
+		PromptTokens:     usage.prompt,
+		CompletionTokens: usage.completion,
+		TotalTokens:      usage.prompt + usage.completion,
+		CostUSD:          llmCostUSD(model, usage),
+		Estimated:        usage.estimated,
+	}
+	if err := database.DB.Create(row).Error; err != nil {
+		util.Log.Warn("[llm] Failed to record usage for %s: %v", feature, err)
+	}
+}
+
+// checkLLMBudget rejects chat calls for a shell that has spent LLM_SHELL_DAILY_BUDGET_USD
+// (across all features) in the last 24 hours. Curation and ensouling are not blocked:
+// their fallbacks would otherwise accept fragments unreviewed.
+func checkLLMBudget(tag LLMCallTag) error {
+	budget := config.Cfg.LLMShellDailyBudgetUSD
+	if budget <= 0 || (tag.Feature != models.LLMFeatureChat && tag.Feature != models.LLMFeatureTimeTravel && tag.Feature != models.LLMFeatureAPIChat) || tag.ShellID == nil || database.DB == nil {
+		return nil
+	}
+	var spent float64
+	database.DB.Model(&models.LLMUsage{}).
+		Select("COALESCE(SUM(cost_usd), 0)").
+		Where("shell_id = ? AND created_at > ?", *tag.ShellID, time.Now().Add(-24*time.Hour)).
+		Scan(&spent)
+	if spent >= budget {
+		util.Log.Warn("[llm] Daily budget exhausted for shell %s (%.4f / %.2f USD)", tag.ShellID, spent, budget)
+		return fmt.Errorf("daily LLM budget for this soul is exhausted, please try again later")
+	}
+	return nil
+}
+
+// LLMUsageTotals aggregates calls, tokens and cost.
+type LLMUsageTotals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// LLMUsageGroup is the totals for one group key (feature, model, day, shell or claw).
+type LLMUsageGroup struct {
+	Key   string `json:"key"`
+	Label string `json:"label,omitempty"` // shell handle or claw name
+	LLMUsageTotals
+}
+
+// LLMUsageReport is the admin view of LLM spend over a time window.
+type LLMUsageReport struct {
+	Days           int             `json:"days"`
+	Since          time.Time       `json:"since"`
+	Totals         LLMUsageTotals  `json:"totals"`
+	ByFeature      []LLMUsageGroup `json:"by_feature"`
+	ByModel        []LLMUsageGroup `json:"by_model"`
+	ByDay          []LLMUsageGroup `json:"by_day"`
+	TopShells      []LLMUsageGroup `json:"top_shells"`
+	TopClaws       []LLMUsageGroup `json:"top_claws"`
+	ShellBudgetUSD float64         `json:"shell_daily_budget_usd"`
+}
+
+// usageTotalsSelect is the aggregate column list shared by all report queries.
+const usageTotalsSelect = "COUNT(*) AS calls, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, " +
+	"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, COALESCE(SUM(total_tokens), 0) AS total_tokens, " +
+	"COALESCE(SUM(cost_usd), 0) AS cost_usd"
+
+// GetLLMUsageReport aggregates LLM usage over the last `days` days.
+func GetLLMUsageReport(days int) (*LLMUsageReport, error) {
+	since := time.Now().AddDate(0, 0, -days)
+	report := &LLMUsageReport{
+		Days:           days,
+		Since:          since,
+		ShellBudgetUSD: config.Cfg.LLMShellDailyBudgetUSD,
+	}
+
+	if err := database.DB.Model(&models.LLMUsage{}).
+		Select(usageTotalsSelect).
+		Where("created_at > ?", since).
+		Scan(&report.Totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate LLM usage: %w", err)
+	}
+
+	report.ByFeature = usageGroups("feature", since)
+	report.ByModel = usageGroups("model", since)
+	report.ByDay = usageGroups("TO_CHAR(created_at, 'YYYY-MM-DD')", since)
+
+	report.TopShells = make([]LLMUsageGroup, 0)
+	database.DB.Table("llm_usage").
+		Select("CAST(llm_usage.shell_id AS TEXT) AS key, shells.handle AS label, "+usageTotalsSelect).
+		Joins("LEFT JOIN shells ON shells.id = llm_usage.shell_id").
+		Where("llm_usage.created_at > ? AND llm_usage.shell_id IS NOT NULL", since).
+		Group("llm_usage.shell_id, shells.handle").
+		Order("cost_usd DESC").
+		Limit(10).Scan(&report.TopShells)
+
+	report.TopClaws = make([]LLMUsageGroup, 0)
+	database.DB.Table("llm_usage").
+		Select("CAST(llm_usage.claw_id AS TEXT) AS key, claws.name AS label, "+usageTotalsSelect).
+		Joins("LEFT JOIN claws ON claws.id = llm_usage.claw_id").
+		Where("llm_usage.created_at > ? AND llm_usage.claw_id IS NOT NULL", since).
+		Group("llm_usage.claw_id, claws.name").
+		Order("cost_usd DESC").
+		Limit(10).Scan(&report.TopClaws)
+
+	return report, nil
+}
+
+// usageGroups aggregates usage since a time, grouped by a column expression.
+func usageGroups(expr string, since time.Time) []LLMUsageGroup {
+	groups := make([]LLMUsageGroup, 0)
+	database.DB.Model(&models.LLMUsage{}).
+		Select(expr+" AS key, "+usageTotalsSelect).
+		Where("created_at > ?", since).
+		Group(expr).
+		Order("key").
+		Scan(&groups)
+	return groups
+}
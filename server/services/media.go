@@ -0,0 +1,392 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm/clause"
+)
+
+// Media kinds served by the media proxy
+const (
+	MediaKindAvatar = "avatar"
+	MediaKindBanner = "banner"
+)
+
+const (
+	mediaMaxDownload = 5 << 20 // bytes
+	mediaMaxPixels   = 25e6    // decoded width × height; bounds decode memory
+	avatarSize       = 400     // avatars are cropped to a square of this size
+	bannerMaxWidth   = 1500
+	bannerMaxHeight  = 500
+)
+
+var mediaHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// mediaFetches collapses concurrent fetches of the same shell image, so
+// first requests for it don't all hit the upstream while other images are
+// fetched in parallel.
+var mediaFetches singleflight.Group
+
+// mediaFetch is the result shared by the callers of one fetch.
+type mediaFetch struct {
+	data  []byte
+	asset *models.MediaAsset
+}
+
+// fetchShellMedia runs fn once per shell and kind at a time; concurrent
+// callers for the same image get its result.
+func fetchShellMedia(shell *models.Shell, kind string, fn func() ([]byte, *models.MediaAsset, error)) ([]byte, *models.MediaAsset, error) {
+	v, err, _ := mediaFetches.Do(shell.ID.String()+":"+kind, func() (interface{}, error) {
+		data, asset, err := fn()
+		return mediaFetch{data, asset}, err
+	})
+	f, _ := v.(mediaFetch)
+	return f.data, f.asset, err
+}
+
+// GetShellMedia returns a shell's cached avatar or banner, downloading and
+// caching it on first request.
+func GetShellMedia(shell *models.Shell, kind string) ([]byte, *models.MediaAsset, error) {
+	if kind != MediaKindAvatar && kind != MediaKindBanner {
+		return nil, nil, fmt.Errorf("unknown media kind %q", kind)
+	}
+
+	var asset models.MediaAsset
+	if database.DB.Where("shell_id = ? AND kind = ?", shell.ID, kind).First(&asset).Error == nil {
+		if data, err := getMediaStore().Get(asset.StorageKey); err == nil {
+			return data, &asset, nil
+		}
+		util.Log.Warn("[media] Cached %s for @%s missing from storage, re-fetching", kind, shell.Handle)
+	}
+
+	return fetchShellMedia(shell, kind, func() ([]byte, *models.MediaAsset, error) {
+		// A fetch that just finished may have cached it
+		var cached models.MediaAsset
+		if database.DB.Where("shell_id = ? AND kind = ?", shell.ID, kind).First(&cached).Error == nil {
+			if data, err := getMediaStore().Get(cached.StorageKey); err == nil {
+				return data, &cached, nil
+			}
+		}
+		return cacheShellMedia(shell, kind, nil)
+	})
+}
+
+// cacheShellMedia downloads, resizes and stores a shell image, falling back
+// to a generated image when the source is missing or broken. When refreshing
+// a previously downloaded image (existing), a failed download keeps the old copy.
+func cacheShellMedia(shell *models.Shell, kind string, existing *models.MediaAsset) ([]byte, *models.MediaAsset, error) {
+	source := mediaSourceURL(shell, kind)
+
+	var img image.Image
+	generated := false
+	if source != "" {
+		var err error
+		if img, err = downloadImage(source); err != nil {
+			util.Log.Debug("[media] Download of %s for @%s failed: %v", kind, shell.Handle, err)
+		}
+	}
+	if img == nil && existing != nil && !existing.Generated {
+		database.DB.Model(existing).Update("fetched_at", time.Now())
+		return nil, existing, nil
+	}
+	if img == nil {
+		generated = true
+		if kind == MediaKindAvatar {
+			img = generateAvatar(shell.Handle)
+		} else {
+			img = generateBanner(shell.Handle)
+		}
+	}
+
+	if kind == MediaKindAvatar {
+		img = resizeImage(cropSquare(img), avatarSize, avatarSize)
+	} else {
+		img = fitImage(img, bannerMaxWidth, bannerMaxHeight)
+	}
+
+	var buf bytes.Buffer
+	contentType, ext := "image/jpeg", "jpg"
+	if generated {
+		contentType, ext = "image/png", "png"
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, nil, err
+		}
+	} else if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, nil, err
+	}
+	data := buf.Bytes()
+
+	key := fmt.Sprintf("shells/%s/%s.%s", shell.ID, kind, ext)
+	if err := getMediaStore().Put(key, data, contentType); err != nil {
+		return nil, nil, fmt.Errorf("failed to store media: %w", err)
+	}
+
+	bounds := img.Bounds()
+	asset := models.MediaAsset{
+		ShellID:     shell.ID,
+		Kind:        kind,
+		SourceURL:   source,
+		StorageKey:  key,
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Size:        len(data),
+		ETag:        sha256Hex(data)[:32],
+		Generated:   generated,
+		FetchedAt:   time.Now(),
+	}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "shell_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"source_url", "storage_key", "content_type", "width", "height",
+			"size", "e_tag", "generated", "fetched_at", "updated_at",
+		}),
+	}).Create(&asset).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to save media record: %w", err)
+	}
+
+	util.Log.Debug("[media] Cached %s for @%s (%dx%d, %d bytes, generated=%v)",
+		kind, shell.Handle, asset.Width, asset.Height, asset.Size, generated)
+	return data, &asset, nil
+}
+
+// mediaSourceURL returns the upstream URL of a shell image, or "" if unknown.
+func mediaSourceURL(shell *models.Shell, kind string) string {
+	if kind == MediaKindAvatar {
+		return shell.AvatarURL
+	}
+	if shell.TwitterMeta != nil {
+		if u, ok := shell.TwitterMeta["banner_url"].(string); ok {
+			return u
+		}
+	}
+	return ""
+}
+
+// downloadImage fetches and decodes an image (JPEG, PNG or GIF).
+func downloadImage(url string) (image.Image, error) {
+	resp, err := mediaHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") {
+		return nil, fmt.Errorf("unexpected content type %q", ct)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, mediaMaxDownload+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > mediaMaxDownload {
+		return nil, fmt.Errorf("image larger than %d bytes", mediaMaxDownload)
+	}
+
+	// Check the header first: a tiny file can claim huge dimensions
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > mediaMaxPixels {
+		return nil, fmt.Errorf("image dimensions %dx%d exceed the limit", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// --- Image processing (stdlib only) ---
+
+// cropSquare returns the centered square of an image.
+func cropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			dst.Set(x, y, img.At(x0+x, y0+y))
+		}
+	}
+	return dst
+}
+
+// fitImage scales an image down (never up) to fit within maxW×maxH.
+func fitImage(img image.Image, maxW, maxH int) image.Image {
+	b := img.Bounds()
+	scale := 1.0
+	if sw := float64(maxW) / float64(b.Dx()); sw < scale {
+		scale = sw
+	}
+	if sh := float64(maxH) / float64(b.Dy()); sh < scale {
+		scale = sh
+	}
+	w, h := int(float64(b.Dx())*scale), int(float64(b.Dy())*scale)
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return resizeImage(img, w, h)
+}
+
+// resizeImage resamples an image to w×h by averaging each source box,
+// which gives clean results when downscaling.
+func resizeImage(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	if b.Dx() == w && b.Dy() == h {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy0 := b.Min.Y + y*b.Dy()/h
+		sy1 := b.Min.Y + (y+1)*b.Dy()/h
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < w; x++ {
+			sx0 := b.Min.X + x*b.Dx()/w
+			sx1 := b.Min.X + (x+1)*b.Dx()/w
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+			var r, g, bl, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+cr, g+cg, bl+cb, a+ca
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// handleColors derives two stable colors from a handle for generated images.
+func handleColors(handle string) (color.RGBA, color.RGBA) {
+	h := fnv.New32a()
+	h.Write([]byte(handle))
+	v := h.Sum32()
+	fg := color.RGBA{uint8(80 + v%150), uint8(80 + (v>>8)%150), uint8(80 + (v>>16)%150), 255}
+	bg := color.RGBA{uint8(20 + (v>>4)%40), uint8(20 + (v>>12)%40), uint8(30 + (v>>20)%50), 255}
+	return fg, bg
+}
+
+// generateAvatar draws a symmetric 5×5 identicon for a handle.
+func generateAvatar(handle string) image.Image {
+	fg, bg := handleColors(handle)
+	h := fnv.New64a()
+	h.Write([]byte("avatar:" + handle))
+	bits := h.Sum64()
+
+	const cells, cell = 5, avatarSize / 5
+	img := image.NewRGBA(image.Rect(0, 0, avatarSize, avatarSize))
+	for cy := 0; cy < cells; cy++ {
+		for cx := 0; cx < cells; cx++ {
+			// Mirror the left half onto the right
+			mx := cx
+			if mx > cells/2 {
+				mx = cells - 1 - cx
+			}
+			c := bg
+			if bits>>(uint(cy*3+mx))&1 == 1 {
+				c = fg
+			}
+			for y := cy * cell; y < (cy+1)*cell; y++ {
+				for x := cx * cell; x < (cx+1)*cell; x++ {
+					img.Set(x, y, c)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// generateBanner draws a horizontal gradient in the handle's colors.
+func generateBanner(handle string) image.Image {
+	fg, bg := handleColors(handle)
+	img := image.NewRGBA(image.Rect(0, 0, bannerMaxWidth, bannerMaxHeight))
+	for x := 0; x < bannerMaxWidth; x++ {
+		t := float64(x) / float64(bannerMaxWidth-1)
+		c := color.RGBA{
+			uint8(float64(bg.R)*(1-t) + float64(fg.R)*t),
+			uint8(float64(bg.G)*(1-t) + float64(fg.G)*t),
+			uint8(float64(bg.B)*(1-t) + float64(fg.B)*t),
+			255,
+		}
+		for y := 0; y < bannerMaxHeight; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// StartMediaRefresh periodically re-downloads cached images older than
+// MEDIA_REFRESH_HOURS, and retries generated fallbacks sooner.
+func StartMediaRefresh(interval time.Duration) {
+	scheduleJob("media-refresh", "Re-download stale cached avatars and banners", interval, false, refreshStaleMedia)
+	util.Log.Info("[media] Media refresh started (every %v)", interval)
+}
+
+func refreshStaleMedia() error {
+	maxAge := time.Duration(config.Cfg.MediaRefreshHours) * time.Hour
+	if maxAge <= 0 {
+		return nil
+	}
+
+	var assets []models.MediaAsset
+	if err := database.DB.Where("fetched_at < ? OR (generated = ? AND fetched_at < ?)",
+		time.Now().Add(-maxAge), true, time.Now().Add(-time.Hour)).
+		Order("fetched_at ASC").Limit(50).Find(&assets).Error; err != nil {
+		return fmt.Errorf("failed to query stale media: %w", err)
+	}
+
+	refreshed := 0
+	for _, a := range assets {
+		var shell models.Shell
+		if err := database.DB.Where("id = ?", a.ShellID).First(&shell).Error; err != nil {
+			database.DB.Delete(&a)
+			continue
+		}
+		asset := a
+		_, _, err := fetchShellMedia(&shell, a.Kind, func() ([]byte, *models.MediaAsset, error) {
+			return cacheShellMedia(&shell, asset.Kind, &asset)
+		})
+		if err != nil {
+			util.Log.Warn("[media] Refresh of %s for @%s failed: %v", a.Kind, shell.Handle, err)
+			continue
+		}
+		refreshed++
+	}
+	if refreshed > 0 {
+		util.Log.Debug("[media] Refreshed %d cached images", refreshed)
+	}
+	return nil
+}
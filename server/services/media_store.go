@@ -0,0 +1,189 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+)
+
+// MediaStore persists cached media blobs by key.
+type MediaStore interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) ([]byte, error)
+}
+
+var (
+	mediaStoreOnce sync.Once
+	mediaStore     MediaStore
+)
+
+// getMediaStore returns the store selected by MEDIA_STORAGE.
+func getMediaStore() MediaStore {
+	mediaStoreOnce.Do(func() {
+		cfg := config.Cfg
+		if strings.EqualFold(cfg.MediaStorage, "s3") {
+			mediaStore = &s3MediaStore{
+				endpoint:  strings.TrimRight(cfg.MediaS3Endpoint, "/"),
+				bucket:    cfg.MediaS3Bucket,
+				region:    cfg.MediaS3Region,
+				accessKey: cfg.MediaS3AccessKey,
+				secretKey: cfg.MediaS3SecretKey,
+				client:    &http.Client{Timeout: 30 * time.Second},
+			}
+			return
+		}
+		mediaStore = &localMediaStore{dir: cfg.MediaDir}
+	})
+	return mediaStore
+}
+
+// --- Local disk ---
+
+type localMediaStore struct {
+	dir string
+}
+
+func (s *localMediaStore) path(key string) (string, error) {
+	p := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(p, filepath.Clean(s.dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid media key %q", key)
+	}
+	return p, nil
+}
+
+func (s *localMediaStore) Put(key string, data []byte, _ string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	// Write then rename so readers never see a partial file
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (s *localMediaStore) Get(key string) ([]byte, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(p)
+}
+
+// --- S3-compatible object storage (AWS S3, Cloudflare R2, MinIO) ---
+
+// s3MediaStore talks to S3 with path-style URLs and SigV4 signing.
+type s3MediaStore struct {
+	endpoint  string
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+func (s *s3MediaStore) Put(key string, data []byte, contentType string) error {
+	resp, err := s.do(http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 put failed (status %d): %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func (s *s3MediaStore) Get(key string) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("S3 get failed (status %d)", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *s3MediaStore) do(method, key string, body []byte, contentType string) (*http.Response, error) {
+	if s.endpoint == "" || s.bucket == "" {
+		return nil, fmt.Errorf("MEDIA_S3_ENDPOINT and MEDIA_S3_BUCKET are required for S3 media storage")
+	}
+	u, err := url.Parse(s.endpoint + "/" + s.bucket + "/" + key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header.
+func (s *s3MediaStore) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
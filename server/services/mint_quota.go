@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Errors for allowlist management.
+var (
+	ErrInvalidWallet  = errors.New("invalid wallet address")
+	ErrNotAllowlisted = errors.New("wallet is not on the mint allowlist")
+)
+
+// MintQuota is how many souls a wallet may own and how many it has. Pending
+// mints don't count until they are confirmed.
+type MintQuota struct {
+	Wallet      string `json:"wallet"`
+	Limit       int    `json:"limit"` // 0 = unlimited
+	Used        int    `json:"used"`
+	Remaining   int    `json:"remaining"`
+	Allowlisted bool   `json:"allowlisted"`
+}
+
+// GetMintQuota returns the wallet's soul quota: its allowlist entry if it has
+// one, else WALLET_MINT_QUOTA.
+func GetMintQuota(wallet string) MintQuota {
+	wallet = strings.ToLower(wallet)
+	q := MintQuota{Wallet: wallet, Limit: config.Cfg.WalletMintQuota}
+
+	var entry models.MintAllowlistEntry
+	if err := database.DB.First(&entry, "wallet_addr = ?", wallet).Error; err == nil {
+		q.Limit = entry.Quota
+		q.Allowlisted = true
+	}
+
+	var used int64
+	database.DB.Model(&models.Shell{}).
+		Where("LOWER(owner_addr) = ? AND stage <> ?", wallet, models.StagePending).Count(&used)
+	q.Used = int(used)
+	if q.Limit > 0 {
+		q.Remaining = max(q.Limit-q.Used, 0)
+	}
+	return q
+}
+
+// checkMintQuota returns ErrSoulLimit if the wallet can't take another soul.
+func checkMintQuota(wallet string) error {
+	q := GetMintQuota(wallet)
+	if q.Limit > 0 && q.Used >= q.Limit {
+		return fmt.Errorf("%w: this wallet owns %d of %d souls", ErrSoulLimit, q.Used, q.Limit)
+	}
+	return nil
+}
+
+// ListMintAllowlist returns every allowlist entry, by wallet.
+func ListMintAllowlist() ([]models.MintAllowlistEntry, error) {
+	var entries []models.MintAllowlistEntry
+	err := database.DB.Order("wallet_addr").Find(&entries).Error
+	return entries, err
+}
+
+// SetMintAllowlistEntry creates or replaces a wallet's allowlist entry.
+func SetMintAllowlistEntry(wallet string, quota int, note, updatedBy string) (*models.MintAllowlistEntry, error) {
+	if !common.IsHexAddress(wallet) {
+		return nil, ErrInvalidWallet
+	}
+	if quota < 1 {
+		return nil, fmt.Errorf("quota must be at least 1")
+	}
+
+	entry := &models.MintAllowlistEntry{
+		WalletAddr: strings.ToLower(wallet),
+		Quota:      quota,
+		Note:       note,
+		UpdatedBy:  updatedBy,
+	}
+	var existing models.MintAllowlistEntry
+	if err := database.DB.First(&existing, "wallet_addr = ?", entry.WalletAddr).Error; err == nil {
+		entry.CreatedAt = existing.CreatedAt
+	}
+	if err := database.DB.Save(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to save allowlist entry: %w", err)
+	}
+	return entry, nil
+}
+
+// DeleteMintAllowlistEntry returns a wallet to the default quota. Souls it
+// already owns are kept.
+func DeleteMintAllowlistEntry(wallet string) error {
+	res := database.DB.Where("wallet_addr = ?", strings.ToLower(wallet)).Delete(&models.MintAllowlistEntry{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrNotAllowlisted, wallet)
+	}
+	return nil
+}
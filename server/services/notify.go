@@ -0,0 +1,557 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fragmentMilestones are the accepted fragment counts owners are told about.
+var fragmentMilestones = []int{10, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// Errors for notification preferences.
+var (
+	// ErrInvalidNotificationPrefs is returned for a malformed email, Telegram
+	// chat ID or event name.
+	ErrInvalidNotificationPrefs = errors.New("invalid notification preferences")
+	ErrNotificationCode         = errors.New("invalid or expired verification code")
+	ErrNotificationRateLimited  = errors.New("too many notification messages")
+)
+
+const (
+	notificationCodeTTL      = 30 * time.Minute
+	notificationCodeAttempts = 5
+	notificationCodeCooldown = time.Minute      // between codes for one channel of a wallet
+	notificationCodesPerDay  = 5                // codes per channel of a wallet per day
+	notificationTestEvery    = 10 * time.Minute // between test sends of a wallet
+)
+
+var (
+	telegramChatID = regexp.MustCompile(`^-?\d{1,20}$`)
+	telegramHTTP   = newOutboundClient("telegram", 10*time.Second, 2)
+)
+
+// NotificationChannel delivers owner notifications to one kind of address.
+type NotificationChannel interface {
+	Name() string
+	// Configured reports whether the server has the channel's credentials.
+	Configured() bool
+	// Address is where a wallet receives the channel's messages, "" if nowhere.
+	Address(pref *models.NotificationPreference) string
+	// VerifiedAt is when the wallet confirmed Address with a code, nil if not.
+	VerifiedAt(pref *models.NotificationPreference) *time.Time
+	// Columns are the preference columns holding Address and VerifiedAt.
+	Columns() (address, verifiedAt string)
+	Send(to, subject, body string) error
+}
+
+// deliverableAddress is where a channel may deliver notifications for a
+// wallet: its address once verified, "" otherwise.
+func deliverableAddress(ch NotificationChannel, pref *models.NotificationPreference) string {
+	if !ch.Configured() || ch.VerifiedAt(pref) == nil {
+		return ""
+	}
+	return ch.Address(pref)
+}
+
+// notificationChannel returns the channel named name.
+func notificationChannel(name string) (NotificationChannel, bool) {
+	for _, ch := range notificationChannels {
+		if ch.Name() == name {
+			return ch, true
+		}
+	}
+	return nil, false
+}
+
+// notificationChannels are tried in order for every notification.
+var notificationChannels = []NotificationChannel{emailChannel{}, telegramChannel{}}
+
+// emailChannel sends plain-text mail through the SMTP_* server.
+type emailChannel struct{}
+
+func (emailChannel) Name() string { return "email" }
+
+func (emailChannel) Configured() bool {
+	return config.Cfg.SMTPHost != "" && config.Cfg.SMTPFrom != ""
+}
+
+func (emailChannel) Address(pref *models.NotificationPreference) string { return pref.Email }
+
+func (emailChannel) VerifiedAt(pref *models.NotificationPreference) *time.Time {
+	return pref.EmailVerifiedAt
+}
+
+func (emailChannel) Columns() (string, string) { return "email", "email_verified_at" }
+
+func (emailChannel) Send(to, subject, body string) error {
+	cfg := config.Cfg
+	from, err := mail.ParseAddress(cfg.SMTPFrom)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\n", from.String(), to, encodeHeader(subject))
+	fmt.Fprintf(&msg, "Date: %s\r\nMIME-Version: 1.0\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+	return smtp.SendMail(addr, auth, from.Address, []string{to}, msg.Bytes())
+}
+
+// encodeHeader encodes a header value that isn't plain ASCII.
+func encodeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return "=?UTF-8?B?" + base64.StdEncoding.EncodeToString([]byte(s)) + "?="
+		}
+	}
+	return s
+}
+
+// telegramChannel sends messages through the TELEGRAM_BOT_TOKEN bot. Owners
+// start a chat with the bot and save its chat ID.
+type telegramChannel struct{}
+
+func (telegramChannel) Name() string { return "telegram" }
+
+func (telegramChannel) Configured() bool { return config.Cfg.TelegramBotToken != "" }
+
+func (telegramChannel) Address(pref *models.NotificationPreference) string {
+	return pref.TelegramChatID
+}
+
+func (telegramChannel) VerifiedAt(pref *models.NotificationPreference) *time.Time {
+	return pref.TelegramVerifiedAt
+}
+
+func (telegramChannel) Columns() (string, string) {
+	return "telegram_chat_id", "telegram_verified_at"
+}
+
+func (telegramChannel) Send(to, subject, body string) error {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"chat_id":                  to,
+		"text":                     subject + "\n\n" + body,
+		"disable_web_page_preview": true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		"https://api.telegram.org/bot"+config.Cfg.TelegramBotToken+"/sendMessage", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := telegramHTTP.Do(req)
+	if err != nil {
+		// The URL holds the bot token; keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Description string `json:"description"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result)
+		return fmt.Errorf("telegram returned %d: %s", resp.StatusCode, result.Description)
+	}
+	return nil
+}
+
+// NotificationSettings is a wallet's preferences with the channels the
+// server can deliver on.
+type NotificationSettings struct {
+	*models.NotificationPreference
+	Channels        map[string]bool   `json:"channels"` // channel name -> configured on this server
+	AvailableEvents []string          `json:"available_events"`
+	Milestones      []int             `json:"milestones"`
+	Verification    map[string]string `json:"verification,omitempty"` // channel -> "code_sent" or why no code was sent
+}
+
+// notificationEvents are the events a preference can subscribe to.
+var notificationEvents = []string{models.NotifyEventEnsouled, models.NotifyEventStageChanged, models.NotifyEventMilestone}
+
+// loadNotificationPreference returns a wallet's preferences, nil if it has none.
+func loadNotificationPreference(walletAddr string) (*models.NotificationPreference, error) {
+	var pref models.NotificationPreference
+	err := database.DB.Where("wallet_addr = ?", strings.ToLower(walletAddr)).Limit(1).Find(&pref).Error
+	if err != nil || pref.WalletAddr == "" {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// GetNotificationSettings returns a wallet's preferences (empty ones if it
+// never saved any).
+func GetNotificationSettings(walletAddr string) (*NotificationSettings, error) {
+	pref, err := loadNotificationPreference(walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+	if pref == nil {
+		pref = &models.NotificationPreference{WalletAddr: strings.ToLower(walletAddr), Events: models.StringList{}}
+	}
+	return notificationSettings(pref), nil
+}
+
+func notificationSettings(pref *models.NotificationPreference) *NotificationSettings {
+	channels := make(map[string]bool, len(notificationChannels))
+	for _, ch := range notificationChannels {
+		channels[ch.Name()] = ch.Configured()
+	}
+	return &NotificationSettings{
+		NotificationPreference: pref,
+		Channels:               channels,
+		AvailableEvents:        notificationEvents,
+		Milestones:             fragmentMilestones,
+	}
+}
+
+// SetNotificationPreferences replaces a wallet's preferences. An empty email
+// or chat ID turns that channel off; empty events means all events. A new
+// address is unverified: it is sent a code (see VerifyNotificationAddress)
+// and receives nothing else until the wallet confirms it.
+func SetNotificationPreferences(walletAddr, email, chatID string, events []string, paused bool) (*NotificationSettings, error) {
+	email, chatID = strings.TrimSpace(email), strings.TrimSpace(chatID)
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Address != email || len(email) > 254 {
+			return nil, fmt.Errorf("%w: email is not a valid address", ErrInvalidNotificationPrefs)
+		}
+	}
+	if chatID != "" && !telegramChatID.MatchString(chatID) {
+		return nil, fmt.Errorf("%w: telegram_chat_id must be a numeric chat ID", ErrInvalidNotificationPrefs)
+	}
+	list := models.StringList{}
+	for _, e := range events {
+		if !slices.Contains(notificationEvents, e) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidNotificationPrefs, e)
+		}
+		if !slices.Contains(list, e) {
+			list = append(list, e)
+		}
+	}
+
+	old, err := loadNotificationPreference(walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+	if old == nil {
+		old = &models.NotificationPreference{}
+	}
+	pref := &models.NotificationPreference{
+		WalletAddr:     strings.ToLower(walletAddr),
+		Email:          email,
+		TelegramChatID: chatID,
+		Events:         list,
+		Paused:         paused,
+		TestSentAt:     old.TestSentAt,
+	}
+	// Verification carries over only while the address stays the same
+	if email != "" && email == old.Email {
+		pref.EmailVerifiedAt = old.EmailVerifiedAt
+	}
+	if chatID != "" && chatID == old.TelegramChatID {
+		pref.TelegramVerifiedAt = old.TelegramVerifiedAt
+	}
+
+	// Reserve a code for every new address before saving, so a wallet can't
+	// cycle addresses faster than the code rate limit
+	var unverified []NotificationChannel
+	codes := map[string]string{}
+	for _, ch := range notificationChannels {
+		if ch.Address(pref) == "" || ch.Address(pref) == ch.Address(old) || !ch.Configured() {
+			continue
+		}
+		code, err := reserveNotificationCode(pref.WalletAddr, ch.Name(), ch.Address(pref))
+		if err != nil {
+			return nil, err
+		}
+		unverified = append(unverified, ch)
+		codes[ch.Name()] = code
+	}
+
+	if err := database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(pref).Error; err != nil {
+		return nil, fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+
+	settings := notificationSettings(pref)
+	for _, ch := range unverified {
+		if settings.Verification == nil {
+			settings.Verification = map[string]string{}
+		}
+		settings.Verification[ch.Name()] = sendNotificationCode(ch, pref, codes[ch.Name()])
+	}
+	return settings, nil
+}
+
+// ResendNotificationCode sends a new verification code to the wallet's
+// unverified address on channel, within the code rate limit.
+func ResendNotificationCode(walletAddr, channel string) (string, error) {
+	ch, ok := notificationChannel(channel)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown channel %q", ErrInvalidNotificationPrefs, channel)
+	}
+	pref, err := loadNotificationPreference(walletAddr)
+	if err != nil {
+		return "", fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+	if pref == nil || ch.Address(pref) == "" {
+		return "", fmt.Errorf("%w: no %s address is set", ErrInvalidNotificationPrefs, channel)
+	}
+	if ch.VerifiedAt(pref) != nil {
+		return "", fmt.Errorf("%w: the %s address is already verified", ErrInvalidNotificationPrefs, channel)
+	}
+	if !ch.Configured() {
+		return "", fmt.Errorf("%w: %s is not enabled on this server", ErrInvalidNotificationPrefs, channel)
+	}
+	code, err := reserveNotificationCode(pref.WalletAddr, ch.Name(), ch.Address(pref))
+	if err != nil {
+		return "", err
+	}
+	return sendNotificationCode(ch, pref, code), nil
+}
+
+// reserveNotificationCode generates a code for address and records it,
+// unless the wallet sent a code on this channel within the cooldown or
+// already sent the day's maximum. The check and the write are one statement,
+// so concurrent requests can't both pass.
+func reserveNotificationCode(walletAddr, channel, address string) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	now := time.Now()
+	dayAgo := now.Add(-24 * time.Hour)
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.NotificationVerification{WalletAddr: walletAddr, Channel: channel}).Error; err != nil {
+		return "", fmt.Errorf("failed to save verification code: %w", err)
+	}
+	res := database.DB.Exec(`
+		UPDATE notification_verifications SET
+			address = ?, code_hash = ?, attempts = 0, expires_at = ?, sent_at = ?,
+			sends = CASE WHEN window_start IS NULL OR window_start < ? THEN 1 ELSE sends + 1 END,
+			window_start = CASE WHEN window_start IS NULL OR window_start < ? THEN ? ELSE window_start END
+		WHERE wallet_addr = ? AND channel = ?
+			AND (sent_at IS NULL OR sent_at < ?)
+			AND (window_start IS NULL OR window_start < ? OR sends < ?)`,
+		address, notificationCodeHash(walletAddr, channel, code), now.Add(notificationCodeTTL), now,
+		dayAgo, dayAgo, now,
+		walletAddr, channel,
+		now.Add(-notificationCodeCooldown), dayAgo, notificationCodesPerDay)
+	if res.Error != nil {
+		return "", fmt.Errorf("failed to save verification code: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return "", fmt.Errorf("%w: wait before requesting another %s verification code", ErrNotificationRateLimited, channel)
+	}
+	return code, nil
+}
+
+// sendNotificationCode delivers a reserved code and returns "code_sent" or
+// the delivery error.
+func sendNotificationCode(ch NotificationChannel, pref *models.NotificationPreference, code string) string {
+	body := fmt.Sprintf("Your Ensoul verification code is %s. It expires in %d minutes.\n\n"+
+		"Enter it in your notification settings to receive notifications about your souls here. "+
+		"If you didn't ask for this, ignore this message.", code, int(notificationCodeTTL.Minutes()))
+	if err := ch.Send(ch.Address(pref), "Ensoul verification code", body); err != nil {
+		util.Log.Warn("[notify] %s verification code to %s failed: %v", ch.Name(), pref.WalletAddr, err)
+		return err.Error()
+	}
+	return "code_sent"
+}
+
+// notificationCodeHash binds a code to the wallet and channel it was sent for.
+func notificationCodeHash(walletAddr, channel, code string) string {
+	return util.HashToken(walletAddr + ":" + channel + ":" + code)
+}
+
+// VerifyNotificationAddress confirms the wallet's address on channel with the
+// code sent to it. A code allows a few attempts and expires with its TTL.
+func VerifyNotificationAddress(walletAddr, channel, code string) (*NotificationSettings, error) {
+	ch, ok := notificationChannel(channel)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown channel %q", ErrInvalidNotificationPrefs, channel)
+	}
+	walletAddr = strings.ToLower(walletAddr)
+	code = strings.TrimSpace(code)
+
+	// Count the attempt first, so guesses are capped even when concurrent
+	res := database.DB.Model(&models.NotificationVerification{}).
+		Where("wallet_addr = ? AND channel = ? AND code_hash <> '' AND expires_at > ? AND attempts < ?",
+			walletAddr, channel, time.Now(), notificationCodeAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to check verification code: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotificationCode
+	}
+	var v models.NotificationVerification
+	if err := database.DB.Where("wallet_addr = ? AND channel = ?", walletAddr, channel).First(&v).Error; err != nil {
+		return nil, fmt.Errorf("failed to check verification code: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(v.CodeHash), []byte(notificationCodeHash(walletAddr, channel, code))) != 1 {
+		return nil, ErrNotificationCode
+	}
+
+	// The code only confirms the address it was sent to
+	addressColumn, verifiedColumn := ch.Columns()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.NotificationPreference{}).
+			Where("wallet_addr = ? AND "+addressColumn+" = ?", walletAddr, v.Address).
+			Update(verifiedColumn, time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotificationCode
+		}
+		return tx.Model(&v).Update("code_hash", "").Error
+	})
+	if errors.Is(err, ErrNotificationCode) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify %s address: %w", channel, err)
+	}
+	return GetNotificationSettings(walletAddr)
+}
+
+// SendTestNotification sends a test message on each of the wallet's verified
+// channels and returns the outcome per channel ("sent" or the error). A
+// wallet may send one test per notificationTestEvery.
+func SendTestNotification(walletAddr string) (map[string]string, error) {
+	pref, err := loadNotificationPreference(walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+	results := map[string]string{}
+	if pref == nil {
+		return results, nil
+	}
+	var channels []NotificationChannel
+	for _, ch := range notificationChannels {
+		if deliverableAddress(ch, pref) != "" {
+			channels = append(channels, ch)
+		}
+	}
+	if len(channels) == 0 {
+		return results, nil
+	}
+
+	now := time.Now()
+	res := database.DB.Model(&models.NotificationPreference{}).
+		Where("wallet_addr = ? AND (test_sent_at IS NULL OR test_sent_at < ?)", pref.WalletAddr, now.Add(-notificationTestEvery)).
+		Update("test_sent_at", now)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to send test notification: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: one test notification per %v", ErrNotificationRateLimited, notificationTestEvery)
+	}
+
+	for _, ch := range channels {
+		if err := ch.Send(ch.Address(pref), "Ensoul test notification",
+			"Notifications for your souls will arrive here.\n\n"+config.Cfg.PublicURL("/dashboard")); err != nil {
+			util.Log.Warn("[notify] Test %s notification to %s failed: %v", ch.Name(), pref.WalletAddr, err)
+			results[ch.Name()] = err.Error()
+			continue
+		}
+		results[ch.Name()] = "sent"
+	}
+	return results, nil
+}
+
+// notifyOwner sends a notification about a soul to its owner on every
+// channel the owner set up for the event. Failures are logged, not retried.
+func notifyOwner(ownerAddr, event, subject, body string) {
+	if ownerAddr == "" {
+		return
+	}
+	pref, err := loadNotificationPreference(ownerAddr)
+	if err != nil {
+		util.Log.Warn("[notify] Failed to load preferences of %s: %v", ownerAddr, err)
+		return
+	}
+	if pref == nil || pref.Paused || (len(pref.Events) > 0 && !slices.Contains(pref.Events, event)) {
+		return
+	}
+	for _, ch := range notificationChannels {
+		to := deliverableAddress(ch, pref)
+		if to == "" {
+			continue
+		}
+		if err := ch.Send(to, subject, body); err != nil {
+			util.Log.Warn("[notify] %s %s notification to %s failed: %v", ch.Name(), event, pref.WalletAddr, err)
+		}
+	}
+}
+
+// registerNotificationSubscribers notifies owners of ensoulings, stage
+// changes and fragment milestones. Sending runs in the background.
+func registerNotificationSubscribers(bus *EventBus) {
+	Subscribe(bus, "notify", func(e EnsoulingCompleted) {
+		owner, handle := e.Shell.OwnerAddr, e.Shell.Handle
+		subject := fmt.Sprintf("@%s evolved to DNA v%d", handle, e.Ensouling.VersionTo)
+		body := fmt.Sprintf("%d new fragments were merged into @%s's soul.\n\n%s",
+			e.Ensouling.FragsMerged, handle, config.Cfg.PublicURL("/soul/"+handle))
+		go notifyOwner(owner, models.NotifyEventEnsouled, subject, body)
+	})
+	Subscribe(bus, "notify", func(e ShellStageChanged) {
+		owner, handle := e.Shell.OwnerAddr, e.Shell.Handle
+		subject := fmt.Sprintf("@%s is now %s", handle, e.Transition.ToStage)
+		body := fmt.Sprintf("@%s grew from %s to %s.\n\n%s",
+			handle, e.Transition.FromStage, e.Transition.ToStage, config.Cfg.PublicURL("/soul/"+handle))
+		go notifyOwner(owner, models.NotifyEventStageChanged, subject, body)
+	})
+	Subscribe(bus, "notify", func(e FragmentAccepted) {
+		// The counter was refreshed from the committed value, so each
+		// milestone is reached by exactly one acceptance
+		count := e.Shell.AcceptedFrags
+		if !slices.Contains(fragmentMilestones, count) {
+			return
+		}
+		owner, handle := e.Shell.OwnerAddr, e.Shell.Handle
+		subject := fmt.Sprintf("@%s reached %d fragments", handle, count)
+		body := fmt.Sprintf("Claws have contributed %d accepted fragments to @%s's soul.\n\n%s",
+			count, handle, config.Cfg.PublicURL("/soul/"+handle))
+		go notifyOwner(owner, models.NotifyEventMilestone, subject, body)
+	})
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// Outbound retry and circuit breaker settings, shared by all upstream APIs.
+const (
+	outboundBackoffBase      = 500 * time.Millisecond
+	outboundBackoffMax       = 8 * time.Second
+	outboundBreakerThreshold = 5                // consecutive failed requests that open a host's breaker
+	outboundBreakerCooldown  = 30 * time.Second // open time before a trial request is let through
+)
+
+// ErrCircuitOpen is returned without contacting an upstream host whose
+// breaker is open after repeated failures.
+var ErrCircuitOpen = errors.New("upstream circuit open")
+
+// Shared clients for upstream APIs. Calls to user-supplied URLs (webhooks,
+// agent registration files) keep their own private-address-safe clients.
+var (
+	twitterHTTP    = newOutboundClient("twitter", 15*time.Second, 2)
+	socialDataHTTP = newOutboundClient("socialdata", 15*time.Second, 2)
+	// No client timeout: LLM calls are bounded by their context
+	// (LLM_TIMEOUT_SECONDS / LLM_STREAM_TIMEOUT_SECONDS), and a client timeout
+	// would also cut off long streams.
+	llmHTTP = newOutboundClient("llm", 0, 2)
+)
+
+// outboundClient is an HTTP client for one upstream service with pooled
+// connections, bounded retries with jittered backoff on 429/5xx and network
+// errors, and a circuit breaker per host.
+type outboundClient struct {
+	name     string
+	client   *http.Client
+	retries  int
+	breakers sync.Map // host -> *circuitBreaker
+}
+
+// newOutboundClient returns a client whose requests time out after timeout
+// (0 = only the request context) and are retried up to retries times.
+func newOutboundClient(name string, timeout time.Duration, retries int) *outboundClient {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          32,
+		MaxIdleConnsPerHost:   16,
+		MaxConnsPerHost:       64,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	return &outboundClient{
+		name:    name,
+		client:  &http.Client{Timeout: timeout, Transport: transport},
+		retries: retries,
+	}
+}
+
+// Do sends req, retrying retryable failures while the request context allows.
+// Request bodies must be replayable (bytes/strings readers, as built by
+// http.NewRequest), or the request is sent once. The last response is
+// returned as is, so callers keep handling non-2xx statuses themselves.
+func (c *outboundClient) Do(req *http.Request) (*http.Response, error) {
+	breaker := c.breaker(req.URL.Host)
+	if !breaker.allow() {
+		return nil, fmt.Errorf("%s: %w (%s)", c.name, ErrCircuitOpen, req.URL.Host)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.client.Do(req)
+		if errors.Is(req.Context().Err(), context.Canceled) {
+			breaker.release() // the caller gave up; says nothing about the host
+			return resp, err
+		}
+		retryable := err != nil ||
+			resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retryable || attempt >= c.retries || req.Context().Err() != nil || req.Body != nil && req.GetBody == nil {
+			breaker.record(c.name, req.URL.Host, !retryable)
+			return resp, err
+		}
+
+		wait := outboundBackoff(attempt)
+		if err == nil {
+			if after := retryAfter(resp); after > 0 {
+				wait = min(after, outboundBackoffMax)
+			}
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			util.Log.Debug("[%s] %s %s returned %d, retrying in %v", c.name, req.Method, req.URL.Host, resp.StatusCode, wait)
+		} else {
+			util.Log.Debug("[%s] %s %s failed, retrying in %v: %v", c.name, req.Method, req.URL.Host, wait, err)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			breaker.release()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+func (c *outboundClient) breaker(host string) *circuitBreaker {
+	b, _ := c.breakers.LoadOrStore(host, &circuitBreaker{})
+	return b.(*circuitBreaker)
+}
+
+// outboundBackoff returns the full-jitter exponential backoff before retry attempt+1.
+func outboundBackoff(attempt int) time.Duration {
+	ceiling := min(outboundBackoffBase<<attempt, outboundBackoffMax)
+	return time.Duration(rand.Int64N(int64(ceiling))) + 50*time.Millisecond
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// circuitBreaker opens after outboundBreakerThreshold consecutive failures
+// and, once outboundBreakerCooldown has passed, lets one trial request
+// through: success closes it, failure reopens it.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool // a half-open trial request is in flight
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < outboundBreakerThreshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// release ends a request without an outcome.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
+
+func (b *circuitBreaker) record(name, host string, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if ok {
+		if b.failures >= outboundBreakerThreshold {
+			util.Log.Info("[%s] Circuit to %s closed", name, host)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= outboundBreakerThreshold {
+		if b.failures == outboundBreakerThreshold {
+			util.Log.Warn("[%s] Circuit to %s opened after %d consecutive failures", name, host, b.failures)
+		}
+		b.openUntil = time.Now().Add(outboundBreakerCooldown)
+	}
+}
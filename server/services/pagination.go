@@ -0,0 +1,63 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned by list functions for a malformed or mismatched cursor.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// listCursor is the position after the last row of a page in keyset pagination.
+// Rows are ordered by (sort key DESC, created_at DESC, id DESC); Key holds the
+// sort column value when the listing is not sorted by created_at alone.
+type listCursor struct {
+	Key       *int64    `json:"k,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// encodeCursor returns an opaque next_cursor token.
+func encodeCursor(c listCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a next_cursor token.
+func decodeCursor(token string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// applyKeyset orders a query for keyset pagination and, if a cursor is given,
+// restricts it to rows after the cursor. keyColumn is "" for created_at order.
+func applyKeyset(query *gorm.DB, table, keyColumn string, cursor *listCursor) (*gorm.DB, error) {
+	if keyColumn == "" {
+		if cursor != nil {
+			query = query.Where("("+table+".created_at, "+table+".id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+		}
+		return query.Order(table + ".created_at DESC").Order(table + ".id DESC"), nil
+	}
+
+	if cursor != nil {
+		if cursor.Key == nil {
+			return nil, fmt.Errorf("%w: cursor does not match the requested sort", ErrInvalidCursor)
+		}
+		query = query.Where("("+table+"."+keyColumn+", "+table+".created_at, "+table+".id) < (?, ?, ?)",
+			*cursor.Key, cursor.CreatedAt, cursor.ID)
+	}
+	return query.Order(table + "." + keyColumn + " DESC").Order(table + ".created_at DESC").Order(table + ".id DESC"), nil
+}
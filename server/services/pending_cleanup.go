@@ -1,6 +1,7 @@
 package services
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/ensoul-labs/ensoul-server/database"
@@ -18,8 +19,9 @@ func HardDeleteShell(shellID uuid.UUID) {
 	)
 	// 2. Delete chat sessions
 	database.DB.Unscoped().Where("shell_id = ?", shellID).Delete(&models.ChatSession{})
-	// 3. Delete fragments
+	// 3. Delete fragments and their batches
 	database.DB.Unscoped().Where("shell_id = ?", shellID).Delete(&models.Fragment{})
+	database.DB.Where("shell_id = ?", shellID).Delete(&models.FragmentBatch{})
 	// 4. Delete ensoulings
 	database.DB.Unscoped().Where("shell_id = ?", shellID).Delete(&models.Ensouling{})
 	// 5. Delete the shell itself
@@ -29,26 +31,27 @@ func HardDeleteShell(shellID uuid.UUID) {
 // StartPendingShellCleanup periodically hard-deletes pending shells
 // that were never confirmed on-chain (i.e. the user abandoned the mint).
 func StartPendingShellCleanup(interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for range ticker.C {
-			cleanPendingShells()
-		}
-	}()
+	scheduleJob("pending-shell-cleanup", "Hard-delete souls stuck in pending past the mint timeout", interval, false, cleanPendingShells)
 	util.Log.Info("[cleanup] Pending shell cleanup started (every %v, timeout %v)", interval, PendingMintTimeout)
 }
 
-func cleanPendingShells() {
+func cleanPendingShells() error {
 	cutoff := time.Now().Add(-PendingMintTimeout)
 	var expired []models.Shell
-	database.DB.Where("stage = ? AND created_at < ?", models.StagePending, cutoff).Find(&expired)
+	// Custodial mints in flight are kept: the platform wallet may already hold their NFT
+	if err := database.DB.Where("stage = ? AND created_at < ? AND agent_id IS NULL", models.StagePending, cutoff).
+		Where("handle NOT IN (?)", database.DB.Model(&models.PendingTx{}).Select("ref_id").
+			Where("kind IN ? AND status = ?", custodialTxKinds, models.PendingTxPending)).
+		Find(&expired).Error; err != nil {
+		return fmt.Errorf("failed to query pending shells: %w", err)
+	}
 	if len(expired) == 0 {
-		return
+		return nil
 	}
 	for _, s := range expired {
 		HardDeleteShell(s.ID)
 		util.Log.Info("[cleanup] Hard-deleted expired pending shell @%s (id=%s)", s.Handle, s.ID)
 	}
 	util.Log.Info("[cleanup] Cleaned up %d expired pending shells", len(expired))
+	return nil
 }
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// registryCursorName is the chain_cursors row of the burn scanner.
+const registryCursorName = "identity_registry_burns"
+
+// registryScanMaxBlocks bounds one eth_getLogs range (RPC providers cap it).
+const registryScanMaxBlocks = 5000
+
+// Errors for souls that can no longer be chatted with or contributed to.
+var (
+	ErrShellRevoked   = errors.New("has been burned on-chain and is no longer available")
+	ErrShellRetired   = errors.New("has been retired by its owner and is read-only")
+	ErrShellDisputed  = errors.New("is under a handle dispute and is read-only until it is resolved")
+	ErrRegistryPaused = errors.New("the identity registry is paused, souls are read-only until it resumes")
+)
+
+// registryPaused mirrors the Identity Registry's paused() state.
+var registryPaused atomic.Bool
+
+// RegistryPaused reports whether the Identity Registry was paused at the last check.
+func RegistryPaused() bool {
+	return registryPaused.Load()
+}
+
+// checkShellActive returns why a soul can't take chats or fragments right now, if anything.
+func checkShellActive(shell *models.Shell) error {
+	if shell.ChainStatus == models.ShellChainRevoked {
+		return fmt.Errorf("soul @%s %w", shell.Handle, ErrShellRevoked)
+	}
+	if shell.ChainStatus == models.ShellChainRetired {
+		return fmt.Errorf("soul @%s %w", shell.Handle, ErrShellRetired)
+	}
+	if shell.DisputedAt != nil {
+		return fmt.Errorf("soul @%s %w", shell.Handle, ErrShellDisputed)
+	}
+	if registryPaused.Load() {
+		return ErrRegistryPaused
+	}
+	return nil
+}
+
+// StartRegistryWatcher follows the Identity Registry: it polls paused() and
+// scans Transfer logs for burns, revoking the burned souls. On start (and then
+// daily) it checks every active soul with ownerOf, to catch burns from before
+// the scan began.
+func StartRegistryWatcher(interval time.Duration) {
+	if chain.C() == nil {
+		if deferUntilChainReady(func() { StartRegistryWatcher(interval) }) {
+			util.Log.Info("[registry] Chain not connected yet, registry watcher starts once it is")
+		} else {
+			util.Log.Info("[registry] Chain not initialized, registry watcher disabled")
+		}
+		return
+	}
+	scheduleJob("registry-sweep", "Check every active soul's NFT still exists", 24*time.Hour, true, sweepBurnedShells)
+	scheduleJob("registry-watch", "Poll paused() and scan Transfer logs for burned souls", interval, true, checkRegistry)
+	util.Log.Info("[registry] Registry watcher started (every %v)", interval)
+}
+
+func checkRegistry() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	paused, err := chain.RegistryPaused(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to read paused(): %w", err)
+	} else if was := registryPaused.Swap(paused); was != paused {
+		if paused {
+			util.Log.Warn("[registry] Identity Registry is paused, chat and fragments are blocked")
+		} else {
+			util.Log.Info("[registry] Identity Registry resumed")
+		}
+	}
+
+	return errors.Join(err, scanBurns(ctx))
+}
+
+// scanBurns reads Transfer-to-zero logs from the cursor to the latest block.
+func scanBurns(ctx context.Context) error {
+	latest, err := chain.LatestBlock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read latest block: %w", err)
+	}
+
+	var cursor models.ChainCursor
+	if err := database.DB.Where("name = ?", registryCursorName).First(&cursor).Error; err != nil {
+		// First run starts at the head; the startup sweep covers earlier burns
+		return database.DB.Create(&models.ChainCursor{Name: registryCursorName, Block: latest}).Error
+	}
+
+	for from := cursor.Block + 1; from <= latest; {
+		to := min(from+registryScanMaxBlocks-1, latest)
+		agentIDs, err := chain.FindBurns(ctx, from, to)
+		if err != nil {
+			return err
+		}
+		for _, id := range agentIDs {
+			var shell models.Shell
+			if err := database.DB.Where("agent_id = ?", id).First(&shell).Error; err == nil {
+				revokeShell(&shell)
+			}
+		}
+		database.DB.Model(&models.ChainCursor{}).Where("name = ?", registryCursorName).Update("block", to)
+		from = to + 1
+	}
+	return nil
+}
+
+// sweepBurnedShells checks every active soul's NFT still exists, reading
+// them in batches.
+func sweepBurnedShells() error {
+	var shells []models.Shell
+	if err := database.DB.Where("agent_id IS NOT NULL AND chain_status = ?", models.ShellChainActive).Find(&shells).Error; err != nil {
+		return fmt.Errorf("failed to query active souls: %w", err)
+	}
+
+	ids := make([]uint64, len(shells))
+	for i, shell := range shells {
+		ids[i] = *shell.AgentID
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	states, err := chain.ReadSouls(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to read soul NFTs: %w", err)
+	}
+	for i := range shells {
+		if state, ok := states[*shells[i].AgentID]; ok && !state.Exists {
+			revokeShell(&shells[i])
+		}
+	}
+	return nil
+}
+
+// revokeShell marks a soul whose NFT was burned as revoked and notifies webhooks.
+func revokeShell(shell *models.Shell) {
+	now := time.Now()
+	res := database.DB.Model(&models.Shell{}).
+		Where("id = ? AND chain_status <> ?", shell.ID, models.ShellChainRevoked).
+		Updates(map[string]interface{}{"chain_status": models.ShellChainRevoked, "revoked_at": &now})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return
+	}
+
+	util.Log.Warn("[registry] Soul @%s (agent %d) was burned on-chain, marked revoked", shell.Handle, *shell.AgentID)
+	go EmitWebhookEvent(models.WebhookEventRevoked, &shell.ID, map[string]interface{}{
+		"handle":     shell.Handle,
+		"agent_id":   *shell.AgentID,
+		"revoked_at": now,
+	})
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// StartReplicaCheck periodically checks the read replica's lag, moving heavy
+// reads back to the primary while it is behind. No-op without DB_REPLICA_URL.
+func StartReplicaCheck(interval time.Duration) {
+	if configured, _ := database.ReplicaStatus(); !configured {
+		return
+	}
+	maxLag := time.Duration(config.Cfg.DBReplicaMaxLagSeconds) * time.Second
+	scheduleJob("replica-check", "Check read replica lag and route reads accordingly", interval, true, func() error {
+		return database.CheckReplica(maxLag)
+	})
+	util.Log.Info("[replica] Replica lag check started (every %v, max lag %v)", interval, maxLag)
+}
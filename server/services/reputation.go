@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/contracts"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+// reputationFeedbackLimit is how many feedback transactions are linked.
+const reputationFeedbackLimit = 20
+
+// ReputationSummary is an aggregated getSummary result. Average is the mean
+// feedback value (0-100, the curator's confidence).
+type ReputationSummary struct {
+	Count   uint64  `json:"count"`
+	Average float64 `json:"average"`
+}
+
+// ReputationFeedback links one accepted fragment to its feedback transaction.
+type ReputationFeedback struct {
+	FragmentID uuid.UUID `json:"fragment_id"`
+	Dimension  string    `json:"dimension"`
+	Value      int       `json:"value"`
+	TxHash     string    `json:"tx_hash"`
+	TxURL      string    `json:"tx_url"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ShellReputation is a soul's on-chain reputation from the Reputation Registry.
+type ShellReputation struct {
+	AgentID uint64 `json:"agent_id"`
+	Clients int    `json:"clients"` // Claw wallets the summary covers
+	ReputationSummary
+	Dimensions map[string]ReputationSummary `json:"dimensions"` // by tag1
+	Feedback   []ReputationFeedback         `json:"feedback"`   // newest first
+	CachedAt   time.Time                    `json:"cached_at"`
+}
+
+// reputationCache holds summaries for REPUTATION_CACHE_SECONDS, since each
+// one costs seven getSummary calls (batched into one request).
+var reputationCache = struct {
+	sync.Mutex
+	entries map[uuid.UUID]*ShellReputation
+}{entries: make(map[uuid.UUID]*ShellReputation)}
+
+// GetShellReputation returns a minted soul's reputation, summarized over the
+// wallets of every Claw that contributed to it.
+func GetShellReputation(ctx context.Context, shell *models.Shell) (*ShellReputation, error) {
+	if shell.AgentID == nil {
+		return nil, fmt.Errorf("@%s %w", shell.Handle, ErrShellNotMinted)
+	}
+	ttl := time.Duration(config.Cfg.ReputationCacheSeconds) * time.Second
+
+	reputationCache.Lock()
+	cached := reputationCache.entries[shell.ID]
+	reputationCache.Unlock()
+	if cached != nil && time.Since(cached.CachedAt) < ttl {
+		return cached, nil
+	}
+
+	rep, err := readShellReputation(ctx, shell)
+	if err != nil {
+		return nil, err
+	}
+
+	reputationCache.Lock()
+	reputationCache.entries[shell.ID] = rep
+	reputationCache.Unlock()
+	return rep, nil
+}
+
+func readShellReputation(ctx context.Context, shell *models.Shell) (*ShellReputation, error) {
+	var wallets []string
+	database.DB.Model(&models.Claw{}).
+		Where("wallet_addr <> '' AND id IN (?)", database.DB.Model(&models.Fragment{}).Select("claw_id").Where("shell_id = ?", shell.ID)).
+		Distinct().Pluck("wallet_addr", &wallets)
+
+	rep := &ShellReputation{
+		AgentID:    *shell.AgentID,
+		Clients:    len(wallets),
+		Dimensions: make(map[string]ReputationSummary),
+		Feedback:   []ReputationFeedback{},
+		CachedAt:   time.Now(),
+	}
+
+	// getSummary requires at least one client address
+	if len(wallets) > 0 {
+		clients := make([]common.Address, len(wallets))
+		for i, w := range wallets {
+			clients[i] = common.HexToAddress(w)
+		}
+		agentID := new(big.Int).SetUint64(*shell.AgentID)
+
+		// The overall summary and one per dimension, read in a single batch
+		tags := []string{""}
+		for dim := range validDimensions {
+			tags = append(tags, dim)
+		}
+		queries := make([]chain.SummaryQuery, len(tags))
+		for i, tag := range tags {
+			queries[i] = chain.SummaryQuery{AgentID: agentID, Clients: clients, Tag1: tag}
+		}
+		results, err := chain.ReadReputationSummaries(ctx, queries)
+		if err != nil {
+			return nil, err
+		}
+		for i, tag := range tags {
+			summary := reputationSummary(results[i])
+			if tag == "" {
+				rep.ReputationSummary = summary
+			} else if summary.Count > 0 {
+				rep.Dimensions[tag] = summary
+			}
+		}
+	}
+
+	var fragments []models.Fragment
+	database.DB.Select("id, dimension, confidence, tx_hash, created_at").
+		Where("shell_id = ? AND status = ? AND tx_hash LIKE '0x%'", shell.ID, models.FragStatusAccepted).
+		Order("created_at DESC").Limit(reputationFeedbackLimit).Find(&fragments)
+	for _, f := range fragments {
+		if !isFeedbackTxHash(f.TxHash) {
+			continue
+		}
+		rep.Feedback = append(rep.Feedback, ReputationFeedback{
+			FragmentID: f.ID,
+			Dimension:  f.Dimension,
+			Value:      int(f.Confidence * 100), // as sent in submitOnChainFeedback
+			TxHash:     f.TxHash,
+			TxURL:      config.Cfg.ExplorerTxURL(f.TxHash),
+			CreatedAt:  f.CreatedAt,
+		})
+	}
+	return rep, nil
+}
+
+// reputationSummary converts a getSummary result.
+func reputationSummary(r contracts.SummaryResult) ReputationSummary {
+	if r.Count == 0 || r.SummaryValue == nil {
+		return ReputationSummary{}
+	}
+	avg, _ := new(big.Float).Quo(new(big.Float).SetInt(r.SummaryValue), big.NewFloat(math.Pow10(int(r.SummaryValueDecimals)))).Float64()
+	return ReputationSummary{Count: r.Count, Average: math.Round(avg*100) / 100}
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Claw deletion policies (CLAW_DELETE_POLICY).
+const (
+	ClawDeleteAnonymize = "anonymize" // keep fragments, scrub the Claw's identity
+	ClawDeleteCascade   = "cascade"   // delete the Claw's fragments with it
+)
+
+// guestPurgeBatchSize bounds how many sessions one purge statement removes.
+const guestPurgeBatchSize = 500
+
+// DeleteChatHistory permanently removes all chat sessions of a wallet, with
+// their messages and any public shares made from them.
+func DeleteChatHistory(walletAddr string) (map[string]int64, error) {
+	counts := map[string]int64{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var sessionIDs []uuid.UUID
+		if err := tx.Unscoped().Model(&models.ChatSession{}).
+			Where("LOWER(wallet_addr) = LOWER(?)", walletAddr).Pluck("id", &sessionIDs).Error; err != nil {
+			return err
+		}
+		if len(sessionIDs) == 0 {
+			return nil
+		}
+		return deleteChatSessions(tx, sessionIDs, true, counts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete chat history: %w", err)
+	}
+
+	recordDeletion(models.DeletionChatHistory, walletAddr, walletAddr, "", counts)
+	util.Log.Info("[retention] Deleted chat history of %s: %v", walletAddr, counts)
+	return counts, nil
+}
+
+// ClawDeletePolicy returns the configured policy, defaulting to anonymize.
+func ClawDeletePolicy() string {
+	if strings.EqualFold(config.Cfg.ClawDeletePolicy, ClawDeleteCascade) {
+		return ClawDeleteCascade
+	}
+	return ClawDeleteAnonymize
+}
+
+// DeleteClaw removes a Claw at its operator's request. Under the anonymize policy
+// its fragments stay (attributed to a scrubbed, soft-deleted Claw); under cascade
+// they are deleted and the affected shells' counters recomputed. Fragments already
+// merged into a soul by ensouling remain part of that soul's DNA either way.
+func DeleteClaw(claw *models.Claw, requestedBy string) (map[string]int64, error) {
+	policy := ClawDeletePolicy()
+	counts := map[string]int64{}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("claw_id = ?", claw.ID).Delete(&models.ClawBinding{})
+		if res.Error != nil {
+			return res.Error
+		}
+		counts["bindings"] = res.RowsAffected
+
+		res = tx.Where("claw_id = ?", claw.ID).Delete(&models.FragmentAppeal{})
+		if res.Error != nil {
+			return res.Error
+		}
+		counts["appeals"] = res.RowsAffected
+
+		if policy == ClawDeleteCascade {
+			return cascadeDeleteClaw(tx, claw, counts)
+		}
+		return anonymizeClaw(tx, claw, counts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete claw: %w", err)
+	}
+
+	recordDeletion(models.DeletionClaw, claw.ID.String(), requestedBy, policy, counts)
+	util.Log.Info("[retention] Deleted claw %s (%s) by %s: %v", claw.Name, policy, requestedBy, counts)
+	return counts, nil
+}
+
+// cascadeDeleteClaw deletes the Claw, its fragments and their embeddings.
+func cascadeDeleteClaw(tx *gorm.DB, claw *models.Claw, counts map[string]int64) error {
+	var shellIDs []uuid.UUID
+	if err := tx.Unscoped().Model(&models.Fragment{}).Where("claw_id = ?", claw.ID).
+		Distinct("shell_id").Pluck("shell_id", &shellIDs).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec("DELETE FROM fragment_embeddings WHERE fragment_id IN (SELECT id FROM fragments WHERE claw_id = ?)",
+		claw.ID).Error; err != nil {
+		return err
+	}
+	res := tx.Unscoped().Where("claw_id = ?", claw.ID).Delete(&models.Fragment{})
+	if res.Error != nil {
+		return res.Error
+	}
+	counts["fragments"] = res.RowsAffected
+	if err := tx.Where("claw_id = ?", claw.ID).Delete(&models.FragmentBatch{}).Error; err != nil {
+		return err
+	}
+
+	if len(shellIDs) > 0 {
+		if err := tx.Exec(`
+			UPDATE shells SET
+				total_frags = (SELECT COUNT(*) FROM fragments f
+					WHERE f.shell_id = shells.id AND f.deleted_at IS NULL),
+				accepted_frags = (SELECT COUNT(*) FROM fragments f
+					WHERE f.shell_id = shells.id AND f.deleted_at IS NULL AND f.status = ?),
+				total_claws = (SELECT COUNT(DISTINCT f.claw_id) FROM fragments f
+					WHERE f.shell_id = shells.id AND f.deleted_at IS NULL AND f.status = ?)
+			WHERE id IN ?`, models.FragStatusAccepted, models.FragStatusAccepted, shellIDs).Error; err != nil {
+			return err
+		}
+		counts["shells_recounted"] = int64(len(shellIDs))
+	}
+
+	return tx.Unscoped().Delete(&models.Claw{}, "id = ?", claw.ID).Error
+}
+
+// anonymizeClaw scrubs identifying and secret fields, then soft-deletes the Claw
+// so its fragments keep a valid owner row. The wallet address stays because
+// on-chain feedback already references it.
+func anonymizeClaw(tx *gorm.DB, claw *models.Claw, counts map[string]int64) error {
+	var fragments int64
+	tx.Model(&models.Fragment{}).Where("claw_id = ?", claw.ID).Count(&fragments)
+	counts["fragments_anonymized"] = fragments
+
+	// Replace the key hash and claim code with unguessable values so neither works again
+	revoked, err := generateAPIKey()
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Claw{}).Where("id = ?", claw.ID).UpdateColumns(map[string]interface{}{
+		"name":              "deleted-" + claw.ID.String(),
+		"description":       "",
+		"api_key_hash":      util.HashToken(revoked),
+		"claim_code":        "deleted-" + util.HashToken(revoked+":claim"),
+		"verification_code": "",
+		"twitter_handle":    "",
+		"twitter_tweet_url": "",
+		"wallet_pk_enc":     "",
+		"register_ip":       "",
+		"agent_version":     "",
+		"capabilities":      models.StringList{},
+		"tags":              models.StringList{},
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.Claw{}, "id = ?", claw.ID).Error
+}
+
+// deleteChatSessions hard-deletes sessions and their messages, and optionally
+// the public shares made from them, adding to counts.
+func deleteChatSessions(tx *gorm.DB, sessionIDs []uuid.UUID, withShares bool, counts map[string]int64) error {
+	res := tx.Where("session_id IN ?", sessionIDs).Delete(&models.ChatMessage{})
+	if res.Error != nil {
+		return res.Error
+	}
+	counts["messages"] += res.RowsAffected
+
+	if withShares {
+		res = tx.Where("session_id IN ?", sessionIDs).Delete(&models.ChatShare{})
+		if res.Error != nil {
+			return res.Error
+		}
+		counts["shares"] += res.RowsAffected
+	}
+
+	res = tx.Where("session_id IN ?", sessionIDs).Delete(&models.ModerationLog{})
+	if res.Error != nil {
+		return res.Error
+	}
+	counts["moderation_logs"] += res.RowsAffected
+
+	res = tx.Unscoped().Where("id IN ?", sessionIDs).Delete(&models.ChatSession{})
+	if res.Error != nil {
+		return res.Error
+	}
+	counts["sessions"] += res.RowsAffected
+	return nil
+}
+
+// recordDeletion writes an audit entry. Failures are logged, not returned:
+// the deletion itself has already been committed.
+func recordDeletion(subject, subjectID, requestedBy, policy string, counts map[string]int64) {
+	c := make(models.JSON, len(counts))
+	for k, v := range counts {
+		c[k] = v
+	}
+	record := &models.DeletionRecord{
+		Subject:     subject,
+		SubjectID:   subjectID,
+		RequestedBy: requestedBy,
+		Policy:      policy,
+		Counts:      c,
+	}
+	if err := database.DB.Create(record).Error; err != nil {
+		util.Log.Error("[retention] Failed to record %s deletion of %s: %v", subject, subjectID, err)
+	}
+}
+
+// ListDeletionRecords returns the most recent deletion audit entries.
+func ListDeletionRecords(subject string, limit int) ([]models.DeletionRecord, error) {
+	query := database.DB.Order("created_at DESC").Limit(limit)
+	if subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+	var records []models.DeletionRecord
+	if err := query.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// StartRetentionPurge periodically purges guest chat sessions idle longer than
+// CHAT_GUEST_RETENTION_DAYS, sessions users already deleted, and daily chat
+// round counts older than a week.
+func StartRetentionPurge(interval time.Duration) {
+	scheduleJob("retention-purge", "Purge deleted and expired guest chat sessions", interval, false, purgeChatSessions)
+	util.Log.Info("[retention] Chat retention purge started (every %v, guest retention %d days)",
+		interval, config.Cfg.ChatGuestRetentionDays)
+}
+
+func purgeChatSessions() error {
+	counts := map[string]int64{}
+
+	purge := func(query func(tx *gorm.DB) *gorm.DB) error {
+		for {
+			var ids []uuid.UUID
+			if err := query(database.DB.Unscoped().Model(&models.ChatSession{})).
+				Limit(guestPurgeBatchSize).Pluck("id", &ids).Error; err != nil {
+				return fmt.Errorf("failed to query sessions to purge: %w", err)
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			// Shares are explicit publications and outlive the retention window
+			if err := database.DB.Transaction(func(tx *gorm.DB) error {
+				return deleteChatSessions(tx, ids, false, counts)
+			}); err != nil {
+				return fmt.Errorf("failed to purge sessions: %w", err)
+			}
+			if len(ids) < guestPurgeBatchSize {
+				return nil
+			}
+		}
+	}
+
+	// Sessions users deleted (soft-deleted by DELETE /api/chat/sessions/:id)
+	err := purge(func(q *gorm.DB) *gorm.DB { return q.Where("deleted_at IS NOT NULL") })
+
+	if days := config.Cfg.ChatGuestRetentionDays; days > 0 && err == nil {
+		cutoff := time.Now().AddDate(0, 0, -days)
+		err = purge(func(q *gorm.DB) *gorm.DB {
+			return q.Where("(wallet_addr IS NULL OR wallet_addr = '') AND updated_at < ?", cutoff)
+		})
+	}
+
+	if counts["sessions"] > 0 {
+		recordDeletion(models.DeletionGuestSessions, "", "system", "", counts)
+		util.Log.Info("[retention] Purged %d chat sessions (%d messages)", counts["sessions"], counts["messages"])
+	}
+
+	// Daily round counts only matter for today's quota
+	if n, purgeErr := purgeChatRoundUsage(); purgeErr != nil {
+		util.Log.Warn("[retention] Failed to purge chat round usage: %v", purgeErr)
+	} else if n > 0 {
+		util.Log.Debug("[retention] Purged %d daily chat round counts", n)
+	}
+	return err
+}
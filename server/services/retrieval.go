@@ -0,0 +1,236 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// embeddingBatchSize caps how many fragments are sent per embeddings call.
+const embeddingBatchSize = 50
+
+// ScoredFragment is an accepted fragment paired with its relevance to a query.
+type ScoredFragment struct {
+	Fragment models.Fragment
+	Score    float64
+}
+
+// RetrievalEnabledForTier reports whether chat sessions of the given tier
+// get retrieved fragment context (configured via CHAT_RETRIEVAL_TIERS).
+func RetrievalEnabledForTier(tier string) bool {
+	for _, t := range config.Cfg.ChatRetrievalTiers {
+		if strings.EqualFold(t, tier) {
+			return true
+		}
+	}
+	return false
+}
+
+// EmbedFragments computes and stores embeddings for the given fragments: the
+// retrieval vector of "dimension: content" and the content-only vector
+// plagiarism screening compares. Existing rows are overwritten so a model
+// change re-embeds cleanly.
+func EmbedFragments(fragments []models.Fragment) error {
+	// Two texts per fragment, so a batch still makes one call of at most
+	// embeddingBatchSize texts
+	for start := 0; start < len(fragments); start += embeddingBatchSize / 2 {
+		end := start + embeddingBatchSize/2
+		if end > len(fragments) {
+			end = len(fragments)
+		}
+		batch := fragments[start:end]
+
+		texts := make([]string, 2*len(batch))
+		for i, f := range batch {
+			texts[i] = f.Dimension + ": " + f.Content
+			texts[len(batch)+i] = f.Content
+		}
+		vectors, err := Embed(texts)
+		if err != nil {
+			return err
+		}
+
+		model := EmbeddingModel()
+		rows := make([]models.FragmentEmbedding, len(batch))
+		for i, f := range batch {
+			rows[i] = models.FragmentEmbedding{
+				FragmentID:    f.ID,
+				ShellID:       f.ShellID,
+				Model:         model,
+				Vector:        vectors[i],
+				ContentVector: vectors[len(batch)+i],
+			}
+		}
+		if err := database.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "fragment_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"model", "vector", "content_vector", "created_at"}),
+		}).Create(&rows).Error; err != nil {
+			return fmt.Errorf("failed to store embeddings: %w", err)
+		}
+	}
+	return nil
+}
+
+// ensureShellEmbeddings embeds any accepted fragments of a shell that have
+// no embedding yet (or one produced by a different model).
+func ensureShellEmbeddings(shellID uuid.UUID) error {
+	var missing []models.Fragment
+	database.DB.Where("shell_id = ? AND status = ?", shellID, models.FragStatusAccepted).
+		Where("id NOT IN (?)", database.DB.Model(&models.FragmentEmbedding{}).
+			Select("fragment_id").Where("shell_id = ? AND model = ? AND content_vector IS NOT NULL", shellID, EmbeddingModel())).
+		Find(&missing)
+	if len(missing) == 0 {
+		return nil
+	}
+	util.Log.Debug("[retrieval] Embedding %d fragments for shell %s", len(missing), shellID)
+	return EmbedFragments(missing)
+}
+
+// fragmentEmbeddingBackfillLimit caps how many fragments one run embeds.
+const fragmentEmbeddingBackfillLimit = 500
+
+// StartFragmentEmbeddingBackfill periodically embeds accepted fragments whose
+// embedding is missing, made by a different model or lacks the content-only
+// vector, so plagiarism screening never has to embed a soul's backlog while
+// a batch waits for review.
+func StartFragmentEmbeddingBackfill(interval time.Duration) {
+	scheduleJob("fragment-embeddings", "Embed accepted fragments that have no current embedding", interval, true, backfillFragmentEmbeddings)
+	util.Log.Info("[retrieval] Fragment embedding backfill started (every %v)", interval)
+}
+
+func backfillFragmentEmbeddings() error {
+	var missing []models.Fragment
+	if err := database.DB.Where("status = ?", models.FragStatusAccepted).
+		Where("id NOT IN (?)", database.DB.Model(&models.FragmentEmbedding{}).
+			Select("fragment_id").Where("model = ? AND content_vector IS NOT NULL", EmbeddingModel())).
+		Order("created_at").Limit(fragmentEmbeddingBackfillLimit).
+		Find(&missing).Error; err != nil {
+		return fmt.Errorf("failed to load fragments to embed: %w", err)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := EmbedFragments(missing); err != nil {
+		return err
+	}
+	util.Log.Info("[retrieval] Embedded %d fragments", len(missing))
+	return nil
+}
+
+// RetrieveFragments returns the k accepted fragments of a shell most relevant
+// to the query, ordered by descending similarity.
+func RetrieveFragments(shellID uuid.UUID, query string, k int) ([]ScoredFragment, error) {
+	if k <= 0 || strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	if err := ensureShellEmbeddings(shellID); err != nil {
+		return nil, err
+	}
+
+	queryVecs, err := Embed([]string{query})
+	if err != nil {
+		return nil, err
+	}
+	queryVec := queryVecs[0]
+
+	var embeddings []models.FragmentEmbedding
+	database.DB.Where("shell_id = ? AND model = ?", shellID, EmbeddingModel()).Find(&embeddings)
+	if len(embeddings) == 0 {
+		return nil, nil
+	}
+
+	type scored struct {
+		id    uuid.UUID
+		score float64
+	}
+	ranked := make([]scored, 0, len(embeddings))
+	for _, e := range embeddings {
+		ranked = append(ranked, scored{id: e.FragmentID, score: CosineSimilarity(queryVec, e.Vector)})
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+
+	ids := make([]uuid.UUID, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.id
+	}
+	var fragments []models.Fragment
+	database.DB.Where("id IN ? AND status = ? AND subject_flag = ''", ids, models.FragStatusAccepted).Find(&fragments)
+	byID := make(map[uuid.UUID]models.Fragment, len(fragments))
+	for _, f := range fragments {
+		byID[f.ID] = f
+	}
+
+	results := make([]ScoredFragment, 0, len(ranked))
+	for _, r := range ranked {
+		if f, ok := byID[r.id]; ok {
+			results = append(results, ScoredFragment{Fragment: f, Score: r.score})
+		}
+	}
+	return results, nil
+}
+
+// buildRetrievedContext formats retrieved fragments as an extra system prompt
+// section. Fragments are numbered so the reply can cite them with [^n] markers
+// (see resolveCitations).
+func buildRetrievedContext(results []ScoredFragment) string {
+	if len(results) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n=== MOST RELEVANT FRAGMENTS FOR THIS MESSAGE ===\n")
+	sb.WriteString("These verified fragments were retrieved because they relate to what the user just said. Prefer their specific facts over general impressions.\n")
+	sb.WriteString("When a sentence states a fact taken from one of them, end it with that fragment's marker, e.g. [^2]. Only cite a fragment for what it actually says, and never explain the markers:\n\n")
+	for i, r := range results {
+		sb.WriteString(fmt.Sprintf("[^%d] (%s) %s\n\n", i+1, r.Fragment.Dimension, r.Fragment.Content))
+	}
+	return sb.String()
+}
+
+// citationMarker matches a [^n] citation marker in a reply.
+var citationMarker = regexp.MustCompile(`\[\^(\d{1,2})\]`)
+
+// resolveCitations maps the [^n] markers of a reply to the retrieved
+// fragments they number, in order of first use. Markers outside the
+// retrieved range are ignored.
+func resolveCitations(reply string, retrieved []ScoredFragment) models.ChatCitations {
+	var citations models.ChatCitations
+	seen := make(map[int]bool)
+	for _, m := range citationMarker.FindAllStringSubmatch(reply, -1) {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > len(retrieved) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		f := retrieved[n-1].Fragment
+		citations = append(citations, models.ChatCitation{
+			Marker:      n,
+			FragmentID:  f.ID,
+			Dimension:   f.Dimension,
+			ContentHash: f.ContentHash,
+		})
+	}
+	return citations
+}
+
+// embedAcceptedFragment embeds a freshly accepted fragment in the background
+// so retrieval doesn't pay the embedding cost on the first chat message.
+func embedAcceptedFragment(fragment models.Fragment) {
+	go func() {
+		if err := EmbedFragments([]models.Fragment{fragment}); err != nil {
+			util.Log.Warn("[retrieval] Failed to embed fragment %s: %v", fragment.ID, err)
+		}
+	}()
+}
@@ -10,19 +10,14 @@ import (
 
 // StartSessionCleanup periodically removes expired wallet sessions from the database.
 func StartSessionCleanup(interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for range ticker.C {
-			cleanExpiredSessions()
-		}
-	}()
+	scheduleJob("session-cleanup", "Delete expired wallet sessions", interval, false, cleanExpiredSessions)
 	util.Log.Info("[cleanup] Expired session cleanup started (every %v)", interval)
 }
 
-func cleanExpiredSessions() {
+func cleanExpiredSessions() error {
 	result := database.DB.Where("expires_at < ?", time.Now()).Delete(&models.WalletSession{})
 	if result.RowsAffected > 0 {
 		util.Log.Debug("[cleanup] Removed %d expired sessions", result.RowsAffected)
 	}
+	return result.Error
 }
@@ -1,18 +1,26 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/chain"
 	"github.com/ensoul-labs/ensoul-server/database"
 	"github.com/ensoul-labs/ensoul-server/models"
 	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
 )
 
+// txHashRegex matches a 0x-prefixed 32-byte transaction hash.
+var txHashRegex = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
 // handleRegex enforces Twitter-compatible handles: ASCII alphanumeric + underscore, 1-15 chars.
 var handleRegex = regexp.MustCompile(`^[a-zA-Z0-9_]{1,15}$`)
 
@@ -77,11 +85,14 @@ type SeedPreview struct {
 	SeedSummary string                          `json:"seed_summary"`
 	Dimensions  map[string]models.DimensionData `json:"dimensions"`
 	TwitterMeta map[string]interface{}          `json:"twitter_meta,omitempty"`
+	Tags        []string                        `json:"tags,omitempty"`
+	ExpiresAt   int64                           `json:"expires_at,omitempty"` // unix seconds, set by SignSeedPreview
+	Signature   string                          `json:"signature,omitempty"`  // server HMAC, checked on mint
 }
 
 // GenerateSeedPreview extracts seed data from a Twitter handle using LLM analysis.
 // Falls back to basic extraction if LLM is not configured.
-func GenerateSeedPreview(handle string) (*SeedPreview, error) {
+func GenerateSeedPreview(ctx context.Context, handle string) (*SeedPreview, error) {
 	// Fetch Twitter profile data
 	profile, err := FetchTwitterProfile(handle)
 	if err != nil {
@@ -89,7 +100,7 @@ func GenerateSeedPreview(handle string) (*SeedPreview, error) {
 	}
 
 	// If LLM is not configured, return basic preview from Twitter data only
-	if config.Cfg.LLMAPIKey == "" {
+	if !LLMConfigured() {
 		util.Log.Debug("[seed] LLM not configured, returning basic preview")
 		return &SeedPreview{
 			Handle:      handle,
@@ -145,6 +156,10 @@ with lower scores and honest summaries indicating limited information.`, handle,
 			profileExtra += fmt.Sprintf("Favourites: %d\n", profile.FavouritesCount)
 		}
 		profileExtra += fmt.Sprintf("Data Source: %s\n", profile.DataSource)
+		if lang := primaryLanguage(profile.Tweets); lang != "" && lang != "en" {
+			profileExtra += fmt.Sprintf("Primary Language: %s (read the tweets in the original; write your analysis in English, "+
+				"and describe how they write in %s under style)\n", LanguageName(lang), LanguageName(lang))
+		}
 
 		dataSection = fmt.Sprintf(`=== PROFILE ===
 Handle: @%s
@@ -178,9 +193,13 @@ For each dimension, provide:
 
 Also write a seed_summary: A comprehensive 2-4 sentence overview of this person.
 
+And pick 2-6 tags: the topics this person is known for, as lowercase words or
+hyphenated phrases (e.g. "crypto", "ai", "politics", "venture-capital", "football").
+
 Respond in JSON format ONLY:
 {
   "seed_summary": "...",
+  "tags": ["...", "..."],
   "dimensions": {
     "personality": {"score": 15, "summary": "..."},
     "knowledge": {"score": 12, "summary": "..."},
@@ -193,10 +212,11 @@ Respond in JSON format ONLY:
 
 	var result struct {
 		SeedSummary string                          `json:"seed_summary"`
+		Tags        []string                        `json:"tags"`
 		Dimensions  map[string]models.DimensionData `json:"dimensions"`
 	}
 
-	err = CallLLMJSON([]ChatMessage{
+	err = CallLLMJSON(ctx, LLMCallTag{Feature: models.LLMFeatureSeed, Mock: &MockInput{Handle: handle}}, []ChatMessage{
 		{Role: "system", Content: "You are a precise personality analysis engine. Output valid JSON only, no markdown."},
 		{Role: "user", Content: seedPrompt},
 	}, 2000, 0.3, &result)
@@ -229,6 +249,7 @@ Respond in JSON format ONLY:
 		SeedSummary: result.SeedSummary,
 		Dimensions:  result.Dimensions,
 		TwitterMeta: buildTwitterMeta(profile),
+		Tags:        seedShellTags(result.Tags),
 	}, nil
 }
 
@@ -269,6 +290,12 @@ func buildTwitterMeta(profile *TwitterProfile) map[string]interface{} {
 	if profile.FavouritesCount > 0 {
 		meta["favourites_count"] = profile.FavouritesCount
 	}
+	if lang := primaryLanguage(profile.Tweets); lang != "" {
+		meta["primary_language"] = lang
+	}
+	if latest, ok := latestTweetTime(profile.Tweets); ok {
+		meta["latest_tweet_at"] = latest.Format(time.RFC3339)
+	}
 	return meta
 }
 
@@ -278,12 +305,80 @@ const PendingMintTimeout = 30 * time.Minute
 // MintShell creates a new shell in the database with stage=pending.
 // The shell is only fully activated after ConfirmMint is called with a tx_hash.
 // If the same wallet retries the same handle (e.g. after a failed signing),
-// the old pending record is replaced.
+// the old pending record is replaced. The preview must be one signed by
+// SignSeedPreview for this handle and not yet expired.
 func MintShell(handle, ownerAddr string, preview *SeedPreview) (*models.Shell, error) {
+	if err := VerifySeedPreview(handle, preview); err != nil {
+		return nil, err
+	}
+	if err := reserveHandle(handle, ownerAddr); err != nil {
+		return nil, err
+	}
+
+	// Create shell record (pending until on-chain confirmation)
+	shell := shellFromPreview(handle, ownerAddr, models.StagePending, preview)
+	if err := database.DB.Create(shell).Error; err != nil {
+		return nil, fmt.Errorf("failed to create shell: %w", err)
+	}
+
+	util.Log.Info("[services] Shell @%s created in DB (owner: %s)", handle, ownerAddr)
+
+	return shell, nil
+}
+
+// shellFromPreview builds a new shell record from a seed preview.
+func shellFromPreview(handle, ownerAddr, stage string, preview *SeedPreview) *models.Shell {
+	// Build dimensions JSON
+	dims := make(models.JSON)
+	for k, v := range preview.Dimensions {
+		dims[k] = map[string]interface{}{
+			"score":   v.Score,
+			"summary": v.Summary,
+		}
+	}
+
+	// Build twitter_meta JSON
+	twitterMeta := models.JSON{}
+	for k, v := range preview.TwitterMeta {
+		twitterMeta[k] = v
+	}
+
+	cutoff := seedKnowledgeCutoff(preview.TwitterMeta)
+	return &models.Shell{
+		Handle:          handle,
+		OwnerAddr:       ownerAddr,
+		Stage:           stage,
+		DNAVersion:      1,
+		SeedSummary:     preview.SeedSummary,
+		Essence:         preview.SeedSummary, // until the first ensouling writes one
+		SoulPrompt:      withKnowledgeCutoff(buildInitialSoulPrompt(handle, preview.SeedSummary), cutoff),
+		Dimensions:      dims,
+		AvatarURL:       preview.AvatarURL,
+		DisplayName:     preview.DisplayName,
+		TwitterMeta:     twitterMeta,
+		Tags:            preview.Tags,
+		KnowledgeCutoff: cutoff,
+	}
+}
+
+// Errors for souls that can't be created for a handle or wallet.
+var (
+	ErrHandleTaken    = errors.New("already has a soul")
+	ErrHandleReserved = errors.New("is being minted by another user, please try again later")
+	ErrSoulLimit      = errors.New("mint quota used up")
+)
+
+// reserveHandle checks a new soul can be created for handle by ownerAddr,
+// clearing a pending reservation by the same wallet or an expired one, and
+// enforces the wallet's mint quota.
+func reserveHandle(handle, ownerAddr string) error {
 	// Check for existing shell
 	var existing models.Shell
 	if err := database.DB.Where("LOWER(handle) = ?", handle).First(&existing).Error; err == nil {
 		if existing.Stage == models.StagePending {
+			if custodialMintInFlight(&existing) {
+				return fmt.Errorf("@%s %w", handle, ErrCustodialMintInFlight)
+			}
 			// Same wallet retrying → cascade-delete old pending and re-create
 			if strings.EqualFold(existing.OwnerAddr, ownerAddr) {
 				HardDeleteShell(existing.ID)
@@ -295,63 +390,106 @@ func MintShell(handle, ownerAddr string, preview *SeedPreview) (*models.Shell, e
 					HardDeleteShell(existing.ID)
 					util.Log.Info("[services] Cleared expired pending shell @%s (was %s)", handle, existing.OwnerAddr)
 				} else {
-					return nil, fmt.Errorf("@%s is being minted by another user, please try again later", handle)
+					return fmt.Errorf("@%s %w", handle, ErrHandleReserved)
 				}
 			}
 		} else {
-			return nil, fmt.Errorf("a soul for @%s already exists", handle)
+			return fmt.Errorf("@%s %w", handle, ErrHandleTaken)
 		}
 	}
 
-	// Limit: each wallet can mint at most 5 confirmed shells (exclude pending)
-	var mintCount int64
-	database.DB.Model(&models.Shell{}).Where("owner_addr = ? AND stage != ?", ownerAddr, models.StagePending).Count(&mintCount)
-	if mintCount >= 5 {
-		return nil, fmt.Errorf("each wallet can mint at most 5 souls")
+	return checkMintQuota(ownerAddr)
+}
+
+// ErrMintPending is returned by ConfirmMint when the mint transaction is not
+// mined yet. The tx watcher confirms the shell once it is.
+var ErrMintPending = errors.New("mint transaction is not mined yet, the soul will be confirmed once it is")
+
+// ConfirmMint updates a shell record with on-chain data after the user mints.
+// Transitions the shell from pending → embryo.
+// Only the original minter wallet can confirm, and only pending shells can be confirmed.
+// The agentId is read from the transaction's Registered event; a client-supplied
+// agentID (0 = not supplied) must match it. If the transaction is not mined yet
+// it is handed to the tx watcher and ErrMintPending is returned.
+func ConfirmMint(handle, txHash string, clientAgentID uint64, walletAddr string) error {
+	if !txHashRegex.MatchString(txHash) {
+		return fmt.Errorf("invalid tx_hash")
 	}
 
-	// Build dimensions JSON
-	dims := make(models.JSON)
-	for k, v := range preview.Dimensions {
-		dims[k] = map[string]interface{}{
-			"score":   v.Score,
-			"summary": v.Summary,
-		}
+	// A mint transaction can confirm only one shell
+	var reused int64
+	database.DB.Model(&models.Shell{}).Where("LOWER(mint_tx_hash) = LOWER(?)", txHash).Count(&reused)
+	if reused > 0 {
+		return fmt.Errorf("tx %s has already been used to confirm a shell", txHash)
 	}
 
-	// Build twitter_meta JSON
-	twitterMeta := models.JSON{}
-	for k, v := range preview.TwitterMeta {
-		twitterMeta[k] = v
+	if chain.C() == nil {
+		util.Log.Warn("[services] Chain not initialized, trusting client agentId %d for tx %s", clientAgentID, txHash)
+		return applyMintConfirmation(handle, txHash, clientAgentID, walletAddr)
 	}
 
-	// Create shell record (pending until on-chain confirmation)
-	shell := &models.Shell{
-		Handle:      handle,
-		OwnerAddr:   ownerAddr,
-		Stage:       models.StagePending,
-		DNAVersion:  1,
-		SeedSummary: preview.SeedSummary,
-		SoulPrompt:  buildInitialSoulPrompt(handle, preview.SeedSummary),
-		Dimensions:  dims,
-		AvatarURL:   preview.AvatarURL,
-		DisplayName: preview.DisplayName,
-		TwitterMeta: twitterMeta,
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := chain.CheckMintTx(ctx, txHash); err != nil {
+		return err
+	}
+	receipt, err := chain.TxReceipt(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to look up tx %s: %w", txHash, err)
+	}
+	if receipt != nil {
+		return confirmMintReceipt(handle, txHash, clientAgentID, walletAddr, receipt)
 	}
 
-	if err := database.DB.Create(shell).Error; err != nil {
-		return nil, fmt.Errorf("failed to create shell: %w", err)
+	// Not mined yet: make sure there is something to confirm, then let the watcher finish
+	var watched int64
+	database.DB.Model(&models.PendingTx{}).Where("tx_hash = ? AND status = ?", txHash, models.PendingTxPending).Count(&watched)
+	if watched > 0 {
+		return ErrMintPending
 	}
+	var pending int64
+	database.DB.Model(&models.Shell{}).
+		Where("LOWER(handle) = ? AND stage = ? AND LOWER(owner_addr) = LOWER(?)", handle, models.StagePending, walletAddr).
+		Count(&pending)
+	if pending == 0 {
+		return mintConfirmError(handle)
+	}
+	if err := WatchTx(txHash, models.TxKindMintConfirm, handle, map[string]interface{}{
+		"wallet":   walletAddr,
+		"agent_id": clientAgentID,
+	}); err != nil {
+		return err
+	}
+	util.Log.Info("[services] Mint tx %s for @%s not mined yet, watching", txHash, handle)
+	return ErrMintPending
+}
 
-	util.Log.Info("[services] Shell @%s created in DB (owner: %s)", handle, ownerAddr)
+// onMintTxFinished confirms (or gives up on) a shell whose mint tx was watched.
+func onMintTxFinished(ptx *models.PendingTx, receipt *types.Receipt) error {
+	if receipt == nil {
+		return fmt.Errorf("mint tx for @%s was not mined in time, the shell stays pending", ptx.RefID)
+	}
+	wallet, _ := ptx.Payload["wallet"].(string)
+	agentID, _ := ptx.Payload["agent_id"].(float64) // JSON numbers decode as float64
+	return confirmMintReceipt(ptx.RefID, ptx.TxHash, uint64(agentID), wallet, receipt)
+}
 
-	return shell, nil
+// confirmMintReceipt verifies a mined mint transaction and confirms the shell.
+func confirmMintReceipt(handle, txHash string, clientAgentID uint64, walletAddr string, receipt *types.Receipt) error {
+	reg, err := chain.MintRegistration(txHash, receipt)
+	if err != nil {
+		return err
+	}
+	agentID, err := checkMintRegistration(reg, txHash, clientAgentID, walletAddr)
+	if err != nil {
+		return err
+	}
+	return applyMintConfirmation(handle, txHash, agentID, walletAddr)
 }
 
-// ConfirmMint updates a shell record with on-chain data after the user mints.
-// Transitions the shell from pending → embryo.
-// Only the original minter wallet can confirm, and only pending shells can be confirmed.
-func ConfirmMint(handle, txHash string, agentID uint64, walletAddr string) error {
+// applyMintConfirmation moves a pending shell to embryo with its on-chain identity.
+func applyMintConfirmation(handle, txHash string, agentID uint64, walletAddr string) error {
 	// Atomic update: only succeeds if stage is still pending AND wallet matches
 	result := database.DB.Model(&models.Shell{}).
 		Where("LOWER(handle) = ? AND stage = ? AND LOWER(owner_addr) = LOWER(?)", handle, models.StagePending, walletAddr).
@@ -364,20 +502,53 @@ func ConfirmMint(handle, txHash string, agentID uint64, walletAddr string) error
 		return fmt.Errorf("failed to update shell: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		// Check why: not found, wrong stage, or wrong wallet?
-		var shell models.Shell
-		if err := database.DB.Where("LOWER(handle) = ?", handle).First(&shell).Error; err != nil {
-			return fmt.Errorf("shell @%s not found", handle)
-		}
-		if shell.Stage != models.StagePending {
-			return fmt.Errorf("shell @%s is not in pending state (stage=%s)", handle, shell.Stage)
-		}
-		return fmt.Errorf("wallet mismatch: only the original minter can confirm")
+		return mintConfirmError(handle)
 	}
 	util.Log.Info("[services] Shell @%s confirmed on-chain: agentId=%d, tx=%s", handle, agentID, txHash)
+
+	var shell models.Shell
+	if database.DB.Where("LOWER(handle) = ?", handle).First(&shell).Error == nil {
+		recordStageChange(&shell, models.StagePending, models.StageEmbryo)
+	}
 	return nil
 }
 
+// mintConfirmError explains why a shell can't be confirmed by this wallet.
+func mintConfirmError(handle string) error {
+	// Check why: not found, wrong stage, or wrong wallet?
+	var shell models.Shell
+	if err := database.DB.Where("LOWER(handle) = ?", handle).First(&shell).Error; err != nil {
+		return fmt.Errorf("shell @%s not found", handle)
+	}
+	if shell.Stage != models.StagePending {
+		return fmt.Errorf("shell @%s is not in pending state (stage=%s)", handle, shell.Stage)
+	}
+	return fmt.Errorf("wallet mismatch: only the original minter can confirm")
+}
+
+// checkMintRegistration checks the Registered event's owner is the minting
+// wallet and returns the agentId it registered.
+func checkMintRegistration(reg *chain.Registration, txHash string, clientAgentID uint64, walletAddr string) (uint64, error) {
+	if !strings.EqualFold(reg.Owner.Hex(), walletAddr) {
+		util.Log.Warn("[services] Mint tx %s registered agent for %s, not minter %s", txHash, reg.Owner.Hex(), walletAddr)
+		return 0, fmt.Errorf("tx %s was not minted by wallet %s", txHash, walletAddr)
+	}
+	if !reg.AgentID.IsUint64() {
+		return 0, fmt.Errorf("unexpected agentId %s", reg.AgentID)
+	}
+	agentID := reg.AgentID.Uint64()
+	if clientAgentID != 0 && clientAgentID != agentID {
+		return 0, fmt.Errorf("agent_id %d does not match agentId %d registered in tx %s", clientAgentID, agentID, txHash)
+	}
+
+	var taken int64
+	database.DB.Model(&models.Shell{}).Where("agent_id = ?", agentID).Count(&taken)
+	if taken > 0 {
+		return 0, fmt.Errorf("agentId %d is already linked to another shell", agentID)
+	}
+	return agentID, nil
+}
+
 // CancelPendingMint removes a pending shell record when the on-chain mint fails.
 // Only the same wallet that created the pending record can cancel it.
 // Uses atomic SELECT + stage check to prevent TOCTOU race with ConfirmMint.
@@ -396,14 +567,83 @@ func CancelPendingMint(handle, walletAddr string) error {
 		}
 		return fmt.Errorf("only the original minter can cancel this pending shell")
 	}
+	if custodialMintInFlight(&shell) {
+		return fmt.Errorf("@%s %w and can't be cancelled", handle, ErrCustodialMintInFlight)
+	}
 
 	HardDeleteShell(shell.ID)
 	util.Log.Info("[services] Pending shell @%s cancelled by owner %s (chain mint failed)", handle, walletAddr)
 	return nil
 }
 
-// ListShells returns a paginated list of shells with optional filters.
-func ListShells(stage, sort, search, pageStr, limitStr string) (map[string]interface{}, error) {
+// ShellSummary is the slim list view of a soul: what an explore card shows,
+// without the dimension and Twitter JSON of the full record. Completion is
+// the mean dimension score the card's progress bar shows.
+type ShellSummary struct {
+	ID            uuid.UUID         `json:"id"`
+	Handle        string            `json:"handle"`
+	DisplayName   string            `json:"display_name"`
+	AvatarURL     string            `json:"avatar_url"`
+	Stage         string            `json:"stage"`
+	DNAVersion    int               `json:"dna_version"`
+	Completion    int               `json:"completion"`
+	TotalFrags    int               `json:"total_frags"`
+	AcceptedFrags int               `json:"accepted_frags"`
+	TotalClaws    int               `json:"total_claws"`
+	TotalChats    int               `json:"total_chats"`
+	Followers     int               `json:"followers"`
+	Tags          models.StringList `json:"tags"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// shellSummaryColumns loads only what ShellSummary needs; twitter_meta is
+// cut down to the follower count.
+func shellSummaryColumns() []string {
+	return []string{
+		"id", "handle", "display_name", "avatar_url", "stage", "dna_version", "dimensions",
+		"total_frags", "accepted_frags", "total_claws", "total_chats", "tags", "created_at",
+		database.JSONObject("twitter_meta", "followers_count") + " AS twitter_meta",
+	}
+}
+
+func shellSummary(shell *models.Shell) ShellSummary {
+	return ShellSummary{
+		ID:            shell.ID,
+		Handle:        shell.Handle,
+		DisplayName:   shell.DisplayName,
+		AvatarURL:     shell.AvatarURL,
+		Stage:         shell.Stage,
+		DNAVersion:    shell.DNAVersion,
+		Completion:    shellCompletion(shell),
+		TotalFrags:    shell.TotalFrags,
+		AcceptedFrags: shell.AcceptedFrags,
+		TotalClaws:    shell.TotalClaws,
+		TotalChats:    shell.TotalChats,
+		Followers:     getFollowers(*shell),
+		Tags:          shell.Tags,
+		CreatedAt:     shell.CreatedAt,
+	}
+}
+
+// shellCompletion is the rounded mean of a soul's dimension scores.
+func shellCompletion(shell *models.Shell) int {
+	dims := shell.GetDimensions()
+	if len(dims) == 0 {
+		return 0
+	}
+	total := 0
+	for _, d := range dims {
+		total += d.Score
+	}
+	return int(math.Round(float64(total) / float64(len(dims))))
+}
+
+// ListShells returns a paginated list of shells with optional filters; tags
+// keeps souls carrying all of the given tags. With slim set the shells are
+// ShellSummary values.
+// With an empty cursor it pages by offset; otherwise it continues after the
+// cursor (keyset pagination). Both modes return next_cursor.
+func ListShells(stage, sort, search string, tags []string, slim bool, pageStr, limitStr, cursorStr string) (map[string]interface{}, error) {
 	page, _ := strconv.Atoi(pageStr)
 	limit, _ := strconv.Atoi(limitStr)
 	if page < 1 {
@@ -414,56 +654,125 @@ func ListShells(stage, sort, search, pageStr, limitStr string) (map[string]inter
 	}
 	offset := (page - 1) * limit
 
-	query := database.DB.Model(&models.Shell{})
+	var cursor *listCursor
+	if cursorStr != "" {
+		c, err := decodeCursor(cursorStr)
+		if err != nil {
+			return nil, err
+		}
+		cursor = c
+	}
 
-	// Always exclude unconfirmed shells (pending or no tx_hash) from listings
-	query = query.Where("stage != ? AND mint_tx_hash != ''", models.StagePending)
+	// Public listing, served from the read replica when one is configured
+	query := database.ReadDB().Model(&models.Shell{})
+
+	// Always exclude unconfirmed shells (pending, or neither minted nor imported) from listings
+	query = query.Where("stage != ? AND "+models.ShellOnChainSQL, models.StagePending)
 
 	// Apply filters
 	if stage != "" && stage != "all" {
 		query = query.Where("stage = ?", stage)
 	}
 	if search != "" {
-		query = query.Where("handle ILIKE ?", "%"+search+"%")
+		query = query.Where("LOWER(handle) LIKE ?", "%"+strings.ToLower(search)+"%")
+	}
+	if len(tags) > 0 {
+		query = query.Where(database.JSONContainsAll("tags"), tagsJSON(tags))
 	}
 
-	// Count total
+	// Count total (offset mode only — keyset pages skip the extra query)
 	var total int64
-	query.Count(&total)
+	if cursor == nil {
+		query.Count(&total)
+	}
 
-	// Apply sorting
+	// Apply sorting; created_at and id break ties so pages are stable
+	keyColumn := ""
 	switch sort {
 	case "most_fragments":
-		query = query.Order("total_frags DESC")
+		keyColumn = "total_frags"
 	case "hot":
-		query = query.Order("total_chats DESC")
-	default: // "newest"
-		query = query.Order("created_at DESC")
+		keyColumn = "total_chats"
+	}
+	query, err := applyKeyset(query, "shells", keyColumn, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == nil {
+		query = query.Offset(offset)
+	}
+
+	if slim {
+		query = query.Select(shellSummaryColumns())
 	}
 
-	// Fetch results
+	// Fetch one extra row to know whether another page follows
 	var shells []models.Shell
-	if err := query.Offset(offset).Limit(limit).Find(&shells).Error; err != nil {
+	if err := query.Limit(limit + 1).Find(&shells).Error; err != nil {
 		return nil, err
 	}
 
-	// Strip soul_prompt from public listings — it's the core paid asset
-	for i := range shells {
-		shells[i].SoulPrompt = ""
+	nextCursor := ""
+	if len(shells) > limit {
+		shells = shells[:limit]
+		last := shells[limit-1]
+		next := listCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		switch keyColumn {
+		case "total_frags":
+			v := int64(last.TotalFrags)
+			next.Key = &v
+		case "total_chats":
+			v := int64(last.TotalChats)
+			next.Key = &v
+		}
+		nextCursor = encodeCursor(next)
+	}
+
+	var items interface{} = shells
+	if slim {
+		summaries := make([]ShellSummary, len(shells))
+		for i := range shells {
+			summaries[i] = shellSummary(&shells[i])
+		}
+		items = summaries
+	} else {
+		// Strip soul_prompt (the core paid asset) and seed_summary from public listings
+		for i := range shells {
+			RedactShell(&shells[i])
+		}
 	}
 
+	if cursor != nil {
+		return map[string]interface{}{
+			"shells":      items,
+			"limit":       limit,
+			"next_cursor": nextCursor,
+		}, nil
+	}
 	return map[string]interface{}{
-		"shells": shells,
-		"total":  total,
-		"page":   page,
-		"limit":  limit,
+		"shells":      items,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"next_cursor": nextCursor,
 	}, nil
 }
 
 // GetShellByHandle returns a single shell by its Twitter handle.
+// Falls back to previous handles of renamed shells; callers can compare
+// shell.Handle with the requested handle to detect a redirect.
 func GetShellByHandle(handle string) (*models.Shell, error) {
 	var shell models.Shell
-	if err := database.DB.Where("LOWER(handle) = ?", handle).First(&shell).Error; err != nil {
+	err := database.DB.Where("LOWER(handle) = ?", handle).First(&shell).Error
+	if err == nil {
+		return &shell, nil
+	}
+
+	var alias models.ShellAlias
+	if database.DB.Where("handle = ?", handle).First(&alias).Error != nil {
+		return nil, err
+	}
+	if err := database.DB.Where("id = ?", alias.ShellID).First(&shell).Error; err != nil {
 		return nil, err
 	}
 	return &shell, nil
@@ -486,7 +795,8 @@ func GetShellHistory(handle string) ([]models.Ensouling, error) {
 	}
 
 	var history []models.Ensouling
-	if err := database.DB.Where("shell_id = ?", shell.ID).Order("created_at DESC").Find(&history).Error; err != nil {
+	if err := database.DB.Where("shell_id = ? AND status = ?", shell.ID, models.EnsoulingDeployed).
+		Order("created_at DESC").Find(&history).Error; err != nil {
 		return nil, err
 	}
 
@@ -528,7 +838,8 @@ func UpdateShellStage(shell *models.Shell) {
 
 	// Count ensouling events
 	var ensoulingCount int64
-	database.DB.Model(&models.Ensouling{}).Where("shell_id = ?", shell.ID).Count(&ensoulingCount)
+	database.DB.Model(&models.Ensouling{}).
+		Where("shell_id = ? AND status = ?", shell.ID, models.EnsoulingDeployed).Count(&ensoulingCount)
 
 	switch {
 	case ensoulingCount >= 3:
@@ -542,6 +853,10 @@ func UpdateShellStage(shell *models.Shell) {
 	}
 
 	if shell.Stage != oldStage {
-		database.DB.Model(shell).Update("stage", shell.Stage)
+		// Conditional on the old stage so concurrent updates record one transition
+		res := database.DB.Model(&models.Shell{}).Where("id = ? AND stage = ?", shell.ID, oldStage).Update("stage", shell.Stage)
+		if res.Error == nil && res.RowsAffected > 0 {
+			recordStageChange(shell, oldStage, shell.Stage)
+		}
 	}
 }
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+// MaxChainStateHandles bounds one GetShellsChainState request.
+const MaxChainStateHandles = 50
+
+// ShellChainState is what a soul list shows from the chain for one soul.
+type ShellChainState struct {
+	Handle     string             `json:"handle"`
+	AgentID    uint64             `json:"agent_id"`
+	Exists     bool               `json:"exists"` // false once the NFT is burned
+	Owner      string             `json:"owner,omitempty"`
+	AgentURI   string             `json:"agent_uri,omitempty"`
+	Reputation *ReputationSummary `json:"reputation,omitempty"` // nil if no Claw wallet contributed
+	ReadAt     time.Time          `json:"read_at"`
+}
+
+// reputationTotalCache holds overall reputation summaries read for soul
+// lists, for REPUTATION_CACHE_SECONDS like reputationCache.
+var reputationTotalCache = struct {
+	sync.Mutex
+	entries map[uuid.UUID]cachedReputationTotal
+}{entries: make(map[uuid.UUID]cachedReputationTotal)}
+
+type cachedReputationTotal struct {
+	summary  *ReputationSummary
+	cachedAt time.Time
+}
+
+// GetShellsChainState returns the owner, agentURI and overall reputation of
+// many minted souls with a handful of batched chain reads instead of several
+// per soul. Unknown or unminted handles are left out. Owners and URIs may
+// be up to CHAIN_READ_CACHE_SECONDS old.
+func GetShellsChainState(ctx context.Context, handles []string) ([]ShellChainState, error) {
+	if chain.C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+	if len(handles) > MaxChainStateHandles {
+		return nil, fmt.Errorf("at most %d handles per request", MaxChainStateHandles)
+	}
+
+	var shells []models.Shell
+	if err := database.DB.Where("LOWER(handle) IN ? AND agent_id IS NOT NULL", handles).Find(&shells).Error; err != nil {
+		return nil, fmt.Errorf("failed to load souls: %w", err)
+	}
+	if len(shells) == 0 {
+		return []ShellChainState{}, nil
+	}
+
+	ids := make([]uint64, len(shells))
+	for i, shell := range shells {
+		ids[i] = *shell.AgentID
+	}
+	souls, err := chain.ReadSouls(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	reputations, err := readReputationTotals(ctx, shells)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]ShellChainState, len(shells))
+	for i, shell := range shells {
+		soul := souls[*shell.AgentID]
+		states[i] = ShellChainState{
+			Handle:     shell.Handle,
+			AgentID:    *shell.AgentID,
+			Exists:     soul.Exists,
+			AgentURI:   soul.URI,
+			Reputation: reputations[shell.ID],
+			ReadAt:     soul.ReadAt,
+		}
+		if soul.Exists {
+			states[i].Owner = soul.Owner.Hex()
+		}
+	}
+	return states, nil
+}
+
+// readReputationTotals returns the overall reputation of each soul, from
+// GetShellReputation's cache, the list cache, or one batched read for the rest.
+func readReputationTotals(ctx context.Context, shells []models.Shell) (map[uuid.UUID]*ReputationSummary, error) {
+	ttl := time.Duration(config.Cfg.ReputationCacheSeconds) * time.Second
+	totals := make(map[uuid.UUID]*ReputationSummary, len(shells))
+
+	var stale []models.Shell
+	reputationCache.Lock()
+	reputationTotalCache.Lock()
+	for _, shell := range shells {
+		if rep := reputationCache.entries[shell.ID]; rep != nil && time.Since(rep.CachedAt) < ttl {
+			if rep.Clients > 0 {
+				summary := rep.ReputationSummary
+				totals[shell.ID] = &summary
+			}
+		} else if cached, ok := reputationTotalCache.entries[shell.ID]; ok && time.Since(cached.cachedAt) < ttl {
+			totals[shell.ID] = cached.summary
+		} else {
+			stale = append(stale, shell)
+		}
+	}
+	reputationTotalCache.Unlock()
+	reputationCache.Unlock()
+	if len(stale) == 0 {
+		return totals, nil
+	}
+
+	staleIDs := make([]uuid.UUID, len(stale))
+	for i, shell := range stale {
+		staleIDs[i] = shell.ID
+	}
+	var rows []struct {
+		ShellID    uuid.UUID
+		WalletAddr string
+	}
+	if err := database.DB.Table("fragments").
+		Select("DISTINCT fragments.shell_id, claws.wallet_addr").
+		Joins("JOIN claws ON claws.id = fragments.claw_id").
+		Where("fragments.shell_id IN ? AND claws.wallet_addr <> ''", staleIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load contributor wallets: %w", err)
+	}
+	clients := make(map[uuid.UUID][]common.Address)
+	for _, r := range rows {
+		clients[r.ShellID] = append(clients[r.ShellID], common.HexToAddress(r.WalletAddr))
+	}
+
+	// getSummary requires at least one client address
+	var queried []models.Shell
+	var queries []chain.SummaryQuery
+	for _, shell := range stale {
+		if len(clients[shell.ID]) == 0 {
+			continue
+		}
+		queried = append(queried, shell)
+		queries = append(queries, chain.SummaryQuery{
+			AgentID: new(big.Int).SetUint64(*shell.AgentID),
+			Clients: clients[shell.ID],
+		})
+	}
+	results, err := chain.ReadReputationSummaries(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	reputationTotalCache.Lock()
+	defer reputationTotalCache.Unlock()
+	for _, shell := range stale {
+		reputationTotalCache.entries[shell.ID] = cachedReputationTotal{cachedAt: now}
+	}
+	for i, shell := range queried {
+		summary := reputationSummary(results[i])
+		totals[shell.ID] = &summary
+		reputationTotalCache.entries[shell.ID] = cachedReputationTotal{summary: &summary, cachedAt: now}
+	}
+	return totals, nil
+}
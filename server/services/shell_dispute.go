@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Dispute reason length bounds, in characters.
+const (
+	disputeReasonMin = 20
+	disputeReasonMax = 2000
+)
+
+// Dispute resolution actions.
+const (
+	DisputeActionTransfer = "transfer"
+	DisputeActionRetire   = "retire"
+	DisputeActionDismiss  = "dismiss"
+)
+
+// Errors for handle disputes.
+var (
+	ErrDisputeOpen        = errors.New("this soul already has an open dispute")
+	ErrDisputeNotFound    = errors.New("dispute not found")
+	ErrDisputeResolved    = errors.New("dispute was already resolved")
+	ErrDisputeOwnSoul     = errors.New("you already own this soul")
+	ErrInvalidDisputeSpec = errors.New("invalid dispute")
+)
+
+// FileHandleDispute records the verified subject's objection to the soul's
+// owner and puts the soul in the disputed state: chats, fragments, renames
+// and retirement are refused until an admin resolves the dispute.
+func FileHandleDispute(shell *models.Shell, wallet, reason string) (*models.HandleDispute, error) {
+	if !IsShellSubject(shell, wallet) {
+		return nil, ErrNotSubject
+	}
+	if strings.EqualFold(shell.OwnerAddr, wallet) {
+		return nil, ErrDisputeOwnSoul
+	}
+	if err := checkShellActive(shell); err != nil && !errors.Is(err, ErrRegistryPaused) {
+		if errors.Is(err, ErrShellDisputed) {
+			return nil, ErrDisputeOpen
+		}
+		return nil, err
+	}
+	reason = strings.TrimSpace(reason)
+	if n := len([]rune(reason)); n < disputeReasonMin || n > disputeReasonMax {
+		return nil, fmt.Errorf("%w: reason must be %d-%d characters", ErrInvalidDisputeSpec, disputeReasonMin, disputeReasonMax)
+	}
+
+	now := time.Now()
+	dispute := &models.HandleDispute{
+		ShellID:   shell.ID,
+		Handle:    shell.Handle,
+		FiledBy:   wallet,
+		OwnerAddr: shell.OwnerAddr,
+		Reason:    reason,
+		Status:    models.DisputeOpen,
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Shell{}).Where("id = ? AND disputed_at IS NULL", shell.ID).Update("disputed_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrDisputeOpen // filed concurrently
+		}
+		return tx.Create(dispute).Error
+	})
+	if errors.Is(err, ErrDisputeOpen) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to file dispute: %w", err)
+	}
+	shell.DisputedAt = &now
+
+	util.Log.Info("[dispute] @%s disputed by its subject %s (owner %s)", shell.Handle, wallet, shell.OwnerAddr)
+	go EmitWebhookEvent(models.WebhookEventDisputed, &shell.ID, map[string]interface{}{
+		"handle":     shell.Handle,
+		"dispute_id": dispute.ID,
+		"status":     dispute.Status,
+	})
+	return dispute, nil
+}
+
+// ListShellDisputes returns a soul's disputes, newest first.
+func ListShellDisputes(shell *models.Shell) ([]models.HandleDispute, error) {
+	var disputes []models.HandleDispute
+	err := database.DB.Where("shell_id = ?", shell.ID).Order("created_at DESC").Find(&disputes).Error
+	return disputes, err
+}
+
+// ListHandleDisputes returns disputes with the given status ("" for all),
+// oldest first so the queue is worked in order.
+func ListHandleDisputes(status string, limit int) ([]models.HandleDispute, error) {
+	q := database.DB.Order("created_at ASC").Limit(limit)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var disputes []models.HandleDispute
+	err := q.Find(&disputes).Error
+	return disputes, err
+}
+
+// ResolveHandleDispute closes an open dispute:
+//   - transfer makes the subject the soul's owner on Ensoul (the NFT itself
+//     moves only if its holder transfers it);
+//   - retire retires the soul as if its owner had;
+//   - dismiss leaves the soul with its owner.
+//
+// The soul leaves the disputed state in every case, and the admin and note
+// are kept on the dispute.
+func ResolveHandleDispute(id, action, note, admin string) (*models.HandleDispute, error) {
+	var status string
+	switch action {
+	case DisputeActionTransfer:
+		status = models.DisputeTransferred
+	case DisputeActionRetire:
+		status = models.DisputeRetired
+	case DisputeActionDismiss:
+		status = models.DisputeDismissed
+	default:
+		return nil, fmt.Errorf("%w: action must be transfer, retire or dismiss", ErrInvalidDisputeSpec)
+	}
+	note = strings.TrimSpace(note)
+	if len([]rune(note)) > disputeReasonMax {
+		return nil, fmt.Errorf("%w: note too long (max %d characters)", ErrInvalidDisputeSpec, disputeReasonMax)
+	}
+	disputeID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrDisputeNotFound
+	}
+
+	var dispute models.HandleDispute
+	if err := database.DB.First(&dispute, "id = ?", disputeID).Error; err != nil {
+		return nil, ErrDisputeNotFound
+	}
+	if dispute.Status != models.DisputeOpen {
+		return nil, ErrDisputeResolved
+	}
+	var shell models.Shell
+	if err := database.DB.First(&shell, "id = ?", dispute.ShellID).Error; err != nil {
+		return nil, ErrShellNotFound
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status": status, "resolution": note, "resolved_by": admin, "resolved_at": now,
+	}
+	if action == DisputeActionTransfer {
+		updates["new_owner_addr"] = dispute.FiledBy
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&dispute).Where("status = ?", models.DisputeOpen).Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrDisputeResolved // decided concurrently
+		}
+		shellUpdates := map[string]interface{}{"disputed_at": nil}
+		if action == DisputeActionTransfer {
+			shellUpdates["owner_addr"] = dispute.FiledBy
+		}
+		return tx.Model(&shell).Updates(shellUpdates).Error
+	})
+	if errors.Is(err, ErrDisputeResolved) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve dispute: %w", err)
+	}
+	shell.DisputedAt = nil
+	dispute.Status, dispute.Resolution, dispute.ResolvedBy, dispute.ResolvedAt = status, note, admin, &now
+	if action == DisputeActionTransfer {
+		dispute.NewOwnerAddr = dispute.FiledBy
+	}
+
+	if action == DisputeActionRetire {
+		if _, err := RetireShell(&shell); err != nil && !errors.Is(err, ErrShellRetired) {
+			util.Log.Error("[dispute] Dispute %s resolved but retiring @%s failed: %v", dispute.ID, shell.Handle, err)
+		}
+	}
+
+	util.Log.Info("[dispute] Dispute %s over @%s resolved by %s: %s", dispute.ID, shell.Handle, admin, status)
+	go EmitWebhookEvent(models.WebhookEventDisputed, &shell.ID, map[string]interface{}{
+		"handle":     shell.Handle,
+		"dispute_id": dispute.ID,
+		"status":     dispute.Status,
+	})
+	return &dispute, nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// shellFullCacheTTL is how long an assembled soul page is served from memory.
+const shellFullCacheTTL = 30 * time.Second
+
+// ShellFull is everything public about a soul in one response: what the soul
+// page otherwise fetches from the detail, dimensions, history, contributors
+// and reputation endpoints.
+type ShellFull struct {
+	Shell          *models.Shell                   `json:"shell"`
+	RegistryPaused bool                            `json:"registry_paused"`
+	Dimensions     map[string]models.DimensionData `json:"dimensions"`
+	History        []models.Ensouling              `json:"history"`
+	Contributors   []map[string]interface{}        `json:"contributors"`
+	Reputation     *ShellReputation                `json:"reputation"` // null if the chain can't be read
+	GeneratedAt    time.Time                       `json:"generated_at"`
+}
+
+// shellFullEntry is a cached, already encoded ShellFull.
+type shellFullEntry struct {
+	body      []byte
+	etag      string
+	version   int       // DNA version it was built at
+	updatedAt time.Time // shell.UpdatedAt it was built at
+	builtAt   time.Time
+}
+
+var shellFullCache = struct {
+	sync.Mutex
+	entries map[uuid.UUID]*shellFullEntry
+}{entries: make(map[uuid.UUID]*shellFullEntry)}
+
+// GetShellFull returns the encoded ShellFull of a public soul and its ETag.
+// Entries are rebuilt after shellFullCacheTTL, or sooner when the soul is
+// ensouled or edited.
+func GetShellFull(ctx context.Context, shell *models.Shell) ([]byte, string, error) {
+	shellFullCache.Lock()
+	cached := shellFullCache.entries[shell.ID]
+	shellFullCache.Unlock()
+	if cached != nil && time.Since(cached.builtAt) < shellFullCacheTTL &&
+		cached.version == shell.DNAVersion && cached.updatedAt.Equal(shell.UpdatedAt) {
+		return cached.body, cached.etag, nil
+	}
+
+	full, err := buildShellFull(ctx, shell)
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := json.Marshal(full)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode soul @%s: %w", shell.Handle, err)
+	}
+
+	// The ETag covers the content only, so an unchanged rebuild still matches
+	hashed := *full
+	hashed.GeneratedAt = time.Time{}
+	if full.Reputation != nil {
+		rep := *full.Reputation
+		rep.CachedAt = time.Time{}
+		hashed.Reputation = &rep
+	}
+	stable, _ := json.Marshal(hashed)
+	entry := &shellFullEntry{
+		body:      body,
+		etag:      `"` + util.HashContent(string(stable))[:32] + `"`,
+		version:   shell.DNAVersion,
+		updatedAt: shell.UpdatedAt,
+		builtAt:   time.Now(),
+	}
+
+	shellFullCache.Lock()
+	shellFullCache.entries[shell.ID] = entry
+	shellFullCache.Unlock()
+	return entry.body, entry.etag, nil
+}
+
+func buildShellFull(ctx context.Context, shell *models.Shell) (*ShellFull, error) {
+	history, err := GetShellHistory(shell.Handle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load history of @%s: %w", shell.Handle, err)
+	}
+	contributors, err := GetShellContributors(shell.Handle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contributors of @%s: %w", shell.Handle, err)
+	}
+
+	rep, err := GetShellReputation(ctx, shell)
+	if err != nil {
+		util.Log.Debug("[shell] Reputation of @%s unavailable for the full view: %v", shell.Handle, err)
+	}
+
+	public := *shell
+	RedactShell(&public)
+	return &ShellFull{
+		Shell:          &public,
+		RegistryPaused: RegistryPaused(),
+		Dimensions:     shell.GetDimensions(),
+		History:        history,
+		Contributors:   contributors,
+		Reputation:     rep,
+		GeneratedAt:    time.Now(),
+	}, nil
+}
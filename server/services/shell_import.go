@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// agentURIMaxBytes caps the size of a registration file fetched for an import.
+const agentURIMaxBytes = 256 << 10
+
+// Errors for agents that can't be imported as souls.
+var (
+	ErrAgentNotFound      = errors.New("agent does not exist on the Identity Registry")
+	ErrAgentNotOwned      = errors.New("the signing wallet does not own this agent")
+	ErrAgentAlreadyLinked = errors.New("agent is already linked to a soul")
+	ErrAgentRegistration  = errors.New("agent registration file could not be read")
+	ErrAgentNoHandle      = errors.New("no valid Twitter handle given or found in the agent's registration file")
+)
+
+// agentURIClient fetches registration files from user-controlled URLs, with
+// the same private-address protection as webhook deliveries.
+var agentURIClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: publicAddressOnly,
+		}).DialContext,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// ImportShell creates a soul for an agent registered on the Identity Registry
+// outside Ensoul. walletAddr must own the agent NFT; the soul is bound to the
+// existing agentId instead of minting a new one. The handle comes from the
+// request (already validated), or else from the registration file (ensoul.handle, then an
+// x.com/twitter.com service URL).
+func ImportShell(ctx context.Context, agentID uint64, walletAddr, handle string) (*models.Shell, error) {
+	if chain.C() == nil {
+		return nil, fmt.Errorf("chain client not initialized")
+	}
+	id := new(big.Int).SetUint64(agentID)
+
+	exists, err := chain.SoulExists(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up agent %d: %w", agentID, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("agent %d: %w", agentID, ErrAgentNotFound)
+	}
+	owner, err := chain.ReadSoulOwner(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read owner of agent %d: %w", agentID, err)
+	}
+	if !strings.EqualFold(owner.Hex(), walletAddr) {
+		return nil, fmt.Errorf("agent %d: %w", agentID, ErrAgentNotOwned)
+	}
+
+	var linked int64
+	database.DB.Model(&models.Shell{}).Where("agent_id = ?", agentID).Count(&linked)
+	if linked > 0 {
+		return nil, fmt.Errorf("agent %d: %w", agentID, ErrAgentAlreadyLinked)
+	}
+
+	agentURI, err := chain.ReadSoulURI(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agentURI of agent %d: %w", agentID, err)
+	}
+	reg, err := FetchAgentRegistration(ctx, agentURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if handle == "" {
+		found, err := ValidateHandle(registrationHandle(reg))
+		if err != nil {
+			return nil, ErrAgentNoHandle
+		}
+		handle = found
+	}
+	if err := reserveHandle(handle, walletAddr); err != nil {
+		return nil, err
+	}
+
+	preview, err := GenerateSeedPreview(ctx, handle)
+	if err != nil {
+		util.Log.Warn("[import] Seed preview for @%s failed, using the registration file: %v", handle, err)
+		preview = registrationPreview(handle, reg)
+	}
+	if preview.AvatarURL == "" {
+		preview.AvatarURL = reg.Image
+	}
+
+	now := time.Now()
+	shell := shellFromPreview(handle, walletAddr, models.StageEmbryo, preview)
+	shell.AgentID = &agentID
+	shell.AgentURI = agentURI
+	shell.ImportedAt = &now
+	// The unique indexes on agent_id and handle settle concurrent imports
+	if err := database.DB.Create(shell).Error; err != nil {
+		if database.IsUniqueViolation(err, database.ShellAgentIndex) {
+			return nil, fmt.Errorf("agent %d: %w", agentID, ErrAgentAlreadyLinked)
+		}
+		if database.IsUniqueViolation(err, "idx_shells_handle") {
+			return nil, fmt.Errorf("@%s %w", handle, ErrHandleTaken)
+		}
+		return nil, fmt.Errorf("failed to create shell: %w", err)
+	}
+
+	util.Log.Info("[import] Agent %d imported as soul @%s (owner: %s)", agentID, handle, walletAddr)
+	recordStageChange(shell, models.StagePending, models.StageEmbryo)
+	return shell, nil
+}
+
+// FetchAgentRegistration reads and parses the ERC-8004 registration file an
+// agentURI points to. Supports data: URIs (base64 or percent-encoded),
+// http(s):// (https only in production) and ipfs:// via IPFS_GATEWAY.
+func FetchAgentRegistration(ctx context.Context, agentURI string) (*chain.AgentRegistrationFile, error) {
+	raw, err := readAgentURI(ctx, agentURI)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAgentRegistration, err)
+	}
+	var reg chain.AgentRegistrationFile
+	if err := json.Unmarshal(raw, &reg); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON: %v", ErrAgentRegistration, err)
+	}
+	return &reg, nil
+}
+
+func readAgentURI(ctx context.Context, agentURI string) ([]byte, error) {
+	switch {
+	case agentURI == "":
+		return nil, fmt.Errorf("agent has no agentURI")
+	case strings.HasPrefix(agentURI, "data:"):
+		meta, data, ok := strings.Cut(strings.TrimPrefix(agentURI, "data:"), ",")
+		if !ok {
+			return nil, fmt.Errorf("malformed data URI")
+		}
+		if strings.HasSuffix(meta, ";base64") {
+			return base64.StdEncoding.DecodeString(data)
+		}
+		s, err := url.PathUnescape(data)
+		return []byte(s), err
+	case strings.HasPrefix(agentURI, "ipfs://"):
+		gateway := strings.TrimSuffix(config.Cfg.IPFSGateway, "/") + "/"
+		return fetchAgentURL(ctx, gateway+strings.TrimPrefix(strings.TrimPrefix(agentURI, "ipfs://"), "ipfs/"))
+	}
+
+	u, err := url.Parse(agentURI)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("unsupported agentURI scheme")
+	}
+	if u.Scheme != "https" && config.Cfg.IsProduction() {
+		return nil, fmt.Errorf("agentURI must use https")
+	}
+	return fetchAgentURL(ctx, agentURI)
+}
+
+func fetchAgentURL(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := agentURIClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %d", rawURL, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, agentURIMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > agentURIMaxBytes {
+		return nil, fmt.Errorf("registration file exceeds %d bytes", agentURIMaxBytes)
+	}
+	return body, nil
+}
+
+// registrationHandle finds the Twitter handle a registration file declares:
+// ensoul.handle, or the first x.com / twitter.com service URL.
+func registrationHandle(reg *chain.AgentRegistrationFile) string {
+	if h, ok := reg.Ensoul["handle"].(string); ok && h != "" {
+		return h
+	}
+	for _, svc := range reg.Services {
+		u, err := url.Parse(svc.URL)
+		if err != nil {
+			continue
+		}
+		switch strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.") {
+		case "x.com", "twitter.com":
+			if h, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/"); h != "" {
+				return h
+			}
+		}
+	}
+	return ""
+}
+
+// registrationPreview is the seed used when the Twitter profile can't be
+// analyzed: the agent's own name and description, with dimensions left for
+// fragments to fill in.
+func registrationPreview(handle string, reg *chain.AgentRegistrationFile) *SeedPreview {
+	summary := strings.TrimSpace(reg.Description)
+	if summary == "" {
+		summary = fmt.Sprintf("Agent @%s, imported from the ERC-8004 Identity Registry.", handle)
+	}
+	dims := make(map[string]models.DimensionData, len(validDimensions))
+	for dim := range validDimensions {
+		dims[dim] = models.DimensionData{Summary: "Initial assessment pending fragments"}
+	}
+	return &SeedPreview{
+		Handle:      handle,
+		DisplayName: reg.Name,
+		AvatarURL:   reg.Image,
+		SeedSummary: summary,
+		Dimensions:  dims,
+	}
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	licenseMaxDays     = 365
+	licenseMaxPerShell = 100 // licenses that are not revoked
+)
+
+var (
+	// ErrLicenseNotFound is returned when a license doesn't exist for the shell and wallet.
+	ErrLicenseNotFound = errors.New("license not found")
+	// ErrLicenseInactive is returned when a license is unpaid, revoked or expired.
+	ErrLicenseInactive = errors.New("license is not active")
+	// ErrLicenseTxReused is returned when a payment tx already paid a license.
+	ErrLicenseTxReused = errors.New("this transaction has already been used to pay a license")
+
+	errLicenseChanged = errors.New("license was changed concurrently, please retry")
+)
+
+// licenseAnchors holds the IDs of licenses whose receipt is being written on-chain.
+var licenseAnchors sync.Map
+
+// CreateShellLicense grants licensee read access to the soul prompt for
+// durationDays. A zero price activates the license right away; otherwise it
+// activates once the licensee's payment to the owner is verified on-chain.
+func CreateShellLicense(shell *models.Shell, owner, licensee string, durationDays int, priceWei string) (*models.ShellLicense, error) {
+	if !common.IsHexAddress(licensee) {
+		return nil, fmt.Errorf("invalid licensee wallet address")
+	}
+	licensee = common.HexToAddress(licensee).Hex()
+	if strings.EqualFold(licensee, shell.OwnerAddr) {
+		return nil, fmt.Errorf("the owner doesn't need a license")
+	}
+	if durationDays < 1 || durationDays > licenseMaxDays {
+		return nil, fmt.Errorf("duration_days must be 1-%d", licenseMaxDays)
+	}
+	if priceWei == "" {
+		priceWei = "0"
+	}
+	price, ok := new(big.Int).SetString(priceWei, 10)
+	if !ok || price.Sign() < 0 {
+		return nil, fmt.Errorf("price_wei must be a non-negative integer")
+	}
+
+	var open int64
+	database.DB.Model(&models.ShellLicense{}).Where("shell_id = ? AND status <> ?", shell.ID, models.LicenseStatusRevoked).Count(&open)
+	if open >= licenseMaxPerShell {
+		return nil, fmt.Errorf("a soul can have at most %d licenses", licenseMaxPerShell)
+	}
+	var existing int64
+	database.DB.Model(&models.ShellLicense{}).
+		Where("shell_id = ? AND LOWER(licensee) = LOWER(?)", shell.ID, licensee).
+		Where("status = ? OR (status = ? AND expires_at > ?)",
+			models.LicenseStatusAwaitingPayment, models.LicenseStatusActive, time.Now()).
+		Count(&existing)
+	if existing > 0 {
+		return nil, fmt.Errorf("%s already holds an open license for @%s", licensee, shell.Handle)
+	}
+
+	license := &models.ShellLicense{
+		ShellID:      shell.ID,
+		Licensee:     licensee,
+		GrantedBy:    owner,
+		PriceWei:     price.String(),
+		DurationDays: durationDays,
+		Status:       models.LicenseStatusAwaitingPayment,
+	}
+	if price.Sign() == 0 {
+		activateLicense(license, time.Now())
+	}
+	if err := database.DB.Create(license).Error; err != nil {
+		return nil, fmt.Errorf("failed to create license: %w", err)
+	}
+
+	util.Log.Info("[license] @%s licensed to %s for %d days (price %s wei, %s)",
+		shell.Handle, licensee, durationDays, license.PriceWei, license.Status)
+	return license, nil
+}
+
+func activateLicense(license *models.ShellLicense, now time.Time) {
+	expires := now.AddDate(0, 0, license.DurationDays)
+	license.Status = models.LicenseStatusActive
+	license.ActivatedAt = &now
+	license.ExpiresAt = &expires
+}
+
+// ListShellLicenses returns all licenses of a shell, newest first.
+func ListShellLicenses(shell *models.Shell) ([]models.ShellLicense, error) {
+	var licenses []models.ShellLicense
+	if err := database.DB.Where("shell_id = ?", shell.ID).Order("created_at DESC").Find(&licenses).Error; err != nil {
+		return nil, err
+	}
+	return licenses, nil
+}
+
+// RevokeShellLicense ends a license immediately.
+func RevokeShellLicense(shell *models.Shell, licenseID string) error {
+	id, err := uuid.Parse(licenseID)
+	if err != nil {
+		return ErrLicenseNotFound
+	}
+	res := database.DB.Model(&models.ShellLicense{}).
+		Where("id = ? AND shell_id = ? AND status <> ?", id, shell.ID, models.LicenseStatusRevoked).
+		Update("status", models.LicenseStatusRevoked)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLicenseNotFound
+	}
+	return nil
+}
+
+// findLicense loads a license of the shell held by wallet.
+func findLicense(shell *models.Shell, licenseID, wallet string) (*models.ShellLicense, error) {
+	id, err := uuid.Parse(licenseID)
+	if err != nil {
+		return nil, ErrLicenseNotFound
+	}
+	var license models.ShellLicense
+	if err := database.DB.Where("id = ? AND shell_id = ? AND LOWER(licensee) = LOWER(?)", id, shell.ID, wallet).
+		First(&license).Error; err != nil {
+		return nil, ErrLicenseNotFound
+	}
+	return &license, nil
+}
+
+// PayShellLicense activates a paid license after verifying on-chain that the
+// licensee sent at least the price to the owner's wallet in txHash.
+func PayShellLicense(shell *models.Shell, licenseID, wallet, txHash string) (*models.ShellLicense, error) {
+	license, err := findLicense(shell, licenseID, wallet)
+	if err != nil {
+		return nil, err
+	}
+	if license.Status != models.LicenseStatusAwaitingPayment {
+		return nil, fmt.Errorf("license is %s, not awaiting payment", license.Status)
+	}
+	if !txHashRegex.MatchString(txHash) {
+		return nil, fmt.Errorf("invalid transaction hash")
+	}
+	txHash = strings.ToLower(txHash)
+	if chain.C() == nil {
+		return nil, fmt.Errorf("on-chain payment verification is unavailable")
+	}
+
+	var reused int64
+	database.DB.Model(&models.ShellLicense{}).Where("payment_tx_hash = ?", txHash).Count(&reused)
+	if reused > 0 {
+		return nil, ErrLicenseTxReused
+	}
+
+	price, _ := new(big.Int).SetString(license.PriceWei, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+	paid, err := chain.VerifyPayment(ctx, txHash,
+		common.HexToAddress(license.Licensee), common.HexToAddress(shell.OwnerAddr), price)
+	if err != nil {
+		return nil, fmt.Errorf("payment verification failed: %w", err)
+	}
+
+	// The unique index on payment_tx_hash settles concurrent claims of one tx
+	activateLicense(license, time.Now())
+	license.PaymentTxHash = txHash
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.ShellLicense{}).
+			Where("id = ? AND status = ?", license.ID, models.LicenseStatusAwaitingPayment).
+			Updates(map[string]interface{}{
+				"status":          license.Status,
+				"payment_tx_hash": txHash,
+				"activated_at":    license.ActivatedAt,
+				"expires_at":      license.ExpiresAt,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errLicenseChanged
+		}
+		return nil
+	})
+	switch {
+	case database.IsUniqueViolation(err, database.LicensePaymentTxIndex):
+		return nil, ErrLicenseTxReused
+	case errors.Is(err, errLicenseChanged):
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("failed to activate license: %w", err)
+	}
+
+	util.Log.Info("[license] @%s license %s paid by %s (%s wei, tx %s)", shell.Handle, license.ID, license.Licensee, paid, txHash)
+	accrueSubjectPayout(shell, license)
+	return license, nil
+}
+
+// LicenseReceipt proves one prompt access. ReceiptHash is the keccak256 of
+//
+//	ensoul-license-receipt:v1:<license_id>:<shell_id>:<wallet>:<dna_version>:<prompt_hash>:<accessed_at>:<prev_receipt>
+//
+// and Signature is the platform wallet's EIP-191 signature of ReceiptHash.
+// PrevReceipt chains each receipt to the previous access of the same license.
+type LicenseReceipt struct {
+	LicenseID   uuid.UUID `json:"license_id"`
+	ShellID     uuid.UUID `json:"shell_id"`
+	Wallet      string    `json:"wallet"`
+	DNAVersion  int       `json:"dna_version"`
+	PromptHash  string    `json:"prompt_hash"`
+	AccessedAt  int64     `json:"accessed_at"`
+	PrevReceipt string    `json:"prev_receipt"`
+	ReceiptHash string    `json:"receipt_hash"`
+	Signature   string    `json:"signature,omitempty"`
+	Signer      string    `json:"signer,omitempty"`
+}
+
+// AccessLicensedPrompt returns the soul prompt to the holder of an active
+// license, logging the access with a signed, hash-chained receipt whose
+// latest hash is then written to the soul's on-chain metadata.
+func AccessLicensedPrompt(shell *models.Shell, licenseID, wallet string) (string, *LicenseReceipt, error) {
+	license, err := findLicense(shell, licenseID, wallet)
+	if err != nil {
+		return "", nil, err
+	}
+
+	promptHash := crypto.Keccak256Hash([]byte(shell.SoulPrompt)).Hex()
+	var receipt *LicenseReceipt
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the license so concurrent reads extend the receipt chain in order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(license, "id = ?", license.ID).Error; err != nil {
+			return err
+		}
+		if license.Status != models.LicenseStatusActive || license.ExpiresAt == nil || time.Now().After(*license.ExpiresAt) {
+			return ErrLicenseInactive
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		receipt = &LicenseReceipt{
+			LicenseID:   license.ID,
+			ShellID:     shell.ID,
+			Wallet:      license.Licensee,
+			DNAVersion:  shell.DNAVersion,
+			PromptHash:  promptHash,
+			AccessedAt:  now.Unix(),
+			PrevReceipt: license.LastReceipt,
+		}
+		message := fmt.Sprintf("ensoul-license-receipt:v1:%s:%s:%s:%d:%s:%d:%s",
+			receipt.LicenseID, receipt.ShellID, receipt.Wallet, receipt.DNAVersion,
+			receipt.PromptHash, receipt.AccessedAt, receipt.PrevReceipt)
+		hash := crypto.Keccak256Hash([]byte(message))
+		receipt.ReceiptHash = hash.Hex()
+
+		sig, err := chain.SignPlatformMessage(hash.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to sign receipt: %w", err)
+		}
+		receipt.Signature = sig
+		if sig != "" {
+			receipt.Signer = chain.C().PlatformAddress().Hex()
+		}
+
+		if err := tx.Create(&models.LicenseAccess{
+			LicenseID:   license.ID,
+			Wallet:      license.Licensee,
+			DNAVersion:  receipt.DNAVersion,
+			PromptHash:  promptHash,
+			PrevReceipt: receipt.PrevReceipt,
+			ReceiptHash: receipt.ReceiptHash,
+			Signature:   sig,
+			CreatedAt:   now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(license).UpdateColumns(map[string]interface{}{
+			"access_count": gorm.Expr("access_count + 1"),
+			"last_receipt": receipt.ReceiptHash,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrLicenseInactive) {
+			return "", nil, err
+		}
+		return "", nil, fmt.Errorf("failed to record license access: %w", err)
+	}
+
+	if shell.AgentID != nil {
+		go anchorLicenseReceipt(license.ID, *shell.AgentID, shell.Handle)
+	}
+	return shell.SoulPrompt, receipt, nil
+}
+
+// anchorLicenseReceipt writes the head of a license's receipt chain on-chain.
+// Only one write per license runs at a time; accesses arriving meanwhile are
+// covered by the next loop iteration, which anchors the newer head.
+func anchorLicenseReceipt(licenseID uuid.UUID, agentID uint64, handle string) {
+	if _, busy := licenseAnchors.LoadOrStore(licenseID, true); busy {
+		return
+	}
+	defer licenseAnchors.Delete(licenseID)
+
+	anchored := ""
+	for {
+		var license models.ShellLicense
+		if err := database.DB.First(&license, "id = ?", licenseID).Error; err != nil {
+			return
+		}
+		head := license.LastReceipt
+		if head == "" || head == anchored {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		txHash, err := chain.SetLicenseReceipt(ctx, new(big.Int).SetUint64(agentID), licenseID.String(), head)
+		cancel()
+		trackChainSpend(txHash, models.ChainFeatureLicense, &license.ShellID, nil)
+		if err != nil {
+			util.Log.Error("[license] Failed to anchor receipt %s for @%s: %v", head, handle, err)
+			return
+		}
+		if txHash == "" {
+			return // chain client not configured
+		}
+		database.DB.Model(&models.LicenseAccess{}).Where("receipt_hash = ?", head).Update("tx_hash", txHash)
+		anchored = head
+	}
+}
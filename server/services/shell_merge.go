@@ -0,0 +1,288 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Errors for soul merges.
+var (
+	ErrMergeSameShell = errors.New("a soul cannot be merged into itself")
+	ErrMergeBlocked   = errors.New("soul cannot be merged")
+)
+
+// ShellMerge is the outcome of MergeShells.
+type ShellMerge struct {
+	Handle         string    `json:"handle"` // the surviving soul
+	AbsorbedHandle string    `json:"absorbed_handle"`
+	AbsorbedID     uuid.UUID `json:"absorbed_id"`
+	Fragments      int64     `json:"fragments"`     // moved, in any status
+	Reopened       int64     `json:"reopened"`      // accepted fragments queued for the new ensouling
+	Ensoulings     int64     `json:"ensoulings"`    // absorbed DNA versions, kept for audit
+	ChatSessions   int64     `json:"chat_sessions"` // moved; pinned sessions now follow the current DNA
+	Contributors   int       `json:"contributors"`  // Claws with accepted fragments on the merged soul
+	Ensouling      bool      `json:"ensouling"`     // a new DNA version over the combined corpus was started
+	MergedBy       string    `json:"merged_by"`
+	MergedAt       time.Time `json:"merged_at"`
+}
+
+// shellMergeTables hold per-fragment and per-soul records that follow the
+// fragments and chats to the surviving soul. Subject payouts only move once
+// paid: checkMergeable refuses souls that still owe any.
+var shellMergeTables = []interface{}{
+	&models.FragmentArchive{},
+	&models.QuizAnswer{},
+	&models.SubjectPayout{},
+	&models.FragmentBatch{},
+	&models.FragmentEmbedding{},
+	&models.PlagiarismMatch{},
+	&models.EnsoulingGateDecision{},
+	&models.ModerationLog{},
+	&models.ChainSpend{},
+	&models.LLMUsage{},
+}
+
+// MergeShells absorbs a duplicate soul (an old handle, or a copy minted before
+// handles were normalized) into the soul at targetHandle. source is a handle,
+// or a shell ID when the duplicates differ only in letter case.
+//
+// Fragments, batches, chats and share links move to the target, so its
+// contributors are the union of both. The absorbed soul's DNA versions move
+// too but leave the version line (status merged): their accepted fragments
+// are reopened and condensed into the target's DNA by a new ensouling. The
+// absorbed soul is soft-deleted, its agent marked retired on-chain, and its
+// handle becomes an alias of the target so old URLs redirect.
+//
+// Souls with licenses, chat purchases or unpaid subject payouts are refused:
+// those are payments to or by the absorbed soul's owner and need to be
+// settled first.
+func MergeShells(targetHandle, source, admin string) (*ShellMerge, error) {
+	target, err := GetShellByHandle(targetHandle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: @%s", ErrShellNotFound, targetHandle)
+	}
+	absorbed, err := mergeSource(source, target)
+	if err != nil {
+		return nil, err
+	}
+	if absorbed.ID == target.ID {
+		return nil, ErrMergeSameShell
+	}
+	if err := checkMergeable(target, absorbed); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	merge := &ShellMerge{
+		Handle:         target.Handle,
+		AbsorbedHandle: absorbed.Handle,
+		AbsorbedID:     absorbed.ID,
+		MergedBy:       admin,
+		MergedAt:       now,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Claim the absorbed soul first so a concurrent merge of it fails
+		res := tx.Model(&models.Shell{}).Where("id = ?", absorbed.ID).Update("updated_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("%w: @%s", ErrShellNotFound, absorbed.Handle)
+		}
+
+		// Accepted fragments condensed into the absorbed DNA go back into the
+		// queue; those of rejected versions stay out of the soul
+		condensed := tx.Model(&models.Ensouling{}).Select("id").
+			Where("shell_id = ? AND status IN ?", absorbed.ID, []string{models.EnsoulingDeployed, models.EnsoulingQuarantined})
+		res = tx.Unscoped().Model(&models.Fragment{}).
+			Where("shell_id = ? AND ensouling_id IN (?)", absorbed.ID, condensed).
+			Update("ensouling_id", nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		res = tx.Model(&models.Fragment{}).
+			Where("shell_id = ? AND status = ? AND ensouling_id IS NULL AND subject_flag = ''", absorbed.ID, models.FragStatusAccepted).
+			Count(&merge.Reopened)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		res = tx.Model(&models.Ensouling{}).
+			Where("shell_id = ? AND status IN ?", absorbed.ID, []string{models.EnsoulingDeployed, models.EnsoulingQuarantined}).
+			Update("status", models.EnsoulingMerged)
+		if res.Error != nil {
+			return res.Error
+		}
+		res = tx.Model(&models.Ensouling{}).Where("shell_id = ?", absorbed.ID).Update("shell_id", target.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		merge.Ensoulings = res.RowsAffected
+
+		res = tx.Unscoped().Model(&models.Fragment{}).Where("shell_id = ?", absorbed.ID).Update("shell_id", target.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		merge.Fragments = res.RowsAffected
+
+		// Version pins belong to the absorbed version line
+		res = tx.Unscoped().Model(&models.ChatSession{}).Where("shell_id = ?", absorbed.ID).
+			Updates(map[string]interface{}{"shell_id": target.ID, "dna_version": 0})
+		if res.Error != nil {
+			return res.Error
+		}
+		merge.ChatSessions = res.RowsAffected
+
+		if err := tx.Model(&models.ChatShare{}).Where("shell_id = ?", absorbed.ID).
+			Updates(map[string]interface{}{"shell_id": target.ID, "handle": target.Handle}).Error; err != nil {
+			return err
+		}
+		for _, table := range shellMergeTables {
+			if err := tx.Model(table).Where("shell_id = ?", absorbed.ID).Update("shell_id", target.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		// The absorbed handle and its own previous handles resolve to the target
+		if err := tx.Model(&models.ShellAlias{}).Where("shell_id = ?", absorbed.ID).Update("shell_id", target.ID).Error; err != nil {
+			return err
+		}
+		if alias := strings.ToLower(absorbed.Handle); alias != strings.ToLower(target.Handle) {
+			if err := tx.Where("handle = ?", alias).Delete(&models.ShellAlias{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.ShellAlias{Handle: alias, ShellID: target.ID}).Error; err != nil {
+				return err
+			}
+		}
+
+		// The counter-reconcile job's counts, archived fragments included
+		if err := tx.Exec(`
+			UPDATE shells SET
+				total_frags = c.total_frags,
+				accepted_frags = c.accepted_frags,
+				total_claws = c.total_claws,
+				total_chats = total_chats + ?,
+				time_travel_chats = time_travel_chats + ?
+			FROM (`+shellCountsSQL+`) c
+			WHERE shells.id = c.id AND shells.id = ?`,
+			absorbed.TotalChats, absorbed.TimeTravelChats,
+			models.FragStatusAccepted, models.FragStatusAccepted, target.ID).Error; err != nil {
+			return err
+		}
+
+		if absorbed.ChainStatus == models.ShellChainActive {
+			if err := tx.Model(absorbed).Updates(map[string]interface{}{
+				"chain_status": models.ShellChainRetired, "retired_at": &now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(absorbed).Error
+	})
+	if errors.Is(err, ErrShellNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge @%s into @%s: %w", absorbed.Handle, target.Handle, err)
+	}
+
+	if err := database.DB.First(target, "id = ?", target.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to reload @%s: %w", target.Handle, err)
+	}
+	merge.Contributors = target.TotalClaws
+
+	util.Log.Info("[merge] @%s (shell %s) merged into @%s by %s: %d fragments (%d reopened), %d ensoulings, %d chat sessions",
+		absorbed.Handle, absorbed.ID, target.Handle, admin, merge.Fragments, merge.Reopened, merge.Ensoulings, merge.ChatSessions)
+
+	go EmitWebhookEvent(models.WebhookEventMerged, &absorbed.ID, map[string]interface{}{
+		"handle":          absorbed.Handle,
+		"merged_into":     target.Handle,
+		"merged_into_id":  target.ID,
+		"agent_id":        absorbed.AgentID,
+		"merged_at":       now,
+		"fragments_moved": merge.Fragments,
+	})
+	if absorbed.AgentID != nil && absorbed.ChainStatus == models.ShellChainActive {
+		go setRetiredOnChain(absorbed.Handle, *absorbed.AgentID)
+	}
+	if merge.Reopened > 0 {
+		merge.Ensouling = true
+		go TriggerEnsouling(target)
+	}
+	return merge, nil
+}
+
+// mergeSource resolves the soul to absorb. A handle that only resolves to the
+// target (duplicates differing in case) picks the other soul of that handle.
+func mergeSource(source string, target *models.Shell) (*models.Shell, error) {
+	var shell models.Shell
+	if id, err := uuid.Parse(source); err == nil {
+		if err := database.DB.First(&shell, "id = ?", id).Error; err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrShellNotFound, source)
+		}
+		return &shell, nil
+	}
+
+	handle, err := ValidateHandle(source)
+	if err != nil {
+		return nil, err
+	}
+	found, err := GetShellByHandle(handle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: @%s", ErrShellNotFound, handle)
+	}
+	if found.ID != target.ID {
+		return found, nil
+	}
+	if err := database.DB.Where("LOWER(handle) = ? AND id <> ?", handle, target.ID).First(&shell).Error; err != nil {
+		return nil, ErrMergeSameShell
+	}
+	return &shell, nil
+}
+
+// checkMergeable refuses merges the absorbed soul's owner or buyers would
+// lose by, and souls in the middle of a mint or dispute.
+func checkMergeable(target, absorbed *models.Shell) error {
+	if target.Stage == models.StagePending || !target.OnChain() {
+		return fmt.Errorf("%w: @%s is not minted yet", ErrMergeBlocked, target.Handle)
+	}
+	if target.ChainStatus != models.ShellChainActive {
+		return fmt.Errorf("%w: @%s is %s", ErrMergeBlocked, target.Handle, target.ChainStatus)
+	}
+	if absorbed.Stage == models.StagePending {
+		return fmt.Errorf("%w: @%s has a mint in progress", ErrMergeBlocked, absorbed.Handle)
+	}
+	for _, s := range []*models.Shell{target, absorbed} {
+		if s.DisputedAt != nil {
+			return fmt.Errorf("soul @%s %w", s.Handle, ErrShellDisputed)
+		}
+	}
+
+	var licenses, purchases int64
+	database.DB.Model(&models.ShellLicense{}).
+		Where("shell_id = ? AND (status = ? OR (status = ? AND (expires_at IS NULL OR expires_at > ?)))",
+			absorbed.ID, models.LicenseStatusAwaitingPayment, models.LicenseStatusActive, time.Now()).
+		Count(&licenses)
+	if licenses > 0 {
+		return fmt.Errorf("%w: @%s has %d open license(s); revoke them first", ErrMergeBlocked, absorbed.Handle, licenses)
+	}
+	database.DB.Model(&models.ChatPurchase{}).Where("shell_id = ?", absorbed.ID).Count(&purchases)
+	if purchases > 0 {
+		return fmt.Errorf("%w: @%s has sold chat rounds", ErrMergeBlocked, absorbed.Handle)
+	}
+	var unpaid int64
+	database.DB.Model(&models.SubjectPayout{}).Where("shell_id = ? AND paid_at IS NULL", absorbed.ID).Count(&unpaid)
+	if unpaid > 0 {
+		return fmt.Errorf("%w: @%s owes %d subject payout(s); pay them first", ErrMergeBlocked, absorbed.Handle, unpaid)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+func TestMergeShellsMovesArchiveQuizAndPayouts(t *testing.T) {
+	util.InitLogger("error")
+	config.Cfg = &config.Config{}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	target := &models.Shell{Handle: "elonmusk", Stage: models.StageEmbryo, MintTxHash: "0x01", ChainStatus: models.ShellChainActive}
+	absorbed := &models.Shell{Handle: "elonmusk_old", Stage: models.StageEmbryo, MintTxHash: "0x02", ChainStatus: models.ShellChainActive, TotalFrags: 2}
+	database.DB.Create(target)
+	database.DB.Create(absorbed)
+	claw := &models.Claw{Name: "hunter", APIKeyHash: "hash", ClaimCode: "claim", VerificationCode: "code"}
+	database.DB.Create(claw)
+
+	fragment := &models.Fragment{ShellID: absorbed.ID, ClawID: claw.ID, Dimension: models.DimStance, Content: "Unsupported claim", Status: models.FragStatusRejected}
+	database.DB.Create(fragment)
+	database.DB.Create(&models.FragmentArchive{ID: uuid.New(), ShellID: absorbed.ID, ClawID: claw.ID, Dimension: models.DimStance,
+		Status: models.FragStatusRejected, Payload: []byte{0}, ArchivedAt: time.Now()})
+	answer := &models.QuizAnswer{QuizID: uuid.New(), Subject: "wallet:0xabc", ShellID: absorbed.ID, FragmentID: fragment.ID}
+	database.DB.Create(answer)
+	payout := &models.SubjectPayout{ShellID: absorbed.ID, LicenseID: uuid.New(), SubjectAddr: "0xabc", AmountWei: "100", ShareBps: 1000}
+	database.DB.Create(payout)
+
+	if _, err := MergeShells(target.Handle, absorbed.Handle, "admin"); !errors.Is(err, ErrMergeBlocked) {
+		t.Fatalf("merge with an unpaid payout: err = %v, want ErrMergeBlocked", err)
+	}
+
+	now := time.Now()
+	database.DB.Model(payout).Updates(map[string]interface{}{"paid_tx_hash": "0x03", "paid_at": &now})
+	if _, err := MergeShells(target.Handle, absorbed.Handle, "admin"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	database.DB.First(target, "id = ?", target.ID)
+	if target.TotalFrags != 2 {
+		t.Errorf("total_frags = %d, want 2 (one fragment, one archived)", target.TotalFrags)
+	}
+	for _, row := range []struct {
+		table interface{}
+		id    uuid.UUID
+	}{{&models.QuizAnswer{}, answer.ID}, {&models.SubjectPayout{}, payout.ID}} {
+		var shellIDs []uuid.UUID
+		database.DB.Model(row.table).Where("id = ?", row.id).Pluck("shell_id", &shellIDs)
+		if len(shellIDs) != 1 || shellIDs[0] != target.ID {
+			t.Errorf("%T %s belongs to %v, want the surviving soul", row.table, row.id, shellIDs)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"gorm.io/gorm"
+)
+
+// RenameShell moves a soul to a new handle (e.g. after the owner changed their
+// Twitter handle). Fragments, history and the on-chain agent are keyed by the
+// shell ID and are preserved; the old handle is kept as an alias so existing
+// URLs keep resolving.
+func RenameShell(shell *models.Shell, newHandle string) (*models.Shell, error) {
+	newHandle, err := ValidateHandle(newHandle)
+	if err != nil {
+		return nil, err
+	}
+	oldHandle := shell.Handle
+	if newHandle == oldHandle {
+		return nil, fmt.Errorf("new handle is the same as the current handle")
+	}
+	if shell.Stage == models.StagePending || !shell.OnChain() {
+		return nil, fmt.Errorf("soul is not minted yet")
+	}
+	// A disputed handle stays put until an admin resolves the dispute
+	if shell.DisputedAt != nil {
+		return nil, fmt.Errorf("soul @%s %w", oldHandle, ErrShellDisputed)
+	}
+
+	// Soft-deleted shells still hold the unique handle index
+	var taken int64
+	database.DB.Unscoped().Model(&models.Shell{}).Where("LOWER(handle) = ?", newHandle).Count(&taken)
+	if taken > 0 {
+		return nil, fmt.Errorf("@%s is already taken by another soul", newHandle)
+	}
+	var alias models.ShellAlias
+	if err := database.DB.Where("handle = ?", newHandle).First(&alias).Error; err == nil && alias.ShellID != shell.ID {
+		return nil, fmt.Errorf("@%s was previously used by another soul", newHandle)
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Renaming back to a previous handle drops its alias
+		if err := tx.Where("handle = ?", newHandle).Delete(&models.ShellAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("handle = ?", oldHandle).Delete(&models.ShellAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.ShellAlias{Handle: oldHandle, ShellID: shell.ID}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(shell).Update("handle", newHandle).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ChatShare{}).Where("shell_id = ?", shell.ID).Update("handle", newHandle).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename soul: %w", err)
+	}
+	shell.Handle = newHandle
+
+	util.Log.Info("[services] Soul renamed: @%s -> @%s (shell %s)", oldHandle, newHandle, shell.ID)
+
+	if shell.AgentID != nil {
+		go updateRenamedSoulOnChain(*shell)
+	}
+	return shell, nil
+}
+
+// updateRenamedSoulOnChain rewrites the ensoul:handle metadata and the agentURI
+// (whose name and service URLs embed the handle) after a rename.
+func updateRenamedSoulOnChain(shell models.Shell) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	agentId := new(big.Int).SetUint64(*shell.AgentID)
+
+	handleTx, err := chain.SetSoulHandle(ctx, agentId, shell.Handle)
+	trackChainSpend(handleTx, models.ChainFeatureMetadata, &shell.ID, nil)
+	if err != nil {
+		util.Log.Error("[services] Failed to update handle metadata on-chain for @%s: %v", shell.Handle, err)
+	}
+
+	txHash, err := chain.UpdateSoulURI(
+		ctx, agentId, shell.Handle, SoulCardURL(shell.Handle),
+		ShellEssence(&shell), shell.Stage, shell.DNAVersion,
+	)
+	trackChainSpend(txHash, models.ChainFeatureAgentURI, &shell.ID, nil)
+	if err != nil {
+		util.Log.Error("[services] Failed to update agentURI on-chain for renamed @%s: %v", shell.Handle, err)
+		return
+	}
+	if txHash != "" {
+		util.Log.Debug("[services] On-chain URI updated for renamed @%s: tx=%s", shell.Handle, txHash)
+	}
+}
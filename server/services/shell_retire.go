@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// BurnGuide tells the owner of a retired soul how to burn its NFT if they want
+// to. Only the owner's wallet can burn it; once the burn's Transfer to the zero
+// address is seen, the registry watcher marks the soul revoked.
+type BurnGuide struct {
+	Registry string `json:"registry"` // Identity Registry contract
+	AgentID  uint64 `json:"agent_id"`
+	Method   string `json:"method"`
+	Note     string `json:"note"`
+}
+
+// RetireShell marks an owner's soul retired: chats and fragment submissions
+// are refused from then on, while its fragments, DNA history and chats stay
+// readable. The ensoul:status metadata is updated on-chain in the background;
+// the NFT itself stays with the owner, who may burn it with the returned guide.
+func RetireShell(shell *models.Shell) (*BurnGuide, error) {
+	if !shell.OnChain() {
+		return nil, fmt.Errorf("soul is not minted yet")
+	}
+	switch shell.ChainStatus {
+	case models.ShellChainRevoked:
+		return nil, fmt.Errorf("soul @%s %w", shell.Handle, ErrShellRevoked)
+	case models.ShellChainRetired:
+		return nil, fmt.Errorf("soul @%s %w", shell.Handle, ErrShellRetired)
+	}
+	if shell.DisputedAt != nil {
+		return nil, fmt.Errorf("soul @%s %w", shell.Handle, ErrShellDisputed)
+	}
+
+	now := time.Now()
+	res := database.DB.Model(&models.Shell{}).
+		Where("id = ? AND chain_status = ?", shell.ID, models.ShellChainActive).
+		Updates(map[string]interface{}{"chain_status": models.ShellChainRetired, "retired_at": &now})
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to retire soul: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("soul @%s %w", shell.Handle, ErrShellRetired)
+	}
+	shell.ChainStatus = models.ShellChainRetired
+	shell.RetiredAt = &now
+
+	util.Log.Info("[services] Soul @%s retired by its owner %s", shell.Handle, shell.OwnerAddr)
+	go EmitWebhookEvent(models.WebhookEventRetired, &shell.ID, map[string]interface{}{
+		"handle":     shell.Handle,
+		"agent_id":   shell.AgentID,
+		"retired_at": now,
+	})
+
+	if shell.AgentID == nil {
+		return nil, nil
+	}
+	go setRetiredOnChain(shell.Handle, *shell.AgentID)
+	return &BurnGuide{
+		Registry: config.Cfg.IdentityRegistryAddr,
+		AgentID:  *shell.AgentID,
+		Method:   "burn(uint256 agentId)",
+		Note:     "Optional. Call from the owner wallet if the registry supports burning; the soul stays retired either way.",
+	}, nil
+}
+
+// setRetiredOnChain writes the ensoul:status metadata of a retired soul.
+func setRetiredOnChain(handle string, agentID uint64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	txHash, err := chain.SetSoulStatus(ctx, new(big.Int).SetUint64(agentID), models.ShellChainRetired)
+	trackShellChainSpend(txHash, models.ChainFeatureMetadata, handle)
+	if err != nil {
+		util.Log.Error("[services] Failed to set retired status on-chain for @%s: %v", handle, err)
+		return
+	}
+	if txHash != "" {
+		util.Log.Debug("[services] On-chain status of @%s set to retired: tx=%s", handle, txHash)
+	}
+}
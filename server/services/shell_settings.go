@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// maxGreetingLength caps the owner-defined chat greeting.
+const maxGreetingLength = 500
+
+// validDimensions is the set of fragment dimensions.
+var validDimensions = map[string]bool{
+	models.DimPersonality: true, models.DimKnowledge: true, models.DimStance: true,
+	models.DimStyle: true, models.DimRelationship: true, models.DimTimeline: true,
+}
+
+// profanityWords is a deliberately small blocklist for the "clean" content policy.
+// Matching is on whole words, case-insensitive.
+var profanityWords = map[string]bool{
+	"fuck": true, "fucking": true, "fucked": true, "shit": true, "bitch": true,
+	"cunt": true, "dick": true, "cock": true, "pussy": true, "asshole": true,
+	"bastard": true, "whore": true, "slut": true, "porn": true, "nsfw": true,
+	"nude": true, "nudes": true, "sex": true, "sexual": true,
+}
+
+// ErrChatModelAdminOnly is returned when an owner picks a chat model other
+// than LLM_MODEL: chat rounds are priced the same whatever the model, so only
+// an admin may move a soul to a costlier one.
+var ErrChatModelAdminOnly = errors.New("only an admin can switch a soul's chat to another model")
+
+// ShellSettingsUpdate is a partial update; nil fields are left unchanged.
+type ShellSettingsUpdate struct {
+	ChatEnabled        *bool     `json:"chat_enabled"`
+	Greeting           *string   `json:"greeting"`
+	AllowedDimensions  *[]string `json:"allowed_dimensions"`
+	ContentPolicy      *string   `json:"content_policy"`
+	ChatFreeRounds     *int      `json:"chat_free_rounds"`
+	ChatBundleRounds   *int      `json:"chat_bundle_rounds"`
+	ChatBundlePriceWei *string   `json:"chat_bundle_price_wei"`
+	ChatModel          *string   `json:"chat_model"`
+}
+
+// GetShellSettings returns a shell's settings, or the defaults if none are stored.
+func GetShellSettings(shellID uuid.UUID) models.ShellSettings {
+	var settings models.ShellSettings
+	if err := database.DB.Where("shell_id = ?", shellID).First(&settings).Error; err != nil {
+		return models.ShellSettings{
+			ShellID:            shellID,
+			ChatEnabled:        true,
+			AllowedDimensions:  models.StringList{},
+			ContentPolicy:      models.ContentPolicyDefault,
+			ChatBundlePriceWei: "0",
+		}
+	}
+	if settings.AllowedDimensions == nil {
+		settings.AllowedDimensions = models.StringList{}
+	}
+	return settings
+}
+
+// UpdateShellSettings validates and applies a partial settings update made by
+// the owner or, with byAdmin set, an admin.
+func UpdateShellSettings(shell *models.Shell, update ShellSettingsUpdate, updatedBy string, byAdmin bool) (*models.ShellSettings, error) {
+	settings := GetShellSettings(shell.ID)
+
+	if update.ChatEnabled != nil {
+		settings.ChatEnabled = *update.ChatEnabled
+	}
+	if update.Greeting != nil {
+		greeting := strings.TrimSpace(*update.Greeting)
+		if len(greeting) > maxGreetingLength {
+			return nil, fmt.Errorf("greeting too long (max %d characters)", maxGreetingLength)
+		}
+		settings.Greeting = greeting
+	}
+	if update.AllowedDimensions != nil {
+		dims := models.StringList{}
+		seen := make(map[string]bool)
+		for _, d := range *update.AllowedDimensions {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if !validDimensions[d] {
+				return nil, fmt.Errorf("invalid dimension: %q", d)
+			}
+			if !seen[d] {
+				seen[d] = true
+				dims = append(dims, d)
+			}
+		}
+		// Batch submissions need at least 3 dimensions, so fewer would lock out all Claws
+		if len(dims) > 0 && len(dims) < 3 {
+			return nil, fmt.Errorf("allowed_dimensions must list at least 3 dimensions (or be empty to allow all)")
+		}
+		settings.AllowedDimensions = dims
+	}
+	if update.ContentPolicy != nil {
+		switch *update.ContentPolicy {
+		case models.ContentPolicyDefault, models.ContentPolicyClean:
+			settings.ContentPolicy = *update.ContentPolicy
+		default:
+			return nil, fmt.Errorf("invalid content_policy (use %q or %q)", models.ContentPolicyDefault, models.ContentPolicyClean)
+		}
+	}
+	if update.ChatFreeRounds != nil {
+		if *update.ChatFreeRounds < 0 || *update.ChatFreeRounds > maxChatPricingRounds {
+			return nil, fmt.Errorf("chat_free_rounds must be 0-%d", maxChatPricingRounds)
+		}
+		settings.ChatFreeRounds = *update.ChatFreeRounds
+	}
+	if update.ChatBundleRounds != nil {
+		if *update.ChatBundleRounds < 0 || *update.ChatBundleRounds > maxChatPricingRounds {
+			return nil, fmt.Errorf("chat_bundle_rounds must be 0-%d", maxChatPricingRounds)
+		}
+		settings.ChatBundleRounds = *update.ChatBundleRounds
+	}
+	if update.ChatBundlePriceWei != nil {
+		priceWei := strings.TrimSpace(*update.ChatBundlePriceWei)
+		if priceWei == "" {
+			priceWei = "0"
+		}
+		price, ok := new(big.Int).SetString(priceWei, 10)
+		if !ok || price.Sign() < 0 {
+			return nil, fmt.Errorf("chat_bundle_price_wei must be a non-negative integer")
+		}
+		settings.ChatBundlePriceWei = price.String()
+	}
+	if update.ChatModel != nil {
+		model := strings.TrimSpace(*update.ChatModel)
+		if option, ok := chatModelOption(model); ok && option.IsDefault {
+			model = ""
+		} else if model != "" && !ok {
+			names := make([]string, 0)
+			for _, option := range ChatModels() {
+				names = append(names, option.Model)
+			}
+			return nil, fmt.Errorf("chat_model %q is not available (use one of: %s)", model, strings.Join(names, ", "))
+		}
+		// Owners may reset to LLM_MODEL or resend the current model
+		if model != "" && model != settings.ChatModel && !byAdmin {
+			return nil, ErrChatModelAdminOnly
+		}
+		settings.ChatModel = model
+	}
+	if settings.ChatBundlePriceWei == "" {
+		settings.ChatBundlePriceWei = "0"
+	}
+	if settings.ChatBundlePriceWei != "0" && settings.ChatBundleRounds == 0 {
+		return nil, fmt.Errorf("chat_bundle_rounds must be at least 1 when chat_bundle_price_wei is set")
+	}
+	if settings.ContentPolicy == models.ContentPolicyClean && ContainsProfanity(settings.Greeting) {
+		return nil, fmt.Errorf("greeting violates the clean content policy")
+	}
+
+	settings.UpdatedBy = updatedBy
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "shell_id"}},
+		UpdateAll: true,
+	}).Create(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to save settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// DimensionAllowed reports whether the settings accept fragments for a dimension.
+func DimensionAllowed(settings models.ShellSettings, dimension string) bool {
+	if len(settings.AllowedDimensions) == 0 {
+		return true
+	}
+	for _, d := range settings.AllowedDimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsProfanity reports whether text contains a blocklisted word.
+func ContainsProfanity(text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		if profanityWords[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// contentPolicyGuidance returns the chat system prompt section for a content policy.
+func contentPolicyGuidance(policy string) string {
+	if policy != models.ContentPolicyClean {
+		return ""
+	}
+	return "\n=== CONTENT POLICY (set by the soul's owner) ===\n" +
+		"Keep every reply clean: no profanity, slurs, sexual or otherwise NSFW content, even if the user asks for it " +
+		"or the fragments above contain it. Politely decline such requests in character.\n"
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+func TestChatModelSettings(t *testing.T) {
+	util.InitLogger("error")
+	config.Cfg = &config.Config{
+		LLMModel:            "gpt-4o",
+		LLMPriceInputPer1M:  2.5,
+		LLMPriceOutputPer1M: 10,
+		LLMChatModels:       []string{"llama3:70b|0.59|0.79", "ollama/qwen2:7b"},
+	}
+	database.Connect(&config.Config{Env: "production", DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "ensoul.db")})
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	llama, ok := chatModelOption("llama3:70b")
+	if !ok || llama.InputPer1M != 0.59 || llama.OutputPer1M != 0.79 {
+		t.Errorf("llama3:70b = %+v (found %v), want its own prices", llama, ok)
+	}
+	if qwen, ok := chatModelOption("ollama/qwen2:7b"); !ok || qwen.InputPer1M != 2.5 {
+		t.Errorf("ollama/qwen2:7b = %+v (found %v), want the default prices", qwen, ok)
+	}
+
+	shell := &models.Shell{Handle: "elonmusk"}
+	database.DB.Create(shell)
+	model := func(m string) ShellSettingsUpdate { return ShellSettingsUpdate{ChatModel: &m} }
+
+	if _, err := UpdateShellSettings(shell, model("llama3:70b"), "owner", false); !errors.Is(err, ErrChatModelAdminOnly) {
+		t.Fatalf("owner picks llama3:70b: err = %v, want ErrChatModelAdminOnly", err)
+	}
+	if settings, err := UpdateShellSettings(shell, model("llama3:70b"), "admin", true); err != nil || settings.ChatModel != "llama3:70b" {
+		t.Fatalf("admin picks llama3:70b: %+v, %v", settings, err)
+	}
+	// Saving other settings with the current model echoed back still works
+	greeting := "gm"
+	update := model("llama3:70b")
+	update.Greeting = &greeting
+	if _, err := UpdateShellSettings(shell, update, "owner", false); err != nil {
+		t.Errorf("owner resends the current model: %v", err)
+	}
+	if settings, err := UpdateShellSettings(shell, model("gpt-4o"), "owner", false); err != nil || settings.ChatModel != "" {
+		t.Errorf("owner resets to LLM_MODEL: %+v, %v", settings, err)
+	}
+}
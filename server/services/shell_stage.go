@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// recordStageChange stores a stage transition and publishes ShellStageChanged,
+// whose subscribers emit the webhook event and, for souls with an agentId,
+// write ensoul:stage on-chain.
+func recordStageChange(shell *models.Shell, from, to string) {
+	transition := &models.ShellStageTransition{
+		ShellID:   shell.ID,
+		FromStage: from,
+		ToStage:   to,
+	}
+	if err := database.DB.Create(transition).Error; err != nil {
+		util.Log.Error("[services] Failed to record stage transition for @%s: %v", shell.Handle, err)
+		return
+	}
+	util.Log.Info("[services] @%s stage %s → %s", shell.Handle, from, to)
+
+	Publish(Events, ShellStageChanged{Shell: shell, Transition: transition})
+}
+
+// setStageOnChain writes the ensoul:stage metadata and keeps the tx hash on the transition.
+func setStageOnChain(transition *models.ShellStageTransition, agentID uint64, handle string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	txHash, err := chain.SetSoulStage(ctx, new(big.Int).SetUint64(agentID), transition.ToStage)
+	trackChainSpend(txHash, models.ChainFeatureMetadata, &transition.ShellID, nil)
+	if err != nil {
+		util.Log.Error("[services] Failed to set stage metadata on-chain for @%s: %v", handle, err)
+		return
+	}
+	if txHash != "" {
+		database.DB.Model(transition).Update("tx_hash", txHash)
+	}
+}
+
+// GetStageHistory returns a shell's stage transitions, oldest first.
+func GetStageHistory(shell *models.Shell) ([]models.ShellStageTransition, error) {
+	var transitions []models.ShellStageTransition
+	if err := database.DB.Where("shell_id = ?", shell.ID).Order("created_at ASC").Find(&transitions).Error; err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// subjectClaimTTL is how long a claim code can be tweeted and verified.
+const subjectClaimTTL = time.Hour
+
+// Errors for the subject claim flow and the subject's powers.
+var (
+	ErrSubjectClaimNotFound   = errors.New("no open claim for this wallet, start one first")
+	ErrSubjectTweetNotFound   = errors.New("no recent tweet with the claim code was found")
+	ErrSubjectVerifyDisabled  = errors.New("handle verification is unavailable: no Twitter data source is configured")
+	ErrSubjectAlreadyVerified = errors.New("this soul already has a verified subject")
+	ErrNotSubject             = errors.New("only the soul's verified subject can do this")
+	ErrSubjectFragment        = errors.New("fragment not found for this soul")
+	ErrPayoutNotFound         = errors.New("payout not found")
+	ErrPayoutTxReused         = errors.New("this transaction has already been used to pay a payout")
+)
+
+// SubjectClaimStart is what the claimant needs to post from the handle.
+type SubjectClaimStart struct {
+	Code      string    `json:"code"`
+	TweetText string    `json:"tweet_text"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// StartSubjectClaim issues a claim code for wallet to tweet from the soul's
+// handle. An earlier open claim of the same wallet is replaced. The current
+// subject may claim again (e.g. to move to a new wallet); anyone else is
+// refused while a subject is verified.
+func StartSubjectClaim(shell *models.Shell, wallet string) (*SubjectClaimStart, error) {
+	if shell.VerifiedSubject != "" && !strings.EqualFold(shell.VerifiedSubject, wallet) {
+		return nil, ErrSubjectAlreadyVerified
+	}
+
+	code, err := generateSubjectCode()
+	if err != nil {
+		return nil, err
+	}
+	claim := &models.SubjectClaim{
+		ShellID:    shell.ID,
+		WalletAddr: wallet,
+		Code:       code,
+		Status:     models.SubjectClaimPending,
+		ExpiresAt:  time.Now().Add(subjectClaimTTL),
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SubjectClaim{}).
+			Where("shell_id = ? AND LOWER(wallet_addr) = LOWER(?) AND status = ?", shell.ID, wallet, models.SubjectClaimPending).
+			Update("status", models.SubjectClaimExpired).Error; err != nil {
+			return err
+		}
+		return tx.Create(claim).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create claim: %w", err)
+	}
+
+	return &SubjectClaimStart{
+		Code:      code,
+		TweetText: fmt.Sprintf("I'm claiming my soul on Ensoul: %s", code),
+		ExpiresAt: claim.ExpiresAt,
+	}, nil
+}
+
+// VerifySubjectClaim looks for the wallet's claim code among the handle's
+// recent tweets and, if found, makes the wallet the soul's verified subject.
+func VerifySubjectClaim(shell *models.Shell, wallet string) (*models.Shell, error) {
+	var claim models.SubjectClaim
+	if err := database.DB.
+		Where("shell_id = ? AND LOWER(wallet_addr) = LOWER(?) AND status = ? AND expires_at > ?",
+			shell.ID, wallet, models.SubjectClaimPending, time.Now()).
+		Order("created_at DESC").First(&claim).Error; err != nil {
+		return nil, ErrSubjectClaimNotFound
+	}
+	if shell.VerifiedSubject != "" && !strings.EqualFold(shell.VerifiedSubject, wallet) {
+		return nil, ErrSubjectAlreadyVerified
+	}
+
+	profile, err := FetchTwitterProfile(shell.Handle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch @%s: %w", shell.Handle, err)
+	}
+	if IsMockProfile(profile) {
+		return nil, ErrSubjectVerifyDisabled
+	}
+	// The tweet must come from the soul's own account, not a lookalike
+	if !strings.EqualFold(profile.User.Username, shell.Handle) {
+		return nil, fmt.Errorf("%w: fetched @%s instead of @%s", ErrSubjectTweetNotFound, profile.User.Username, shell.Handle)
+	}
+	tweetID := ""
+	for _, t := range profile.Tweets {
+		if strings.Contains(t.Text, claim.Code) {
+			tweetID = t.ID
+			break
+		}
+	}
+	if tweetID == "" {
+		return nil, ErrSubjectTweetNotFound
+	}
+
+	now := time.Now()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&claim).Updates(map[string]interface{}{
+			"status": models.SubjectClaimVerified, "tweet_id": tweetID, "verified_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(shell).Updates(map[string]interface{}{
+			"verified_subject": wallet, "subject_verified_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record verification: %w", err)
+	}
+	shell.VerifiedSubject = wallet
+	shell.SubjectVerifiedAt = &now
+
+	util.Log.Info("[subject] @%s verified as its subject by %s (tweet %s)", shell.Handle, wallet, tweetID)
+	return shell, nil
+}
+
+// RevokeSubject removes a soul's verified subject, e.g. after a mistaken or
+// abused claim. The subject's flags and chat pause are lifted with it.
+func RevokeSubject(shell *models.Shell) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(shell).Updates(map[string]interface{}{
+			"verified_subject": "", "subject_verified_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ShellSettings{}).Where("shell_id = ?", shell.ID).
+			Update("subject_paused", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Fragment{}).Where("shell_id = ? AND subject_flag <> ''", shell.ID).
+			Updates(map[string]interface{}{"subject_flag": "", "subject_flagged_at": nil}).Error
+	})
+}
+
+// IsShellSubject reports whether wallet is the soul's verified subject.
+func IsShellSubject(shell *models.Shell, wallet string) bool {
+	return shell.VerifiedSubject != "" && strings.EqualFold(shell.VerifiedSubject, wallet)
+}
+
+// SetSubjectChatPaused pauses or resumes chat with the soul on behalf of its
+// subject. A pause applies on top of the owner's chat setting.
+func SetSubjectChatPaused(shell *models.Shell, wallet string, paused bool) (*models.ShellSettings, error) {
+	if !IsShellSubject(shell, wallet) {
+		return nil, ErrNotSubject
+	}
+	settings := GetShellSettings(shell.ID)
+	settings.SubjectPaused = paused
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "shell_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subject_paused"}),
+	}).Create(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to save settings: %w", err)
+	}
+	util.Log.Info("[subject] Chat with @%s paused=%v by its subject", shell.Handle, paused)
+	return &settings, nil
+}
+
+// FlagFragment records the subject's objection to one of the soul's
+// fragments. A flagged fragment is not merged by later ensoulings and is left
+// out of chat retrieval; an empty reason clears the flag.
+func FlagFragment(shell *models.Shell, wallet, fragmentID, reason string) (*models.Fragment, error) {
+	if !IsShellSubject(shell, wallet) {
+		return nil, ErrNotSubject
+	}
+	id, err := uuid.Parse(fragmentID)
+	if err != nil {
+		return nil, ErrSubjectFragment
+	}
+	var fragment models.Fragment
+	if err := database.DB.Where("id = ? AND shell_id = ?", id, shell.ID).First(&fragment).Error; err != nil {
+		return nil, ErrSubjectFragment
+	}
+
+	reason = strings.TrimSpace(reason)
+	if len(reason) > 1000 {
+		return nil, fmt.Errorf("reason too long (max 1000 characters)")
+	}
+	updates := map[string]interface{}{"subject_flag": reason, "subject_flagged_at": nil}
+	if reason != "" {
+		now := time.Now()
+		updates["subject_flagged_at"] = now
+		fragment.SubjectFlaggedAt = &now
+	} else {
+		fragment.SubjectFlaggedAt = nil
+	}
+	if err := database.DB.Model(&fragment).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to flag fragment: %w", err)
+	}
+	fragment.SubjectFlag = reason
+	fragment.Content = ""
+	fragment.Claims = nil
+	return &fragment, nil
+}
+
+// SubjectSummary is the public view of a soul's verified subject.
+type SubjectSummary struct {
+	Handle           string     `json:"handle"`
+	VerifiedSubject  string     `json:"verified_subject,omitempty"`
+	VerifiedAt       *time.Time `json:"verified_at,omitempty"`
+	ChatPaused       bool       `json:"chat_paused"`
+	FlaggedFragments int64      `json:"flagged_fragments"`
+	RevenueShareBps  int        `json:"revenue_share_bps"`
+	OwedWei          string     `json:"owed_wei"` // subject payouts not marked paid yet
+}
+
+// GetSubjectSummary returns the subject status of a soul.
+func GetSubjectSummary(shell *models.Shell) SubjectSummary {
+	summary := SubjectSummary{
+		Handle:          shell.Handle,
+		VerifiedSubject: shell.VerifiedSubject,
+		VerifiedAt:      shell.SubjectVerifiedAt,
+		ChatPaused:      GetShellSettings(shell.ID).SubjectPaused,
+		RevenueShareBps: config.Cfg.SubjectRevenueShareBps,
+		OwedWei:         "0",
+	}
+	database.DB.Model(&models.Fragment{}).
+		Where("shell_id = ? AND subject_flag <> ''", shell.ID).Count(&summary.FlaggedFragments)
+	if shell.VerifiedSubject != "" {
+		var owed string
+		database.DB.Model(&models.SubjectPayout{}).
+			Select("CAST(COALESCE(SUM(amount_wei), 0) AS TEXT)").
+			Where("shell_id = ? AND LOWER(subject_addr) = LOWER(?) AND paid_at IS NULL", shell.ID, shell.VerifiedSubject).
+			Scan(&owed)
+		if owed != "" {
+			summary.OwedWei = owed
+		}
+	}
+	return summary
+}
+
+// ListSubjectPayouts returns the soul's subject payouts, newest first.
+func ListSubjectPayouts(shell *models.Shell) ([]models.SubjectPayout, error) {
+	var payouts []models.SubjectPayout
+	err := database.DB.Where("shell_id = ?", shell.ID).Order("created_at DESC").Find(&payouts).Error
+	return payouts, err
+}
+
+// MarkSubjectPayoutPaid records the owner's transfer of a payout to the subject.
+// The tx must be a mined transfer from the owner to the payout's subject
+// address of at least the payout amount, and pay no other payout.
+func MarkSubjectPayoutPaid(shell *models.Shell, payoutID, txHash string) (*models.SubjectPayout, error) {
+	if !txHashRegex.MatchString(txHash) {
+		return nil, fmt.Errorf("invalid transaction hash")
+	}
+	txHash = strings.ToLower(txHash)
+	var payout models.SubjectPayout
+	if err := database.DB.Where("id = ? AND shell_id = ?", payoutID, shell.ID).First(&payout).Error; err != nil {
+		return nil, ErrPayoutNotFound
+	}
+	if payout.PaidAt != nil {
+		return nil, fmt.Errorf("payout was already marked paid")
+	}
+	if chain.C() == nil {
+		return nil, fmt.Errorf("on-chain payment verification is unavailable")
+	}
+
+	var reused int64
+	database.DB.Model(&models.SubjectPayout{}).Where("paid_tx_hash = ?", txHash).Count(&reused)
+	if reused > 0 {
+		return nil, ErrPayoutTxReused
+	}
+
+	amount, ok := new(big.Int).SetString(payout.AmountWei, 10)
+	if !ok {
+		return nil, fmt.Errorf("payout has an invalid amount %q", payout.AmountWei)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+	if _, err := chain.VerifyPayment(ctx, txHash,
+		common.HexToAddress(shell.OwnerAddr), common.HexToAddress(payout.SubjectAddr), amount); err != nil {
+		return nil, fmt.Errorf("payment verification failed: %w", err)
+	}
+
+	// The unique index on paid_tx_hash settles concurrent claims of one tx
+	now := time.Now()
+	res := database.DB.Model(&models.SubjectPayout{}).
+		Where("id = ? AND paid_at IS NULL", payout.ID).
+		Updates(map[string]interface{}{"paid_at": now, "paid_tx_hash": txHash})
+	if database.IsUniqueViolation(res.Error, database.SubjectPayoutTxIndex) {
+		return nil, ErrPayoutTxReused
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("payout was already marked paid")
+	}
+	payout.PaidAt = &now
+	payout.PaidTxHash = txHash
+	return &payout, nil
+}
+
+// accrueSubjectPayout books the verified subject's share of a paid license.
+func accrueSubjectPayout(shell *models.Shell, license *models.ShellLicense) {
+	bps := config.Cfg.SubjectRevenueShareBps
+	if shell.VerifiedSubject == "" || bps <= 0 {
+		return
+	}
+	price, ok := new(big.Int).SetString(license.PriceWei, 10)
+	if !ok || price.Sign() <= 0 {
+		return
+	}
+	share := new(big.Int).Div(new(big.Int).Mul(price, big.NewInt(int64(bps))), big.NewInt(10000))
+	if share.Sign() == 0 {
+		return
+	}
+	payout := &models.SubjectPayout{
+		ShellID:     shell.ID,
+		LicenseID:   license.ID,
+		SubjectAddr: shell.VerifiedSubject,
+		AmountWei:   share.String(),
+		ShareBps:    bps,
+	}
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(payout).Error; err != nil {
+		util.Log.Error("[subject] Failed to book payout for license %s of @%s: %v", license.ID, shell.Handle, err)
+	}
+}
+
+func generateSubjectCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "ensoul-" + strings.ToUpper(hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+)
+
+// Limits on soul topic tags.
+const (
+	maxShellTags     = 8
+	maxShellTagList  = 200 // tags returned by ListShellTags
+	maxTagFilterTags = 3   // tags combined in one ?tag= filter
+)
+
+// NormalizeShellTags lowercases, trims and de-duplicates a soul's topic tags
+// and validates their format, like Claw capability tags.
+func NormalizeShellTags(tags []string) ([]string, error) {
+	return normalizeTags(tags, maxShellTags)
+}
+
+// seedShellTags keeps the well-formed tags the seed LLM suggested, dropping
+// the rest instead of failing the preview.
+func seedShellTags(suggested []string) []string {
+	tags := make([]string, 0, maxShellTags)
+	for _, t := range suggested {
+		t = strings.ReplaceAll(strings.TrimSpace(t), " ", "-")
+		normalized, err := normalizeTags([]string{t}, 1)
+		if err != nil || containsTag(tags, normalized[0]) {
+			continue
+		}
+		tags = append(tags, normalized[0])
+		if len(tags) == maxShellTags {
+			break
+		}
+	}
+	return tags
+}
+
+// ParseTagFilter parses a comma-separated ?tag= filter.
+func ParseTagFilter(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	return normalizeTags(strings.Split(raw, ","), maxTagFilterTags)
+}
+
+// SetShellTags replaces a soul's topic tags; by is the owner or admin wallet.
+func SetShellTags(shell *models.Shell, tags []string, by string) error {
+	tags, err := NormalizeShellTags(tags)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Model(shell).UpdateColumn("tags", models.StringList(tags)).Error; err != nil {
+		return fmt.Errorf("failed to update tags: %w", err)
+	}
+	shell.Tags = tags
+	util.Log.Info("[shell] Tags of @%s set to %v by %s", shell.Handle, tags, by)
+	return nil
+}
+
+// containsTag reports whether tags includes tag.
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// tagsJSON encodes tags for a jsonb containment (@>) query.
+func tagsJSON(tags []string) string {
+	b, _ := json.Marshal(tags)
+	return string(b)
+}
+
+// ShellTagCount is a tag and how many listed souls carry it.
+type ShellTagCount struct {
+	Tag   string `json:"tag"`
+	Souls int    `json:"souls"`
+}
+
+// ListShellTags returns the tags of listed souls, most used first.
+func ListShellTags() ([]ShellTagCount, error) {
+	counts := []ShellTagCount{}
+	from, tag := database.JSONArrayElements("s.tags", "t")
+	err := database.ReadDB().Raw(`
+		SELECT `+tag+` AS tag, COUNT(*) AS souls
+		FROM shells s, `+from+`
+		WHERE s.deleted_at IS NULL AND s.stage <> ? AND `+models.ShellOnChainSQL+`
+		GROUP BY `+tag+`
+		ORDER BY souls DESC, tag ASC
+		LIMIT ?`, models.StagePending, maxShellTagList).Scan(&counts).Error
+	return counts, err
+}
+
+// TagCoverage summarizes open task board work for souls with one tag.
+type TagCoverage struct {
+	Tag          string `json:"tag"`
+	Souls        int    `json:"souls"`         // souls with open tasks carrying the tag
+	OpenTasks    int    `json:"open_tasks"`    // their open (soul, dimension) tasks
+	HighPriority int    `json:"high_priority"` // of which barely started
+	TaggedClaws  int    `json:"tagged_claws"`  // claimed Claws declaring the tag
+}
+
+// GetTagCoverage groups the task board by soul tag, so Claws can pick a
+// domain to specialize in. Domains with the most open work per tagged Claw
+// come first.
+func GetTagCoverage() ([]TagCoverage, error) {
+	tasks, err := GetTaskBoard()
+	if err != nil {
+		return nil, err
+	}
+
+	byTag := make(map[string]*TagCoverage)
+	souls := make(map[string]map[string]bool)
+	for _, t := range tasks {
+		handle := t["handle"].(string)
+		for _, tag := range t["tags"].([]string) {
+			cov := byTag[tag]
+			if cov == nil {
+				cov = &TagCoverage{Tag: tag}
+				byTag[tag] = cov
+				souls[tag] = make(map[string]bool)
+			}
+			cov.OpenTasks++
+			if t["priority"] == "high" {
+				cov.HighPriority++
+			}
+			souls[tag][handle] = true
+		}
+	}
+
+	var tagCounts []struct {
+		Tag   string
+		Claws int
+	}
+	from, tag := database.JSONArrayElements("c.tags", "t")
+	if err := database.DB.Raw(`
+		SELECT `+tag+` AS tag, COUNT(*) AS claws
+		FROM claws c, `+from+`
+		WHERE c.deleted_at IS NULL AND c.status = ?
+		GROUP BY `+tag, models.ClawStatusClaimed).Scan(&tagCounts).Error; err != nil {
+		return nil, err
+	}
+	for _, tc := range tagCounts {
+		if cov := byTag[tagValue(tc.Tag)]; cov != nil {
+			cov.TaggedClaws += tc.Claws
+		}
+	}
+
+	coverage := make([]TagCoverage, 0, len(byTag))
+	for tag, cov := range byTag {
+		cov.Souls = len(souls[tag])
+		coverage = append(coverage, *cov)
+	}
+	sort.Slice(coverage, func(i, j int) bool {
+		a := float64(coverage[i].OpenTasks) / float64(coverage[i].TaggedClaws+1)
+		b := float64(coverage[j].OpenTasks) / float64(coverage[j].TaggedClaws+1)
+		if a != b {
+			return a > b
+		}
+		return coverage[i].Tag < coverage[j].Tag
+	})
+	return coverage, nil
+}
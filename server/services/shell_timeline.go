@@ -0,0 +1,194 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/google/uuid"
+)
+
+// timelineMaxFragments caps the notable fragments shown on a timeline.
+const timelineMaxFragments = 20
+
+// Timeline event types
+const (
+	TimelineMint      = "mint"
+	TimelineImport    = "import"
+	TimelineEnsouling = "ensouling"
+	TimelineStage     = "stage"
+	TimelineFragment  = "fragment"
+	TimelineRetired   = "retired"
+	TimelineRevoked   = "revoked"
+)
+
+// TimelineDelta is a dimension score change made by an ensouling.
+type TimelineDelta struct {
+	Dimension string `json:"dimension"`
+	Before    int    `json:"before"`
+	After     int    `json:"after"`
+	Delta     int    `json:"delta"`
+}
+
+// TimelineEvent is one milestone on a soul's timeline. Type decides which of
+// the optional fields are set.
+type TimelineEvent struct {
+	Type  string    `json:"type"`
+	At    time.Time `json:"at"`
+	Title string    `json:"title"`
+
+	// ensouling
+	Version     int             `json:"version,omitempty"`
+	FragsMerged int             `json:"frags_merged,omitempty"`
+	Summary     string          `json:"summary,omitempty"`
+	Deltas      []TimelineDelta `json:"deltas,omitempty"`
+	// ensouling (partial) and fragment
+	Dimension string `json:"dimension,omitempty"`
+	// stage
+	FromStage string `json:"from_stage,omitempty"`
+	ToStage   string `json:"to_stage,omitempty"`
+	// fragment
+	FragmentID *uuid.UUID `json:"fragment_id,omitempty"`
+	Excerpt    string     `json:"excerpt,omitempty"`
+	ClawName   string     `json:"claw_name,omitempty"`
+	Confidence float64    `json:"confidence,omitempty"`
+
+	TxHash string `json:"tx_hash,omitempty"`
+}
+
+// TimelinePoint is the soul's dimension scores as of one DNA version, for
+// drawing score lines under the events.
+type TimelinePoint struct {
+	Version int            `json:"version"`
+	At      time.Time      `json:"at"`
+	Scores  map[string]int `json:"scores"`
+}
+
+// ShellTimeline is a soul's history laid out for a timeline view.
+type ShellTimeline struct {
+	Handle     string          `json:"handle"`
+	Stage      string          `json:"stage"`
+	DNAVersion int             `json:"dna_version"`
+	Start      time.Time       `json:"start"`
+	End        time.Time       `json:"end"` // the latest event
+	Events     []TimelineEvent `json:"events"`
+	Scores     []TimelinePoint `json:"scores"`
+}
+
+// GetShellTimeline assembles a soul's milestones oldest first: its mint or
+// import, every deployed DNA version with the dimension scores it moved,
+// stage changes, notable accepted fragments (curator confidence of at least
+// feedNotableConfidence, not flagged by the subject), and retirement or
+// revocation. Scores holds the six dimension scores per version.
+func GetShellTimeline(shell *models.Shell) (*ShellTimeline, error) {
+	var ensoulings []models.Ensouling
+	if err := database.DB.Select("id", "version_to", "frags_merged", "summary_diff", "dimension", "tx_hash",
+		"dimensions_before", "dimensions_after", "created_at").
+		Where("shell_id = ? AND status = ?", shell.ID, models.EnsoulingDeployed).
+		Order("version_to ASC").Find(&ensoulings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load ensoulings: %w", err)
+	}
+	transitions, err := GetStageHistory(shell)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stage history: %w", err)
+	}
+	var fragments []models.Fragment
+	if err := database.DB.Preload("Claw").
+		Where("shell_id = ? AND status = ? AND confidence >= ? AND subject_flag = ''",
+			shell.ID, models.FragStatusAccepted, feedNotableConfidence).
+		Order("confidence DESC, created_at ASC").Limit(timelineMaxFragments).Find(&fragments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load fragments: %w", err)
+	}
+
+	events := make([]TimelineEvent, 0, len(ensoulings)+len(transitions)+len(fragments)+2)
+	if shell.ImportedAt != nil {
+		events = append(events, TimelineEvent{Type: TimelineImport, At: *shell.ImportedAt,
+			Title: fmt.Sprintf("@%s imported from the Identity Registry", shell.Handle)})
+	} else {
+		events = append(events, TimelineEvent{Type: TimelineMint, At: shell.CreatedAt,
+			Title: fmt.Sprintf("@%s minted", shell.Handle), TxHash: shell.MintTxHash})
+	}
+
+	scores := []TimelinePoint{}
+	for i, e := range ensoulings {
+		if i == 0 && len(e.DimensionsBefore) > 0 {
+			scores = append(scores, TimelinePoint{Version: e.VersionTo - 1, At: shell.CreatedAt, Scores: timelineScores(e.DimensionsBefore)})
+		}
+		event := TimelineEvent{
+			Type:        TimelineEnsouling,
+			At:          e.CreatedAt,
+			Title:       fmt.Sprintf("Evolved to DNA v%d", e.VersionTo),
+			Version:     e.VersionTo,
+			FragsMerged: e.FragsMerged,
+			Summary:     e.SummaryDiff,
+			Dimension:   e.Dimension,
+			Deltas:      []TimelineDelta{},
+			TxHash:      e.TxHash,
+		}
+		if e.Dimension != "" {
+			event.Title += " (" + e.Dimension + ")"
+		}
+		if len(e.DimensionsAfter) > 0 {
+			for _, d := range diffDimensions(e.DimensionsBefore, e.DimensionsAfter) {
+				if d.Delta != 0 {
+					event.Deltas = append(event.Deltas, TimelineDelta{Dimension: d.Dimension, Before: d.ScoreBefore, After: d.ScoreAfter, Delta: d.Delta})
+				}
+			}
+			scores = append(scores, TimelinePoint{Version: e.VersionTo, At: e.CreatedAt, Scores: timelineScores(e.DimensionsAfter)})
+		}
+		events = append(events, event)
+	}
+	for _, t := range transitions {
+		events = append(events, TimelineEvent{
+			Type:      TimelineStage,
+			At:        t.CreatedAt,
+			Title:     fmt.Sprintf("Became %s", t.ToStage),
+			FromStage: t.FromStage,
+			ToStage:   t.ToStage,
+			TxHash:    t.TxHash,
+		})
+	}
+	for i := range fragments {
+		f := &fragments[i]
+		events = append(events, TimelineEvent{
+			Type:       TimelineFragment,
+			At:         f.CreatedAt,
+			Title:      fmt.Sprintf("Notable %s fragment", f.Dimension),
+			Dimension:  f.Dimension,
+			FragmentID: &f.ID,
+			Excerpt:    feedExcerpt(f.Content, feedExcerptChars),
+			ClawName:   f.Claw.Name,
+			Confidence: f.Confidence,
+		})
+	}
+	if shell.RetiredAt != nil {
+		events = append(events, TimelineEvent{Type: TimelineRetired, At: *shell.RetiredAt, Title: "Retired by its owner"})
+	}
+	if shell.RevokedAt != nil {
+		events = append(events, TimelineEvent{Type: TimelineRevoked, At: *shell.RevokedAt, Title: "NFT burned"})
+	}
+
+	// Stable, so a mint stays ahead of anything recorded in the same instant
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return &ShellTimeline{
+		Handle:     shell.Handle,
+		Stage:      shell.Stage,
+		DNAVersion: shell.DNAVersion,
+		Start:      events[0].At,
+		End:        events[len(events)-1].At,
+		Events:     events,
+		Scores:     scores,
+	}, nil
+}
+
+// timelineScores returns the score of each of the six dimensions.
+func timelineScores(dims models.JSON) map[string]int {
+	parsed := (&models.Shell{Dimensions: dims}).GetDimensions()
+	scores := make(map[string]int, len(validDimensions))
+	for dim := range validDimensions {
+		scores[dim] = parsed[dim].Score
+	}
+	return scores
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// Weights of the similarity components; they sum to 1.
+const (
+	similarSummaryWeight   = 0.6  // seed summary embedding
+	similarDimensionWeight = 0.25 // dimension summaries embedding
+	similarProfileWeight   = 0.15 // shape of the six dimension scores
+)
+
+// dimensionOrder fixes the order of dimensions in text and score vectors.
+var dimensionOrder = []string{
+	models.DimPersonality, models.DimKnowledge, models.DimStance,
+	models.DimStyle, models.DimRelationship, models.DimTimeline,
+}
+
+// SimilarShell is one recommendation for GET /api/shell/:handle/similar.
+type SimilarShell struct {
+	Handle            string  `json:"handle"`
+	DisplayName       string  `json:"display_name"`
+	AvatarURL         string  `json:"avatar_url"`
+	Stage             string  `json:"stage"`
+	DNAVersion        int     `json:"dna_version"`
+	Similarity        float64 `json:"similarity"`
+	SummarySimilarity float64 `json:"summary_similarity"`
+	ProfileSimilarity float64 `json:"profile_similarity"`
+}
+
+// FindSimilarShells returns up to limit minted souls most similar to the given one,
+// by seed summary, dimension summaries and dimension score profile. It only
+// reads stored embeddings; the soul-embeddings job keeps them current.
+func FindSimilarShells(shell *models.Shell, limit int) ([]SimilarShell, error) {
+	var candidates []models.Shell
+	database.DB.Where("stage <> ? AND "+models.ShellOnChainSQL, models.StagePending).Find(&candidates)
+
+	embeddings, err := loadSoulEmbeddings(candidates)
+	if err != nil {
+		return nil, err
+	}
+	self, ok := embeddings[shell.ID]
+	if !ok {
+		return []SimilarShell{}, nil
+	}
+	selfProfile := dimensionScoreVector(shell)
+
+	results := make([]SimilarShell, 0, len(candidates))
+	for _, c := range candidates {
+		if c.ID == shell.ID {
+			continue
+		}
+		e, ok := embeddings[c.ID]
+		if !ok {
+			continue
+		}
+		summarySim := CosineSimilarity(self.SummaryVector, e.SummaryVector)
+		dimensionSim := CosineSimilarity(self.DimensionVector, e.DimensionVector)
+		profileSim := CosineSimilarity(selfProfile, dimensionScoreVector(&c))
+		score := similarSummaryWeight*summarySim + similarDimensionWeight*dimensionSim + similarProfileWeight*profileSim
+
+		results = append(results, SimilarShell{
+			Handle:            c.Handle,
+			DisplayName:       c.DisplayName,
+			AvatarURL:         c.AvatarURL,
+			Stage:             c.Stage,
+			DNAVersion:        c.DNAVersion,
+			Similarity:        roundTo(score, 4),
+			SummarySimilarity: roundTo(summarySim, 4),
+			ProfileSimilarity: roundTo(profileSim, 4),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Similarity > results[j].Similarity })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// loadSoulEmbeddings returns the stored embeddings of the given shells that
+// were made by the current embedding model. A soul whose texts changed keeps
+// its previous vectors until the refresh job catches up.
+func loadSoulEmbeddings(shells []models.Shell) (map[uuid.UUID]models.ShellEmbedding, error) {
+	byID := make(map[uuid.UUID]models.ShellEmbedding, len(shells))
+	if len(shells) == 0 {
+		return byID, nil
+	}
+	ids := make([]uuid.UUID, len(shells))
+	for i, s := range shells {
+		ids[i] = s.ID
+	}
+
+	var stored []models.ShellEmbedding
+	if err := database.DB.Where("shell_id IN ? AND model = ?", ids, EmbeddingModel()).Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load soul embeddings: %w", err)
+	}
+	for _, e := range stored {
+		byID[e.ShellID] = e
+	}
+	return byID, nil
+}
+
+// StartSoulEmbeddingRefresh periodically (re-)embeds souls whose embeddings
+// are missing, stale or made by a different model.
+func StartSoulEmbeddingRefresh(interval time.Duration) {
+	scheduleJob("soul-embeddings", "Embed new and changed souls for similar-soul recommendations", interval, true, refreshSoulEmbeddings)
+	util.Log.Info("[similar] Soul embedding refresh started (every %v)", interval)
+}
+
+// refreshSoulEmbeddings embeds every minted soul whose stored embedding is
+// out of date. Empty texts are not sent to the provider; their vector stays
+// empty and contributes no similarity.
+func refreshSoulEmbeddings() error {
+	var shells []models.Shell
+	if err := database.DB.Where("stage <> ? AND "+models.ShellOnChainSQL, models.StagePending).Find(&shells).Error; err != nil {
+		return fmt.Errorf("failed to load souls: %w", err)
+	}
+
+	var stored []models.ShellEmbedding
+	database.DB.Select("shell_id", "model", "source_hash").Find(&stored)
+	byID := make(map[uuid.UUID]models.ShellEmbedding, len(stored))
+	for _, e := range stored {
+		byID[e.ShellID] = e
+	}
+
+	model := EmbeddingModel()
+	var stale []models.Shell
+	for _, s := range shells {
+		e, ok := byID[s.ID]
+		if !ok || e.Model != model || e.SourceHash != soulEmbeddingHash(&s) {
+			stale = append(stale, s)
+		}
+	}
+
+	embedded := 0
+	for start := 0; start < len(stale); start += embeddingBatchSize / 2 {
+		end := start + embeddingBatchSize/2
+		if end > len(stale) {
+			end = len(stale)
+		}
+		batch := stale[start:end]
+
+		// Up to two texts per shell: summary, then dimension summaries
+		var texts []string
+		slots := make([][2]int, len(batch)) // index into texts, -1 = empty
+		for i, s := range batch {
+			for j, text := range []string{soulSummaryText(&s), soulDimensionText(&s)} {
+				slots[i][j] = -1
+				if strings.TrimSpace(text) != "" {
+					slots[i][j] = len(texts)
+					texts = append(texts, text)
+				}
+			}
+		}
+		var vectors [][]float64
+		if len(texts) > 0 {
+			var err error
+			if vectors, err = Embed(texts); err != nil {
+				return fmt.Errorf("failed to embed souls: %w", err)
+			}
+		}
+		vector := func(slot int) []float64 {
+			if slot < 0 {
+				return nil
+			}
+			return vectors[slot]
+		}
+
+		rows := make([]models.ShellEmbedding, len(batch))
+		for i, s := range batch {
+			rows[i] = models.ShellEmbedding{
+				ShellID:         s.ID,
+				Model:           model,
+				SourceHash:      soulEmbeddingHash(&s),
+				SummaryVector:   vector(slots[i][0]),
+				DimensionVector: vector(slots[i][1]),
+			}
+		}
+		if err := database.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "shell_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"model", "source_hash", "summary_vector", "dimension_vector", "updated_at"}),
+		}).Create(&rows).Error; err != nil {
+			return fmt.Errorf("failed to store soul embeddings: %w", err)
+		}
+		embedded += len(batch)
+	}
+	if embedded > 0 {
+		util.Log.Debug("[similar] Embedded %d souls", embedded)
+	}
+	return nil
+}
+
+// soulSummaryText is the text embedded for a soul's overall identity.
+func soulSummaryText(shell *models.Shell) string {
+	return strings.TrimSpace(shell.DisplayName + " (@" + shell.Handle + "): " + shell.SeedSummary)
+}
+
+// soulDimensionText concatenates the dimension summaries in a fixed order.
+func soulDimensionText(shell *models.Shell) string {
+	dims := shell.GetDimensions()
+	var sb strings.Builder
+	for _, d := range dimensionOrder {
+		if data, ok := dims[d]; ok && data.Summary != "" {
+			sb.WriteString(d + ": " + data.Summary + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// soulEmbeddingHash fingerprints the embedded texts.
+func soulEmbeddingHash(shell *models.Shell) string {
+	return util.HashContent(soulSummaryText(shell) + "\n" + soulDimensionText(shell))
+}
+
+// dimensionScoreVector returns the six dimension scores, centered on their mean
+// so cosine similarity compares the profile's shape rather than its overall level.
+func dimensionScoreVector(shell *models.Shell) []float64 {
+	dims := shell.GetDimensions()
+	vec := make([]float64, len(dimensionOrder))
+	mean := 0.0
+	for i, d := range dimensionOrder {
+		vec[i] = float64(dims[d].Score)
+		mean += vec[i]
+	}
+	mean /= float64(len(vec))
+	for i := range vec {
+		vec[i] -= mean
+	}
+	return vec
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
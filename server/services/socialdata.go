@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
-	"time"
 
 	"github.com/ensoul-labs/ensoul-server/config"
 	"github.com/ensoul-labs/ensoul-server/util"
@@ -28,7 +28,7 @@ const (
 type socialDataClient struct {
 	baseURL    string
 	apiKey     string
-	httpClient *http.Client
+	httpClient *outboundClient
 }
 
 func newSocialDataClient() *socialDataClient {
@@ -40,11 +40,9 @@ func newSocialDataClient() *socialDataClient {
 	base = strings.TrimRight(base, "/")
 
 	return &socialDataClient{
-		baseURL: base,
-		apiKey:  config.Cfg.SocialDataAPIKey,
-		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
-		},
+		baseURL:    base,
+		apiKey:     config.Cfg.SocialDataAPIKey,
+		httpClient: socialDataHTTP,
 	}
 }
 
@@ -221,6 +219,23 @@ func (c *socialDataClient) FetchTweets(userID string, maxTweets int) ([]sdTweet,
 	return allTweets, nil
 }
 
+// FetchTweet retrieves a single tweet with its author by tweet ID.
+func (c *socialDataClient) FetchTweet(tweetID string) (*sdTweet, error) {
+	body, status, err := c.doRequest("/twitter/statuses/show?id=" + url.QueryEscape(tweetID))
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("socialdata: tweet request failed (status %d): %s", status, string(body))
+	}
+
+	var tweet sdTweet
+	if err := json.Unmarshal(body, &tweet); err != nil {
+		return nil, fmt.Errorf("socialdata: failed to decode tweet: %w", err)
+	}
+	return &tweet, nil
+}
+
 // ──────────────────────────────────────────────────────────────────────────────
 // Conversion helpers: SocialData → internal TwitterProfile
 // ──────────────────────────────────────────────────────────────────────────────
@@ -297,6 +312,7 @@ func convertSocialDataProfile(user *sdUserProfile, tweets []sdTweet) *TwitterPro
 			ID:        t.IDStr,
 			Text:      text,
 			CreatedAt: t.TweetCreatedAt,
+			Lang:      t.Lang,
 		})
 	}
 
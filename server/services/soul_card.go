@@ -0,0 +1,346 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/models"
+)
+
+// Soul cards are the NFT art of a soul: handle, stage, DNA version and the
+// six-dimension radar, rendered as SVG or PNG. The agentURI points at the PNG,
+// which always reflects the soul's current state.
+
+const (
+	soulCardSize    = 600
+	soulCardVersion = "v1" // bump to invalidate cached cards when the layout changes
+)
+
+// stageColors are the radar fill of each stage.
+var stageColors = map[string]color.RGBA{
+	models.StageEmbryo:   {148, 163, 184, 255},
+	models.StageGrowing:  {52, 211, 153, 255},
+	models.StageMature:   {96, 165, 250, 255},
+	models.StageEvolving: {244, 114, 182, 255},
+}
+
+// SoulCardURL is the public URL of a soul's card, used as the image of its
+// ERC-8004 registration file.
+func SoulCardURL(handle string) string {
+	return config.Cfg.PublicURL("/api/shell/" + handle + "/card.png")
+}
+
+// soulCard is the data a card shows.
+type soulCard struct {
+	Handle     string
+	Stage      string
+	DNAVersion int
+	Scores     []float64 // dimensionOrder, 0–1
+}
+
+func newSoulCard(shell *models.Shell) soulCard {
+	dims := shell.GetDimensions()
+	scores := make([]float64, len(dimensionOrder))
+	for i, d := range dimensionOrder {
+		scores[i] = math.Max(0, math.Min(float64(dims[d].Score), 100)) / 100
+	}
+	return soulCard{Handle: shell.Handle, Stage: shell.Stage, DNAVersion: shell.DNAVersion, Scores: scores}
+}
+
+// SoulCardETag fingerprints what a shell's card shows, so clients and
+// marketplaces can revalidate cheaply.
+func SoulCardETag(shell *models.Shell) string {
+	raw, _ := json.Marshal(newSoulCard(shell))
+	sum := sha256.Sum256(append([]byte(soulCardVersion), raw...))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (c soulCard) title() string {
+	return "@" + c.Handle
+}
+
+func (c soulCard) subtitle() string {
+	return fmt.Sprintf("%s · DNA v%d", strings.ToUpper(c.Stage), c.DNAVersion)
+}
+
+func (c soulCard) accent() color.RGBA {
+	if col, ok := stageColors[c.Stage]; ok {
+		return col
+	}
+	return stageColors[models.StageEmbryo]
+}
+
+// radarPoint returns the point on axis i at fraction r of the radar's radius
+// (axis 0 points up, the others follow clockwise).
+func radarPoint(i int, r float64) (float64, float64) {
+	const cx, cy, radius = soulCardSize / 2, 345.0, 160.0
+	angle := -math.Pi/2 + 2*math.Pi*float64(i)/float64(len(dimensionOrder))
+	return cx + radius*r*math.Cos(angle), cy + radius*r*math.Sin(angle)
+}
+
+func cssColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// RenderSoulCardSVG renders a shell's card as SVG.
+func RenderSoulCardSVG(shell *models.Shell) []byte {
+	card := newSoulCard(shell)
+	fg, bg := handleColors(card.Handle)
+	accent := card.accent()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, soulCardSize, soulCardSize, soulCardSize, soulCardSize)
+	fmt.Fprintf(&sb, `<defs><linearGradient id="bg" x1="0" y1="0" x2="0" y2="1"><stop offset="0" stop-color="%s"/><stop offset="1" stop-color="#0b0f19"/></linearGradient></defs>`, cssColor(bg))
+	fmt.Fprintf(&sb, `<rect width="100%%" height="100%%" fill="url(#bg)"/><rect x="6" y="6" width="%d" height="%d" rx="24" fill="none" stroke="%s" stroke-width="4"/>`, soulCardSize-12, soulCardSize-12, cssColor(fg))
+	fmt.Fprintf(&sb, `<text x="300" y="80" text-anchor="middle" font-family="monospace" font-size="40" font-weight="bold" fill="#f8fafc">%s</text>`, html.EscapeString(card.title()))
+	fmt.Fprintf(&sb, `<text x="300" y="120" text-anchor="middle" font-family="monospace" font-size="22" fill="%s">%s</text>`, cssColor(accent), html.EscapeString(card.subtitle()))
+
+	// Grid rings and spokes
+	for _, ring := range []float64{0.25, 0.5, 0.75, 1} {
+		sb.WriteString(`<polygon fill="none" stroke="#334155" stroke-width="1" points="`)
+		for i := range dimensionOrder {
+			x, y := radarPoint(i, ring)
+			fmt.Fprintf(&sb, "%.1f,%.1f ", x, y)
+		}
+		sb.WriteString(`"/>`)
+	}
+	for i, d := range dimensionOrder {
+		cx, cy := radarPoint(i, 0)
+		x, y := radarPoint(i, 1)
+		fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#334155" stroke-width="1"/>`, cx, cy, x, y)
+		lx, ly := radarPoint(i, 1.18)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="middle" font-family="monospace" font-size="14" fill="#94a3b8">%s</text>`, lx, ly, strings.ToUpper(d))
+	}
+
+	// Scores
+	sb.WriteString(`<polygon fill="` + cssColor(accent) + `" fill-opacity="0.45" stroke="` + cssColor(accent) + `" stroke-width="3" points="`)
+	for i, s := range card.Scores {
+		x, y := radarPoint(i, s)
+		fmt.Fprintf(&sb, "%.1f,%.1f ", x, y)
+	}
+	sb.WriteString(`"/>`)
+	sb.WriteString(`<text x="300" y="575" text-anchor="middle" font-family="monospace" font-size="16" fill="#64748b">ENSOUL</text></svg>`)
+	return []byte(sb.String())
+}
+
+// RenderSoulCardPNG renders a shell's card as PNG. It draws the same layout
+// as the SVG with a built-in bitmap font, so no font files are needed.
+func RenderSoulCardPNG(shell *models.Shell) ([]byte, error) {
+	card := newSoulCard(shell)
+	fg, bg := handleColors(card.Handle)
+	accent := card.accent()
+	img := image.NewRGBA(image.Rect(0, 0, soulCardSize, soulCardSize))
+
+	// Background: the handle's color fading to near-black, with a border
+	bottom := color.RGBA{11, 15, 25, 255}
+	for y := 0; y < soulCardSize; y++ {
+		t := float64(y) / float64(soulCardSize-1)
+		row := lerpColor(bg, bottom, t)
+		for x := 0; x < soulCardSize; x++ {
+			img.SetRGBA(x, y, row)
+		}
+	}
+	for i := 6; i < 10; i++ {
+		strokeRect(img, i, fg)
+	}
+
+	title := card.title()
+	scale := 5
+	for scale > 2 && textWidth(title, scale) > soulCardSize-60 {
+		scale--
+	}
+	drawText(img, title, 60, scale, color.RGBA{248, 250, 252, 255})
+	drawText(img, strings.ReplaceAll(card.subtitle(), "·", "-"), 110, 3, accent)
+
+	grid := color.RGBA{51, 65, 85, 255}
+	for _, ring := range []float64{0.25, 0.5, 0.75, 1} {
+		pts := make([][2]float64, len(dimensionOrder))
+		for i := range dimensionOrder {
+			pts[i][0], pts[i][1] = radarPoint(i, ring)
+		}
+		strokePolygon(img, pts, 1, grid)
+	}
+	for i, d := range dimensionOrder {
+		cx, cy := radarPoint(i, 0)
+		x, y := radarPoint(i, 1)
+		drawLine(img, cx, cy, x, y, 1, grid)
+		lx, ly := radarPoint(i, 1.18)
+		label := strings.ToUpper(d)
+		drawTextAt(img, label, int(lx)-textWidth(label, 2)/2, int(ly)-7, 2, color.RGBA{148, 163, 184, 255})
+	}
+
+	pts := make([][2]float64, len(card.Scores))
+	for i, s := range card.Scores {
+		pts[i][0], pts[i][1] = radarPoint(i, s)
+	}
+	fillPolygon(img, pts, accent, 0.45)
+	strokePolygon(img, pts, 3, accent)
+
+	drawText(img, "ENSOUL", 568, 2, color.RGBA{100, 116, 139, 255})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// --- Raster helpers ---
+
+func lerpColor(a, b color.RGBA, t float64) color.RGBA {
+	return color.RGBA{
+		uint8(float64(a.R)*(1-t) + float64(b.R)*t),
+		uint8(float64(a.G)*(1-t) + float64(b.G)*t),
+		uint8(float64(a.B)*(1-t) + float64(b.B)*t),
+		255,
+	}
+}
+
+// blend paints c over the pixel at (x, y) with the given opacity.
+func blend(img *image.RGBA, x, y int, c color.RGBA, alpha float64) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+	img.SetRGBA(x, y, lerpColor(img.RGBAAt(x, y), c, alpha))
+}
+
+func strokeRect(img *image.RGBA, inset int, c color.RGBA) {
+	lo, hi := inset, soulCardSize-1-inset
+	for i := lo; i <= hi; i++ {
+		img.SetRGBA(i, lo, c)
+		img.SetRGBA(i, hi, c)
+		img.SetRGBA(lo, i, c)
+		img.SetRGBA(hi, i, c)
+	}
+}
+
+// drawLine draws a line of the given width by stamping squares along it.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 float64, width int, c color.RGBA) {
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	half := width / 2
+	for s := 0; s <= steps; s++ {
+		t := float64(s) / float64(steps)
+		x := int(math.Round(x0 + (x1-x0)*t))
+		y := int(math.Round(y0 + (y1-y0)*t))
+		for dy := -half; dy <= half; dy++ {
+			for dx := -half; dx <= half; dx++ {
+				blend(img, x+dx, y+dy, c, 1)
+			}
+		}
+	}
+}
+
+func strokePolygon(img *image.RGBA, pts [][2]float64, width int, c color.RGBA) {
+	for i := range pts {
+		j := (i + 1) % len(pts)
+		drawLine(img, pts[i][0], pts[i][1], pts[j][0], pts[j][1], width, c)
+	}
+}
+
+// fillPolygon fills a polygon with the even-odd rule, one scanline at a time.
+func fillPolygon(img *image.RGBA, pts [][2]float64, c color.RGBA, alpha float64) {
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range pts {
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	for y := int(math.Ceil(minY)); y <= int(maxY); y++ {
+		fy := float64(y) + 0.5
+		var xs []float64
+		for i := range pts {
+			a, b := pts[i], pts[(i+1)%len(pts)]
+			if (a[1] <= fy) != (b[1] <= fy) {
+				xs = append(xs, a[0]+(fy-a[1])*(b[0]-a[0])/(b[1]-a[1]))
+			}
+		}
+		for i := 0; i+1 < len(xs); i += 2 {
+			x0, x1 := math.Min(xs[i], xs[i+1]), math.Max(xs[i], xs[i+1])
+			for x := int(math.Round(x0)); x < int(math.Round(x1)); x++ {
+				blend(img, x, y, c, alpha)
+			}
+		}
+	}
+}
+
+// glyphs is a 5×7 bitmap font: one byte per row, the low five bits are pixels.
+// Lowercase letters are drawn as uppercase; unknown characters as blanks.
+var glyphs = map[rune][7]byte{
+	'A': {0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D': {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'@': {0x0E, 0x11, 0x01, 0x0D, 0x15, 0x15, 0x0E},
+	'_': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F},
+	'-': {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+}
+
+// textWidth is the width in pixels of s drawn at scale (6 columns per character).
+func textWidth(s string, scale int) int {
+	return (len([]rune(s))*6 - 1) * scale
+}
+
+// drawText draws s horizontally centered with its top at y.
+func drawText(img *image.RGBA, s string, y, scale int, c color.RGBA) {
+	drawTextAt(img, s, (soulCardSize-textWidth(s, scale))/2, y, scale, c)
+}
+
+func drawTextAt(img *image.RGBA, s string, x, y, scale int, c color.RGBA) {
+	for _, r := range strings.ToUpper(s) {
+		g := glyphs[r]
+		for row := 0; row < 7; row++ {
+			for col := 0; col < 5; col++ {
+				if g[row]>>(4-col)&1 == 0 {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						blend(img, x+col*scale+dx, y+row*scale+dy, c, 1)
+					}
+				}
+			}
+		}
+		x += 6 * scale
+	}
+}
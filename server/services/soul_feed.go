@@ -0,0 +1,183 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+)
+
+// Soul activity feed limits.
+const (
+	feedMaxEntries        = 50
+	feedNotableConfidence = 0.85 // accepted fragments at or above this are "notable"
+	feedExcerptChars      = 500
+)
+
+// atomFeed is the subset of RFC 4287 the soul feed uses.
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomPerson  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Link       atomLink       `xml:"link"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    atomText       `xml:"summary"`
+
+	at time.Time
+}
+
+// feedTime formats a time for Atom (RFC 3339, UTC).
+func feedTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// feedExcerpt shortens text to n characters, never splitting one.
+func feedExcerpt(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) <= n {
+		return string(r)
+	}
+	return strings.TrimSpace(string(r[:n])) + "…"
+}
+
+// RenderSoulFeed builds the Atom feed of a soul's public activity: deployed
+// DNA versions with their summary, stage changes, and accepted fragments the
+// curator rated at least feedNotableConfidence. Quarantined versions and
+// fragments the subject flagged are left out. It returns the feed and the
+// time of its newest entry.
+func RenderSoulFeed(shell *models.Shell) ([]byte, time.Time, error) {
+	soulURL := config.Cfg.PublicURL("/soul/" + shell.Handle)
+
+	var ensoulings []models.Ensouling
+	if err := database.DB.Select("id", "version_to", "frags_merged", "summary_diff", "dimension", "created_at").
+		Where("shell_id = ? AND status = ?", shell.ID, models.EnsoulingDeployed).
+		Order("created_at DESC").Limit(feedMaxEntries).Find(&ensoulings).Error; err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to load ensoulings: %w", err)
+	}
+	var transitions []models.ShellStageTransition
+	if err := database.DB.Where("shell_id = ?", shell.ID).
+		Order("created_at DESC").Limit(feedMaxEntries).Find(&transitions).Error; err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to load stage history: %w", err)
+	}
+	var fragments []models.Fragment
+	if err := database.DB.Preload("Claw").
+		Where("shell_id = ? AND status = ? AND confidence >= ? AND subject_flag = ''",
+			shell.ID, models.FragStatusAccepted, feedNotableConfidence).
+		Order("created_at DESC").Limit(feedMaxEntries).Find(&fragments).Error; err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to load fragments: %w", err)
+	}
+
+	entries := make([]atomEntry, 0, len(ensoulings)+len(transitions)+len(fragments))
+	for _, e := range ensoulings {
+		title := fmt.Sprintf("@%s evolved to DNA v%d", shell.Handle, e.VersionTo)
+		categories := []atomCategory{{Term: "ensouling"}}
+		if e.Dimension != "" {
+			title += " (" + e.Dimension + ")"
+			categories = append(categories, atomCategory{Term: e.Dimension})
+		}
+		summary := e.SummaryDiff
+		if summary == "" {
+			summary = fmt.Sprintf("%d fragments merged.", e.FragsMerged)
+		}
+		entries = append(entries, atomEntry{
+			ID:         "urn:uuid:" + e.ID.String(),
+			Title:      title,
+			Link:       atomLink{Rel: "alternate", Type: "text/html", Href: soulURL},
+			Categories: categories,
+			Summary:    atomText{Type: "text", Body: summary},
+			at:         e.CreatedAt,
+		})
+	}
+	for _, t := range transitions {
+		entries = append(entries, atomEntry{
+			ID:         "urn:uuid:" + t.ID.String(),
+			Title:      fmt.Sprintf("@%s is now %s", shell.Handle, t.ToStage),
+			Link:       atomLink{Rel: "alternate", Type: "text/html", Href: soulURL},
+			Categories: []atomCategory{{Term: "stage"}, {Term: t.ToStage}},
+			Summary:    atomText{Type: "text", Body: fmt.Sprintf("@%s grew from %s to %s.", shell.Handle, t.FromStage, t.ToStage)},
+			at:         t.CreatedAt,
+		})
+	}
+	for _, f := range fragments {
+		entry := atomEntry{
+			ID:         "urn:uuid:" + f.ID.String(),
+			Title:      fmt.Sprintf("New %s fragment for @%s", f.Dimension, shell.Handle),
+			Link:       atomLink{Rel: "alternate", Type: "application/json", Href: config.Cfg.PublicURL("/api/fragment/" + f.ID.String())},
+			Categories: []atomCategory{{Term: "fragment"}, {Term: f.Dimension}},
+			Summary:    atomText{Type: "text", Body: feedExcerpt(f.Content, feedExcerptChars)},
+			at:         f.CreatedAt,
+		}
+		if f.Claw.Name != "" {
+			entry.Author = &atomPerson{Name: f.Claw.Name}
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].at.After(entries[j].at) })
+	if len(entries) > feedMaxEntries {
+		entries = entries[:feedMaxEntries]
+	}
+	updated := shell.CreatedAt
+	if len(entries) > 0 {
+		updated = entries[0].at
+	}
+	for i := range entries {
+		entries[i].Updated = feedTime(entries[i].at)
+		entries[i].Published = entries[i].Updated
+	}
+
+	feed := atomFeed{
+		ID:       soulURL,
+		Title:    "@" + shell.Handle + " on Ensoul",
+		Subtitle: feedExcerpt(ShellEssence(shell), 280),
+		Updated:  feedTime(updated),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: config.Cfg.PublicURL("/api/shell/" + shell.Handle + "/feed.atom")},
+			{Rel: "alternate", Type: "text/html", Href: soulURL},
+		},
+		Author:  atomPerson{Name: "Ensoul"},
+		Entries: entries,
+	}
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return append([]byte(xml.Header), out...), updated, nil
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/google/uuid"
+)
+
+// Task board page sizes. The default covers a typical Claw's pick in one call.
+const (
+	taskBoardDefaultLimit = 100
+	taskBoardMaxLimit     = 500
+)
+
+// taskExamplesPerTask is how many recent accepted fragments a task shows.
+const taskExamplesPerTask = 2
+
+// taskExcerptLength is the rune length of an example fragment's excerpt.
+const taskExcerptLength = 160
+
+// taskEvidenceTypes is the evidence the curator looks for per dimension,
+// most persuasive first.
+var taskEvidenceTypes = map[string][]string{
+	models.DimPersonality:  {"behavior_example", "direct_quote", "third_party_account"},
+	models.DimKnowledge:    {"domain_claim_with_source", "published_work", "talk_or_interview"},
+	models.DimStance:       {"dated_statement", "direct_quote", "position_change"},
+	models.DimStyle:        {"verbatim_excerpt", "recurring_phrase", "format_pattern"},
+	models.DimRelationship: {"named_interaction", "public_collaboration", "dated_exchange"},
+	models.DimTimeline:     {"dated_event", "role_change", "source_link"},
+}
+
+// TaskBoardFilter narrows GET /api/tasks. Empty fields match everything.
+type TaskBoardFilter struct {
+	Handle       string
+	Dimension    string
+	Priority     string
+	Tags         []string // soul topic tags, all required
+	MinFollowers int
+}
+
+// TaskExample is a recently accepted fragment for a task's soul and
+// dimension, so a Claw can see the bar and avoid duplicates.
+type TaskExample struct {
+	FragmentID  uuid.UUID `json:"fragment_id"`
+	ContentHash string    `json:"content_hash"`
+	Excerpt     string    `json:"excerpt"`
+}
+
+// ListTasks returns one page of the task board, or of the Claw's fit-filtered
+// board when fit is set. Tasks gain reward_weight, evidence_types and the
+// (soul, dimension) acceptance rate; Claws also get example excerpts, since
+// fragment content is otherwise never public.
+func ListTasks(claw *models.Claw, fit bool, filter TaskBoardFilter, pageStr, limitStr string) (map[string]interface{}, error) {
+	page, _ := strconv.Atoi(pageStr)
+	limit, _ := strconv.Atoi(limitStr)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > taskBoardMaxLimit {
+		limit = taskBoardDefaultLimit
+	}
+
+	matched, err := matchTasks(claw, fit, filter)
+	if err != nil {
+		return nil, err
+	}
+	start := min((page-1)*limit, len(matched))
+	pageTasks := matched[start:min(start+limit, len(matched))]
+	if err := annotateTasks(pageTasks, claw != nil); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"tasks": pageTasks,
+		"total": len(matched),
+		"page":  page,
+		"limit": limit,
+	}, nil
+}
+
+// ListAllTasks returns every task matching the filter, annotated like
+// ListTasks. It backs the unpaginated array form of GET /api/tasks.
+func ListAllTasks(claw *models.Claw, fit bool, filter TaskBoardFilter) ([]map[string]interface{}, error) {
+	matched, err := matchTasks(claw, fit, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := annotateTasks(matched, claw != nil); err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// matchTasks validates the filter and returns the matching tasks of the
+// board, or of the Claw's board when fit is set.
+func matchTasks(claw *models.Claw, fit bool, filter TaskBoardFilter) ([]map[string]interface{}, error) {
+	filter.Handle = SanitizeHandle(filter.Handle)
+	filter.Dimension = strings.ToLower(filter.Dimension)
+	filter.Priority = strings.ToLower(filter.Priority)
+	if filter.Dimension != "" && !validDimensions[filter.Dimension] {
+		return nil, fmt.Errorf("invalid dimension %q", filter.Dimension)
+	}
+	if _, ok := taskPriorityOrder[filter.Priority]; filter.Priority != "" && !ok {
+		return nil, fmt.Errorf("invalid priority %q: use high, medium or low", filter.Priority)
+	}
+
+	var tasks []map[string]interface{}
+	var err error
+	if fit {
+		tasks, err = GetTaskBoardForClaw(claw)
+	} else {
+		tasks, err = GetTaskBoard()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]map[string]interface{}, 0, len(tasks))
+	for _, t := range tasks {
+		if filter.Handle != "" && t["handle"] != filter.Handle ||
+			filter.Dimension != "" && t["dimension"] != filter.Dimension ||
+			filter.Priority != "" && t["priority"] != filter.Priority ||
+			t["followers"].(int) < filter.MinFollowers ||
+			!hasAllTags(t["tags"].([]string), filter.Tags) {
+			continue
+		}
+		matched = append(matched, t)
+	}
+	return matched, nil
+}
+
+// hasAllTags reports whether tags includes every tag in want.
+func hasAllTags(tags, want []string) bool {
+	for _, w := range want {
+		if !containsTag(tags, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// taskRewardWeight is a 0-1 hint of how much an accepted fragment is worth
+// for a task: mostly the dimension's remaining gap (tasks close at score 80),
+// partly the soul's audience, which drives its chat traffic.
+func taskRewardWeight(score, followers int) float64 {
+	gap := float64(80-min(score, 80)) / 80
+	audience := math.Min(math.Log10(float64(followers)+1)/7, 1) // 10M followers = 1
+	return math.Round((0.7*gap+0.3*audience)*100) / 100
+}
+
+// annotateTasks adds reward, evidence, acceptance and (if withExamples)
+// example fields to a page of tasks.
+func annotateTasks(tasks []map[string]interface{}, withExamples bool) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	handles := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		handles = append(handles, t["handle"].(string))
+	}
+	var shells []models.Shell
+	if err := database.DB.Select("id", "handle").Where("handle IN ?", handles).Find(&shells).Error; err != nil {
+		return err
+	}
+	idByHandle := make(map[string]uuid.UUID, len(shells))
+	shellIDs := make([]uuid.UUID, 0, len(shells))
+	for _, s := range shells {
+		idByHandle[s.Handle] = s.ID
+		shellIDs = append(shellIDs, s.ID)
+	}
+
+	type key struct {
+		shellID   uuid.UUID
+		dimension string
+	}
+	var reviews []struct {
+		ShellID   uuid.UUID
+		Dimension string
+		Accepted  int
+		Rejected  int
+	}
+	if err := database.DB.Model(&models.Fragment{}).
+		Select("shell_id, dimension, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS accepted, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS rejected",
+			models.FragStatusAccepted, models.FragStatusRejected).
+		Where("shell_id IN ?", shellIDs).
+		Group("shell_id, dimension").
+		Scan(&reviews).Error; err != nil {
+		return err
+	}
+	rates := make(map[key]float64, len(reviews))
+	for _, r := range reviews {
+		if n := r.Accepted + r.Rejected; n > 0 {
+			rates[key{r.ShellID, r.Dimension}] = math.Round(float64(r.Accepted)/float64(n)*100) / 100
+		}
+	}
+
+	examples := make(map[key][]TaskExample)
+	if withExamples {
+		var rows []struct {
+			ID          uuid.UUID
+			ShellID     uuid.UUID
+			Dimension   string
+			ContentHash string
+			Content     string
+		}
+		if err := database.DB.Raw(`
+			SELECT id, shell_id, dimension, content_hash, content FROM (
+				SELECT id, shell_id, dimension, content_hash, content,
+					ROW_NUMBER() OVER (PARTITION BY shell_id, dimension ORDER BY created_at DESC) AS rn
+				FROM fragments
+				WHERE shell_id IN ? AND status = ?
+			) f WHERE rn <= ?`, shellIDs, models.FragStatusAccepted, taskExamplesPerTask).
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, r := range rows {
+			k := key{r.ShellID, r.Dimension}
+			examples[k] = append(examples[k], TaskExample{
+				FragmentID:  r.ID,
+				ContentHash: r.ContentHash,
+				Excerpt:     taskExcerpt(r.Content),
+			})
+		}
+	}
+
+	for _, t := range tasks {
+		dim := t["dimension"].(string)
+		k := key{idByHandle[t["handle"].(string)], dim}
+		t["reward_weight"] = taskRewardWeight(t["score"].(int), t["followers"].(int))
+		t["evidence_types"] = taskEvidenceTypes[dim]
+		if rate, ok := rates[k]; ok {
+			t["acceptance_rate"] = rate
+		} else {
+			t["acceptance_rate"] = nil // nothing reviewed yet
+		}
+		if withExamples {
+			ex := examples[k]
+			if ex == nil {
+				ex = []TaskExample{}
+			}
+			t["examples"] = ex
+		}
+	}
+	return nil
+}
+
+// taskExcerpt shortens fragment content to an excerpt.
+func taskExcerpt(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if r := []rune(content); len(r) > taskExcerptLength {
+		return strings.TrimSpace(string(r[:taskExcerptLength])) + "…"
+	}
+	return content
+}
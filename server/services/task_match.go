@@ -0,0 +1,277 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+)
+
+// Limits on declared capability tags.
+const (
+	maxClawTags      = 15
+	maxClawTagLength = 40
+)
+
+// clawTagPattern allows plain tags ("crypto") and namespaced ones ("lang:zh", "source:farcaster").
+var clawTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]*(:[a-z0-9][a-z0-9+#._-]*)?$`)
+
+// taskPriorityOrder ranks task board priorities, most urgent first.
+var taskPriorityOrder = map[string]int{"high": 0, "medium": 1, "low": 2}
+
+// NormalizeClawTags lowercases, trims and de-duplicates capability tags and
+// validates their format.
+func NormalizeClawTags(tags []string) ([]string, error) {
+	return normalizeTags(tags, maxClawTags)
+}
+
+// normalizeTags lowercases, trims and de-duplicates up to max tags and
+// validates their format.
+func normalizeTags(tags []string, max int) ([]string, error) {
+	if len(tags) > max {
+		return nil, fmt.Errorf("too many tags (max %d)", max)
+	}
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || len(t) > maxClawTagLength || !clawTagPattern.MatchString(t) {
+			return nil, fmt.Errorf("invalid tag %q: use 1-%d lowercase letters, digits or +#._- with an optional \"kind:\" prefix", t, maxClawTagLength)
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// SetClawTags replaces a Claw's capability tags.
+func SetClawTags(claw *models.Claw, tags []string) error {
+	tags, err := NormalizeClawTags(tags)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Model(claw).UpdateColumn("tags", models.StringList(tags)).Error; err != nil {
+		return fmt.Errorf("failed to update tags: %w", err)
+	}
+	claw.Tags = tags
+	return nil
+}
+
+// tagValue strips the optional "kind:" namespace from a tag.
+func tagValue(tag string) string {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return tag[i+1:]
+	}
+	return tag
+}
+
+// shellMatchText is the lowercased public text a soul's tasks are matched against.
+func shellMatchText(shell *models.Shell) string {
+	parts := []string{shell.Handle, shell.DisplayName, shell.SeedSummary}
+	parts = append(parts, shell.Tags...)
+	for _, key := range []string{"bio", "location", "data_source"} {
+		if v, ok := shell.TwitterMeta[key].(string); ok {
+			parts = append(parts, v)
+		}
+	}
+	for _, d := range shell.GetDimensions() {
+		parts = append(parts, d.Summary)
+	}
+	return " " + strings.ToLower(strings.Join(parts, " ")) + " "
+}
+
+// containsWord reports whether text (lowercased, space-padded) contains word
+// delimited by non-alphanumeric characters.
+func containsWord(text, word string) bool {
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		if !isWordByte(text[start-1]) && (end >= len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
+
+// matchTaskTags returns the Claw tags that fit a task: tags naming the task's
+// dimension, and tags whose value appears in the soul's public text.
+func matchTaskTags(tags []string, dimension, shellText string) []string {
+	var matched []string
+	for _, tag := range tags {
+		v := tagValue(tag)
+		if v == dimension || containsWord(shellText, v) {
+			matched = append(matched, tag)
+		}
+	}
+	return matched
+}
+
+// GetTaskBoardForClaw returns the task board filtered to tasks that fit the
+// Claw's capability tags, best fit first, then by priority and followers.
+// Each task gains "fit_score" and "matched_tags".
+func GetTaskBoardForClaw(claw *models.Claw) ([]map[string]interface{}, error) {
+	if len(claw.Tags) == 0 {
+		return nil, fmt.Errorf("this Claw has no capability tags, set them with PUT /api/claw/tags")
+	}
+
+	tasks, err := GetTaskBoard()
+	if err != nil {
+		return nil, err
+	}
+
+	handles := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		handles = append(handles, t["handle"].(string))
+	}
+	var shells []models.Shell
+	if len(handles) > 0 {
+		database.DB.Where("handle IN ?", handles).Find(&shells)
+	}
+	textByHandle := make(map[string]string, len(shells))
+	for i := range shells {
+		textByHandle[shells[i].Handle] = shellMatchText(&shells[i])
+	}
+
+	fit := make([]map[string]interface{}, 0, len(tasks))
+	for _, t := range tasks {
+		matched := matchTaskTags(claw.Tags, t["dimension"].(string), textByHandle[t["handle"].(string)])
+		if len(matched) == 0 {
+			continue
+		}
+		t["fit_score"] = len(matched)
+		t["matched_tags"] = matched
+		fit = append(fit, t)
+	}
+
+	sort.SliceStable(fit, func(i, j int) bool {
+		if a, b := fit[i]["fit_score"].(int), fit[j]["fit_score"].(int); a != b {
+			return a > b
+		}
+		return taskPriorityOrder[fit[i]["priority"].(string)] < taskPriorityOrder[fit[j]["priority"].(string)]
+	})
+	return fit, nil
+}
+
+// DimensionCoverage summarizes demand and supply for one dimension.
+type DimensionCoverage struct {
+	Dimension    string  `json:"dimension"`
+	OpenTasks    int     `json:"open_tasks"`     // souls still needing fragments here
+	HighPriority int     `json:"high_priority"`  // of which barely started
+	ActiveClaws  int     `json:"active_claws"`   // Claws that submitted here in the window
+	Submitted    int     `json:"submitted"`      // fragments submitted in the window
+	Accepted     int     `json:"accepted"`       // fragments accepted in the window
+	TaggedClaws  int     `json:"tagged_claws"`   // claimed Claws tagged with the dimension
+	TasksPerClaw float64 `json:"tasks_per_claw"` // open tasks per active Claw (open tasks if none)
+	CoverageGap  bool    `json:"coverage_gap"`   // open high-priority work but nobody contributing
+	AcceptRate   float64 `json:"accept_rate"`    // accepted / submitted in the window
+}
+
+// GetCoverageReport compares open task board demand with recent Claw activity
+// per dimension, lists the capability tags claimed Claws declare, and the open
+// work per soul tag.
+func GetCoverageReport(days int) (map[string]interface{}, error) {
+	tasks, err := GetTaskBoard()
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := []string{models.DimPersonality, models.DimKnowledge, models.DimStance,
+		models.DimStyle, models.DimRelationship, models.DimTimeline}
+	byDim := make(map[string]*DimensionCoverage, len(dimensions))
+	for _, d := range dimensions {
+		byDim[d] = &DimensionCoverage{Dimension: d}
+	}
+	for _, t := range tasks {
+		cov := byDim[t["dimension"].(string)]
+		if cov == nil {
+			continue
+		}
+		cov.OpenTasks++
+		if t["priority"] == "high" {
+			cov.HighPriority++
+		}
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	var activity []struct {
+		Dimension string
+		Claws     int
+		Submitted int
+		Accepted  int
+	}
+	if err := database.DB.Model(&models.Fragment{}).
+		Select("dimension, COUNT(DISTINCT claw_id) AS claws, COUNT(*) AS submitted, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS accepted", models.FragStatusAccepted).
+		Where("created_at >= ?", since).
+		Group("dimension").
+		Scan(&activity).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range activity {
+		if cov := byDim[a.Dimension]; cov != nil {
+			cov.ActiveClaws, cov.Submitted, cov.Accepted = a.Claws, a.Submitted, a.Accepted
+		}
+	}
+
+	var tagCounts []struct {
+		Tag   string `json:"tag"`
+		Claws int    `json:"claws"`
+	}
+	from, tag := database.JSONArrayElements("c.tags", "t")
+	if err := database.DB.Raw(`
+		SELECT `+tag+` AS tag, COUNT(*) AS claws
+		FROM claws c, `+from+`
+		WHERE c.deleted_at IS NULL AND c.status = ?
+		GROUP BY `+tag+`
+		ORDER BY claws DESC, tag ASC
+		LIMIT 100`, models.ClawStatusClaimed).Scan(&tagCounts).Error; err != nil {
+		return nil, err
+	}
+	for _, tc := range tagCounts {
+		if cov := byDim[tagValue(tc.Tag)]; cov != nil {
+			cov.TaggedClaws += tc.Claws
+		}
+	}
+
+	coverage := make([]DimensionCoverage, 0, len(dimensions))
+	for _, d := range dimensions {
+		cov := byDim[d]
+		cov.TasksPerClaw = float64(cov.OpenTasks)
+		if cov.ActiveClaws > 0 {
+			cov.TasksPerClaw = float64(cov.OpenTasks) / float64(cov.ActiveClaws)
+		}
+		if cov.Submitted > 0 {
+			cov.AcceptRate = float64(cov.Accepted) / float64(cov.Submitted)
+		}
+		cov.CoverageGap = cov.HighPriority > 0 && cov.ActiveClaws == 0
+		coverage = append(coverage, *cov)
+	}
+	// Worst-served dimensions first
+	sort.SliceStable(coverage, func(i, j int) bool {
+		return coverage[i].TasksPerClaw > coverage[j].TasksPerClaw
+	})
+
+	soulTags, err := GetTagCoverage()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"days":       days,
+		"dimensions": coverage,
+		"claw_tags":  tagCounts,
+		"soul_tags":  soulTags,
+	}, nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -31,6 +32,7 @@ type TwitterTweet struct {
 	ID        string `json:"id"`
 	Text      string `json:"text"`
 	CreatedAt string `json:"created_at"`
+	Lang      string `json:"lang,omitempty"` // as tagged by the platform ("und" = undetermined)
 }
 
 // TwitterProfile aggregates user info and recent tweets for seed extraction.
@@ -106,7 +108,7 @@ func fetchTwitterUser(username, token string) (*TwitterUser, error) {
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := twitterHTTP.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -130,7 +132,7 @@ func fetchTwitterUser(username, token string) (*TwitterUser, error) {
 func fetchUserTweets(userID, token string) ([]TwitterTweet, error) {
 	params := url.Values{}
 	params.Set("max_results", "50")
-	params.Set("tweet.fields", "id,text,created_at")
+	params.Set("tweet.fields", "id,text,created_at,lang")
 	params.Set("exclude", "retweets,replies")
 
 	apiURL := fmt.Sprintf("https://api.twitter.com/2/users/%s/tweets?%s",
@@ -142,7 +144,7 @@ func fetchUserTweets(userID, token string) ([]TwitterTweet, error) {
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := twitterHTTP.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -163,6 +165,76 @@ func fetchUserTweets(userID, token string) ([]TwitterTweet, error) {
 	return result.Data, nil
 }
 
+// ErrTwitterUnavailable is returned when no Twitter data source is configured.
+var ErrTwitterUnavailable = errors.New("no Twitter data source is configured")
+
+// AuthoredTweet is a single tweet with its author's username.
+type AuthoredTweet struct {
+	ID     string
+	Text   string
+	Author string
+}
+
+// FetchTweet looks up one tweet by ID via SocialData, falling back to the
+// Twitter v2 API. Unlike profiles there is no mock: callers verify the result.
+func FetchTweet(tweetID string) (*AuthoredTweet, error) {
+	if SocialDataAvailable() {
+		t, err := newSocialDataClient().FetchTweet(tweetID)
+		if err == nil {
+			tweet := &AuthoredTweet{ID: t.IDStr, Text: t.FullText}
+			if tweet.Text == "" && t.Text != nil {
+				tweet.Text = *t.Text
+			}
+			if t.User != nil {
+				tweet.Author = t.User.ScreenName
+			}
+			return tweet, nil
+		}
+		util.Log.Warn("[twitter] SocialData failed for tweet %s, trying Twitter v2: %v", tweetID, err)
+	}
+
+	token := config.Cfg.TwitterBearerToken
+	if token == "" {
+		return nil, ErrTwitterUnavailable
+	}
+	params := url.Values{}
+	params.Set("expansions", "author_id")
+	params.Set("user.fields", "username")
+	apiURL := fmt.Sprintf("https://api.twitter.com/2/tweets/%s?%s", url.PathEscape(tweetID), params.Encode())
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := twitterHTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Twitter API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data     TwitterTweet `json:"data"`
+		Includes struct {
+			Users []TwitterUser `json:"users"`
+		} `json:"includes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode Twitter tweet response: %w", err)
+	}
+	tweet := &AuthoredTweet{ID: result.Data.ID, Text: result.Data.Text}
+	if len(result.Includes.Users) > 0 {
+		tweet.Author = result.Includes.Users[0].Username
+	}
+	return tweet, nil
+}
+
 // mockTwitterProfile returns a placeholder profile when Twitter API is not configured.
 // Tweets are left empty so the seed prompt tells the LLM to rely on public knowledge.
 func mockTwitterProfile(handle string) *TwitterProfile {
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/chain"
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// txWatchBatchLimit is how many pending transactions one poll looks up.
+const txWatchBatchLimit = 200
+
+// WatchTx registers a submitted transaction with the watcher. Once it is mined
+// (or its deadline passes) the handler for kind runs with the receipt.
+// refID and payload carry whatever the handler needs to finish the work.
+func WatchTx(txHash, kind, refID string, payload map[string]interface{}) error {
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	ptx := &models.PendingTx{
+		TxHash:   txHash,
+		Kind:     kind,
+		RefID:    refID,
+		Payload:  models.JSON(payload),
+		Status:   models.PendingTxPending,
+		Deadline: time.Now().Add(time.Duration(config.Cfg.TxWatchTimeoutMinutes) * time.Minute),
+	}
+	if err := database.DB.Create(ptx).Error; err != nil {
+		return fmt.Errorf("failed to watch tx %s: %w", txHash, err)
+	}
+	return nil
+}
+
+// StartTxWatcher polls the receipts of all pending transactions in one batched
+// RPC call per tick, instead of a goroutine blocking on each transaction.
+func StartTxWatcher(interval time.Duration) {
+	if chain.C() == nil {
+		if deferUntilChainReady(func() { StartTxWatcher(interval) }) {
+			util.Log.Info("[tx-watcher] Chain not connected yet, watcher starts once it is")
+		} else {
+			util.Log.Info("[tx-watcher] Chain not initialized, watcher disabled")
+		}
+		return
+	}
+	scheduleJob("tx-watcher", "Poll receipts of submitted transactions", interval, false, pollPendingTxs)
+	util.Log.Info("[tx-watcher] Started (every %v, timeout %dm)", interval, config.Cfg.TxWatchTimeoutMinutes)
+}
+
+func pollPendingTxs() error {
+	var pending []models.PendingTx
+	if err := database.DB.Where("status = ?", models.PendingTxPending).
+		Order("created_at ASC").Limit(txWatchBatchLimit).Find(&pending).Error; err != nil {
+		return fmt.Errorf("failed to query pending transactions: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	hashes := make([]string, len(pending))
+	for i, p := range pending {
+		hashes[i] = p.TxHash
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	receipts, err := chain.FetchReceipts(ctx, hashes)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range pending {
+		ptx := &pending[i]
+		receipt := receipts[ptx.TxHash]
+		switch {
+		case receipt != nil && receipt.Status == types.ReceiptStatusSuccessful:
+			finishPendingTx(ptx, models.PendingTxConfirmed, receipt)
+		case receipt != nil:
+			finishPendingTx(ptx, models.PendingTxReverted, receipt)
+		case now.After(ptx.Deadline):
+			finishPendingTx(ptx, models.PendingTxTimedOut, nil)
+		}
+	}
+	return nil
+}
+
+// finishPendingTx records the outcome and runs the kind's handler. The status
+// update is conditional so only one watcher instance handles each transaction.
+func finishPendingTx(ptx *models.PendingTx, status string, receipt *types.Receipt) {
+	now := time.Now()
+	updates := map[string]interface{}{"status": status, "finished_at": &now}
+	if receipt != nil {
+		updates["block_number"] = receipt.BlockNumber.Uint64()
+	}
+	res := database.DB.Model(&models.PendingTx{}).
+		Where("id = ? AND status = ?", ptx.ID, models.PendingTxPending).
+		Updates(updates)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return
+	}
+	ptx.Status = status
+
+	if err := dispatchPendingTx(ptx, receipt); err != nil {
+		util.Log.Error("[tx-watcher] %s tx %s (%s): %v", ptx.Kind, ptx.TxHash, status, err)
+		database.DB.Model(ptx).Update("error", err.Error())
+	}
+}
+
+// dispatchPendingTx runs the handler for a finished transaction. receipt is
+// nil when the transaction timed out.
+func dispatchPendingTx(ptx *models.PendingTx, receipt *types.Receipt) error {
+	switch ptx.Kind {
+	case models.TxKindFeedback:
+		return onFeedbackTxFinished(ptx)
+	case models.TxKindMintConfirm:
+		return onMintTxFinished(ptx, receipt)
+	case models.TxKindCustodialMint:
+		return onCustodialMintFinished(ptx, receipt)
+	case models.TxKindSoulTransfer:
+		return onSoulTransferFinished(ptx, receipt)
+	}
+	return fmt.Errorf("unknown tx kind %q", ptx.Kind)
+}
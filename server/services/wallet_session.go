@@ -0,0 +1,170 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+)
+
+// maxWalletSessions caps concurrent sessions per wallet; logging in on one
+// more device ends the least recently used session.
+const maxWalletSessions = 10
+
+// ErrWalletSessionNotFound is returned when revoking a session the wallet doesn't hold.
+var ErrWalletSessionNotFound = errors.New("session not found")
+
+// WalletSessionInfo is a session as listed to its wallet.
+type WalletSessionInfo struct {
+	models.WalletSession
+	Current bool `json:"current"` // the session making the request
+}
+
+// CreateWalletSession starts a session for a wallet and returns its raw
+// token (only the hash is stored). Sessions beyond maxWalletSessions are
+// ended, least recently used first.
+func CreateWalletSession(walletAddr, userAgent, clientIP string, duration time.Duration) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("failed to generate session: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	now := time.Now()
+	session := &models.WalletSession{
+		TokenHash:  util.HashToken(token),
+		WalletAddr: walletAddr,
+		Device:     DeviceLabel(userAgent),
+		LoginIP:    clientIP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(duration),
+	}
+	if err := database.DB.Create(session).Error; err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	var stale []uuid.UUID
+	database.DB.Model(&models.WalletSession{}).
+		Where("wallet_addr = ?", walletAddr).
+		Order("last_seen_at DESC").
+		Offset(maxWalletSessions).
+		Pluck("id", &stale)
+	if len(stale) > 0 {
+		database.DB.Where("id IN ?", stale).Delete(&models.WalletSession{})
+	}
+	return token, nil
+}
+
+// ListWalletSessions returns a wallet's unexpired sessions, most recently
+// used first, marking the one whose token hash is currentHash.
+func ListWalletSessions(walletAddr, currentHash string) ([]WalletSessionInfo, error) {
+	var sessions []models.WalletSession
+	if err := database.DB.Where("wallet_addr = ? AND expires_at > ?", walletAddr, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	out := make([]WalletSessionInfo, len(sessions))
+	for i, s := range sessions {
+		out[i] = WalletSessionInfo{WalletSession: s, Current: s.TokenHash == currentHash}
+	}
+	return out, nil
+}
+
+// CurrentWalletSessionID returns the ID of the session with a token hash, or uuid.Nil.
+func CurrentWalletSessionID(tokenHash string) uuid.UUID {
+	var session models.WalletSession
+	if tokenHash == "" || database.DB.Select("id").Where("token_hash = ?", tokenHash).First(&session).Error != nil {
+		return uuid.Nil
+	}
+	return session.ID
+}
+
+// RevokeWalletSession ends one of a wallet's sessions.
+func RevokeWalletSession(walletAddr string, sessionID uuid.UUID) error {
+	res := database.DB.Where("id = ? AND wallet_addr = ?", sessionID, walletAddr).Delete(&models.WalletSession{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrWalletSessionNotFound
+	}
+	return nil
+}
+
+// RevokeWalletSessions ends all of a wallet's sessions except the one whose
+// token hash is keepHash ("" = log out everywhere). Returns how many ended.
+func RevokeWalletSessions(walletAddr, keepHash string) (int64, error) {
+	query := database.DB.Where("wallet_addr = ?", walletAddr)
+	if keepHash != "" {
+		query = query.Where("token_hash <> ?", keepHash)
+	}
+	res := query.Delete(&models.WalletSession{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+// DeviceLabel derives a short "Browser on OS" label from a User-Agent header.
+func DeviceLabel(userAgent string) string {
+	userAgent = strings.TrimSpace(userAgent)
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	var browser string
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/") || strings.Contains(ua, "fxios/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"), strings.Contains(ua, "python"), strings.Contains(ua, "go-http-client"):
+		browser = "Script"
+	}
+
+	var platform string
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os") || strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "cros"):
+		platform = "ChromeOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	// Unrecognized clients keep their product token, e.g. "MyWallet/2.1"
+	label := strings.Fields(userAgent)[0]
+	if len(label) > 60 {
+		label = label[:60]
+	}
+	return label
+}
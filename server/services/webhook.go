@@ -0,0 +1,326 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/ensoul-labs/ensoul-server/config"
+	"github.com/ensoul-labs/ensoul-server/database"
+	"github.com/ensoul-labs/ensoul-server/models"
+	"github.com/ensoul-labs/ensoul-server/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	webhookMaxAttempts        = 6               // first try + 5 retries
+	webhookRetryBase          = time.Minute     // doubled after each failed attempt
+	webhookLease              = 2 * time.Minute // a claimed delivery is not retried by others meanwhile
+	webhookDisableAfter       = 10              // consecutive failed deliveries before a webhook is disabled
+	webhookMaxPerShell        = 5
+	webhookDeliveryBatchLimit = 50
+)
+
+// webhookEvents lists the events clients may subscribe to.
+var webhookEvents = map[string]bool{
+	models.WebhookEventStageChanged: true,
+	models.WebhookEventRevoked:      true,
+	models.WebhookEventRetired:      true,
+	models.WebhookEventEnsouled:     true,
+	models.WebhookEventDisputed:     true,
+	models.WebhookEventMerged:       true,
+}
+
+// webhookClient refuses to connect to private, loopback and link-local
+// addresses in production, so webhooks can't be used to probe internal services.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: publicAddressOnly,
+		}).DialContext,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// publicAddressOnly is a net.Dialer Control that, in production, refuses
+// private, loopback and link-local addresses for user-supplied URLs.
+func publicAddressOnly(_, address string, _ syscall.RawConn) error {
+	if !config.Cfg.IsProduction() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s is not a public address", host)
+	}
+	return nil
+}
+
+// CreateWebhook subscribes a URL to events. shellID nil subscribes to all shells.
+// The returned secret signs deliveries and is only shown once.
+func CreateWebhook(shellID *uuid.UUID, createdBy, rawURL string, events []string) (*models.Webhook, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, "", fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if u.Scheme != "https" && config.Cfg.IsProduction() {
+		return nil, "", fmt.Errorf("url must use https")
+	}
+	for _, e := range events {
+		if !webhookEvents[e] {
+			return nil, "", fmt.Errorf("unknown event %q", e)
+		}
+	}
+
+	if shellID != nil {
+		var count int64
+		database.DB.Model(&models.Webhook{}).Where("shell_id = ?", *shellID).Count(&count)
+		if count >= webhookMaxPerShell {
+			return nil, "", fmt.Errorf("a soul can have at most %d webhooks", webhookMaxPerShell)
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := hex.EncodeToString(b)
+
+	hook := &models.Webhook{
+		ShellID:   shellID,
+		CreatedBy: createdBy,
+		URL:       u.String(),
+		Secret:    secret,
+		Events:    events,
+		Active:    true,
+	}
+	if err := database.DB.Create(hook).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return hook, secret, nil
+}
+
+// ListWebhooks returns the webhooks of a shell, or the global ones when shellID is nil.
+func ListWebhooks(shellID *uuid.UUID) ([]models.Webhook, error) {
+	query := database.DB.Order("created_at DESC")
+	if shellID != nil {
+		query = query.Where("shell_id = ?", *shellID)
+	} else {
+		query = query.Where("shell_id IS NULL")
+	}
+	var hooks []models.Webhook
+	if err := query.Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook removes a webhook and its pending deliveries. shellID scopes
+// the deletion the same way as ListWebhooks.
+func DeleteWebhook(shellID *uuid.UUID, webhookID string) error {
+	id, err := uuid.Parse(webhookID)
+	if err != nil {
+		return fmt.Errorf("invalid webhook ID")
+	}
+	query := database.DB.Where("id = ?", id)
+	if shellID != nil {
+		query = query.Where("shell_id = ?", *shellID)
+	} else {
+		query = query.Where("shell_id IS NULL")
+	}
+	res := query.Delete(&models.Webhook{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+	database.DB.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{})
+	return nil
+}
+
+// EmitWebhookEvent queues an event for every active webhook subscribed to it
+// (global webhooks and, if shellID is set, that shell's webhooks) and attempts
+// delivery right away. Failed deliveries are retried by StartWebhookDelivery.
+func EmitWebhookEvent(event string, shellID *uuid.UUID, data map[string]interface{}) {
+	var hooks []models.Webhook
+	query := database.DB.Where("active = ?", true)
+	if shellID != nil {
+		query = query.Where("shell_id IS NULL OR shell_id = ?", *shellID)
+	} else {
+		query = query.Where("shell_id IS NULL")
+	}
+	if err := query.Find(&hooks).Error; err != nil {
+		util.Log.Error("[webhook] Failed to load webhooks for %s: %v", event, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if len(hook.Events) > 0 && !containsString(hook.Events, event) {
+			continue
+		}
+
+		delivery := &models.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     hook.ID,
+			Event:         event,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		payload, err := json.Marshal(map[string]interface{}{
+			"id":         delivery.ID,
+			"event":      event,
+			"created_at": time.Now().UTC().Format(time.RFC3339),
+			"data":       data,
+		})
+		if err != nil {
+			util.Log.Error("[webhook] Failed to encode %s payload: %v", event, err)
+			return
+		}
+		delivery.Payload = string(payload)
+
+		if err := database.DB.Create(delivery).Error; err != nil {
+			util.Log.Error("[webhook] Failed to queue %s for webhook %s: %v", event, hook.ID, err)
+			continue
+		}
+		go attemptWebhookDelivery(delivery.ID)
+	}
+}
+
+// StartWebhookDelivery periodically retries pending webhook deliveries that are due.
+func StartWebhookDelivery(interval time.Duration) {
+	scheduleJob("webhook-delivery", "Retry due webhook deliveries", interval, false, retryDueWebhookDeliveries)
+	util.Log.Info("[webhook] Delivery retry loop started (every %v)", interval)
+}
+
+func retryDueWebhookDeliveries() error {
+	var ids []uuid.UUID
+	if err := database.DB.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+		Order("next_attempt_at ASC").Limit(webhookDeliveryBatchLimit).Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to query due deliveries: %w", err)
+	}
+	for _, id := range ids {
+		attemptWebhookDelivery(id)
+	}
+	return nil
+}
+
+// attemptWebhookDelivery claims a due delivery, sends it once and schedules a
+// retry with exponential backoff on failure.
+func attemptWebhookDelivery(id uuid.UUID) {
+	now := time.Now()
+	claim := database.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.WebhookDeliveryPending, now).
+		Updates(map[string]interface{}{
+			"next_attempt_at": now.Add(webhookLease),
+			"attempts":        gorm.Expr("attempts + 1"),
+		})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return // delivered, given up, or claimed by another worker
+	}
+
+	var delivery models.WebhookDelivery
+	var hook models.Webhook
+	if database.DB.First(&delivery, "id = ?", id).Error != nil {
+		return
+	}
+	if database.DB.First(&hook, "id = ?", delivery.WebhookID).Error != nil || !hook.Active {
+		database.DB.Model(&delivery).Updates(map[string]interface{}{
+			"status":     models.WebhookDeliveryFailed,
+			"last_error": "webhook deleted or disabled",
+		})
+		return
+	}
+
+	code, err := sendWebhook(&hook, &delivery)
+	if err == nil {
+		delivered := time.Now()
+		database.DB.Model(&delivery).Updates(map[string]interface{}{
+			"status":        models.WebhookDeliveryDelivered,
+			"response_code": code,
+			"last_error":    "",
+			"delivered_at":  &delivered,
+		})
+		if hook.FailureCount > 0 {
+			database.DB.Model(&hook).Updates(map[string]interface{}{"failure_count": 0, "last_error": ""})
+		}
+		return
+	}
+
+	updates := map[string]interface{}{
+		"response_code": code,
+		"last_error":    err.Error(),
+	}
+	if delivery.Attempts >= webhookMaxAttempts {
+		updates["status"] = models.WebhookDeliveryFailed
+		database.DB.Model(&hook).Updates(map[string]interface{}{
+			"failure_count": gorm.Expr("failure_count + 1"),
+			"last_error":    err.Error(),
+			"active":        hook.FailureCount+1 < webhookDisableAfter,
+		})
+		util.Log.Warn("[webhook] Giving up on %s delivery %s to %s after %d attempts: %v",
+			delivery.Event, delivery.ID, hook.URL, delivery.Attempts, err)
+	} else {
+		updates["next_attempt_at"] = time.Now().Add(webhookRetryBase << (delivery.Attempts - 1))
+		util.Log.Debug("[webhook] %s delivery %s to %s failed (attempt %d): %v",
+			delivery.Event, delivery.ID, hook.URL, delivery.Attempts, err)
+	}
+	database.DB.Model(&delivery).Updates(updates)
+}
+
+// sendWebhook POSTs the payload signed with the webhook secret:
+// X-Ensoul-Signature: sha256=<hex HMAC-SHA256 of the body>.
+func sendWebhook(hook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Ensoul-Webhook/1.0")
+	req.Header.Set("X-Ensoul-Event", delivery.Event)
+	req.Header.Set("X-Ensoul-Delivery", delivery.ID.String())
+	req.Header.Set("X-Ensoul-Signature", "sha256="+hex.EncodeToString(hmacSHA256([]byte(hook.Secret), string(body))))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
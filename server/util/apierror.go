@@ -0,0 +1,114 @@
+package util
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode is a stable, machine-readable error identifier. Clients (Claws in
+// particular) branch on the code; the message is for humans and may change.
+type ErrorCode string
+
+// Error codes. Keep in sync with the table in README.md and skill.md.
+const (
+	// Request validation (400)
+	CodeInvalidRequest         ErrorCode = "INVALID_REQUEST"
+	CodeInvalidHandle          ErrorCode = "INVALID_HANDLE"
+	CodeInvalidDimension       ErrorCode = "INVALID_DIMENSION"
+	CodeDuplicateDimension     ErrorCode = "DUPLICATE_DIMENSION"
+	CodeUnsupportedLanguage    ErrorCode = "UNSUPPORTED_LANGUAGE"
+	CodeContentLength          ErrorCode = "CONTENT_LENGTH"
+	CodeLowQuality             ErrorCode = "LOW_QUALITY_CONTENT"
+	CodeInvalidClaims          ErrorCode = "INVALID_CLAIMS"
+	CodeDimensionNotAccepted   ErrorCode = "DIMENSION_NOT_ACCEPTED"
+	CodeContentPolicyViolation ErrorCode = "CONTENT_POLICY_VIOLATION"
+	CodeConfirmRequired        ErrorCode = "CONFIRM_REQUIRED"
+	CodePreviewInvalid         ErrorCode = "PREVIEW_INVALID"
+	CodeJSONTooDeep            ErrorCode = "JSON_TOO_DEEP"
+
+	// Authentication (401)
+	CodeAuthRequired     ErrorCode = "AUTH_REQUIRED"
+	CodeInvalidAPIKey    ErrorCode = "INVALID_API_KEY"
+	CodeSessionExpired   ErrorCode = "SESSION_EXPIRED"
+	CodeInvalidSignature ErrorCode = "INVALID_SIGNATURE"
+	CodeSignatureExpired ErrorCode = "SIGNATURE_EXPIRED"
+
+	// Authorization (403)
+	CodeForbidden          ErrorCode = "FORBIDDEN"
+	CodeAdminRequired      ErrorCode = "ADMIN_REQUIRED"
+	CodeNotOwner           ErrorCode = "NOT_OWNER"
+	CodeClawNotClaimed     ErrorCode = "CLAW_NOT_CLAIMED"
+	CodeClawRetired        ErrorCode = "CLAW_RETIRED"
+	CodeVerificationFailed ErrorCode = "VERIFICATION_FAILED"
+	CodeMintLimit          ErrorCode = "MINT_LIMIT"
+	CodeLicenseInactive    ErrorCode = "LICENSE_INACTIVE"
+
+	// Payment (402)
+	CodePaymentRequired ErrorCode = "PAYMENT_REQUIRED"
+
+	// Missing resources (404, 410)
+	CodeNotFound         ErrorCode = "NOT_FOUND"
+	CodeShellNotFound    ErrorCode = "SHELL_NOT_FOUND"
+	CodeShellNotMinted   ErrorCode = "SHELL_NOT_MINTED"
+	CodeClawNotFound     ErrorCode = "CLAW_NOT_FOUND"
+	CodeFragmentNotFound ErrorCode = "FRAGMENT_NOT_FOUND"
+	CodeDeprecated       ErrorCode = "ENDPOINT_DEPRECATED"
+	CodeShellRevoked     ErrorCode = "SHELL_REVOKED"
+	CodeShellRetired     ErrorCode = "SHELL_RETIRED"
+
+	// State conflicts (409)
+	CodeAlreadyExists  ErrorCode = "ALREADY_EXISTS"
+	CodeAppealExists   ErrorCode = "APPEAL_EXISTS"
+	CodeJobRunning     ErrorCode = "JOB_RUNNING"
+	CodeNotQuarantined ErrorCode = "NOT_QUARANTINED"
+	CodeShellDisputed  ErrorCode = "SHELL_DISPUTED"
+	CodeDisputeOpen    ErrorCode = "DISPUTE_OPEN"
+	CodeMergeBlocked   ErrorCode = "MERGE_BLOCKED"
+
+	// Curation outcome: reject_code on GET /api/fragment/:id, not an HTTP error
+	CodeCuratorRejected ErrorCode = "CURATOR_REJECTED"
+
+	// Request size (413)
+	CodePayloadTooLarge ErrorCode = "PAYLOAD_TOO_LARGE"
+
+	// Throttling (429)
+	CodeRateLimited ErrorCode = "RATE_LIMITED"
+	CodeBatchQuota  ErrorCode = "BATCH_QUOTA_EXCEEDED"
+	CodeAPIQuota    ErrorCode = "API_QUOTA_EXCEEDED"
+
+	// Server side (5xx)
+	CodeInternal       ErrorCode = "INTERNAL_ERROR"
+	CodeUpstream       ErrorCode = "UPSTREAM_ERROR"
+	CodeRegistryPaused ErrorCode = "REGISTRY_PAUSED"
+	CodeMaintenance    ErrorCode = "MAINTENANCE"
+)
+
+// APIError is the error envelope every endpoint returns.
+type APIError struct {
+	Code       ErrorCode              `json:"code"`
+	Message    string                 `json:"message"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	RetryAfter int                    `json:"retry_after,omitempty"` // seconds
+}
+
+// errorBody keeps the old "error" string next to the envelope, so clients
+// written before error codes keep working.
+type errorBody struct {
+	Error string `json:"error"`
+	APIError
+}
+
+// RespondError writes an error envelope with the given code and message.
+func RespondError(c *gin.Context, status int, code ErrorCode, message string) {
+	RespondAPIError(c, status, APIError{Code: code, Message: message})
+}
+
+// RespondAPIError writes a full error envelope, setting Retry-After when
+// the error carries one.
+func RespondAPIError(c *gin.Context, status int, e APIError) {
+	if e.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
+	c.JSON(status, errorBody{Error: e.Message, APIError: e})
+}
@@ -1,15 +1,16 @@
 "use client";
 
-import { useState, useEffect, useCallback } from "react";
+import { useState, useEffect, useCallback, type FormEvent } from "react";
 import { Link } from "@/i18n/navigation";
 import { useAccount, useSignMessage } from "wagmi";
 import {
   sessionApi,
   clawKeyApi,
   ClawBindingInfo,
+  ClawBatchQuota,
   Fragment,
 } from "@/lib/api";
-import { dimensionLabels, timeAgo } from "@/lib/utils";
+import { dimensionLabels, timeAgo, timeUntil } from "@/lib/utils";
 
 export default function DashboardPage() {
   const { address, isConnected } = useAccount();
@@ -30,6 +31,10 @@ export default function DashboardPage() {
     earnings: number;
   } | null>(null);
   const [contributions, setContributions] = useState<Fragment[]>([]);
+  const [batchQuota, setBatchQuota] = useState<ClawBatchQuota | null>(null);
+  const [search, setSearch] = useState("");
+  const [searchResults, setSearchResults] = useState<Fragment[] | null>(null);
+  const [searching, setSearching] = useState(false);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState("");
 
@@ -90,10 +95,12 @@ export default function DashboardPage() {
       const data = await clawKeyApi.dashboard(bindingId);
       setOverview(data.overview);
       setContributions(data.recent_contributions || []);
+      setBatchQuota(data.batch_quota || null);
     } catch (err: unknown) {
       setError(err instanceof Error ? err.message : "Failed to load dashboard");
       setOverview(null);
       setContributions([]);
+      setBatchQuota(null);
     } finally {
       setLoading(false);
     }
@@ -102,9 +109,32 @@ export default function DashboardPage() {
   function switchClaw(idx: number) {
     setActiveIdx(idx);
     setError("");
+    setSearch("");
+    setSearchResults(null);
     fetchDashboard(claws[idx].id);
   }
 
+  async function searchContributions(e: FormEvent) {
+    e.preventDefault();
+    const q = search.trim();
+    if (!q || !claws[activeIdx]) {
+      setSearchResults(null);
+      return;
+    }
+    setSearching(true);
+    setError("");
+    try {
+      const data = await clawKeyApi.contributions(claws[activeIdx].id, q, 1, 50);
+      setSearchResults(data.contributions || []);
+    } catch (err: unknown) {
+      setError(err instanceof Error ? err.message : "Search failed");
+    } finally {
+      setSearching(false);
+    }
+  }
+
+  const shownContributions = searchResults ?? contributions;
+
   async function handleLogin() {
     if (!address) return;
     setLoggingIn(true);
@@ -378,21 +408,92 @@ export default function DashboardPage() {
             </Link>
           </div>
 
+          {/* Per-soul batch quota (rolling window) */}
+          {batchQuota && batchQuota.limit_per_soul > 0 && (
+            <div className="mb-8">
+              <h3 className="mb-1 text-lg font-medium text-[#e2e8f0]">
+                Batch Quota
+              </h3>
+              <p className="mb-4 text-xs text-[#94a3b8]">
+                Up to {batchQuota.limit_per_soul} batches per soul every{" "}
+                {batchQuota.window_hours}h
+              </p>
+              {batchQuota.souls.length === 0 ? (
+                <p className="text-sm text-[#94a3b8]">
+                  No batches in the last {batchQuota.window_hours}h.
+                </p>
+              ) : (
+                <div className="space-y-2">
+                  {batchQuota.souls.map((q) => (
+                    <div
+                      key={q.handle}
+                      className="flex items-center justify-between rounded-lg border border-[#1e1e2e] bg-[#14141f] px-4 py-2 text-sm"
+                    >
+                      <Link
+                        href={`/soul/${q.handle}`}
+                        className="text-[#8b5cf6] hover:underline"
+                      >
+                        @{q.handle}
+                      </Link>
+                      <span
+                        className={`font-mono ${q.remaining === 0 ? "text-red-400" : "text-[#e2e8f0]"}`}
+                      >
+                        {q.used}/{q.limit}
+                        {q.remaining === 0 && q.resets_at && (
+                          <span className="ml-2 text-xs text-[#94a3b8]">
+                            next batch {timeUntil(q.resets_at)}
+                          </span>
+                        )}
+                      </span>
+                    </div>
+                  ))}
+                </div>
+              )}
+            </div>
+          )}
+
           {/* Recent contributions */}
           <div>
-            <h3 className="mb-4 text-lg font-medium text-[#e2e8f0]">
-              Recent Contributions
-            </h3>
-            {contributions.length === 0 ? (
+            <div className="mb-4 flex flex-wrap items-center justify-between gap-3">
+              <h3 className="text-lg font-medium text-[#e2e8f0]">
+                {searchResults ? "Search Results" : "Recent Contributions"}
+              </h3>
+              <form onSubmit={searchContributions} className="flex gap-2">
+                <input
+                  type="search"
+                  value={search}
+                  onChange={(e) => {
+                    setSearch(e.target.value);
+                    if (!e.target.value) setSearchResults(null);
+                  }}
+                  placeholder="Search notes and content"
+                  className="w-56 rounded-lg border border-[#1e1e2e] bg-[#14141f] px-3 py-1.5 text-sm text-[#e2e8f0] placeholder-[#64748b] focus:border-[#8b5cf6] focus:outline-none"
+                />
+                <button
+                  type="submit"
+                  disabled={searching}
+                  className="rounded-lg border border-[#1e1e2e] px-3 py-1.5 text-sm text-[#e2e8f0] hover:border-[#8b5cf6] disabled:opacity-50"
+                >
+                  {searching ? "..." : "Search"}
+                </button>
+              </form>
+            </div>
+            {shownContributions.length === 0 ? (
               <div className="rounded-lg border border-[#1e1e2e] bg-[#14141f] p-8 text-center text-[#94a3b8]">
-                <p className="mb-2">No contributions yet</p>
-                <p className="text-sm">
-                  Start contributing fragments to souls via the API.
-                </p>
+                {searchResults ? (
+                  <p>No contributions match your search</p>
+                ) : (
+                  <>
+                    <p className="mb-2">No contributions yet</p>
+                    <p className="text-sm">
+                      Start contributing fragments to souls via the API.
+                    </p>
+                  </>
+                )}
               </div>
             ) : (
               <div className="space-y-3">
-                {contributions.map((c) => {
+                {shownContributions.map((c) => {
                   const statusColor = {
                     accepted: "text-green-400 bg-green-500/10",
                     pending: "text-yellow-400 bg-yellow-500/10",
@@ -434,6 +535,12 @@ export default function DashboardPage() {
                           Reason: {c.reject_reason}
                         </p>
                       )}
+                      {c.notes && (
+                        <p className="mt-2 whitespace-pre-wrap rounded border border-[#1e1e2e] bg-[#0a0a0f] px-3 py-2 text-xs text-[#94a3b8]">
+                          <span className="mr-1">📝</span>
+                          {c.notes}
+                        </p>
+                      )}
                     </div>
                   );
                 })}
@@ -2,12 +2,12 @@
 
 import { useEffect, useState, useCallback, useRef } from "react";
 import { useTranslations } from "next-intl";
-import { shellApi, type Shell } from "@/lib/api";
+import { shellApi, type ShellSummary } from "@/lib/api";
 import SoulCard from "@/components/SoulCard";
 
 export default function ExplorePage() {
   const t = useTranslations("Explore");
-  const [souls, setSouls] = useState<Shell[]>([]);
+  const [souls, setSouls] = useState<ShellSummary[]>([]);
   const [total, setTotal] = useState(0);
   const [loading, setLoading] = useState(true);
   const [loadingMore, setLoadingMore] = useState(false);
@@ -38,7 +38,7 @@ export default function ExplorePage() {
   const fetchSouls = useCallback(async () => {
     setLoading(true);
     try {
-      const result = await shellApi.list({ stage, sort, search, page: 1, limit });
+      const result = await shellApi.listSlim({ stage, sort, search, page: 1, limit });
       setSouls(result.shells || []);
       setTotal(result.total);
       setPage(1);
@@ -56,7 +56,7 @@ export default function ExplorePage() {
     const nextPage = page + 1;
     setLoadingMore(true);
     try {
-      const result = await shellApi.list({ stage, sort, search, page: nextPage, limit });
+      const result = await shellApi.listSlim({ stage, sort, search, page: nextPage, limit });
       setSouls((prev) => [...prev, ...(result.shells || [])]);
       setTotal(result.total);
       setPage(nextPage);
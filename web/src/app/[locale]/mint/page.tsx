@@ -94,7 +94,7 @@ export default function MintPage() {
       type: "https://eips.ethereum.org/EIPS/eip-8004#registration-v1",
       name: `@${p.handle} · Ensoul`,
       description: p.seed_summary,
-      image: p.avatar_url,
+      image: `https://ensoul.ac/api/shell/${p.handle}/card.png`,
       services: [
         { name: "web", endpoint: `https://ensoul.ac/soul/${p.handle}` },
         { name: "chat", endpoint: `https://ensoul.ac/soul/${p.handle}/chat` },
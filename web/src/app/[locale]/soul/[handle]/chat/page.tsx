@@ -12,6 +12,9 @@ import {
   Shell,
   ChatSession,
   ChatSessionMessage,
+  ChatCitation,
+  ChatRoundQuota,
+  fragmentApi,
 } from "@/lib/api";
 import { stageConfig, Stage } from "@/lib/utils";
 import ReactMarkdown from "react-markdown";
@@ -21,8 +24,10 @@ import ShareCardModal from "@/components/ShareCardModal";
 interface DisplayMessage {
   role: "user" | "assistant";
   content: string;
+  citations?: ChatCitation[];
 }
 
+// Fallback until the server reports the quota (CHAT_DAILY_ROUNDS_GUEST)
 const GUEST_MAX_ROUNDS = 5;
 
 export default function ChatPage({
@@ -44,6 +49,7 @@ export default function ChatPage({
   const [sessionId, setSessionId] = useState<string | null>(null);
   const [tier, setTier] = useState<"guest" | "free" | "paid">("guest");
   const [rounds, setRounds] = useState(0);
+  const [quota, setQuota] = useState<ChatRoundQuota | null>(null);
   const [walletAddr, setWalletAddr] = useState<string | null>(null);
   const [isLoggedIn, setIsLoggedIn] = useState(false);
   const [initLoading, setInitLoading] = useState(true);
@@ -127,6 +133,7 @@ export default function ChatPage({
                 (m: ChatSessionMessage) => ({
                   role: m.role as "user" | "assistant",
                   content: m.content,
+                  citations: m.citations,
                 })
               );
               setMessages(msgs);
@@ -147,6 +154,7 @@ export default function ChatPage({
             setSessionId(res.session_id);
             setTier(res.tier as "guest" | "free" | "paid");
             setRounds(0);
+            setQuota(res.quota ?? null);
           }
         } catch (err: unknown) {
           if (!cancelled) {
@@ -204,6 +212,7 @@ export default function ChatPage({
         (m: ChatSessionMessage) => ({
           role: m.role,
           content: m.content,
+          citations: m.citations,
         })
       );
       setMessages(msgs);
@@ -241,6 +250,7 @@ export default function ChatPage({
       setSessionId(res.session_id);
       setTier(res.tier as "guest" | "free" | "paid");
       setRounds(0);
+      setQuota(res.quota ?? null);
       loadHistory();
     } catch (err: unknown) {
       setError(
@@ -366,9 +376,9 @@ export default function ChatPage({
     if (!text || streaming || !sessionId) return;
 
     // Check guest round limit on frontend too
-    if (tier === "guest" && rounds >= GUEST_MAX_ROUNDS) {
+    if (tier === "guest" && guestLimitReached) {
       setError(
-        `${t("guestLimitError", { max: GUEST_MAX_ROUNDS })}`
+        `${t("guestLimitError", { max: roundLimit })}`
       );
       return;
     }
@@ -394,55 +404,116 @@ export default function ChatPage({
         throw new Error(errData.error || `HTTP ${res.status}`);
       }
 
-      const reader = res.body?.getReader();
-      const decoder = new TextDecoder();
-
-      if (!reader) throw new Error("No response stream");
+      if (!res.body) throw new Error("No response stream");
 
       setRounds((prev) => prev + 1);
 
-      let buffer = "";
-      while (true) {
-        const { done, value } = await reader.read();
-        if (done) break;
-
-        buffer += decoder.decode(value, { stream: true });
-        const lines = buffer.split("\n");
-        buffer = lines.pop() || "";
-
-        for (const line of lines) {
-          if (
-            line.startsWith("event:done") ||
-            line.startsWith("event: done")
-          ) {
-            break;
-          }
-          if (line.startsWith("data:")) {
-            const raw = line.startsWith("data: ")
-              ? line.slice(6)
-              : line.slice(5);
-            if (raw === "[DONE]" || raw === "") continue;
-            // JSON-decode the SSE data to restore newlines
-            let data: string;
-            try {
-              data = JSON.parse(raw);
-            } catch {
-              data = raw;
+      // Last chunk received, and whether the reply reached its "done" event
+      let lastEventId = "";
+      let ended = false;
+
+      const readStream = async (body: ReadableStream<Uint8Array>) => {
+        const reader = body.getReader();
+        const decoder = new TextDecoder();
+        let buffer = "";
+        let event = "message";
+        while (true) {
+          const { done, value } = await reader.read();
+          if (done) break;
+
+          buffer += decoder.decode(value, { stream: true });
+          const lines = buffer.split("\n");
+          buffer = lines.pop() || "";
+
+          for (const line of lines) {
+            if (
+              line.startsWith("event:done") ||
+              line.startsWith("event: done")
+            ) {
+              ended = true;
+              break;
+            }
+            // Chunk ids ("<message_id>:<offset>") let a dropped stream resume
+            if (line.startsWith("id:")) {
+              lastEventId = line.slice(3).trim();
+              continue;
             }
-            // Append chunk to last assistant message
-            setMessages((prev) => {
-              const updated = [...prev];
-              const last = updated[updated.length - 1];
-              if (last && last.role === "assistant") {
-                updated[updated.length - 1] = {
-                  ...last,
-                  content: last.content + data,
-                };
+            if (line.startsWith("event:")) {
+              event = line.slice(6).trim();
+              continue;
+            }
+            if (line.startsWith("data:")) {
+              const raw = line.startsWith("data: ")
+                ? line.slice(6)
+                : line.slice(5);
+              if (raw === "[DONE]" || raw === "") continue;
+              // JSON-decode the SSE data to restore newlines
+              let data: string;
+              try {
+                data = JSON.parse(raw);
+              } catch {
+                data = raw;
+              }
+              // Rounds left today, counted across sessions
+              if (event === "quota") {
+                try {
+                  setQuota(JSON.parse(data));
+                } catch {
+                  // keep the previous quota
+                }
+                continue;
+              }
+              // Citations resolve the reply's [^n] markers to fragments
+              if (event === "citations") {
+                let citations: ChatCitation[] = [];
+                try {
+                  citations = JSON.parse(data);
+                } catch {
+                  continue;
+                }
+                setMessages((prev) => {
+                  const updated = [...prev];
+                  const last = updated[updated.length - 1];
+                  if (last && last.role === "assistant") {
+                    updated[updated.length - 1] = { ...last, citations };
+                  }
+                  return updated;
+                });
+                continue;
               }
-              return updated;
-            });
+              // Append chunk to last assistant message
+              setMessages((prev) => {
+                const updated = [...prev];
+                const last = updated[updated.length - 1];
+                if (last && last.role === "assistant") {
+                  updated[updated.length - 1] = {
+                    ...last,
+                    content: last.content + data,
+                  };
+                }
+                return updated;
+              });
+            }
           }
         }
+      };
+
+      try {
+        await readStream(res.body);
+      } catch (err) {
+        if (!lastEventId) throw err;
+      }
+
+      // The connection dropped mid-reply (e.g. a replica went away): the reply
+      // keeps generating server-side, so pick it up from the last chunk
+      for (let attempt = 0; !ended && lastEventId && attempt < 3; attempt++) {
+        try {
+          const resumed = await chatApi.resumeStream(sessionId, lastEventId);
+          if (!resumed.ok || !resumed.body) break;
+          await readStream(resumed.body);
+        } catch {
+          await new Promise((resolve) => setTimeout(resolve, 1000));
+        }
       }
     } catch (err: unknown) {
       setError(err instanceof Error ? err.message : "Chat failed");
@@ -472,7 +543,11 @@ export default function ChatPage({
     ? stageConfig[(shell.stage as Stage)] || stageConfig.embryo
     : stageConfig.embryo;
 
-  const guestLimitReached = tier === "guest" && rounds >= GUEST_MAX_ROUNDS;
+  // The server counts rounds per wallet / IP per day; the session's own count is the fallback
+  const roundLimit = quota ? quota.limit : GUEST_MAX_ROUNDS;
+  const roundsUsed = quota ? quota.used : rounds;
+  const roundsLeft = quota ? (quota.remaining ?? Infinity) : GUEST_MAX_ROUNDS - rounds;
+  const guestLimitReached = tier === "guest" && roundLimit > 0 && roundsLeft <= 0;
 
   return (
     <div className="flex h-screen pt-16">
@@ -512,6 +587,14 @@ export default function ChatPage({
                       {s.rounds} {t("rounds")} · {s.tier}
                     </p>
                   </div>
+                  <a
+                    href={chatApi.exportUrl(s.id, "markdown")}
+                    onClick={(e) => e.stopPropagation()}
+                    className="hidden text-xs text-[#94a3b8] hover:text-[#e2e8f0] group-hover:block"
+                    title="Export (Markdown)"
+                  >
+                    ⇩
+                  </a>
                   <button
                     onClick={(e) => {
                       e.stopPropagation();
@@ -585,7 +668,7 @@ export default function ChatPage({
               {/* Round counter */}
               {tier === "guest" && (
                 <span className="rounded-full bg-[#1e1e2e] px-2 py-0.5 text-xs text-[#f59e0b]">
-                  {t("roundCounter", { rounds, max: GUEST_MAX_ROUNDS })}
+                  {t("roundCounter", { rounds: roundsUsed, max: roundLimit })}
                 </span>
               )}
               {tier === "free" && (
@@ -632,7 +715,7 @@ export default function ChatPage({
                 </p>
                 {tier === "guest" && !isLoggedIn && (
                   <p className="mt-3 text-xs text-[#f59e0b]">
-                    {t("guestMode", { max: GUEST_MAX_ROUNDS })}
+                    {t("guestMode", { max: roundLimit })}
                   </p>
                 )}
               </div>
@@ -666,6 +749,23 @@ export default function ChatPage({
                               {msg.content}
                             </ReactMarkdown>
                           </div>
+                          {msg.citations && msg.citations.length > 0 && (
+                            <div className="mt-2 flex flex-wrap items-center gap-2 text-xs text-[#64748b]">
+                              <span>{t("sources")}</span>
+                              {msg.citations.map((cite) => (
+                                <a
+                                  key={cite.marker}
+                                  href={fragmentApi.url(cite.fragment_id)}
+                                  target="_blank"
+                                  rel="noopener noreferrer"
+                                  className="rounded bg-[#1e1e2e] px-1.5 py-0.5 font-mono transition-colors hover:text-[#8b5cf6]"
+                                  title={`${cite.dimension} · ${cite.content_hash}`}
+                                >
+                                  [^{cite.marker}] {cite.content_hash.slice(0, 8)}
+                                </a>
+                              ))}
+                            </div>
+                          )}
                           {/* Share buttons — visible on hover */}
                           {hoveredMsg === i && !streaming && (
                             <div className="mt-2 flex items-center gap-2 border-t border-[#1e1e2e] pt-2">
@@ -776,9 +876,9 @@ export default function ChatPage({
                 disabled={streaming || guestLimitReached || initLoading}
               />
               <div className="absolute right-3 bottom-3 flex items-center gap-2">
-                {tier === "guest" && !guestLimitReached && rounds > 0 && (
+                {tier === "guest" && !guestLimitReached && roundsUsed > 0 && roundLimit > 0 && (
                   <span className="text-xs text-[#94a3b8]">
-                    {t("roundsLeft", { count: GUEST_MAX_ROUNDS - rounds })}
+                    {t("roundsLeft", { count: roundsLeft })}
                   </span>
                 )}
                 <button
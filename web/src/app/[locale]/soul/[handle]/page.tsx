@@ -29,17 +29,15 @@ export default function SoulPage({
   useEffect(() => {
     async function load() {
       try {
-        const s = await shellApi.get(handle);
-        setShell(s);
-        // Load fragments and history in parallel
-        const [fragRes, hist, contribs] = await Promise.all([
+        // Soul page data in one call, fragments in parallel
+        const [full, fragRes] = await Promise.all([
+          shellApi.getFull(handle),
           fragmentApi.list({ handle, limit: 50 }),
-          shellApi.getHistory(handle).catch(() => []),
-          shellApi.getContributors(handle).catch(() => ({ contributors: [] })),
         ]);
+        setShell(full.shell);
         setFragments(fragRes.fragments || []);
-        setHistory(hist || []);
-        setContributors(contribs.contributors || []);
+        setHistory(full.history || []);
+        setContributors(full.contributors || []);
       } catch (err: unknown) {
         setError(err instanceof Error ? err.message : "Failed to load soul");
       } finally {
@@ -163,9 +161,9 @@ export default function SoulPage({
           </a>
 
           {/* Bio */}
-          {(meta.bio || shell.seed_summary) && (
+          {(meta.bio || shell.essence) && (
             <p className="mb-4 text-sm leading-relaxed text-[#94a3b8]">
-              {meta.bio || shell.seed_summary}
+              {meta.bio || shell.essence}
             </p>
           )}
 
@@ -253,11 +251,11 @@ export default function SoulPage({
           <span className="ml-auto font-mono text-[#8b5cf6]">{dimCoverage}/6</span>
         </div>
 
-        {/* Seed Summary */}
-        {shell.seed_summary && (
+        {/* Essence */}
+        {shell.essence && (
           <div className="mt-4 rounded-lg border border-[#1e1e2e]/40 bg-[#0a0a0f]/30 p-3">
             <p className="text-xs leading-relaxed text-[#e2e8f0]/80">
-              {shell.seed_summary}
+              {shell.essence}
             </p>
           </div>
         )}
@@ -354,7 +352,7 @@ export default function SoulPage({
               ) : (
                 <div className="space-y-3">
                   <p className="text-sm leading-relaxed text-[#e2e8f0]">
-                    {shell.seed_summary || t("formingIdentity")}
+                    {shell.essence || t("formingIdentity")}
                   </p>
                   <div className="flex flex-wrap gap-2">
                     <span className="rounded-full bg-[#8b5cf6]/10 px-2.5 py-1 text-xs text-[#a78bfa]">
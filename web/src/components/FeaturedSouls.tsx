@@ -3,17 +3,17 @@
 import { useState, useEffect } from "react";
 import { useTranslations } from "next-intl";
 import { Link } from "@/i18n/navigation";
-import { shellApi, Shell } from "@/lib/api";
+import { shellApi, ShellSummary } from "@/lib/api";
 import SoulCard from "@/components/SoulCard";
 
 export default function FeaturedSouls() {
   const t = useTranslations("Home");
-  const [shells, setShells] = useState<Shell[]>([]);
+  const [shells, setShells] = useState<ShellSummary[]>([]);
   const [loading, setLoading] = useState(true);
 
   useEffect(() => {
     shellApi
-      .list({ sort: "hot", limit: 6 })
+      .listSlim({ sort: "hot", limit: 6 })
       .then((res) => setShells(res.shells || []))
       .catch(() => {})
       .finally(() => setLoading(false));
@@ -3,16 +3,16 @@
 import { useTranslations } from "next-intl";
 import { Link } from "@/i18n/navigation";
 import { stageConfig, type Stage, calcCompletion } from "@/lib/utils";
-import type { Shell } from "@/lib/api";
+import type { Shell, ShellSummary } from "@/lib/api";
 
 interface SoulCardProps {
-  soul: Shell;
+  soul: Shell | ShellSummary;
 }
 
 export default function SoulCard({ soul }: SoulCardProps) {
   const t = useTranslations("SoulCard");
   const stage = stageConfig[soul.stage as Stage] || stageConfig.embryo;
-  const completion = calcCompletion(soul.dimensions || {});
+  const completion = "completion" in soul ? soul.completion : calcCompletion(soul.dimensions || {});
 
   return (
     <Link href={`/soul/${soul.handle}`}>
@@ -62,7 +62,8 @@ export interface Shell {
   owner_addr: string;
   stage: "embryo" | "growing" | "mature" | "evolving";
   dna_version: number;
-  seed_summary: string;
+  // Public summary, rewritten by each ensouling (the seed summary is not returned)
+  essence: string;
   soul_prompt: string;
   dimensions: Record<string, DimensionData>;
   total_frags: number;
@@ -77,6 +78,24 @@ export interface Shell {
   updated_at: string;
 }
 
+// Explore-card view of a soul (GET /api/shell/list?view=slim)
+export interface ShellSummary {
+  id: string;
+  handle: string;
+  display_name: string;
+  avatar_url: string;
+  stage: Shell["stage"];
+  dna_version: number;
+  completion: number;
+  total_frags: number;
+  accepted_frags: number;
+  total_claws: number;
+  total_chats: number;
+  followers: number;
+  tags: string[];
+  created_at: string;
+}
+
 export interface Fragment {
   id: string;
   shell_id: string;
@@ -88,11 +107,26 @@ export interface Fragment {
   confidence: number;
   reject_reason?: string;
   tx_hash?: string;
+  notes?: string; // private operator notes, only in the Claw's own views
   created_at: string;
   claw?: Claw;
   shell?: Shell;
 }
 
+export interface BatchQuota {
+  handle: string;
+  limit: number; // 0 = unlimited
+  used: number;
+  remaining: number;
+  resets_at?: string;
+}
+
+export interface ClawBatchQuota {
+  limit_per_soul: number;
+  window_hours: number;
+  souls: BatchQuota[];
+}
+
 export interface Claw {
   id: string;
   name: string;
@@ -113,6 +147,9 @@ export interface SeedPreview {
   seed_summary: string;
   dimensions: Record<string, DimensionData>;
   twitter_meta?: TwitterMeta;
+  // Server signature over the preview; send the preview back unchanged to mint
+  expires_at?: number;
+  signature?: string;
 }
 
 export interface Ensouling {
@@ -183,12 +220,30 @@ export interface GlobalStats {
   chats: number;
 }
 
+export interface TaskExample {
+  fragment_id: string;
+  content_hash: string;
+  excerpt: string;
+}
+
 export interface TaskItem {
   handle: string;
   dimension: string;
   score: number;
   priority: string;
+  followers: number;
   message: string;
+  reward_weight: number; // 0-1
+  evidence_types: string[];
+  acceptance_rate: number | null; // null until something was reviewed
+  examples?: TaskExample[]; // Claw API key only
+}
+
+export interface TaskPage {
+  tasks: TaskItem[];
+  total: number;
+  page: number;
+  limit: number;
 }
 
 export interface PaginatedResult<T> {
@@ -243,17 +298,22 @@ export const shellApi = {
     search?: string;
     page?: number;
     limit?: number;
-  }) => {
-    const query = new URLSearchParams();
-    if (params?.stage) query.set("stage", params.stage);
-    if (params?.sort) query.set("sort", params.sort);
-    if (params?.search) query.set("search", params.search);
-    if (params?.page) query.set("page", String(params.page));
-    if (params?.limit) query.set("limit", String(params.limit));
-    return apiFetch<{ shells: Shell[]; total: number; page: number; limit: number }>(
-      `/api/shell/list?${query}`
-    );
-  },
+  }) =>
+    apiFetch<{ shells: Shell[]; total: number; page: number; limit: number }>(
+      `/api/shell/list?${shellListQuery(params)}`
+    ),
+
+  // Explore cards only: a fraction of the full records' size
+  listSlim: (params?: {
+    stage?: string;
+    sort?: string;
+    search?: string;
+    page?: number;
+    limit?: number;
+  }) =>
+    apiFetch<{ shells: ShellSummary[]; total: number; page: number; limit: number }>(
+      `/api/shell/list?${shellListQuery(params, "slim")}`
+    ),
 
   get: (handle: string) => apiFetch<Shell>(`/api/shell/${handle}`),
 
@@ -265,8 +325,34 @@ export const shellApi = {
 
   getContributors: (handle: string) =>
     apiFetch<{ contributors: ShellContributor[] }>(`/api/shell/${handle}/contributors`),
+
+  // Shell, dimensions, history, contributors and reputation in one call
+  getFull: (handle: string) =>
+    apiFetch<{
+      shell: Shell;
+      registry_paused: boolean;
+      dimensions: Record<string, DimensionData>;
+      history: Ensouling[];
+      contributors: ShellContributor[];
+      reputation: Record<string, unknown> | null;
+      generated_at: string;
+    }>(`/api/shell/${handle}/full`),
 };
 
+function shellListQuery(
+  params?: { stage?: string; sort?: string; search?: string; page?: number; limit?: number },
+  view?: "slim"
+) {
+  const query = new URLSearchParams();
+  if (params?.stage) query.set("stage", params.stage);
+  if (params?.sort) query.set("sort", params.sort);
+  if (params?.search) query.set("search", params.search);
+  if (params?.page) query.set("page", String(params.page));
+  if (params?.limit) query.set("limit", String(params.limit));
+  if (view) query.set("view", view);
+  return query;
+}
+
 // --- Fragment API ---
 
 export const fragmentApi = {
@@ -295,6 +381,9 @@ export const fragmentApi = {
   },
 
   get: (id: string) => apiFetch<Fragment>(`/api/fragment/${id}`),
+
+  // Public hash-only record, used to resolve chat citations
+  url: (id: string) => `${API_BASE}/api/fragment/${id}`,
 };
 
 // --- Claw API ---
@@ -337,6 +426,7 @@ export const clawApi = {
         earnings: number;
       };
       recent_contributions: Fragment[];
+      batch_quota: ClawBatchQuota;
     }>("/api/claw/dashboard", apiKey),
 
   contributions: (apiKey: string, page?: number, limit?: number) => {
@@ -383,13 +473,30 @@ export interface ChatSessionMessage {
   session_id: string;
   role: "user" | "assistant";
   content: string;
+  citations?: ChatCitation[];
   created_at: string;
 }
 
+// A [^n] marker in an assistant reply and the fragment it cites
+export interface ChatCitation {
+  marker: number;
+  fragment_id: string;
+  dimension: string;
+  content_hash: string;
+}
+
+// Rounds left today with one soul, counted per wallet (per IP for guests)
+export interface ChatRoundQuota {
+  limit: number; // 0 = unlimited
+  used: number;
+  remaining: number | null; // null when unlimited
+  resets_at: string;
+}
+
 export const chatApi = {
   // Create a new chat session for a soul
   createSession: (handle: string) =>
-    apiFetch<{ session_id: string; tier: string }>(`/api/chat/${handle}/session`, {
+    apiFetch<{ session_id: string; tier: string; quota?: ChatRoundQuota }>(`/api/chat/${handle}/session`, {
       method: "POST",
     }),
 
@@ -404,6 +511,15 @@ export const chatApi = {
     });
   },
 
+  // Resume a reply after a dropped connection (returns raw Response for SSE streaming)
+  resumeStream: (sessionId: string, lastEventId: string) => {
+    const url = `${API_BASE}/api/chat/sessions/${sessionId}/stream`;
+    return fetch(url, {
+      credentials: "include",
+      headers: { "Last-Event-ID": lastEventId },
+    });
+  },
+
   // Get a chat session with its messages
   getSession: (sessionId: string) =>
     apiFetch<ChatSession>(`/api/chat/sessions/${sessionId}`),
@@ -419,6 +535,10 @@ export const chatApi = {
     apiFetch<{ status: string }>(`/api/chat/sessions/${sessionId}`, {
       method: "DELETE",
     }),
+
+  // Download URL of a session transcript (requires login + ownership)
+  exportUrl: (sessionId: string, format: "markdown" | "json") =>
+    `${API_BASE}/api/chat/sessions/${sessionId}/export?format=${format}`,
 };
 
 // --- Share API ---
@@ -463,11 +583,33 @@ export const statsApi = {
 // --- Tasks API ---
 
 export const tasksApi = {
-  list: () => apiFetch<TaskItem[]>("/api/tasks"),
+  list: (params?: {
+    handle?: string;
+    dimension?: string;
+    priority?: string;
+    page?: number;
+    limit?: number;
+  }) => {
+    const query = new URLSearchParams({ format: "board" });
+    if (params?.handle) query.set("handle", params.handle);
+    if (params?.dimension) query.set("dimension", params.dimension);
+    if (params?.priority) query.set("priority", params.priority);
+    if (params?.page) query.set("page", String(params.page));
+    if (params?.limit) query.set("limit", String(params.limit));
+    return apiFetch<TaskPage>(`/api/tasks?${query}`);
+  },
 };
 
 // --- Session Auth API (wallet signature login, HttpOnly cookie) ---
 
+export interface MintQuota {
+  wallet: string;
+  limit: number; // 0 = unlimited
+  used: number;
+  remaining: number;
+  allowlisted: boolean;
+}
+
 export const sessionApi = {
   login: (address: string, signature: string, message: string) =>
     apiFetch<{ address: string; message: string }>("/api/auth/login", {
@@ -481,7 +623,7 @@ export const sessionApi = {
     }),
 
   session: () =>
-    apiFetch<{ address: string }>("/api/auth/session"),
+    apiFetch<{ address: string; mint_quota: MintQuota }>("/api/auth/session"),
 };
 
 // --- Claw Key Management API (session-based, no API key in frontend) ---
@@ -520,5 +662,16 @@ export const clawKeyApi = {
         earnings: number;
       };
       recent_contributions: Fragment[];
+      batch_quota: ClawBatchQuota;
     }>(`/api/claw/keys/${bindingId}/dashboard`),
+
+  // Search a bound Claw's contributions by private notes and content
+  contributions: (bindingId: string, q: string, page?: number, limit?: number) => {
+    const query = new URLSearchParams({ q });
+    if (page) query.set("page", String(page));
+    if (limit) query.set("limit", String(limit));
+    return apiFetch<{ contributions: Fragment[]; total: number }>(
+      `/api/claw/keys/${bindingId}/contributions?${query}`
+    );
+  },
 };
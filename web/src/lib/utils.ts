@@ -55,6 +55,15 @@ export function timeAgo(dateString: string): string {
   return date.toLocaleDateString("en-US", { month: "short", day: "numeric" });
 }
 
+// Format a future timestamp as "in 5m", "in 3h"
+export function timeUntil(dateString: string): string {
+  const seconds = Math.ceil((new Date(dateString).getTime() - Date.now()) / 1000);
+
+  if (seconds <= 0) return "now";
+  if (seconds < 3600) return `in ${Math.ceil(seconds / 60)}m`;
+  return `in ${Math.ceil(seconds / 3600)}h`;
+}
+
 // Truncate wallet address for display
 export function truncateAddr(addr: string): string {
   if (!addr || addr.length < 10) return addr;